@@ -7,13 +7,14 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/http"
-	"os"
+	"sync"
 	"time"
 
 	"github.com/btcsuite/btcd/btcec"
 	"github.com/btcsuite/btcd/txscript"
 	"github.com/btcsuite/btcd/wire"
 	"github.com/btcsuite/btcutil"
+	"github.com/renproject/libzec-go/clients"
 	"github.com/sirupsen/logrus"
 )
 
@@ -29,10 +30,49 @@ const (
 	Fast
 )
 
+// TxReceipt describes a transaction that was submitted to the ZCash
+// blockchain. The ChangeIndex is -1 if the transaction has no change output.
+type TxReceipt struct {
+	TxHash       string         `json:"txHash"`
+	Inputs       []clients.UTXO `json:"inputs"`
+	Outputs      []*wire.TxOut  `json:"outputs"`
+	Value        int64          `json:"value"`
+	Fee          int64          `json:"fee"`
+	Change       int64          `json:"change"`
+	ChangeIndex  int            `json:"changeIndex"`
+	ExpiryHeight uint32         `json:"expiryHeight"`
+
+	// RedeemScripts are the redeem scripts of the inputs locked by P2SH
+	// scripts, in the order of Inputs, and Unlock is the script pushed
+	// between the public key and the redeem script of these inputs. They
+	// are empty if every input is locked by a P2PKH script. Together with
+	// LockTime, they are used to sign the replacement of the transaction.
+	RedeemScripts [][]byte `json:"redeemScripts,omitempty"`
+	Unlock        []byte   `json:"unlock,omitempty"`
+	LockTime      uint32   `json:"lockTime,omitempty"`
+
+	// Confirmation is the expected time until the transaction is mined. It
+	// is only estimated by Transfer and SendTransaction.
+	Confirmation ConfirmationEstimate `json:"confirmation"`
+}
+
 type account struct {
-	PrivKey *btcec.PrivateKey
-	Logger  logrus.FieldLogger
+	Signer       Signer
+	PubKey       *btcec.PublicKey
+	WatchAddress btcutil.Address
+	Logger       logrus.FieldLogger
+	FeeEstimator FeeEstimator
 	Client
+
+	// mu guards the cache and the tx store, which can be set while the
+	// account is in use.
+	mu           *sync.Mutex
+	cache        *utxoCache
+	txStore      TxStore
+	metadata     MetadataStore
+	coinSelector CoinSelector
+	feeLimits    feeLimits
+	*utxoFreezer
 }
 
 // Account is an ZCash external account that can sign and submit transactions
@@ -43,7 +83,104 @@ type Account interface {
 	BTCClient() Client
 	Address() (btcutil.Address, error)
 	SerializedPublicKey() ([]byte, error)
-	Transfer(ctx context.Context, to string, value int64, speed TxExecutionSpeed, sendAll bool) (string, int64, error)
+	// Transfer transfers the value to the given address. If sendAll is set,
+	// the value is ignored, and every spendable utxo of the account is sent
+	// to the address, minus the fee.
+	Transfer(ctx context.Context, to string, value int64, speed TxExecutionSpeed, sendAll bool) (TxReceipt, error)
+
+	// PreviewTransfer selects the utxos that would be used by the same call to
+	// Transfer, and returns a preview of the transaction without signing or
+	// broadcasting it.
+	PreviewTransfer(ctx context.Context, to string, value int64, speed TxExecutionSpeed, sendAll bool) (TxPreview, error)
+
+	// TransferToScript transfers zcash to an output locked by the script, or
+	// by the P2SH script of the script if wrap is set, without encoding it as
+	// an address.
+	TransferToScript(ctx context.Context, script []byte, value int64, speed TxExecutionSpeed, wrap bool) (TxReceipt, error)
+
+	// TransferWithCoinSelector transfers zcash to the given address like
+	// Transfer, funding the transaction with the utxos chosen by the
+	// selector instead of the coin selector of the account.
+	TransferWithCoinSelector(ctx context.Context, to string, value int64, speed TxExecutionSpeed, selector CoinSelector) (TxReceipt, error)
+
+	// SetCoinSelector sets the coin selector that chooses the utxos funding
+	// the transactions of the account.
+	SetCoinSelector(selector CoinSelector)
+
+	// TransferAndWait transfers zcash to the given address, and waits until
+	// the transaction has the given number of confirmations. A TxExpiredError,
+	// matching ErrTxExpired, is returned if the transaction expires before it
+	// is mined.
+	TransferAndWait(ctx context.Context, to string, value int64, speed TxExecutionSpeed, sendAll bool, confirmations int64) (TxReceipt, error)
+
+	// Resubmit replaces a transaction that was sent by this account with a
+	// new transaction spending the same utxos to the same outputs, with a new
+	// expiry height. The fee bump is deducted from the change output, or from
+	// the last output if there is no change.
+	Resubmit(ctx context.Context, receipt TxReceipt, feeBump int64) (TxReceipt, error)
+
+	// BumpFee builds a child-pays-for-parent transaction, that spends the
+	// outputs of the parent transaction that belong to this account back to
+	// the account, paying the given fee. The fee should be high enough to pay
+	// for both the parent and the child transactions.
+	BumpFee(ctx context.Context, parentTxHash string, fee int64) (TxReceipt, error)
+
+	// BuildTransfer builds the same transaction as Transfer, without signing
+	// or broadcasting it. The hashes of the unsigned transaction can be signed
+	// externally, which allows watch-only accounts to spend from cold storage.
+	BuildTransfer(ctx context.Context, to string, value int64, speed TxExecutionSpeed, sendAll bool) (Tx, error)
+
+	// SignMessage signs the message with the key of the account, in the format
+	// of the signmessage RPC of zcashd, to prove the control of its address.
+	// The signature can be verified using VerifyMessage.
+	SignMessage(ctx context.Context, message string) (string, error)
+
+	// SetFeeEstimator sets the fee estimator used to price the transactions
+	// sent by this account.
+	SetFeeEstimator(estimator FeeEstimator)
+
+	// SetFeeLimits sets the minimum and the maximum fee of the transactions
+	// sent by the account. Building a transaction paying a fee outside of
+	// these limits fails with a FeeOutOfBoundsError. By default the fee is
+	// at most DefaultMaxFee.
+	SetFeeLimits(min, max int64)
+
+	// EnableUTXOCache caches the unconfirmed utxos of the account in memory.
+	// The cache is updated when the account broadcasts a transaction, and is
+	// refreshed from the client at the given interval until the context is
+	// done.
+	EnableUTXOCache(ctx context.Context, refreshInterval time.Duration)
+
+	// SetTxStore sets the store in which the account records every
+	// transaction it broadcasts. The unconfirmed utxos returned by the account
+	// account for the pending transactions of the store, including those
+	// broadcast before a restart.
+	SetTxStore(store TxStore)
+
+	// PendingTxs updates the state of the pending transactions of the tx
+	// store, and returns those that are still pending.
+	PendingTxs() ([]StoredTx, error)
+
+	// SetMetadataStore sets the store of the labels and notes that annotate
+	// the history of the account.
+	SetMetadataStore(store MetadataStore)
+
+	// History returns the transactions of the account, from the most recent
+	// one, with the net change of the balance of the account caused by every
+	// transaction and the running balance. A limit of 0 returns every
+	// transaction after the offset.
+	History(ctx context.Context, limit, offset int) ([]HistoryEntry, error)
+
+	// FreezeUTXO prevents the utxo from being selected to fund transactions,
+	// or from being swept, until it is unfrozen.
+	FreezeUTXO(txHash string, vout uint32)
+
+	// UnfreezeUTXO allows a frozen utxo to be spent again.
+	UnfreezeUTXO(txHash string, vout uint32)
+
+	// FrozenUTXOs returns the frozen outpoints, formatted as "txhash:vout".
+	FrozenUTXOs() []string
+
 	SendTransaction(
 		ctx context.Context,
 		script []byte,
@@ -53,30 +190,140 @@ type Account interface {
 		f func(*txscript.ScriptBuilder),
 		postCond func(*wire.MsgTx) bool,
 		sendAll bool,
-	) (string, int64, error)
+	) (TxReceipt, error)
+
+	// SweepSlaves spends the funded slave scripts, created with the account's
+	// public key hash and the given nonces, back to the account's address.
+	SweepSlaves(ctx context.Context, nonces [][]byte, speed TxExecutionSpeed) ([]TxReceipt, error)
+
+	// BuildSweep builds the unsigned transaction that sweeps the funded slave
+	// scripts of the nonces back to the account's address.
+	BuildSweep(ctx context.Context, nonces [][]byte, speed TxExecutionSpeed) (Tx, error)
+
+	// BuildSponsoredSweep builds the unsigned transaction that sweeps the
+	// funded slave scripts of the nonces back to the account's address
+	// without paying a fee, signed with SigHashAll|SigHashAnyOneCanPay so that
+	// a sponsor can add the fee with TopUpFee.
+	BuildSponsoredSweep(ctx context.Context, nonces [][]byte) (Tx, error)
+
+	// BuildChainedSweep builds and signs the transactions that sweep the
+	// funded slave scripts of the nonces back to the account's address, split
+	// into a chain of transactions within MaxStandardTxSize. They are
+	// returned in the order in which they must be broadcast. The utxos that
+	// do not fit within the mempool ancestor limits are left unspent.
+	BuildChainedSweep(ctx context.Context, nonces [][]byte, speed TxExecutionSpeed) ([]ChainedTx, error)
+
+	// BuildConsolidation builds and signs the transactions that spend every
+	// spendable utxo of the account back to its address, split into a chain
+	// of transactions within MaxStandardTxSize. The utxos that do not fit
+	// within the mempool ancestor limits are left unspent.
+	BuildConsolidation(ctx context.Context, speed TxExecutionSpeed) ([]ChainedTx, error)
+
+	// TopUpFee adds inputs worth at least the fee, spending the utxos of the
+	// account, to the signed transaction and submits it. Every input of the
+	// transaction must be validly signed with SigHashAll|SigHashAnyOneCanPay,
+	// and the value paid must be within the fee limits of the account.
+	TopUpFee(ctx context.Context, stx []byte, fee int64) (TxReceipt, error)
+
+	// InitiateHTLC funds the given hash time locked contract.
+	InitiateHTLC(ctx context.Context, script []byte, value int64, speed TxExecutionSpeed) (TxReceipt, error)
+
+	// RedeemHTLC redeems the given hash time locked contract using the secret.
+	RedeemHTLC(ctx context.Context, script []byte, secret [32]byte, speed TxExecutionSpeed) (TxReceipt, error)
+
+	// RefundHTLC refunds the given hash time locked contract after its lock
+	// time has passed.
+	RefundHTLC(ctx context.Context, script []byte, speed TxExecutionSpeed) (TxReceipt, error)
+
+	// RefundSlave refunds a refundable slave script, that names this account
+	// as the refunder, after its lock time has passed.
+	RefundSlave(ctx context.Context, mpkh, nonce []byte, lockTime int64, speed TxExecutionSpeed) (TxReceipt, error)
+
+	// ShieldTo sends the amount to the shielded address with the given memo,
+	// and returns the txid of the transaction. It requires a client backed by
+	// a zcashd wallet that controls the address of the account.
+	ShieldTo(ctx context.Context, zaddr string, amount int64, memo string) (string, error)
+
+	// ShieldCoinbase sends the coinbase utxos of the account to the shielded
+	// address, and returns the txid of the transaction. It requires a client
+	// backed by a zcashd wallet that controls the address of the account.
+	ShieldCoinbase(ctx context.Context, zaddr string) (string, error)
 }
 
 // NewAccount returns a user account for the provided private key which is
 // connected to a ZCash client.
 func NewAccount(client Client, privateKey *ecdsa.PrivateKey, logger logrus.FieldLogger) Account {
-	if logger == nil {
-		nullLogger := logrus.New()
-		logFile, err := os.OpenFile(os.DevNull, os.O_APPEND|os.O_WRONLY, 0666)
-		if err != nil {
-			panic(err)
-		}
-		nullLogger.SetOutput(logFile)
-		logger = nullLogger
+	return NewAccountWithSigner(client, NewSigner(privateKey), logger)
+}
+
+// NewAccountWithSigner returns a user account which signs its transactions
+// using the provided signer.
+func NewAccountWithSigner(client Client, signer Signer, logger logrus.FieldLogger) Account {
+	return newAccount(client, signer, signer.PublicKey(), nil, logger)
+}
+
+// NewWatchOnlyAccount returns an account for the provided public key which is
+// connected to a ZCash client. A watch-only account can query its balance and
+// build unsigned transactions, but returns ErrWatchOnly on any attempt to sign.
+func NewWatchOnlyAccount(client Client, publicKey *ecdsa.PublicKey, logger logrus.FieldLogger) Account {
+	return newAccount(client, nil, (*btcec.PublicKey)(publicKey), nil, logger)
+}
+
+// NewWatchOnlyAccountFromAddress returns a watch-only account for the provided
+// address. As the public key is unknown, the account cannot build unsigned
+// transactions, and SerializedPublicKey returns ErrNoPublicKey.
+func NewWatchOnlyAccountFromAddress(client Client, address string, logger logrus.FieldLogger) (Account, error) {
+	addr, err := DecodeAddress(address, client.NetworkParams())
+	if err != nil {
+		return nil, err
 	}
+	return newAccount(client, nil, nil, addr, logger), nil
+}
+
+func newAccount(client Client, signer Signer, pubKey *btcec.PublicKey, watchAddress btcutil.Address, logger logrus.FieldLogger) *account {
 	return &account{
-		(*btcec.PrivateKey)(privateKey),
-		logger,
+		signer,
+		pubKey,
+		watchAddress,
+		defaultLogger(logger),
+		NewStaticFeeEstimator(DefaultSlowFeeRate, DefaultStandardFeeRate, DefaultFastFeeRate),
 		client,
+		new(sync.Mutex),
+		nil,
+		nil,
+		nil,
+		nil,
+		defaultFeeLimits(),
+		newUTXOFreezer(),
+	}
+}
+
+// defaultLogger returns the logger, or a logger that discards everything if it
+// is nil.
+func defaultLogger(logger logrus.FieldLogger) logrus.FieldLogger {
+	if logger != nil {
+		return logger
 	}
+	nullLogger := logrus.New()
+	nullLogger.SetOutput(ioutil.Discard)
+	return nullLogger
+}
+
+// SetFeeLimits sets the minimum and the maximum fee of the transactions sent
+// by the account, whatever the fee rate returned by its estimator.
+func (account *account) SetFeeLimits(min, max int64) {
+	account.feeLimits = feeLimits{min, max}
+}
+
+func (account *account) SetFeeEstimator(estimator FeeEstimator) {
+	account.FeeEstimator = estimator
 }
 
 // Address returns the address of the given private key
 func (account *account) Address() (btcutil.Address, error) {
+	if account.WatchAddress != nil {
+		return account.WatchAddress, nil
+	}
 	pubKeyBytes, err := account.SerializedPublicKey()
 	if err != nil {
 		return nil, err
@@ -85,24 +332,10 @@ func (account *account) Address() (btcutil.Address, error) {
 }
 
 // Transfer zcash to the given address
-func (account *account) Transfer(ctx context.Context, to string, value int64, speed TxExecutionSpeed, sendAll bool) (string, int64, error) {
-	if sendAll {
-		me, err := account.Address()
-		if err != nil {
-			return "", 0, err
-		}
-		balance, err := account.Balance(me.EncodeAddress(), 0)
-		if err != nil {
-			return "", 0, err
-		}
-		value = balance
-	}
-
-	value -= MaxZCashFee
-
+func (account *account) Transfer(ctx context.Context, to string, value int64, speed TxExecutionSpeed, sendAll bool) (TxReceipt, error) {
 	address, err := DecodeAddress(to, account.NetworkParams())
 	if err != nil {
-		return "", 0, err
+		return TxReceipt{}, err
 	}
 	return account.SendTransaction(
 		ctx,
@@ -123,16 +356,252 @@ func (account *account) Transfer(ctx context.Context, to string, value int64, sp
 	)
 }
 
+// TransferToScript transfers zcash to an output locked by the script, or by
+// the P2SH script of the script if wrap is set. Nodes only relay transactions
+// whose outputs are locked by standard scripts, so a script that is not
+// standard should be wrapped.
+func (account *account) TransferToScript(ctx context.Context, script []byte, value int64, speed TxExecutionSpeed, wrap bool) (TxReceipt, error) {
+	if len(script) == 0 {
+		return TxReceipt{}, fmt.Errorf("empty output script")
+	}
+	pkScript := script
+	if wrap {
+		var err error
+		if pkScript, err = PayToScriptHashScript(script); err != nil {
+			return TxReceipt{}, err
+		}
+	}
+	return account.SendTransaction(
+		ctx,
+		nil,
+		speed,
+		nil,
+		func(tx *wire.MsgTx) bool {
+			tx.AddTxOut(wire.NewTxOut(value, pkScript))
+			return true
+		},
+		nil,
+		nil,
+		false,
+	)
+}
+
+// TransferAndWait transfers zcash to the given address and waits for the
+// transaction to be confirmed.
+func (account *account) TransferAndWait(ctx context.Context, to string, value int64, speed TxExecutionSpeed, sendAll bool, confirmations int64) (TxReceipt, error) {
+	receipt, err := account.Transfer(ctx, to, value, speed, sendAll)
+	if err != nil {
+		return receipt, err
+	}
+	return receipt, account.waitForConfirmations(ctx, receipt.TxHash, receipt.ExpiryHeight, confirmations)
+}
+
+// waitForConfirmations blocks until the transaction has the given number of
+// confirmations, the transaction expires, or the context is done. Expiry is
+// only detected if the client supports fetching the block height.
+func (account *account) waitForConfirmations(ctx context.Context, txHash string, expiryHeight uint32, confirmations int64) error {
+	ticker := time.NewTicker(10 * time.Second)
+	defer ticker.Stop()
+	for {
+		conf, err := account.Confirmations(txHash)
+		if err != nil {
+			account.Logger.Infof("cannot get the confirmations of %s: %v", txHash, err)
+		}
+		if err == nil && conf >= confirmations {
+			return nil
+		}
+		if err == nil && conf == 0 && expiryHeight != 0 {
+			height, err := account.BlockHeight()
+			if err == nil && expired(expiryHeight, height) {
+				return NewErrTxExpired(txHash, expiryHeight, height)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Resubmit re-signs and submits the transaction described by the receipt.
+func (account *account) Resubmit(ctx context.Context, receipt TxReceipt, feeBump int64) (TxReceipt, error) {
+	if len(receipt.Inputs) == 0 || len(receipt.Outputs) == 0 {
+		return TxReceipt{}, fmt.Errorf("cannot resubmit a transaction without inputs or outputs")
+	}
+
+	tx, err := account.newTx(wire.NewMsgTx(4))
+	if err != nil {
+		return TxReceipt{}, err
+	}
+	if len(receipt.RedeemScripts) != 0 && len(receipt.RedeemScripts) != len(receipt.Inputs) {
+		return TxReceipt{}, fmt.Errorf("invalid number of redeem scripts: got: %d required: %d", len(receipt.RedeemScripts), len(receipt.Inputs))
+	}
+	for i, utxo := range receipt.Inputs {
+		var redeemScript []byte
+		if len(receipt.RedeemScripts) != 0 {
+			redeemScript = receipt.RedeemScripts[i]
+		}
+		if err := tx.addScriptInput(utxo, redeemScript); err != nil {
+			return TxReceipt{}, err
+		}
+	}
+	tx.msgTx.LockTime = receipt.LockTime
+	for _, txOut := range receipt.Outputs {
+		tx.msgTx.AddTxOut(wire.NewTxOut(txOut.Value, txOut.PkScript))
+	}
+	tx.changeIndex = receipt.ChangeIndex
+
+	bumped := tx.changeIndex
+	if bumped < 0 || bumped >= len(tx.msgTx.TxOut) {
+		bumped = len(tx.msgTx.TxOut) - 1
+	}
+	if tx.msgTx.TxOut[bumped].Value-feeBump < ZCashDust {
+		return TxReceipt{}, fmt.Errorf("cannot bump the fee by %d: output %d has %d", feeBump, bumped, tx.msgTx.TxOut[bumped].Value)
+	}
+	tx.msgTx.TxOut[bumped].Value -= feeBump
+
+	// Time locked contracts are only spendable once their lock time has
+	// passed, which requires non final sequence numbers.
+	var updateTxIn func(*wire.TxIn)
+	if receipt.LockTime != 0 {
+		updateTxIn = func(txIn *wire.TxIn) {
+			txIn.Sequence = wire.MaxTxInSequenceNum - 1
+		}
+	}
+	var f func(*txscript.ScriptBuilder)
+	if receipt.Unlock != nil {
+		f = func(builder *txscript.ScriptBuilder) {
+			builder.AddOps(receipt.Unlock)
+		}
+	}
+	if err := tx.sign(ctx, f, updateTxIn, nil); err != nil {
+		return TxReceipt{}, err
+	}
+	select {
+	case <-ctx.Done():
+		return TxReceipt{}, ctx.Err()
+	default:
+	}
+	if err := tx.submit(); err != nil {
+		return TxReceipt{}, err
+	}
+	account.Logger.Infof("replaced %s with %s", receipt.TxHash, tx.msgTx.TxHash().String())
+	return tx.receipt()
+}
+
+// BumpFee spends the account's outputs of the parent transaction to pull it
+// into a block.
+func (account *account) BumpFee(ctx context.Context, parentTxHash string, fee int64) (TxReceipt, error) {
+	me, err := account.Address()
+	if err != nil {
+		return TxReceipt{}, err
+	}
+	utxos, err := account.GetUTXOs(me.EncodeAddress(), 0, 0)
+	if err != nil {
+		return TxReceipt{}, err
+	}
+
+	tx, err := account.newTx(wire.NewMsgTx(4))
+	if err != nil {
+		return TxReceipt{}, err
+	}
+	var value int64
+	for _, utxo := range utxos {
+		if utxo.TxHash != parentTxHash {
+			continue
+		}
+		if err := tx.addInput(utxo); err != nil {
+			return TxReceipt{}, err
+		}
+		value += utxo.Amount
+	}
+	if len(tx.msgTx.TxIn) == 0 {
+		return TxReceipt{}, fmt.Errorf("transaction %s has no unspent outputs belonging to %s", parentTxHash, me.EncodeAddress())
+	}
+	if value-fee < ZCashDust {
+		return TxReceipt{}, NewErrInsufficientBalance(me.EncodeAddress(), fee+ZCashDust, value)
+	}
+
+	P2PKHScript, err := PayToAddrScript(me)
+	if err != nil {
+		return TxReceipt{}, err
+	}
+	tx.msgTx.AddTxOut(wire.NewTxOut(value-fee, P2PKHScript))
+	tx.changeIndex = 0
+
+	if err := tx.sign(ctx, nil, nil, nil); err != nil {
+		return TxReceipt{}, err
+	}
+	select {
+	case <-ctx.Done():
+		return TxReceipt{}, ctx.Err()
+	default:
+	}
+	if err := tx.submit(); err != nil {
+		return TxReceipt{}, err
+	}
+	account.Logger.Infof("bumped the fee of %s with %s", parentTxHash, tx.msgTx.TxHash().String())
+	return tx.receipt()
+}
+
+// PreviewTransfer returns a preview of the transaction that would be built by
+// Transfer.
+func (account *account) PreviewTransfer(ctx context.Context, to string, value int64, speed TxExecutionSpeed, sendAll bool) (TxPreview, error) {
+	tx, err := account.buildTransfer(ctx, to, value, speed, sendAll)
+	if err != nil {
+		return TxPreview{}, err
+	}
+	return tx.preview()
+}
+
+// BuildTransfer returns the unsigned transaction that would be built by
+// Transfer.
+func (account *account) BuildTransfer(ctx context.Context, to string, value int64, speed TxExecutionSpeed, sendAll bool) (Tx, error) {
+	tx, err := account.buildTransfer(ctx, to, value, speed, sendAll)
+	if err != nil {
+		return nil, err
+	}
+	return tx.unsigned()
+}
+
+func (account *account) buildTransfer(ctx context.Context, to string, value int64, speed TxExecutionSpeed, sendAll bool) (*tx, error) {
+	me, err := account.Address()
+	if err != nil {
+		return nil, err
+	}
+	address, err := DecodeAddress(to, account.NetworkParams())
+	if err != nil {
+		return nil, err
+	}
+	P2PKHScript, err := PayToAddrScript(address)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := account.newTx(wire.NewMsgTx(4))
+	if err != nil {
+		return nil, err
+	}
+	tx.msgTx.AddTxOut(wire.NewTxOut(value, P2PKHScript))
+	if _, err := account.fundAndPayFee(ctx, tx, me, speed, sendAll); err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
 // SendTransaction builds, signs, verifies and publishes a transaction to the
 // corresponding blockchain. If contract is provided then the transaction uses
 // the contract's unspent outputs for the transaction, otherwise uses the
 // account's unspent outputs to fund the transaction. preCond is executed in
 // the starting of the process, if it returns false SendTransaction returns
 // ErrPreConditionCheckFailed and stops the process. This function can be used
-// to modify how the unspent outputs are spent, this can be nil. f is supposed
-// to be used with non empty contracts, to modify the signature script. preCond
-// is executed in the starting of the process, if it returns false
-// SendTransaction returns ErrPreConditionCheckFailed and stops the process.
+// to add the outputs of the transaction. updateTxIn is used to modify how the
+// unspent outputs are spent, this can be nil. f is supposed to be used with
+// non empty contracts, to modify the signature script. postCond is polled
+// after the transaction is submitted until it returns true, if the context is
+// done before then SendTransaction returns ErrPostConditionCheckFailed.
 func (account *account) SendTransaction(
 	ctx context.Context,
 	contract []byte,
@@ -142,74 +611,193 @@ func (account *account) SendTransaction(
 	f func(*txscript.ScriptBuilder),
 	postCond func(*wire.MsgTx) bool,
 	sendAll bool,
-) (string, int64, error) {
+) (receipt TxReceipt, err error) {
+	ctx, span := startSpan(ctx, "zcash.SendTransaction")
+	defer func() {
+		if receipt.TxHash != "" {
+			span.SetAttribute(AttributeTxID, receipt.TxHash)
+		}
+		endSpan(span, err)
+	}()
+
 	// Current ZCash Transaction Version (Sapling: 4) .
-	tx := account.newTx(wire.NewMsgTx(4))
+	tx, err := account.newTx(wire.NewMsgTx(4))
+	if err != nil {
+		return TxReceipt{}, err
+	}
 	if preCond != nil && !preCond(tx.msgTx.MsgTx) {
-		return "", 0, ErrPreConditionCheckFailed
+		return TxReceipt{}, ErrPreConditionCheckFailed
 	}
 
 	var address btcutil.Address
-	var err error
 	if contract == nil {
 		address, err = account.Address()
 		if err != nil {
-			return "", 0, err
+			return TxReceipt{}, err
 		}
 	} else {
 		hash20 := [20]byte{}
 		copy(hash20[:], btcutil.Hash160(contract))
 		address, err = AddressFromHash160(hash20, account.NetworkParams(), true)
 		if err != nil {
-			return "", 0, err
+			return TxReceipt{}, err
 		}
 	}
 
-	account.Logger.Infof("funding %s, with fee %d SAT/byte", address.EncodeAddress(), speed)
-	if sendAll {
-		if err := tx.fundAll(address); err != nil {
-			return "", 0, err
-		}
-	} else {
-		if err := tx.fund(address); err != nil {
-			return "", 0, err
-		}
+	span.SetAttribute(AttributeAddress, address.EncodeAddress())
+	account.Logger.Infof("funding %s", address.EncodeAddress())
+	fee, err := account.fundAndPayFee(ctx, tx, address, speed, sendAll)
+	if err != nil {
+		return TxReceipt{}, err
 	}
 	account.Logger.Info("successfully funded the transaction")
-
-	txFee := MaxZCashFee
-	tx.msgTx.TxOut[len(tx.msgTx.TxOut)-1].Value -= txFee
+	account.Logger.Infof("paying a fee of %d ZAT", fee)
 
 	account.Logger.Info("signing the tx")
-	if err := tx.sign(f, updateTxIn, contract); err != nil {
-		return "", 0, err
+	if err := tx.sign(ctx, f, updateTxIn, contract); err != nil {
+		return TxReceipt{}, err
 	}
 	account.Logger.Info("successfully signined the tx")
 
+	account.Logger.Info("trying to submit the tx")
+	select {
+	case <-ctx.Done():
+		return TxReceipt{}, ctx.Err()
+	default:
+	}
+	if err := tx.broadcast(ctx); err != nil {
+		account.Logger.Infof("submitting failed due to %s", err)
+		return TxReceipt{}, err
+	}
+
+	ticker := time.NewTicker(5 * time.Second)
+	defer ticker.Stop()
 	for {
-		account.Logger.Info("trying to submit the tx")
+		if postCond == nil || postCond(tx.msgTx.MsgTx) {
+			account.Logger.Info("successfully submitted the tx")
+			receipt, err := tx.receipt()
+			if err != nil {
+				return TxReceipt{}, err
+			}
+			receipt.Confirmation = account.estimateConfirmation(ctx, tx)
+			return receipt, nil
+		}
 		select {
 		case <-ctx.Done():
 			account.Logger.Info("submitting failed due to failed post condition")
-			return "", 0, ErrPostConditionCheckFailed
-		default:
-			if err := tx.submit(); err != nil {
-				account.Logger.Infof("submitting failed due to %s", err)
-				return "", 0, err
-			}
-			for i := 0; i < 60; i++ {
-				if postCond == nil || postCond(tx.msgTx.MsgTx) {
-					account.Logger.Info("successfully submitted the tx")
-					return tx.msgTx.TxHash().String(), txFee, nil
-				}
-				time.Sleep(5 * time.Second)
-			}
+			return TxReceipt{}, ErrPostConditionCheckFailed
+		case <-ticker.C:
 		}
 	}
 }
 
+// estimateConfirmation estimates the time until the transaction is mined,
+// from the fee rate it pays. The estimate is left empty if the fee estimator
+// fails, as the transaction has already been broadcast.
+func (account *account) estimateConfirmation(ctx context.Context, tx *tx) ConfirmationEstimate {
+	preview, err := tx.preview()
+	if err != nil || preview.EstimatedSize == 0 {
+		return ConfirmationEstimate{}
+	}
+	estimate, err := EstimateConfirmationTime(ctx, account.FeeEstimator, preview.Fee/int64(preview.EstimatedSize))
+	if err != nil {
+		account.Logger.Infof("cannot estimate the confirmation time: %v", err)
+	}
+	return estimate
+}
+
+// payFee estimates the fee of the funded transaction at the given speed, and
+// deducts the part of it that is not already paid by the inputs from the
+// change output. The recipients are paid the exact value of their outputs,
+// unless the transaction spends every utxo, in which case the fee is deducted
+// from the last output as there is no change. The fee is at
+// least the minimum relay fee of the network, and transactions larger than the
+// maximum size of its relay rules are rejected, as they would not be relayed.
+func (account *account) payFee(ctx context.Context, tx *tx, speed TxExecutionSpeed) (int64, error) {
+	preview, err := tx.preview()
+	if err != nil {
+		return 0, err
+	}
+	rules := relayRulesOf(account.NetworkParams())
+	if rules.MaxTxSize > 0 && preview.EstimatedSize > rules.MaxTxSize {
+		return 0, NewErrTxTooLarge(preview.EstimatedSize, rules.MaxTxSize)
+	}
+	fee, err := estimateFee(ctx, account.FeeEstimator, speed, preview.EstimatedSize)
+	if err != nil {
+		return 0, err
+	}
+	// Transactions paying less than the minimum relay fee would never reach
+	// the miners, whatever the fee rate of the estimator.
+	if fee < rules.MinRelayFee {
+		fee = rules.MinRelayFee
+	}
+	limits := account.feeLimits.relayable(rules)
+	if preview.Fee >= fee {
+		return preview.Fee, limits.check(preview.Fee)
+	}
+	if err := limits.check(fee); err != nil {
+		return 0, err
+	}
+	index := tx.changeIndex
+	if index < 0 {
+		if !tx.sendAll {
+			return fee, errFeeExceedsOutput
+		}
+		index = len(tx.msgTx.TxOut) - 1
+	}
+	// The fee can exceed the fee reserved while funding the transaction, in
+	// which case the output it is deducted from may not cover it.
+	if value := tx.msgTx.TxOut[index].Value - (fee - preview.Fee); value < ZCashDust {
+		return fee, errFeeExceedsOutput
+	}
+	tx.msgTx.TxOut[index].Value -= fee - preview.Fee
+	return fee, nil
+}
+
+// maxFundingAttempts bounds the number of times the utxos of a transaction are
+// selected again to cover its fee.
+const maxFundingAttempts = 4
+
+// fundAndPayFee funds the transaction using the utxos of the address, and pays
+// the fee of the given speed. The fee depends on the number of inputs, so
+// unless every utxo is spent, the utxos are selected again, reserving the last
+// estimated fee, until the change covers the fee.
+func (account *account) fundAndPayFee(ctx context.Context, tx *tx, addr btcutil.Address, speed TxExecutionSpeed, sendAll bool) (int64, error) {
+	reserve := int64(0)
+	for attempt := 1; ; attempt++ {
+		if err := tx.fundFrom(ctx, addr, sendAll, reserve); err != nil {
+			return 0, err
+		}
+		fee, err := account.payFee(ctx, tx, speed)
+		if err != errFeeExceedsOutput || sendAll || fee <= reserve || attempt == maxFundingAttempts {
+			return fee, err
+		}
+		tx.unfund()
+		reserve = fee
+	}
+}
+
+// errFeeExceedsOutput is returned by payFee, along with the fee, when the
+// output the fee is deducted from would be left with less than the dust
+// threshold, or when there is no change output to deduct it from.
+var errFeeExceedsOutput = fmt.Errorf("insufficient balance to pay the fee")
+
 func (account *account) SerializedPublicKey() ([]byte, error) {
-	return account.SerializePublicKey(account.PrivKey.PubKey())
+	if account.PubKey == nil {
+		return nil, ErrNoPublicKey
+	}
+	return account.SerializePublicKey(account.PubKey)
+}
+
+// consensusBranchID returns the consensus branch id of the next block of the
+// chain. Signing fails if the branch cannot be selected, as a signature that
+// commits to the wrong branch is rejected by the network.
+func (account *account) consensusBranchID() (uint32, error) {
+	height, err := account.BlockHeight()
+	if err != nil {
+		return 0, fmt.Errorf("cannot get the block height to select the consensus branch: %v", err)
+	}
+	return ConsensusBranchID(account.NetworkParams(), height)
 }
 
 func (account *account) BTCClient() Client {
@@ -225,10 +813,11 @@ func SuggestedTxRate(txSpeed TxExecutionSpeed) (int64, error) {
 	}
 	request.Header.Set("Content-Type", "application/json")
 
-	res, err := (&http.Client{}).Do(request)
+	res, err := clients.HTTPClient().Do(request)
 	if err != nil {
 		return 0, fmt.Errorf("cannot connect to zcashfees.earn.com = %v", err)
 	}
+	defer res.Body.Close()
 	if res.StatusCode != http.StatusOK {
 		return 0, fmt.Errorf("unexpected status code %v from zcashfees.earn.com", res.StatusCode)
 	}
@@ -1,46 +1,183 @@
 package libzec
 
 import (
+	"bytes"
 	"crypto/sha256"
+	"fmt"
 
 	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
 	"github.com/btcsuite/btcutil"
 	"github.com/btcsuite/btcutil/base58"
 	"github.com/iqoption/zecutil"
 )
 
+// networkEncoding is how the addresses and keys of a network are encoded.
+type networkEncoding struct {
+	params *chaincfg.Params
+
+	// pubKeyHashPrefix and scriptHashPrefix are the version bytes of the
+	// transparent P2PKH ("t1", "tm") and P2SH ("t3", "t2") addresses.
+	pubKeyHashPrefix [2]byte
+	scriptHashPrefix [2]byte
+
+	// The human readable parts of the bech32 encodings.
+	texHRP        string
+	saplingHRP    string
+	saplingFVKHRP string
+	unifiedHRP    string
+}
+
+// networkEncodings are the encodings of the supported networks.
+var networkEncodings = map[Network]networkEncoding{
+	Mainnet: {
+		params:           &chaincfg.MainNetParams,
+		pubKeyHashPrefix: [2]byte{0x1C, 0xB8},
+		scriptHashPrefix: [2]byte{0x1C, 0xBD},
+		texHRP:           "tex",
+		saplingHRP:       "zs",
+		saplingFVKHRP:    "zxviews",
+		unifiedHRP:       "u",
+	},
+	Testnet: {
+		params:           &chaincfg.TestNet3Params,
+		pubKeyHashPrefix: [2]byte{0x1D, 0x25},
+		scriptHashPrefix: [2]byte{0x1C, 0xBA},
+		texHRP:           "textest",
+		saplingHRP:       "ztestsapling",
+		saplingFVKHRP:    "zxviewtestsapling",
+		unifiedHRP:       "utest",
+	},
+	Regtest: {
+		params:           &chaincfg.RegressionNetParams,
+		pubKeyHashPrefix: [2]byte{0x1D, 0x25},
+		scriptHashPrefix: [2]byte{0x1C, 0xBA},
+		texHRP:           "texregtest",
+		saplingHRP:       "zregtestsapling",
+		saplingFVKHRP:    "zxviewregtestsapling",
+		unifiedHRP:       "uregtest",
+	},
+}
+
+// encodingOf returns the encoding of the network, and an
+// UnsupportedNetworkError if the network is unknown.
+func encodingOf(params *chaincfg.Params) (networkEncoding, error) {
+	network, err := NetworkOf(params)
+	if err != nil {
+		return networkEncoding{}, err
+	}
+	return networkEncodings[network], nil
+}
+
+// AddressFromHash160 returns the P2PKH address of the public key hash, or the
+// P2SH address of the script hash. An UnsupportedNetworkError is returned for
+// networks other than mainnet, testnet3 and regtest.
 func AddressFromHash160(hash [20]byte, params *chaincfg.Params, isScript bool) (btcutil.Address, error) {
-	prefixes := map[string]map[string][]byte{
-		"mainnet": map[string][]byte{
-			"pubkey": []byte{0x1C, 0xB8},
-			"script": []byte{0x1C, 0xBD},
-		},
-		"testnet3": map[string][]byte{
-			"pubkey": []byte{0x1D, 0x25},
-			"script": []byte{0x1C, 0xBA},
-		},
-		"regtest": map[string][]byte{
-			"pubkey": []byte{0x1D, 0x25},
-			"script": []byte{0x1C, 0xBA},
-		},
+	encoding, err := encodingOf(params)
+	if err != nil {
+		return nil, err
 	}
+	prefix := encoding.pubKeyHashPrefix
 	if isScript {
-		return DecodeAddress(encodeHash(hash[:], prefixes[params.Name]["script"]), params)
+		prefix = encoding.scriptHashPrefix
+	}
+	return DecodeAddress(encodeHash(hash[:], prefix[:]), params)
+}
+
+// ScriptAddress returns the P2SH address of the given script.
+func ScriptAddress(script []byte, params *chaincfg.Params) (btcutil.Address, error) {
+	scriptHash := [20]byte{}
+	copy(scriptHash[:], btcutil.Hash160(script))
+	return AddressFromHash160(scriptHash, params, true)
+}
+
+// ExtractAddress returns the address paid by the given P2PKH or P2SH public key
+// script.
+func ExtractAddress(pkScript []byte, params *chaincfg.Params) (btcutil.Address, error) {
+	hash := [20]byte{}
+	switch {
+	case len(pkScript) == 25 && pkScript[0] == txscript.OP_DUP && pkScript[1] == txscript.OP_HASH160 &&
+		pkScript[2] == txscript.OP_DATA_20 && pkScript[23] == txscript.OP_EQUALVERIFY && pkScript[24] == txscript.OP_CHECKSIG:
+		copy(hash[:], pkScript[3:23])
+		return AddressFromHash160(hash, params, false)
+	case len(pkScript) == 23 && pkScript[0] == txscript.OP_HASH160 && pkScript[1] == txscript.OP_DATA_20 &&
+		pkScript[22] == txscript.OP_EQUAL:
+		copy(hash[:], pkScript[2:22])
+		return AddressFromHash160(hash, params, true)
+	default:
+		return nil, fmt.Errorf("unsupported public key script %x", pkScript)
 	}
-	return DecodeAddress(encodeHash(hash[:], prefixes[params.Name]["pubkey"]), params)
 }
 
+// DecodeAddress decodes a transparent address, or a ZIP-320 TEX address. The
+// transparent receiver of a unified address is returned, so that it can be
+// paid, and an UnsupportedReceiversError if it does not have one. A
+// WrongNetworkError is returned for a valid address of another network.
 func DecodeAddress(address string, params *chaincfg.Params) (btcutil.Address, error) {
+	if _, err := encodingOf(params); err != nil {
+		return nil, err
+	}
+	addr, err := decodeAddress(address, params)
+	if err != nil {
+		if info, inspectErr := InspectAddress(address); inspectErr == nil && !info.isForNet(params) {
+			return nil, NewErrWrongNetwork(address, info.Network, params.Name)
+		}
+		return nil, err
+	}
+	return addr, nil
+}
+
+func decodeAddress(address string, params *chaincfg.Params) (btcutil.Address, error) {
+	if isTEXAddress(address) {
+		return DecodeTEXAddress(address, params)
+	}
+	if isUnifiedAddress(address) {
+		unified, err := DecodeUnifiedAddress(address, params)
+		if err != nil {
+			return nil, err
+		}
+		return unified.TransparentAddress()
+	}
 	return zecutil.DecodeAddress(address, params.Name)
 }
 
 func PayToAddrScript(address btcutil.Address) ([]byte, error) {
+	if tex, ok := address.(*TEXAddress); ok {
+		return tex.PayToAddrScript()
+	}
 	return zecutil.PayToAddrScript(address)
 }
 
+// PayToScriptHashScript returns the P2SH public key script locking an output
+// to the redeem script.
+func PayToScriptHashScript(redeemScript []byte) ([]byte, error) {
+	return txscript.NewScriptBuilder().
+		AddOp(txscript.OP_HASH160).
+		AddData(btcutil.Hash160(redeemScript)).
+		AddOp(txscript.OP_EQUAL).
+		Script()
+}
+
+// decodeHash decodes a base58 transparent address, and returns its version
+// bytes and its hash.
+func decodeHash(address string) ([2]byte, [20]byte, error) {
+	var prefix [2]byte
+	var hash [20]byte
+	decoded := base58.Decode(address)
+	if len(decoded) != 26 {
+		return prefix, hash, fmt.Errorf("invalid transparent address length: got: %d required: %d", len(decoded), 26)
+	}
+	if cksum := addrChecksum(decoded[:22]); !bytes.Equal(cksum[:], decoded[22:]) {
+		return prefix, hash, fmt.Errorf("invalid transparent address checksum")
+	}
+	copy(prefix[:], decoded[:2])
+	copy(hash[:], decoded[2:22])
+	return prefix, hash, nil
+}
+
 func encodeHash(addrHash, prefix []byte) string {
 	var (
-		body  = append(prefix, addrHash...)
+		body  = append(append([]byte{}, prefix...), addrHash...)
 		chk   = addrChecksum(body)
 		cksum [4]byte
 	)
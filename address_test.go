@@ -0,0 +1,101 @@
+package libzec_test
+
+import (
+	"encoding/hex"
+	"errors"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/renproject/libzec-go"
+)
+
+var _ = Describe("Transparent addresses", func() {
+	It("should use the version bytes of the network", func() {
+		prefixes := []struct {
+			params   *chaincfg.Params
+			isScript bool
+			prefix   string
+		}{
+			{&chaincfg.MainNetParams, false, "t1"},
+			{&chaincfg.MainNetParams, true, "t3"},
+			{&chaincfg.TestNet3Params, false, "tm"},
+			{&chaincfg.TestNet3Params, true, "t2"},
+			{&chaincfg.RegressionNetParams, false, "tm"},
+			{&chaincfg.RegressionNetParams, true, "t2"},
+		}
+		for _, p := range prefixes {
+			addr, err := AddressFromHash160([20]byte{1, 2, 3}, p.params, p.isScript)
+			Expect(err).Should(BeNil())
+			Expect(addr.EncodeAddress()[:2]).Should(Equal(p.prefix))
+		}
+	})
+
+	It("should return an error for unsupported networks", func() {
+		_, err := AddressFromHash160([20]byte{}, &chaincfg.SimNetParams, false)
+		Expect(errors.Is(err, ErrUnsupportedNetwork)).Should(BeTrue())
+
+		_, err = DecodeAddress("t1VmmGiyjVNeCjxDZzg7vZmd99WyzVby9yC", &chaincfg.SimNetParams)
+		Expect(errors.Is(err, ErrUnsupportedNetwork)).Should(BeTrue())
+	})
+
+	It("should inspect addresses of every type", func() {
+		infos := []struct {
+			address string
+			network string
+			typ     AddressType
+			hash    string
+		}{
+			{"t1VmmGiyjVNeCjxDZzg7vZmd99WyzVby9yC", "mainnet", AddressP2PKH, "8286bf790866805397e3a947640b77a43f0b43a5"},
+			{"tex1s2rt77ggv6q989lr49rkgzmh5slsksa9khdgte", "mainnet", AddressTEX, "8286bf790866805397e3a947640b77a43f0b43a5"},
+			{"zs1mnnha08vpgn2l45e338j5fzrq3h58gyfl420vsd2lkz8mec2ggzrunx5pquuw3rsvv49kk08dj0", "mainnet", AddressSapling, ""},
+		}
+		for _, exp := range infos {
+			info, err := InspectAddress(exp.address)
+			Expect(err).Should(BeNil())
+			Expect(info.Network).Should(Equal(exp.network))
+			Expect(info.Type).Should(Equal(exp.typ))
+			Expect(hex.EncodeToString(info.Hash160)).Should(Equal(exp.hash))
+			Expect(info.IsTransparent()).Should(Equal(exp.hash != ""))
+		}
+	})
+
+	It("should inspect addresses derived from a hash", func() {
+		for _, params := range []*chaincfg.Params{&chaincfg.MainNetParams, &chaincfg.TestNet3Params} {
+			addr, err := AddressFromHash160([20]byte{1, 2, 3}, params, true)
+			Expect(err).Should(BeNil())
+			info, err := InspectAddress(addr.EncodeAddress())
+			Expect(err).Should(BeNil())
+			Expect(info.Network).Should(Equal(params.Name))
+			Expect(info.Type).Should(Equal(AddressP2SH))
+			Expect(info.Hash160[:3]).Should(Equal([]byte{1, 2, 3}))
+		}
+	})
+
+	It("should detect addresses of the wrong network", func() {
+		for _, address := range []string{
+			"t1VmmGiyjVNeCjxDZzg7vZmd99WyzVby9yC",
+			"tex1s2rt77ggv6q989lr49rkgzmh5slsksa9khdgte",
+		} {
+			_, err := DecodeAddress(address, &chaincfg.TestNet3Params)
+			Expect(errors.Is(err, ErrWrongNetwork)).Should(BeTrue())
+			wrongNetwork := &WrongNetworkError{}
+			Expect(errors.As(err, &wrongNetwork)).Should(BeTrue())
+			Expect(wrongNetwork.Network).Should(Equal("mainnet"))
+			Expect(wrongNetwork.Expected).Should(Equal("testnet3"))
+		}
+	})
+
+	It("should normalize addresses", func() {
+		normalized, err := NormalizeAddress(" TEX1S2RT77GGV6Q989LR49RKGZMH5SLSKSA9KHDGTE\n", &chaincfg.MainNetParams)
+		Expect(err).Should(BeNil())
+		Expect(normalized).Should(Equal("tex1s2rt77ggv6q989lr49rkgzmh5slsksa9khdgte"))
+
+		normalized, err = NormalizeAddress("t1VmmGiyjVNeCjxDZzg7vZmd99WyzVby9yC ", &chaincfg.MainNetParams)
+		Expect(err).Should(BeNil())
+		Expect(normalized).Should(Equal("t1VmmGiyjVNeCjxDZzg7vZmd99WyzVby9yC"))
+
+		_, err = NormalizeAddress("t1VmmGiyjVNeCjxDZzg7vZmd99WyzVby9yD", &chaincfg.MainNetParams)
+		Expect(err).ShouldNot(BeNil())
+	})
+})
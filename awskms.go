@@ -0,0 +1,184 @@
+package libzec
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/renproject/libzec-go/clients"
+)
+
+// AWSCredentials are the credentials of an AWS identity that is allowed to
+// call kms:Sign and kms:GetPublicKey on the key. The session token is only set
+// for temporary credentials.
+type AWSCredentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+}
+
+type awsKMSKey struct {
+	client      *http.Client
+	endpoint    string
+	region      string
+	keyID       string
+	credentials AWSCredentials
+	now         func() time.Time
+}
+
+// NewAWSKMSKey returns the ECC_SECG_P256K1 key of AWS KMS with the given key
+// id, ARN or alias, in the given region. Requests are signed with the
+// credentials using Signature Version 4, and sent using the http client, or
+// the http client shared by all clients if it is nil.
+func NewAWSKMSKey(client *http.Client, region, keyID string, credentials AWSCredentials) KMSKey {
+	return NewAWSKMSKeyWithEndpoint(client, fmt.Sprintf("https://kms.%s.amazonaws.com", region), region, keyID, credentials)
+}
+
+// NewAWSKMSKeyWithEndpoint is the same as NewAWSKMSKey, but sends the requests
+// to the given endpoint, such as a VPC endpoint of AWS KMS.
+func NewAWSKMSKeyWithEndpoint(client *http.Client, endpoint, region, keyID string, credentials AWSCredentials) KMSKey {
+	if client == nil {
+		client = clients.HTTPClient()
+	}
+	return &awsKMSKey{client, strings.TrimSuffix(endpoint, "/"), region, keyID, credentials, time.Now}
+}
+
+func (key *awsKMSKey) Sign(ctx context.Context, digest []byte) ([]byte, error) {
+	res := struct {
+		Signature []byte `json:"Signature"`
+	}{}
+	if err := key.call(ctx, "Sign", map[string]interface{}{
+		"KeyId":            key.keyID,
+		"Message":          digest,
+		"MessageType":      "DIGEST",
+		"SigningAlgorithm": "ECDSA_SHA_256",
+	}, &res); err != nil {
+		return nil, err
+	}
+	return res.Signature, nil
+}
+
+func (key *awsKMSKey) PublicKey(ctx context.Context) ([]byte, error) {
+	res := struct {
+		PublicKey []byte `json:"PublicKey"`
+		KeySpec   string `json:"KeySpec"`
+	}{}
+	if err := key.call(ctx, "GetPublicKey", map[string]interface{}{"KeyId": key.keyID}, &res); err != nil {
+		return nil, err
+	}
+	if res.KeySpec != "" && res.KeySpec != "ECC_SECG_P256K1" {
+		return nil, fmt.Errorf("unsupported aws kms key spec %s", res.KeySpec)
+	}
+	return res.PublicKey, nil
+}
+
+// call calls the action of the AWS KMS JSON API, and decodes the response
+// into res.
+func (key *awsKMSKey) call(ctx context.Context, action string, params, res interface{}) error {
+	body, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, key.endpoint+"/", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "TrentService."+action)
+	signAWSRequest(req, body, key.region, "kms", key.credentials, key.now())
+
+	resp, err := key.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("cannot call aws kms %s: %v", action, err)
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		failure := struct {
+			Type    string `json:"__type"`
+			Message string `json:"message"`
+		}{}
+		json.Unmarshal(data, &failure)
+		return fmt.Errorf("aws kms %s failed with status %d: %s %s", action, resp.StatusCode, failure.Type, failure.Message)
+	}
+	return json.Unmarshal(data, res)
+}
+
+// signAWSRequest signs the request using Signature Version 4. The request must
+// not have a query, and every header that is set before it is signed is
+// signed.
+func signAWSRequest(req *http.Request, body []byte, region, service string, credentials AWSCredentials, now time.Time) {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+	req.Header.Set("X-Amz-Date", amzDate)
+	if credentials.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", credentials.SessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	canonicalHeaders := ""
+	for _, name := range names {
+		canonicalHeaders += name + ":" + headers[name] + "\n"
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	payloadHash := sha256.Sum256(body)
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		path,
+		req.URL.Query().Encode(),
+		canonicalHeaders,
+		signedHeaders,
+		hex.EncodeToString(payloadHash[:]),
+	}, "\n")
+
+	scope := strings.Join([]string{date, region, service, "aws4_request"}, "/")
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		hex.EncodeToString(requestHash[:]),
+	}, "\n")
+
+	signingKey := []byte("AWS4" + credentials.SecretAccessKey)
+	for _, part := range []string{date, region, service, "aws4_request"} {
+		signingKey = hmacSHA256(signingKey, []byte(part))
+	}
+	signature := hex.EncodeToString(hmacSHA256(signingKey, []byte(stringToSign)))
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		credentials.AccessKeyID, scope, signedHeaders, signature,
+	))
+}
+
+func hmacSHA256(key, data []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(data)
+	return mac.Sum(nil)
+}
@@ -0,0 +1,39 @@
+package libzec
+
+import (
+	"errors"
+
+	"github.com/renproject/libzec-go/clients"
+)
+
+// BatchConfirmations returns the confirmations of the transactions, omitting
+// those that cannot be found. Clients implementing clients.BatchClient look
+// them up in batches, other clients look them up one by one.
+func BatchConfirmations(c Client, txHashes []string) (map[string]int64, error) {
+	if batcher, ok := batchClient(c); ok {
+		return batcher.BatchConfirmations(txHashes)
+	}
+	confs := map[string]int64{}
+	for _, txHash := range txHashes {
+		conf, err := c.Confirmations(txHash)
+		if errors.Is(err, ErrTxNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		confs[txHash] = conf
+	}
+	return confs, nil
+}
+
+// batchClient returns the batch client of the client, if one of its cores
+// implements it.
+func batchClient(c Client) (clients.BatchClient, bool) {
+	for _, core := range clientCores(c) {
+		if batcher, ok := core.(clients.BatchClient); ok {
+			return batcher, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,133 @@
+package libzec
+
+import (
+	"fmt"
+	"strings"
+)
+
+// bech32Variant is the checksum constant of the bech32 encoding (BIP-173),
+// or of the bech32m encoding (BIP-350).
+type bech32Variant uint32
+
+const (
+	bech32Classic bech32Variant = 1
+	bech32m       bech32Variant = 0x2bc830a3
+)
+
+const bech32Charset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+
+// bech32MaxLength is the maximum length of the encodings used by Zcash. It
+// is larger than the 90 characters of BIP-173, as Sapling addresses are 78
+// characters long and unified addresses are even longer.
+const bech32MaxLength = 1023
+
+func bech32Polymod(values []byte) uint32 {
+	generator := [5]uint32{0x3b6a57b2, 0x26508e6d, 0x1ea119fa, 0x3d4233dd, 0x2a1462b3}
+	chk := uint32(1)
+	for _, value := range values {
+		top := chk >> 25
+		chk = (chk&0x1ffffff)<<5 ^ uint32(value)
+		for i := 0; i < 5; i++ {
+			if (top>>uint(i))&1 == 1 {
+				chk ^= generator[i]
+			}
+		}
+	}
+	return chk
+}
+
+func bech32HRPExpand(hrp string) []byte {
+	expanded := make([]byte, 0, 2*len(hrp)+1)
+	for i := 0; i < len(hrp); i++ {
+		expanded = append(expanded, hrp[i]>>5)
+	}
+	expanded = append(expanded, 0)
+	for i := 0; i < len(hrp); i++ {
+		expanded = append(expanded, hrp[i]&31)
+	}
+	return expanded
+}
+
+// bech32Encode encodes the data, given as 8-bit bytes, with the human readable
+// part.
+func bech32Encode(hrp string, data []byte, variant bech32Variant) (string, error) {
+	values, err := convertBits(data, 8, 5, true)
+	if err != nil {
+		return "", err
+	}
+	polymod := bech32Polymod(append(append(bech32HRPExpand(hrp), values...), 0, 0, 0, 0, 0, 0)) ^ uint32(variant)
+	for i := 0; i < 6; i++ {
+		values = append(values, byte(polymod>>uint(5*(5-i)))&31)
+	}
+
+	encoded := strings.Builder{}
+	encoded.WriteString(hrp)
+	encoded.WriteByte('1')
+	for _, value := range values {
+		encoded.WriteByte(bech32Charset[value])
+	}
+	return encoded.String(), nil
+}
+
+// bech32Decode decodes the string, and returns its human readable part and its
+// data as 8-bit bytes.
+func bech32Decode(encoded string, variant bech32Variant) (string, []byte, error) {
+	if len(encoded) > bech32MaxLength {
+		return "", nil, fmt.Errorf("invalid bech32 string length %d", len(encoded))
+	}
+	if strings.ToLower(encoded) != encoded && strings.ToUpper(encoded) != encoded {
+		return "", nil, fmt.Errorf("invalid bech32 string: mixed case")
+	}
+	encoded = strings.ToLower(encoded)
+
+	sep := strings.LastIndexByte(encoded, '1')
+	if sep < 1 || sep+7 > len(encoded) {
+		return "", nil, fmt.Errorf("invalid bech32 separator index %d", sep)
+	}
+	hrp := encoded[:sep]
+	values := make([]byte, 0, len(encoded)-sep-1)
+	for i := sep + 1; i < len(encoded); i++ {
+		value := strings.IndexByte(bech32Charset, encoded[i])
+		if value < 0 {
+			return "", nil, fmt.Errorf("invalid bech32 character %q", encoded[i])
+		}
+		values = append(values, byte(value))
+	}
+	if bech32Polymod(append(bech32HRPExpand(hrp), values...)) != uint32(variant) {
+		return "", nil, fmt.Errorf("invalid bech32 checksum")
+	}
+
+	data, err := convertBits(values[:len(values)-6], 5, 8, false)
+	if err != nil {
+		return "", nil, err
+	}
+	return hrp, data, nil
+}
+
+// convertBits regroups the bits of the data from groups of fromBits to groups
+// of toBits.
+func convertBits(data []byte, fromBits, toBits uint, pad bool) ([]byte, error) {
+	var acc uint32
+	var bits uint
+	maxValue := uint32(1)<<toBits - 1
+	converted := make([]byte, 0, len(data)*int(fromBits)/int(toBits)+1)
+	for _, value := range data {
+		if uint32(value)>>fromBits != 0 {
+			return nil, fmt.Errorf("invalid data value %d", value)
+		}
+		acc = acc<<fromBits | uint32(value)
+		bits += fromBits
+		for bits >= toBits {
+			bits -= toBits
+			converted = append(converted, byte(acc>>bits&maxValue))
+		}
+	}
+	if pad {
+		if bits > 0 {
+			converted = append(converted, byte(acc<<(toBits-bits)&maxValue))
+		}
+	} else if bits >= fromBits || acc<<(toBits-bits)&maxValue != 0 {
+		return nil, fmt.Errorf("invalid padding")
+	}
+	return converted, nil
+}
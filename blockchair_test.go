@@ -0,0 +1,161 @@
+package libzec_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/renproject/libzec-go"
+	"github.com/renproject/libzec-go/clients"
+)
+
+// redirectTransport sends every request to the test server, whatever the
+// host of its url.
+type redirectTransport struct {
+	server *url.URL
+}
+
+func (transport redirectTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req.URL.Scheme = transport.server.Scheme
+	req.URL.Host = transport.server.Host
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+var _ = Describe("Blockchair client cores", func() {
+	address := "t1Hsc1LR8yKnbbe3twRp88p6vFfC5t7DLbs"
+	script := "76a914000000000000000000000000000000000000000088ac"
+	mempoolTx := chainhash.Hash{1}.String()
+	youngTx := chainhash.Hash{2}.String()
+	oldTx := chainhash.Hash{3}.String()
+
+	type recorder struct {
+		mu       *sync.Mutex
+		requests []*url.URL
+	}
+
+	newCore := func(handler func(w http.ResponseWriter, r *http.Request)) (clients.ClientCore, *recorder, func()) {
+		rec := &recorder{mu: new(sync.Mutex)}
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rec.mu.Lock()
+			rec.requests = append(rec.requests, r.URL)
+			rec.mu.Unlock()
+			handler(w, r)
+		}))
+		serverURL, err := url.Parse(server.URL)
+		Expect(err).Should(BeNil())
+		core, err := clients.NewBlockchairClientCore("mainnet", "KEY", clients.WithRoundTripper(redirectTransport{serverURL}))
+		Expect(err).Should(BeNil())
+		return core, rec, server.Close
+	}
+
+	respond := func(w http.ResponseWriter, state int64, data interface{}) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"data":    data,
+			"context": map[string]interface{}{"code": 200, "state": state},
+		})
+	}
+
+	It("should return the utxos of an address with their heights", func() {
+		core, rec, closeServer := newCore(func(w http.ResponseWriter, r *http.Request) {
+			Expect(r.URL.Query().Get("key")).Should(Equal("KEY"))
+			switch {
+			case r.URL.Path == "/zcash/dashboards/address/"+address:
+				respond(w, 1000, map[string]interface{}{
+					address: map[string]interface{}{
+						"address":      map[string]interface{}{"script_hex": script},
+						"transactions": []string{},
+						"utxo": []map[string]interface{}{
+							{"block_id": -1, "transaction_hash": mempoolTx, "index": 0, "value": 1000},
+							{"block_id": 990, "transaction_hash": youngTx, "index": 0, "value": 2000},
+							{"block_id": 800, "transaction_hash": oldTx, "index": 1, "value": 3000},
+						},
+					},
+				})
+			case r.URL.Path == "/zcash/dashboards/transactions/"+youngTx:
+				respond(w, 1000, map[string]interface{}{
+					youngTx: map[string]interface{}{
+						"transaction": map[string]interface{}{"block_id": 990, "hash": youngTx, "is_coinbase": true},
+					},
+				})
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		})
+		defer closeServer()
+
+		utxos, err := core.GetUTXOs(address, 0, 0)
+		Expect(err).Should(BeNil())
+		Expect(utxos).Should(ConsistOf(
+			clients.UTXO{TxHash: mempoolTx, Amount: 1000, ScriptPubKey: script, Vout: 0, Address: address},
+			clients.UTXO{TxHash: youngTx, Amount: 2000, ScriptPubKey: script, Vout: 0, Confirmations: 11, BlockHeight: 990, Address: address, Coinbase: true},
+			clients.UTXO{TxHash: oldTx, Amount: 3000, ScriptPubKey: script, Vout: 1, Confirmations: 201, BlockHeight: 800, Address: address},
+		))
+
+		// The utxo pages do not fetch transactions, and only the transaction
+		// of the mined utxo that has not matured is fetched.
+		Expect(rec.requests).Should(HaveLen(2))
+		Expect(rec.requests[0].Query().Get("limit")).Should(Equal("0,100"))
+		Expect(rec.requests[1].Path).Should(Equal("/zcash/dashboards/transactions/" + youngTx))
+
+		utxos, err = core.GetUTXOs(address, 0, 12)
+		Expect(err).Should(BeNil())
+		Expect(utxos).Should(HaveLen(1))
+		Expect(utxos[0].TxHash).Should(Equal(oldTx))
+	})
+
+	It("should not find transactions that blockchair does not know", func() {
+		core, _, closeServer := newCore(func(w http.ResponseWriter, r *http.Request) {
+			respond(w, 1000, []interface{}{})
+		})
+		defer closeServer()
+
+		_, err := core.Confirmations(youngTx)
+		Expect(err).Should(Equal(ErrTxNotFound))
+	})
+
+	It("should stop sending requests while it is rate limited", func() {
+		core, rec, closeServer := newCore(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Retry-After", "60")
+			w.WriteHeader(http.StatusTooManyRequests)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"data":    nil,
+				"context": map[string]interface{}{"code": 429, "error": "Too many requests"},
+			})
+		})
+		defer closeServer()
+
+		_, err := core.BlockHeight()
+		unavailable, ok := err.(*BackendUnavailableError)
+		Expect(ok).Should(BeTrue())
+		Expect(unavailable.RetryAt).Should(BeTemporally("~", time.Now().Add(time.Minute), 5*time.Second))
+		Expect(unavailable.Err.Error()).Should(ContainSubstring("Too many requests"))
+
+		_, err = core.GetUTXOs(address, 0, 0)
+		unavailable, ok = err.(*BackendUnavailableError)
+		Expect(ok).Should(BeTrue())
+		Expect(unavailable.RetryAt).Should(BeTemporally("~", time.Now().Add(time.Minute), 5*time.Second))
+		Expect(rec.requests).Should(HaveLen(1))
+	})
+
+	It("should return the errors of blockchair", func() {
+		core, _, closeServer := newCore(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"data":    nil,
+				"context": map[string]interface{}{"code": 400, "error": "Invalid transaction"},
+			})
+		})
+		defer closeServer()
+
+		err := core.PublishTransaction([]byte{1, 2, 3})
+		Expect(err).ShouldNot(BeNil())
+		Expect(strings.Contains(err.Error(), "Invalid transaction")).Should(BeTrue())
+	})
+})
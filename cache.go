@@ -0,0 +1,290 @@
+package libzec
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/iqoption/zecutil"
+	"github.com/renproject/libzec-go/clients"
+)
+
+// utxoCache caches the unconfirmed utxos of the addresses used by an account.
+// It is updated optimistically when a transaction is broadcast, by removing
+// the spent utxos and adding the outputs that pay to cached addresses, and is
+// refreshed from the client in the background.
+type utxoCache struct {
+	mu      *sync.Mutex
+	scripts map[string]string
+	utxos   map[string][]clients.UTXO
+
+	// spent and created are the outpoints spent and created by the
+	// transactions broadcast by the account, with the expiry height of these
+	// transactions. They are applied to the utxos returned by the client until
+	// the client sees the transactions, or until they expire.
+	spent   map[string]uint32
+	created map[string]createdUTXO
+}
+
+// createdUTXO is an output of a transaction broadcast by the account.
+type createdUTXO struct {
+	utxo         clients.UTXO
+	expiryHeight uint32
+}
+
+func newUTXOCache() *utxoCache {
+	return &utxoCache{
+		mu:      new(sync.Mutex),
+		scripts: map[string]string{},
+		utxos:   map[string][]clients.UTXO{},
+		spent:   map[string]uint32{},
+		created: map[string]createdUTXO{},
+	}
+}
+
+func (cache *utxoCache) get(address string) ([]clients.UTXO, bool) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	utxos, ok := cache.utxos[address]
+	return append([]clients.UTXO{}, utxos...), ok
+}
+
+// set replaces the utxos of the address, adding the outputs of the broadcast
+// transactions that the client has not seen yet and ignoring the utxos that
+// they spend.
+func (cache *utxoCache) set(address string, scriptPubKey []byte, utxos []clients.UTXO) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	cache.scripts[hex.EncodeToString(scriptPubKey)] = address
+	known := map[string]bool{}
+	unspent := []clients.UTXO{}
+	for _, utxo := range utxos {
+		key := outPointKey(utxo.TxHash, utxo.Vout)
+		known[key] = true
+		if _, ok := cache.spent[key]; !ok {
+			unspent = append(unspent, utxo)
+		}
+	}
+	for key, created := range cache.created {
+		if _, ok := cache.spent[key]; ok || known[key] || created.utxo.Address != address {
+			continue
+		}
+		unspent = append(unspent, created.utxo)
+	}
+	cache.utxos[address] = unspent
+}
+
+// update removes the utxos spent by the transaction, and adds its outputs
+// that pay to cached addresses. The transaction hash is the txid of the
+// transaction.
+func (cache *utxoCache) update(msgTx *zecutil.MsgTx, txHash string) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	for _, txIn := range msgTx.TxIn {
+		key := outPointKey(txIn.PreviousOutPoint.Hash.String(), txIn.PreviousOutPoint.Index)
+		cache.spent[key] = msgTx.ExpiryHeight
+		for address, utxos := range cache.utxos {
+			for i, utxo := range utxos {
+				if outPointKey(utxo.TxHash, utxo.Vout) == key {
+					cache.utxos[address] = append(utxos[:i], utxos[i+1:]...)
+					break
+				}
+			}
+		}
+	}
+	for i, txOut := range msgTx.TxOut {
+		scriptPubKey := hex.EncodeToString(txOut.PkScript)
+		if address, ok := cache.scripts[scriptPubKey]; ok {
+			utxo := clients.UTXO{
+				TxHash:       txHash,
+				Amount:       txOut.Value,
+				ScriptPubKey: scriptPubKey,
+				Vout:         uint32(i),
+				Address:      address,
+			}
+			cache.created[outPointKey(txHash, uint32(i))] = createdUTXO{utxo, msgTx.ExpiryHeight}
+			cache.utxos[address] = append(cache.utxos[address], utxo)
+		}
+	}
+}
+
+// refresh fetches the utxos of every cached address from the client. Spent
+// utxos are forgotten once the client no longer returns them, and created
+// utxos once the client returns them. Both are forgotten once the transaction
+// that spends or creates them has expired.
+func (cache *utxoCache) refresh(client Client) error {
+	cache.mu.Lock()
+	scripts := map[string]string{}
+	for scriptPubKey, address := range cache.scripts {
+		scripts[address] = scriptPubKey
+	}
+	cache.mu.Unlock()
+
+	fetched := map[string][]clients.UTXO{}
+	seen := map[string]bool{}
+	for address := range scripts {
+		utxos, err := client.GetUTXOs(address, 0, 0)
+		if err != nil {
+			return err
+		}
+		for _, utxo := range utxos {
+			seen[outPointKey(utxo.TxHash, utxo.Vout)] = true
+		}
+		fetched[address] = utxos
+	}
+	// Nothing expires if the height of the chain is unknown.
+	height, err := client.BlockHeight()
+	if err != nil {
+		height = 0
+	}
+
+	cache.mu.Lock()
+	for key, expiryHeight := range cache.spent {
+		if !seen[key] || expired(expiryHeight, height) {
+			delete(cache.spent, key)
+		}
+	}
+	for key, created := range cache.created {
+		if seen[key] || expired(created.expiryHeight, height) {
+			delete(cache.created, key)
+		}
+	}
+	cache.mu.Unlock()
+
+	for address, utxos := range fetched {
+		script, err := hex.DecodeString(scripts[address])
+		if err != nil {
+			return err
+		}
+		cache.set(address, script, utxos)
+	}
+	return nil
+}
+
+func outPointKey(txHash string, vout uint32) string {
+	return fmt.Sprintf("%s:%d", txHash, vout)
+}
+
+// EnableUTXOCache caches the unconfirmed utxos of the addresses used by the
+// account, and refreshes them at the given interval until the context is done.
+func (account *account) EnableUTXOCache(ctx context.Context, refreshInterval time.Duration) {
+	cache := newUTXOCache()
+	account.mu.Lock()
+	account.cache = cache
+	account.mu.Unlock()
+	go func() {
+		ticker := time.NewTicker(refreshInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := cache.refresh(account.Client); err != nil {
+					account.Logger.Infof("cannot refresh the utxo cache: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+// GetUTXOs returns the utxos of the address, from the cache if it is enabled
+// and unconfirmed utxos are requested. If the account has a tx store, the
+// unconfirmed utxos also account for its pending transactions.
+func (account *account) GetUTXOs(address string, limit, confirmations int64) ([]clients.UTXO, error) {
+	if err := clients.CheckUTXOQuery(limit, confirmations); err != nil {
+		return nil, err
+	}
+	cache, store := account.utxoCache(), account.store()
+	if confirmations > 0 || (cache == nil && store == nil) {
+		return account.Client.GetUTXOs(address, limit, confirmations)
+	}
+	utxos, err := account.cachedUTXOs(cache, address)
+	if err != nil {
+		return nil, err
+	}
+	if store != nil {
+		if utxos, err = account.withPendingTxs(store, address, utxos); err != nil {
+			return nil, err
+		}
+	}
+	return clients.LimitUTXOs(utxos, limit), nil
+}
+
+// cachedUTXOs returns every unconfirmed utxo of the address, from the cache if
+// it is enabled.
+func (account *account) cachedUTXOs(cache *utxoCache, address string) ([]clients.UTXO, error) {
+	if cache == nil {
+		return account.Client.GetUTXOs(address, 0, 0)
+	}
+	utxos, ok := cache.get(address)
+	if !ok {
+		addr, err := DecodeAddress(address, account.NetworkParams())
+		if err != nil {
+			return nil, err
+		}
+		scriptPubKey, err := PayToAddrScript(addr)
+		if err != nil {
+			return nil, err
+		}
+		if utxos, err = account.Client.GetUTXOs(address, 0, 0); err != nil {
+			return nil, err
+		}
+		cache.set(address, scriptPubKey, utxos)
+		utxos, _ = cache.get(address)
+	}
+	return utxos, nil
+}
+
+// Balance returns the balance of the address, from the cache if it is enabled
+// and unconfirmed utxos are requested.
+func (account *account) Balance(address string, confirmations int64) (int64, error) {
+	if account.utxoCache() == nil || confirmations > 0 {
+		return account.Client.Balance(address, confirmations)
+	}
+	utxos, err := account.GetUTXOs(address, 0, confirmations)
+	if err != nil {
+		return 0, err
+	}
+	var balance int64
+	for _, utxo := range utxos {
+		balance += utxo.Amount
+	}
+	return balance, nil
+}
+
+// publish publishes the transaction, updates the utxo cache if it is enabled,
+// and records the transaction in the tx store if there is one. A transaction
+// that cannot be recorded is still reported as published, as it is.
+func (account *account) publish(msgTx *zecutil.MsgTx, stx []byte) error {
+	if err := account.PublishTransaction(stx); err != nil {
+		return err
+	}
+	cache, store := account.utxoCache(), account.store()
+	if cache == nil && store == nil {
+		return nil
+	}
+	txID, err := TxID(stx)
+	if err != nil {
+		return err
+	}
+	txHash := hex.EncodeToString(txID)
+	if cache != nil {
+		cache.update(msgTx, txHash)
+	}
+	if store != nil {
+		if err := account.recordTx(store, msgTx.MsgTx, msgTx.ExpiryHeight, stx, txHash); err != nil {
+			account.Logger.Errorf("cannot record transaction %s: %v", txHash, err)
+		}
+	}
+	return nil
+}
+
+// utxoCache returns the utxo cache of the account, or nil if it is disabled.
+func (account *account) utxoCache() *utxoCache {
+	account.mu.Lock()
+	defer account.mu.Unlock()
+	return account.cache
+}
@@ -0,0 +1,48 @@
+package libzec_test
+
+import (
+	"context"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/renproject/libzec-go"
+	"github.com/renproject/libzec-go/clients"
+)
+
+var _ = Describe("UTXO cache", func() {
+	It("should keep the change of a broadcast transaction until it expires", func() {
+		core := newMockClientCore(&chaincfg.TestNet3Params, 1842420)
+		account, _, addr := newMockAccount(core, 1000000)
+		_, _, to := newMockAccount(core, 0)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		account.EnableUTXOCache(ctx, 10*time.Millisecond)
+
+		receipt, err := account.Transfer(ctx, to.EncodeAddress(), 100000, Standard, false)
+		Expect(err).Should(BeNil())
+		change := receipt.Outputs[receipt.ChangeIndex].Value
+		utxos := func() []clients.UTXO {
+			utxos, err := account.GetUTXOs(addr.EncodeAddress(), 0, 0)
+			Expect(err).Should(BeNil())
+			return utxos
+		}
+		Expect(utxos()).Should(HaveLen(1))
+		Expect(utxos()[0].TxHash).Should(Equal(receipt.TxHash))
+		Expect(utxos()[0].Amount).Should(Equal(change))
+
+		// The client does not see the transaction, but the cache is refreshed
+		// several times without dropping its change.
+		time.Sleep(100 * time.Millisecond)
+		Expect(utxos()).Should(HaveLen(1))
+		Expect(utxos()[0].TxHash).Should(Equal(receipt.TxHash))
+
+		// Once the transaction has expired, the utxo that it spends is
+		// available again.
+		core.setHeight(int64(receipt.ExpiryHeight))
+		Eventually(func() []clients.UTXO {
+			return utxos()
+		}).Should(Equal(core.utxos[addr.EncodeAddress()]))
+	})
+})
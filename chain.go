@@ -0,0 +1,179 @@
+package libzec
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/btcsuite/btcd/wire"
+	"github.com/renproject/libzec-go/clients"
+)
+
+// MaxStandardTxSize is the maximum size, in bytes, of the transactions relayed
+// by zcashd.
+const MaxStandardTxSize = 100000
+
+// MaxAncestorCount and MaxAncestorSize are the default limits of zcashd on the
+// number of unconfirmed ancestors of a transaction in its mempool, including
+// the transaction itself, and on their total size in bytes. A chain of
+// transactions exceeding them is not accepted by the mempool.
+const (
+	MaxAncestorCount = 25
+	MaxAncestorSize  = 101000
+)
+
+// ChainedTx is a signed transaction of a chain, in which every transaction
+// spends the output of the previous one.
+type ChainedTx struct {
+	Stx     []byte
+	Receipt TxReceipt
+}
+
+// BuildChainedSweep builds and signs the transactions that sweep every funded
+// slave script of the nonces back to the account's address. The utxos are
+// split between as many transactions as required for each of them to stay
+// within MaxStandardTxSize, and every transaction after the first also spends
+// the output of the previous one, so that the last transaction holds the whole
+// swept value. The transactions are returned in the order in which they must
+// be broadcast.
+//
+// The chain stays within MaxAncestorCount and MaxAncestorSize, so that the
+// mempool accepts all of its transactions. The utxos that do not fit are left
+// unspent, and can be swept once the chain is mined. The ancestors of the
+// unconfirmed utxos are not counted.
+func (account *account) BuildChainedSweep(ctx context.Context, nonces [][]byte, speed TxExecutionSpeed) ([]ChainedTx, error) {
+	utxos, scripts, err := account.slaveUTXOs(nonces)
+	if err != nil {
+		return nil, err
+	}
+	if len(utxos) == 0 {
+		return nil, fmt.Errorf("no funded slave scripts to sweep")
+	}
+	return account.buildChain(ctx, utxos, scripts, speed)
+}
+
+// BuildConsolidation builds and signs the transactions that spend every
+// spendable utxo of the account back to its address, split in the same way as
+// BuildChainedSweep.
+func (account *account) BuildConsolidation(ctx context.Context, speed TxExecutionSpeed) ([]ChainedTx, error) {
+	me, err := account.Address()
+	if err != nil {
+		return nil, err
+	}
+	utxos, err := account.GetUTXOs(me.EncodeAddress(), 0, 0)
+	if err != nil {
+		return nil, err
+	}
+	utxos = account.spendable(utxos)
+	if len(utxos) < 2 {
+		return nil, fmt.Errorf("nothing to consolidate: got: %d utxos required: %d", len(utxos), 2)
+	}
+	return account.buildChain(ctx, utxos, make([][]byte, len(utxos)), speed)
+}
+
+// buildChain builds and signs a chain of transactions spending the utxos to the
+// account's address. The redeem script of every utxo is nil if it is locked by
+// a P2PKH script.
+func (account *account) buildChain(ctx context.Context, utxos []clients.UTXO, scripts [][]byte, speed TxExecutionSpeed) ([]ChainedTx, error) {
+	if account.Signer == nil {
+		return nil, ErrWatchOnly
+	}
+	me, err := account.Address()
+	if err != nil {
+		return nil, err
+	}
+	P2PKHScript, err := PayToAddrScript(me)
+	if err != nil {
+		return nil, err
+	}
+	serializedPublicKey, err := account.SerializedPublicKey()
+	if err != nil {
+		return nil, err
+	}
+
+	chain := []ChainedTx{}
+	chainSize, next := 0, 0
+	var prev *clients.UTXO
+	for next < len(utxos) && len(chain) < MaxAncestorCount {
+		tx, err := account.newTx(wire.NewMsgTx(4))
+		if err != nil {
+			return nil, err
+		}
+		var value int64
+		if prev != nil {
+			if err := tx.addInput(*prev); err != nil {
+				return nil, err
+			}
+			value += prev.Amount
+		}
+		tx.msgTx.AddTxOut(wire.NewTxOut(0, P2PKHScript))
+		preview, err := tx.preview()
+		if err != nil {
+			return nil, err
+		}
+
+		// Inputs are added while the estimated size of the signed transaction
+		// stays within the limits, leaving room for the input count to grow.
+		maxSize := MaxStandardTxSize
+		if MaxAncestorSize-chainSize < maxSize {
+			maxSize = MaxAncestorSize - chainSize
+		}
+		size := preview.EstimatedSize + 2
+		start := next
+		for ; next < len(utxos); next++ {
+			sigScriptSize := estimateSigScriptSize(len(serializedPublicKey), nil, scripts[next])
+			inputSize := 32 + 4 + 4 + wire.VarIntSerializeSize(uint64(sigScriptSize)) + sigScriptSize
+			if size+inputSize > maxSize {
+				break
+			}
+			if err := tx.addScriptInput(utxos[next], scripts[next]); err != nil {
+				return nil, err
+			}
+			size += inputSize
+			value += utxos[next].Amount
+		}
+		if next == start {
+			if len(chain) > 0 {
+				break
+			}
+			return nil, fmt.Errorf("utxo %s:%d cannot be spent within the maximum standard size", utxos[next].TxHash, utxos[next].Vout)
+		}
+
+		tx.msgTx.TxOut[0].Value = value
+		tx.changeIndex = 0
+		fee, err := account.payFee(ctx, tx, speed)
+		if err == errFeeExceedsOutput {
+			return nil, NewErrInsufficientBalance(me.EncodeAddress(), fee+ZCashDust, value)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if err := tx.sign(ctx, nil, nil, nil); err != nil {
+			return nil, err
+		}
+
+		buf := new(bytes.Buffer)
+		if err := tx.msgTx.ZecEncode(buf, 0, wire.BaseEncoding); err != nil {
+			return nil, err
+		}
+		receipt, err := tx.receipt()
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, ChainedTx{buf.Bytes(), receipt})
+		chainSize += buf.Len()
+		prev = &clients.UTXO{
+			TxHash:       receipt.TxHash,
+			Amount:       tx.msgTx.TxOut[0].Value,
+			ScriptPubKey: hex.EncodeToString(P2PKHScript),
+			Vout:         0,
+		}
+	}
+	if next < len(utxos) {
+		account.Logger.Infof("built a chain of %d transactions spending %d of %d utxos, the others exceed the mempool ancestor limits", len(chain), next, len(utxos))
+		return chain, nil
+	}
+	account.Logger.Infof("built a chain of %d transactions spending %d utxos", len(chain), len(utxos))
+	return chain, nil
+}
@@ -0,0 +1,77 @@
+package libzec_test
+
+import (
+	"context"
+	"encoding/binary"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcutil"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/renproject/libzec-go"
+)
+
+var _ = Describe("Chained transactions", func() {
+	// expectChain checks that every transaction of the chain spends the
+	// output of the previous one, and returns the number of utxos spent by
+	// the chain and the swept value.
+	expectChain := func(chain []ChainedTx) (int, int64) {
+		Expect(len(chain)).Should(BeNumerically("<=", MaxAncestorCount))
+		size, spent := 0, 0
+		for i, chained := range chain {
+			size += len(chained.Stx)
+			Expect(len(chained.Stx)).Should(BeNumerically("<=", MaxStandardTxSize))
+			Expect(chained.Receipt.Outputs).Should(HaveLen(1))
+			spent += len(chained.Receipt.Inputs)
+			if i > 0 {
+				Expect(chained.Receipt.Inputs[0].TxHash).Should(Equal(chain[i-1].Receipt.TxHash))
+				spent--
+			}
+		}
+		Expect(size).Should(BeNumerically("<=", MaxAncestorSize))
+		return spent, chain[len(chain)-1].Receipt.Outputs[0].Value
+	}
+
+	It("should consolidate the utxos of the account within the ancestor limits", func() {
+		core := newMockClientCore(&chaincfg.TestNet3Params, 1842420)
+		account, _, addr := newMockAccount(core, 0)
+		account.SetFeeEstimator(NewStaticFeeEstimator(1, 1, 1))
+		for i := 0; i < 1000; i++ {
+			hash := chainhash.Hash{}
+			binary.LittleEndian.PutUint32(hash[:], uint32(i))
+			core.addUTXO(addr, hash, 10000, 1)
+		}
+
+		chain, err := account.BuildConsolidation(context.Background(), Standard)
+		Expect(err).Should(BeNil())
+		Expect(len(chain)).Should(BeNumerically(">", 1))
+		spent, value := expectChain(chain)
+		Expect(spent).Should(BeNumerically("<", 1000))
+		Expect(value).Should(BeNumerically(">", int64(spent)*10000-int64(len(chain))*100000))
+		Expect(value).Should(BeNumerically("<", int64(spent)*10000))
+	})
+
+	It("should sweep the slave scripts of the nonces", func() {
+		core := newMockClientCore(&chaincfg.TestNet3Params, 1842420)
+		account, _, _ := newMockAccount(core, 0)
+		pubKey, err := account.SerializedPublicKey()
+		Expect(err).Should(BeNil())
+		nonces := [][]byte{[]byte("a"), []byte("b"), []byte("c")}
+		for i, nonce := range nonces {
+			slave, err := account.SlaveAddress(btcutil.Hash160(pubKey), nonce)
+			Expect(err).Should(BeNil())
+			core.addUTXO(slave, chainhash.Hash{0xC0, byte(i)}, 100000, 1)
+		}
+
+		chain, err := account.BuildChainedSweep(context.Background(), nonces, Standard)
+		Expect(err).Should(BeNil())
+		Expect(chain).Should(HaveLen(1))
+		spent, value := expectChain(chain)
+		Expect(spent).Should(Equal(3))
+		Expect(value).Should(Equal(int64(300000) - chain[0].Receipt.Fee))
+
+		_, err = account.BuildChainedSweep(context.Background(), [][]byte{[]byte("d")}, Standard)
+		Expect(err).ShouldNot(BeNil())
+	})
+})
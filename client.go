@@ -1,15 +1,13 @@
 package libzec
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/btcsuite/btcd/btcec"
-	"github.com/btcsuite/btcd/chaincfg"
 	"github.com/btcsuite/btcd/txscript"
 	"github.com/btcsuite/btcutil"
-	"github.com/iqoption/zecutil"
 	"github.com/renproject/libzec-go/clients"
-	"github.com/renproject/libzec-go/errors"
 )
 
 type Client interface {
@@ -18,16 +16,71 @@ type Client interface {
 	// Balance of the given address on ZCash blockchain.
 	Balance(address string, confirmations int64) (int64, error)
 
+	// BalanceOf returns the total balance of the given addresses, querying
+	// them concurrently.
+	BalanceOf(addresses []string, confirmations int64) (int64, error)
+
+	// GetUTXOsMulti returns the utxos of all the given addresses, querying
+	// them concurrently. The limit applies to every address, and a limit of 0
+	// returns every utxo.
+	GetUTXOsMulti(addresses []string, limit, confirmations int64) ([]clients.UTXO, error)
+
 	// FormatTransactionView formats the message and txhash into a user friendly
-	// message.
+	// message, with a link to the transaction on the block explorer. An empty
+	// string is returned if the explorer does not support the network.
 	FormatTransactionView(msg, txhash string) string
 
-	// SerializePublicKey serializes the given public key.
+	// ViewTransaction returns a structured view of a transaction sent by an
+	// account, which can be marshaled to JSON.
+	ViewTransaction(receipt TxReceipt) (TransactionView, error)
+
+	// FormatAddressView formats the message and address into a user friendly
+	// message, with a link to the address on the block explorer. An empty
+	// string is returned if the explorer does not support the network.
+	FormatAddressView(msg, address string) string
+
+	// SetExplorer sets the block explorer used to format the views of
+	// transactions and addresses. Chain.so is used by default.
+	SetExplorer(explorer Explorer)
+
+	// SerializePublicKey serializes the given public key. Public keys are
+	// compressed on mainnet and uncompressed on testnet3 and regtest, unless
+	// compression is set explicitly using SetPubKeyCompression.
 	SerializePublicKey(pubKey *btcec.PublicKey) ([]byte, error)
 
+	// SetPubKeyCompression sets whether public keys are serialized in their
+	// compressed form. It must be set for networks other than mainnet,
+	// testnet3 and regtest. Changing it changes the addresses of accounts.
+	SetPubKeyCompression(compressed bool)
+
 	// PublicKeyToAddress converts the public key to a zcash address.
 	PublicKeyToAddress(pubKeyBytes []byte) (btcutil.Address, error)
 
+	// WaitForScriptFunded polls until the address has received at least the
+	// given value, or the context is done, and returns its balance.
+	WaitForScriptFunded(ctx context.Context, address string, value int64) (int64, error)
+
+	// WaitForScriptRedeemed polls until the address has received at least
+	// the given value and has been spent entirely, or the context is done.
+	WaitForScriptRedeemed(ctx context.Context, address string, value int64) error
+
+	// WaitForScriptSpent polls until the script is spent by the spender, or
+	// the context is done, and returns the signature script that spent it.
+	WaitForScriptSpent(ctx context.Context, script, spender string) (ScriptSpend, error)
+
+	// TxStatus returns the confirmations of the transaction, and whether it
+	// has expired without being mined.
+	TxStatus(txHash string) (TxStatus, error)
+
+	// IsExpired returns whether the transaction was not mined before its
+	// expiry height, and will never be mined.
+	IsExpired(txHash string) (bool, error)
+
+	// TestMempoolAccept checks that the signed transaction would be accepted
+	// into the mempool, without broadcasting it. Only transparent v4
+	// transactions are supported.
+	TestMempoolAccept(stx []byte) error
+
 	// SlaveAddress creates an a deterministic unique address that can be spent
 	// by the private key correspndong to the given master public key hash
 	SlaveAddress(mpkh, nonce []byte) (btcutil.Address, error)
@@ -36,19 +89,64 @@ type Client interface {
 	// the private key correspndong to the given master public key hash
 	SlaveScript(mpkh, nonce []byte) ([]byte, error)
 
+	// SlaveAddressWithRefund creates a deterministic unique address that can be
+	// spent by the private key corresponding to the given master public key
+	// hash, or by the private key corresponding to the given refund public key
+	// hash once the lock time has passed.
+	SlaveAddressWithRefund(mpkh, nonce, refundPKH []byte, lockTime int64) (btcutil.Address, error)
+
+	// SlaveScriptWithRefund creates a deterministic unique script that can be
+	// spent by the private key corresponding to the given master public key
+	// hash, or by the private key corresponding to the given refund public key
+	// hash once the lock time has passed.
+	SlaveScriptWithRefund(mpkh, nonce, refundPKH []byte, lockTime int64) ([]byte, error)
+
+	// SlaveEntries derives the slave addresses of the master public key hash
+	// and the nonces, such as the nonces returned by SlaveNonces.
+	SlaveEntries(mpkh []byte, nonces [][]byte) ([]SlaveEntry, error)
+
+	// ScanSlaves fetches the status of the slave addresses of the master
+	// public key hash and the nonces concurrently, to find the funded ones.
+	ScanSlaves(mpkh []byte, nonces [][]byte) ([]SlaveStatus, error)
+
 	// UTXOCount returns the number of utxos that can be spent.
 	UTXOCount(address string, confirmations int64) (int, error)
 
 	// Validate returns whether an address is valid or not
 	Validate(address string) error
+
+	// ValidateAll validates a batch of addresses concurrently, and returns
+	// the error of every address, or nil if it is valid. Addresses are
+	// validated in their canonical form, as returned by NormalizeAddress.
+	ValidateAll(addresses []string) map[string]error
+
+	// HTLCFunded checks whether the given hash time locked contract is funded.
+	HTLCFunded(script []byte, value int64) (bool, int64, error)
+
+	// HTLCRedeemed checks whether the given hash time locked contract is
+	// redeemed.
+	HTLCRedeemed(script []byte, value int64) (bool, int64, error)
+
+	// HTLCSpent checks whether the given hash time locked contract is spent by
+	// the spender, and returns the signature script of the spending input.
+	HTLCSpent(script []byte, spender string) (bool, string, error)
 }
 
 type client struct {
 	clients.ClientCore
+	compressed *bool
+	explorer   Explorer
 }
 
+// Balance returns the balance reported by the backend, or the sum of the utxos
+// of the address if the backend cannot report it.
 func (client *client) Balance(address string, confirmations int64) (int64, error) {
-	utxos, err := client.GetUTXOs(address, 999999, confirmations)
+	if client.Capabilities().AddressBalance {
+		if balance, err := client.AddressBalance(address, confirmations); err != ErrNotSupported {
+			return balance, err
+		}
+	}
+	utxos, err := client.GetUTXOs(address, 0, confirmations)
 	if err != nil {
 		return 0, err
 	}
@@ -60,26 +158,58 @@ func (client *client) Balance(address string, confirmations int64) (int64, error
 }
 
 func (client *client) FormatTransactionView(msg, txhash string) string {
-	switch client.NetworkParams().Name {
-	case "mainnet":
-		return fmt.Sprintf("%s, transaction can be viewed at https://chain.so/tx/ZEC/%s", msg, txhash)
-	case "testnet3":
-		return fmt.Sprintf("%s, transaction can be viewed at https://chain.so/tx/ZECTEST/%s", msg, txhash)
-	default:
+	txURL := client.blockExplorer().TxURL(client.NetworkParams().Name, txhash)
+	if txURL == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s, transaction can be viewed at %s", msg, txURL)
+}
+
+func (client *client) FormatAddressView(msg, address string) string {
+	addressURL := client.blockExplorer().AddressURL(client.NetworkParams().Name, address)
+	if addressURL == "" {
 		return ""
 	}
+	return fmt.Sprintf("%s, address can be viewed at %s", msg, addressURL)
+}
+
+func (client *client) SetExplorer(explorer Explorer) {
+	client.explorer = explorer
+}
+
+func (client *client) blockExplorer() Explorer {
+	if client.explorer == nil {
+		return ChainSoExplorer
+	}
+	return client.explorer
 }
 
 func (client *client) SerializePublicKey(pubKey *btcec.PublicKey) ([]byte, error) {
-	net := client.NetworkParams()
-	switch net {
-	case &chaincfg.MainNetParams:
+	compressed, err := client.pubKeyCompression()
+	if err != nil {
+		return nil, err
+	}
+	if compressed {
 		return pubKey.SerializeCompressed(), nil
-	case &chaincfg.TestNet3Params:
-		return pubKey.SerializeUncompressed(), nil
-	default:
-		return nil, errors.NewErrUnsupportedNetwork(net.Name)
 	}
+	return pubKey.SerializeUncompressed(), nil
+}
+
+func (client *client) SetPubKeyCompression(compressed bool) {
+	client.compressed = &compressed
+}
+
+// pubKeyCompression returns whether public keys are compressed. Networks are
+// compared by name, so that custom network parameters are supported.
+func (client *client) pubKeyCompression() (bool, error) {
+	if client.compressed != nil {
+		return *client.compressed, nil
+	}
+	network, err := NetworkOf(client.NetworkParams())
+	if err != nil {
+		return false, err
+	}
+	return network == Mainnet, nil
 }
 
 func (client *client) PublicKeyToAddress(pubKeyBytes []byte) (btcutil.Address, error) {
@@ -89,7 +219,10 @@ func (client *client) PublicKeyToAddress(pubKeyBytes []byte) (btcutil.Address, e
 }
 
 func (client *client) UTXOCount(address string, confirmations int64) (int, error) {
-	utxos, err := client.GetUTXOs(address, 999999, confirmations)
+	if count, err := client.AddressUTXOCount(address, confirmations); err != ErrNotSupported {
+		return count, err
+	}
+	utxos, err := client.GetUTXOs(address, 0, confirmations)
 	if err != nil {
 		return 0, err
 	}
@@ -97,7 +230,7 @@ func (client *client) UTXOCount(address string, confirmations int64) (int, error
 }
 
 func (client *client) Validate(address string) error {
-	_, err := zecutil.DecodeAddress(address, client.NetworkParams().Name)
+	_, err := DecodeAddress(address, client.NetworkParams())
 	return err
 }
 
@@ -122,18 +255,85 @@ func (client *client) SlaveScript(mpkh, nonce []byte) ([]byte, error) {
 	b.AddOp(txscript.OP_CHECKSIG)
 	return b.Script()
 }
-func NewMercuryClient(network string) (Client, error) {
-	core, err := clients.NewMercuryClientCore(network)
+
+func (client *client) SlaveAddressWithRefund(mpkh, nonce, refundPKH []byte, lockTime int64) (btcutil.Address, error) {
+	script, err := client.SlaveScriptWithRefund(mpkh, nonce, refundPKH, lockTime)
+	if err != nil {
+		return nil, err
+	}
+	return ScriptAddress(script, client.NetworkParams())
+}
+
+func (client *client) SlaveScriptWithRefund(mpkh, nonce, refundPKH []byte, lockTime int64) ([]byte, error) {
+	b := txscript.NewScriptBuilder()
+	b.AddOp(txscript.OP_IF)
+	b.AddData(nonce)
+	b.AddOp(txscript.OP_DROP)
+	b.AddOp(txscript.OP_DUP)
+	b.AddOp(txscript.OP_HASH160)
+	b.AddData(mpkh)
+	b.AddOp(txscript.OP_ELSE)
+	b.AddInt64(lockTime)
+	b.AddOp(txscript.OP_CHECKLOCKTIMEVERIFY)
+	b.AddOp(txscript.OP_DROP)
+	b.AddOp(txscript.OP_DUP)
+	b.AddOp(txscript.OP_HASH160)
+	b.AddData(refundPKH)
+	b.AddOp(txscript.OP_ENDIF)
+	b.AddOp(txscript.OP_EQUALVERIFY)
+	b.AddOp(txscript.OP_CHECKSIG)
+	return b.Script()
+}
+
+// NewClient returns a client backed by the given client core, such as a core
+// wrapped by NewTracedClientCore.
+func NewClient(core clients.ClientCore) Client {
+	return &client{core, nil, nil}
+}
+
+// clientCores returns the core of the client followed by the cores it wraps,
+// so that the extensions of a core are found behind circuit breakers and
+// tracing.
+func clientCores(c Client) []clients.ClientCore {
+	if c, ok := c.(*client); ok {
+		return clients.Unwrap(c.ClientCore)
+	}
+	return clients.Unwrap(c)
+}
+
+func NewMercuryClient(network string, options ...clients.Option) (Client, error) {
+	core, err := clients.NewMercuryClientCore(network, options...)
+	if err != nil {
+		return nil, err
+	}
+	return &client{core, nil, nil}, nil
+}
+
+// NewZcashdClient returns a client backed by the JSON-RPC interface of a
+// zcashd node at the given url.
+func NewZcashdClient(network, url, user, password string, options ...clients.Option) (Client, error) {
+	core, err := clients.NewZcashdClientCore(network, url, user, password, options...)
+	if err != nil {
+		return nil, err
+	}
+	return &client{core, nil, nil}, nil
+}
+
+func NewChainSoClient(network string, options ...clients.Option) (Client, error) {
+	core, err := clients.NewChainSoClientCore(network, options...)
 	if err != nil {
 		return nil, err
 	}
-	return &client{core}, nil
+	return &client{core, nil, nil}, nil
 }
 
-func NewChainSoClient(network string) (Client, error) {
-	core, err := clients.NewChainSoClientCore(network)
+// NewBlockchairClient returns a client using the Zcash API of Blockchair, with
+// the given API key, or without one if it is empty. Its views link to the
+// Blockchair explorer.
+func NewBlockchairClient(network, apiKey string, options ...clients.Option) (Client, error) {
+	core, err := clients.NewBlockchairClientCore(network, apiKey, options...)
 	if err != nil {
 		return nil, err
 	}
-	return &client{core}, nil
+	return &client{core, nil, BlockchairExplorer}, nil
 }
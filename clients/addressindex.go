@@ -0,0 +1,158 @@
+package clients
+
+import (
+	"fmt"
+
+	"github.com/renproject/libzec-go/errors"
+)
+
+// The codes of the errors returned by zcashd when a method does not exist, or
+// is disabled because the node does not maintain the address index.
+const (
+	rpcMethodNotFound = -32601
+	rpcMiscError      = -1
+)
+
+type zcashdAddressUTXO struct {
+	Address     string `json:"address"`
+	TxID        string `json:"txid"`
+	OutputIndex uint32 `json:"outputIndex"`
+	Script      string `json:"script"`
+	Satoshis    int64  `json:"satoshis"`
+	Height      int64  `json:"height"`
+}
+
+type zcashdAddressBalance struct {
+	Balance  int64 `json:"balance"`
+	Received int64 `json:"received"`
+}
+
+type zcashdMempoolDelta struct {
+	TxID     string `json:"txid"`
+	Index    uint32 `json:"index"`
+	Satoshis int64  `json:"satoshis"`
+	PrevTxID string `json:"prevtxid"`
+	PrevOut  uint32 `json:"prevout"`
+}
+
+// callIndex invokes a method of the address index. It returns false if the
+// node does not maintain the index, in which case the caller falls back to the
+// wallet of the node. The first call decides whether the index is available,
+// and later calls skip the index if it is not.
+func (client *zcashdClient) callIndex(method string, result interface{}, params ...interface{}) (bool, error) {
+	client.indexMu.Lock()
+	known, enabled := client.indexKnown, client.indexEnabled
+	client.indexMu.Unlock()
+	if known && !enabled {
+		return false, nil
+	}
+
+	err := client.call(method, result, params...)
+	if rpcErr, ok := err.(*RPCError); ok && !known && (rpcErr.Code == rpcMethodNotFound || rpcErr.Code == rpcMiscError) {
+		client.setAddressIndex(false)
+		return false, nil
+	}
+	if err != nil {
+		return true, err
+	}
+	client.setAddressIndex(true)
+	return true, nil
+}
+
+func (client *zcashdClient) setAddressIndex(enabled bool) {
+	client.indexMu.Lock()
+	defer client.indexMu.Unlock()
+	client.indexKnown, client.indexEnabled = true, enabled
+}
+
+// indexedUTXOs returns the utxos of the address using getaddressutxos. The
+// outputs spent by transactions of the mempool are excluded, and the outputs
+// of the mempool are included if confirmations is 0. The index does not
+// report whether an output is a coinbase output.
+func (client *zcashdClient) indexedUTXOs(address string, confirmations int64) ([]UTXO, bool, error) {
+	query := zcashdAddressQuery{[]string{address}}
+	mined := []zcashdAddressUTXO{}
+	if ok, err := client.callIndex("getaddressutxos", &mined, query); !ok || err != nil {
+		return nil, ok, err
+	}
+	mempool := []zcashdMempoolDelta{}
+	if err := client.call("getaddressmempool", &mempool, query); err != nil {
+		return nil, true, err
+	}
+	height, err := client.BlockHeight()
+	if err != nil {
+		return nil, true, err
+	}
+
+	spent := map[string]bool{}
+	for _, delta := range mempool {
+		if delta.Satoshis < 0 {
+			spent[fmt.Sprintf("%s:%d", delta.PrevTxID, delta.PrevOut)] = true
+		}
+	}
+	utxos := []UTXO{}
+	for _, utxo := range mined {
+		conf := height - utxo.Height + 1
+		if spent[fmt.Sprintf("%s:%d", utxo.TxID, utxo.OutputIndex)] || conf < confirmations {
+			continue
+		}
+		utxos = append(utxos, UTXO{
+			TxHash:        utxo.TxID,
+			Amount:        utxo.Satoshis,
+			ScriptPubKey:  utxo.Script,
+			Vout:          utxo.OutputIndex,
+			Confirmations: conf,
+			BlockHeight:   utxo.Height,
+			Address:       utxo.Address,
+		})
+	}
+	if confirmations > 0 {
+		return utxos, true, nil
+	}
+
+	// The mempool does not report the scripts of its outputs, so they are
+	// looked up one by one.
+	for _, delta := range mempool {
+		if delta.Satoshis <= 0 || spent[fmt.Sprintf("%s:%d", delta.TxID, delta.Index)] {
+			continue
+		}
+		utxo, err := client.GetUTXO(delta.TxID, delta.Index)
+		if err == errors.ErrUTXOSpent {
+			continue
+		}
+		if err != nil {
+			return nil, true, err
+		}
+		utxos = append(utxos, utxo)
+	}
+	return utxos, true, nil
+}
+
+// indexedBalance returns the balance of the address, and the total value it
+// received, using getaddressbalance. The index only counts mined outputs, so
+// the deltas of the mempool are added if confirmations is 0, and balances
+// with more than one confirmation are not supported.
+func (client *zcashdClient) indexedBalance(address string, confirmations int64) (int64, int64, bool, error) {
+	if confirmations > 1 {
+		return 0, 0, true, errors.ErrNotSupported
+	}
+	query := zcashdAddressQuery{[]string{address}}
+	balance := zcashdAddressBalance{}
+	if ok, err := client.callIndex("getaddressbalance", &balance, query); !ok || err != nil {
+		return 0, 0, ok, err
+	}
+	if confirmations == 1 {
+		return balance.Balance, balance.Received, true, nil
+	}
+	mempool := []zcashdMempoolDelta{}
+	if err := client.call("getaddressmempool", &mempool, query); err != nil {
+		return 0, 0, true, err
+	}
+	for _, delta := range mempool {
+		balance.Balance += delta.Satoshis
+		if delta.Satoshis > 0 {
+			balance.Received += delta.Satoshis
+		}
+	}
+	return balance.Balance, balance.Received, true, nil
+}
@@ -0,0 +1,167 @@
+package clients
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/renproject/libzec-go/errors"
+)
+
+// maxBatchSize is the number of calls sent in a single JSON-RPC batch.
+const maxBatchSize = 100
+
+// BatchClient is implemented by the client cores that can look up many
+// transactions in a few round trips, such as a zcashd client rescanning the
+// transactions of a tx store.
+type BatchClient interface {
+	// BatchConfirmations returns the confirmations of the transactions.
+	// Transactions that cannot be found are omitted.
+	BatchConfirmations(txHashes []string) (map[string]int64, error)
+
+	// BatchRawTransactions returns the serialized transactions. Transactions
+	// that cannot be found are omitted.
+	BatchRawTransactions(txHashes []string) (map[string][]byte, error)
+}
+
+// rpcCall is a call of a JSON-RPC batch.
+type rpcCall struct {
+	method string
+	params []interface{}
+	result interface{}
+}
+
+// batch sends the calls as JSON-RPC batches of at most maxBatchSize calls, and
+// decodes their results. It returns the error of every call, and an error if
+// a batch fails as a whole.
+func (client *zcashdClient) batch(calls []rpcCall) ([]error, error) {
+	errs := make([]error, len(calls))
+	for start := 0; start < len(calls); start += maxBatchSize {
+		end := start + maxBatchSize
+		if end > len(calls) {
+			end = len(calls)
+		}
+		if err := client.sendBatch(calls[start:end], errs[start:end]); err != nil {
+			return nil, err
+		}
+	}
+	return errs, nil
+}
+
+func (client *zcashdClient) sendBatch(calls []rpcCall, errs []error) error {
+	reqs := make([]rpcRequest, len(calls))
+	for i, call := range calls {
+		params := call.params
+		if params == nil {
+			params = []interface{}{}
+		}
+		reqs[i] = rpcRequest{"1.0", strconv.Itoa(i), call.method, params}
+	}
+	buf := new(bytes.Buffer)
+	if err := json.NewEncoder(buf).Encode(reqs); err != nil {
+		return err
+	}
+	req, err := http.NewRequest("POST", client.URL, buf)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.SetBasicAuth(client.User, client.Password)
+
+	resps := []rpcResponse{}
+	if err := doRequest(client.httpConfig, req, func(resp *http.Response) error {
+		if err := json.NewDecoder(resp.Body).Decode(&resps); err != nil {
+			return fmt.Errorf("request failed with (%d): %v", resp.StatusCode, err)
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	// The responses of a batch can be in any order, so they are matched
+	// with their calls by id.
+	answered := make([]bool, len(calls))
+	for _, resp := range resps {
+		i, err := strconv.Atoi(resp.ID)
+		if err != nil || i < 0 || i >= len(calls) {
+			return fmt.Errorf("unexpected response id %q", resp.ID)
+		}
+		answered[i] = true
+		switch {
+		case resp.Error != nil:
+			errs[i] = resp.Error
+		case calls[i].result != nil:
+			errs[i] = json.Unmarshal(resp.Result, calls[i].result)
+		}
+	}
+	for i := range calls {
+		if !answered[i] {
+			errs[i] = fmt.Errorf("no response to %s", calls[i].method)
+		}
+	}
+	return nil
+}
+
+// BatchConfirmations returns the confirmations of the transactions using
+// batches of getrawtransaction calls.
+func (client *zcashdClient) BatchConfirmations(txHashes []string) (map[string]int64, error) {
+	txs := make([]zcashdRawTx, len(txHashes))
+	calls := make([]rpcCall, len(txHashes))
+	for i, txHash := range txHashes {
+		calls[i] = rpcCall{"getrawtransaction", []interface{}{txHash, 1}, &txs[i]}
+	}
+	errs, err := client.batch(calls)
+	if err != nil {
+		return nil, err
+	}
+	confs := map[string]int64{}
+	for i, txHash := range txHashes {
+		if err := batchError(errs[i]); err != nil {
+			if err == errors.ErrTxNotFound {
+				continue
+			}
+			return nil, err
+		}
+		confs[txHash] = txs[i].Confirmations
+	}
+	return confs, nil
+}
+
+// BatchRawTransactions returns the serialized transactions using batches of
+// getrawtransaction calls.
+func (client *zcashdClient) BatchRawTransactions(txHashes []string) (map[string][]byte, error) {
+	txs := make([]string, len(txHashes))
+	calls := make([]rpcCall, len(txHashes))
+	for i, txHash := range txHashes {
+		calls[i] = rpcCall{"getrawtransaction", []interface{}{txHash, 0}, &txs[i]}
+	}
+	errs, err := client.batch(calls)
+	if err != nil {
+		return nil, err
+	}
+	raw := map[string][]byte{}
+	for i, txHash := range txHashes {
+		if err := batchError(errs[i]); err != nil {
+			if err == errors.ErrTxNotFound {
+				continue
+			}
+			return nil, err
+		}
+		if raw[txHash], err = hex.DecodeString(txs[i]); err != nil {
+			return nil, err
+		}
+	}
+	return raw, nil
+}
+
+// batchError returns ErrTxNotFound if the error of a call reports a missing
+// transaction.
+func batchError(err error) error {
+	if rpcErr, ok := err.(*RPCError); ok && rpcErr.Code == rpcInvalidAddressOrKey {
+		return errors.ErrTxNotFound
+	}
+	return err
+}
@@ -0,0 +1,648 @@
+package clients
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/renproject/libzec-go/errors"
+)
+
+const (
+	// BlockchairFreeInterval is the interval between the requests of a
+	// Blockchair client without an API key, which keeps it within the
+	// limits of the free plan.
+	BlockchairFreeInterval = 2 * time.Second
+
+	// BlockchairFreeDailyRequests is the number of requests a Blockchair
+	// client without an API key sends per day, which is the daily quota of
+	// the free plan.
+	BlockchairFreeDailyRequests = 1440
+
+	// blockchairBackoff is how long a rate limited client stops sending
+	// requests when Blockchair does not say when to retry.
+	blockchairBackoff = time.Minute
+
+	// blockchairPageSize is the number of utxos, or transactions, of an
+	// address dashboard page.
+	blockchairPageSize = 100
+
+	// blockchairMaxTxs is the number of transactions that can be fetched by
+	// a single request of the transactions dashboard.
+	blockchairMaxTxs = 10
+
+	// blockchairCoinbaseMaturity is the number of confirmations after which
+	// the outputs of coinbase transactions can be spent. Only the
+	// transactions of utxos with fewer confirmations are fetched to tell
+	// whether they are coinbase transactions.
+	blockchairCoinbaseMaturity = 100
+)
+
+// blockchairRateLimited are the statuses Blockchair answers with when the
+// requests exceed the limits of the plan, or the client is banned.
+var blockchairRateLimited = map[int]bool{402: true, 429: true, 430: true, 434: true, 435: true, 436: true}
+
+type blockchairClient struct {
+	URL        string
+	apiKey     string
+	params     *chaincfg.Params
+	httpConfig *httpConfig
+	limiter    *rateLimiter
+}
+
+// NewBlockchairClientCore returns a client core using the Zcash API of
+// Blockchair, which only supports mainnet. The API key is optional: without
+// it, requests are spaced by BlockchairFreeInterval, and at most
+// BlockchairFreeDailyRequests are sent per day. Requests that exceed the
+// limits of the plan fail with a BackendUnavailableError, and the client does
+// not send any other request until Blockchair allows it.
+func NewBlockchairClientCore(network, apiKey string, options ...Option) (ClientCore, error) {
+	httpConfig, err := applyOptions(options)
+	if err != nil {
+		return nil, err
+	}
+	net, err := ParseNetwork(network)
+	if err != nil {
+		return nil, err
+	}
+	if net != Mainnet {
+		return nil, errors.NewErrUnsupportedNetwork(network)
+	}
+	interval, quota := BlockchairFreeInterval, BlockchairFreeDailyRequests
+	if apiKey != "" {
+		interval, quota = 0, 0
+	}
+	return &blockchairClient{
+		URL:        "https://api.blockchair.com/zcash",
+		apiKey:     apiKey,
+		params:     net.Params(),
+		httpConfig: httpConfig,
+		limiter:    newRateLimiter(interval, quota),
+	}, nil
+}
+
+type blockchairResponse struct {
+	Data    json.RawMessage   `json:"data"`
+	Context blockchairContext `json:"context"`
+}
+
+type blockchairContext struct {
+	Code  int    `json:"code"`
+	Error string `json:"error"`
+
+	// State is the height of the best block when the request was served.
+	State int64 `json:"state"`
+}
+
+// BlockchairAddressDashboard is the dashboard of an address returned by
+// Blockchair.
+type BlockchairAddressDashboard struct {
+	Address      BlockchairAddress `json:"address"`
+	Transactions []string          `json:"transactions"`
+	UTXO         []BlockchairUTXO  `json:"utxo"`
+}
+
+type BlockchairAddress struct {
+	ScriptHex          string `json:"script_hex"`
+	Balance            int64  `json:"balance"`
+	Received           int64  `json:"received"`
+	TransactionCount   int    `json:"transaction_count"`
+	UnspentOutputCount int    `json:"unspent_output_count"`
+}
+
+// BlockchairUTXO is an unspent output of an address. The block id is -1 for
+// outputs of transactions in the mempool.
+type BlockchairUTXO struct {
+	BlockID         int64  `json:"block_id"`
+	TransactionHash string `json:"transaction_hash"`
+	Index           uint32 `json:"index"`
+	Value           int64  `json:"value"`
+}
+
+// BlockchairTxDashboard is the dashboard of a transaction returned by
+// Blockchair.
+type BlockchairTxDashboard struct {
+	Transaction BlockchairTx       `json:"transaction"`
+	Inputs      []BlockchairOutput `json:"inputs"`
+	Outputs     []BlockchairOutput `json:"outputs"`
+}
+
+type BlockchairTx struct {
+	BlockID    int64  `json:"block_id"`
+	Hash       string `json:"hash"`
+	IsCoinbase bool   `json:"is_coinbase"`
+}
+
+// BlockchairOutput is an output of a transaction. The inputs of a transaction
+// are the outputs they spend, with the signature script of the spending input.
+type BlockchairOutput struct {
+	TransactionHash         string `json:"transaction_hash"`
+	Index                   uint32 `json:"index"`
+	Value                   int64  `json:"value"`
+	Recipient               string `json:"recipient"`
+	ScriptHex               string `json:"script_hex"`
+	IsSpent                 bool   `json:"is_spent"`
+	SpendingTransactionHash string `json:"spending_transaction_hash"`
+	SpendingSignatureHex    string `json:"spending_signature_hex"`
+}
+
+type blockchairStats struct {
+	BestBlockHeight int64 `json:"best_block_height"`
+}
+
+// blockchairError is returned when Blockchair answers with an error status.
+type blockchairError struct {
+	description string
+	status      int
+	message     string
+}
+
+func (err *blockchairError) Error() string {
+	return fmt.Sprintf("%s (%d): %s", err.description, err.status, err.message)
+}
+
+func (client blockchairClient) NetworkParams() *chaincfg.Params {
+	return client.params
+}
+
+// addressDashboard returns a page of the dashboard of the address, with the
+// given limits and offsets in its transactions and its utxos.
+func (client blockchairClient) addressDashboard(address string, txLimit, txOffset, utxoLimit, utxoOffset int) (BlockchairAddressDashboard, int64, error) {
+	query := url.Values{}
+	query.Set("limit", fmt.Sprintf("%d,%d", txLimit, utxoLimit))
+	query.Set("offset", fmt.Sprintf("%d,%d", txOffset, utxoOffset))
+	dashboards := map[string]BlockchairAddressDashboard{}
+	height, err := client.get("/dashboards/address/"+address, query, "failed to get address", &dashboards)
+	if err != nil {
+		return BlockchairAddressDashboard{}, 0, err
+	}
+	dashboard, ok := dashboards[address]
+	if !ok {
+		return BlockchairAddressDashboard{}, 0, fmt.Errorf("failed to get address: no dashboard for %s", address)
+	}
+	return dashboard, height, nil
+}
+
+func (client blockchairClient) GetUTXOs(address string, limit, confirmations int64) ([]UTXO, error) {
+	if err := CheckUTXOQuery(limit, confirmations); err != nil {
+		return nil, err
+	}
+	utxos := []UTXO{}
+	for offset := 0; ; offset += blockchairPageSize {
+		dashboard, height, err := client.addressDashboard(address, 0, 0, blockchairPageSize, offset)
+		if err != nil {
+			return nil, err
+		}
+		for _, output := range dashboard.UTXO {
+			conf := blockchairConfirmations(output.BlockID, height)
+			if conf < confirmations {
+				continue
+			}
+			if err := validateOutput(output.TransactionHash, dashboard.Address.ScriptHex); err != nil {
+				return nil, err
+			}
+			utxo := UTXO{
+				TxHash:        output.TransactionHash,
+				Amount:        output.Value,
+				ScriptPubKey:  dashboard.Address.ScriptHex,
+				Vout:          output.Index,
+				Confirmations: conf,
+				Address:       address,
+			}
+			if output.BlockID >= 0 {
+				utxo.BlockHeight = output.BlockID
+			}
+			utxos = append(utxos, utxo)
+		}
+		if len(dashboard.UTXO) < blockchairPageSize {
+			break
+		}
+	}
+	if err := client.markCoinbase(utxos); err != nil {
+		return nil, err
+	}
+	return LimitUTXOs(utxos, limit), nil
+}
+
+// markCoinbase marks the utxos of coinbase transactions. The address
+// dashboard does not tell whether an output belongs to a coinbase
+// transaction, so the transactions of the mined utxos that have not matured
+// are fetched.
+func (client blockchairClient) markCoinbase(utxos []UTXO) error {
+	txHashes := []string{}
+	seen := map[string]bool{}
+	for _, utxo := range utxos {
+		if utxo.BlockHeight == 0 || utxo.Confirmations >= blockchairCoinbaseMaturity || seen[utxo.TxHash] {
+			continue
+		}
+		seen[utxo.TxHash] = true
+		txHashes = append(txHashes, utxo.TxHash)
+	}
+	if len(txHashes) == 0 {
+		return nil
+	}
+	txs, _, err := client.GetTxs(txHashes)
+	if err != nil {
+		return err
+	}
+	for i := range utxos {
+		if tx, ok := txs[utxos[i].TxHash]; ok {
+			utxos[i].Coinbase = tx.Transaction.IsCoinbase
+		}
+	}
+	return nil
+}
+
+// addressTxs returns the hashes of every transaction of the address.
+func (client blockchairClient) addressTxs(address string) ([]string, error) {
+	txHashes := []string{}
+	for offset := 0; ; offset += blockchairPageSize {
+		dashboard, _, err := client.addressDashboard(address, blockchairPageSize, offset, 0, 0)
+		if err != nil {
+			return nil, err
+		}
+		txHashes = append(txHashes, dashboard.Transactions...)
+		if len(dashboard.Transactions) < blockchairPageSize {
+			return txHashes, nil
+		}
+	}
+}
+
+// GetTxs returns the dashboards of the transactions, fetching up to 10 of them
+// per request, and the height of the best block. Transactions that Blockchair
+// does not know are omitted.
+func (client blockchairClient) GetTxs(txHashes []string) (map[string]BlockchairTxDashboard, int64, error) {
+	txs := map[string]BlockchairTxDashboard{}
+	var height int64
+	for start := 0; start < len(txHashes); start += blockchairMaxTxs {
+		end := start + blockchairMaxTxs
+		if end > len(txHashes) {
+			end = len(txHashes)
+		}
+		page := map[string]BlockchairTxDashboard{}
+		var err error
+		height, err = client.get("/dashboards/transactions/"+strings.Join(txHashes[start:end], ","), nil, "failed to get txs", &page)
+		if err != nil {
+			if bcErr, ok := err.(*blockchairError); ok && bcErr.status == http.StatusNotFound {
+				continue
+			}
+			return nil, 0, err
+		}
+		for txHash, tx := range page {
+			txs[txHash] = tx
+		}
+	}
+	return txs, height, nil
+}
+
+// GetTx returns the dashboard of the transaction and the height of the best
+// block, and ErrTxNotFound if Blockchair does not know the transaction.
+func (client blockchairClient) GetTx(txHash string) (BlockchairTxDashboard, int64, error) {
+	txs, height, err := client.GetTxs([]string{txHash})
+	if err != nil {
+		return BlockchairTxDashboard{}, 0, err
+	}
+	tx, ok := txs[txHash]
+	if !ok {
+		return BlockchairTxDashboard{}, 0, errors.ErrTxNotFound
+	}
+	if tx.Transaction.Hash != txHash {
+		return BlockchairTxDashboard{}, 0, fmt.Errorf("failed to get tx: got: %s required: %s", tx.Transaction.Hash, txHash)
+	}
+	return tx, height, nil
+}
+
+func (client blockchairClient) Confirmations(txHash string) (int64, error) {
+	tx, height, err := client.GetTx(txHash)
+	if err != nil {
+		return 0, err
+	}
+	return blockchairConfirmations(tx.Transaction.BlockID, height), nil
+}
+
+// GetUTXO returns the utxo, including outputs of transactions in the mempool.
+// ErrUTXOSpent is returned if the output is spent, or does not exist.
+func (client blockchairClient) GetUTXO(txhash string, vout uint32) (UTXO, error) {
+	tx, height, err := client.GetTx(txhash)
+	if err != nil {
+		if err == errors.ErrTxNotFound {
+			return UTXO{}, errors.ErrUTXOSpent
+		}
+		return UTXO{}, err
+	}
+	for _, output := range tx.Outputs {
+		if output.Index != vout {
+			continue
+		}
+		if output.IsSpent {
+			return UTXO{}, errors.ErrUTXOSpent
+		}
+		if err := validateOutput(txhash, output.ScriptHex); err != nil {
+			return UTXO{}, err
+		}
+		return UTXO{
+			TxHash:        txhash,
+			Amount:        output.Value,
+			ScriptPubKey:  output.ScriptHex,
+			Vout:          vout,
+			Confirmations: blockchairConfirmations(tx.Transaction.BlockID, height),
+			Address:       output.Recipient,
+		}, nil
+	}
+	return UTXO{}, errors.ErrUTXOSpent
+}
+
+// Spender returns the hash of the transaction spending the output, including
+// transactions in the mempool. ErrTxNotFound is returned if the output is
+// unspent, or does not exist.
+func (client blockchairClient) Spender(txhash string, vout uint32) (string, error) {
+	tx, _, err := client.GetTx(txhash)
+	if err != nil {
+		return "", err
+	}
+	for _, output := range tx.Outputs {
+		if output.Index == vout && output.IsSpent && output.SpendingTransactionHash != "" {
+			return output.SpendingTransactionHash, nil
+		}
+	}
+	return "", errors.ErrTxNotFound
+}
+
+// AddressBalance returns the balance of the address, including the outputs
+// of transactions in the mempool. Blockchair does not report the balance at a
+// given depth, so ErrNotSupported is returned if confirmations are required.
+func (client blockchairClient) AddressBalance(address string, confirmations int64) (int64, error) {
+	if confirmations > 0 {
+		return 0, errors.ErrNotSupported
+	}
+	dashboard, _, err := client.addressDashboard(address, 0, 0, 0, 0)
+	if err != nil {
+		return 0, err
+	}
+	return dashboard.Address.Balance, nil
+}
+
+// AddressUTXOCount returns the number of utxos of the address, including the
+// outputs of transactions in the mempool. ErrNotSupported is returned if
+// confirmations are required.
+func (client blockchairClient) AddressUTXOCount(address string, confirmations int64) (int, error) {
+	if confirmations > 0 {
+		return 0, errors.ErrNotSupported
+	}
+	dashboard, _, err := client.addressDashboard(address, 0, 0, 0, 0)
+	if err != nil {
+		return 0, err
+	}
+	return dashboard.Address.UnspentOutputCount, nil
+}
+
+func (client blockchairClient) BlockHeight() (int64, error) {
+	stats := blockchairStats{}
+	if _, err := client.get("/stats", nil, "failed to get stats", &stats); err != nil {
+		return 0, err
+	}
+	return stats.BestBlockHeight, nil
+}
+
+func (client blockchairClient) ScriptFunded(address string, value int64) (bool, int64, error) {
+	dashboard, _, err := client.addressDashboard(address, 0, 0, 0, 0)
+	if err != nil {
+		return false, 0, err
+	}
+	return dashboard.Address.Received >= value, dashboard.Address.Balance, nil
+}
+
+func (client blockchairClient) ScriptRedeemed(address string, value int64) (bool, int64, error) {
+	dashboard, _, err := client.addressDashboard(address, 0, 0, 0, 0)
+	if err != nil {
+		return false, 0, err
+	}
+	balance := dashboard.Address.Balance
+	return dashboard.Address.Received >= value && balance == 0, balance, nil
+}
+
+// ScriptSpent checks whether an output of the script address is spent by a
+// transaction paying the spender, and returns the signature script of the
+// spending input.
+func (client blockchairClient) ScriptSpent(script, spender string) (bool, string, error) {
+	txHashes, err := client.addressTxs(script)
+	if err != nil {
+		return false, "", err
+	}
+	txs, _, err := client.GetTxs(txHashes)
+	if err != nil {
+		return false, "", err
+	}
+	for _, txHash := range txHashes {
+		tx, ok := txs[txHash]
+		if !ok {
+			continue
+		}
+		paysSpender := false
+		for _, output := range tx.Outputs {
+			if output.Recipient == spender {
+				paysSpender = true
+				break
+			}
+		}
+		if !paysSpender {
+			continue
+		}
+		for _, input := range tx.Inputs {
+			if input.Recipient == script {
+				return true, input.SpendingSignatureHex, nil
+			}
+		}
+	}
+	return false, "", nil
+}
+
+func (client blockchairClient) PublishTransaction(stx []byte) error {
+	getLogger().WithField("size", len(stx)).Debugf("publishing transaction to blockchair")
+
+	form := url.Values{}
+	form.Set("data", hex.EncodeToString(stx))
+	resp := struct {
+		TransactionHash string `json:"transaction_hash"`
+	}{}
+	_, err := client.post("/push/transaction", form, "failed to publish transaction", &resp)
+	if bcErr, ok := err.(*blockchairError); ok && bcErr.status == http.StatusBadRequest {
+		return errors.NewErrZCashSubmitTx(bcErr.message)
+	}
+	return err
+}
+
+func (client blockchairClient) Ping(ctx context.Context) error {
+	return pingRequest(ctx, client.httpConfig, client.url("/stats", nil))
+}
+
+func (client blockchairClient) Capabilities() Capabilities {
+	return Capabilities{
+		Mempool:        true,
+		TxLookup:       true,
+		BlockHeight:    true,
+		AddressBalance: true,
+		UTXOCount:      true,
+		ScriptSpent:    true,
+	}
+}
+
+// url returns the url of the endpoint, authenticated with the API key of the
+// client if it has one.
+func (client blockchairClient) url(path string, query url.Values) string {
+	if client.apiKey != "" {
+		if query == nil {
+			query = url.Values{}
+		}
+		query.Set("key", client.apiKey)
+	}
+	if len(query) == 0 {
+		return client.URL + path
+	}
+	return client.URL + path + "?" + query.Encode()
+}
+
+// get fetches the endpoint, decodes the data of the response into result, and
+// returns the height of the best block. The description prefixes the error if
+// the request fails.
+func (client blockchairClient) get(path string, query url.Values, description string, result interface{}) (int64, error) {
+	if err := client.limiter.wait(); err != nil {
+		return 0, err
+	}
+	var height int64
+	err := getRequest(client.httpConfig, client.url(path, query), func(resp *http.Response) (err error) {
+		height, err = client.handle(resp, description, result)
+		return err
+	})
+	return height, err
+}
+
+func (client blockchairClient) post(path string, form url.Values, description string, result interface{}) (int64, error) {
+	if err := client.limiter.wait(); err != nil {
+		return 0, err
+	}
+	var height int64
+	body := bytes.NewBufferString(form.Encode())
+	err := postRequest(client.httpConfig, client.url(path, nil), "application/x-www-form-urlencoded", body, func(resp *http.Response) (err error) {
+		height, err = client.handle(resp, description, result)
+		return err
+	})
+	return height, err
+}
+
+// handle decodes a response of Blockchair. Rate limited requests stop the
+// client until Blockchair allows requests again.
+func (client blockchairClient) handle(resp *http.Response, description string, result interface{}) (int64, error) {
+	respBytes, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return 0, err
+	}
+	bcResp := blockchairResponse{}
+	if err := json.Unmarshal(respBytes, &bcResp); err != nil && resp.StatusCode == http.StatusOK {
+		return 0, err
+	}
+
+	if blockchairRateLimited[resp.StatusCode] {
+		retryAt := time.Now().Add(blockchairBackoff)
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds >= 0 {
+			retryAt = time.Now().Add(time.Duration(seconds) * time.Second)
+		}
+		client.limiter.backoff(retryAt)
+		return 0, &errors.BackendUnavailableError{
+			RetryAt: retryAt,
+			Err:     &blockchairError{description, resp.StatusCode, bcResp.Context.Error},
+		}
+	}
+	if resp.StatusCode != http.StatusOK {
+		message := bcResp.Context.Error
+		if message == "" {
+			message = string(respBytes)
+		}
+		return 0, &blockchairError{description, resp.StatusCode, message}
+	}
+
+	// Blockchair answers with an empty array instead of an empty object
+	// when none of the requested items exist.
+	if len(bcResp.Data) == 0 || string(bcResp.Data) == "[]" || string(bcResp.Data) == "null" {
+		return 0, &blockchairError{description, http.StatusNotFound, "no data"}
+	}
+	return bcResp.Context.State, json.Unmarshal(bcResp.Data, result)
+}
+
+// blockchairConfirmations returns the confirmations of a transaction mined in
+// the given block, which is -1 for transactions in the mempool.
+func blockchairConfirmations(blockID, height int64) int64 {
+	if blockID < 0 || height < blockID {
+		return 0
+	}
+	return height - blockID + 1
+}
+
+// rateLimiter spaces the requests of a client, limits the number of requests
+// per day, and stops them while the backend rate limits the client. A quota
+// of 0 does not limit the number of requests per day.
+type rateLimiter struct {
+	mu       *sync.Mutex
+	interval time.Duration
+	next     time.Time
+	retryAt  time.Time
+
+	quota    int
+	dayStart time.Time
+	dayCount int
+}
+
+func newRateLimiter(interval time.Duration, quota int) *rateLimiter {
+	return &rateLimiter{mu: new(sync.Mutex), interval: interval, quota: quota}
+}
+
+// wait blocks until the next request can be sent, and returns a
+// BackendUnavailableError if the backend rate limits the client.
+func (limiter *rateLimiter) wait() error {
+	limiter.mu.Lock()
+	now := time.Now()
+	if now.Before(limiter.retryAt) {
+		retryAt := limiter.retryAt
+		limiter.mu.Unlock()
+		return &errors.BackendUnavailableError{RetryAt: retryAt, Err: fmt.Errorf("rate limited")}
+	}
+	if limiter.quota > 0 {
+		if now.Sub(limiter.dayStart) >= 24*time.Hour {
+			limiter.dayStart, limiter.dayCount = now, 0
+		}
+		if limiter.dayCount >= limiter.quota {
+			retryAt := limiter.dayStart.Add(24 * time.Hour)
+			limiter.mu.Unlock()
+			return &errors.BackendUnavailableError{RetryAt: retryAt, Err: fmt.Errorf("daily quota of %d requests exceeded", limiter.quota)}
+		}
+		limiter.dayCount++
+	}
+	delay := limiter.next.Sub(now)
+	if delay < 0 {
+		delay = 0
+	}
+	limiter.next = now.Add(delay + limiter.interval)
+	limiter.mu.Unlock()
+
+	time.Sleep(delay)
+	return nil
+}
+
+// backoff stops the requests until the given time.
+func (limiter *rateLimiter) backoff(retryAt time.Time) {
+	limiter.mu.Lock()
+	defer limiter.mu.Unlock()
+	if retryAt.After(limiter.retryAt) {
+		limiter.retryAt = retryAt
+	}
+}
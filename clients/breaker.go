@@ -0,0 +1,178 @@
+package clients
+
+import (
+	"context"
+	goerrors "errors"
+	"sync"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/renproject/libzec-go/errors"
+)
+
+type circuitBreaker struct {
+	core      ClientCore
+	threshold int
+	cooldown  time.Duration
+
+	mu        *sync.Mutex
+	failures  int
+	openUntil time.Time
+	lastErr   error
+}
+
+// NewCircuitBreaker wraps the client core with a circuit breaker. The breaker
+// opens after threshold consecutive failures of the backend, and then fails
+// every call with a BackendUnavailableError for the cooldown period. Once the
+// cooldown has passed calls go through again, and the first failure reopens
+// the breaker. Errors that are valid answers of the backend, such as
+// ErrTxNotFound or a rejected transaction, are not failures.
+func NewCircuitBreaker(core ClientCore, threshold int, cooldown time.Duration) ClientCore {
+	if threshold < 1 {
+		threshold = 1
+	}
+	return &circuitBreaker{
+		core:      core,
+		threshold: threshold,
+		cooldown:  cooldown,
+		mu:        new(sync.Mutex),
+	}
+}
+
+// Unwrap returns the client core behind the breaker. Calls to the extensions
+// of the core, such as BatchClient, do not go through the breaker.
+func (breaker *circuitBreaker) Unwrap() ClientCore {
+	return breaker.core
+}
+
+// call calls f unless the breaker is open, and records its outcome.
+func (breaker *circuitBreaker) call(f func() error) error {
+	breaker.mu.Lock()
+	if time.Now().Before(breaker.openUntil) {
+		err := &errors.BackendUnavailableError{RetryAt: breaker.openUntil, Err: breaker.lastErr}
+		breaker.mu.Unlock()
+		return err
+	}
+	breaker.mu.Unlock()
+
+	err := f()
+
+	breaker.mu.Lock()
+	defer breaker.mu.Unlock()
+	if !isBackendFailure(err) {
+		breaker.failures = 0
+		return err
+	}
+	breaker.failures++
+	breaker.lastErr = err
+	if breaker.failures >= breaker.threshold {
+		breaker.openUntil = time.Now().Add(breaker.cooldown)
+	}
+	return err
+}
+
+// isBackendFailure returns whether the error means that the backend could not
+// answer, rather than an answer of the backend.
+func isBackendFailure(err error) bool {
+	if err == nil {
+		return false
+	}
+	for _, answer := range []error{errors.ErrNotSupported, errors.ErrTxNotFound, errors.ErrUTXOSpent, errors.ErrBroadcast, errors.ErrNegativeLimit, errors.ErrNegativeConfirmations} {
+		if goerrors.Is(err, answer) {
+			return false
+		}
+	}
+	return true
+}
+
+func (breaker *circuitBreaker) NetworkParams() *chaincfg.Params {
+	return breaker.core.NetworkParams()
+}
+
+func (breaker *circuitBreaker) GetUTXO(txhash string, vout uint32) (utxo UTXO, err error) {
+	err = breaker.call(func() (err error) {
+		utxo, err = breaker.core.GetUTXO(txhash, vout)
+		return
+	})
+	return
+}
+
+func (breaker *circuitBreaker) GetUTXOs(address string, limit, confirmations int64) (utxos []UTXO, err error) {
+	err = breaker.call(func() (err error) {
+		utxos, err = breaker.core.GetUTXOs(address, limit, confirmations)
+		return
+	})
+	return
+}
+
+func (breaker *circuitBreaker) AddressBalance(address string, confirmations int64) (balance int64, err error) {
+	err = breaker.call(func() (err error) {
+		balance, err = breaker.core.AddressBalance(address, confirmations)
+		return
+	})
+	return
+}
+
+func (breaker *circuitBreaker) AddressUTXOCount(address string, confirmations int64) (count int, err error) {
+	err = breaker.call(func() (err error) {
+		count, err = breaker.core.AddressUTXOCount(address, confirmations)
+		return
+	})
+	return
+}
+
+func (breaker *circuitBreaker) Confirmations(txHash string) (conf int64, err error) {
+	err = breaker.call(func() (err error) {
+		conf, err = breaker.core.Confirmations(txHash)
+		return
+	})
+	return
+}
+
+func (breaker *circuitBreaker) BlockHeight() (height int64, err error) {
+	err = breaker.call(func() (err error) {
+		height, err = breaker.core.BlockHeight()
+		return
+	})
+	return
+}
+
+func (breaker *circuitBreaker) ScriptFunded(address string, value int64) (funded bool, balance int64, err error) {
+	err = breaker.call(func() (err error) {
+		funded, balance, err = breaker.core.ScriptFunded(address, value)
+		return
+	})
+	return
+}
+
+func (breaker *circuitBreaker) ScriptRedeemed(address string, value int64) (redeemed bool, balance int64, err error) {
+	err = breaker.call(func() (err error) {
+		redeemed, balance, err = breaker.core.ScriptRedeemed(address, value)
+		return
+	})
+	return
+}
+
+func (breaker *circuitBreaker) ScriptSpent(script, spender string) (spent bool, sigScript string, err error) {
+	err = breaker.call(func() (err error) {
+		spent, sigScript, err = breaker.core.ScriptSpent(script, spender)
+		return
+	})
+	return
+}
+
+func (breaker *circuitBreaker) PublishTransaction(stx []byte) error {
+	return breaker.call(func() error {
+		return breaker.core.PublishTransaction(stx)
+	})
+}
+
+func (breaker *circuitBreaker) Ping(ctx context.Context) error {
+	return breaker.call(func() error {
+		return breaker.core.Ping(ctx)
+	})
+}
+
+func (breaker *circuitBreaker) Capabilities() Capabilities {
+	return breaker.core.Capabilities()
+}
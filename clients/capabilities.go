@@ -0,0 +1,37 @@
+package clients
+
+// Capabilities describes the optional features of a client core, so that
+// wrappers and higher level features can route calls to the backends that
+// support them instead of relying on ErrNotSupported.
+type Capabilities struct {
+	// Mempool is whether unconfirmed utxos are returned when querying utxos
+	// without confirmations.
+	Mempool bool `json:"mempool"`
+
+	// TxLookup is whether Confirmations and GetUTXO can look up transactions.
+	TxLookup bool `json:"txLookup"`
+
+	// BlockHeight is whether BlockHeight is supported.
+	BlockHeight bool `json:"blockHeight"`
+
+	// AddressBalance is whether AddressBalance is supported.
+	AddressBalance bool `json:"addressBalance"`
+
+	// UTXOCount is whether AddressUTXOCount is supported.
+	UTXOCount bool `json:"utxoCount"`
+
+	// ScriptSpent is whether ScriptSpent is supported.
+	ScriptSpent bool `json:"scriptSpent"`
+
+	// History is whether the core implements AddressHistory.
+	History bool `json:"history"`
+
+	// FeeEstimation is whether the backend estimates fee rates.
+	FeeEstimation bool `json:"feeEstimation"`
+
+	// Batch is whether the core implements BatchClient.
+	Batch bool `json:"batch"`
+
+	// Wallet is whether the core implements ZcashdWallet.
+	Wallet bool `json:"wallet"`
+}
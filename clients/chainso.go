@@ -2,38 +2,47 @@ package clients
 
 import (
 	"bytes"
+	"context"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"math/big"
 	"net/http"
-	"strings"
 
 	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/renproject/libzec-go/errors"
 )
 
 type chainSoClient struct {
-	token  string
-	URL    string
-	params *chaincfg.Params
+	token      string
+	URL        string
+	params     *chaincfg.Params
+	httpConfig *httpConfig
 }
 
-func NewChainSoClientCore(network string) (ClientCore, error) {
+func NewChainSoClientCore(network string, options ...Option) (ClientCore, error) {
+	httpConfig, err := applyOptions(options)
+	if err != nil {
+		return nil, err
+	}
 	client := &chainSoClient{
-		URL: "https://chain.so/api/v2",
+		URL:        "https://chain.so/api/v2",
+		httpConfig: httpConfig,
+	}
+	net, err := ParseNetwork(network)
+	if err != nil {
+		return nil, err
 	}
-	network = strings.ToLower(network)
-	switch network {
-	case "mainnet":
+	switch net {
+	case Mainnet:
 		client.token = "ZEC"
-		client.params = &chaincfg.MainNetParams
-	case "testnet", "testnet3", "":
+	case Testnet:
 		client.token = "ZECTEST"
-		client.params = &chaincfg.TestNet3Params
 	default:
-		return nil, fmt.Errorf("unsupported network: %s", network)
+		return nil, errors.NewErrUnsupportedNetwork(network)
 	}
+	client.params = net.Params()
 	return client, nil
 }
 
@@ -56,6 +65,58 @@ type Tx struct {
 	Confirmations int64  `json:"confirmations"`
 }
 
+type AddressBalance struct {
+	ConfirmedBalance   string `json:"confirmed_balance"`
+	UnconfirmedBalance string `json:"unconfirmed_balance"`
+}
+
+type ChainInfo struct {
+	Blocks int64 `json:"blocks"`
+}
+
+// ChainSoTx is a transaction returned by the get_tx endpoint of chain.so.
+type ChainSoTx struct {
+	TxID          string          `json:"txid"`
+	Confirmations int64           `json:"confirmations"`
+	Inputs        []ChainSoInput  `json:"inputs"`
+	Outputs       []ChainSoOutput `json:"outputs"`
+}
+
+type ChainSoInput struct {
+	InputNo   uint32 `json:"input_no"`
+	Address   string `json:"address"`
+	ScriptHex string `json:"script_hex"`
+}
+
+type ChainSoOutput struct {
+	OutputNo  uint32        `json:"output_no"`
+	Address   string        `json:"address"`
+	Value     string        `json:"value"`
+	ScriptHex string        `json:"script_hex"`
+	Spent     *ChainSoSpent `json:"spent"`
+}
+
+// ChainSoSpent is the input that spends an output.
+type ChainSoSpent struct {
+	TxID    string `json:"txid"`
+	InputNo uint32 `json:"input_no"`
+}
+
+// chainSoPageSize is the number of transactions returned by a page of the
+// endpoints that list the transactions of an address.
+const chainSoPageSize = 100
+
+// chainSoError is returned when chain.so answers with an error status.
+type chainSoError struct {
+	description string
+	status      int
+	body        []byte
+}
+
+func (err *chainSoError) Error() string {
+	return fmt.Sprintf("%s: %s", err.description, err.body)
+}
+
 type RawAddress struct {
 	Balance  string `json:"balance"`
 	Received string `json:"received_value"`
@@ -67,6 +128,9 @@ func (client chainSoClient) NetworkParams() *chaincfg.Params {
 }
 
 func (client chainSoClient) GetUTXOs(address string, limit, confitmations int64) ([]UTXO, error) {
+	if err := CheckUTXOQuery(limit, confitmations); err != nil {
+		return nil, err
+	}
 	unspent, err := client.GetUnspentOutputs(address)
 	if err != nil {
 		return nil, err
@@ -79,23 +143,25 @@ func (client chainSoClient) GetUTXOs(address string, limit, confitmations int64)
 			if err != nil {
 				return nil, fmt.Errorf("unable to convert %s into sat: %v", output.Value, err)
 			}
+			if err := validateOutput(output.ID, output.ScriptHex); err != nil {
+				return nil, err
+			}
 
 			utxos = append(utxos, UTXO{
-				TxHash:       output.ID,
-				Amount:       amount,
-				ScriptPubKey: output.ScriptHex,
-				Vout:         output.OutNo,
+				TxHash:        output.ID,
+				Amount:        amount,
+				ScriptPubKey:  output.ScriptHex,
+				Vout:          output.OutNo,
+				Confirmations: output.Confirmations,
+				Address:       address,
 			})
 		}
 	}
-	if len(utxos) > int(limit) {
-		return utxos[:limit], nil
-	}
-	return utxos, nil
+	return LimitUTXOs(utxos, limit), nil
 }
 
 func (client chainSoClient) balance(address string, confirmations int64) (int64, error) {
-	utxos, err := client.GetUTXOs(address, 999999, confirmations)
+	utxos, err := client.GetUTXOs(address, 0, confirmations)
 	if err != nil {
 		return 0, nil
 	}
@@ -106,28 +172,94 @@ func (client chainSoClient) balance(address string, confirmations int64) (int64,
 	return balance, err
 }
 
+// GetUnspentOutputs returns the unspent outputs of the address. chain.so
+// returns at most 100 outputs per request, so the pages are fetched until a
+// page is not full.
 func (client chainSoClient) GetUnspentOutputs(address string) (UnspentTxResponse, error) {
-	utxos := UnspentTxResponse{}
-	csoResp := ChainSoResponse{}
-	resp, err := http.Get(fmt.Sprintf("%s/get_tx_unspent/%s/%s", client.URL, client.token, address))
-	if err != nil {
-		return utxos, err
+	return client.addressTxs("get_tx_unspent", address, "failed to get unspent txs")
+}
+
+// GetReceivedOutputs returns the outputs received by the address, spent or
+// not.
+func (client chainSoClient) GetReceivedOutputs(address string) (UnspentTxResponse, error) {
+	return client.addressTxs("get_tx_received", address, "failed to get received txs")
+}
+
+// addressTxs fetches every page of an endpoint listing the transactions of an
+// address. The next page starts after the last transaction of the previous
+// one.
+func (client chainSoClient) addressTxs(endpoint, address, description string) (UnspentTxResponse, error) {
+	all := UnspentTxResponse{Network: client.token, Address: address, Txs: []Tx{}}
+	after := ""
+	for {
+		url := fmt.Sprintf("%s/%s/%s/%s", client.URL, endpoint, client.token, address)
+		if after != "" {
+			url += "/" + after
+		}
+		page := UnspentTxResponse{}
+		if err := client.get(url, description, &page); err != nil {
+			return UnspentTxResponse{}, err
+		}
+		if page.Network != client.token || page.Address != address {
+			return UnspentTxResponse{}, fmt.Errorf("%s: unexpected response for %s on %s", description, page.Address, page.Network)
+		}
+		all.Txs = append(all.Txs, page.Txs...)
+		if len(page.Txs) < chainSoPageSize || page.Txs[len(page.Txs)-1].ID == after {
+			return all, nil
+		}
+		after = page.Txs[len(page.Txs)-1].ID
 	}
-	defer resp.Body.Close()
+}
 
-	respBytes, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return utxos, err
+// GetTx returns the transaction, and ErrTxNotFound if chain.so does not know
+// it.
+func (client chainSoClient) GetTx(txHash string) (ChainSoTx, error) {
+	tx := ChainSoTx{}
+	if err := client.get(fmt.Sprintf("%s/get_tx/%s/%s", client.URL, client.token, txHash), "failed to get tx", &tx); err != nil {
+		if csoErr, ok := err.(*chainSoError); ok && csoErr.status == http.StatusNotFound {
+			return ChainSoTx{}, errors.ErrTxNotFound
+		}
+		return ChainSoTx{}, err
 	}
-	if resp.StatusCode != http.StatusOK {
-		return utxos, fmt.Errorf("failed to get unspent txs: %s", respBytes)
+	if tx.TxID != txHash {
+		return ChainSoTx{}, fmt.Errorf("failed to get tx: got: %s required: %s", tx.TxID, txHash)
 	}
+	return tx, nil
+}
 
-	if err := json.Unmarshal(respBytes, &csoResp); err != nil {
-		return utxos, err
+// validateOutput checks the hash of the transaction and the script of an
+// output returned by chain.so.
+func validateOutput(txHash, scriptHex string) error {
+	if hash, err := hex.DecodeString(txHash); err != nil || len(hash) != 32 {
+		return fmt.Errorf("invalid tx hash %q", txHash)
+	}
+	if script, err := hex.DecodeString(scriptHex); err != nil || len(script) == 0 {
+		return fmt.Errorf("invalid script %q", scriptHex)
 	}
+	return nil
+}
 
-	return utxos, json.Unmarshal(csoResp.Data, &utxos)
+// get fetches the url, and decodes the data of the chain.so response into
+// result. The description prefixes the error if the request fails.
+func (client chainSoClient) get(url, description string, result interface{}) error {
+	return getRequest(client.httpConfig, url, func(resp *http.Response) error {
+		respBytes, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			return err
+		}
+		if resp.StatusCode != http.StatusOK {
+			return &chainSoError{description, resp.StatusCode, respBytes}
+		}
+
+		csoResp := ChainSoResponse{}
+		if err := json.Unmarshal(respBytes, &csoResp); err != nil {
+			return err
+		}
+		if csoResp.Status != "success" || len(csoResp.Data) == 0 {
+			return &chainSoError{description, resp.StatusCode, respBytes}
+		}
+		return json.Unmarshal(csoResp.Data, result)
+	})
 }
 
 func (client chainSoClient) PublishTransaction(stx []byte) error {
@@ -137,68 +269,163 @@ func (client chainSoClient) PublishTransaction(stx []byte) error {
 		TxHex: hex.EncodeToString(stx),
 	}
 
-	fmt.Println(hex.EncodeToString(stx))
+	getLogger().WithField("size", len(stx)).Debugf("publishing transaction to chain.so")
 
 	buf := new(bytes.Buffer)
 	if err := json.NewEncoder(buf).Encode(txObj); err != nil {
 		return err
 	}
 
-	resp, err := http.Post(fmt.Sprintf("%s/send_tx/%s", client.URL, client.token), "application/json", buf)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
+	return postRequest(client.httpConfig, fmt.Sprintf("%s/send_tx/%s", client.URL, client.token), "application/json", buf, func(resp *http.Response) error {
+		respBytes, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			return err
+		}
+		if resp.StatusCode != http.StatusOK {
+			getLogger().WithField("status", resp.StatusCode).Debugf("failed to publish transaction: %s", respBytes)
+			return errors.NewErrZCashSubmitTx(string(respBytes))
+		}
+		return nil
+	})
+}
 
-	respBytes, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return err
-	}
-	if resp.StatusCode != http.StatusOK {
-		fmt.Println(fmt.Errorf("failed to publish transaction txs: %s", respBytes))
-		return fmt.Errorf("failed to publish transaction txs: %s", respBytes)
+func (client chainSoClient) Ping(ctx context.Context) error {
+	return pingRequest(ctx, client.httpConfig, fmt.Sprintf("%s/get_info/%s", client.URL, client.token))
+}
+
+func (client chainSoClient) Capabilities() Capabilities {
+	return Capabilities{
+		Mempool:        true,
+		TxLookup:       true,
+		BlockHeight:    true,
+		AddressBalance: true,
+		ScriptSpent:    true,
 	}
-	return nil
 }
 
 func (client chainSoClient) Health() bool {
 	return true
 }
 
-func (client chainSoClient) Confirmations(txHashStr string) (int64, error) {
-	return 0, fmt.Errorf("TODO: chain.so api doesnot support confirmations")
+func (client chainSoClient) Confirmations(txHash string) (int64, error) {
+	tx, err := client.GetTx(txHash)
+	if err != nil {
+		return 0, err
+	}
+	return tx.Confirmations, nil
 }
 
-func (client chainSoClient) GetUTXO(txhash string, vout uint32) (UTXO, error) {
-	panic("unimplemented")
+// AddressBalance returns the confirmed balance reported by chain.so, which
+// only counts the outputs with at least the given number of confirmations.
+func (client chainSoClient) AddressBalance(address string, confirmations int64) (int64, error) {
+	balance := AddressBalance{}
+	if err := client.get(fmt.Sprintf("%s/get_address_balance/%s/%s/%d", client.URL, client.token, address, confirmations), "failed to get address balance", &balance); err != nil {
+		return 0, err
+	}
+	amount, err := strToInt(balance.ConfirmedBalance)
+	if err != nil {
+		return 0, fmt.Errorf("unable to convert %s into sat: %v", balance.ConfirmedBalance, err)
+	}
+	return amount, nil
 }
 
-func (client chainSoClient) GetRawAddressInformation(addr string) (RawAddress, error) {
-	addressInfo := RawAddress{}
-	csoResp := ChainSoResponse{}
-	resp, err := http.Get(fmt.Sprintf("%s/address/%s/%s", client.URL, client.token, addr))
-	if err != nil {
-		return addressInfo, err
+func (client chainSoClient) AddressUTXOCount(address string, confirmations int64) (int, error) {
+	return 0, errors.ErrNotSupported
+}
+
+func (client chainSoClient) BlockHeight() (int64, error) {
+	info := ChainInfo{}
+	if err := client.get(fmt.Sprintf("%s/get_info/%s", client.URL, client.token), "failed to get chain info", &info); err != nil {
+		return 0, err
 	}
-	defer resp.Body.Close()
+	return info.Blocks, nil
+}
 
-	respBytes, err := ioutil.ReadAll(resp.Body)
+// GetUTXO returns the utxo, including outputs of transactions in the mempool.
+// ErrUTXOSpent is returned if the output is spent, or does not exist.
+func (client chainSoClient) GetUTXO(txhash string, vout uint32) (UTXO, error) {
+	tx, err := client.GetTx(txhash)
 	if err != nil {
-		return addressInfo, err
+		if err == errors.ErrTxNotFound {
+			return UTXO{}, errors.ErrUTXOSpent
+		}
+		return UTXO{}, err
+	}
+	if int(vout) >= len(tx.Outputs) || tx.Outputs[vout].Spent != nil {
+		return UTXO{}, errors.ErrUTXOSpent
+	}
+	output := tx.Outputs[vout]
+	if output.OutputNo != vout {
+		return UTXO{}, fmt.Errorf("failed to get utxo: got: output %d required: output %d", output.OutputNo, vout)
 	}
-	if resp.StatusCode != http.StatusOK {
-		return addressInfo, fmt.Errorf("failed to get unspent txs: %s", respBytes)
+	if err := validateOutput(tx.TxID, output.ScriptHex); err != nil {
+		return UTXO{}, err
 	}
+	amount, err := strToInt(output.Value)
+	if err != nil {
+		return UTXO{}, fmt.Errorf("unable to convert %s into sat: %v", output.Value, err)
+	}
+	return UTXO{
+		TxHash:        txhash,
+		Amount:        amount,
+		ScriptPubKey:  output.ScriptHex,
+		Vout:          vout,
+		Confirmations: tx.Confirmations,
+		Address:       output.Address,
+	}, nil
+}
 
-	if err := json.Unmarshal(respBytes, &csoResp); err != nil {
-		return addressInfo, err
+// Spender returns the hash of the transaction spending the output, including
+// transactions in the mempool. ErrTxNotFound is returned if the output is
+// unspent, or does not exist.
+func (client chainSoClient) Spender(txhash string, vout uint32) (string, error) {
+	tx, err := client.GetTx(txhash)
+	if err != nil {
+		return "", err
+	}
+	if int(vout) >= len(tx.Outputs) || tx.Outputs[vout].Spent == nil {
+		return "", errors.ErrTxNotFound
 	}
+	return tx.Outputs[vout].Spent.TxID, nil
+}
 
-	return addressInfo, json.Unmarshal(csoResp.Data, &addressInfo)
+func (client chainSoClient) GetRawAddressInformation(addr string) (RawAddress, error) {
+	addressInfo := RawAddress{}
+	err := client.get(fmt.Sprintf("%s/address/%s/%s", client.URL, client.token, addr), "failed to get unspent txs", &addressInfo)
+	return addressInfo, err
 }
 
+// ScriptSpent checks whether an output received by the script address is spent
+// by a transaction paying the spender, and returns the signature script of the
+// spending input.
 func (client chainSoClient) ScriptSpent(script, spender string) (bool, string, error) {
-	return false, "", fmt.Errorf("TODO: chain.so api doesnot support omnilayer")
+	received, err := client.GetReceivedOutputs(script)
+	if err != nil {
+		return false, "", err
+	}
+	for _, output := range received.Txs {
+		tx, err := client.GetTx(output.ID)
+		if err != nil {
+			return false, "", err
+		}
+		if int(output.OutNo) >= len(tx.Outputs) || tx.Outputs[output.OutNo].Spent == nil {
+			continue
+		}
+		spent := tx.Outputs[output.OutNo].Spent
+		spendingTx, err := client.GetTx(spent.TxID)
+		if err != nil {
+			return false, "", err
+		}
+		if int(spent.InputNo) >= len(spendingTx.Inputs) {
+			return false, "", fmt.Errorf("invalid input %d of %s", spent.InputNo, spent.TxID)
+		}
+		for _, spendingOutput := range spendingTx.Outputs {
+			if spendingOutput.Address == spender {
+				return true, spendingTx.Inputs[spent.InputNo].ScriptHex, nil
+			}
+		}
+	}
+	return false, "", nil
 }
 
 func (client chainSoClient) ScriptFunded(address string, value int64) (bool, int64, error) {
@@ -1,14 +1,26 @@
 package clients
 
 import (
+	"context"
+
 	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/renproject/libzec-go/errors"
 )
 
+// UTXO is an unspent transaction output. The confirmations, block height,
+// address and coinbase flag are populated by the backends that report them:
+// the block height is zero if the utxo is unconfirmed or if the backend does
+// not report it.
 type UTXO struct {
 	TxHash       string `json:"txHash"`
 	Amount       int64  `json:"amount"`
 	ScriptPubKey string `json:"scriptPubKey"`
 	Vout         uint32 `json:"vout"`
+
+	Confirmations int64  `json:"confirmations,omitempty"`
+	BlockHeight   int64  `json:"blockHeight,omitempty"`
+	Address       string `json:"address,omitempty"`
+	Coinbase      bool   `json:"coinbase,omitempty"`
 }
 type ClientCore interface {
 	// NetworkParams should return the network parameters of the underlying
@@ -16,9 +28,30 @@ type ClientCore interface {
 	NetworkParams() *chaincfg.Params
 
 	GetUTXO(txhash string, vout uint32) (UTXO, error)
+
+	// GetUTXOs returns at most limit utxos of the address with at least the
+	// given number of confirmations. A limit of 0 returns every utxo, and
+	// unconfirmed utxos are included if confirmations is 0.
+	// ErrNegativeLimit and ErrNegativeConfirmations are returned for negative
+	// arguments, without querying the backend.
 	GetUTXOs(address string, limit, confitmations int64) ([]UTXO, error)
+
 	Confirmations(txHash string) (int64, error)
 
+	// AddressBalance returns the balance of the address, counting the utxos
+	// with at least the given number of confirmations. ErrNotSupported is
+	// returned if the backend cannot compute it without listing the utxos.
+	AddressBalance(address string, confirmations int64) (int64, error)
+
+	// AddressUTXOCount returns the number of utxos of the address with at
+	// least the given number of confirmations. ErrNotSupported is returned if
+	// the backend cannot count them without listing them.
+	AddressUTXOCount(address string, confirmations int64) (int, error)
+
+	// BlockHeight returns the height of the latest block of the ZCash
+	// blockchain.
+	BlockHeight() (int64, error)
+
 	// ScriptFunded checks whether a script is funded.
 	ScriptFunded(address string, value int64) (bool, int64, error)
 
@@ -31,4 +64,31 @@ type ClientCore interface {
 	// PublishTransaction should publish a signed transaction to the ZCash
 	// blockchain.
 	PublishTransaction(signedTransaction []byte) error
+
+	// Ping returns an error if the backend cannot be reached, or is not
+	// healthy.
+	Ping(ctx context.Context) error
+
+	// Capabilities returns the optional features supported by the backend.
+	Capabilities() Capabilities
+}
+
+// CheckUTXOQuery returns an error if the limit or confirmations of a GetUTXOs
+// call are negative.
+func CheckUTXOQuery(limit, confirmations int64) error {
+	if limit < 0 {
+		return errors.ErrNegativeLimit
+	}
+	if confirmations < 0 {
+		return errors.ErrNegativeConfirmations
+	}
+	return nil
+}
+
+// LimitUTXOs returns the first limit utxos, or every utxo if the limit is 0.
+func LimitUTXOs(utxos []UTXO, limit int64) []UTXO {
+	if limit > 0 && int64(len(utxos)) > limit {
+		return utxos[:limit]
+	}
+	return utxos
 }
@@ -0,0 +1,58 @@
+package clients
+
+// AddressHistory is implemented by the client cores that can list the
+// transactions of any address.
+type AddressHistory interface {
+	// AddressDeltas returns the change of the balance of the address caused
+	// by every transaction that spends from it or pays to it, in the order in
+	// which they were mined. Transactions of the mempool come last, with a
+	// block height of zero.
+	AddressDeltas(address string) ([]AddressDelta, error)
+}
+
+// AddressDelta is the change of the balance of an address caused by a
+// transaction. The amount is negative if the transaction spends more from the
+// address than it pays to it.
+type AddressDelta struct {
+	TxHash      string `json:"txHash"`
+	Amount      int64  `json:"amount"`
+	BlockHeight int64  `json:"blockHeight"`
+}
+
+type zcashdAddressDelta struct {
+	Satoshis int64  `json:"satoshis"`
+	TxID     string `json:"txid"`
+	Height   int64  `json:"height"`
+}
+
+type zcashdAddressQuery struct {
+	Addresses []string `json:"addresses"`
+}
+
+// AddressDeltas returns the deltas of the address using the address index of
+// the node, which must be run with -addressindex. The inputs and outputs of a
+// transaction are merged into a single delta.
+func (client *zcashdClient) AddressDeltas(address string) ([]AddressDelta, error) {
+	query := zcashdAddressQuery{[]string{address}}
+	mined := []zcashdAddressDelta{}
+	if err := client.call("getaddressdeltas", &mined, query); err != nil {
+		return nil, err
+	}
+	mempool := []zcashdAddressDelta{}
+	if err := client.call("getaddressmempool", &mempool, query); err != nil {
+		return nil, err
+	}
+
+	deltas := []AddressDelta{}
+	indices := map[string]int{}
+	for _, delta := range append(mined, mempool...) {
+		i, ok := indices[delta.TxID]
+		if !ok {
+			i = len(deltas)
+			indices[delta.TxID] = i
+			deltas = append(deltas, AddressDelta{TxHash: delta.TxID, BlockHeight: delta.Height})
+		}
+		deltas[i].Amount += delta.Satoshis
+	}
+	return deltas, nil
+}
@@ -0,0 +1,252 @@
+package clients
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// DefaultHTTPTimeout is the timeout of the requests sent by the default http
+// client.
+const DefaultHTTPTimeout = 30 * time.Second
+
+// maxDrainedBody is the number of bytes of a response body that are read
+// before it is closed, so that the connection can be reused.
+const maxDrainedBody = 64 << 10
+
+var (
+	httpClientMu = new(sync.RWMutex)
+	httpClient   = NewHTTPClient(DefaultHTTPTimeout)
+)
+
+// NewHTTPClient returns an http client that keeps connections to the backends
+// alive, and times out requests after the given duration.
+func NewHTTPClient(timeout time.Duration) *http.Client {
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: newTransport(http.ProxyFromEnvironment),
+	}
+}
+
+func newTransport(proxy func(*http.Request) (*url.URL, error)) *http.Transport {
+	return &http.Transport{
+		Proxy: proxy,
+		DialContext: (&net.Dialer{
+			Timeout:   10 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		MaxIdleConns:          100,
+		MaxIdleConnsPerHost:   16,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: time.Second,
+	}
+}
+
+// An Option configures the http requests of a client core. By default client
+// cores use the http client shared by all clients.
+type Option func(*coreOptions) error
+
+type coreOptions struct {
+	httpClient   *http.Client
+	roundTripper http.RoundTripper
+	proxy        *url.URL
+	tlsConfig    *tls.Config
+	header       http.Header
+}
+
+// WithHTTPClient makes the client core send its requests using the given http
+// client. The proxy and TLS options are ignored if it is set.
+func WithHTTPClient(client *http.Client) Option {
+	return func(options *coreOptions) error {
+		options.httpClient = client
+		return nil
+	}
+}
+
+// WithRoundTripper makes the client core send its requests using the given
+// round tripper, and the default timeout. The proxy and TLS options are
+// ignored if it is set.
+func WithRoundTripper(roundTripper http.RoundTripper) Option {
+	return func(options *coreOptions) error {
+		options.roundTripper = roundTripper
+		return nil
+	}
+}
+
+// WithProxy routes the requests of the client core through the proxy at the
+// given url. SOCKS5 proxies are supported, such as "socks5://127.0.0.1:9050"
+// for Tor: host names are resolved by the proxy, so that DNS requests do not
+// leak.
+func WithProxy(proxyURL string) Option {
+	return func(options *coreOptions) error {
+		u, err := url.Parse(proxyURL)
+		if err != nil {
+			return fmt.Errorf("invalid proxy url %s: %v", proxyURL, err)
+		}
+		switch u.Scheme {
+		case "socks5", "http", "https":
+		default:
+			return fmt.Errorf("unsupported proxy scheme: %s", u.Scheme)
+		}
+		options.proxy = u
+		return nil
+	}
+}
+
+// WithTLSConfig makes the client core use the given TLS configuration, for
+// example to trust the certificate authority of a private deployment or to
+// authenticate with a client certificate.
+func WithTLSConfig(config *tls.Config) Option {
+	return func(options *coreOptions) error {
+		options.tlsConfig = config
+		return nil
+	}
+}
+
+// WithBasicAuth authenticates the requests of the client core using HTTP basic
+// authentication. The credentials of a zcashd client are replaced by these,
+// which is required to use the rpcauth credentials of a node.
+func WithBasicAuth(user, password string) Option {
+	credentials := base64.StdEncoding.EncodeToString([]byte(user + ":" + password))
+	return WithHeader("Authorization", "Basic "+credentials)
+}
+
+// WithBearerToken authenticates the requests of the client core using the
+// given bearer token.
+func WithBearerToken(token string) Option {
+	return WithHeader("Authorization", "Bearer "+token)
+}
+
+// WithHeader sets a header on every request of the client core, such as the
+// API key of an explorer.
+func WithHeader(key, value string) Option {
+	return func(options *coreOptions) error {
+		if options.header == nil {
+			options.header = http.Header{}
+		}
+		options.header.Set(key, value)
+		return nil
+	}
+}
+
+// httpConfig is the http client and the headers used by a client core. A nil
+// httpConfig uses the shared http client, and no additional headers.
+type httpConfig struct {
+	client *http.Client
+	header http.Header
+}
+
+// applyOptions returns the http configuration of the options.
+func applyOptions(options []Option) (*httpConfig, error) {
+	coreOpts := coreOptions{}
+	for _, option := range options {
+		if err := option(&coreOpts); err != nil {
+			return nil, err
+		}
+	}
+
+	config := &httpConfig{client: coreOpts.httpClient, header: coreOpts.header}
+	switch {
+	case config.client != nil:
+	case coreOpts.roundTripper != nil:
+		config.client = &http.Client{
+			Timeout:   DefaultHTTPTimeout,
+			Transport: coreOpts.roundTripper,
+		}
+	case coreOpts.proxy != nil || coreOpts.tlsConfig != nil:
+		proxy := http.ProxyFromEnvironment
+		if coreOpts.proxy != nil {
+			proxy = http.ProxyURL(coreOpts.proxy)
+		}
+		transport := newTransport(proxy)
+		transport.TLSClientConfig = coreOpts.tlsConfig
+		config.client = &http.Client{
+			Timeout:   DefaultHTTPTimeout,
+			Transport: transport,
+		}
+	}
+	return config, nil
+}
+
+// HTTPClient returns the http client shared by the clients.
+func HTTPClient() *http.Client {
+	httpClientMu.RLock()
+	defer httpClientMu.RUnlock()
+	return httpClient
+}
+
+// SetHTTPClient replaces the http client shared by the clients, to configure
+// its timeouts, its transport or its proxy.
+func SetHTTPClient(client *http.Client) {
+	httpClientMu.Lock()
+	defer httpClientMu.Unlock()
+	httpClient = client
+}
+
+// doRequest sends the request using the http configuration of a client core,
+// and calls handle with the response. The body is drained and closed once
+// handle returns, so that the connection is returned to the pool.
+func doRequest(config *httpConfig, req *http.Request, handle func(resp *http.Response) error) error {
+	client := HTTPClient()
+	if config != nil {
+		if config.client != nil {
+			client = config.client
+		}
+		for key, values := range config.header {
+			req.Header[key] = values
+		}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer closeBody(resp.Body)
+	return handle(resp)
+}
+
+// pingRequest sends a GET request to the url, and returns an error if the
+// backend cannot be reached, or answers with a server error.
+func pingRequest(ctx context.Context, config *httpConfig, url string) error {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return err
+	}
+	return doRequest(config, req.WithContext(ctx), func(resp *http.Response) error {
+		if resp.StatusCode >= http.StatusInternalServerError {
+			return fmt.Errorf("backend is unhealthy (%d)", resp.StatusCode)
+		}
+		return nil
+	})
+}
+
+func getRequest(config *httpConfig, url string, handle func(resp *http.Response) error) error {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return err
+	}
+	return doRequest(config, req, handle)
+}
+
+func postRequest(config *httpConfig, url, contentType string, body io.Reader, handle func(resp *http.Response) error) error {
+	req, err := http.NewRequest("POST", url, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	return doRequest(config, req, handle)
+}
+
+// closeBody drains and closes the body of a response.
+func closeBody(body io.ReadCloser) {
+	io.Copy(ioutil.Discard, io.LimitReader(body, maxDrainedBody))
+	body.Close()
+}
@@ -0,0 +1,36 @@
+package clients
+
+import (
+	"io/ioutil"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	loggerMu = new(sync.RWMutex)
+	logger   = discardLogger()
+)
+
+// SetLogger sets the logger to which the clients write their diagnostics. By
+// default the diagnostics are discarded. Signed transactions are never logged.
+func SetLogger(l logrus.FieldLogger) {
+	loggerMu.Lock()
+	defer loggerMu.Unlock()
+	if l == nil {
+		l = discardLogger()
+	}
+	logger = l
+}
+
+func getLogger() logrus.FieldLogger {
+	loggerMu.RLock()
+	defer loggerMu.RUnlock()
+	return logger
+}
+
+func discardLogger() logrus.FieldLogger {
+	l := logrus.New()
+	l.SetOutput(ioutil.Discard)
+	return l
+}
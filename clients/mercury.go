@@ -2,11 +2,11 @@ package clients
 
 import (
 	"bytes"
+	"context"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"net/http"
-	"strings"
 
 	"github.com/btcsuite/btcd/chaincfg"
 	"github.com/renproject/libzec-go/errors"
@@ -14,22 +14,32 @@ import (
 )
 
 type mercuryClient struct {
-	URL    string
-	Params *chaincfg.Params
+	URL        string
+	Params     *chaincfg.Params
+	httpConfig *httpConfig
 }
 
-func NewMercuryClientCore(network string) (ClientCore, error) {
-	network = strings.ToLower(network)
-	switch network {
-	case "mainnet":
+func NewMercuryClientCore(network string, options ...Option) (ClientCore, error) {
+	httpConfig, err := applyOptions(options)
+	if err != nil {
+		return nil, err
+	}
+	net, err := ParseNetwork(network)
+	if err != nil {
+		return nil, err
+	}
+	switch net {
+	case Mainnet:
 		return &mercuryClient{
-			URL:    "http://139.59.221.34/zec",
-			Params: &chaincfg.MainNetParams,
+			URL:        "http://139.59.221.34/zec",
+			Params:     net.Params(),
+			httpConfig: httpConfig,
 		}, nil
-	case "testnet", "testnet3", "":
+	case Testnet:
 		return &mercuryClient{
-			URL:    "http://139.59.221.34/zec-testnet",
-			Params: &chaincfg.TestNet3Params,
+			URL:        "http://139.59.221.34/zec-testnet",
+			Params:     net.Params(),
+			httpConfig: httpConfig,
 		}, nil
 	default:
 		return nil, errors.NewErrUnsupportedNetwork(network)
@@ -41,39 +51,28 @@ func (client *mercuryClient) NetworkParams() *chaincfg.Params {
 }
 
 func (client *mercuryClient) GetUTXOs(address string, limit, confitmations int64) ([]UTXO, error) {
+	if err := CheckUTXOQuery(limit, confitmations); err != nil {
+		return nil, err
+	}
+	// The limit is omitted when every utxo is requested, as mercury
+	// interprets a limit of 0 literally.
+	url := fmt.Sprintf("%s/utxo/%s?confirmations=%d", client.URL, address, confitmations)
+	if limit > 0 {
+		url += fmt.Sprintf("&limit=%d", limit)
+	}
 	utxos := []UTXO{}
-	resp, err := http.Get(fmt.Sprintf("%s/utxo/%s?limit=%d&confirmations=%d", client.URL, address, limit, confitmations))
-	if err != nil || resp.StatusCode != http.StatusOK {
-		if err != nil {
-			return utxos, err
-		}
-		respErr := MercuryError{}
-		if err := json.NewDecoder(resp.Body).Decode(&respErr); err != nil {
-			return utxos, err
-		}
-		return utxos, fmt.Errorf("request failed with (%d): %s", resp.StatusCode, respErr.Error)
+	if err := client.get(url, &utxos); err != nil {
+		return []UTXO{}, err
 	}
-	if err := json.NewDecoder(resp.Body).Decode(&utxos); err != nil {
-		return utxos, err
+	for i := range utxos {
+		utxos[i].Address = address
 	}
-	return utxos, nil
+	return LimitUTXOs(utxos, limit), nil
 }
 
 func (client *mercuryClient) GetUTXO(txhash string, vout uint32) (UTXO, error) {
 	utxo := UTXO{}
-	resp, err := http.Get(fmt.Sprintf("%s/unspent/%s?vout=%d", client.URL, txhash, vout))
-	if err != nil || resp.StatusCode != http.StatusOK {
-		if err != nil {
-			return utxo, err
-		}
-		respErr := MercuryError{}
-		if err := json.NewDecoder(resp.Body).Decode(&respErr); err != nil {
-			return utxo, err
-		}
-		return utxo, fmt.Errorf("request failed with (%d): %s", resp.StatusCode, respErr.Error)
-	}
-
-	if err := json.NewDecoder(resp.Body).Decode(&utxo); err != nil {
+	if err := client.get(fmt.Sprintf("%s/unspent/%s?vout=%d", client.URL, txhash, vout), &utxo); err != nil {
 		return utxo, err
 	}
 	return utxo, nil
@@ -81,37 +80,27 @@ func (client *mercuryClient) GetUTXO(txhash string, vout uint32) (UTXO, error) {
 
 func (client *mercuryClient) Confirmations(txHash string) (int64, error) {
 	var conf btc.GetConfirmationsResponse
-	resp, err := http.Get(fmt.Sprintf("%s/confirmations/%s", client.URL, txHash))
-	if err != nil || resp.StatusCode != http.StatusOK {
-		if err != nil {
-			return 0, err
-		}
-		respErr := MercuryError{}
-		if err := json.NewDecoder(resp.Body).Decode(&respErr); err != nil {
-			return 0, err
-		}
-		return 0, fmt.Errorf("request failed with (%d): %s", resp.StatusCode, respErr.Error)
-	}
-	if err := json.NewDecoder(resp.Body).Decode(&conf); err != nil {
+	if err := client.get(fmt.Sprintf("%s/confirmations/%s", client.URL, txHash), &conf); err != nil {
 		return 0, err
 	}
 	return int64(conf), nil
 }
 
+func (client *mercuryClient) AddressBalance(address string, confirmations int64) (int64, error) {
+	return 0, errors.ErrNotSupported
+}
+
+func (client *mercuryClient) AddressUTXOCount(address string, confirmations int64) (int, error) {
+	return 0, errors.ErrNotSupported
+}
+
+func (client *mercuryClient) BlockHeight() (int64, error) {
+	return 0, errors.ErrNotSupported
+}
+
 func (client *mercuryClient) ScriptSpent(script, spender string) (bool, string, error) {
 	var scriptResp btc.GetScriptResponse
-	resp, err := http.Get(fmt.Sprintf("%s/script/spent/%s?spender=%s", client.URL, script, spender))
-	if err != nil || resp.StatusCode != http.StatusOK {
-		if err != nil {
-			return false, "", err
-		}
-		respErr := MercuryError{}
-		if err := json.NewDecoder(resp.Body).Decode(&respErr); err != nil {
-			return false, "", err
-		}
-		return false, "", fmt.Errorf("request failed with (%d): %s", resp.StatusCode, respErr.Error)
-	}
-	if err := json.NewDecoder(resp.Body).Decode(&scriptResp); err != nil {
+	if err := client.get(fmt.Sprintf("%s/script/spent/%s?spender=%s", client.URL, script, spender), &scriptResp); err != nil {
 		return false, "", err
 	}
 	return scriptResp.Status, scriptResp.Script, nil
@@ -119,18 +108,7 @@ func (client *mercuryClient) ScriptSpent(script, spender string) (bool, string,
 
 func (client *mercuryClient) ScriptFunded(address string, value int64) (bool, int64, error) {
 	var scriptResp btc.GetScriptResponse
-	resp, err := http.Get(fmt.Sprintf("%s/script/funded/%s?value=%d", client.URL, address, value))
-	if err != nil || resp.StatusCode != http.StatusOK {
-		if err != nil {
-			return false, 0, err
-		}
-		respErr := MercuryError{}
-		if err := json.NewDecoder(resp.Body).Decode(&respErr); err != nil {
-			return false, 0, err
-		}
-		return false, 0, fmt.Errorf("request failed with (%d): %s", resp.StatusCode, respErr.Error)
-	}
-	if err := json.NewDecoder(resp.Body).Decode(&scriptResp); err != nil {
+	if err := client.get(fmt.Sprintf("%s/script/funded/%s?value=%d", client.URL, address, value), &scriptResp); err != nil {
 		return false, 0, err
 	}
 	return scriptResp.Status, scriptResp.Value, nil
@@ -138,18 +116,7 @@ func (client *mercuryClient) ScriptFunded(address string, value int64) (bool, in
 
 func (client *mercuryClient) ScriptRedeemed(address string, value int64) (bool, int64, error) {
 	var scriptResp btc.GetScriptResponse
-	resp, err := http.Get(fmt.Sprintf("%s/script/redeemed/%s?value=%d", client.URL, address, value))
-	if err != nil || resp.StatusCode != http.StatusOK {
-		if err != nil {
-			return false, 0, err
-		}
-		respErr := MercuryError{}
-		if err := json.NewDecoder(resp.Body).Decode(&respErr); err != nil {
-			return false, 0, err
-		}
-		return false, 0, fmt.Errorf("request failed with (%d): %s", resp.StatusCode, respErr.Error)
-	}
-	if err := json.NewDecoder(resp.Body).Decode(&scriptResp); err != nil {
+	if err := client.get(fmt.Sprintf("%s/script/redeemed/%s?value=%d", client.URL, address, value), &scriptResp); err != nil {
 		return false, 0, err
 	}
 	return scriptResp.Status, scriptResp.Value, nil
@@ -163,17 +130,43 @@ func (client *mercuryClient) PublishTransaction(stx []byte) error {
 	if err := json.NewEncoder(buf).Encode(&req); err != nil {
 		return err
 	}
-	if resp, err := http.Post(fmt.Sprintf("%s/tx", client.URL), "application/json", buf); err != nil || resp.StatusCode != http.StatusCreated {
-		if err != nil {
-			return err
-		}
-		respErr := MercuryError{}
-		if err := json.NewDecoder(resp.Body).Decode(&respErr); err != nil {
-			return err
+	return postRequest(client.httpConfig, fmt.Sprintf("%s/tx", client.URL), "application/json", buf, func(resp *http.Response) error {
+		if resp.StatusCode != http.StatusCreated {
+			respErr := MercuryError{}
+			if err := json.NewDecoder(resp.Body).Decode(&respErr); err != nil {
+				return err
+			}
+			return errors.NewErrZCashSubmitTx(fmt.Sprintf("request failed with (%d): %s", resp.StatusCode, respErr.Error))
 		}
-		return fmt.Errorf("request failed with (%d): %s", resp.StatusCode, respErr.Error)
+		return nil
+	})
+}
+
+func (client *mercuryClient) Ping(ctx context.Context) error {
+	return pingRequest(ctx, client.httpConfig, client.URL)
+}
+
+func (client *mercuryClient) Capabilities() Capabilities {
+	return Capabilities{
+		Mempool:     true,
+		TxLookup:    true,
+		ScriptSpent: true,
 	}
-	return nil
+}
+
+// get fetches the url, and decodes the JSON response into result. The error
+// reported by mercury is returned if the request fails.
+func (client *mercuryClient) get(url string, result interface{}) error {
+	return getRequest(client.httpConfig, url, func(resp *http.Response) error {
+		if resp.StatusCode != http.StatusOK {
+			respErr := MercuryError{}
+			if err := json.NewDecoder(resp.Body).Decode(&respErr); err != nil {
+				return err
+			}
+			return fmt.Errorf("request failed with (%d): %s", resp.StatusCode, respErr.Error)
+		}
+		return json.NewDecoder(resp.Body).Decode(result)
+	})
 }
 
 type MercuryError struct {
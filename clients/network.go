@@ -0,0 +1,77 @@
+package clients
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/renproject/libzec-go/errors"
+)
+
+// Network is a ZCash network. The zero value is Testnet, the network of the
+// client constructors when no network is given.
+type Network uint8
+
+const (
+	Testnet Network = iota
+	Mainnet
+	Regtest
+)
+
+// ParseNetwork parses the name of a network. Names are case insensitive, and
+// "testnet3" and the empty string are aliases of "testnet". An
+// UnsupportedNetworkError is returned for other names.
+func ParseNetwork(name string) (Network, error) {
+	switch strings.ToLower(name) {
+	case "mainnet":
+		return Mainnet, nil
+	case "testnet", "testnet3", "":
+		return Testnet, nil
+	case "regtest":
+		return Regtest, nil
+	default:
+		return 0, errors.NewErrUnsupportedNetwork(name)
+	}
+}
+
+// NetworkOf returns the network of the parameters. Parameters are compared by
+// name, so that custom network parameters are supported.
+func NetworkOf(params *chaincfg.Params) (Network, error) {
+	if params == nil {
+		return 0, errors.NewErrUnsupportedNetwork("<nil>")
+	}
+	switch params.Name {
+	case chaincfg.MainNetParams.Name:
+		return Mainnet, nil
+	case chaincfg.TestNet3Params.Name:
+		return Testnet, nil
+	case chaincfg.RegressionNetParams.Name:
+		return Regtest, nil
+	default:
+		return 0, errors.NewErrUnsupportedNetwork(params.Name)
+	}
+}
+
+// String returns the name of the parameters of the network, which is
+// "mainnet", "testnet3" or "regtest".
+func (network Network) String() string {
+	if params := network.Params(); params != nil {
+		return params.Name
+	}
+	return fmt.Sprintf("Network(%d)", uint8(network))
+}
+
+// Params returns the parameters of the network, or nil if the network is
+// unknown.
+func (network Network) Params() *chaincfg.Params {
+	switch network {
+	case Mainnet:
+		return &chaincfg.MainNetParams
+	case Testnet:
+		return &chaincfg.TestNet3Params
+	case Regtest:
+		return &chaincfg.RegressionNetParams
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,9 @@
+package clients
+
+// SpenderClient is implemented by the client cores that index the inputs of
+// transactions, and can therefore tell which transaction spends an output.
+type SpenderClient interface {
+	// Spender returns the hash of the transaction spending the output.
+	// ErrTxNotFound is returned if the output is unspent, or does not exist.
+	Spender(txHash string, vout uint32) (string, error)
+}
@@ -0,0 +1,23 @@
+package clients
+
+// Wrapper is implemented by the client cores that wrap another client core,
+// such as circuit breakers, so that the extensions of the wrapped core, like
+// BatchClient or AddressHistory, can still be found behind them.
+type Wrapper interface {
+	// Unwrap returns the wrapped client core.
+	Unwrap() ClientCore
+}
+
+// Unwrap returns the client core followed by the client cores it wraps, from
+// the outermost to the innermost one.
+func Unwrap(core ClientCore) []ClientCore {
+	cores := []ClientCore{core}
+	for {
+		wrapper, ok := core.(Wrapper)
+		if !ok {
+			return cores
+		}
+		core = wrapper.Unwrap()
+		cores = append(cores, core)
+	}
+}
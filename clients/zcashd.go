@@ -0,0 +1,368 @@
+package clients
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sync"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/renproject/libzec-go/errors"
+)
+
+type zcashdClient struct {
+	URL        string
+	User       string
+	Password   string
+	Params     *chaincfg.Params
+	httpConfig *httpConfig
+
+	indexMu                  *sync.Mutex
+	indexKnown, indexEnabled bool
+}
+
+// NewZcashdClientCore returns a client core backed by the JSON-RPC interface
+// of a zcashd node. If the node maintains the address index (it is run with
+// -insightexplorer or -addressindex), utxos and balances are read from the
+// index, and any address can be queried. Otherwise utxos are listed using the
+// wallet of the node, so the addresses that are queried must be imported into
+// it (using importaddress).
+func NewZcashdClientCore(network, url, user, password string, options ...Option) (ClientCore, error) {
+	httpConfig, err := applyOptions(options)
+	if err != nil {
+		return nil, err
+	}
+	client := &zcashdClient{
+		URL:        url,
+		User:       user,
+		Password:   password,
+		httpConfig: httpConfig,
+		indexMu:    new(sync.Mutex),
+	}
+	net, err := ParseNetwork(network)
+	if err != nil {
+		return nil, err
+	}
+	client.Params = net.Params()
+	return client, nil
+}
+
+// rpcInvalidAddressOrKey is the code of the error returned by zcashd when a
+// transaction or an address cannot be found.
+const rpcInvalidAddressOrKey = -5
+
+// RPCError is an error returned by the JSON-RPC interface of zcashd.
+type RPCError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (err *RPCError) Error() string {
+	return fmt.Sprintf("rpc error (%d): %s", err.Code, err.Message)
+}
+
+type rpcRequest struct {
+	JSONRPC string        `json:"jsonrpc"`
+	ID      string        `json:"id"`
+	Method  string        `json:"method"`
+	Params  []interface{} `json:"params"`
+}
+
+type rpcResponse struct {
+	ID     string          `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  *RPCError       `json:"error"`
+}
+
+type zcashdUnspent struct {
+	TxID          string      `json:"txid"`
+	Vout          uint32      `json:"vout"`
+	Address       string      `json:"address"`
+	ScriptPubKey  string      `json:"scriptPubKey"`
+	Amount        json.Number `json:"amount"`
+	Confirmations int64       `json:"confirmations"`
+	Generated     bool        `json:"generated"`
+}
+
+type zcashdTxOut struct {
+	Confirmations int64       `json:"confirmations"`
+	Coinbase      bool        `json:"coinbase"`
+	Value         json.Number `json:"value"`
+	ScriptPubKey  struct {
+		Hex       string   `json:"hex"`
+		Addresses []string `json:"addresses"`
+	} `json:"scriptPubKey"`
+}
+
+type zcashdRawTx struct {
+	Confirmations int64 `json:"confirmations"`
+}
+
+// call invokes the JSON-RPC method, and decodes its result into result.
+func (client *zcashdClient) call(method string, result interface{}, params ...interface{}) error {
+	return client.callContext(context.Background(), method, result, params...)
+}
+
+func (client *zcashdClient) callContext(ctx context.Context, method string, result interface{}, params ...interface{}) error {
+	if params == nil {
+		params = []interface{}{}
+	}
+	buf := new(bytes.Buffer)
+	if err := json.NewEncoder(buf).Encode(rpcRequest{"1.0", "libzec", method, params}); err != nil {
+		return err
+	}
+	req, err := http.NewRequest("POST", client.URL, buf)
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	req.SetBasicAuth(client.User, client.Password)
+
+	// zcashd responds to failed calls with an error status, and a JSON-RPC
+	// error in the body.
+	rpcResp := rpcResponse{}
+	if err := doRequest(client.httpConfig, req, func(resp *http.Response) error {
+		if err := json.NewDecoder(resp.Body).Decode(&rpcResp); err != nil {
+			return fmt.Errorf("request failed with (%d): %v", resp.StatusCode, err)
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+	if rpcResp.Error != nil {
+		return rpcResp.Error
+	}
+	if result == nil {
+		return nil
+	}
+	return json.Unmarshal(rpcResp.Result, result)
+}
+
+func (client *zcashdClient) NetworkParams() *chaincfg.Params {
+	return client.Params
+}
+
+func (client *zcashdClient) GetUTXOs(address string, limit, confitmations int64) ([]UTXO, error) {
+	if err := CheckUTXOQuery(limit, confitmations); err != nil {
+		return nil, err
+	}
+	if utxos, ok, err := client.indexedUTXOs(address, confitmations); ok {
+		if err != nil {
+			return nil, err
+		}
+		return LimitUTXOs(utxos, limit), nil
+	}
+	unspents := []zcashdUnspent{}
+	if err := client.call("listunspent", &unspents, confitmations, 9999999, []string{address}); err != nil {
+		return nil, err
+	}
+	// The height is only needed to compute the block heights of confirmed
+	// utxos.
+	var height int64
+	for _, unspent := range unspents {
+		if unspent.Confirmations > 0 {
+			var err error
+			if height, err = client.BlockHeight(); err != nil {
+				return nil, err
+			}
+			break
+		}
+	}
+
+	utxos := []UTXO{}
+	for _, unspent := range unspents {
+		if limit > 0 && int64(len(utxos)) >= limit {
+			break
+		}
+		amount, err := zecToZat(unspent.Amount)
+		if err != nil {
+			return nil, err
+		}
+		utxos = append(utxos, UTXO{
+			TxHash:        unspent.TxID,
+			Amount:        amount,
+			ScriptPubKey:  unspent.ScriptPubKey,
+			Vout:          unspent.Vout,
+			Confirmations: unspent.Confirmations,
+			BlockHeight:   blockHeight(height, unspent.Confirmations),
+			Address:       unspent.Address,
+			Coinbase:      unspent.Generated,
+		})
+	}
+	return utxos, nil
+}
+
+// GetUTXO returns the utxo, including outputs of transactions in the mempool.
+// ErrUTXOSpent is returned if the output is spent, or does not exist.
+func (client *zcashdClient) GetUTXO(txhash string, vout uint32) (UTXO, error) {
+	var txOut *zcashdTxOut
+	if err := client.call("gettxout", &txOut, txhash, vout, true); err != nil {
+		return UTXO{}, err
+	}
+	if txOut == nil {
+		return UTXO{}, errors.ErrUTXOSpent
+	}
+	amount, err := zecToZat(txOut.Value)
+	if err != nil {
+		return UTXO{}, err
+	}
+	utxo := UTXO{
+		TxHash:        txhash,
+		Amount:        amount,
+		ScriptPubKey:  txOut.ScriptPubKey.Hex,
+		Vout:          vout,
+		Confirmations: txOut.Confirmations,
+		Coinbase:      txOut.Coinbase,
+	}
+	if len(txOut.ScriptPubKey.Addresses) == 1 {
+		utxo.Address = txOut.ScriptPubKey.Addresses[0]
+	}
+	if txOut.Confirmations > 0 {
+		height, err := client.BlockHeight()
+		if err != nil {
+			return UTXO{}, err
+		}
+		utxo.BlockHeight = blockHeight(height, txOut.Confirmations)
+	}
+	return utxo, nil
+}
+
+// blockHeight returns the height of the block that includes an output with the
+// given confirmations, or zero if the output is unconfirmed.
+func blockHeight(height, confirmations int64) int64 {
+	if confirmations <= 0 {
+		return 0
+	}
+	return height - confirmations + 1
+}
+
+// Confirmations returns the confirmations of the transaction, or
+// ErrTxNotFound. The node must be run with -txindex to look up transactions
+// that are not in its wallet or in its mempool.
+func (client *zcashdClient) Confirmations(txHash string) (int64, error) {
+	tx := zcashdRawTx{}
+	if err := client.call("getrawtransaction", &tx, txHash, 1); err != nil {
+		if rpcErr, ok := err.(*RPCError); ok && rpcErr.Code == rpcInvalidAddressOrKey {
+			return 0, errors.ErrTxNotFound
+		}
+		return 0, err
+	}
+	return tx.Confirmations, nil
+}
+
+// AddressBalance returns the balance of the address using the address index.
+// It is not supported without the index, as zcashd lists the utxos of the
+// address in a single call anyway.
+func (client *zcashdClient) AddressBalance(address string, confirmations int64) (int64, error) {
+	balance, _, ok, err := client.indexedBalance(address, confirmations)
+	if !ok {
+		return 0, errors.ErrNotSupported
+	}
+	return balance, err
+}
+
+func (client *zcashdClient) AddressUTXOCount(address string, confirmations int64) (int, error) {
+	return 0, errors.ErrNotSupported
+}
+
+func (client *zcashdClient) BlockHeight() (int64, error) {
+	var height int64
+	if err := client.call("getblockcount", &height); err != nil {
+		return 0, err
+	}
+	return height, nil
+}
+
+func (client *zcashdClient) ScriptSpent(script, spender string) (bool, string, error) {
+	return false, "", errors.ErrNotSupported
+}
+
+func (client *zcashdClient) ScriptFunded(address string, value int64) (bool, int64, error) {
+	received, balance, err := client.received(address)
+	if err != nil {
+		return false, 0, err
+	}
+	return received >= value, balance, nil
+}
+
+func (client *zcashdClient) ScriptRedeemed(address string, value int64) (bool, int64, error) {
+	received, balance, err := client.received(address)
+	if err != nil {
+		return false, 0, err
+	}
+	return received >= value && balance == 0, balance, nil
+}
+
+// received returns the total value received by the address, and its balance.
+func (client *zcashdClient) received(address string) (int64, int64, error) {
+	if balance, received, ok, err := client.indexedBalance(address, 0); ok {
+		return received, balance, err
+	}
+	var receivedZEC json.Number
+	if err := client.call("getreceivedbyaddress", &receivedZEC, address, 0); err != nil {
+		return 0, 0, err
+	}
+	received, err := zecToZat(receivedZEC)
+	if err != nil {
+		return 0, 0, err
+	}
+	utxos, err := client.GetUTXOs(address, 0, 0)
+	if err != nil {
+		return 0, 0, err
+	}
+	var balance int64
+	for _, utxo := range utxos {
+		balance += utxo.Amount
+	}
+	return received, balance, nil
+}
+
+func (client *zcashdClient) PublishTransaction(stx []byte) error {
+	if err := client.call("sendrawtransaction", nil, hex.EncodeToString(stx)); err != nil {
+		if rpcErr, ok := err.(*RPCError); ok {
+			return errors.NewErrZCashSubmitTx(rpcErr.Message)
+		}
+		return err
+	}
+	return nil
+}
+
+// zecToZat converts an amount in ZEC, as returned by zcashd, to zatoshis.
+func zecToZat(amount json.Number) (int64, error) {
+	zec, ok := new(big.Rat).SetString(amount.String())
+	if !ok {
+		return 0, fmt.Errorf("invalid amount %s", amount)
+	}
+	zat := zec.Mul(zec, big.NewRat(100000000, 1))
+	if !zat.IsInt() {
+		return 0, fmt.Errorf("invalid amount %s: more than 8 decimals", amount)
+	}
+	return zat.Num().Int64(), nil
+}
+
+// Ping calls the ping method of the node.
+func (client *zcashdClient) Ping(ctx context.Context) error {
+	return client.callContext(ctx, "ping", nil)
+}
+
+// Capabilities reports the features of the address index, unless the client
+// has found out that the node does not maintain it.
+func (client *zcashdClient) Capabilities() Capabilities {
+	client.indexMu.Lock()
+	indexed := !client.indexKnown || client.indexEnabled
+	client.indexMu.Unlock()
+	return Capabilities{
+		Mempool:        true,
+		TxLookup:       true,
+		BlockHeight:    true,
+		AddressBalance: indexed,
+		History:        indexed,
+		Batch:          true,
+		Wallet:         true,
+	}
+}
@@ -0,0 +1,189 @@
+package clients
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+)
+
+// ZcashdWallet is implemented by the client cores that are backed by the
+// wallet of a zcashd node. The shielded operations of zcashd are asynchronous:
+// they return the id of an operation, whose status is then polled until the
+// transaction is created.
+type ZcashdWallet interface {
+	// ZSendMany sends from the address, which must be controlled by the
+	// wallet of the node, to the recipients.
+	ZSendMany(from string, recipients []ZRecipient, minConf, fee int64) (string, error)
+
+	// ZShieldCoinbase sends the coinbase utxos of the address, which must be
+	// controlled by the wallet of the node, to the shielded address.
+	ZShieldCoinbase(from, to string, fee int64) (string, error)
+
+	// OperationStatus returns the status of an asynchronous operation.
+	OperationStatus(opid string) (OperationStatus, error)
+
+	// CreateRawTransaction creates a transaction without inputs, paying the
+	// amounts to the addresses.
+	CreateRawTransaction(outputs map[string]int64) ([]byte, error)
+
+	// FundRawTransaction adds inputs selected by the wallet to the
+	// transaction, and a change output if needed.
+	FundRawTransaction(stx []byte) (FundedTx, error)
+
+	// SignRawTransaction signs the inputs of the transaction that are
+	// controlled by the wallet. It returns false if some inputs could not be
+	// signed.
+	SignRawTransaction(stx []byte) ([]byte, bool, error)
+}
+
+// FundedTx is a transaction funded by the wallet of a zcashd node. The change
+// index is -1 if the transaction has no change output.
+type FundedTx struct {
+	Tx          []byte
+	Fee         int64
+	ChangeIndex int
+}
+
+// ZRecipient is a recipient of ZSendMany. The memo can only be sent to
+// shielded addresses.
+type ZRecipient struct {
+	Address string
+	Amount  int64
+	Memo    []byte
+}
+
+// Statuses of the asynchronous operations of zcashd.
+const (
+	OperationQueued    = "queued"
+	OperationExecuting = "executing"
+	OperationSuccess   = "success"
+	OperationFailed    = "failed"
+	OperationCancelled = "cancelled"
+)
+
+// OperationStatus is the status of an asynchronous operation of zcashd. The
+// txid is set once the operation succeeds, and the error once it fails.
+type OperationStatus struct {
+	ID     string
+	Status string
+	TxID   string
+	Error  string
+}
+
+type zcashdRecipient struct {
+	Address string      `json:"address"`
+	Amount  json.Number `json:"amount"`
+	Memo    string      `json:"memo,omitempty"`
+}
+
+type zcashdOperationStatus struct {
+	ID     string `json:"id"`
+	Status string `json:"status"`
+	Result *struct {
+		TxID string `json:"txid"`
+	} `json:"result"`
+	Error *RPCError `json:"error"`
+}
+
+func (client *zcashdClient) ZSendMany(from string, recipients []ZRecipient, minConf, fee int64) (string, error) {
+	amounts := make([]zcashdRecipient, len(recipients))
+	for i, recipient := range recipients {
+		amounts[i] = zcashdRecipient{
+			Address: recipient.Address,
+			Amount:  zatToZEC(recipient.Amount),
+			Memo:    hex.EncodeToString(recipient.Memo),
+		}
+	}
+	var opid string
+	if err := client.call("z_sendmany", &opid, from, amounts, minConf, zatToZEC(fee)); err != nil {
+		return "", err
+	}
+	return opid, nil
+}
+
+func (client *zcashdClient) ZShieldCoinbase(from, to string, fee int64) (string, error) {
+	result := struct {
+		OpID string `json:"opid"`
+	}{}
+	if err := client.call("z_shieldcoinbase", &result, from, to, zatToZEC(fee)); err != nil {
+		return "", err
+	}
+	return result.OpID, nil
+}
+
+func (client *zcashdClient) OperationStatus(opid string) (OperationStatus, error) {
+	statuses := []zcashdOperationStatus{}
+	if err := client.call("z_getoperationstatus", &statuses, []string{opid}); err != nil {
+		return OperationStatus{}, err
+	}
+	if len(statuses) == 0 {
+		return OperationStatus{}, fmt.Errorf("unknown operation %s", opid)
+	}
+	status := OperationStatus{
+		ID:     statuses[0].ID,
+		Status: statuses[0].Status,
+	}
+	if statuses[0].Result != nil {
+		status.TxID = statuses[0].Result.TxID
+	}
+	if statuses[0].Error != nil {
+		status.Error = statuses[0].Error.Message
+	}
+	return status, nil
+}
+
+func (client *zcashdClient) CreateRawTransaction(outputs map[string]int64) ([]byte, error) {
+	amounts := map[string]json.Number{}
+	for address, amount := range outputs {
+		amounts[address] = zatToZEC(amount)
+	}
+	var raw string
+	if err := client.call("createrawtransaction", &raw, []struct{}{}, amounts); err != nil {
+		return nil, err
+	}
+	return hex.DecodeString(raw)
+}
+
+func (client *zcashdClient) FundRawTransaction(stx []byte) (FundedTx, error) {
+	result := struct {
+		Hex       string      `json:"hex"`
+		Fee       json.Number `json:"fee"`
+		ChangePos int         `json:"changepos"`
+	}{}
+	if err := client.call("fundrawtransaction", &result, hex.EncodeToString(stx)); err != nil {
+		return FundedTx{}, err
+	}
+	funded, err := hex.DecodeString(result.Hex)
+	if err != nil {
+		return FundedTx{}, err
+	}
+	fee, err := zecToZat(result.Fee)
+	if err != nil {
+		return FundedTx{}, err
+	}
+	return FundedTx{funded, fee, result.ChangePos}, nil
+}
+
+func (client *zcashdClient) SignRawTransaction(stx []byte) ([]byte, bool, error) {
+	result := struct {
+		Hex      string `json:"hex"`
+		Complete bool   `json:"complete"`
+	}{}
+	if err := client.call("signrawtransaction", &result, hex.EncodeToString(stx)); err != nil {
+		return nil, false, err
+	}
+	signed, err := hex.DecodeString(result.Hex)
+	if err != nil {
+		return nil, false, err
+	}
+	return signed, result.Complete, nil
+}
+
+// zatToZEC converts an amount in zatoshis to ZEC, as expected by zcashd.
+func zatToZEC(amount int64) json.Number {
+	sign := ""
+	if amount < 0 {
+		sign, amount = "-", -amount
+	}
+	return json.Number(fmt.Sprintf("%s%d.%08d", sign, amount/100000000, amount%100000000))
+}
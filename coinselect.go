@@ -0,0 +1,138 @@
+package libzec
+
+import (
+	"context"
+	"sort"
+
+	"github.com/renproject/libzec-go/clients"
+)
+
+// A CoinSelector chooses the utxos that fund a transaction, among the
+// spendable utxos of the account.
+type CoinSelector interface {
+	// SelectCoins returns utxos worth at least the target, and false if the
+	// utxos are not worth enough.
+	SelectCoins(utxos []clients.UTXO, target int64) ([]clients.UTXO, bool)
+}
+
+type firstFitSelector struct{}
+
+// NewFirstFitSelector returns the default coin selector of accounts, which
+// spends the utxos in the order in which they are returned by the client until
+// the target is reached.
+func NewFirstFitSelector() CoinSelector {
+	return firstFitSelector{}
+}
+
+func (firstFitSelector) SelectCoins(utxos []clients.UTXO, target int64) ([]clients.UTXO, bool) {
+	var selected []clients.UTXO
+	var total int64
+	for _, utxo := range utxos {
+		if total >= target {
+			break
+		}
+		selected = append(selected, utxo)
+		total += utxo.Amount
+	}
+	return selected, total >= target
+}
+
+type privacySelector struct {
+	minConfirmations int64
+}
+
+// NewPrivacySelector returns a coin selector that weighs the confirmation
+// depth, the linkage of addresses and the change of the transactions it
+// funds:
+//
+//   - utxos with at least minConfirmations confirmations are spent first, then
+//     confirmed utxos, and unconfirmed utxos last;
+//   - the utxos of a single address are spent whenever possible, so that the
+//     transaction does not link the addresses of the account, and addresses
+//     are only combined when none of them is worth enough on its own;
+//   - among the utxos that can fund the transaction, a single utxo covering
+//     the target is preferred, and otherwise the fewest utxos are spent, so
+//     that the change is as small as possible.
+func NewPrivacySelector(minConfirmations int64) CoinSelector {
+	return privacySelector{minConfirmations}
+}
+
+func (selector privacySelector) SelectCoins(utxos []clients.UTXO, target int64) ([]clients.UTXO, bool) {
+	for _, minConf := range []int64{selector.minConfirmations, 1, 0} {
+		candidates := []clients.UTXO{}
+		for _, utxo := range utxos {
+			if utxo.Confirmations >= minConf {
+				candidates = append(candidates, utxo)
+			}
+		}
+		if selected, ok := selectFromOneAddress(candidates, target); ok {
+			return selected, true
+		}
+	}
+	return selectWithLeastChange(utxos, target)
+}
+
+// selectFromOneAddress returns the selection with the least change among the
+// selections that only spend the utxos of a single address.
+func selectFromOneAddress(utxos []clients.UTXO, target int64) ([]clients.UTXO, bool) {
+	byAddress := map[string][]clients.UTXO{}
+	addresses := []string{}
+	for _, utxo := range utxos {
+		if _, ok := byAddress[utxo.Address]; !ok {
+			addresses = append(addresses, utxo.Address)
+		}
+		byAddress[utxo.Address] = append(byAddress[utxo.Address], utxo)
+	}
+	sort.Strings(addresses)
+
+	var best []clients.UTXO
+	found := false
+	for _, address := range addresses {
+		selected, ok := selectWithLeastChange(byAddress[address], target)
+		if ok && (!found || sumUTXOs(selected) < sumUTXOs(best)) {
+			best, found = selected, true
+		}
+	}
+	return best, found
+}
+
+// selectWithLeastChange selects the smallest utxo covering the target, or the
+// largest utxos until the target is covered, and then drops the selected utxos
+// that are not needed to cover it.
+func selectWithLeastChange(utxos []clients.UTXO, target int64) ([]clients.UTXO, bool) {
+	selected, ok := selectTopUp(utxos, target)
+	if !ok {
+		return nil, false
+	}
+	total := sumUTXOs(selected)
+	for i := len(selected) - 1; i >= 0; i-- {
+		if total-selected[i].Amount >= target {
+			total -= selected[i].Amount
+			selected = append(selected[:i], selected[i+1:]...)
+		}
+	}
+	return selected, true
+}
+
+// SetCoinSelector sets the coin selector that chooses the utxos funding the
+// transactions of the account. A nil selector restores the default first fit
+// selection.
+func (account *account) SetCoinSelector(selector CoinSelector) {
+	account.coinSelector = selector
+}
+
+// TransferWithCoinSelector transfers the value to the address like Transfer,
+// funding the transaction with the utxos chosen by the selector.
+func (account *account) TransferWithCoinSelector(ctx context.Context, to string, value int64, speed TxExecutionSpeed, selector CoinSelector) (TxReceipt, error) {
+	selecting := *account
+	selecting.coinSelector = selector
+	return selecting.Transfer(ctx, to, value, speed, false)
+}
+
+// coinSelection returns the coin selector of the account.
+func (account *account) coinSelection() CoinSelector {
+	if account.coinSelector == nil {
+		return firstFitSelector{}
+	}
+	return account.coinSelector
+}
@@ -0,0 +1,52 @@
+package libzec_test
+
+import (
+	"github.com/renproject/libzec-go/clients"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/renproject/libzec-go"
+)
+
+var _ = Describe("Coin selection", func() {
+	utxo := func(txHash string, amount, confirmations int64, address string) clients.UTXO {
+		return clients.UTXO{TxHash: txHash, Amount: amount, Confirmations: confirmations, Address: address}
+	}
+
+	It("should spend utxos in order with the first fit selector", func() {
+		utxos := []clients.UTXO{utxo("a", 5000, 1, ""), utxo("b", 20000, 1, ""), utxo("c", 1000, 1, "")}
+		selected, ok := NewFirstFitSelector().SelectCoins(utxos, 10000)
+		Expect(ok).Should(BeTrue())
+		Expect(selected).Should(Equal(utxos[:2]))
+
+		_, ok = NewFirstFitSelector().SelectCoins(utxos, 30000)
+		Expect(ok).Should(BeFalse())
+	})
+
+	It("should prefer the smallest deep utxo covering the target", func() {
+		utxos := []clients.UTXO{
+			utxo("unconfirmed", 11000, 0, "t1a"),
+			utxo("shallow", 12000, 1, "t1a"),
+			utxo("deep", 50000, 10, "t1a"),
+			utxo("deeper", 15000, 20, "t1a"),
+		}
+		selected, ok := NewPrivacySelector(6).SelectCoins(utxos, 10000)
+		Expect(ok).Should(BeTrue())
+		Expect(selected).Should(Equal([]clients.UTXO{utxos[3]}))
+	})
+
+	It("should not combine addresses unless it has to", func() {
+		utxos := []clients.UTXO{
+			utxo("a1", 6000, 10, "t1a"),
+			utxo("b1", 4000, 10, "t1b"),
+			utxo("b2", 7000, 10, "t1b"),
+		}
+		selected, ok := NewPrivacySelector(6).SelectCoins(utxos, 10000)
+		Expect(ok).Should(BeTrue())
+		Expect(selected).Should(ConsistOf(utxos[1], utxos[2]))
+
+		selected, ok = NewPrivacySelector(6).SelectCoins(utxos, 15000)
+		Expect(ok).Should(BeTrue())
+		Expect(selected).Should(ConsistOf(utxos[0], utxos[1], utxos[2]))
+	})
+})
@@ -0,0 +1,359 @@
+package libzec
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/iqoption/zecutil"
+)
+
+const (
+	versionNU5        int32  = 5
+	versionNU5GroupID uint32 = 0x26A7270A
+
+	// Sizes of the JoinSplit descriptions, which only differ in the size of
+	// their proofs (PHGR13 before Sapling, Groth16 since Sapling).
+	joinSplitSizeOverwinter = 1802
+	joinSplitSizeSapling    = 1698
+
+	// Size of an Orchard action description.
+	orchardActionSize = 820
+
+	// maxDecodeItems bounds the number of items of every list in a raw
+	// transaction, so that a malformed length cannot exhaust memory.
+	maxDecodeItems = 1 << 16
+)
+
+// DecodedTx is a raw ZCash transaction parsed by DecodeTransaction.
+type DecodedTx struct {
+	// Version is the transaction version, without the overwintered flag.
+	Version        int32
+	VersionGroupID uint32
+
+	// ConsensusBranchID is only encoded in v5 transactions. It is zero for
+	// older versions.
+	ConsensusBranchID uint32
+
+	LockTime     uint32
+	ExpiryHeight uint32
+	TxIn         []*wire.TxIn
+	TxOut        []*wire.TxOut
+
+	// Shielded are the Sapling and Sprout components of the transaction.
+	Shielded ShieldedData
+
+	// Orchard is the serialized Orchard bundle of a v5 transaction, or nil if
+	// the transaction has no Orchard actions.
+	Orchard []byte
+}
+
+// DecodeTransaction parses a raw Overwinter (v3), Sapling (v4) or NU5 (v5)
+// transaction. It is the inverse of zecutil.MsgTx.ZecEncode.
+func DecodeTransaction(raw []byte) (*DecodedTx, error) {
+	r := bytes.NewReader(raw)
+	tx := &DecodedTx{}
+
+	var header uint32
+	if err := binary.Read(r, binary.LittleEndian, &header); err != nil {
+		return nil, fmt.Errorf("cannot read the transaction header: %v", err)
+	}
+	if header&(1<<31) == 0 {
+		return nil, fmt.Errorf("unsupported transaction: not overwintered")
+	}
+	tx.Version = int32(header &^ (1 << 31))
+	if err := binary.Read(r, binary.LittleEndian, &tx.VersionGroupID); err != nil {
+		return nil, err
+	}
+
+	var err error
+	switch {
+	case tx.Version == versionOverwinter && tx.VersionGroupID == versionOverwinterGroupID:
+		err = tx.decodeV3V4(r, joinSplitSizeOverwinter)
+	case tx.Version == versionSapling && tx.VersionGroupID == versionSaplingGroupID:
+		err = tx.decodeV3V4(r, joinSplitSizeSapling)
+	case tx.Version == versionNU5 && tx.VersionGroupID == versionNU5GroupID:
+		err = tx.decodeV5(r)
+	default:
+		return nil, fmt.Errorf("unsupported transaction version %d with version group id %x", tx.Version, tx.VersionGroupID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if r.Len() != 0 {
+		return nil, fmt.Errorf("unexpected %d bytes after the transaction", r.Len())
+	}
+	return tx, nil
+}
+
+// MsgTx returns the transparent components of a v3 or v4 transaction, which
+// can be used to compute signature hashes and to verify scripts.
+func (tx *DecodedTx) MsgTx() (*zecutil.MsgTx, error) {
+	if tx.Version != versionOverwinter && tx.Version != versionSapling {
+		return nil, fmt.Errorf("unsupported transaction version %d", tx.Version)
+	}
+	msgTx := &zecutil.MsgTx{
+		MsgTx:        wire.NewMsgTx(tx.Version),
+		ExpiryHeight: tx.ExpiryHeight,
+	}
+	msgTx.LockTime = tx.LockTime
+	msgTx.TxIn = tx.TxIn
+	msgTx.TxOut = tx.TxOut
+	return msgTx, nil
+}
+
+func (tx *DecodedTx) decodeV3V4(r io.Reader, joinSplitSize int) error {
+	var err error
+	if tx.TxIn, err = readTxIns(r); err != nil {
+		return err
+	}
+	if tx.TxOut, err = readTxOuts(r); err != nil {
+		return err
+	}
+	if err := binary.Read(r, binary.LittleEndian, &tx.LockTime); err != nil {
+		return err
+	}
+	if err := binary.Read(r, binary.LittleEndian, &tx.ExpiryHeight); err != nil {
+		return err
+	}
+
+	if tx.Version == versionSapling {
+		if err := binary.Read(r, binary.LittleEndian, &tx.Shielded.ValueBalance); err != nil {
+			return err
+		}
+		numSpends, err := readCount(r)
+		if err != nil {
+			return err
+		}
+		tx.Shielded.Spends = make([]SaplingSpend, numSpends)
+		for i := range tx.Shielded.Spends {
+			spend := &tx.Shielded.Spends[i]
+			if err := readFull(r, spend.CV[:], spend.Anchor[:], spend.Nullifier[:], spend.RK[:], spend.ZKProof[:], spend.SpendAuthSig[:]); err != nil {
+				return err
+			}
+		}
+		numOutputs, err := readCount(r)
+		if err != nil {
+			return err
+		}
+		tx.Shielded.Outputs = make([]SaplingOutput, numOutputs)
+		for i := range tx.Shielded.Outputs {
+			output := &tx.Shielded.Outputs[i]
+			if err := readFull(r, output.CV[:], output.CMU[:], output.EphemeralKey[:], output.EncCiphertext[:], output.OutCiphertext[:], output.ZKProof[:]); err != nil {
+				return err
+			}
+		}
+	}
+
+	numJoinSplits, err := readCount(r)
+	if err != nil {
+		return err
+	}
+	tx.Shielded.JoinSplits = make([][]byte, numJoinSplits)
+	for i := range tx.Shielded.JoinSplits {
+		tx.Shielded.JoinSplits[i] = make([]byte, joinSplitSize)
+		if err := readFull(r, tx.Shielded.JoinSplits[i]); err != nil {
+			return err
+		}
+	}
+	if numJoinSplits > 0 {
+		if err := readFull(r, tx.Shielded.JoinSplitPubKey[:], tx.Shielded.JoinSplitSig[:]); err != nil {
+			return err
+		}
+	}
+
+	if len(tx.Shielded.Spends)+len(tx.Shielded.Outputs) > 0 {
+		return readFull(r, tx.Shielded.BindingSig[:])
+	}
+	return nil
+}
+
+// decodeV5 decodes the body of a v5 transaction, as specified by ZIP-225.
+func (tx *DecodedTx) decodeV5(r *bytes.Reader) error {
+	if err := binary.Read(r, binary.LittleEndian, &tx.ConsensusBranchID); err != nil {
+		return err
+	}
+	if err := binary.Read(r, binary.LittleEndian, &tx.LockTime); err != nil {
+		return err
+	}
+	if err := binary.Read(r, binary.LittleEndian, &tx.ExpiryHeight); err != nil {
+		return err
+	}
+	var err error
+	if tx.TxIn, err = readTxIns(r); err != nil {
+		return err
+	}
+	if tx.TxOut, err = readTxOuts(r); err != nil {
+		return err
+	}
+
+	// The Sapling bundle stores the proofs and the signatures of the spends
+	// and outputs after their descriptions, and shares a single anchor.
+	numSpends, err := readCount(r)
+	if err != nil {
+		return err
+	}
+	tx.Shielded.Spends = make([]SaplingSpend, numSpends)
+	for i := range tx.Shielded.Spends {
+		spend := &tx.Shielded.Spends[i]
+		if err := readFull(r, spend.CV[:], spend.Nullifier[:], spend.RK[:]); err != nil {
+			return err
+		}
+	}
+	numOutputs, err := readCount(r)
+	if err != nil {
+		return err
+	}
+	tx.Shielded.Outputs = make([]SaplingOutput, numOutputs)
+	for i := range tx.Shielded.Outputs {
+		output := &tx.Shielded.Outputs[i]
+		if err := readFull(r, output.CV[:], output.CMU[:], output.EphemeralKey[:], output.EncCiphertext[:], output.OutCiphertext[:]); err != nil {
+			return err
+		}
+	}
+	if numSpends+numOutputs > 0 {
+		if err := binary.Read(r, binary.LittleEndian, &tx.Shielded.ValueBalance); err != nil {
+			return err
+		}
+	}
+	if numSpends > 0 {
+		anchor := [32]byte{}
+		if err := readFull(r, anchor[:]); err != nil {
+			return err
+		}
+		for i := range tx.Shielded.Spends {
+			tx.Shielded.Spends[i].Anchor = anchor
+		}
+	}
+	for i := range tx.Shielded.Spends {
+		if err := readFull(r, tx.Shielded.Spends[i].ZKProof[:]); err != nil {
+			return err
+		}
+	}
+	for i := range tx.Shielded.Spends {
+		if err := readFull(r, tx.Shielded.Spends[i].SpendAuthSig[:]); err != nil {
+			return err
+		}
+	}
+	for i := range tx.Shielded.Outputs {
+		if err := readFull(r, tx.Shielded.Outputs[i].ZKProof[:]); err != nil {
+			return err
+		}
+	}
+	if numSpends+numOutputs > 0 {
+		if err := readFull(r, tx.Shielded.BindingSig[:]); err != nil {
+			return err
+		}
+	}
+
+	// The Orchard bundle is kept serialized, from its number of actions to
+	// its binding signature.
+	start := int(r.Size()) - r.Len()
+	numActions, err := readCount(r)
+	if err != nil {
+		return err
+	}
+	if numActions == 0 {
+		return nil
+	}
+	// Actions, flags, value balance and anchor.
+	if err := skip(r, numActions*orchardActionSize+1+8+32); err != nil {
+		return err
+	}
+	proofsSize, err := wire.ReadVarInt(r, 0)
+	if err != nil {
+		return err
+	}
+	if proofsSize > uint64(r.Len()) {
+		return io.ErrUnexpectedEOF
+	}
+	// Proofs, spend authorization signatures and binding signature.
+	if err := skip(r, int(proofsSize)+numActions*64+64); err != nil {
+		return err
+	}
+	end := int(r.Size()) - r.Len()
+	tx.Orchard = make([]byte, end-start)
+	_, err = r.ReadAt(tx.Orchard, int64(start))
+	return err
+}
+
+func readTxIns(r io.Reader) ([]*wire.TxIn, error) {
+	count, err := readCount(r)
+	if err != nil {
+		return nil, err
+	}
+	txIns := make([]*wire.TxIn, count)
+	for i := range txIns {
+		hash := chainhash.Hash{}
+		if err := readFull(r, hash[:]); err != nil {
+			return nil, err
+		}
+		var index uint32
+		if err := binary.Read(r, binary.LittleEndian, &index); err != nil {
+			return nil, err
+		}
+		sigScript, err := wire.ReadVarBytes(r, 0, maxDecodeItems, "signature script")
+		if err != nil {
+			return nil, err
+		}
+		txIns[i] = wire.NewTxIn(wire.NewOutPoint(&hash, index), sigScript, nil)
+		if err := binary.Read(r, binary.LittleEndian, &txIns[i].Sequence); err != nil {
+			return nil, err
+		}
+	}
+	return txIns, nil
+}
+
+func readTxOuts(r io.Reader) ([]*wire.TxOut, error) {
+	count, err := readCount(r)
+	if err != nil {
+		return nil, err
+	}
+	txOuts := make([]*wire.TxOut, count)
+	for i := range txOuts {
+		var value int64
+		if err := binary.Read(r, binary.LittleEndian, &value); err != nil {
+			return nil, err
+		}
+		pkScript, err := wire.ReadVarBytes(r, 0, maxDecodeItems, "public key script")
+		if err != nil {
+			return nil, err
+		}
+		txOuts[i] = wire.NewTxOut(value, pkScript)
+	}
+	return txOuts, nil
+}
+
+// readCount reads a compact size, and checks that it is a sane number of
+// items.
+func readCount(r io.Reader) (int, error) {
+	count, err := wire.ReadVarInt(r, 0)
+	if err != nil {
+		return 0, err
+	}
+	if count > maxDecodeItems {
+		return 0, fmt.Errorf("too many items: got: %d max: %d", count, maxDecodeItems)
+	}
+	return int(count), nil
+}
+
+// readFull fills every buffer in order.
+func readFull(r io.Reader, bufs ...[]byte) error {
+	for _, buf := range bufs {
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func skip(r *bytes.Reader, n int) error {
+	if n > r.Len() {
+		return io.ErrUnexpectedEOF
+	}
+	_, err := r.Seek(int64(n), io.SeekCurrent)
+	return err
+}
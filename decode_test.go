@@ -0,0 +1,70 @@
+package libzec_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/renproject/libzec-go"
+)
+
+var _ = Describe("Transaction decoding", func() {
+	// rawSaplingTx returns a v4 transaction with one input, one output and no
+	// shielded components.
+	rawSaplingTx := func() []byte {
+		buf := new(bytes.Buffer)
+		binary.Write(buf, binary.LittleEndian, uint32(4|1<<31))
+		binary.Write(buf, binary.LittleEndian, uint32(0x892F2085))
+		buf.WriteByte(1)
+		buf.Write(bytes.Repeat([]byte{0xAB}, 32))
+		binary.Write(buf, binary.LittleEndian, uint32(1))
+		buf.Write([]byte{2, 0x51, 0x51})
+		binary.Write(buf, binary.LittleEndian, uint32(0xFFFFFFFE))
+		buf.WriteByte(1)
+		binary.Write(buf, binary.LittleEndian, int64(20000))
+		buf.Write([]byte{1, 0x51})
+		binary.Write(buf, binary.LittleEndian, uint32(100))
+		binary.Write(buf, binary.LittleEndian, uint32(500000))
+		binary.Write(buf, binary.LittleEndian, int64(0))
+		buf.Write([]byte{0, 0, 0})
+		return buf.Bytes()
+	}
+
+	It("should decode a transparent Sapling transaction", func() {
+		tx, err := DecodeTransaction(rawSaplingTx())
+		Expect(err).Should(BeNil())
+		Expect(tx.Version).Should(Equal(int32(4)))
+		Expect(tx.VersionGroupID).Should(Equal(uint32(0x892F2085)))
+		Expect(tx.LockTime).Should(Equal(uint32(100)))
+		Expect(tx.ExpiryHeight).Should(Equal(uint32(500000)))
+		Expect(tx.TxIn).Should(HaveLen(1))
+		Expect(tx.TxIn[0].PreviousOutPoint.Index).Should(Equal(uint32(1)))
+		Expect(tx.TxIn[0].SignatureScript).Should(Equal([]byte{0x51, 0x51}))
+		Expect(tx.TxIn[0].Sequence).Should(Equal(uint32(0xFFFFFFFE)))
+		Expect(tx.TxOut).Should(HaveLen(1))
+		Expect(tx.TxOut[0].Value).Should(Equal(int64(20000)))
+		Expect(tx.Shielded.Spends).Should(BeEmpty())
+	})
+
+	It("should compute the txid of a Sapling transaction", func() {
+		raw := rawSaplingTx()
+		first := sha256.Sum256(raw)
+		second := sha256.Sum256(first[:])
+		for i, j := 0, len(second)-1; i < j; i, j = i+1, j-1 {
+			second[i], second[j] = second[j], second[i]
+		}
+		txID, err := TxID(raw)
+		Expect(err).Should(BeNil())
+		Expect(txID).Should(Equal(second[:]))
+	})
+
+	It("should reject truncated and padded transactions", func() {
+		raw := rawSaplingTx()
+		_, err := DecodeTransaction(raw[:len(raw)-1])
+		Expect(err).ShouldNot(BeNil())
+		_, err = DecodeTransaction(append(raw, 0))
+		Expect(err).ShouldNot(BeNil())
+	})
+})
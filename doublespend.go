@@ -0,0 +1,272 @@
+package libzec
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/renproject/libzec-go/clients"
+	"github.com/sirupsen/logrus"
+)
+
+// DoubleSpend is an alert that an outpoint spent by a pending transaction of a
+// tx store has been spent by another transaction, so the pending transaction
+// will never be mined.
+type DoubleSpend struct {
+	// TxHash is the hash of the transaction that was double spent.
+	TxHash string
+
+	// OutPoint is the outpoint spent by the other transaction, formatted as
+	// "txhash:vout".
+	OutPoint string
+
+	// ConflictingTxHash is the hash of the transaction that spent the
+	// outpoint. It is empty if the client cannot look up the spender of an
+	// outpoint.
+	ConflictingTxHash string
+
+	// ConflictingConfirmations is the number of confirmations of the
+	// conflicting transaction. The double spend can still be reverted by a
+	// reorg while the conflicting transaction has no confirmation.
+	ConflictingConfirmations int64
+
+	DetectedAt time.Time
+}
+
+// Confirmed returns whether the conflicting transaction has been mined.
+func (doubleSpend DoubleSpend) Confirmed() bool {
+	return doubleSpend.ConflictingConfirmations > 0
+}
+
+// A DoubleSpendDetector watches the pending transactions of a tx store, and
+// delivers an alert when one of them is double spent. Services that credit
+// transactions without confirmations should stop trusting a transaction as
+// soon as it is reported.
+type DoubleSpendDetector interface {
+	// Alerts returns the channel on which double spends are delivered.
+	Alerts() <-chan DoubleSpend
+
+	// Check checks the pending transactions of the store once, and returns
+	// the double spends that are detected. The double spent transactions
+	// are marked as conflicted in the store, so they are reported once.
+	Check() ([]DoubleSpend, error)
+
+	// Run checks the pending transactions at the given interval, until the
+	// context is done. The alerts channel is closed when Run returns.
+	Run(ctx context.Context, interval time.Duration)
+}
+
+type doubleSpendDetector struct {
+	client Client
+	store  TxStore
+	logger logrus.FieldLogger
+	alerts chan DoubleSpend
+}
+
+// NewDoubleSpendDetector returns a DoubleSpendDetector that watches the
+// pending transactions of the store using the client. A pending transaction
+// is double spent if neither the mempool nor the chain contain it, while one
+// of the outpoints it spends is spent. Outpoints created by transactions of
+// the store that are not confirmed, or by transactions the client cannot
+// find, are ignored, as they are missing rather than spent. The conflicting
+// transaction is reported if the client core implements
+// clients.SpenderClient.
+func NewDoubleSpendDetector(client Client, store TxStore, logger logrus.FieldLogger) DoubleSpendDetector {
+	return &doubleSpendDetector{
+		client: client,
+		store:  store,
+		logger: defaultLogger(logger),
+		alerts: make(chan DoubleSpend, 16),
+	}
+}
+
+func (detector *doubleSpendDetector) Alerts() <-chan DoubleSpend {
+	return detector.alerts
+}
+
+func (detector *doubleSpendDetector) Run(ctx context.Context, interval time.Duration) {
+	defer close(detector.alerts)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		doubleSpends, err := detector.Check()
+		if err != nil {
+			detector.logger.Infof("cannot check for double spends: %v", err)
+		}
+		for _, doubleSpend := range doubleSpends {
+			select {
+			case <-ctx.Done():
+				return
+			case detector.alerts <- doubleSpend:
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (detector *doubleSpendDetector) Check() ([]DoubleSpend, error) {
+	txs, err := detector.store.All()
+	if err != nil {
+		return nil, err
+	}
+	unconfirmed := map[string]bool{}
+	for _, tx := range txs {
+		if tx.State != TxStateConfirmed {
+			unconfirmed[tx.TxHash] = true
+		}
+	}
+
+	doubleSpends := []DoubleSpend{}
+	for _, tx := range txs {
+		if tx.State != TxStatePending {
+			continue
+		}
+		_, err := detector.client.Confirmations(tx.TxHash)
+		if err == nil {
+			continue
+		}
+		if !errors.Is(err, ErrTxNotFound) {
+			detector.logger.Infof("cannot get the confirmations of %s: %v", tx.TxHash, err)
+			continue
+		}
+
+		outPoint, err := detector.spentInput(tx, unconfirmed)
+		if err != nil {
+			detector.logger.Infof("cannot check the inputs of %s: %v", tx.TxHash, err)
+			continue
+		}
+		if outPoint == "" {
+			continue
+		}
+
+		// The transaction may have been mined, or accepted to the mempool,
+		// while its inputs were checked, in which case it spent the outpoint
+		// itself.
+		conf, err := detector.client.Confirmations(tx.TxHash)
+		if err == nil {
+			if conf > 0 {
+				tx.State = TxStateConfirmed
+				if err := detector.store.Put(tx); err != nil {
+					return doubleSpends, err
+				}
+			}
+			continue
+		}
+		if !errors.Is(err, ErrTxNotFound) {
+			detector.logger.Infof("cannot get the confirmations of %s: %v", tx.TxHash, err)
+			continue
+		}
+
+		doubleSpend, err := detector.conflict(tx.TxHash, outPoint)
+		if err != nil {
+			detector.logger.Infof("cannot get the transaction spending %s: %v", outPoint, err)
+			continue
+		}
+		if doubleSpend.ConflictingTxHash == "" {
+			detector.logger.Warnf("transaction %s is double spent: %s is spent by another transaction", tx.TxHash, outPoint)
+		} else {
+			detector.logger.Warnf("transaction %s is double spent: %s is spent by %s with %d confirmations", tx.TxHash, outPoint, doubleSpend.ConflictingTxHash, doubleSpend.ConflictingConfirmations)
+		}
+		tx.State = TxStateConflicted
+		if err := detector.store.Put(tx); err != nil {
+			return doubleSpends, err
+		}
+		doubleSpends = append(doubleSpends, doubleSpend)
+	}
+	return doubleSpends, nil
+}
+
+// spentInput returns the first input of the transaction that is spent, or an
+// empty string if none of them is. Clients also report outputs that do not
+// exist as spent, so the transaction of a spent output must be found for the
+// output to be spent.
+func (detector *doubleSpendDetector) spentInput(tx StoredTx, unconfirmed map[string]bool) (string, error) {
+	for _, input := range tx.Inputs {
+		txHash, vout, err := parseOutPointKey(input)
+		if err != nil {
+			return "", err
+		}
+		if unconfirmed[txHash] {
+			continue
+		}
+		_, err = detector.client.GetUTXO(txHash, vout)
+		if err == nil {
+			continue
+		}
+		if !errors.Is(err, ErrUTXOSpent) {
+			return "", err
+		}
+		_, err = detector.client.Confirmations(txHash)
+		if errors.Is(err, ErrTxNotFound) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		return input, nil
+	}
+	return "", nil
+}
+
+// conflict returns the double spend of the transaction, with the transaction
+// spending the outpoint if the client can look it up.
+func (detector *doubleSpendDetector) conflict(txHash, outPoint string) (DoubleSpend, error) {
+	doubleSpend := DoubleSpend{
+		TxHash:     txHash,
+		OutPoint:   outPoint,
+		DetectedAt: time.Now(),
+	}
+	finder, ok := spenderClient(detector.client)
+	if !ok {
+		return doubleSpend, nil
+	}
+	outPointTxHash, vout, err := parseOutPointKey(outPoint)
+	if err != nil {
+		return DoubleSpend{}, err
+	}
+	spender, err := finder.Spender(outPointTxHash, vout)
+	if errors.Is(err, ErrTxNotFound) {
+		return doubleSpend, nil
+	}
+	if err != nil {
+		return DoubleSpend{}, err
+	}
+	conf, err := detector.client.Confirmations(spender)
+	if err != nil && !errors.Is(err, ErrTxNotFound) {
+		return DoubleSpend{}, err
+	}
+	doubleSpend.ConflictingTxHash = spender
+	doubleSpend.ConflictingConfirmations = conf
+	return doubleSpend, nil
+}
+
+// spenderClient returns the spender client of the client, if one of its
+// cores implements it.
+func spenderClient(c Client) (clients.SpenderClient, bool) {
+	for _, core := range clientCores(c) {
+		if finder, ok := core.(clients.SpenderClient); ok {
+			return finder, true
+		}
+	}
+	return nil, false
+}
+
+// parseOutPointKey is the inverse of outPointKey.
+func parseOutPointKey(key string) (string, uint32, error) {
+	i := strings.LastIndex(key, ":")
+	if i < 0 {
+		return "", 0, fmt.Errorf("invalid outpoint %s", key)
+	}
+	vout, err := strconv.ParseUint(key[i+1:], 10, 32)
+	if err != nil {
+		return "", 0, err
+	}
+	return key[:i], uint32(vout), nil
+}
@@ -0,0 +1,144 @@
+package libzec_test
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/renproject/libzec-go"
+	"github.com/renproject/libzec-go/clients"
+	"github.com/renproject/libzec-go/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// spenderMockClientCore is a mock client core that knows the transactions
+// spending the outputs, like a block explorer.
+type spenderMockClientCore struct {
+	*mockClientCore
+	spenders map[string]string
+}
+
+func (core *spenderMockClientCore) Spender(txHash string, vout uint32) (string, error) {
+	spender, ok := core.spenders[fmt.Sprintf("%s:%d", txHash, vout)]
+	if !ok {
+		return "", errors.ErrTxNotFound
+	}
+	return spender, nil
+}
+
+// minedMockClientCore is a mock client core that mines a transaction after it
+// has been looked up once.
+type minedMockClientCore struct {
+	*mockClientCore
+	txHash  string
+	lookups int
+}
+
+func (core *minedMockClientCore) Confirmations(txHash string) (int64, error) {
+	if txHash == core.txHash {
+		core.lookups++
+		if core.lookups > 1 {
+			return 1, nil
+		}
+	}
+	return core.mockClientCore.Confirmations(txHash)
+}
+
+var _ = Describe("Double spend detection", func() {
+	// transfer publishes a transfer of the account, and returns the utxo it
+	// spends.
+	transfer := func(core *mockClientCore, store TxStore) (string, clients.UTXO) {
+		account, _, addr := newMockAccount(core, 1000000)
+		account.SetTxStore(store)
+		utxos, err := core.GetUTXOs(addr.EncodeAddress(), 0, 0)
+		Expect(err).Should(BeNil())
+		Expect(utxos).Should(HaveLen(1))
+		_, _, to := newMockAccount(core, 0)
+		receipt, err := account.Transfer(context.Background(), to.EncodeAddress(), 100000, Standard, false)
+		Expect(err).Should(BeNil())
+		return receipt.TxHash, utxos[0]
+	}
+
+	expectState := func(store TxStore, txHash string, state TxState) {
+		tx, ok, err := store.Get(txHash)
+		Expect(err).Should(BeNil())
+		Expect(ok).Should(BeTrue())
+		Expect(tx.State).Should(Equal(state))
+	}
+
+	It("should report the pending transactions whose inputs are spent once", func() {
+		core := newMockClientCore(&chaincfg.TestNet3Params, 1842420)
+		store := NewMemoryTxStore()
+		txHash, utxo := transfer(core, store)
+		detector := NewDoubleSpendDetector(NewClient(core), store, logrus.StandardLogger())
+
+		doubleSpends, err := detector.Check()
+		Expect(err).Should(BeNil())
+		Expect(doubleSpends).Should(BeEmpty())
+
+		core.spend(utxo)
+		doubleSpends, err = detector.Check()
+		Expect(err).Should(BeNil())
+		Expect(doubleSpends).Should(HaveLen(1))
+		Expect(doubleSpends[0].TxHash).Should(Equal(txHash))
+		Expect(doubleSpends[0].OutPoint).Should(Equal(fmt.Sprintf("%s:%d", utxo.TxHash, utxo.Vout)))
+		Expect(doubleSpends[0].ConflictingTxHash).Should(BeEmpty())
+		expectState(store, txHash, TxStateConflicted)
+
+		doubleSpends, err = detector.Check()
+		Expect(err).Should(BeNil())
+		Expect(doubleSpends).Should(BeEmpty())
+	})
+
+	It("should report the conflicting transaction and its confirmations", func() {
+		core := newMockClientCore(&chaincfg.TestNet3Params, 1842420)
+		spenderCore := &spenderMockClientCore{core, map[string]string{}}
+		store := NewMemoryTxStore()
+		txHash, utxo := transfer(core, store)
+		detector := NewDoubleSpendDetector(NewClient(spenderCore), store, logrus.StandardLogger())
+
+		conflicting := chainhash.Hash{0xC0}.String()
+		core.spend(utxo)
+		spenderCore.spenders[fmt.Sprintf("%s:%d", utxo.TxHash, utxo.Vout)] = conflicting
+		core.confirmations[conflicting] = 2
+		doubleSpends, err := detector.Check()
+		Expect(err).Should(BeNil())
+		Expect(doubleSpends).Should(HaveLen(1))
+		Expect(doubleSpends[0].TxHash).Should(Equal(txHash))
+		Expect(doubleSpends[0].ConflictingTxHash).Should(Equal(conflicting))
+		Expect(doubleSpends[0].ConflictingConfirmations).Should(Equal(int64(2)))
+		Expect(doubleSpends[0].Confirmed()).Should(BeTrue())
+	})
+
+	It("should not report transactions mined while their inputs are checked", func() {
+		core := newMockClientCore(&chaincfg.TestNet3Params, 1842420)
+		store := NewMemoryTxStore()
+		txHash, utxo := transfer(core, store)
+		detector := NewDoubleSpendDetector(NewClient(&minedMockClientCore{mockClientCore: core, txHash: txHash}), store, logrus.StandardLogger())
+
+		// The transaction spends the utxo itself once mined.
+		core.spend(utxo)
+		doubleSpends, err := detector.Check()
+		Expect(err).Should(BeNil())
+		Expect(doubleSpends).Should(BeEmpty())
+		expectState(store, txHash, TxStateConfirmed)
+	})
+
+	It("should not report inputs whose transaction cannot be found", func() {
+		core := newMockClientCore(&chaincfg.TestNet3Params, 1842420)
+		store := NewMemoryTxStore()
+		txHash, utxo := transfer(core, store)
+		detector := NewDoubleSpendDetector(NewClient(core), store, logrus.StandardLogger())
+
+		// Clients report the outputs of missing transactions as spent.
+		core.spend(utxo)
+		delete(core.confirmations, utxo.TxHash)
+		doubleSpends, err := detector.Check()
+		Expect(err).Should(BeNil())
+		Expect(doubleSpends).Should(BeEmpty())
+		expectState(store, txHash, TxStatePending)
+	})
+})
@@ -0,0 +1,513 @@
+package libzec
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/iqoption/zecutil"
+)
+
+// The btcd script engine computes bitcoin signature hashes, so it cannot be
+// used to validate ZCash transactions. VerifyScript implements the subset of
+// the script language used by the scripts in this package (P2PKH, P2SH, slave
+// scripts, multisig and hash time locked contracts), using the ZCash signature
+// hash. It enforces the standard verification flags of zcashd that apply to
+// these scripts: strictly encoded signatures and public keys, low S values,
+// a null dummy for OP_CHECKMULTISIG and a clean stack.
+
+type scriptOp struct {
+	opcode byte
+	data   []byte
+	push   bool
+}
+
+// VerifyScript executes the signature script of the input at the given index
+// against the public key script it spends, and returns an error if the input
+// is not validly signed. Signatures are checked against the given consensus
+// branch id, as in CalcSignatureHash.
+func VerifyScript(msgTx *zecutil.MsgTx, idx int, scriptPubKey []byte, amount int64, branchID uint32) error {
+	return verifyScript(msgTx, idx, scriptPubKey, amount, branchSigHashKey(branchID))
+}
+
+// VerifyScriptForBranch is the same as VerifyScript.
+//
+// Deprecated: use VerifyScript.
+func VerifyScriptForBranch(msgTx *zecutil.MsgTx, idx int, scriptPubKey []byte, amount int64, branchID uint32) error {
+	return VerifyScript(msgTx, idx, scriptPubKey, amount, branchID)
+}
+
+func verifyScript(msgTx *zecutil.MsgTx, idx int, scriptPubKey []byte, amount int64, sigHashKey []byte) error {
+	if idx < 0 || idx >= len(msgTx.TxIn) {
+		return fmt.Errorf("invalid input index %d: transaction has %d inputs", idx, len(msgTx.TxIn))
+	}
+
+	sigOps, err := parseScript(msgTx.TxIn[idx].SignatureScript)
+	if err != nil {
+		return err
+	}
+	stack := [][]byte{}
+	for _, op := range sigOps {
+		if !op.push {
+			return fmt.Errorf("signature script is not push only")
+		}
+		stack = append(stack, op.data)
+	}
+
+	subScript := scriptPubKey
+	if txscript.IsPayToScriptHash(scriptPubKey) {
+		if len(stack) == 0 {
+			return fmt.Errorf("missing redeem script")
+		}
+		subScript = stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if !bytes.Equal(btcutil.Hash160(subScript), scriptPubKey[2:22]) {
+			return fmt.Errorf("redeem script does not match the script hash")
+		}
+	}
+
+	vm := &engine{msgTx: msgTx, idx: idx, amount: amount, subScript: subScript, sigHashKey: sigHashKey, stack: stack}
+	if err := vm.execute(); err != nil {
+		return err
+	}
+	if len(vm.stack) == 0 || !asBool(vm.stack[len(vm.stack)-1]) {
+		return fmt.Errorf("script evaluated to false")
+	}
+	if len(vm.stack) != 1 {
+		return fmt.Errorf("stack is not clean: %d items left", len(vm.stack))
+	}
+	return nil
+}
+
+type engine struct {
+	msgTx      *zecutil.MsgTx
+	idx        int
+	amount     int64
+	subScript  []byte
+	sigHashKey []byte
+	stack      [][]byte
+	condStack  []bool
+}
+
+func (vm *engine) execute() error {
+	ops, err := parseScript(vm.subScript)
+	if err != nil {
+		return err
+	}
+	for _, op := range ops {
+		executing := true
+		for _, cond := range vm.condStack {
+			executing = executing && cond
+		}
+
+		switch op.opcode {
+		case txscript.OP_IF, txscript.OP_NOTIF:
+			cond := false
+			if executing {
+				val, err := vm.pop()
+				if err != nil {
+					return err
+				}
+				cond = asBool(val) == (op.opcode == txscript.OP_IF)
+			}
+			vm.condStack = append(vm.condStack, cond)
+			continue
+		case txscript.OP_ELSE:
+			if len(vm.condStack) == 0 {
+				return fmt.Errorf("unbalanced conditional")
+			}
+			vm.condStack[len(vm.condStack)-1] = !vm.condStack[len(vm.condStack)-1]
+			continue
+		case txscript.OP_ENDIF:
+			if len(vm.condStack) == 0 {
+				return fmt.Errorf("unbalanced conditional")
+			}
+			vm.condStack = vm.condStack[:len(vm.condStack)-1]
+			continue
+		}
+		if !executing {
+			continue
+		}
+		if op.push {
+			vm.stack = append(vm.stack, op.data)
+			continue
+		}
+		if err := vm.executeOp(op.opcode); err != nil {
+			return err
+		}
+	}
+	if len(vm.condStack) != 0 {
+		return fmt.Errorf("unbalanced conditional")
+	}
+	return nil
+}
+
+func (vm *engine) executeOp(opcode byte) error {
+	switch opcode {
+	case txscript.OP_NOP:
+		return nil
+	case txscript.OP_VERIFY:
+		return vm.verify()
+	case txscript.OP_RETURN:
+		return fmt.Errorf("script returned early")
+	case txscript.OP_DROP:
+		_, err := vm.pop()
+		return err
+	case txscript.OP_DUP:
+		val, err := vm.peek()
+		if err != nil {
+			return err
+		}
+		vm.stack = append(vm.stack, val)
+		return nil
+	case txscript.OP_SIZE:
+		val, err := vm.peek()
+		if err != nil {
+			return err
+		}
+		vm.stack = append(vm.stack, encodeScriptNum(int64(len(val))))
+		return nil
+	case txscript.OP_HASH160:
+		val, err := vm.pop()
+		if err != nil {
+			return err
+		}
+		vm.stack = append(vm.stack, btcutil.Hash160(val))
+		return nil
+	case txscript.OP_SHA256:
+		val, err := vm.pop()
+		if err != nil {
+			return err
+		}
+		hash := sha256.Sum256(val)
+		vm.stack = append(vm.stack, hash[:])
+		return nil
+	case txscript.OP_EQUAL, txscript.OP_EQUALVERIFY:
+		a, err := vm.pop()
+		if err != nil {
+			return err
+		}
+		b, err := vm.pop()
+		if err != nil {
+			return err
+		}
+		vm.stack = append(vm.stack, fromBool(bytes.Equal(a, b)))
+		if opcode == txscript.OP_EQUALVERIFY {
+			return vm.verify()
+		}
+		return nil
+	case txscript.OP_CHECKSIG, txscript.OP_CHECKSIGVERIFY:
+		pubKey, err := vm.pop()
+		if err != nil {
+			return err
+		}
+		sig, err := vm.pop()
+		if err != nil {
+			return err
+		}
+		valid, err := vm.checkSig(sig, pubKey)
+		if err != nil {
+			return err
+		}
+		vm.stack = append(vm.stack, fromBool(valid))
+		if opcode == txscript.OP_CHECKSIGVERIFY {
+			return vm.verify()
+		}
+		return nil
+	case txscript.OP_CHECKMULTISIG, txscript.OP_CHECKMULTISIGVERIFY:
+		if err := vm.checkMultiSig(); err != nil {
+			return err
+		}
+		if opcode == txscript.OP_CHECKMULTISIGVERIFY {
+			return vm.verify()
+		}
+		return nil
+	case txscript.OP_CHECKLOCKTIMEVERIFY:
+		return vm.checkLockTime()
+	default:
+		return fmt.Errorf("unsupported opcode 0x%x", opcode)
+	}
+}
+
+// checkSig returns whether the signature is a valid signature of the input by
+// the public key. An error is returned if the signature or the public key is
+// not strictly encoded, as such inputs are not standard.
+func (vm *engine) checkSig(sig, pubKeyBytes []byte) (bool, error) {
+	if len(sig) < 1 {
+		return false, nil
+	}
+	if err := checkSigEncoding(sig); err != nil {
+		return false, err
+	}
+	if err := checkPubKeyEncoding(pubKeyBytes); err != nil {
+		return false, err
+	}
+	hashType := txscript.SigHashType(sig[len(sig)-1])
+	signature, err := btcec.ParseDERSignature(sig[:len(sig)-1], btcec.S256())
+	if err != nil {
+		return false, err
+	}
+	pubKey, err := btcec.ParsePubKey(pubKeyBytes, btcec.S256())
+	if err != nil {
+		return false, err
+	}
+	hash, err := calcSignatureHash(vm.subScript, hashType, vm.msgTx, vm.idx, vm.amount, vm.sigHashKey)
+	if err != nil {
+		return false, err
+	}
+	return signature.Verify(hash, pubKey), nil
+}
+
+// checkSigEncoding returns an error if the signature, followed by its hash
+// type, is not strictly DER encoded with a low S value and a defined hash
+// type.
+func checkSigEncoding(sig []byte) error {
+	switch txscript.SigHashType(sig[len(sig)-1]) &^ txscript.SigHashAnyOneCanPay {
+	case txscript.SigHashAll, txscript.SigHashNone, txscript.SigHashSingle:
+	default:
+		return fmt.Errorf("undefined signature hash type 0x%x", sig[len(sig)-1])
+	}
+	signature, err := btcec.ParseDERSignature(sig[:len(sig)-1], btcec.S256())
+	if err != nil {
+		return fmt.Errorf("signature is not strictly DER encoded: %v", err)
+	}
+	// Serialize encodes the low S value minimally, so any other encoding of
+	// the same signature is not canonical.
+	if !bytes.Equal(signature.Serialize(), sig[:len(sig)-1]) {
+		return fmt.Errorf("signature is not canonical: high S value or non minimal encoding")
+	}
+	return nil
+}
+
+// checkPubKeyEncoding returns an error if the public key is neither a
+// compressed nor an uncompressed public key.
+func checkPubKeyEncoding(pubKey []byte) error {
+	if len(pubKey) == btcec.PubKeyBytesLenCompressed && (pubKey[0] == 0x02 || pubKey[0] == 0x03) {
+		return nil
+	}
+	if len(pubKey) == btcec.PubKeyBytesLenUncompressed && pubKey[0] == 0x04 {
+		return nil
+	}
+	return fmt.Errorf("public key is not strictly encoded: %x", pubKey)
+}
+
+func (vm *engine) checkMultiSig() error {
+	n, err := vm.popInt()
+	if err != nil {
+		return err
+	}
+	if n < 0 || n > txscript.MaxPubKeysPerMultiSig {
+		return fmt.Errorf("invalid number of public keys: %d", n)
+	}
+	pubKeys := make([][]byte, n)
+	for i := n - 1; i >= 0; i-- {
+		if pubKeys[i], err = vm.pop(); err != nil {
+			return err
+		}
+	}
+	m, err := vm.popInt()
+	if err != nil {
+		return err
+	}
+	if m < 0 || m > n {
+		return fmt.Errorf("invalid number of signatures: %d", m)
+	}
+	sigs := make([][]byte, m)
+	for i := m - 1; i >= 0; i-- {
+		if sigs[i], err = vm.pop(); err != nil {
+			return err
+		}
+	}
+	// OP_CHECKMULTISIG pops an extra unused item from the stack, that must
+	// be empty.
+	dummy, err := vm.pop()
+	if err != nil {
+		return err
+	}
+	if len(dummy) != 0 {
+		return fmt.Errorf("OP_CHECKMULTISIG dummy is not null")
+	}
+
+	success := true
+	pubKeyIdx := 0
+	for _, sig := range sigs {
+		for pubKeyIdx < len(pubKeys) {
+			valid, err := vm.checkSig(sig, pubKeys[pubKeyIdx])
+			if err != nil {
+				return err
+			}
+			if valid {
+				break
+			}
+			pubKeyIdx++
+		}
+		if pubKeyIdx == len(pubKeys) {
+			success = false
+			break
+		}
+		pubKeyIdx++
+	}
+	vm.stack = append(vm.stack, fromBool(success))
+	return nil
+}
+
+func (vm *engine) checkLockTime() error {
+	val, err := vm.peek()
+	if err != nil {
+		return err
+	}
+	lockTime, err := scriptNum(val)
+	if err != nil {
+		return err
+	}
+	if lockTime < 0 {
+		return fmt.Errorf("negative lock time: %d", lockTime)
+	}
+	txLockTime := int64(vm.msgTx.LockTime)
+	if (txLockTime < txscript.LockTimeThreshold) != (lockTime < txscript.LockTimeThreshold) {
+		return fmt.Errorf("mismatched lock time types: tx: %d script: %d", txLockTime, lockTime)
+	}
+	if lockTime > txLockTime {
+		return fmt.Errorf("lock time has not been reached: tx: %d script: %d", txLockTime, lockTime)
+	}
+	if vm.msgTx.TxIn[vm.idx].Sequence == wire.MaxTxInSequenceNum {
+		return fmt.Errorf("lock time is disabled by a final sequence number")
+	}
+	return nil
+}
+
+func (vm *engine) verify() error {
+	val, err := vm.pop()
+	if err != nil {
+		return err
+	}
+	if !asBool(val) {
+		return fmt.Errorf("verify failed")
+	}
+	return nil
+}
+
+func (vm *engine) pop() ([]byte, error) {
+	if len(vm.stack) == 0 {
+		return nil, fmt.Errorf("stack underflow")
+	}
+	val := vm.stack[len(vm.stack)-1]
+	vm.stack = vm.stack[:len(vm.stack)-1]
+	return val, nil
+}
+
+func (vm *engine) peek() ([]byte, error) {
+	if len(vm.stack) == 0 {
+		return nil, fmt.Errorf("stack underflow")
+	}
+	return vm.stack[len(vm.stack)-1], nil
+}
+
+func (vm *engine) popInt() (int, error) {
+	val, err := vm.pop()
+	if err != nil {
+		return 0, err
+	}
+	num, err := scriptNum(val)
+	return int(num), err
+}
+
+// parseScript splits the script into its opcodes and pushed data.
+func parseScript(script []byte) ([]scriptOp, error) {
+	ops := []scriptOp{}
+	for i := 0; i < len(script); {
+		opcode := script[i]
+		i++
+
+		var size int
+		switch {
+		case opcode == txscript.OP_0:
+			ops = append(ops, scriptOp{opcode: opcode, data: []byte{}, push: true})
+			continue
+		case opcode <= txscript.OP_DATA_75:
+			size = int(opcode)
+		case opcode == txscript.OP_PUSHDATA1:
+			if i+1 > len(script) {
+				return nil, fmt.Errorf("malformed push at %d", i)
+			}
+			size = int(script[i])
+			i++
+		case opcode == txscript.OP_PUSHDATA2:
+			if i+2 > len(script) {
+				return nil, fmt.Errorf("malformed push at %d", i)
+			}
+			size = int(binary.LittleEndian.Uint16(script[i:]))
+			i += 2
+		case opcode == txscript.OP_PUSHDATA4:
+			if i+4 > len(script) {
+				return nil, fmt.Errorf("malformed push at %d", i)
+			}
+			size = int(binary.LittleEndian.Uint32(script[i:]))
+			i += 4
+		case opcode == txscript.OP_1NEGATE:
+			ops = append(ops, scriptOp{opcode: opcode, data: encodeScriptNum(-1), push: true})
+			continue
+		case opcode >= txscript.OP_1 && opcode <= txscript.OP_16:
+			ops = append(ops, scriptOp{opcode: opcode, data: encodeScriptNum(int64(opcode - txscript.OP_1 + 1)), push: true})
+			continue
+		default:
+			ops = append(ops, scriptOp{opcode: opcode})
+			continue
+		}
+
+		if size < 0 || i+size > len(script) {
+			return nil, fmt.Errorf("malformed push at %d", i)
+		}
+		ops = append(ops, scriptOp{opcode: opcode, data: script[i : i+size], push: true})
+		i += size
+	}
+	return ops, nil
+}
+
+// encodeScriptNum encodes the number as a minimally encoded script number.
+func encodeScriptNum(num int64) []byte {
+	if num == 0 {
+		return []byte{}
+	}
+	negative := num < 0
+	if negative {
+		num = -num
+	}
+	result := []byte{}
+	for num > 0 {
+		result = append(result, byte(num&0xff))
+		num >>= 8
+	}
+	if result[len(result)-1]&0x80 != 0 {
+		extra := byte(0x00)
+		if negative {
+			extra = 0x80
+		}
+		result = append(result, extra)
+	} else if negative {
+		result[len(result)-1] |= 0x80
+	}
+	return result
+}
+
+func asBool(val []byte) bool {
+	for i, b := range val {
+		if b != 0 {
+			// Negative zero is false.
+			return !(i == len(val)-1 && b == 0x80)
+		}
+	}
+	return false
+}
+
+func fromBool(val bool) []byte {
+	if val {
+		return []byte{1}
+	}
+	return []byte{}
+}
@@ -0,0 +1,260 @@
+package libzec_test
+
+import (
+	"math/big"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/iqoption/zecutil"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/renproject/libzec-go"
+)
+
+var _ = Describe("Script verification", func() {
+	buildTx := func() *zecutil.MsgTx {
+		msgTx := &zecutil.MsgTx{
+			MsgTx:        wire.NewMsgTx(4),
+			ExpiryHeight: ZCashExpiryHeight,
+		}
+		msgTx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&chainhash.Hash{}, 0), []byte{}, [][]byte{}))
+		msgTx.AddTxOut(wire.NewTxOut(10000, []byte{txscript.OP_TRUE}))
+		return msgTx
+	}
+
+	sign := func(privKey *btcec.PrivateKey, msgTx *zecutil.MsgTx, subScript []byte, amount int64) []byte {
+		hash, err := CalcSignatureHash(subScript, txscript.SigHashAll, msgTx, 0, amount, 0xC2D6D0B4)
+		Expect(err).Should(BeNil())
+		sig, err := privKey.Sign(hash)
+		Expect(err).Should(BeNil())
+		return append(sig.Serialize(), byte(txscript.SigHashAll))
+	}
+
+	It("should verify a signed P2PKH input", func() {
+		privKey, err := btcec.NewPrivateKey(btcec.S256())
+		Expect(err).Should(BeNil())
+		pubKey := privKey.PubKey().SerializeCompressed()
+		addr, err := AddressFromHash160(toHash160(btcutil.Hash160(pubKey)), &chaincfg.TestNet3Params, false)
+		Expect(err).Should(BeNil())
+		scriptPubKey, err := PayToAddrScript(addr)
+		Expect(err).Should(BeNil())
+
+		msgTx := buildTx()
+		sigScript, err := txscript.NewScriptBuilder().
+			AddData(sign(privKey, msgTx, scriptPubKey, 20000)).
+			AddData(pubKey).
+			Script()
+		Expect(err).Should(BeNil())
+		msgTx.TxIn[0].SignatureScript = sigScript
+
+		Expect(VerifyScript(msgTx, 0, scriptPubKey, 20000, 0xC2D6D0B4)).Should(BeNil())
+		Expect(VerifyScript(msgTx, 0, scriptPubKey, 30000, 0xC2D6D0B4)).ShouldNot(BeNil())
+	})
+
+	It("should verify a signed multisig input", func() {
+		privKeys := make([]*btcec.PrivateKey, 3)
+		pubKeys := make([][]byte, 3)
+		for i := range privKeys {
+			var err error
+			privKeys[i], err = btcec.NewPrivateKey(btcec.S256())
+			Expect(err).Should(BeNil())
+			pubKeys[i] = privKeys[i].PubKey().SerializeCompressed()
+		}
+		script, err := MultisigScript(2, pubKeys)
+		Expect(err).Should(BeNil())
+		addr, err := ScriptAddress(script, &chaincfg.TestNet3Params)
+		Expect(err).Should(BeNil())
+		scriptPubKey, err := PayToAddrScript(addr)
+		Expect(err).Should(BeNil())
+
+		msgTx := buildTx()
+		sigScript, err := MultisigSigScript([][]byte{
+			sign(privKeys[0], msgTx, script, 20000),
+			sign(privKeys[2], msgTx, script, 20000),
+		}, script)
+		Expect(err).Should(BeNil())
+		msgTx.TxIn[0].SignatureScript = sigScript
+		Expect(VerifyScript(msgTx, 0, scriptPubKey, 20000, 0xC2D6D0B4)).Should(BeNil())
+
+		sigScript, err = MultisigSigScript([][]byte{
+			sign(privKeys[2], msgTx, script, 20000),
+			sign(privKeys[0], msgTx, script, 20000),
+		}, script)
+		Expect(err).Should(BeNil())
+		msgTx.TxIn[0].SignatureScript = sigScript
+		Expect(VerifyScript(msgTx, 0, scriptPubKey, 20000, 0xC2D6D0B4)).ShouldNot(BeNil())
+	})
+
+	It("should reject non standard signature scripts", func() {
+		privKey, err := btcec.NewPrivateKey(btcec.S256())
+		Expect(err).Should(BeNil())
+		pubKey := privKey.PubKey().SerializeCompressed()
+		addr, err := AddressFromHash160(toHash160(btcutil.Hash160(pubKey)), &chaincfg.TestNet3Params, false)
+		Expect(err).Should(BeNil())
+		scriptPubKey, err := PayToAddrScript(addr)
+		Expect(err).Should(BeNil())
+		msgTx := buildTx()
+		hash, err := CalcSignatureHash(scriptPubKey, txscript.SigHashAll, msgTx, 0, 20000, 0xC2D6D0B4)
+		Expect(err).Should(BeNil())
+		sig, err := privKey.Sign(hash)
+		Expect(err).Should(BeNil())
+
+		verify := func(pushes ...[]byte) error {
+			builder := txscript.NewScriptBuilder()
+			for _, push := range pushes {
+				builder.AddData(push)
+			}
+			sigScript, err := builder.Script()
+			Expect(err).Should(BeNil())
+			msgTx.TxIn[0].SignatureScript = sigScript
+			return VerifyScript(msgTx, 0, scriptPubKey, 20000, 0xC2D6D0B4)
+		}
+		lowS := append(sig.Serialize(), byte(txscript.SigHashAll))
+		Expect(verify(lowS, pubKey)).Should(BeNil())
+
+		// The high S value of the same signature.
+		highS := new(big.Int).Sub(btcec.S256().N, sig.S)
+		r, s := sig.R.Bytes(), highS.Bytes()
+		if r[0]&0x80 != 0 {
+			r = append([]byte{0}, r...)
+		}
+		if s[0]&0x80 != 0 {
+			s = append([]byte{0}, s...)
+		}
+		der := append([]byte{0x30, byte(4 + len(r) + len(s)), 0x02, byte(len(r))}, r...)
+		der = append(der, 0x02, byte(len(s)))
+		der = append(der, s...)
+		Expect(verify(append(der, byte(txscript.SigHashAll)), pubKey)).ShouldNot(BeNil())
+
+		// An undefined hash type, and a clean stack violation.
+		Expect(verify(append(sig.Serialize(), 0x04), pubKey)).ShouldNot(BeNil())
+		Expect(verify([]byte{1}, lowS, pubKey)).ShouldNot(BeNil())
+	})
+
+	It("should reject a multisig input with a non null dummy", func() {
+		privKeys := make([]*btcec.PrivateKey, 2)
+		pubKeys := make([][]byte, 2)
+		for i := range privKeys {
+			var err error
+			privKeys[i], err = btcec.NewPrivateKey(btcec.S256())
+			Expect(err).Should(BeNil())
+			pubKeys[i] = privKeys[i].PubKey().SerializeCompressed()
+		}
+		script, err := MultisigScript(1, pubKeys)
+		Expect(err).Should(BeNil())
+		scriptPubKey, err := PayToScriptHashScript(script)
+		Expect(err).Should(BeNil())
+
+		msgTx := buildTx()
+		sig := sign(privKeys[1], msgTx, script, 20000)
+		sigScript, err := txscript.NewScriptBuilder().AddOp(txscript.OP_0).AddData(sig).AddData(script).Script()
+		Expect(err).Should(BeNil())
+		msgTx.TxIn[0].SignatureScript = sigScript
+		Expect(VerifyScript(msgTx, 0, scriptPubKey, 20000, 0xC2D6D0B4)).Should(BeNil())
+
+		sigScript, err = txscript.NewScriptBuilder().AddOp(txscript.OP_1).AddData(sig).AddData(script).Script()
+		Expect(err).Should(BeNil())
+		msgTx.TxIn[0].SignatureScript = sigScript
+		Expect(VerifyScript(msgTx, 0, scriptPubKey, 20000, 0xC2D6D0B4)).ShouldNot(BeNil())
+	})
+
+	It("should verify an input against the consensus branch it was signed for", func() {
+		privKey, err := btcec.NewPrivateKey(btcec.S256())
+		Expect(err).Should(BeNil())
+		pubKey := privKey.PubKey().SerializeCompressed()
+		addr, err := AddressFromHash160(toHash160(btcutil.Hash160(pubKey)), &chaincfg.TestNet3Params, false)
+		Expect(err).Should(BeNil())
+		scriptPubKey, err := PayToAddrScript(addr)
+		Expect(err).Should(BeNil())
+
+		msgTx := buildTx()
+		hash, err := CalcSignatureHash(scriptPubKey, txscript.SigHashAll, msgTx, 0, 20000, 0xC2D6D0B4)
+		Expect(err).Should(BeNil())
+		sig, err := privKey.Sign(hash)
+		Expect(err).Should(BeNil())
+		sigScript, err := txscript.NewScriptBuilder().
+			AddData(append(sig.Serialize(), byte(txscript.SigHashAll))).
+			AddData(pubKey).
+			Script()
+		Expect(err).Should(BeNil())
+		msgTx.TxIn[0].SignatureScript = sigScript
+
+		Expect(VerifyScript(msgTx, 0, scriptPubKey, 20000, 0xC2D6D0B4)).Should(BeNil())
+		Expect(VerifyScript(msgTx, 0, scriptPubKey, 20000, 0xE9FF75A6)).ShouldNot(BeNil())
+	})
+})
+
+var _ = Describe("Shielded signature hashes", func() {
+	It("should commit to the shielded components", func() {
+		msgTx := &zecutil.MsgTx{
+			MsgTx:        wire.NewMsgTx(4),
+			ExpiryHeight: ZCashExpiryHeight,
+		}
+		msgTx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&chainhash.Hash{}, 0), []byte{}, [][]byte{}))
+		subScript := []byte{txscript.OP_TRUE}
+
+		transparent, err := CalcSignatureHash(subScript, txscript.SigHashAll, msgTx, 0, 20000, 0xC2D6D0B4)
+		Expect(err).Should(BeNil())
+		empty, err := CalcSignatureHashWithShieldedData(subScript, txscript.SigHashAll, msgTx, 0, 20000, 0xC2D6D0B4, &ShieldedData{})
+		Expect(err).Should(BeNil())
+		Expect(empty).Should(Equal(transparent))
+
+		shielded, err := CalcSignatureHashWithShieldedData(subScript, txscript.SigHashAll, msgTx, 0, 20000, 0xC2D6D0B4, &ShieldedData{
+			ValueBalance: -10000,
+			Outputs:      []SaplingOutput{{CMU: [32]byte{1}}},
+		})
+		Expect(err).Should(BeNil())
+		Expect(shielded).ShouldNot(Equal(transparent))
+	})
+})
+
+var _ = Describe("Consensus branches", func() {
+	It("should select the branch of the next block", func() {
+		branchID, err := ConsensusBranchID(&chaincfg.MainNetParams, 419198)
+		Expect(err).Should(BeNil())
+		Expect(branchID).Should(Equal(uint32(0x5BA81B19)))
+
+		branchID, err = ConsensusBranchID(&chaincfg.MainNetParams, 419199)
+		Expect(err).Should(BeNil())
+		Expect(branchID).Should(Equal(uint32(0x76B809BB)))
+
+		branchID, err = ConsensusBranchID(&chaincfg.TestNet3Params, 1842419)
+		Expect(err).Should(BeNil())
+		Expect(branchID).Should(Equal(uint32(0xC2D6D0B4)))
+	})
+
+	It("should select NU6.1 after its activation", func() {
+		branchID, err := ConsensusBranchID(&chaincfg.MainNetParams, 3146398)
+		Expect(err).Should(BeNil())
+		Expect(branchID).Should(Equal(uint32(0xC8E71055)))
+
+		branchID, err = ConsensusBranchID(&chaincfg.MainNetParams, 3146399)
+		Expect(err).Should(BeNil())
+		Expect(branchID).Should(Equal(uint32(0x4DEC4DF0)))
+
+		branchID, err = ConsensusBranchID(&chaincfg.TestNet3Params, 3536499)
+		Expect(err).Should(BeNil())
+		Expect(branchID).Should(Equal(uint32(0x4DEC4DF0)))
+	})
+
+	It("should select NU5 on regtest", func() {
+		branchID, err := ConsensusBranchID(&chaincfg.RegressionNetParams, 100)
+		Expect(err).Should(BeNil())
+		Expect(branchID).Should(Equal(uint32(0xC2D6D0B4)))
+	})
+
+	It("should not select a branch for unsupported networks", func() {
+		_, err := ConsensusBranchID(&chaincfg.SimNetParams, 100)
+		Expect(err).ShouldNot(BeNil())
+	})
+})
+
+func toHash160(b []byte) [20]byte {
+	hash := [20]byte{}
+	copy(hash[:], b)
+	return hash
+}
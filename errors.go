@@ -3,31 +3,121 @@ package libzec
 import (
 	"errors"
 	"fmt"
+
+	liberrors "github.com/renproject/libzec-go/errors"
+)
+
+// The errors shared with the clients are defined in the errors package, so
+// that they can be matched with errors.Is regardless of where they were
+// returned.
+var (
+	ErrPreConditionCheckFailed  = liberrors.ErrPreConditionCheckFailed
+	ErrPostConditionCheckFailed = liberrors.ErrPostConditionCheckFailed
+	ErrTimedOut                 = liberrors.ErrTimedOut
+	ErrNotSupported             = liberrors.ErrNotSupported
+	ErrNoSpendingTransactions   = liberrors.ErrNoSpendingTransactions
+	ErrMismatchedPubKeys        = liberrors.ErrMismatchedPubKeys
+	ErrInsufficientBalance      = liberrors.ErrInsufficientBalance
+	ErrUnsupportedNetwork       = liberrors.ErrUnsupportedNetwork
+	ErrBroadcast                = liberrors.ErrBroadcast
+	ErrAlreadyInMempool         = liberrors.ErrAlreadyInMempool
+	ErrMissingInputs            = liberrors.ErrMissingInputs
+	ErrInsufficientFee          = liberrors.ErrInsufficientFee
+	ErrExpired                  = liberrors.ErrExpired
+	ErrScriptVerification       = liberrors.ErrScriptVerification
+	ErrNonStandard              = liberrors.ErrNonStandard
+	ErrUTXOSpent                = liberrors.ErrUTXOSpent
+	ErrTxNotFound               = liberrors.ErrTxNotFound
+	ErrBackendUnavailable       = liberrors.ErrBackendUnavailable
+	ErrWrongNetwork             = liberrors.ErrWrongNetwork
+	ErrUnsupportedReceivers     = liberrors.ErrUnsupportedReceivers
+	ErrOrchardOnly              = liberrors.ErrOrchardOnly
+	ErrTxTooLarge               = liberrors.ErrTxTooLarge
+	ErrFeeOutOfBounds           = liberrors.ErrFeeOutOfBounds
+	ErrDustChange               = liberrors.ErrDustChange
+	ErrNegativeLimit            = liberrors.ErrNegativeLimit
+	ErrNegativeConfirmations    = liberrors.ErrNegativeConfirmations
 )
 
-// ErrPreConditionCheckFailed indicates that the pre-condition for executing
-// a transaction failed.
-var ErrPreConditionCheckFailed = errors.New("pre-condition check failed")
+// Typed errors, that can be inspected using errors.As.
+type (
+	BroadcastError            = liberrors.BroadcastError
+	InsufficientBalanceError  = liberrors.InsufficientBalanceError
+	UnsupportedNetworkError   = liberrors.UnsupportedNetworkError
+	BackendUnavailableError   = liberrors.BackendUnavailableError
+	UnsupportedReceiversError = liberrors.UnsupportedReceiversError
+	WrongNetworkError         = liberrors.WrongNetworkError
+	TxTooLargeError           = liberrors.TxTooLargeError
+	FeeOutOfBoundsError       = liberrors.FeeOutOfBoundsError
+	DustChangeError           = liberrors.DustChangeError
+	TxExpiredError            = liberrors.TxExpiredError
+)
+
+// ErrTxExpired indicates that a transaction was not mined before its expiry
+// height, and will never be mined.
+var ErrTxExpired = liberrors.ErrExpired
+
+// ErrWatchOnly indicates that a watch-only account was asked to sign.
+var ErrWatchOnly = errors.New("cannot sign with a watch-only account")
 
-// ErrPostConditionCheckFailed indicates that the post-condition for executing
-// a transaction failed.
-var ErrPostConditionCheckFailed = errors.New("post-condition check failed")
+// ErrNoPublicKey indicates that the public key of an account is unknown.
+var ErrNoPublicKey = errors.New("public key is unknown")
 
-var ErrTimedOut = errors.New("timed out")
+// ErrKeystoreLocked indicates that a locked keystore was used.
+var ErrKeystoreLocked = errors.New("keystore is locked")
 
-var ErrNoSpendingTransactions = fmt.Errorf("No spending transactions")
+// ErrInvalidDiversifier indicates that a diversifier index of a Sapling key
+// does not have an address.
+var ErrInvalidDiversifier = errors.New("invalid sapling diversifier")
 
-var ErrMismatchedPubKeys = fmt.Errorf("failed to fund the transaction mismatched script public keys")
+// ErrDiversifierSpaceExhausted indicates that every diversified address of a
+// Sapling key has been used.
+var ErrDiversifierSpaceExhausted = errors.New("sapling diversifier space exhausted")
+
+// ErrNoZcashdWallet indicates that a shielded operation was requested from an
+// account whose client is not backed by a zcashd wallet.
+var ErrNoZcashdWallet = errors.New("client is not backed by a zcashd wallet")
+
+// ErrInvalidPassphrase indicates that a keystore could not be unlocked with
+// the given passphrase.
+var ErrInvalidPassphrase = errors.New("invalid passphrase")
 
 func NewErrUnsupportedNetwork(network string) error {
-	return fmt.Errorf("unsupported network %s", network)
+	return liberrors.NewErrUnsupportedNetwork(network)
+}
+
+func NewErrWrongNetwork(address, network, expected string) error {
+	return liberrors.NewErrWrongNetwork(address, network, expected)
+}
+
+func NewErrUnsupportedReceivers(address string, receivers []string) error {
+	return liberrors.NewErrUnsupportedReceivers(address, receivers)
 }
 
 func NewErrZCashSubmitTx(msg string) error {
-	return fmt.Errorf("error while submitting ZCash transaction: %s", msg)
+	return liberrors.NewErrZCashSubmitTx(msg)
 }
 
 func NewErrInsufficientBalance(address string, required, current int64) error {
-	return fmt.Errorf("insufficient balance in %s "+
-		"required:%d current:%d", address, required, current)
+	return liberrors.NewErrInsufficientBalance(address, required, current)
+}
+
+func NewErrTxTooLarge(size, maxSize int) error {
+	return liberrors.NewErrTxTooLarge(size, maxSize)
+}
+
+func NewErrFeeOutOfBounds(fee, min, max int64) error {
+	return liberrors.NewErrFeeOutOfBounds(fee, min, max)
+}
+
+func NewErrDustChange(change, dust int64) error {
+	return liberrors.NewErrDustChange(change, dust)
+}
+
+func NewErrTxExpired(txHash string, expiryHeight uint32, height int64) error {
+	return liberrors.NewErrTxExpired(txHash, expiryHeight, height)
+}
+
+func NewErrInvalidSignature(input int, reason string) error {
+	return fmt.Errorf("invalid signature for input %d: %s", input, reason)
 }
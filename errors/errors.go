@@ -3,6 +3,8 @@ package errors
 import (
 	"errors"
 	"fmt"
+	"strings"
+	"time"
 )
 
 // ErrPreConditionCheckFailed indicates that the pre-condition for executing
@@ -15,18 +17,332 @@ var ErrPostConditionCheckFailed = errors.New("post-condition check failed")
 
 var ErrTimedOut = errors.New("timed out")
 
+// ErrNotSupported indicates that the backend does not support the requested
+// operation.
+var ErrNotSupported = errors.New("operation not supported by the backend")
+
 var ErrNoSpendingTransactions = fmt.Errorf("No spending transactions")
 
 var ErrMismatchedPubKeys = fmt.Errorf("failed to fund the transaction mismatched script public keys")
 
+// ErrTxNotFound indicates that a transaction is neither in the mempool nor in
+// the blockchain.
+var ErrTxNotFound = errors.New("transaction not found")
+
+// ErrUTXOSpent indicates that an output is spent, or does not exist.
+var ErrUTXOSpent = errors.New("utxo is spent or does not exist")
+
+// ErrNegativeLimit indicates that utxos were requested with a negative limit.
+var ErrNegativeLimit = errors.New("limit must not be negative")
+
+// ErrNegativeConfirmations indicates that utxos were requested with a negative
+// number of confirmations.
+var ErrNegativeConfirmations = errors.New("confirmations must not be negative")
+
+// ErrInsufficientBalance is matched by errors.Is for every
+// InsufficientBalanceError.
+var ErrInsufficientBalance = errors.New("insufficient balance")
+
+// ErrUnsupportedNetwork is matched by errors.Is for every
+// UnsupportedNetworkError.
+var ErrUnsupportedNetwork = errors.New("unsupported network")
+
+// ErrBackendUnavailable is matched by errors.Is for every
+// BackendUnavailableError.
+var ErrBackendUnavailable = errors.New("backend unavailable")
+
+// ErrWrongNetwork is matched by errors.Is for every WrongNetworkError.
+var ErrWrongNetwork = errors.New("address of the wrong network")
+
+// ErrUnsupportedReceivers is matched by errors.Is for every
+// UnsupportedReceiversError.
+var ErrUnsupportedReceivers = errors.New("unsupported receivers")
+
+// ErrOrchardOnly is matched by errors.Is for the UnsupportedReceiversError of
+// a unified address whose only receiver is an Orchard receiver.
+var ErrOrchardOnly = errors.New("address only has an orchard receiver")
+
+// ErrTxTooLarge is matched by errors.Is for every TxTooLargeError.
+var ErrTxTooLarge = errors.New("transaction exceeds the maximum standard size")
+
+// ErrFeeOutOfBounds is matched by errors.Is for every FeeOutOfBoundsError.
+var ErrFeeOutOfBounds = errors.New("fee out of bounds")
+
+// ErrDustChange is matched by errors.Is for every DustChangeError.
+var ErrDustChange = errors.New("change is dust")
+
+// Classes of broadcast failures. A BroadcastError matches its class with
+// errors.Is, so that callers can decide whether to retry.
+var (
+	// ErrBroadcast is matched by every BroadcastError, including those that
+	// could not be classified.
+	ErrBroadcast = errors.New("broadcast failed")
+
+	// ErrAlreadyInMempool indicates that the transaction is already in the
+	// mempool or in the blockchain. It is usually safe to ignore.
+	ErrAlreadyInMempool = errors.New("transaction already in mempool")
+
+	// ErrMissingInputs indicates that the inputs of the transaction do not
+	// exist, or have already been spent.
+	ErrMissingInputs = errors.New("missing inputs")
+
+	// ErrInsufficientFee indicates that the fee of the transaction is too low
+	// to be relayed.
+	ErrInsufficientFee = errors.New("insufficient fee")
+
+	// ErrExpired indicates that the expiry height of the transaction has
+	// passed.
+	ErrExpired = errors.New("transaction expired")
+
+	// ErrScriptVerification indicates that the script of an input failed.
+	ErrScriptVerification = errors.New("script verification failed")
+
+	// ErrNonStandard indicates that the transaction is valid, but is not
+	// relayed by the default policy of the nodes, for example because it has
+	// dust outputs.
+	ErrNonStandard = errors.New("non-standard transaction")
+)
+
+// broadcastClasses maps substrings of the rejection messages of zcashd and of
+// block explorers to the class of the failure.
+var broadcastClasses = []struct {
+	substr string
+	class  error
+}{
+	{"txn-already-in-mempool", ErrAlreadyInMempool},
+	{"txn-already-known", ErrAlreadyInMempool},
+	{"already in block chain", ErrAlreadyInMempool},
+	{"missing inputs", ErrMissingInputs},
+	{"missing-inputs", ErrMissingInputs},
+	{"bad-txns-inputs-spent", ErrMissingInputs},
+	{"bad-txns-inputs-missingorspent", ErrMissingInputs},
+	{"txn-mempool-conflict", ErrMissingInputs},
+	{"insufficient fee", ErrInsufficientFee},
+	{"insufficient priority", ErrInsufficientFee},
+	{"min relay fee not met", ErrInsufficientFee},
+	{"mempool min fee not met", ErrInsufficientFee},
+	{"tx-overwinter-expired", ErrExpired},
+	{"tx-expiring-soon", ErrExpired},
+	{"script-verify-flag-failed", ErrScriptVerification},
+	{"dust", ErrNonStandard},
+	{"scriptpubkey", ErrNonStandard},
+	{"tx-size", ErrNonStandard},
+}
+
+// BroadcastError is returned when a node or a block explorer rejects a
+// transaction. Class is one of the broadcast failure classes, or nil if the
+// failure could not be classified.
+type BroadcastError struct {
+	Class   error
+	Message string
+}
+
+func (err *BroadcastError) Error() string {
+	return fmt.Sprintf("error while submitting ZCash transaction: %s", err.Message)
+}
+
+// Is matches ErrBroadcast, and the class of the error.
+func (err *BroadcastError) Is(target error) bool {
+	return target == ErrBroadcast || (err.Class != nil && target == err.Class)
+}
+
+// ClassifyBroadcastError returns the class of the rejection message, or nil
+// if it is unknown.
+func ClassifyBroadcastError(msg string) error {
+	msg = strings.ToLower(msg)
+	for _, class := range broadcastClasses {
+		if strings.Contains(msg, class.substr) {
+			return class.class
+		}
+	}
+	return nil
+}
+
+// InsufficientBalanceError is returned when an address does not have enough
+// funds to pay for a transaction.
+type InsufficientBalanceError struct {
+	Address  string
+	Required int64
+	Current  int64
+}
+
+func (err *InsufficientBalanceError) Error() string {
+	return fmt.Sprintf("insufficient balance in %s "+
+		"required:%d current:%d", err.Address, err.Required, err.Current)
+}
+
+// Is matches ErrInsufficientBalance.
+func (err *InsufficientBalanceError) Is(target error) bool {
+	return target == ErrInsufficientBalance
+}
+
+// UnsupportedNetworkError is returned when a network is not supported.
+type UnsupportedNetworkError struct {
+	Network string
+}
+
+func (err *UnsupportedNetworkError) Error() string {
+	return fmt.Sprintf("unsupported network %s", err.Network)
+}
+
+// Is matches ErrUnsupportedNetwork.
+func (err *UnsupportedNetworkError) Is(target error) bool {
+	return target == ErrUnsupportedNetwork
+}
+
+// BackendUnavailableError is returned without calling the backend while a
+// circuit breaker is open, or while the backend rate limits the client. Err is
+// the failure that opened the breaker, or the answer of the backend.
+type BackendUnavailableError struct {
+	RetryAt time.Time
+	Err     error
+}
+
+func (err *BackendUnavailableError) Error() string {
+	return fmt.Sprintf("backend unavailable until %s: %v", err.RetryAt.Format(time.RFC3339), err.Err)
+}
+
+// Is matches ErrBackendUnavailable.
+func (err *BackendUnavailableError) Is(target error) bool {
+	return target == ErrBackendUnavailable
+}
+
+// WrongNetworkError is returned when a valid address of another network is
+// used, for example a mainnet address given to a testnet client.
+type WrongNetworkError struct {
+	Address  string
+	Network  string
+	Expected string
+}
+
+func (err *WrongNetworkError) Error() string {
+	return fmt.Sprintf("address %s is a %s address, expected a %s address", err.Address, err.Network, err.Expected)
+}
+
+// Is matches ErrWrongNetwork.
+func (err *WrongNetworkError) Is(target error) bool {
+	return target == ErrWrongNetwork
+}
+
+// UnsupportedReceiversError is returned when a unified address has none of
+// the receivers that a transparent transaction can pay.
+type UnsupportedReceiversError struct {
+	Address   string
+	Receivers []string
+}
+
+func (err *UnsupportedReceiversError) Error() string {
+	return fmt.Sprintf("cannot pay %s from a transparent transaction: unsupported receivers %s", err.Address, strings.Join(err.Receivers, ", "))
+}
+
+// Is matches ErrUnsupportedReceivers, and ErrOrchardOnly if the only receiver
+// is an Orchard receiver.
+func (err *UnsupportedReceiversError) Is(target error) bool {
+	if target == ErrOrchardOnly {
+		return len(err.Receivers) == 1 && err.Receivers[0] == "orchard"
+	}
+	return target == ErrUnsupportedReceivers
+}
+
+// TxTooLargeError is returned when a transaction would be larger than the
+// maximum size of the transactions relayed by the network.
+type TxTooLargeError struct {
+	Size    int
+	MaxSize int
+}
+
+func (err *TxTooLargeError) Error() string {
+	return fmt.Sprintf("transaction exceeds the maximum standard size: got: %d required: %d", err.Size, err.MaxSize)
+}
+
+// Is matches ErrTxTooLarge.
+func (err *TxTooLargeError) Is(target error) bool {
+	return target == ErrTxTooLarge
+}
+
+// FeeOutOfBoundsError is returned when a transaction would pay a fee below the
+// minimum fee, or above the maximum fee, allowed by the builder.
+type FeeOutOfBoundsError struct {
+	Fee      int64
+	Min, Max int64
+}
+
+func (err *FeeOutOfBoundsError) Error() string {
+	return fmt.Sprintf("fee out of bounds: got: %d required: between %d and %d", err.Fee, err.Min, err.Max)
+}
+
+// Is matches ErrFeeOutOfBounds.
+func (err *FeeOutOfBoundsError) Is(target error) bool {
+	return target == ErrFeeOutOfBounds
+}
+
+// DustChangeError is returned by builders that refuse to forfeit a change that
+// is too small to be sent back.
+type DustChangeError struct {
+	Change int64
+	Dust   int64
+}
+
+func (err *DustChangeError) Error() string {
+	return fmt.Sprintf("change is dust: got: %d required: %d", err.Change, err.Dust)
+}
+
+// Is matches ErrDustChange.
+func (err *DustChangeError) Is(target error) bool {
+	return target == ErrDustChange
+}
+
+// TxExpiredError is returned when a transaction was not mined before its expiry
+// height, and will never be mined.
+type TxExpiredError struct {
+	TxHash       string
+	ExpiryHeight uint32
+	Height       int64
+}
+
+func (err *TxExpiredError) Error() string {
+	return fmt.Sprintf("transaction %s expired at height %d: got: %d", err.TxHash, err.ExpiryHeight, err.Height)
+}
+
+// Is matches ErrExpired.
+func (err *TxExpiredError) Is(target error) bool {
+	return target == ErrExpired
+}
+
 func NewErrUnsupportedNetwork(network string) error {
-	return fmt.Errorf("unsupported network %s", network)
+	return &UnsupportedNetworkError{network}
 }
 
+// NewErrZCashSubmitTx returns a BroadcastError, classified using the
+// rejection message.
 func NewErrZCashSubmitTx(msg string) error {
-	return fmt.Errorf("error while submitting ZCash transaction: %s", msg)
+	return &BroadcastError{ClassifyBroadcastError(msg), msg}
 }
+
 func NewErrInsufficientBalance(address string, required, current int64) error {
-	return fmt.Errorf("insufficient balance in %s "+
-		"required:%d current:%d", address, required, current)
+	return &InsufficientBalanceError{address, required, current}
+}
+
+func NewErrUnsupportedReceivers(address string, receivers []string) error {
+	return &UnsupportedReceiversError{address, receivers}
+}
+
+func NewErrWrongNetwork(address, network, expected string) error {
+	return &WrongNetworkError{address, network, expected}
+}
+
+func NewErrTxTooLarge(size, maxSize int) error {
+	return &TxTooLargeError{size, maxSize}
+}
+
+func NewErrFeeOutOfBounds(fee, min, max int64) error {
+	return &FeeOutOfBoundsError{fee, min, max}
+}
+
+func NewErrDustChange(change, dust int64) error {
+	return &DustChangeError{change, dust}
+}
+
+func NewErrTxExpired(txHash string, expiryHeight uint32, height int64) error {
+	return &TxExpiredError{txHash, expiryHeight, height}
 }
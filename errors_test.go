@@ -0,0 +1,56 @@
+package libzec_test
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/renproject/libzec-go"
+)
+
+var _ = Describe("Errors", func() {
+	It("should classify broadcast failures", func() {
+		classes := map[string]error{
+			"16: txn-already-in-mempool":              ErrAlreadyInMempool,
+			"Missing inputs":                          ErrMissingInputs,
+			"66: min relay fee not met":               ErrInsufficientFee,
+			"tx-overwinter-expired":                   ErrExpired,
+			"16: mandatory-script-verify-flag-failed": ErrScriptVerification,
+			"64: dust": ErrNonStandard,
+			"failed to publish transaction: bad signature": nil,
+		}
+		for msg, class := range classes {
+			err := NewErrZCashSubmitTx(msg)
+			Expect(errors.Is(err, ErrBroadcast)).Should(BeTrue())
+			broadcastErr := &BroadcastError{}
+			Expect(errors.As(err, &broadcastErr)).Should(BeTrue())
+			if class == nil {
+				Expect(broadcastErr.Class).Should(BeNil())
+				continue
+			}
+			Expect(errors.Is(err, class)).Should(BeTrue())
+			Expect(broadcastErr.Class).Should(Equal(class))
+		}
+	})
+
+	It("should match insufficient balance errors", func() {
+		err := NewErrInsufficientBalance("address", 10, 5)
+		Expect(errors.Is(err, ErrInsufficientBalance)).Should(BeTrue())
+		balanceErr := &InsufficientBalanceError{}
+		Expect(errors.As(err, &balanceErr)).Should(BeTrue())
+		Expect(balanceErr.Required - balanceErr.Current).Should(Equal(int64(5)))
+	})
+
+	It("should tell expired transactions from pending ones", func() {
+		status := TxStatus{TxHash: "txhash", ExpiryHeight: 100, Height: 120, Expired: true}
+		Expect(status.Pending()).Should(BeFalse())
+		Expect(errors.Is(status.Err(), ErrTxExpired)).Should(BeTrue())
+		expiredErr := &TxExpiredError{}
+		Expect(errors.As(status.Err(), &expiredErr)).Should(BeTrue())
+		Expect(expiredErr.ExpiryHeight).Should(Equal(uint32(100)))
+
+		status = TxStatus{TxHash: "txhash", ExpiryHeight: 100, Height: 90}
+		Expect(status.Pending()).Should(BeTrue())
+		Expect(status.Err()).ShouldNot(HaveOccurred())
+	})
+})
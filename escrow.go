@@ -0,0 +1,87 @@
+package libzec
+
+import (
+	"fmt"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcutil"
+	"github.com/renproject/libzec-go/clients"
+)
+
+// Escrow is a 2-of-3 multisig escrow between a buyer, a seller and an
+// arbiter. The funds are released cooperatively when the buyer and the seller
+// sign, or by arbitration when the arbiter signs with one of them.
+type Escrow struct {
+	Buyer   []byte
+	Seller  []byte
+	Arbiter []byte
+
+	// Script is the multisig redeem script of the escrow, and Address its
+	// P2SH address to which the buyer sends the funds.
+	Script  []byte
+	Address btcutil.Address
+
+	params *chaincfg.Params
+}
+
+// NewEscrow returns the escrow of the serialized public keys of the buyer,
+// the seller and the arbiter.
+func NewEscrow(buyer, seller, arbiter []byte, params *chaincfg.Params) (*Escrow, error) {
+	script, err := MultisigScript(2, [][]byte{buyer, seller, arbiter})
+	if err != nil {
+		return nil, err
+	}
+	address, err := MultisigAddress(script, params)
+	if err != nil {
+		return nil, err
+	}
+	return &Escrow{buyer, seller, arbiter, script, address, params}, nil
+}
+
+// Funded returns whether the escrow has received at least the given value,
+// and its balance.
+func (escrow *Escrow) Funded(client Client, value int64) (bool, int64, error) {
+	return client.ScriptFunded(escrow.Address.EncodeAddress(), value)
+}
+
+// UTXOs returns the utxos held by the escrow, with at least the given number
+// of confirmations.
+func (escrow *Escrow) UTXOs(client Client, confirmations int64) ([]clients.UTXO, error) {
+	return client.GetUTXOs(escrow.Address.EncodeAddress(), 0, confirmations)
+}
+
+// Release builds the partially signed transaction that pays all the utxos of
+// the escrow, minus the fee, to the address. It must be signed by two of the
+// three parties before it is finalized.
+func (escrow *Escrow) Release(builder TxBuilder, utxos []clients.UTXO, to string) (*PSZT, error) {
+	if len(utxos) == 0 {
+		return nil, fmt.Errorf("escrow %s holds no utxos", escrow.Address.EncodeAddress())
+	}
+	var value int64
+	for _, utxo := range utxos {
+		value += utxo.Amount
+	}
+	return builder.BuildPSZT(to, escrow.Script, value, utxos)
+}
+
+// PaySeller builds the partially signed transaction that releases the funds
+// to the P2PKH address of the seller, once the goods are delivered.
+func (escrow *Escrow) PaySeller(builder TxBuilder, utxos []clients.UTXO) (*PSZT, error) {
+	return escrow.releaseTo(builder, utxos, escrow.Seller)
+}
+
+// RefundBuyer builds the partially signed transaction that releases the funds
+// back to the P2PKH address of the buyer.
+func (escrow *Escrow) RefundBuyer(builder TxBuilder, utxos []clients.UTXO) (*PSZT, error) {
+	return escrow.releaseTo(builder, utxos, escrow.Buyer)
+}
+
+func (escrow *Escrow) releaseTo(builder TxBuilder, utxos []clients.UTXO, pubKey []byte) (*PSZT, error) {
+	hash := [20]byte{}
+	copy(hash[:], btcutil.Hash160(pubKey))
+	to, err := AddressFromHash160(hash, escrow.params, false)
+	if err != nil {
+		return nil, err
+	}
+	return escrow.Release(builder, utxos, to.EncodeAddress())
+}
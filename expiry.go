@@ -0,0 +1,164 @@
+package libzec
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+// TxStatus is the status of a transaction. An unmined transaction is expired
+// once the chain has reached its expiry height: it will never be mined, unlike
+// a transaction that is still pending. The expiry height is 0 if the
+// transaction does not expire, or if it is mined.
+type TxStatus struct {
+	TxHash        string `json:"txHash"`
+	Confirmations int64  `json:"confirmations"`
+	ExpiryHeight  uint32 `json:"expiryHeight"`
+	Height        int64  `json:"height"`
+	Expired       bool   `json:"expired"`
+}
+
+// Pending returns whether the transaction can still be mined.
+func (status TxStatus) Pending() bool {
+	return status.Confirmations == 0 && !status.Expired
+}
+
+// Err returns a TxExpiredError if the transaction has expired, and nil
+// otherwise.
+func (status TxStatus) Err() error {
+	if !status.Expired {
+		return nil
+	}
+	return NewErrTxExpired(status.TxHash, status.ExpiryHeight, status.Height)
+}
+
+// TxStatus returns the status of the transaction. The expiry height of an
+// unmined transaction is decoded from the transaction, which is fetched using
+// clients.BatchClient, so ErrNotSupported is returned for unmined transactions
+// if the client does not implement it. ErrTxNotFound is returned if the
+// transaction cannot be found, which is the case of transactions that have
+// been evicted from the mempool once expired; the TxStatus of an account
+// falls back to the transactions of its tx store, and TxExpiryStatus can be
+// used if the signed transaction is known.
+func (client *client) TxStatus(txHash string) (TxStatus, error) {
+	conf, err := client.Confirmations(txHash)
+	if err != nil && !errors.Is(err, ErrTxNotFound) {
+		return TxStatus{}, err
+	}
+	if err == nil && conf > 0 {
+		return TxStatus{TxHash: txHash, Confirmations: conf}, nil
+	}
+	batcher, ok := batchClient(client)
+	if !ok {
+		if err != nil {
+			return TxStatus{}, err
+		}
+		return TxStatus{}, ErrNotSupported
+	}
+	txs, err := batcher.BatchRawTransactions([]string{txHash})
+	if err != nil {
+		return TxStatus{}, err
+	}
+	stx, ok := txs[txHash]
+	if !ok {
+		return TxStatus{}, ErrTxNotFound
+	}
+	return TxExpiryStatus(client, stx)
+}
+
+// IsExpired returns whether the transaction was not mined before its expiry
+// height. It fails in the same cases as TxStatus.
+func (client *client) IsExpired(txHash string) (bool, error) {
+	status, err := client.TxStatus(txHash)
+	if err != nil {
+		return false, err
+	}
+	return status.Expired, nil
+}
+
+// TxStatus returns the status of the transaction. zcashd evicts expired
+// transactions from its mempool, after which they cannot be found, and most
+// backends cannot fetch unmined transactions. In both cases the transaction
+// is looked up in the tx store of the account, and its status is computed
+// from the stored transaction using TxExpiryStatus.
+func (account *account) TxStatus(txHash string) (TxStatus, error) {
+	status, err := account.Client.TxStatus(txHash)
+	if err == nil || !(errors.Is(err, ErrTxNotFound) || errors.Is(err, ErrNotSupported)) {
+		return status, err
+	}
+	store := account.store()
+	if store == nil {
+		return TxStatus{}, err
+	}
+	stored, ok, storeErr := store.Get(txHash)
+	if storeErr != nil {
+		return TxStatus{}, storeErr
+	}
+	if !ok {
+		return TxStatus{}, err
+	}
+	return TxExpiryStatus(account.Client, stored.Stx)
+}
+
+// IsExpired returns whether the transaction was not mined before its expiry
+// height. It falls back to the tx store of the account in the same cases as
+// TxStatus.
+func (account *account) IsExpired(txHash string) (bool, error) {
+	status, err := account.TxStatus(txHash)
+	if err != nil {
+		return false, err
+	}
+	return status.Expired, nil
+}
+
+// TxExpiryStatus returns the status of the signed transaction, comparing its
+// expiry height to the height of the chain. It works for transactions that
+// are no longer in the mempool, as long as the client can fetch the height of
+// the chain.
+func TxExpiryStatus(c Client, stx []byte) (TxStatus, error) {
+	decoded, err := DecodeTransaction(stx)
+	if err != nil {
+		return TxStatus{}, err
+	}
+	txID, err := TxID(stx)
+	if err != nil {
+		return TxStatus{}, err
+	}
+	txHash := hex.EncodeToString(txID)
+	conf, err := c.Confirmations(txHash)
+	if err != nil && !errors.Is(err, ErrTxNotFound) {
+		return TxStatus{}, err
+	}
+	if err == nil && conf > 0 {
+		return TxStatus{TxHash: txHash, Confirmations: conf}, nil
+	}
+	height, err := c.BlockHeight()
+	if err != nil {
+		return TxStatus{}, err
+	}
+	return TxStatus{
+		TxHash:       txHash,
+		ExpiryHeight: decoded.ExpiryHeight,
+		Height:       height,
+		Expired:      expired(decoded.ExpiryHeight, height),
+	}, nil
+}
+
+// nextExpiryHeight returns the expiry height of a transaction built now, that
+// is ZCashExpiryDelta blocks after the current height of the chain. An error
+// is returned if the height cannot be fetched, as a transaction that expires
+// far in the future cannot be safely replaced once it is dropped.
+func nextExpiryHeight(c Client) (uint32, error) {
+	height, err := c.BlockHeight()
+	if err != nil {
+		return 0, fmt.Errorf("cannot get the block height to set the expiry height: %v", err)
+	}
+	return uint32(height) + ZCashExpiryDelta, nil
+}
+
+// expired returns whether a transaction with the expiry height can no longer
+// be mined in the block after the given height. An expiry height of 0 never
+// expires.
+func expired(expiryHeight uint32, height int64) bool {
+	return expiryHeight != 0 && height >= int64(expiryHeight)
+}
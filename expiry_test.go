@@ -0,0 +1,184 @@
+package libzec_test
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/renproject/libzec-go"
+)
+
+// batchMockClientCore is a mock client core that can fetch the transactions it
+// has published until they are evicted, like a zcashd node.
+type batchMockClientCore struct {
+	*mockClientCore
+	evicted map[string]bool
+}
+
+func (core *batchMockClientCore) BatchConfirmations(txHashes []string) (map[string]int64, error) {
+	confs := map[string]int64{}
+	for _, txHash := range txHashes {
+		if conf, err := core.Confirmations(txHash); err == nil {
+			confs[txHash] = conf
+		}
+	}
+	return confs, nil
+}
+
+func (core *batchMockClientCore) BatchRawTransactions(txHashes []string) (map[string][]byte, error) {
+	core.mu.Lock()
+	defer core.mu.Unlock()
+	txs := map[string][]byte{}
+	for _, stx := range core.published {
+		txID, err := TxID(stx)
+		Expect(err).Should(BeNil())
+		txHash := hex.EncodeToString(txID)
+		if core.evicted[txHash] {
+			continue
+		}
+		for _, wanted := range txHashes {
+			if wanted == txHash {
+				txs[txHash] = stx
+			}
+		}
+	}
+	return txs, nil
+}
+
+var _ = Describe("Transaction expiry", func() {
+	// transfer publishes a transfer built at height 1842420, which expires at
+	// height 1842440.
+	transfer := func(core *mockClientCore) (Account, TxReceipt) {
+		account, _, _ := newMockAccount(core, 1000000)
+		_, _, to := newMockAccount(core, 0)
+		receipt, err := account.Transfer(context.Background(), to.EncodeAddress(), 100000, Standard, false)
+		Expect(err).Should(BeNil())
+		Expect(receipt.ExpiryHeight).Should(Equal(uint32(1842440)))
+		return account, receipt
+	}
+
+	expectExpired := func(status TxStatus, err error, expired bool) {
+		Expect(err).Should(BeNil())
+		Expect(status.Expired).Should(Equal(expired))
+		Expect(status.Pending()).Should(Equal(!expired))
+		if expired {
+			Expect(errors.Is(status.Err(), ErrTxExpired)).Should(BeTrue())
+		} else {
+			Expect(status.Err()).Should(BeNil())
+		}
+	}
+
+	It("should expire unmined transactions at their expiry height", func() {
+		core := newMockClientCore(&chaincfg.TestNet3Params, 1842420)
+		_, receipt := transfer(core)
+		client := NewClient(&batchMockClientCore{core, map[string]bool{}})
+
+		status, err := client.TxStatus(receipt.TxHash)
+		expectExpired(status, err, false)
+		Expect(status.ExpiryHeight).Should(Equal(uint32(1842440)))
+
+		core.setHeight(1842439)
+		status, err = client.TxStatus(receipt.TxHash)
+		expectExpired(status, err, false)
+		expired, err := client.IsExpired(receipt.TxHash)
+		Expect(err).Should(BeNil())
+		Expect(expired).Should(BeFalse())
+
+		core.setHeight(1842440)
+		status, err = client.TxStatus(receipt.TxHash)
+		expectExpired(status, err, true)
+		expired, err = client.IsExpired(receipt.TxHash)
+		Expect(err).Should(BeNil())
+		Expect(expired).Should(BeTrue())
+	})
+
+	It("should not expire mined transactions", func() {
+		core := newMockClientCore(&chaincfg.TestNet3Params, 1842420)
+		_, receipt := transfer(core)
+		client := NewClient(&batchMockClientCore{core, map[string]bool{}})
+		core.confirmations[receipt.TxHash] = 3
+		core.setHeight(1842500)
+
+		status, err := client.TxStatus(receipt.TxHash)
+		Expect(err).Should(BeNil())
+		Expect(status.Confirmations).Should(Equal(int64(3)))
+		Expect(status.Expired).Should(BeFalse())
+		Expect(status.Pending()).Should(BeFalse())
+		Expect(status.Err()).Should(BeNil())
+	})
+
+	It("should compute the status of a signed transaction", func() {
+		core := newMockClientCore(&chaincfg.TestNet3Params, 1842420)
+		account, _ := transfer(core)
+		stx := core.published[0]
+
+		core.setHeight(1842439)
+		status, err := TxExpiryStatus(account, stx)
+		expectExpired(status, err, false)
+		core.setHeight(1842440)
+		status, err = TxExpiryStatus(account, stx)
+		expectExpired(status, err, true)
+		Expect(status.Height).Should(Equal(int64(1842440)))
+
+		core.heightErr = errors.New("unavailable")
+		_, err = TxExpiryStatus(account, stx)
+		Expect(err).ShouldNot(BeNil())
+	})
+
+	It("should fall back to the tx store once the transaction is evicted", func() {
+		core := newMockClientCore(&chaincfg.TestNet3Params, 1842420)
+		batchCore := &batchMockClientCore{core, map[string]bool{}}
+		privKey, err := btcec.NewPrivateKey(btcec.S256())
+		Expect(err).Should(BeNil())
+		client := NewClient(batchCore)
+		client.SetPubKeyCompression(true)
+		sender := NewAccount(client, privKey.ToECDSA(), nil)
+		addr, err := sender.Address()
+		Expect(err).Should(BeNil())
+		core.addUTXO(addr, chainhash.Hash{0xE0}, 1000000, 1)
+		sender.SetTxStore(NewMemoryTxStore())
+		_, _, to := newMockAccount(core, 0)
+		receipt, err := sender.Transfer(context.Background(), to.EncodeAddress(), 100000, Standard, false)
+		Expect(err).Should(BeNil())
+
+		// Once evicted, the node cannot find the transaction, and only the tx
+		// store knows its expiry height.
+		core.setHeight(1842440)
+		batchCore.evicted[receipt.TxHash] = true
+		_, err = client.TxStatus(receipt.TxHash)
+		Expect(errors.Is(err, ErrTxNotFound)).Should(BeTrue())
+		status, err := sender.TxStatus(receipt.TxHash)
+		expectExpired(status, err, true)
+		expired, err := sender.IsExpired(receipt.TxHash)
+		Expect(err).Should(BeNil())
+		Expect(expired).Should(BeTrue())
+
+		core.setHeight(1842439)
+		status, err = sender.TxStatus(receipt.TxHash)
+		expectExpired(status, err, false)
+
+		// Transactions that are not in the tx store still cannot be found.
+		_, err = sender.TxStatus(chainhash.Hash{0xEE}.String())
+		Expect(errors.Is(err, ErrTxNotFound)).Should(BeTrue())
+	})
+
+	It("should fall back to the tx store when the backend cannot fetch unmined transactions", func() {
+		core := newMockClientCore(&chaincfg.TestNet3Params, 1842420)
+		sender, _, _ := newMockAccount(core, 1000000)
+		sender.SetTxStore(NewMemoryTxStore())
+		_, _, to := newMockAccount(core, 0)
+		receipt, err := sender.Transfer(context.Background(), to.EncodeAddress(), 100000, Standard, false)
+		Expect(err).Should(BeNil())
+
+		core.setHeight(1842440)
+		_, err = NewClient(core).TxStatus(receipt.TxHash)
+		Expect(err).ShouldNot(BeNil())
+		status, err := sender.TxStatus(receipt.TxHash)
+		expectExpired(status, err, true)
+	})
+})
@@ -0,0 +1,75 @@
+package libzec
+
+import (
+	"fmt"
+	"net/url"
+)
+
+// An Explorer formats the links to transactions and addresses on a block
+// explorer. An empty string is returned for networks that the explorer does
+// not support.
+type Explorer interface {
+	TxURL(network, txHash string) string
+	AddressURL(network, address string) string
+}
+
+// Block explorers, with the networks they support.
+var (
+	ChainSoExplorer = NewExplorer(
+		map[string]string{
+			"mainnet":  "https://chain.so/tx/ZEC/%s",
+			"testnet3": "https://chain.so/tx/ZECTEST/%s",
+		},
+		map[string]string{
+			"mainnet":  "https://chain.so/address/ZEC/%s",
+			"testnet3": "https://chain.so/address/ZECTEST/%s",
+		},
+	)
+	ZcashBlockExplorer = NewExplorer(
+		map[string]string{
+			"mainnet":  "https://zcashblockexplorer.com/transactions/%s",
+			"testnet3": "https://testnet.zcashblockexplorer.com/transactions/%s",
+		},
+		map[string]string{
+			"mainnet":  "https://zcashblockexplorer.com/address/%s",
+			"testnet3": "https://testnet.zcashblockexplorer.com/address/%s",
+		},
+	)
+	BlockchairExplorer = NewExplorer(
+		map[string]string{
+			"mainnet": "https://blockchair.com/zcash/transaction/%s",
+		},
+		map[string]string{
+			"mainnet": "https://blockchair.com/zcash/address/%s",
+		},
+	)
+)
+
+type explorer struct {
+	txURLs      map[string]string
+	addressURLs map[string]string
+}
+
+// NewExplorer returns an explorer that formats its links using the given
+// formats, by network name. Every format has a single %s verb, replaced by
+// the txid or the address. A regtest explorer can be used by giving formats
+// for "regtest", such as "http://localhost:3001/tx/%s".
+func NewExplorer(txURLs, addressURLs map[string]string) Explorer {
+	return &explorer{txURLs, addressURLs}
+}
+
+func (explorer *explorer) TxURL(network, txHash string) string {
+	format, ok := explorer.txURLs[network]
+	if !ok {
+		return ""
+	}
+	return fmt.Sprintf(format, url.PathEscape(txHash))
+}
+
+func (explorer *explorer) AddressURL(network, address string) string {
+	format, ok := explorer.addressURLs[network]
+	if !ok {
+		return ""
+	}
+	return fmt.Sprintf(format, url.PathEscape(address))
+}
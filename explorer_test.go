@@ -0,0 +1,24 @@
+package libzec_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/renproject/libzec-go"
+)
+
+var _ = Describe("Block explorers", func() {
+	It("should format links for the supported networks", func() {
+		Expect(ChainSoExplorer.TxURL("testnet3", "abcd")).Should(Equal("https://chain.so/tx/ZECTEST/abcd"))
+		Expect(BlockchairExplorer.AddressURL("mainnet", "t1VmmGiyjVNeCjxDZzg7vZmd99WyzVby9yC")).Should(Equal("https://blockchair.com/zcash/address/t1VmmGiyjVNeCjxDZzg7vZmd99WyzVby9yC"))
+		Expect(BlockchairExplorer.TxURL("testnet3", "abcd")).Should(BeEmpty())
+	})
+
+	It("should support custom regtest explorers", func() {
+		explorer := NewExplorer(
+			map[string]string{"regtest": "http://localhost:3001/tx/%s"},
+			map[string]string{"regtest": "http://localhost:3001/address/%s"},
+		)
+		Expect(explorer.TxURL("regtest", "abcd")).Should(Equal("http://localhost:3001/tx/abcd"))
+		Expect(explorer.AddressURL("mainnet", "t1VmmGiyjVNeCjxDZzg7vZmd99WyzVby9yC")).Should(BeEmpty())
+	})
+})
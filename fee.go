@@ -0,0 +1,142 @@
+package libzec
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Default fee rates, in ZAT per byte, used when no fee estimator is provided.
+const (
+	DefaultSlowFeeRate     = int64(10)
+	DefaultStandardFeeRate = int64(20)
+	DefaultFastFeeRate     = int64(40)
+)
+
+// ZCashBlockTime is the target spacing of ZCash blocks since Blossom.
+const ZCashBlockTime = 75 * time.Second
+
+// confirmationTargets are the number of blocks within which a transaction
+// paying the fee rate of each speed is expected to be mined, from the fastest
+// speed to the slowest.
+var confirmationTargets = []struct {
+	speed  TxExecutionSpeed
+	blocks int64
+}{
+	{Fast, 1},
+	{Standard, 3},
+	{Slow, 6},
+}
+
+// ConfirmationEstimate is the expected time until a transaction is mined. The
+// speed is the fastest speed whose fee rate is paid by the transaction, and is
+// Nil, with no blocks, if the transaction pays less than the slow fee rate.
+type ConfirmationEstimate struct {
+	Speed    TxExecutionSpeed `json:"speed"`
+	Blocks   int64            `json:"blocks"`
+	Duration time.Duration    `json:"duration"`
+}
+
+// EstimateConfirmationTime estimates the number of blocks, and the time, until
+// a transaction paying the fee rate, in ZAT per byte, is mined. The fee rate
+// is compared to the current rates of the estimator for every speed.
+func EstimateConfirmationTime(ctx context.Context, estimator FeeEstimator, feeRate int64) (ConfirmationEstimate, error) {
+	for _, target := range confirmationTargets {
+		rate, err := estimator.EstimateFeeRate(ctx, target.speed)
+		if err != nil {
+			return ConfirmationEstimate{}, err
+		}
+		if feeRate >= rate {
+			return ConfirmationEstimate{
+				Speed:    target.speed,
+				Blocks:   target.blocks,
+				Duration: time.Duration(target.blocks) * ZCashBlockTime,
+			}, nil
+		}
+	}
+	return ConfirmationEstimate{}, nil
+}
+
+// A FeeEstimator estimates the fee rate, in ZAT per byte, that a transaction
+// needs to pay to be mined at the given speed.
+type FeeEstimator interface {
+	EstimateFeeRate(ctx context.Context, speed TxExecutionSpeed) (int64, error)
+}
+
+type staticFeeEstimator struct {
+	slow, standard, fast int64
+}
+
+// NewStaticFeeEstimator returns a fee estimator that always returns the given
+// fee rates.
+func NewStaticFeeEstimator(slow, standard, fast int64) FeeEstimator {
+	return &staticFeeEstimator{slow, standard, fast}
+}
+
+func (estimator *staticFeeEstimator) EstimateFeeRate(ctx context.Context, speed TxExecutionSpeed) (int64, error) {
+	switch speed {
+	case Slow:
+		return estimator.slow, nil
+	case Standard:
+		return estimator.standard, nil
+	case Fast:
+		return estimator.fast, nil
+	default:
+		return 0, fmt.Errorf("invalid speed tier: %v", speed)
+	}
+}
+
+type suggestedFeeEstimator struct{}
+
+// NewSuggestedFeeEstimator returns a fee estimator that uses SuggestedTxRate.
+func NewSuggestedFeeEstimator() FeeEstimator {
+	return suggestedFeeEstimator{}
+}
+
+func (suggestedFeeEstimator) EstimateFeeRate(ctx context.Context, speed TxExecutionSpeed) (int64, error) {
+	return SuggestedTxRate(speed)
+}
+
+// DefaultMaxFee is the maximum fee, in ZAT, that accounts and tx builders
+// agree to pay by default, whatever the fee rate returned by their estimator.
+const DefaultMaxFee = int64(1000000)
+
+// feeLimits are the minimum and the maximum fee of the transactions built by
+// an account or a tx builder.
+type feeLimits struct {
+	min, max int64
+}
+
+func defaultFeeLimits() feeLimits {
+	return feeLimits{0, DefaultMaxFee}
+}
+
+// check returns a FeeOutOfBoundsError if the fee is not within the limits.
+func (limits feeLimits) check(fee int64) error {
+	if fee < limits.min || fee > limits.max {
+		return NewErrFeeOutOfBounds(fee, limits.min, limits.max)
+	}
+	return nil
+}
+
+// relayable returns the limits raised, if needed, so that the fee is not below
+// the minimum relay fee of the rules.
+func (limits feeLimits) relayable(rules RelayRules) feeLimits {
+	if limits.min < rules.MinRelayFee {
+		limits.min = rules.MinRelayFee
+	}
+	return limits
+}
+
+// estimateFee returns the fee for a transaction of the given size at the given
+// speed. The fee is not capped, it is bounded by the fee limits of the caller.
+func estimateFee(ctx context.Context, estimator FeeEstimator, speed TxExecutionSpeed, size int) (int64, error) {
+	rate, err := estimator.EstimateFeeRate(ctx, speed)
+	if err != nil {
+		return 0, err
+	}
+	if rate <= 0 {
+		return 0, fmt.Errorf("invalid fee rate: %d", rate)
+	}
+	return rate * int64(size), nil
+}
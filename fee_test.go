@@ -0,0 +1,102 @@
+package libzec_test
+
+import (
+	"context"
+	"encoding/hex"
+	"time"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/renproject/libzec-go"
+	"github.com/renproject/libzec-go/clients"
+)
+
+var _ = Describe("Confirmation time estimates", func() {
+	estimator := NewStaticFeeEstimator(10, 20, 40)
+
+	It("should estimate the target of the fastest speed that is paid for", func() {
+		estimate, err := EstimateConfirmationTime(context.Background(), estimator, 25)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(estimate.Speed).Should(Equal(Standard))
+		Expect(estimate.Blocks).Should(Equal(int64(3)))
+		Expect(estimate.Duration).Should(Equal(3 * 75 * time.Second))
+
+		estimate, err = EstimateConfirmationTime(context.Background(), estimator, 40)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(estimate.Speed).Should(Equal(Fast))
+	})
+
+	It("should not estimate fee rates below the slow rate", func() {
+		estimate, err := EstimateConfirmationTime(context.Background(), estimator, 5)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(estimate).Should(Equal(ConfirmationEstimate{}))
+	})
+})
+
+var _ = Describe("Relay rules", func() {
+	It("should enforce the minimum relay fee of ZIP-313 on every network", func() {
+		for _, network := range []Network{Mainnet, Testnet, Regtest} {
+			Expect(StandardRelayRules(network).MinRelayFee).Should(Equal(int64(1000)))
+		}
+	})
+
+	It("should only enforce the dust threshold outside of regtest", func() {
+		Expect(StandardRelayRules(Mainnet).Dust).Should(Equal(int64(ZCashDust)))
+		Expect(StandardRelayRules(Regtest).Dust).Should(Equal(int64(0)))
+	})
+})
+
+var _ = Describe("Fee estimation", func() {
+	It("should pay the estimated fee when it exceeds the fee reserved while funding", func() {
+		core := newMockClientCore(&chaincfg.TestNet3Params, 1842420)
+		account, _, addr := newMockAccount(core, 1000000)
+		account.SetFeeEstimator(NewStaticFeeEstimator(100, 100, 100))
+
+		preview, err := account.PreviewTransfer(context.Background(), addr.EncodeAddress(), 100000, Standard, false)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(preview.Fee).Should(BeNumerically(">", MaxZCashFee))
+		Expect(preview.Fee).Should(Equal(100 * int64(preview.EstimatedSize)))
+	})
+
+	It("should not pay a fee above the maximum fee of the account", func() {
+		core := newMockClientCore(&chaincfg.TestNet3Params, 1842420)
+		account, _, addr := newMockAccount(core, 1000000)
+		account.SetFeeEstimator(NewStaticFeeEstimator(100, 100, 100))
+		account.SetFeeLimits(0, 20000)
+
+		_, err := account.PreviewTransfer(context.Background(), addr.EncodeAddress(), 100000, Standard, false)
+		Expect(err).Should(HaveOccurred())
+	})
+
+	It("should not build a transaction paying a fee outside of the limits of the builder", func() {
+		privKey, err := btcec.NewPrivateKey(btcec.S256())
+		Expect(err).ShouldNot(HaveOccurred())
+		client := NewClient(newMockClientCore(&chaincfg.TestNet3Params, 1842420))
+		client.SetPubKeyCompression(true)
+		addr, err := client.PublicKeyToAddress(privKey.PubKey().SerializeCompressed())
+		Expect(err).ShouldNot(HaveOccurred())
+		scriptPubKey, err := PayToAddrScript(addr)
+		Expect(err).ShouldNot(HaveOccurred())
+		utxos := []clients.UTXO{{
+			TxHash:       chainhash.Hash{3}.String(),
+			Amount:       100000,
+			ScriptPubKey: hex.EncodeToString(scriptPubKey),
+		}}
+		builder := NewTxBuilder(client)
+
+		builder.SetFeeLimits(0, 5000)
+		_, err = builder.Build(privKey.PublicKey, addr.EncodeAddress(), nil, 50000, utxos, nil)
+		Expect(err).Should(HaveOccurred())
+
+		builder.SetFeeLimits(20000, 30000)
+		_, err = builder.Build(privKey.PublicKey, addr.EncodeAddress(), nil, 50000, utxos, nil)
+		Expect(err).Should(HaveOccurred())
+
+		builder.SetFeeLimits(0, 20000)
+		_, err = builder.Build(privKey.PublicKey, addr.EncodeAddress(), nil, 50000, utxos, nil)
+		Expect(err).ShouldNot(HaveOccurred())
+	})
+})
@@ -0,0 +1,107 @@
+package libzec
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+)
+
+// ff1Encrypt encrypts the numerals of the given radix with FF1 (NIST SP
+// 800-38G), using AES with the given key as the block cipher.
+func ff1Encrypt(key []byte, radix uint32, tweak []byte, x []uint32) ([]uint32, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	n := len(x)
+	if n < 2 {
+		return nil, fmt.Errorf("invalid ff1 input length: got: %d required: %d", n, 2)
+	}
+	u, v := n/2, n-n/2
+	bigRadix := new(big.Int).SetUint64(uint64(radix))
+	maxB := new(big.Int).Exp(bigRadix, big.NewInt(int64(v)), nil)
+	b := (maxB.Sub(maxB, big.NewInt(1)).BitLen() + 7) / 8
+	d := 4*((b+3)/4) + 4
+
+	p := [16]byte{1, 2, 1, byte(radix >> 16), byte(radix >> 8), byte(radix), 10, byte(u)}
+	binary.BigEndian.PutUint32(p[8:], uint32(n))
+	binary.BigEndian.PutUint32(p[12:], uint32(len(tweak)))
+
+	a, bs := x[:u], x[u:]
+	for i := 0; i < 10; i++ {
+		q := append([]byte{}, tweak...)
+		q = append(q, make([]byte, ((-len(tweak)-b-1)%16+16)%16)...)
+		q = append(q, byte(i))
+		q = append(q, paddedBytes(numRadix(bs, bigRadix), b)...)
+		r := ff1PRF(block, append(p[:], q...))
+
+		s := append([]byte{}, r...)
+		for j := 1; len(s) < d; j++ {
+			in := make([]byte, 16)
+			for k := range in {
+				in[k] = r[k] ^ byte(j>>uint(8*(15-k)))
+			}
+			out := make([]byte, 16)
+			block.Encrypt(out, in)
+			s = append(s, out...)
+		}
+
+		m := u
+		if i%2 == 1 {
+			m = v
+		}
+		c := numRadix(a, bigRadix)
+		c.Add(c, new(big.Int).SetBytes(s[:d]))
+		c.Mod(c, new(big.Int).Exp(bigRadix, big.NewInt(int64(m)), nil))
+		a, bs = bs, strRadix(c, bigRadix, m)
+	}
+	return append(append([]uint32{}, a...), bs...), nil
+}
+
+// ff1PRF returns the CBC-MAC of the data, whose length is a multiple of the
+// block size.
+func ff1PRF(block cipher.Block, data []byte) []byte {
+	y := make([]byte, 16)
+	for i := 0; i < len(data); i += 16 {
+		for k := range y {
+			y[k] ^= data[i+k]
+		}
+		block.Encrypt(y, y)
+	}
+	return y
+}
+
+// numRadix returns the number represented by the numerals, most significant
+// first.
+func numRadix(x []uint32, radix *big.Int) *big.Int {
+	num := new(big.Int)
+	for _, numeral := range x {
+		num.Mul(num, radix)
+		num.Add(num, new(big.Int).SetUint64(uint64(numeral)))
+	}
+	return num
+}
+
+// strRadix returns the m numerals representing the number, most significant
+// first.
+func strRadix(num, radix *big.Int, m int) []uint32 {
+	num = new(big.Int).Set(num)
+	x := make([]uint32, m)
+	rem := new(big.Int)
+	for i := m - 1; i >= 0; i-- {
+		num.DivMod(num, radix, rem)
+		x[i] = uint32(rem.Uint64())
+	}
+	return x
+}
+
+// paddedBytes returns the big endian encoding of the number, left padded to
+// the given length.
+func paddedBytes(num *big.Int, length int) []byte {
+	padded := make([]byte, length)
+	b := num.Bytes()
+	copy(padded[length-len(b):], b)
+	return padded
+}
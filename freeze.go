@@ -0,0 +1,67 @@
+package libzec
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/renproject/libzec-go/clients"
+)
+
+// utxoFreezer is the set of outpoints frozen by an account. Frozen utxos are
+// never selected to fund a transaction, or swept, until they are unfrozen.
+type utxoFreezer struct {
+	frozenMu *sync.Mutex
+	frozen   map[string]bool
+}
+
+func newUTXOFreezer() *utxoFreezer {
+	return &utxoFreezer{
+		frozenMu: new(sync.Mutex),
+		frozen:   map[string]bool{},
+	}
+}
+
+// FreezeUTXO prevents the utxo from being spent by the account, for example
+// because it is under dispute or reserved for a pending swap.
+func (freezer *utxoFreezer) FreezeUTXO(txHash string, vout uint32) {
+	freezer.frozenMu.Lock()
+	defer freezer.frozenMu.Unlock()
+	freezer.frozen[outPointKey(txHash, vout)] = true
+}
+
+// UnfreezeUTXO allows the account to spend a frozen utxo again.
+func (freezer *utxoFreezer) UnfreezeUTXO(txHash string, vout uint32) {
+	freezer.frozenMu.Lock()
+	defer freezer.frozenMu.Unlock()
+	delete(freezer.frozen, outPointKey(txHash, vout))
+}
+
+// FrozenUTXOs returns the frozen outpoints, formatted as "txhash:vout".
+func (freezer *utxoFreezer) FrozenUTXOs() []string {
+	freezer.frozenMu.Lock()
+	defer freezer.frozenMu.Unlock()
+	outPoints := make([]string, 0, len(freezer.frozen))
+	for outPoint := range freezer.frozen {
+		outPoints = append(outPoints, outPoint)
+	}
+	sort.Strings(outPoints)
+	return outPoints
+}
+
+// spendable returns the utxos that can be spent, excluding the frozen utxos
+// and the outputs of coinbase transactions that have not matured yet.
+func (freezer *utxoFreezer) spendable(utxos []clients.UTXO) []clients.UTXO {
+	freezer.frozenMu.Lock()
+	defer freezer.frozenMu.Unlock()
+	spendable := make([]clients.UTXO, 0, len(utxos))
+	for _, utxo := range utxos {
+		if utxo.Coinbase && utxo.Confirmations < CoinbaseMaturity {
+			continue
+		}
+		if freezer.frozen[outPointKey(utxo.TxHash, utxo.Vout)] {
+			continue
+		}
+		spendable = append(spendable, utxo)
+	}
+	return spendable
+}
@@ -0,0 +1,270 @@
+package libzec
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"sync"
+
+	"github.com/btcsuite/btcutil"
+)
+
+// GatewayState is the state of a gateway in its lifecycle.
+type GatewayState int
+
+const (
+	// GatewayCreated is the state of a gateway waiting for a deposit.
+	GatewayCreated GatewayState = iota
+
+	// GatewayDeposited is the state of a gateway that has received a
+	// deposit, which does not have enough confirmations yet.
+	GatewayDeposited
+
+	// GatewayConfirmed is the state of a gateway whose deposits have enough
+	// confirmations to be swept.
+	GatewayConfirmed
+
+	// GatewayCompleted is the state of a gateway that has been swept.
+	GatewayCompleted
+)
+
+func (state GatewayState) String() string {
+	switch state {
+	case GatewayCreated:
+		return "created"
+	case GatewayDeposited:
+		return "deposited"
+	case GatewayConfirmed:
+		return "confirmed"
+	case GatewayCompleted:
+		return "completed"
+	default:
+		return fmt.Sprintf("unknown(%d)", int(state))
+	}
+}
+
+// A Gateway is a slave address of an account, handed out to receive a single
+// deposit that is then swept back to the account.
+type Gateway struct {
+	Nonce   []byte       `json:"nonce"`
+	Address string       `json:"address"`
+	State   GatewayState `json:"state"`
+
+	// Amount is the total value deposited to the gateway, and Confirmations
+	// the confirmations of its least confirmed deposit.
+	Amount        int64 `json:"amount"`
+	Confirmations int64 `json:"confirmations"`
+
+	// SweepTxHash is the hash of the transaction that swept the gateway.
+	SweepTxHash string `json:"sweepTxHash,omitempty"`
+}
+
+// A GatewayStore persists the gateways of a GatewayManager.
+type GatewayStore interface {
+	Put(gateway Gateway) error
+	Get(nonce []byte) (Gateway, bool, error)
+	All() ([]Gateway, error)
+}
+
+// GatewayManager drives gateways through their lifecycle: a gateway is
+// created, receives a deposit that is confirmed, and is swept back to the
+// account which marks it as completed.
+type GatewayManager interface {
+	// Create creates the gateway of the nonce, whose slave address can be
+	// spent by the key of the account.
+	Create(nonce []byte) (Gateway, error)
+
+	// Get returns the stored gateway of the nonce.
+	Get(nonce []byte) (Gateway, error)
+
+	// Refresh fetches the deposits of the gateway, and updates its state.
+	// Confirmed gateways whose deposits have been spent are left unchanged,
+	// as they are being swept.
+	Refresh(nonce []byte) (Gateway, error)
+
+	// WaitForDeposit polls the gateway until its deposits are confirmed, or
+	// the context is done.
+	WaitForDeposit(ctx context.Context, nonce []byte) (Gateway, error)
+
+	// BuildSweep builds the unsigned transaction that spends the confirmed
+	// deposits of the gateway back to the account.
+	BuildSweep(ctx context.Context, nonce []byte, speed TxExecutionSpeed) (Tx, error)
+
+	// BuildSponsoredSweep builds the unsigned transaction that spends the
+	// confirmed deposits of the gateway back to the account without paying a
+	// fee. Once signed, the fee is added by a sponsor using TopUpFee.
+	BuildSponsoredSweep(ctx context.Context, nonce []byte) (Tx, error)
+
+	// Complete marks the gateway as swept by the transaction.
+	Complete(nonce []byte, sweepTxHash string) (Gateway, error)
+}
+
+type gatewayManager struct {
+	account       Account
+	store         GatewayStore
+	confirmations int64
+}
+
+// NewGatewayManager returns a gateway manager for the slave addresses of the
+// account, persisting the gateways in the store. Deposits can be swept once
+// they have the given number of confirmations.
+func NewGatewayManager(account Account, store GatewayStore, confirmations int64) GatewayManager {
+	return &gatewayManager{account, store, confirmations}
+}
+
+func (manager *gatewayManager) Create(nonce []byte) (Gateway, error) {
+	pubKey, err := manager.account.SerializedPublicKey()
+	if err != nil {
+		return Gateway{}, err
+	}
+	address, err := manager.account.SlaveAddress(btcutil.Hash160(pubKey), nonce)
+	if err != nil {
+		return Gateway{}, err
+	}
+	if address == nil {
+		return Gateway{}, fmt.Errorf("cannot create slave address for nonce %x", nonce)
+	}
+	gateway := Gateway{
+		Nonce:   nonce,
+		Address: address.EncodeAddress(),
+		State:   GatewayCreated,
+	}
+	return gateway, manager.store.Put(gateway)
+}
+
+func (manager *gatewayManager) Get(nonce []byte) (Gateway, error) {
+	gateway, ok, err := manager.store.Get(nonce)
+	if err != nil {
+		return Gateway{}, err
+	}
+	if !ok {
+		return Gateway{}, fmt.Errorf("gateway for nonce %x does not exist", nonce)
+	}
+	return gateway, nil
+}
+
+func (manager *gatewayManager) Refresh(nonce []byte) (Gateway, error) {
+	gateway, err := manager.Get(nonce)
+	if err != nil || gateway.State == GatewayCompleted {
+		return gateway, err
+	}
+
+	utxos, err := manager.account.GetUTXOs(gateway.Address, 0, 0)
+	if err != nil {
+		return gateway, err
+	}
+	// The deposits of a confirmed gateway disappear once they are swept, and
+	// the gateway stays confirmed until the sweep is completed.
+	if gateway.State == GatewayConfirmed && len(utxos) == 0 {
+		return gateway, nil
+	}
+	gateway.Amount, gateway.Confirmations = 0, 0
+	for i, utxo := range utxos {
+		conf := utxo.Confirmations
+		if conf == 0 {
+			// Not every backend reports the confirmations of utxos.
+			if conf, err = manager.account.Confirmations(utxo.TxHash); err != nil {
+				return gateway, err
+			}
+		}
+		if i == 0 || conf < gateway.Confirmations {
+			gateway.Confirmations = conf
+		}
+		gateway.Amount += utxo.Amount
+	}
+
+	switch {
+	case gateway.Amount == 0:
+		gateway.State = GatewayCreated
+	case gateway.Confirmations < manager.confirmations:
+		gateway.State = GatewayDeposited
+	default:
+		gateway.State = GatewayConfirmed
+	}
+	return gateway, manager.store.Put(gateway)
+}
+
+func (manager *gatewayManager) WaitForDeposit(ctx context.Context, nonce []byte) (Gateway, error) {
+	var gateway Gateway
+	err := pollWithBackoff(ctx, func() (bool, error) {
+		var err error
+		gateway, err = manager.Refresh(nonce)
+		return gateway.State >= GatewayConfirmed, err
+	})
+	return gateway, err
+}
+
+func (manager *gatewayManager) BuildSweep(ctx context.Context, nonce []byte, speed TxExecutionSpeed) (Tx, error) {
+	if err := manager.checkSweepable(nonce); err != nil {
+		return nil, err
+	}
+	return manager.account.BuildSweep(ctx, [][]byte{nonce}, speed)
+}
+
+func (manager *gatewayManager) BuildSponsoredSweep(ctx context.Context, nonce []byte) (Tx, error) {
+	if err := manager.checkSweepable(nonce); err != nil {
+		return nil, err
+	}
+	return manager.account.BuildSponsoredSweep(ctx, [][]byte{nonce})
+}
+
+// checkSweepable returns an error unless the deposits of the gateway are
+// confirmed and not yet swept.
+func (manager *gatewayManager) checkSweepable(nonce []byte) error {
+	gateway, err := manager.Get(nonce)
+	if err != nil {
+		return err
+	}
+	if gateway.State != GatewayConfirmed {
+		return fmt.Errorf("cannot sweep gateway %s: got: %v required: %v", gateway.Address, gateway.State, GatewayConfirmed)
+	}
+	return nil
+}
+
+func (manager *gatewayManager) Complete(nonce []byte, sweepTxHash string) (Gateway, error) {
+	gateway, err := manager.Get(nonce)
+	if err != nil {
+		return Gateway{}, err
+	}
+	gateway.State = GatewayCompleted
+	gateway.SweepTxHash = sweepTxHash
+	return gateway, manager.store.Put(gateway)
+}
+
+type memoryGatewayStore struct {
+	mu       *sync.RWMutex
+	gateways map[string]Gateway
+}
+
+// NewMemoryGatewayStore returns a gateway store that keeps the gateways in
+// memory.
+func NewMemoryGatewayStore() GatewayStore {
+	return &memoryGatewayStore{
+		mu:       new(sync.RWMutex),
+		gateways: map[string]Gateway{},
+	}
+}
+
+func (store *memoryGatewayStore) Put(gateway Gateway) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	store.gateways[hex.EncodeToString(gateway.Nonce)] = gateway
+	return nil
+}
+
+func (store *memoryGatewayStore) Get(nonce []byte) (Gateway, bool, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+	gateway, ok := store.gateways[hex.EncodeToString(nonce)]
+	return gateway, ok, nil
+}
+
+func (store *memoryGatewayStore) All() ([]Gateway, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+	gateways := make([]Gateway, 0, len(store.gateways))
+	for _, gateway := range store.gateways {
+		gateways = append(gateways, gateway)
+	}
+	return gateways, nil
+}
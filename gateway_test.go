@@ -0,0 +1,100 @@
+package libzec_test
+
+import (
+	"context"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/renproject/libzec-go"
+)
+
+var _ = Describe("Gateways", func() {
+	nonce := []byte("gateway")
+
+	It("should drive gateways from creation to completion", func() {
+		core := newMockClientCore(&chaincfg.TestNet3Params, 1842420)
+		account, _, _ := newMockAccount(core, 0)
+		manager := NewGatewayManager(account, NewMemoryGatewayStore(), 6)
+
+		expectState := func(gateway Gateway, err error, state GatewayState) {
+			Expect(err).Should(BeNil())
+			Expect(gateway.State).Should(Equal(state))
+			stored, err := manager.Get(nonce)
+			Expect(err).Should(BeNil())
+			Expect(stored).Should(Equal(gateway))
+		}
+
+		gateway, err := manager.Create(nonce)
+		expectState(gateway, err, GatewayCreated)
+		gateway, err = manager.Refresh(nonce)
+		expectState(gateway, err, GatewayCreated)
+		_, err = manager.BuildSweep(context.Background(), nonce, Standard)
+		Expect(err).ShouldNot(BeNil())
+
+		addr, err := DecodeAddress(gateway.Address, &chaincfg.TestNet3Params)
+		Expect(err).Should(BeNil())
+		deposit := core.addUTXO(addr, chainhash.Hash{0x6A}, 100000, 0)
+		gateway, err = manager.Refresh(nonce)
+		expectState(gateway, err, GatewayDeposited)
+		Expect(gateway.Amount).Should(Equal(int64(100000)))
+		_, err = manager.BuildSweep(context.Background(), nonce, Standard)
+		Expect(err).ShouldNot(BeNil())
+
+		core.confirmations[deposit.TxHash] = 6
+		gateway, err = manager.Refresh(nonce)
+		expectState(gateway, err, GatewayConfirmed)
+		Expect(gateway.Confirmations).Should(Equal(int64(6)))
+		_, err = manager.BuildSweep(context.Background(), nonce, Standard)
+		Expect(err).Should(BeNil())
+
+		// Once the sweep spends the deposit, the gateway stays confirmed
+		// until the sweep is completed.
+		core.spend(deposit)
+		gateway, err = manager.Refresh(nonce)
+		expectState(gateway, err, GatewayConfirmed)
+		Expect(gateway.Amount).Should(Equal(int64(100000)))
+
+		gateway, err = manager.Complete(nonce, chainhash.Hash{0x5E}.String())
+		expectState(gateway, err, GatewayCompleted)
+		Expect(gateway.SweepTxHash).Should(Equal(chainhash.Hash{0x5E}.String()))
+		gateway, err = manager.Refresh(nonce)
+		expectState(gateway, err, GatewayCompleted)
+		_, err = manager.BuildSweep(context.Background(), nonce, Standard)
+		Expect(err).ShouldNot(BeNil())
+	})
+
+	It("should return to created when an unconfirmed deposit disappears", func() {
+		core := newMockClientCore(&chaincfg.TestNet3Params, 1842420)
+		account, _, _ := newMockAccount(core, 0)
+		manager := NewGatewayManager(account, NewMemoryGatewayStore(), 6)
+		gateway, err := manager.Create(nonce)
+		Expect(err).Should(BeNil())
+
+		addr, err := DecodeAddress(gateway.Address, &chaincfg.TestNet3Params)
+		Expect(err).Should(BeNil())
+		deposit := core.addUTXO(addr, chainhash.Hash{0x6B}, 100000, 0)
+		gateway, err = manager.Refresh(nonce)
+		Expect(err).Should(BeNil())
+		Expect(gateway.State).Should(Equal(GatewayDeposited))
+
+		core.spend(deposit)
+		gateway, err = manager.Refresh(nonce)
+		Expect(err).Should(BeNil())
+		Expect(gateway.State).Should(Equal(GatewayCreated))
+		Expect(gateway.Amount).Should(Equal(int64(0)))
+	})
+
+	It("should not get gateways that were not created", func() {
+		core := newMockClientCore(&chaincfg.TestNet3Params, 1842420)
+		account, _, _ := newMockAccount(core, 0)
+		manager := NewGatewayManager(account, NewMemoryGatewayStore(), 6)
+		_, err := manager.Get(nonce)
+		Expect(err).ShouldNot(BeNil())
+		_, err = manager.Refresh(nonce)
+		Expect(err).ShouldNot(BeNil())
+		_, err = manager.Complete(nonce, chainhash.Hash{0x5E}.String())
+		Expect(err).ShouldNot(BeNil())
+	})
+})
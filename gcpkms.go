@@ -0,0 +1,106 @@
+package libzec
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/renproject/libzec-go/clients"
+)
+
+type gcpKMSKey struct {
+	client   *http.Client
+	endpoint string
+	name     string
+}
+
+// NewGCPKMSKey returns the EC_SIGN_SECP256K1_SHA256 key version of Google Cloud
+// KMS with the given resource name, such as
+// projects/p/locations/l/keyRings/r/cryptoKeys/k/cryptoKeyVersions/1. The http
+// client must authenticate the requests, for example the client returned by
+// google.DefaultClient of golang.org/x/oauth2.
+func NewGCPKMSKey(client *http.Client, name string) KMSKey {
+	return NewGCPKMSKeyWithEndpoint(client, "https://cloudkms.googleapis.com", name)
+}
+
+// NewGCPKMSKeyWithEndpoint is the same as NewGCPKMSKey, but sends the requests
+// to the given endpoint, such as a private endpoint of Google Cloud KMS.
+func NewGCPKMSKeyWithEndpoint(client *http.Client, endpoint, name string) KMSKey {
+	if client == nil {
+		client = clients.HTTPClient()
+	}
+	return &gcpKMSKey{client, strings.TrimSuffix(endpoint, "/"), strings.TrimPrefix(name, "/")}
+}
+
+func (key *gcpKMSKey) Sign(ctx context.Context, digest []byte) ([]byte, error) {
+	req := struct {
+		Digest struct {
+			SHA256 []byte `json:"sha256"`
+		} `json:"digest"`
+	}{}
+	req.Digest.SHA256 = digest
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	res := struct {
+		Signature []byte `json:"signature"`
+	}{}
+	if err := key.call(ctx, http.MethodPost, ":asymmetricSign", bytes.NewReader(body), &res); err != nil {
+		return nil, err
+	}
+	return res.Signature, nil
+}
+
+func (key *gcpKMSKey) PublicKey(ctx context.Context) ([]byte, error) {
+	res := struct {
+		PEM       string `json:"pem"`
+		Algorithm string `json:"algorithm"`
+	}{}
+	if err := key.call(ctx, http.MethodGet, "/publicKey", nil, &res); err != nil {
+		return nil, err
+	}
+	if res.Algorithm != "" && res.Algorithm != "EC_SIGN_SECP256K1_SHA256" {
+		return nil, fmt.Errorf("unsupported google cloud kms key algorithm %s", res.Algorithm)
+	}
+	return []byte(res.PEM), nil
+}
+
+// call sends a request to the method of the key version, and decodes the
+// response into res.
+func (key *gcpKMSKey) call(ctx context.Context, httpMethod, method string, body io.Reader, res interface{}) error {
+	req, err := http.NewRequest(httpMethod, fmt.Sprintf("%s/v1/%s%s", key.endpoint, key.name, method), body)
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := key.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("cannot call google cloud kms %s: %v", method, err)
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		failure := struct {
+			Error struct {
+				Status  string `json:"status"`
+				Message string `json:"message"`
+			} `json:"error"`
+		}{}
+		json.Unmarshal(data, &failure)
+		return fmt.Errorf("google cloud kms %s failed with status %d: %s %s", method, resp.StatusCode, failure.Error.Status, failure.Error.Message)
+	}
+	return json.Unmarshal(data, res)
+}
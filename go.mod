@@ -10,9 +10,12 @@ require (
 	github.com/btcsuite/websocket v0.0.0-20150119174127-31079b680792
 	github.com/codahale/blake2 v0.0.0-20150924215134-8d10d0420cbf
 	github.com/ethereum/go-ethereum v1.8.23
+	github.com/golang/protobuf v1.3.1
 	github.com/hpcloud/tail v1.0.0
 	github.com/iqoption/zecutil v0.0.0-20181123060914-2cb80ea5c0ce
+	github.com/karalabe/hid v1.0.0
 	github.com/konsorten/go-windows-terminal-sequences v1.0.1
+	github.com/miekg/pkcs11 v1.0.3
 	github.com/minio/blake2b-simd v0.0.0-20160723061019-3f5f724cb5b1
 	github.com/mr-tron/base58 v1.1.1
 	github.com/onsi/ginkgo v1.8.0
@@ -24,10 +27,15 @@ require (
 	github.com/sirupsen/logrus v1.3.0
 	github.com/tyler-smith/go-bip32 v0.0.0-20170922074101-2c9cfd177564
 	github.com/tyler-smith/go-bip39 v1.0.0
+	go.etcd.io/bbolt v1.3.3
+	go.opentelemetry.io/otel v1.24.0
+	go.opentelemetry.io/otel/trace v1.24.0
 	golang.org/x/crypto v0.0.0-20190219172222-a4c6cb3142f2
 	golang.org/x/net v0.0.0-20190213061140-3a22650c66bd
 	golang.org/x/sys v0.0.0-20190222171317-cd391775e71e
 	golang.org/x/text v0.3.0
+	google.golang.org/genproto v0.0.0-20180817151627-c66870c02cf8 // indirect
+	google.golang.org/grpc v1.19.1
 	gopkg.in/fsnotify.v1 v1.4.7
 	gopkg.in/tomb.v1 v1.0.0-20141024135613-dd632973f1e7
 	gopkg.in/yaml.v2 v2.2.2
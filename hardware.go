@@ -0,0 +1,116 @@
+package libzec
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/sirupsen/logrus"
+)
+
+// A HardwareDevice is a hardware wallet, such as a Ledger or a Trezor, that
+// derives keys and signs on the device. Implementations own the HID transport
+// and the device protocol, such as the APDUs of the Ledger device returned by
+// NewLedgerDevice, and are expected to ask the user to confirm every signature
+// on the device. Signatures are verified by the signer returned by
+// NewHardwareSigner, so devices do not need to normalize them.
+type HardwareDevice interface {
+	// PublicKey returns the public key at the given derivation path.
+	PublicKey(ctx context.Context, derivationPath []uint32) (*btcec.PublicKey, error)
+
+	// Sign signs the hashes using the key at the given derivation path, and
+	// returns a signature for every hash, in the same order.
+	Sign(ctx context.Context, derivationPath []uint32, hashes [][]byte) ([]*btcec.Signature, error)
+}
+
+type hardwareSigner struct {
+	device         HardwareDevice
+	derivationPath []uint32
+	pubKey         *btcec.PublicKey
+}
+
+// NewHardwareSigner returns a signer that signs using the key at the given
+// derivation path of the hardware device. The public key is fetched once, when
+// the signer is created.
+func NewHardwareSigner(ctx context.Context, device HardwareDevice, derivationPath []uint32) (Signer, error) {
+	pubKey, err := device.PublicKey(ctx, derivationPath)
+	if err != nil {
+		return nil, fmt.Errorf("cannot get the public key from the device: %v", err)
+	}
+	return &hardwareSigner{device, derivationPath, pubKey}, nil
+}
+
+// Sign signs the hashes on the device. The signatures returned by the device
+// are verified against the public key of the signer, and normalized to low S
+// values, so that a faulty or compromised device cannot produce transactions
+// that are invalid or non standard.
+func (signer *hardwareSigner) Sign(ctx context.Context, hashes [][]byte) ([]*btcec.Signature, error) {
+	sigs, err := signer.device.Sign(ctx, signer.derivationPath, hashes)
+	if err != nil {
+		return nil, err
+	}
+	if len(sigs) != len(hashes) {
+		return nil, fmt.Errorf("invalid number of signatures: got: %d required: %d", len(sigs), len(hashes))
+	}
+	normalized := make([]*btcec.Signature, len(sigs))
+	for i, sig := range sigs {
+		if normalized[i], err = verifySig(sig, hashes[i], signer.pubKey); err != nil {
+			return nil, NewErrInvalidSignature(i, err.Error())
+		}
+	}
+	return normalized, nil
+}
+
+func (signer *hardwareSigner) PublicKey() *btcec.PublicKey {
+	return signer.pubKey
+}
+
+type hardwareWallet struct {
+	device   HardwareDevice
+	metadata MetadataStore
+	client   Client
+	logger   logrus.FieldLogger
+}
+
+// NewHardwareWallet returns a wallet whose accounts are derived and signed on
+// the hardware device.
+func NewHardwareWallet(device HardwareDevice, client Client, logger logrus.FieldLogger) Wallet {
+	return &hardwareWallet{device, NewMemoryMetadataStore(), client, logger}
+}
+
+// NewAccount returns the account at the given derivation path of the device.
+// The password is ignored, as passphrases are entered on the device.
+func (wallet *hardwareWallet) NewAccount(derivationPath []uint32, password string) (Account, error) {
+	signer, err := NewHardwareSigner(context.Background(), wallet.device, derivationPath)
+	if err != nil {
+		return nil, err
+	}
+	return NewAccountWithSigner(wallet.client, signer, wallet.logger), nil
+}
+
+// NewHDAccount is not supported, as the private keys of the derived addresses
+// cannot be exported from the device.
+func (wallet *hardwareWallet) NewHDAccount(derivationPath []uint32, password string) (HDAccount, error) {
+	return nil, fmt.Errorf("hd accounts are not supported by hardware wallets")
+}
+
+func (wallet *hardwareWallet) AccountAt(accountIndex uint32, password string) (Account, error) {
+	return wallet.NewAccount(ZCashDerivationPath(accountIndex, ExternalChain, 0), password)
+}
+
+func (wallet *hardwareWallet) HDAccountAt(accountIndex uint32, password string) (HDAccount, error) {
+	return wallet.NewHDAccount(zcashAccountPath(accountIndex), password)
+}
+
+// ExtendedPublicKey is not supported, as devices only export public keys.
+func (wallet *hardwareWallet) ExtendedPublicKey(derivationPath []uint32, password string) (string, error) {
+	return "", fmt.Errorf("extended public keys are not supported by hardware wallets")
+}
+
+func (wallet *hardwareWallet) Metadata() MetadataStore {
+	return wallet.metadata
+}
+
+func (wallet *hardwareWallet) SetMetadataStore(store MetadataStore) {
+	wallet.metadata = store
+}
@@ -0,0 +1,133 @@
+package libzec_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"math/big"
+
+	"github.com/btcsuite/btcd/btcec"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/renproject/libzec-go"
+)
+
+// mockDevice is a hardware device holding a single key, whose signatures can
+// be altered before they are returned.
+type mockDevice struct {
+	privKey *btcec.PrivateKey
+	alter   func(sig *btcec.Signature) *btcec.Signature
+}
+
+func (device *mockDevice) PublicKey(ctx context.Context, derivationPath []uint32) (*btcec.PublicKey, error) {
+	return device.privKey.PubKey(), nil
+}
+
+func (device *mockDevice) Sign(ctx context.Context, derivationPath []uint32, hashes [][]byte) ([]*btcec.Signature, error) {
+	sigs := make([]*btcec.Signature, len(hashes))
+	for i, hash := range hashes {
+		sig, err := device.privKey.Sign(hash)
+		if err != nil {
+			return nil, err
+		}
+		sigs[i] = device.alter(sig)
+	}
+	return sigs, nil
+}
+
+// mockLedgerConn is the HID connection of a Ledger device that answers every
+// APDU with the given response.
+type mockLedgerConn struct {
+	written  []byte
+	response []byte
+	reports  *bytes.Buffer
+}
+
+func (conn *mockLedgerConn) Write(report []byte) (int, error) {
+	Expect(report).Should(HaveLen(64))
+	Expect(report[:3]).Should(Equal([]byte{0x01, 0x01, 0x05}))
+	Expect(binary.BigEndian.Uint16(report[3:5])).Should(Equal(uint16(len(conn.written) / 59)))
+	conn.written = append(conn.written, report[5:]...)
+
+	length := int(binary.BigEndian.Uint16(conn.written))
+	if len(conn.written) >= 2+length {
+		res := append([]byte{byte(len(conn.response) >> 8), byte(len(conn.response))}, conn.response...)
+		for seq := 0; len(res) > 0; seq++ {
+			packet := make([]byte, 64)
+			copy(packet, []byte{0x01, 0x01, 0x05, 0x00, byte(seq)})
+			res = res[copy(packet[5:], res):]
+			conn.reports.Write(packet)
+		}
+	}
+	return len(report), nil
+}
+
+func (conn *mockLedgerConn) Read(report []byte) (int, error) {
+	return conn.reports.Read(report)
+}
+
+var _ = Describe("Hardware signers", func() {
+	hash := sha256.Sum256([]byte("libzec"))
+
+	It("should normalize the signatures of the device to low S values", func() {
+		privKey, err := btcec.NewPrivateKey(btcec.S256())
+		Expect(err).Should(BeNil())
+		device := &mockDevice{privKey, func(sig *btcec.Signature) *btcec.Signature {
+			return &btcec.Signature{R: sig.R, S: new(big.Int).Sub(btcec.S256().N, sig.S)}
+		}}
+		signer, err := NewHardwareSigner(context.Background(), device, ZCashDerivationPath(0, ExternalChain, 0))
+		Expect(err).Should(BeNil())
+
+		sigs, err := signer.Sign(context.Background(), [][]byte{hash[:]})
+		Expect(err).Should(BeNil())
+		Expect(sigs[0].S.Cmp(new(big.Int).Rsh(btcec.S256().N, 1))).Should(BeNumerically("<=", 0))
+		Expect(sigs[0].Verify(hash[:], privKey.PubKey())).Should(BeTrue())
+	})
+
+	It("should reject signatures of another key", func() {
+		privKey, err := btcec.NewPrivateKey(btcec.S256())
+		Expect(err).Should(BeNil())
+		other, err := btcec.NewPrivateKey(btcec.S256())
+		Expect(err).Should(BeNil())
+		device := &mockDevice{privKey, func(sig *btcec.Signature) *btcec.Signature {
+			sig, err := other.Sign(hash[:])
+			Expect(err).Should(BeNil())
+			return sig
+		}}
+		signer, err := NewHardwareSigner(context.Background(), device, ZCashDerivationPath(0, ExternalChain, 0))
+		Expect(err).Should(BeNil())
+
+		_, err = signer.Sign(context.Background(), [][]byte{hash[:]})
+		Expect(err).ShouldNot(BeNil())
+	})
+
+	It("should get the public key of a ledger device", func() {
+		privKey, err := btcec.NewPrivateKey(btcec.S256())
+		Expect(err).Should(BeNil())
+		pubKey := privKey.PubKey().SerializeUncompressed()
+		address := []byte("t1Hsc1LR8yKnbbe3twRp88p6vFfC5t7DLbs")
+		response := append([]byte{byte(len(pubKey))}, pubKey...)
+		response = append(response, byte(len(address)))
+		response = append(response, address...)
+		response = append(response, make([]byte, 32)...)
+		response = append(response, 0x90, 0x00)
+		conn := &mockLedgerConn{response: response, reports: new(bytes.Buffer)}
+
+		path := ZCashDerivationPath(0, ExternalChain, 0)
+		key, err := NewLedgerDevice(conn).PublicKey(context.Background(), path)
+		Expect(err).Should(BeNil())
+		Expect(key.IsEqual(privKey.PubKey())).Should(BeTrue())
+
+		apdu := conn.written[2 : 2+binary.BigEndian.Uint16(conn.written)]
+		Expect(apdu[:5]).Should(Equal([]byte{0xe0, 0x40, 0x00, 0x00, byte(1 + 4*len(path))}))
+		Expect(apdu[5]).Should(Equal(byte(len(path))))
+		Expect(binary.BigEndian.Uint32(apdu[6:])).Should(Equal(path[0]))
+	})
+
+	It("should fail on the error status of a ledger device", func() {
+		conn := &mockLedgerConn{response: []byte{0x6d, 0x00}, reports: new(bytes.Buffer)}
+		_, err := NewLedgerDevice(conn).PublicKey(context.Background(), ZCashDerivationPath(0, ExternalChain, 0))
+		Expect(err).ShouldNot(BeNil())
+	})
+})
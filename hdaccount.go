@@ -0,0 +1,323 @@
+package libzec
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/hex"
+	"fmt"
+	"sync"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/renproject/libzec-go/clients"
+	"github.com/sirupsen/logrus"
+	"github.com/tyler-smith/go-bip32"
+)
+
+// BIP-44 chains of an HD account.
+const (
+	ExternalChain = uint32(0)
+	InternalChain = uint32(1)
+)
+
+// HDAccount is a ZCash account that manages the receive (external) and change
+// (internal) addresses derived from a BIP-44 account key. Every transaction
+// sends its change to a fresh change address, and the balance and utxos of
+// the account are aggregated over all of its derived addresses.
+type HDAccount interface {
+	// ReceiveAddress returns the receive address at the given index.
+	ReceiveAddress(index uint32) (btcutil.Address, error)
+
+	// NextReceiveAddress returns a receive address that has not been handed
+	// out before.
+	NextReceiveAddress() (btcutil.Address, error)
+
+	// Balance returns the balance of all the derived addresses.
+	Balance(confirmations int64) (int64, error)
+
+	// UTXOs returns the utxos of all the derived addresses.
+	UTXOs(confirmations int64) ([]clients.UTXO, error)
+
+	// Transfer sends the value to the given address, funded by the utxos of
+	// the derived addresses, and sends the change to a fresh change address.
+	Transfer(ctx context.Context, to string, value int64) (TxReceipt, error)
+
+	// Rescan walks the derivation indices of both chains, and marks every
+	// address up to the last used one as handed out. The scan of a chain
+	// stops after gapLimit consecutive unused addresses. It is required to
+	// restore an existing account from its mnemonic.
+	Rescan(ctx context.Context, gapLimit uint32) error
+
+	// FreezeUTXO prevents the utxo from being spent by Transfer until it is
+	// unfrozen.
+	FreezeUTXO(txHash string, vout uint32)
+
+	// UnfreezeUTXO allows a frozen utxo to be spent again.
+	UnfreezeUTXO(txHash string, vout uint32)
+
+	// FrozenUTXOs returns the frozen outpoints, formatted as "txhash:vout".
+	FrozenUTXOs() []string
+}
+
+// DefaultGapLimit is the gap limit recommended by BIP-44.
+const DefaultGapLimit = uint32(20)
+
+type hdAccount struct {
+	mu     *sync.Mutex
+	chains map[uint32]*bip32.Key
+	next   map[uint32]uint32
+	client Client
+	logger logrus.FieldLogger
+
+	*utxoFreezer
+}
+
+// hdAddress is a derived address and its keys. The private key is nil if the
+// account was created from an extended public key.
+type hdAddress struct {
+	address btcutil.Address
+	pubKey  *btcec.PublicKey
+	privKey *ecdsa.PrivateKey
+}
+
+// NewHDAccount returns an HD account for the given BIP-44 account key. The
+// account starts using the addresses at index 0 of both chains. If the key is
+// an extended public key the account is watch-only, and Transfer returns
+// ErrWatchOnly.
+func NewHDAccount(client Client, accountKey *bip32.Key, logger logrus.FieldLogger) (HDAccount, error) {
+	external, err := accountKey.NewChildKey(ExternalChain)
+	if err != nil {
+		return nil, err
+	}
+	internal, err := accountKey.NewChildKey(InternalChain)
+	if err != nil {
+		return nil, err
+	}
+	return &hdAccount{
+		mu:     new(sync.Mutex),
+		chains: map[uint32]*bip32.Key{ExternalChain: external, InternalChain: internal},
+		next:   map[uint32]uint32{ExternalChain: 0, InternalChain: 0},
+		client: client,
+		logger: defaultLogger(logger),
+
+		utxoFreezer: newUTXOFreezer(),
+	}, nil
+}
+
+func (account *hdAccount) ReceiveAddress(index uint32) (btcutil.Address, error) {
+	addr, err := account.derive(ExternalChain, index)
+	if err != nil {
+		return nil, err
+	}
+	return addr.address, nil
+}
+
+func (account *hdAccount) NextReceiveAddress() (btcutil.Address, error) {
+	addr, err := account.nextAddress(ExternalChain)
+	if err != nil {
+		return nil, err
+	}
+	return addr.address, nil
+}
+
+func (account *hdAccount) Balance(confirmations int64) (int64, error) {
+	utxos, err := account.UTXOs(confirmations)
+	if err != nil {
+		return 0, err
+	}
+	var balance int64
+	for _, utxo := range utxos {
+		balance += utxo.Amount
+	}
+	return balance, nil
+}
+
+func (account *hdAccount) UTXOs(confirmations int64) ([]clients.UTXO, error) {
+	addrs, err := account.usedAddresses()
+	if err != nil {
+		return nil, err
+	}
+	addresses := make([]string, len(addrs))
+	for i, addr := range addrs {
+		addresses[i] = addr.address.EncodeAddress()
+	}
+	return account.client.GetUTXOsMulti(addresses, 0, confirmations)
+}
+
+func (account *hdAccount) Transfer(ctx context.Context, to string, value int64) (TxReceipt, error) {
+	if !account.chains[ExternalChain].IsPrivate {
+		return TxReceipt{}, ErrWatchOnly
+	}
+	addrs, err := account.usedAddresses()
+	if err != nil {
+		return TxReceipt{}, err
+	}
+
+	signerUTXOs := []SignerUTXOs{}
+	privKeys := map[string]*ecdsa.PrivateKey{}
+	for _, addr := range addrs {
+		utxos, err := account.client.GetUTXOs(addr.address.EncodeAddress(), 0, 0)
+		if err != nil {
+			return TxReceipt{}, err
+		}
+		utxos = account.spendable(utxos)
+		if len(utxos) == 0 {
+			continue
+		}
+		signerUTXOs = append(signerUTXOs, SignerUTXOs{PubKey: addr.privKey.PublicKey, UTXOs: utxos})
+		privKeys[hex.EncodeToString((*btcec.PublicKey)(&addr.privKey.PublicKey).SerializeCompressed())] = addr.privKey
+	}
+
+	// The change address is only marked as used once the transaction is
+	// built, so that failed transfers do not leave gaps in the change chain
+	// that a rescan would not cross.
+	account.mu.Lock()
+	changeIndex := account.next[InternalChain]
+	account.mu.Unlock()
+	change, err := account.derive(InternalChain, changeIndex)
+	if err != nil {
+		return TxReceipt{}, err
+	}
+	builder := NewTxBuilder(account.client)
+	builder.SetLogger(account.logger)
+	tx, err := builder.BuildMulti(to, change.address.EncodeAddress(), value, signerUTXOs)
+	if err != nil {
+		return TxReceipt{}, err
+	}
+	account.markUsed(InternalChain, changeIndex)
+
+	sigs := make([]*btcec.Signature, len(tx.Hashes()))
+	for _, signerHashes := range tx.SignerHashes() {
+		pubKey := signerHashes.PubKey
+		privKey := (*btcec.PrivateKey)(privKeys[hex.EncodeToString((*btcec.PublicKey)(&pubKey).SerializeCompressed())])
+		for i, hash := range signerHashes.Hashes {
+			sig, err := privKey.Sign(hash)
+			if err != nil {
+				return TxReceipt{}, err
+			}
+			sigs[signerHashes.Inputs[i]] = sig
+		}
+	}
+	if err := tx.InjectSigs(sigs); err != nil {
+		return TxReceipt{}, err
+	}
+
+	select {
+	case <-ctx.Done():
+		return TxReceipt{}, ctx.Err()
+	default:
+	}
+	if _, err := tx.Submit(); err != nil {
+		return TxReceipt{}, err
+	}
+	account.logger.Infof("sent %d ZAT to %s with change to %s", value, to, change.address.EncodeAddress())
+	return tx.(*transaction).receipt()
+}
+
+func (account *hdAccount) Rescan(ctx context.Context, gapLimit uint32) error {
+	if gapLimit == 0 {
+		gapLimit = DefaultGapLimit
+	}
+	for _, chain := range []uint32{ExternalChain, InternalChain} {
+		next := uint32(0)
+		for index := uint32(0); index < next+gapLimit; index++ {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			default:
+			}
+			addr, err := account.derive(chain, index)
+			if err != nil {
+				return err
+			}
+			used, _, err := account.client.ScriptFunded(addr.address.EncodeAddress(), 1)
+			if err != nil {
+				return fmt.Errorf("cannot check the usage of %s: %v", addr.address.EncodeAddress(), err)
+			}
+			if used {
+				next = index + 1
+			}
+		}
+		account.logger.Infof("found %d used addresses on chain %d", next, chain)
+
+		account.mu.Lock()
+		if next > account.next[chain] {
+			account.next[chain] = next
+		}
+		account.mu.Unlock()
+	}
+	return nil
+}
+
+// nextAddress returns the next unused address of the chain, and marks it as
+// used.
+func (account *hdAccount) nextAddress(chain uint32) (hdAddress, error) {
+	account.mu.Lock()
+	index := account.next[chain]
+	account.next[chain]++
+	account.mu.Unlock()
+	return account.derive(chain, index)
+}
+
+// markUsed marks the address at the index of the chain, and every address
+// before it, as used.
+func (account *hdAccount) markUsed(chain, index uint32) {
+	account.mu.Lock()
+	defer account.mu.Unlock()
+	if index >= account.next[chain] {
+		account.next[chain] = index + 1
+	}
+}
+
+// usedAddresses returns the addresses of both chains that have been handed
+// out, including the first receive address.
+func (account *hdAccount) usedAddresses() ([]hdAddress, error) {
+	account.mu.Lock()
+	next := map[uint32]uint32{}
+	for chain, index := range account.next {
+		next[chain] = index
+	}
+	account.mu.Unlock()
+	if next[ExternalChain] == 0 {
+		next[ExternalChain] = 1
+	}
+
+	addrs := []hdAddress{}
+	for _, chain := range []uint32{ExternalChain, InternalChain} {
+		for index := uint32(0); index < next[chain]; index++ {
+			addr, err := account.derive(chain, index)
+			if err != nil {
+				return nil, err
+			}
+			addrs = append(addrs, addr)
+		}
+	}
+	return addrs, nil
+}
+
+func (account *hdAccount) derive(chain, index uint32) (hdAddress, error) {
+	key, err := account.chains[chain].NewChildKey(index)
+	if err != nil {
+		return hdAddress{}, err
+	}
+	addr := hdAddress{}
+	if key.IsPrivate {
+		if addr.privKey, err = crypto.ToECDSA(key.Key); err != nil {
+			return hdAddress{}, err
+		}
+		addr.pubKey = (*btcec.PublicKey)(&addr.privKey.PublicKey)
+	} else {
+		if addr.pubKey, err = btcec.ParsePubKey(key.Key, btcec.S256()); err != nil {
+			return hdAddress{}, err
+		}
+	}
+	pubKeyBytes, err := account.client.SerializePublicKey(addr.pubKey)
+	if err != nil {
+		return hdAddress{}, err
+	}
+	if addr.address, err = account.client.PublicKeyToAddress(pubKeyBytes); err != nil {
+		return hdAddress{}, err
+	}
+	return addr, nil
+}
@@ -0,0 +1,49 @@
+package libzec_test
+
+import (
+	"context"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/renproject/libzec-go"
+	"github.com/sirupsen/logrus"
+)
+
+var _ = Describe("HD accounts", func() {
+	const mnemonic = "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"
+
+	It("should only use a change address once a transfer is built", func() {
+		core := newMockClientCore(&chaincfg.TestNet3Params, 1842420)
+		client := NewClient(core)
+		wallet := NewWallet(mnemonic, client, logrus.StandardLogger())
+		account, err := wallet.HDAccountAt(0, "")
+		Expect(err).Should(BeNil())
+		receive, err := account.ReceiveAddress(0)
+		Expect(err).Should(BeNil())
+		core.addUTXO(receive, chainhash.Hash{0xA0}, 1000000, 1)
+		_, _, to := newMockAccount(core, 0)
+
+		// changeScript returns the script of the change address at the index.
+		changeScript := func(index uint32) []byte {
+			change, err := wallet.NewAccount(ZCashDerivationPath(0, InternalChain, index), "")
+			Expect(err).Should(BeNil())
+			addr, err := change.Address()
+			Expect(err).Should(BeNil())
+			script, err := PayToAddrScript(addr)
+			Expect(err).Should(BeNil())
+			return script
+		}
+
+		_, err = account.Transfer(context.Background(), to.EncodeAddress(), 2000000)
+		Expect(err).ShouldNot(BeNil())
+
+		for index := uint32(0); index < 2; index++ {
+			receipt, err := account.Transfer(context.Background(), to.EncodeAddress(), 100000)
+			Expect(err).Should(BeNil())
+			Expect(receipt.ChangeIndex).Should(BeNumerically(">=", 0))
+			Expect(receipt.Outputs[receipt.ChangeIndex].PkScript).Should(Equal(changeScript(index)))
+		}
+	})
+})
@@ -0,0 +1,148 @@
+package libzec
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"github.com/renproject/libzec-go/clients"
+)
+
+// HistoryEntry is a transaction of the history of an account. The amount is
+// the net change of the balance of the account caused by the transaction, and
+// the balance is the balance of the account once the transaction, and every
+// transaction before it, is applied.
+//
+// The change, and the labels of the addresses paid by the transaction, are
+// only known for the transactions recorded in the tx store of the account.
+type HistoryEntry struct {
+	TxHash      string            `json:"txHash"`
+	BlockHeight int64             `json:"blockHeight"`
+	Amount      int64             `json:"amount"`
+	Balance     int64             `json:"balance"`
+	Change      int64             `json:"change"`
+	Note        string            `json:"note,omitempty"`
+	Labels      map[string]string `json:"labels,omitempty"`
+}
+
+// SetMetadataStore sets the store of the labels and notes that annotate the
+// history of the account.
+func (account *account) SetMetadataStore(store MetadataStore) {
+	account.metadata = store
+}
+
+// History returns the transactions of the account, from the most recent one,
+// skipping the first offset transactions. A limit of 0 returns every
+// remaining transaction. A core of the client must implement
+// clients.AddressHistory, otherwise ErrNotSupported is returned.
+func (account *account) History(ctx context.Context, limit, offset int) ([]HistoryEntry, error) {
+	if limit < 0 || offset < 0 {
+		return nil, fmt.Errorf("invalid page: limit %d offset %d", limit, offset)
+	}
+	history, ok := addressHistory(account.Client)
+	if !ok {
+		return nil, ErrNotSupported
+	}
+	me, err := account.Address()
+	if err != nil {
+		return nil, err
+	}
+	deltas, err := history.AddressDeltas(me.EncodeAddress())
+	if err != nil {
+		return nil, err
+	}
+	P2PKHScript, err := PayToAddrScript(me)
+	if err != nil {
+		return nil, err
+	}
+
+	// The running balance is computed from the oldest transaction, before
+	// the entries are returned from the most recent one.
+	entries := make([]HistoryEntry, len(deltas))
+	var balance int64
+	for i, delta := range deltas {
+		balance += delta.Amount
+		entries[len(deltas)-1-i] = HistoryEntry{
+			TxHash:      delta.TxHash,
+			BlockHeight: delta.BlockHeight,
+			Amount:      delta.Amount,
+			Balance:     balance,
+		}
+	}
+	if offset >= len(entries) {
+		return []HistoryEntry{}, nil
+	}
+	entries = entries[offset:]
+	if limit > 0 && len(entries) > limit {
+		entries = entries[:limit]
+	}
+
+	for i := range entries {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		default:
+		}
+		if err := account.annotate(&entries[i], P2PKHScript); err != nil {
+			return nil, err
+		}
+	}
+	return entries, nil
+}
+
+// annotate sets the change and the labels of the entry using the tx store,
+// and its note using the metadata store.
+func (account *account) annotate(entry *HistoryEntry, P2PKHScript []byte) error {
+	if store := account.store(); store != nil {
+		tx, ok, err := store.Get(entry.TxHash)
+		if err != nil {
+			return err
+		}
+		if ok {
+			for _, txOut := range tx.Outputs {
+				if bytes.Equal(txOut.PkScript, P2PKHScript) {
+					entry.Change += txOut.Value
+					continue
+				}
+				if account.metadata == nil {
+					continue
+				}
+				address, err := ExtractAddress(txOut.PkScript, account.NetworkParams())
+				if err != nil {
+					continue
+				}
+				label, ok, err := account.metadata.Label(address.EncodeAddress())
+				if err != nil {
+					return err
+				}
+				if ok {
+					if entry.Labels == nil {
+						entry.Labels = map[string]string{}
+					}
+					entry.Labels[address.EncodeAddress()] = label
+				}
+			}
+		}
+	}
+	if account.metadata != nil {
+		note, ok, err := account.metadata.Note(entry.TxHash)
+		if err != nil {
+			return err
+		}
+		if ok {
+			entry.Note = note
+		}
+	}
+	return nil
+}
+
+// addressHistory returns the address history of the client, if one of its
+// cores implements it.
+func addressHistory(c Client) (clients.AddressHistory, bool) {
+	for _, core := range clientCores(c) {
+		if history, ok := core.(clients.AddressHistory); ok {
+			return history, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,74 @@
+package libzec_test
+
+import (
+	"context"
+	"time"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/renproject/libzec-go"
+	"github.com/renproject/libzec-go/clients"
+)
+
+// historyMockClientCore is a batch mock client core that can list the
+// transactions of any address, like a zcashd node with -addressindex.
+type historyMockClientCore struct {
+	*batchMockClientCore
+	deltas map[string][]clients.AddressDelta
+}
+
+func newHistoryMockClientCore(core *mockClientCore) *historyMockClientCore {
+	return &historyMockClientCore{&batchMockClientCore{core, map[string]bool{}}, map[string][]clients.AddressDelta{}}
+}
+
+func (core *historyMockClientCore) AddressDeltas(address string) ([]clients.AddressDelta, error) {
+	return core.deltas[address], nil
+}
+
+// wrap wraps the client core with a circuit breaker and tracing, which hide
+// its extensions unless they are unwrapped.
+func wrap(core clients.ClientCore) clients.ClientCore {
+	return NewTracedClientCore(clients.NewCircuitBreaker(core, 3, time.Minute))
+}
+
+var _ = Describe("Account history", func() {
+	It("should list the transactions of the account behind wrapped client cores", func() {
+		core := newHistoryMockClientCore(newMockClientCore(&chaincfg.TestNet3Params, 1842420))
+		privKey, err := btcec.NewPrivateKey(btcec.S256())
+		Expect(err).Should(BeNil())
+		account := NewAccount(NewClient(wrap(core)), privKey.ToECDSA(), nil)
+		addr, err := account.Address()
+		Expect(err).Should(BeNil())
+		core.deltas[addr.EncodeAddress()] = []clients.AddressDelta{
+			{TxHash: "a", Amount: 1000, BlockHeight: 10},
+			{TxHash: "b", Amount: -300, BlockHeight: 11},
+			{TxHash: "c", Amount: 50},
+		}
+
+		entries, err := account.History(context.Background(), 0, 0)
+		Expect(err).Should(BeNil())
+		Expect(entries).Should(Equal([]HistoryEntry{
+			{TxHash: "c", Amount: 50, Balance: 750},
+			{TxHash: "b", BlockHeight: 11, Amount: -300, Balance: 700},
+			{TxHash: "a", BlockHeight: 10, Amount: 1000, Balance: 1000},
+		}))
+
+		entries, err = account.History(context.Background(), 1, 1)
+		Expect(err).Should(BeNil())
+		Expect(entries).Should(HaveLen(1))
+		Expect(entries[0].TxHash).Should(Equal("b"))
+		entries, err = account.History(context.Background(), 0, 3)
+		Expect(err).Should(BeNil())
+		Expect(entries).Should(BeEmpty())
+		_, err = account.History(context.Background(), -1, 0)
+		Expect(err).ShouldNot(BeNil())
+	})
+
+	It("should not list the transactions of accounts without an address history", func() {
+		account, _, _ := newMockAccount(newMockClientCore(&chaincfg.TestNet3Params, 1842420), 0)
+		_, err := account.History(context.Background(), 0, 0)
+		Expect(err).Should(Equal(ErrNotSupported))
+	})
+})
@@ -0,0 +1,259 @@
+package libzec
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+)
+
+// HTLCScript creates a hash time locked contract, that can be redeemed by the
+// spender by revealing the preimage of the secret hash, or refunded by the
+// refunder once the lock time has passed.
+func HTLCScript(spenderPKH, refunderPKH []byte, secretHash [32]byte, lockTime int64) ([]byte, error) {
+	b := txscript.NewScriptBuilder()
+	b.AddOp(txscript.OP_IF)
+	b.AddOp(txscript.OP_SHA256)
+	b.AddData(secretHash[:])
+	b.AddOp(txscript.OP_EQUALVERIFY)
+	b.AddOp(txscript.OP_DUP)
+	b.AddOp(txscript.OP_HASH160)
+	b.AddData(spenderPKH)
+	b.AddOp(txscript.OP_ELSE)
+	b.AddInt64(lockTime)
+	b.AddOp(txscript.OP_CHECKLOCKTIMEVERIFY)
+	b.AddOp(txscript.OP_DROP)
+	b.AddOp(txscript.OP_DUP)
+	b.AddOp(txscript.OP_HASH160)
+	b.AddData(refunderPKH)
+	b.AddOp(txscript.OP_ENDIF)
+	b.AddOp(txscript.OP_EQUALVERIFY)
+	b.AddOp(txscript.OP_CHECKSIG)
+	return b.Script()
+}
+
+// HTLCLockTime returns the lock time of the given hash time locked contract.
+func HTLCLockTime(script []byte) (int64, error) {
+	info, err := classifyHTLC(script)
+	if err != nil {
+		return 0, err
+	}
+	return info.LockTime, nil
+}
+
+// classifyHTLC classifies the script using ClassifyScript, which parses the
+// small integers pushed by OP_1 to OP_16, and returns an error unless the
+// script is a hash time locked contract.
+func classifyHTLC(script []byte) (ScriptInfo, error) {
+	info, err := ClassifyScript(script)
+	if err != nil {
+		return ScriptInfo{}, err
+	}
+	if info.Type != ScriptHTLC {
+		return ScriptInfo{}, fmt.Errorf("not a hash time locked contract: %x", script)
+	}
+	return info, nil
+}
+
+// HTLCRedeemUnlock returns the unlock script that spends a hash time locked
+// contract by revealing the secret, to be used with SignerUTXOs.
+func HTLCRedeemUnlock(secret [32]byte) ([]byte, error) {
+	return txscript.NewScriptBuilder().
+		AddData(secret[:]).
+		AddOp(txscript.OP_TRUE).
+		Script()
+}
+
+// HTLCRefundUnlock returns the unlock script that spends a hash time locked
+// contract, or a refundable slave script, once its lock time has passed, to
+// be used with SignerUTXOs.
+func HTLCRefundUnlock() ([]byte, error) {
+	return txscript.NewScriptBuilder().
+		AddOp(txscript.OP_FALSE).
+		Script()
+}
+
+// ExtractSecret returns the 32 byte secret, whose SHA-256 hash is the given
+// hash, pushed by the signature script.
+func ExtractSecret(sigScript []byte, secretHash [32]byte) ([32]byte, error) {
+	pushes, err := txscript.PushedData(sigScript)
+	if err != nil {
+		return [32]byte{}, err
+	}
+	for _, push := range pushes {
+		if len(push) == 32 && sha256.Sum256(push) == secretHash {
+			secret := [32]byte{}
+			copy(secret[:], push)
+			return secret, nil
+		}
+	}
+	return [32]byte{}, fmt.Errorf("no secret with hash %x in the signature script", secretHash)
+}
+
+// ExtractHTLCSecret returns the secret revealed by a signature script that
+// redeems a hash time locked contract. The contract is the last push of the
+// signature script, and the secret is checked against its secret hash.
+func ExtractHTLCSecret(sigScript []byte) ([32]byte, error) {
+	pushes, err := txscript.PushedData(sigScript)
+	if err != nil {
+		return [32]byte{}, err
+	}
+	if len(pushes) == 0 {
+		return [32]byte{}, fmt.Errorf("empty signature script")
+	}
+	contract, err := classifyHTLC(pushes[len(pushes)-1])
+	if err != nil {
+		return [32]byte{}, err
+	}
+	secretHash := [32]byte{}
+	copy(secretHash[:], contract.SecretHash)
+	return ExtractSecret(sigScript, secretHash)
+}
+
+// InitiateHTLC funds the given hash time locked contract with the given value.
+func (account *account) InitiateHTLC(ctx context.Context, script []byte, value int64, speed TxExecutionSpeed) (TxReceipt, error) {
+	return account.TransferToScript(ctx, script, value, speed, true)
+}
+
+// RedeemHTLC spends the given hash time locked contract to the account's
+// address by revealing the secret.
+func (account *account) RedeemHTLC(ctx context.Context, script []byte, secret [32]byte, speed TxExecutionSpeed) (TxReceipt, error) {
+	preCond, err := account.spendContract(script)
+	if err != nil {
+		return TxReceipt{}, err
+	}
+	return account.SendTransaction(
+		ctx,
+		script,
+		speed,
+		nil,
+		preCond,
+		func(builder *txscript.ScriptBuilder) {
+			builder.AddData(secret[:])
+			builder.AddOp(txscript.OP_TRUE)
+		},
+		nil,
+		true,
+	)
+}
+
+// RefundHTLC spends the given hash time locked contract back to the account's
+// address once its lock time has passed.
+func (account *account) RefundHTLC(ctx context.Context, script []byte, speed TxExecutionSpeed) (TxReceipt, error) {
+	lockTime, err := HTLCLockTime(script)
+	if err != nil {
+		return TxReceipt{}, err
+	}
+	return account.refund(ctx, script, lockTime, speed)
+}
+
+// RefundSlave spends the refundable slave script, created from the given
+// master public key hash, nonce and lock time, back to the account's address
+// once its lock time has passed.
+func (account *account) RefundSlave(ctx context.Context, mpkh, nonce []byte, lockTime int64, speed TxExecutionSpeed) (TxReceipt, error) {
+	pubKeyBytes, err := account.SerializedPublicKey()
+	if err != nil {
+		return TxReceipt{}, err
+	}
+	script, err := account.SlaveScriptWithRefund(mpkh, nonce, btcutil.Hash160(pubKeyBytes), lockTime)
+	if err != nil {
+		return TxReceipt{}, err
+	}
+	return account.refund(ctx, script, lockTime, speed)
+}
+
+// refund spends the else branch of the given time locked script.
+func (account *account) refund(ctx context.Context, script []byte, lockTime int64, speed TxExecutionSpeed) (TxReceipt, error) {
+	preCond, err := account.spendContract(script)
+	if err != nil {
+		return TxReceipt{}, err
+	}
+	return account.SendTransaction(
+		ctx,
+		script,
+		speed,
+		func(txIn *wire.TxIn) {
+			txIn.Sequence = wire.MaxTxInSequenceNum - 1
+		},
+		func(tx *wire.MsgTx) bool {
+			tx.LockTime = uint32(lockTime)
+			return preCond(tx)
+		},
+		func(builder *txscript.ScriptBuilder) {
+			builder.AddOp(txscript.OP_FALSE)
+		},
+		nil,
+		true,
+	)
+}
+
+// spendContract returns a pre-condition that adds an output, paying the balance of
+// the given contract to the account's address.
+func (account *account) spendContract(script []byte) (func(*wire.MsgTx) bool, error) {
+	contractAddr, err := ScriptAddress(script, account.NetworkParams())
+	if err != nil {
+		return nil, err
+	}
+	address, err := account.Address()
+	if err != nil {
+		return nil, err
+	}
+	P2PKHScript, err := PayToAddrScript(address)
+	if err != nil {
+		return nil, err
+	}
+	return func(tx *wire.MsgTx) bool {
+		balance, err := account.Balance(contractAddr.EncodeAddress(), 0)
+		if err != nil || balance <= MaxZCashFee+ZCashDust {
+			return false
+		}
+		tx.AddTxOut(wire.NewTxOut(balance, P2PKHScript))
+		return true
+	}, nil
+}
+
+func (client *client) HTLCFunded(script []byte, value int64) (bool, int64, error) {
+	address, err := ScriptAddress(script, client.NetworkParams())
+	if err != nil {
+		return false, 0, err
+	}
+	return client.ScriptFunded(address.EncodeAddress(), value)
+}
+
+func (client *client) HTLCRedeemed(script []byte, value int64) (bool, int64, error) {
+	address, err := ScriptAddress(script, client.NetworkParams())
+	if err != nil {
+		return false, 0, err
+	}
+	return client.ScriptRedeemed(address.EncodeAddress(), value)
+}
+
+func (client *client) HTLCSpent(script []byte, spender string) (bool, string, error) {
+	address, err := ScriptAddress(script, client.NetworkParams())
+	if err != nil {
+		return false, "", err
+	}
+	return client.ScriptSpent(address.EncodeAddress(), spender)
+}
+
+// scriptNum decodes a minimally encoded script number.
+func scriptNum(data []byte) (int64, error) {
+	if len(data) > 5 {
+		return 0, fmt.Errorf("script number overflow: %d bytes", len(data))
+	}
+	if len(data) == 0 {
+		return 0, nil
+	}
+	var num int64
+	for i, b := range data {
+		num |= int64(b) << uint(8*i)
+	}
+	if data[len(data)-1]&0x80 != 0 {
+		num &= ^(int64(0x80) << uint(8*(len(data)-1)))
+		return -num, nil
+	}
+	return num, nil
+}
@@ -0,0 +1,209 @@
+package libzec_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"math/big"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcutil"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/renproject/libzec-go"
+	"github.com/renproject/libzec-go/clients"
+)
+
+var _ = Describe("HTLC secrets", func() {
+	secret := [32]byte{1, 2, 3}
+	secretHash := sha256.Sum256(secret[:])
+
+	redeemSigScriptWithLockTime := func(revealed []byte, lockTime int64) []byte {
+		contract, err := HTLCScript(make([]byte, 20), make([]byte, 20), secretHash, lockTime)
+		Expect(err).Should(BeNil())
+		sigScript, err := txscript.NewScriptBuilder().
+			AddData(make([]byte, 71)).
+			AddData(make([]byte, 33)).
+			AddData(revealed).
+			AddOp(txscript.OP_TRUE).
+			AddData(contract).
+			Script()
+		Expect(err).Should(BeNil())
+		return sigScript
+	}
+	redeemSigScript := func(revealed []byte) []byte {
+		return redeemSigScriptWithLockTime(revealed, 1000)
+	}
+
+	It("should extract the secret of a redeemed contract", func() {
+		extracted, err := ExtractHTLCSecret(redeemSigScript(secret[:]))
+		Expect(err).Should(BeNil())
+		Expect(extracted).Should(Equal(secret))
+
+		extracted, err = ExtractSecret(redeemSigScript(secret[:]), secretHash)
+		Expect(err).Should(BeNil())
+		Expect(extracted).Should(Equal(secret))
+	})
+
+	It("should extract the secret of a contract with a small lock time", func() {
+		extracted, err := ExtractHTLCSecret(redeemSigScriptWithLockTime(secret[:], 16))
+		Expect(err).Should(BeNil())
+		Expect(extracted).Should(Equal(secret))
+	})
+
+	It("should not extract a secret that does not match the secret hash", func() {
+		_, err := ExtractHTLCSecret(redeemSigScript(make([]byte, 32)))
+		Expect(err).ShouldNot(BeNil())
+	})
+})
+
+var _ = Describe("HTLC lock times", func() {
+	It("should return the lock time of contracts, including small integers", func() {
+		for _, lockTime := range []int64{0, 1, 16, 17, 1000, 1842440} {
+			contract, err := HTLCScript(make([]byte, 20), make([]byte, 20), [32]byte{}, lockTime)
+			Expect(err).Should(BeNil())
+			parsed, err := HTLCLockTime(contract)
+			Expect(err).Should(BeNil())
+			Expect(parsed).Should(Equal(lockTime))
+		}
+	})
+
+	It("should not return the lock time of other scripts", func() {
+		_, err := HTLCLockTime([]byte{txscript.OP_TRUE})
+		Expect(err).ShouldNot(BeNil())
+	})
+})
+
+var _ = Describe("HTLC spends", func() {
+	secret := [32]byte{1, 2, 3}
+	secretHash := sha256.Sum256(secret[:])
+
+	type htlcFixture struct {
+		privKey  *btcec.PrivateKey
+		contract []byte
+		addr     string
+		utxo     clients.UTXO
+		builder  TxBuilder
+	}
+
+	newFixture := func() htlcFixture {
+		privKey, err := btcec.NewPrivateKey(btcec.S256())
+		Expect(err).Should(BeNil())
+		pkh := btcutil.Hash160(privKey.PubKey().SerializeCompressed())
+		contract, err := HTLCScript(pkh, pkh, secretHash, 1000)
+		Expect(err).Should(BeNil())
+		scriptPubKey, err := PayToScriptHashScript(contract)
+		Expect(err).Should(BeNil())
+		addr, err := AddressFromHash160(toHash160(pkh), &chaincfg.TestNet3Params, false)
+		Expect(err).Should(BeNil())
+		client := NewClient(newMockClientCore(&chaincfg.TestNet3Params, 1842420))
+		client.SetPubKeyCompression(true)
+		return htlcFixture{
+			privKey:  privKey,
+			contract: contract,
+			addr:     addr.EncodeAddress(),
+			utxo: clients.UTXO{
+				TxHash:       chainhash.Hash{2}.String(),
+				Amount:       50000,
+				ScriptPubKey: hex.EncodeToString(scriptPubKey),
+			},
+			builder: NewTxBuilder(client),
+		}
+	}
+
+	spendWith := func(fixture htlcFixture, unlock []byte, sign func([]byte) *btcec.Signature) (Tx, error) {
+		tx, err := fixture.builder.BuildMulti(fixture.addr, fixture.addr, 40000, []SignerUTXOs{{
+			PubKey:   fixture.privKey.PublicKey,
+			Contract: fixture.contract,
+			Unlock:   unlock,
+			UTXOs:    []clients.UTXO{fixture.utxo},
+		}})
+		if err != nil {
+			return nil, err
+		}
+		sigs := []*btcec.Signature{}
+		for _, hash := range tx.Hashes() {
+			sigs = append(sigs, sign(hash))
+		}
+		Expect(tx.InjectSigs(sigs)).Should(BeNil())
+		return tx, nil
+	}
+
+	spend := func(fixture htlcFixture, unlock []byte) (Tx, error) {
+		return spendWith(fixture, unlock, func(hash []byte) *btcec.Signature {
+			sig, err := fixture.privKey.Sign(hash)
+			Expect(err).Should(BeNil())
+			return sig
+		})
+	}
+
+	It("should redeem a contract with the secret", func() {
+		fixture := newFixture()
+		unlock, err := HTLCRedeemUnlock(secret)
+		Expect(err).Should(BeNil())
+		tx, err := spend(fixture, unlock)
+		Expect(err).Should(BeNil())
+		Expect(tx.Verify()).Should(BeNil())
+
+		stx, err := tx.Serialize()
+		Expect(err).Should(BeNil())
+		decoded, err := DecodeTransaction(stx)
+		Expect(err).Should(BeNil())
+		msgTx, err := decoded.MsgTx()
+		Expect(err).Should(BeNil())
+		extracted, err := ExtractHTLCSecret(msgTx.TxIn[0].SignatureScript)
+		Expect(err).Should(BeNil())
+		Expect(extracted).Should(Equal(secret))
+	})
+
+	It("should refund a contract once its lock time has passed", func() {
+		fixture := newFixture()
+		fixture.builder.SetLockTime(1000)
+		unlock, err := HTLCRefundUnlock()
+		Expect(err).Should(BeNil())
+		tx, err := spend(fixture, unlock)
+		Expect(err).Should(BeNil())
+		Expect(tx.Verify()).Should(BeNil())
+	})
+
+	It("should estimate the size of the signature script of a redeem", func() {
+		fixture := newFixture()
+		unlock, err := HTLCRedeemUnlock(secret)
+		Expect(err).Should(BeNil())
+		tx, err := spend(fixture, unlock)
+		Expect(err).Should(BeNil())
+		preview, err := tx.Preview()
+		Expect(err).Should(BeNil())
+		stx, err := tx.Serialize()
+		Expect(err).Should(BeNil())
+		Expect(preview.EstimatedSize).Should(BeNumerically(">=", len(stx)))
+		Expect(preview.EstimatedSize).Should(BeNumerically("<=", len(stx)+2))
+	})
+
+	It("should accept signatures with a high S value", func() {
+		fixture := newFixture()
+		unlock, err := HTLCRedeemUnlock(secret)
+		Expect(err).Should(BeNil())
+		tx, err := spendWith(fixture, unlock, func(hash []byte) *btcec.Signature {
+			sig, err := fixture.privKey.Sign(hash)
+			Expect(err).Should(BeNil())
+			return &btcec.Signature{R: sig.R, S: new(big.Int).Sub(btcec.S256().N, sig.S)}
+		})
+		Expect(err).Should(BeNil())
+		Expect(tx.Verify()).Should(BeNil())
+	})
+
+	It("should not spend a contract without its unlock script", func() {
+		_, err := spend(newFixture(), nil)
+		Expect(err).ShouldNot(BeNil())
+	})
+
+	It("should not spend an empty contract", func() {
+		fixture := newFixture()
+		fixture.contract = []byte{}
+		_, err := spend(fixture, nil)
+		Expect(err).ShouldNot(BeNil())
+	})
+})
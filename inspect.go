@@ -0,0 +1,128 @@
+package libzec
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/btcsuite/btcd/chaincfg"
+)
+
+// AddressType is the type of an address.
+type AddressType int
+
+const (
+	AddressP2PKH AddressType = iota
+	AddressP2SH
+	AddressTEX
+	AddressSapling
+	AddressUnified
+)
+
+func (typ AddressType) String() string {
+	switch typ {
+	case AddressP2PKH:
+		return "p2pkh"
+	case AddressP2SH:
+		return "p2sh"
+	case AddressTEX:
+		return "tex"
+	case AddressSapling:
+		return "sapling"
+	case AddressUnified:
+		return "unified"
+	default:
+		return fmt.Sprintf("unknown(%d)", int(typ))
+	}
+}
+
+// AddressInfo describes an address, as returned by InspectAddress.
+type AddressInfo struct {
+	// Network is the name of the network of the address. The transparent
+	// addresses of testnet3 and regtest are the same, and are reported as
+	// testnet3.
+	Network string
+
+	Type AddressType
+
+	// Hash160 is the public key hash or the script hash paid by the address.
+	// It is the hash of the transparent receiver of a unified address, and
+	// nil if the address does not have one.
+	Hash160 []byte
+
+	// Receivers are the types of the receivers of a unified address.
+	Receivers []ReceiverType
+}
+
+// IsTransparent returns whether the address can be paid by a transparent
+// transaction.
+func (info AddressInfo) IsTransparent() bool {
+	return info.Hash160 != nil
+}
+
+// isForNet returns whether the address belongs to the network. The
+// transparent addresses of testnet3 also belong to regtest.
+func (info AddressInfo) isForNet(params *chaincfg.Params) bool {
+	network, err := NetworkOf(params)
+	if err != nil {
+		return false
+	}
+	if network == Regtest && (info.Type == AddressP2PKH || info.Type == AddressP2SH) {
+		return info.Network == Testnet.String()
+	}
+	return info.Network == network.String()
+}
+
+// InspectAddress returns the network, the type and the hash of an address of
+// any supported network.
+func InspectAddress(address string) (AddressInfo, error) {
+	if sep := strings.LastIndexByte(address, '1'); sep > 0 {
+		hrp := strings.ToLower(address[:sep])
+		for _, network := range networks {
+			encoding := networkEncodings[network]
+			switch hrp {
+			case encoding.texHRP:
+				addr, err := DecodeTEXAddress(address, encoding.params)
+				if err != nil {
+					return AddressInfo{}, err
+				}
+				return AddressInfo{Network: network.String(), Type: AddressTEX, Hash160: addr.ScriptAddress()}, nil
+			case encoding.saplingHRP:
+				if _, err := DecodeSaplingAddress(address, encoding.params); err != nil {
+					return AddressInfo{}, err
+				}
+				return AddressInfo{Network: network.String(), Type: AddressSapling}, nil
+			case encoding.unifiedHRP:
+				return inspectUnifiedAddress(address, network)
+			}
+		}
+	}
+
+	prefix, hash, err := decodeHash(address)
+	if err != nil {
+		return AddressInfo{}, fmt.Errorf("unrecognized address %s: %v", address, err)
+	}
+	for _, network := range networks {
+		switch prefix {
+		case networkEncodings[network].pubKeyHashPrefix:
+			return AddressInfo{Network: network.String(), Type: AddressP2PKH, Hash160: hash[:]}, nil
+		case networkEncodings[network].scriptHashPrefix:
+			return AddressInfo{Network: network.String(), Type: AddressP2SH, Hash160: hash[:]}, nil
+		}
+	}
+	return AddressInfo{}, fmt.Errorf("unrecognized address %s: unknown version bytes %x", address, prefix)
+}
+
+func inspectUnifiedAddress(address string, network Network) (AddressInfo, error) {
+	addr, err := DecodeUnifiedAddress(address, networkEncodings[network].params)
+	if err != nil {
+		return AddressInfo{}, err
+	}
+	info := AddressInfo{Network: network.String(), Type: AddressUnified}
+	for _, receiver := range addr.Receivers() {
+		info.Receivers = append(info.Receivers, receiver.Type)
+		if receiver.Type == ReceiverP2PKH || receiver.Type == ReceiverP2SH {
+			info.Hash160 = receiver.Data
+		}
+	}
+	return info, nil
+}
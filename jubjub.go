@@ -0,0 +1,125 @@
+package libzec
+
+import (
+	"math/big"
+)
+
+// Jubjub is the twisted Edwards curve -u^2 + v^2 = 1 + d.u^2.v^2 over the
+// scalar field of BLS12-381, used by the Sapling keys and addresses.
+var (
+	jubjubQ, _ = new(big.Int).SetString("73eda753299d7d483339d80809a1d80553bda402fffe5bfeffffffff00000001", 16)
+	jubjubD    = jubjubCurveD()
+)
+
+// jubjubCofactor is the cofactor of the curve.
+const jubjubCofactor = 8
+
+func jubjubCurveD() *big.Int {
+	d := new(big.Int).ModInverse(big.NewInt(10241), jubjubQ)
+	d.Mul(d, big.NewInt(10240))
+	d.Neg(d)
+	return d.Mod(d, jubjubQ)
+}
+
+// jubjubPoint is a point of Jubjub in affine coordinates.
+type jubjubPoint struct {
+	u, v *big.Int
+}
+
+func jubjubIdentity() jubjubPoint {
+	return jubjubPoint{big.NewInt(0), big.NewInt(1)}
+}
+
+// decodeJubjubPoint decodes the 32 byte encoding of a point, which is the
+// little endian v-coordinate with the sign of the u-coordinate in its most
+// significant bit. It returns false if the encoding is not a point of the
+// curve.
+func decodeJubjubPoint(encoded [32]byte) (jubjubPoint, bool) {
+	sign := encoded[31] >> 7
+	encoded[31] &= 0x7f
+	v := new(big.Int).SetBytes(reverseBytes(encoded[:]))
+	if v.Cmp(jubjubQ) >= 0 {
+		return jubjubPoint{}, false
+	}
+
+	// u^2 = (v^2 - 1) / (d.v^2 + 1)
+	vv := new(big.Int).Mul(v, v)
+	num := new(big.Int).Sub(vv, big.NewInt(1))
+	den := new(big.Int).Mul(jubjubD, vv)
+	den.Add(den, big.NewInt(1)).Mod(den, jubjubQ)
+	denInv := new(big.Int).ModInverse(den, jubjubQ)
+	if denInv == nil {
+		return jubjubPoint{}, false
+	}
+	uu := num.Mul(num, denInv)
+	uu.Mod(uu, jubjubQ)
+	u := new(big.Int).ModSqrt(uu, jubjubQ)
+	if u == nil {
+		return jubjubPoint{}, false
+	}
+	if u.Sign() == 0 && sign == 1 {
+		return jubjubPoint{}, false
+	}
+	if byte(u.Bit(0)) != sign {
+		u.Sub(jubjubQ, u)
+	}
+	return jubjubPoint{u, v}, true
+}
+
+// bytes returns the 32 byte encoding of the point.
+func (p jubjubPoint) bytes() [32]byte {
+	encoded := [32]byte{}
+	v := p.v.Bytes()
+	copy(encoded[32-len(v):], v)
+	copy(encoded[:], reverseBytes(encoded[:]))
+	encoded[31] |= byte(p.u.Bit(0)) << 7
+	return encoded
+}
+
+func (p jubjubPoint) isIdentity() bool {
+	return p.u.Sign() == 0 && p.v.Cmp(big.NewInt(1)) == 0
+}
+
+// add returns p + r. The addition law is complete, so it also doubles points
+// and adds the identity.
+func (p jubjubPoint) add(r jubjubPoint) jubjubPoint {
+	uv := new(big.Int).Mul(p.u, r.v)
+	vu := new(big.Int).Mul(p.v, r.u)
+	uu := new(big.Int).Mul(p.u, r.u)
+	vv := new(big.Int).Mul(p.v, r.v)
+	t := new(big.Int).Mul(jubjubD, uu)
+	t.Mul(t, vv).Mod(t, jubjubQ)
+
+	uDen := new(big.Int).Add(big.NewInt(1), t)
+	uDen.ModInverse(uDen.Mod(uDen, jubjubQ), jubjubQ)
+	vDen := new(big.Int).Sub(big.NewInt(1), t)
+	vDen.ModInverse(vDen.Mod(vDen, jubjubQ), jubjubQ)
+
+	u := uv.Add(uv, vu)
+	u.Mul(u, uDen).Mod(u, jubjubQ)
+	v := vv.Add(vv, uu)
+	v.Mul(v, vDen).Mod(v, jubjubQ)
+	return jubjubPoint{u, v}
+}
+
+// mul returns [scalar] p.
+func (p jubjubPoint) mul(scalar *big.Int) jubjubPoint {
+	result := jubjubIdentity()
+	for i := scalar.BitLen() - 1; i >= 0; i-- {
+		result = result.add(result)
+		if scalar.Bit(i) == 1 {
+			result = result.add(p)
+		}
+	}
+	return result
+}
+
+// reverseBytes returns a reversed copy of the bytes, to convert between the
+// little endian encodings of Zcash and the big endian encodings of math/big.
+func reverseBytes(b []byte) []byte {
+	reversed := make([]byte, len(b))
+	for i := range b {
+		reversed[len(b)-1-i] = b[i]
+	}
+	return reversed
+}
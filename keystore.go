@@ -0,0 +1,239 @@
+package libzec
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+	"github.com/tyler-smith/go-bip32"
+	"github.com/tyler-smith/go-bip39"
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/crypto/scrypt"
+)
+
+// Default scrypt parameters used to derive the encryption key of a keystore.
+const (
+	KeystoreScryptN = 1 << 18
+	KeystoreScryptR = 8
+	KeystoreScryptP = 1
+)
+
+// Keystore is a mnemonic that is encrypted at rest using a key derived from a
+// passphrase with scrypt, and AES-256-GCM. The mnemonic is only held in memory
+// while the keystore is unlocked, and is zeroed when it is locked, together
+// with the master keys of the wallets created from it. The decrypted mnemonic
+// is never converted to a string, as strings cannot be zeroed.
+type Keystore struct {
+	mu        *sync.Mutex
+	path      string
+	encrypted keystoreJSON
+	mnemonic  []byte
+	keys      []*bip32.Key
+}
+
+type keystoreJSON struct {
+	Version    int              `json:"version"`
+	KDF        string           `json:"kdf"`
+	KDFParams  keystoreKDFParam `json:"kdfParams"`
+	Cipher     string           `json:"cipher"`
+	Nonce      string           `json:"nonce"`
+	Ciphertext string           `json:"ciphertext"`
+}
+
+type keystoreKDFParam struct {
+	N    int    `json:"n"`
+	R    int    `json:"r"`
+	P    int    `json:"p"`
+	Salt string `json:"salt"`
+}
+
+// NewKeystore encrypts the mnemonic with the passphrase. The keystore is
+// unlocked, and is not written to the path until Save is called.
+func NewKeystore(path, mnemonic, passphrase string) (*Keystore, error) {
+	if !bip39.IsMnemonicValid(mnemonic) {
+		return nil, fmt.Errorf("invalid mnemonic")
+	}
+	salt := make([]byte, 32)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	params := keystoreKDFParam{
+		N:    KeystoreScryptN,
+		R:    KeystoreScryptR,
+		P:    KeystoreScryptP,
+		Salt: hex.EncodeToString(salt),
+	}
+	aead, err := keystoreCipher(passphrase, params)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return &Keystore{
+		mu:   new(sync.Mutex),
+		path: path,
+		encrypted: keystoreJSON{
+			Version:    1,
+			KDF:        "scrypt",
+			KDFParams:  params,
+			Cipher:     "aes-256-gcm",
+			Nonce:      hex.EncodeToString(nonce),
+			Ciphertext: hex.EncodeToString(aead.Seal(nil, nonce, []byte(mnemonic), nil)),
+		},
+		mnemonic: []byte(mnemonic),
+	}, nil
+}
+
+// LoadKeystore loads the keystore at the given path. The keystore is locked.
+func LoadKeystore(path string) (*Keystore, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	encrypted := keystoreJSON{}
+	if err := json.Unmarshal(data, &encrypted); err != nil {
+		return nil, fmt.Errorf("cannot decode keystore %s: %v", path, err)
+	}
+	if encrypted.Version != 1 || encrypted.KDF != "scrypt" || encrypted.Cipher != "aes-256-gcm" {
+		return nil, fmt.Errorf("unsupported keystore %s: version %d kdf %s cipher %s", path, encrypted.Version, encrypted.KDF, encrypted.Cipher)
+	}
+	return &Keystore{
+		mu:        new(sync.Mutex),
+		path:      path,
+		encrypted: encrypted,
+	}, nil
+}
+
+// Save writes the encrypted keystore to its path.
+func (keystore *Keystore) Save() error {
+	keystore.mu.Lock()
+	defer keystore.mu.Unlock()
+	data, err := json.MarshalIndent(keystore.encrypted, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := keystore.path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, keystore.path)
+}
+
+// Unlock decrypts the mnemonic using the passphrase. ErrInvalidPassphrase is
+// returned if the passphrase is wrong.
+func (keystore *Keystore) Unlock(passphrase string) error {
+	keystore.mu.Lock()
+	defer keystore.mu.Unlock()
+	if keystore.mnemonic != nil {
+		return nil
+	}
+	aead, err := keystoreCipher(passphrase, keystore.encrypted.KDFParams)
+	if err != nil {
+		return err
+	}
+	nonce, err := hex.DecodeString(keystore.encrypted.Nonce)
+	if err != nil {
+		return err
+	}
+	ciphertext, err := hex.DecodeString(keystore.encrypted.Ciphertext)
+	if err != nil {
+		return err
+	}
+	if len(nonce) != aead.NonceSize() {
+		return fmt.Errorf("invalid keystore nonce length %d", len(nonce))
+	}
+	mnemonic, err := aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return ErrInvalidPassphrase
+	}
+	keystore.mnemonic = mnemonic
+	return nil
+}
+
+// Lock zeroes the mnemonic, and the master keys of the wallets created from
+// the keystore. These wallets return ErrKeystoreLocked once the keystore is
+// locked, even if it is unlocked again. Accounts that were derived before the
+// keystore was locked keep their keys.
+func (keystore *Keystore) Lock() {
+	keystore.mu.Lock()
+	defer keystore.mu.Unlock()
+	zero(keystore.mnemonic)
+	keystore.mnemonic = nil
+	for _, key := range keystore.keys {
+		zero(key.Key)
+		zero(key.ChainCode)
+	}
+	keystore.keys = nil
+}
+
+// IsLocked returns true if the keystore is locked.
+func (keystore *Keystore) IsLocked() bool {
+	keystore.mu.Lock()
+	defer keystore.mu.Unlock()
+	return keystore.mnemonic == nil
+}
+
+// Wallet returns a wallet for the mnemonic of the unlocked keystore, and the
+// given BIP-39 password. ErrKeystoreLocked is returned if the keystore is
+// locked.
+func (keystore *Keystore) Wallet(password string, client Client, logger logrus.FieldLogger) (Wallet, error) {
+	keystore.mu.Lock()
+	defer keystore.mu.Unlock()
+	if keystore.mnemonic == nil {
+		return nil, ErrKeystoreLocked
+	}
+	// This is the BIP-39 seed computed by bip39.NewSeed, without copying the
+	// mnemonic into a string.
+	seed := pbkdf2.Key(keystore.mnemonic, []byte("mnemonic"+password), 2048, 64, sha512.New)
+	defer zero(seed)
+	key, err := bip32.NewMasterKey(seed)
+	if err != nil {
+		return nil, err
+	}
+	keystore.keys = append(keystore.keys, key)
+	return &wallet{"", key, NewMemoryMetadataStore(), client, logger, keystore}, nil
+}
+
+// holds returns true if the master key of a wallet created from the keystore
+// has not been zeroed. It must be called with the mutex of the keystore held.
+func (keystore *Keystore) holds(key *bip32.Key) bool {
+	for _, held := range keystore.keys {
+		if held == key {
+			return true
+		}
+	}
+	return false
+}
+
+func keystoreCipher(passphrase string, params keystoreKDFParam) (cipher.AEAD, error) {
+	salt, err := hex.DecodeString(params.Salt)
+	if err != nil {
+		return nil, err
+	}
+	key, err := scrypt.Key([]byte(passphrase), salt, params.N, params.R, params.P, 32)
+	if err != nil {
+		return nil, err
+	}
+	defer zero(key)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func zero(data []byte) {
+	for i := range data {
+		data[i] = 0
+	}
+}
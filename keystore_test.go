@@ -0,0 +1,85 @@
+package libzec_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/renproject/libzec-go"
+	"github.com/sirupsen/logrus"
+)
+
+var _ = Describe("Keystores", func() {
+	const mnemonic = "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"
+
+	var dir string
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "libzec-keystore")
+		Expect(err).Should(BeNil())
+	})
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	address := func(wallet Wallet) string {
+		account, err := wallet.AccountAt(0, "")
+		Expect(err).Should(BeNil())
+		addr, err := account.Address()
+		Expect(err).Should(BeNil())
+		return addr.EncodeAddress()
+	}
+
+	It("should derive the accounts of the mnemonic", func() {
+		client := NewClient(newMockClientCore(&chaincfg.TestNet3Params, 1842420))
+		keystore, err := NewKeystore(filepath.Join(dir, "keystore.json"), mnemonic, "passphrase")
+		Expect(err).Should(BeNil())
+		Expect(keystore.Save()).Should(BeNil())
+
+		data, err := ioutil.ReadFile(filepath.Join(dir, "keystore.json"))
+		Expect(err).Should(BeNil())
+		Expect(strings.Contains(string(data), "abandon")).Should(BeFalse())
+
+		loaded, err := LoadKeystore(filepath.Join(dir, "keystore.json"))
+		Expect(err).Should(BeNil())
+		Expect(loaded.IsLocked()).Should(BeTrue())
+		Expect(loaded.Unlock("wrong")).Should(Equal(ErrInvalidPassphrase))
+		Expect(loaded.Unlock("passphrase")).Should(BeNil())
+		wallet, err := loaded.Wallet("", client, logrus.StandardLogger())
+		Expect(err).Should(BeNil())
+		Expect(address(wallet)).Should(Equal(address(NewWallet(mnemonic, client, logrus.StandardLogger()))))
+	})
+
+	It("should not derive keys from the wallets of a locked keystore", func() {
+		client := NewClient(newMockClientCore(&chaincfg.TestNet3Params, 1842420))
+		keystore, err := NewKeystore(filepath.Join(dir, "keystore.json"), mnemonic, "passphrase")
+		Expect(err).Should(BeNil())
+		wallet, err := keystore.Wallet("", client, logrus.StandardLogger())
+		Expect(err).Should(BeNil())
+		addr := address(wallet)
+
+		keystore.Lock()
+		Expect(keystore.IsLocked()).Should(BeTrue())
+		_, err = wallet.AccountAt(0, "")
+		Expect(err).Should(Equal(ErrKeystoreLocked))
+		_, err = wallet.HDAccountAt(0, "")
+		Expect(err).Should(Equal(ErrKeystoreLocked))
+		_, err = wallet.ExtendedPublicKey(ZCashDerivationPath(0, ExternalChain, 0), "")
+		Expect(err).Should(Equal(ErrKeystoreLocked))
+		_, err = keystore.Wallet("", client, logrus.StandardLogger())
+		Expect(err).Should(Equal(ErrKeystoreLocked))
+
+		// The master key of the wallet has been zeroed, so the wallet stays
+		// unusable once the keystore is unlocked again.
+		Expect(keystore.Unlock("passphrase")).Should(BeNil())
+		_, err = wallet.AccountAt(0, "")
+		Expect(err).Should(Equal(ErrKeystoreLocked))
+		wallet, err = keystore.Wallet("", client, logrus.StandardLogger())
+		Expect(err).Should(BeNil())
+		Expect(address(wallet)).Should(Equal(addr))
+	})
+})
@@ -0,0 +1,130 @@
+package libzec
+
+import (
+	"context"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+
+	"github.com/btcsuite/btcd/btcec"
+)
+
+var (
+	oidPublicKeyECDSA = asn1.ObjectIdentifier{1, 2, 840, 10045, 2, 1}
+	oidCurveSecp256k1 = asn1.ObjectIdentifier{1, 3, 132, 0, 10}
+)
+
+// A KMSKey is an asymmetric secp256k1 key held by a key management service.
+// NewAWSKMSKey returns an AWS KMS key with the ECC_SECG_P256K1 key spec, and
+// NewGCPKMSKey returns a Google Cloud KMS key with the EC_SIGN_SECP256K1_SHA256
+// algorithm. Other services can be used by implementing the interface. The
+// digest must be signed as is, without hashing it again.
+type KMSKey interface {
+	// Sign signs the digest, and returns the DER encoded signature.
+	Sign(ctx context.Context, digest []byte) ([]byte, error)
+
+	// PublicKey returns the DER or PEM encoded SubjectPublicKeyInfo of the
+	// key.
+	PublicKey(ctx context.Context) ([]byte, error)
+}
+
+type kmsSigner struct {
+	key    KMSKey
+	pubKey *btcec.PublicKey
+}
+
+// NewKMSSigner returns a signer that signs using a key held by a key
+// management service, so that the private key never leaves the service. The
+// public key is fetched once, when the signer is created.
+func NewKMSSigner(ctx context.Context, key KMSKey) (Signer, error) {
+	spki, err := key.PublicKey(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("cannot get the public key from the kms: %v", err)
+	}
+	pubKey, err := parseKMSPublicKey(spki)
+	if err != nil {
+		return nil, err
+	}
+	return &kmsSigner{key, pubKey}, nil
+}
+
+func (signer *kmsSigner) Sign(ctx context.Context, hashes [][]byte) ([]*btcec.Signature, error) {
+	sigs := make([]*btcec.Signature, len(hashes))
+	for i, hash := range hashes {
+		der, err := signer.key.Sign(ctx, hash)
+		if err != nil {
+			return nil, fmt.Errorf("cannot sign hash %d with the kms: %v", i, err)
+		}
+		sig, err := parseKMSSignature(der)
+		if err != nil {
+			return nil, err
+		}
+		if !sig.Verify(hash, signer.pubKey) {
+			return nil, fmt.Errorf("kms returned an invalid signature for hash %d", i)
+		}
+		sigs[i] = sig
+	}
+	return sigs, nil
+}
+
+func (signer *kmsSigner) PublicKey() *btcec.PublicKey {
+	return signer.pubKey
+}
+
+// parseKMSSignature converts a DER encoded signature into its (r, s) values.
+// Key management services do not normalize s, so it is normalized as ZCash
+// only accepts low-S signatures.
+func parseKMSSignature(der []byte) (*btcec.Signature, error) {
+	sig := struct {
+		R, S *big.Int
+	}{}
+	rest, err := asn1.Unmarshal(der, &sig)
+	if err != nil {
+		return nil, fmt.Errorf("cannot decode kms signature: %v", err)
+	}
+	if len(rest) != 0 {
+		return nil, fmt.Errorf("cannot decode kms signature: %d trailing bytes", len(rest))
+	}
+	if sig.R == nil || sig.S == nil || sig.R.Sign() <= 0 || sig.S.Sign() <= 0 {
+		return nil, fmt.Errorf("cannot decode kms signature: invalid r or s value")
+	}
+	return newLowSSignature(sig.R, sig.S), nil
+}
+
+// newLowSSignature returns the signature (r, s), replacing s by N - s if it is
+// in the upper half of the curve order.
+func newLowSSignature(r, s *big.Int) *btcec.Signature {
+	n := btcec.S256().N
+	if s.Cmp(new(big.Int).Rsh(n, 1)) > 0 {
+		s = new(big.Int).Sub(n, s)
+	}
+	return &btcec.Signature{R: r, S: s}
+}
+
+// parseKMSPublicKey parses a DER or PEM encoded SubjectPublicKeyInfo that
+// contains a secp256k1 public key.
+func parseKMSPublicKey(spki []byte) (*btcec.PublicKey, error) {
+	if block, _ := pem.Decode(spki); block != nil {
+		spki = block.Bytes
+	}
+	info := struct {
+		Algorithm pkix.AlgorithmIdentifier
+		PublicKey asn1.BitString
+	}{}
+	if _, err := asn1.Unmarshal(spki, &info); err != nil {
+		return nil, fmt.Errorf("cannot decode kms public key: %v", err)
+	}
+	if !info.Algorithm.Algorithm.Equal(oidPublicKeyECDSA) {
+		return nil, fmt.Errorf("unsupported kms key algorithm %v", info.Algorithm.Algorithm)
+	}
+	curve := asn1.ObjectIdentifier{}
+	if _, err := asn1.Unmarshal(info.Algorithm.Parameters.FullBytes, &curve); err != nil {
+		return nil, fmt.Errorf("cannot decode kms key curve: %v", err)
+	}
+	if !curve.Equal(oidCurveSecp256k1) {
+		return nil, fmt.Errorf("unsupported kms key curve %v", curve)
+	}
+	return btcec.ParsePubKey(info.PublicKey.Bytes, btcec.S256())
+}
@@ -0,0 +1,136 @@
+package libzec_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/btcsuite/btcd/btcec"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/renproject/libzec-go"
+)
+
+var _ = Describe("KMS keys", func() {
+	// spki returns the DER encoded SubjectPublicKeyInfo of the key.
+	spki := func(privKey *btcec.PrivateKey) []byte {
+		curve, err := asn1.Marshal(asn1.ObjectIdentifier{1, 3, 132, 0, 10})
+		Expect(err).Should(BeNil())
+		pubKey := privKey.PubKey().SerializeUncompressed()
+		der, err := asn1.Marshal(struct {
+			Algorithm pkix.AlgorithmIdentifier
+			PublicKey asn1.BitString
+		}{
+			pkix.AlgorithmIdentifier{
+				Algorithm:  asn1.ObjectIdentifier{1, 2, 840, 10045, 2, 1},
+				Parameters: asn1.RawValue{FullBytes: curve},
+			},
+			asn1.BitString{Bytes: pubKey, BitLength: 8 * len(pubKey)},
+		})
+		Expect(err).Should(BeNil())
+		return der
+	}
+
+	// highS signs the digest and returns the DER encoded signature with a high
+	// S value, as key management services do not normalize it.
+	highS := func(privKey *btcec.PrivateKey, digest []byte) []byte {
+		sig, err := privKey.Sign(digest)
+		Expect(err).Should(BeNil())
+		der, err := asn1.Marshal(struct {
+			R, S *big.Int
+		}{sig.R, new(big.Int).Sub(btcec.S256().N, sig.S)})
+		Expect(err).Should(BeNil())
+		return der
+	}
+
+	expectSigner := func(key KMSKey, privKey *btcec.PrivateKey) {
+		signer, err := NewKMSSigner(context.Background(), key)
+		Expect(err).Should(BeNil())
+		Expect(signer.PublicKey().IsEqual(privKey.PubKey())).Should(BeTrue())
+
+		digest := sha256.Sum256([]byte("libzec"))
+		sigs, err := signer.Sign(context.Background(), [][]byte{digest[:]})
+		Expect(err).Should(BeNil())
+		Expect(sigs).Should(HaveLen(1))
+		Expect(sigs[0].Verify(digest[:], privKey.PubKey())).Should(BeTrue())
+		Expect(sigs[0].S.Cmp(new(big.Int).Rsh(btcec.S256().N, 1))).Should(BeNumerically("<=", 0))
+	}
+
+	It("should sign with an aws kms key", func() {
+		privKey, err := btcec.NewPrivateKey(btcec.S256())
+		Expect(err).Should(BeNil())
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			auth := r.Header.Get("Authorization")
+			if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=AKID/") ||
+				!strings.Contains(auth, "/us-east-1/kms/aws4_request, SignedHeaders=content-type;host;x-amz-date;x-amz-security-token;x-amz-target, Signature=") ||
+				r.Header.Get("X-Amz-Security-Token") != "SESSION" {
+				w.WriteHeader(http.StatusForbidden)
+				json.NewEncoder(w).Encode(map[string]string{"__type": "InvalidSignatureException"})
+				return
+			}
+			req := struct {
+				KeyId            string
+				Message          []byte
+				MessageType      string
+				SigningAlgorithm string
+			}{}
+			Expect(json.NewDecoder(r.Body).Decode(&req)).Should(BeNil())
+			Expect(req.KeyId).Should(Equal("alias/libzec"))
+			switch r.Header.Get("X-Amz-Target") {
+			case "TrentService.GetPublicKey":
+				json.NewEncoder(w).Encode(map[string]interface{}{"KeySpec": "ECC_SECG_P256K1", "PublicKey": spki(privKey)})
+			case "TrentService.Sign":
+				Expect(req.MessageType).Should(Equal("DIGEST"))
+				Expect(req.SigningAlgorithm).Should(Equal("ECDSA_SHA_256"))
+				json.NewEncoder(w).Encode(map[string]interface{}{"Signature": highS(privKey, req.Message)})
+			default:
+				w.WriteHeader(http.StatusBadRequest)
+			}
+		}))
+		defer server.Close()
+
+		expectSigner(NewAWSKMSKeyWithEndpoint(server.Client(), server.URL, "us-east-1", "alias/libzec", AWSCredentials{"AKID", "SECRET", "SESSION"}), privKey)
+
+		_, err = NewKMSSigner(context.Background(), NewAWSKMSKeyWithEndpoint(server.Client(), server.URL, "us-east-1", "alias/libzec", AWSCredentials{"AKID", "SECRET", ""}))
+		Expect(err).ShouldNot(BeNil())
+	})
+
+	It("should sign with a google cloud kms key", func() {
+		privKey, err := btcec.NewPrivateKey(btcec.S256())
+		Expect(err).Should(BeNil())
+		name := "projects/p/locations/global/keyRings/r/cryptoKeys/k/cryptoKeyVersions/1"
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch {
+			case r.Method == http.MethodGet && r.URL.Path == "/v1/"+name+"/publicKey":
+				json.NewEncoder(w).Encode(map[string]string{
+					"algorithm": "EC_SIGN_SECP256K1_SHA256",
+					"pem":       string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: spki(privKey)})),
+				})
+			case r.Method == http.MethodPost && r.URL.Path == "/v1/"+name+":asymmetricSign":
+				req := struct {
+					Digest struct {
+						SHA256 []byte `json:"sha256"`
+					} `json:"digest"`
+				}{}
+				Expect(json.NewDecoder(r.Body).Decode(&req)).Should(BeNil())
+				json.NewEncoder(w).Encode(map[string]interface{}{"signature": highS(privKey, req.Digest.SHA256)})
+			default:
+				w.WriteHeader(http.StatusNotFound)
+				json.NewEncoder(w).Encode(map[string]interface{}{"error": map[string]string{"status": "NOT_FOUND"}})
+			}
+		}))
+		defer server.Close()
+
+		expectSigner(NewGCPKMSKeyWithEndpoint(server.Client(), server.URL, name), privKey)
+
+		_, err = NewKMSSigner(context.Background(), NewGCPKMSKeyWithEndpoint(server.Client(), server.URL, "projects/p/unknown"))
+		Expect(err).ShouldNot(BeNil())
+	})
+})
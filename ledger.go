@@ -0,0 +1,182 @@
+package libzec
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/karalabe/hid"
+)
+
+// LedgerVendorID is the USB vendor id of Ledger devices.
+const LedgerVendorID = 0x2c97
+
+// ledgerUsagePage is the HID usage page of the interface of Ledger devices
+// that carries APDUs, reported on macOS and Windows.
+const ledgerUsagePage = 0xffa0
+
+const (
+	ledgerCLA                = 0xe0
+	ledgerInsGetWalletPubKey = 0x40
+	ledgerStatusOK           = 0x9000
+)
+
+// ledgerPacketSize is the size of the HID reports exchanged with the device.
+const ledgerPacketSize = 64
+
+// ledgerChannel and ledgerTagAPDU prefix every HID report of an APDU
+// exchange.
+const (
+	ledgerChannel = 0x0101
+	ledgerTagAPDU = 0x05
+)
+
+// errLedgerHashSigning is returned when a Ledger device is asked to sign
+// signature hashes: the Ledger apps only sign transactions that are streamed
+// to, and reviewed on, the device.
+var errLedgerHashSigning = fmt.Errorf("ledger devices do not sign signature hashes, only transactions reviewed on the device")
+
+type ledgerDevice struct {
+	conn io.ReadWriter
+}
+
+// NewLedgerDevice returns the Ledger device connected over the HID connection.
+// Public keys are derived on the device using the GET WALLET PUBLIC KEY
+// command of the Bitcoin family of Ledger apps, which includes the ZCash app.
+// As Ledger apps do not sign signature hashes, the device can back watch-only
+// accounts, but Sign always fails.
+func NewLedgerDevice(conn io.ReadWriter) HardwareDevice {
+	return &ledgerDevice{conn}
+}
+
+// OpenLedgerDevice opens the first Ledger device connected over USB. The
+// returned closer closes the HID connection. Opening a device requires cgo.
+func OpenLedgerDevice() (HardwareDevice, io.Closer, error) {
+	if !hid.Supported() {
+		return nil, nil, hid.ErrUnsupportedPlatform
+	}
+	for _, info := range hid.Enumerate(LedgerVendorID, 0) {
+		// Ledger devices expose several interfaces, and only the first one,
+		// or the one with the vendor usage page, carries APDUs.
+		if info.UsagePage != ledgerUsagePage && info.Interface != 0 {
+			continue
+		}
+		device, err := info.Open()
+		if err != nil {
+			return nil, nil, fmt.Errorf("cannot open ledger device %s: %v", info.Path, err)
+		}
+		return NewLedgerDevice(device), device, nil
+	}
+	return nil, nil, fmt.Errorf("no ledger device found")
+}
+
+func (device *ledgerDevice) PublicKey(ctx context.Context, derivationPath []uint32) (*btcec.PublicKey, error) {
+	if len(derivationPath) > 10 {
+		return nil, fmt.Errorf("derivation path of %d indices is too long for the device", len(derivationPath))
+	}
+	data := make([]byte, 1+4*len(derivationPath))
+	data[0] = byte(len(derivationPath))
+	for i, index := range derivationPath {
+		binary.BigEndian.PutUint32(data[1+4*i:], index)
+	}
+	res, err := device.exchange(ledgerInsGetWalletPubKey, 0, 0, data)
+	if err != nil {
+		return nil, err
+	}
+
+	// The response is the length prefixed public key, followed by the length
+	// prefixed address and the chain code, which are not needed.
+	if len(res) == 0 || len(res) < 1+int(res[0]) {
+		return nil, fmt.Errorf("invalid public key response of %d bytes", len(res))
+	}
+	return btcec.ParsePubKey(res[1:1+int(res[0])], btcec.S256())
+}
+
+func (device *ledgerDevice) Sign(ctx context.Context, derivationPath []uint32, hashes [][]byte) ([]*btcec.Signature, error) {
+	return nil, errLedgerHashSigning
+}
+
+// exchange sends the APDU to the device, and returns the data of the response
+// once its status word is checked.
+func (device *ledgerDevice) exchange(ins, p1, p2 byte, data []byte) ([]byte, error) {
+	if len(data) > 255 {
+		return nil, fmt.Errorf("apdu data of %d bytes is too long", len(data))
+	}
+	apdu := append([]byte{ledgerCLA, ins, p1, p2, byte(len(data))}, data...)
+	if err := device.write(apdu); err != nil {
+		return nil, fmt.Errorf("cannot write to the ledger device: %v", err)
+	}
+	res, err := device.read()
+	if err != nil {
+		return nil, fmt.Errorf("cannot read from the ledger device: %v", err)
+	}
+	if len(res) < 2 {
+		return nil, fmt.Errorf("invalid ledger response of %d bytes", len(res))
+	}
+	if status := binary.BigEndian.Uint16(res[len(res)-2:]); status != ledgerStatusOK {
+		return nil, fmt.Errorf("ledger device returned status %#04x", status)
+	}
+	return res[:len(res)-2], nil
+}
+
+// write splits the APDU, prefixed by its length, into HID reports. Every
+// report starts with the channel, the tag and the sequence number of the
+// report, and the last one is padded with zeros.
+func (device *ledgerDevice) write(apdu []byte) error {
+	payload := make([]byte, 2, 2+len(apdu))
+	binary.BigEndian.PutUint16(payload, uint16(len(apdu)))
+	payload = append(payload, apdu...)
+	for seq := 0; len(payload) > 0; seq++ {
+		packet := make([]byte, ledgerPacketSize)
+		ledgerHeader(packet, uint16(seq))
+		n := copy(packet[5:], payload)
+		payload = payload[n:]
+		if _, err := device.conn.Write(packet); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// read reassembles the response from the HID reports sent by the device.
+func (device *ledgerDevice) read() ([]byte, error) {
+	var res []byte
+	length := -1
+	for seq := 0; length < 0 || len(res) < length; seq++ {
+		packet := make([]byte, ledgerPacketSize)
+		n, err := io.ReadFull(device.conn, packet)
+		if err != nil {
+			return nil, err
+		}
+		if n < 7 {
+			return nil, fmt.Errorf("invalid report of %d bytes", n)
+		}
+		header := make([]byte, 5)
+		ledgerHeader(header, uint16(seq))
+		for i := range header {
+			if packet[i] != header[i] {
+				return nil, fmt.Errorf("invalid report header %x", packet[:5])
+			}
+		}
+		payload := packet[5:]
+		if seq == 0 {
+			length = int(binary.BigEndian.Uint16(payload))
+			payload = payload[2:]
+		}
+		if remaining := length - len(res); len(payload) > remaining {
+			payload = payload[:remaining]
+		}
+		res = append(res, payload...)
+	}
+	return res, nil
+}
+
+// ledgerHeader writes the header of the HID report with the given sequence
+// number.
+func ledgerHeader(packet []byte, seq uint16) {
+	binary.BigEndian.PutUint16(packet, ledgerChannel)
+	packet[2] = ledgerTagAPDU
+	binary.BigEndian.PutUint16(packet[3:], seq)
+}
@@ -0,0 +1,132 @@
+package libzec
+
+import (
+	"bytes"
+	"fmt"
+	"unicode/utf8"
+)
+
+// MemoSize is the size of the memo field of shielded outputs.
+const MemoSize = 512
+
+// MemoType is the interpretation of a memo, given by its first byte as
+// specified in ZIP-302.
+type MemoType int
+
+const (
+	// MemoText is a UTF-8 text, padded with zeros.
+	MemoText MemoType = iota
+
+	// MemoEmpty indicates that no memo was provided.
+	MemoEmpty
+
+	// MemoArbitrary is arbitrary data, that is not interpreted.
+	MemoArbitrary
+
+	// MemoReserved is a memo whose interpretation is reserved for future
+	// use, or for an agreed upon protocol.
+	MemoReserved
+)
+
+// First bytes of the memos that are not texts.
+const (
+	memoMaxTextByte  = 0xF4
+	memoEmptyByte    = 0xF6
+	memoArbitraryTag = 0xFF
+)
+
+// Memo is the memo field of a shielded output, encoded as specified in
+// ZIP-302.
+type Memo [MemoSize]byte
+
+// NewTextMemo encodes the text, which must be valid UTF-8 of at most
+// MemoSize bytes. The empty text is encoded as the empty memo.
+func NewTextMemo(text string) (Memo, error) {
+	memo := Memo{}
+	if text == "" {
+		return EmptyMemo(), nil
+	}
+	if len(text) > MemoSize {
+		return memo, fmt.Errorf("memo is too long: got: %d required: %d", len(text), MemoSize)
+	}
+	if !utf8.ValidString(text) {
+		return memo, fmt.Errorf("memo is not valid utf-8")
+	}
+	copy(memo[:], text)
+	return memo, nil
+}
+
+// NewArbitraryMemo encodes the data, of at most MemoSize-1 bytes, as a memo
+// that is not interpreted by wallets.
+func NewArbitraryMemo(data []byte) (Memo, error) {
+	memo := Memo{memoArbitraryTag}
+	if len(data) > MemoSize-1 {
+		return memo, fmt.Errorf("memo data is too long: got: %d required: %d", len(data), MemoSize-1)
+	}
+	copy(memo[1:], data)
+	return memo, nil
+}
+
+// EmptyMemo returns the memo that indicates that no memo was provided.
+func EmptyMemo() Memo {
+	return Memo{memoEmptyByte}
+}
+
+// DecodeMemo decodes the memo field of a shielded output.
+func DecodeMemo(data []byte) (Memo, error) {
+	memo := Memo{}
+	if len(data) != MemoSize {
+		return memo, fmt.Errorf("invalid memo size: got: %d required: %d", len(data), MemoSize)
+	}
+	copy(memo[:], data)
+	return memo, nil
+}
+
+// Type returns the interpretation of the memo.
+func (memo Memo) Type() MemoType {
+	switch {
+	case memo[0] <= memoMaxTextByte:
+		return MemoText
+	case memo[0] == memoEmptyByte && isZero(memo[1:]):
+		return MemoEmpty
+	case memo[0] == memoArbitraryTag:
+		return MemoArbitrary
+	default:
+		return MemoReserved
+	}
+}
+
+// Text returns the text of a text memo, without its padding. The text of an
+// empty memo is empty.
+func (memo Memo) Text() (string, error) {
+	switch memo.Type() {
+	case MemoEmpty:
+		return "", nil
+	case MemoText:
+		text := bytes.TrimRight(memo[:], "\x00")
+		if !utf8.Valid(text) {
+			return "", fmt.Errorf("memo is not valid utf-8")
+		}
+		return string(text), nil
+	default:
+		return "", fmt.Errorf("memo is not a text")
+	}
+}
+
+// Data returns the data of an arbitrary memo, including its padding as the
+// length of the data is not encoded.
+func (memo Memo) Data() ([]byte, error) {
+	if memo.Type() != MemoArbitrary {
+		return nil, fmt.Errorf("memo is not arbitrary data")
+	}
+	return append([]byte{}, memo[1:]...), nil
+}
+
+func isZero(data []byte) bool {
+	for _, b := range data {
+		if b != 0 {
+			return false
+		}
+	}
+	return true
+}
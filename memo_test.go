@@ -0,0 +1,49 @@
+package libzec_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/renproject/libzec-go"
+)
+
+var _ = Describe("Memos", func() {
+	It("should encode and decode text memos", func() {
+		memo, err := NewTextMemo("thanks for the coffee ☕")
+		Expect(err).Should(BeNil())
+		Expect(memo.Type()).Should(Equal(MemoText))
+
+		decoded, err := DecodeMemo(memo[:])
+		Expect(err).Should(BeNil())
+		text, err := decoded.Text()
+		Expect(err).Should(BeNil())
+		Expect(text).Should(Equal("thanks for the coffee ☕"))
+	})
+
+	It("should encode the empty text as the empty memo", func() {
+		memo, err := NewTextMemo("")
+		Expect(err).Should(BeNil())
+		Expect(memo).Should(Equal(EmptyMemo()))
+		Expect(memo.Type()).Should(Equal(MemoEmpty))
+		Expect(memo[0]).Should(Equal(byte(0xF6)))
+	})
+
+	It("should encode arbitrary data", func() {
+		memo, err := NewArbitraryMemo([]byte{1, 2, 3})
+		Expect(err).Should(BeNil())
+		Expect(memo.Type()).Should(Equal(MemoArbitrary))
+		data, err := memo.Data()
+		Expect(err).Should(BeNil())
+		Expect(data[:3]).Should(Equal([]byte{1, 2, 3}))
+		_, err = memo.Text()
+		Expect(err).ShouldNot(BeNil())
+	})
+
+	It("should reject memos that do not fit", func() {
+		_, err := NewTextMemo(string(make([]byte, MemoSize+1)))
+		Expect(err).ShouldNot(BeNil())
+		_, err = NewArbitraryMemo(make([]byte, MemoSize))
+		Expect(err).ShouldNot(BeNil())
+		_, err = DecodeMemo(make([]byte, MemoSize-1))
+		Expect(err).ShouldNot(BeNil())
+	})
+})
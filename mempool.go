@@ -0,0 +1,126 @@
+package libzec
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/btcsuite/btcd/chaincfg"
+)
+
+// MinRelayFee is the minimum fee, in ZAT, of the transactions that zcashd
+// relays by default. It is the default fee of ZIP-313.
+const MinRelayFee = int64(1000)
+
+// RelayRules are the standardness rules that a transaction has to follow to be
+// relayed by the zcashd nodes of a network. A zero dust threshold, or maximum
+// size, is not enforced.
+type RelayRules struct {
+	MinRelayFee int64
+	Dust        int64
+	MaxTxSize   int
+}
+
+// relayRules are the default relay rules of zcashd. Regtest nodes accept
+// non-standard transactions, such as the ones with dust outputs, but still
+// enforce the minimum relay fee.
+var relayRules = map[Network]RelayRules{
+	Mainnet: {MinRelayFee, ZCashDust, MaxStandardTxSize},
+	Testnet: {MinRelayFee, ZCashDust, MaxStandardTxSize},
+	Regtest: {MinRelayFee, 0, 0},
+}
+
+// StandardRelayRules returns the default relay rules of the zcashd nodes of
+// the network.
+func StandardRelayRules(network Network) RelayRules {
+	rules, ok := relayRules[network]
+	if !ok {
+		return relayRules[Mainnet]
+	}
+	return rules
+}
+
+// relayRulesOf returns the relay rules of the network of the parameters, or
+// the ones of mainnet if the network is unknown.
+func relayRulesOf(params *chaincfg.Params) RelayRules {
+	network, err := NetworkOf(params)
+	if err != nil {
+		return relayRules[Mainnet]
+	}
+	return StandardRelayRules(network)
+}
+
+// expiringSoonThreshold is the number of blocks before its expiry height
+// after which zcashd no longer accepts a transaction into its mempool.
+const expiringSoonThreshold = 3
+
+// TestMempoolAccept checks that the signed transaction would be accepted into
+// the mempool, without broadcasting it. zcashd has no RPC to test the
+// acceptance of a transaction, so the checks are done locally, and only cover
+// transparent Sapling (v4) transactions: ErrNotSupported is returned for
+// other versions, and for transactions with shielded components, whose
+// proofs and signatures cannot be verified. The inputs are fetched using
+// GetUTXO, so the check is only exhaustive when the backend can fetch any
+// utxo, such as a zcashd node. The script of every input, the outputs, the
+// fee and the expiry height of the transaction are checked. Rejections are
+// returned as BroadcastErrors, classified in the same way as the errors
+// returned when publishing the transaction.
+func (client *client) TestMempoolAccept(stx []byte) error {
+	decoded, err := DecodeTransaction(stx)
+	if err != nil {
+		return err
+	}
+	shielded := decoded.Shielded
+	if decoded.Version != versionSapling || len(shielded.Spends) != 0 || len(shielded.Outputs) != 0 || len(shielded.JoinSplits) != 0 {
+		return ErrNotSupported
+	}
+	msgTx, err := decoded.MsgTx()
+	if err != nil {
+		return err
+	}
+
+	height, err := client.BlockHeight()
+	if err != nil {
+		return fmt.Errorf("cannot get the block height to select the consensus branch: %v", err)
+	}
+	branchID, err := ConsensusBranchID(client.NetworkParams(), height)
+	if err != nil {
+		return err
+	}
+	if msgTx.ExpiryHeight != 0 && int64(msgTx.ExpiryHeight) <= height+1+expiringSoonThreshold {
+		return NewErrZCashSubmitTx(fmt.Sprintf("tx-expiring-soon: expiry height %d at height %d", msgTx.ExpiryHeight, height))
+	}
+
+	rules := relayRulesOf(client.NetworkParams())
+	var in, out int64
+	for i, txIn := range msgTx.TxIn {
+		utxo, err := client.GetUTXO(txIn.PreviousOutPoint.Hash.String(), txIn.PreviousOutPoint.Index)
+		if err != nil {
+			if errors.Is(err, ErrUTXOSpent) {
+				return NewErrZCashSubmitTx(fmt.Sprintf("bad-txns-inputs-missingorspent: input %d", i))
+			}
+			return fmt.Errorf("cannot fetch input %d: %v", i, err)
+		}
+		scriptPubKey, err := hex.DecodeString(utxo.ScriptPubKey)
+		if err != nil {
+			return err
+		}
+		if err := verifyScript(msgTx, i, scriptPubKey, utxo.Amount, branchSigHashKey(branchID)); err != nil {
+			return NewErrZCashSubmitTx(fmt.Sprintf("mandatory-script-verify-flag-failed: input %d: %v", i, err))
+		}
+		in += utxo.Amount
+	}
+	for i, txOut := range msgTx.TxOut {
+		if txOut.Value < rules.Dust {
+			return NewErrZCashSubmitTx(fmt.Sprintf("dust: output %d has %d", i, txOut.Value))
+		}
+		out += txOut.Value
+	}
+	if in < out {
+		return NewErrZCashSubmitTx(fmt.Sprintf("bad-txns-in-belowout: got: %d required: %d", in, out))
+	}
+	if in-out < rules.MinRelayFee {
+		return NewErrZCashSubmitTx(fmt.Sprintf("min relay fee not met: got: %d required: %d", in-out, rules.MinRelayFee))
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package libzec_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/renproject/libzec-go"
+)
+
+var _ = Describe("Mempool acceptance", func() {
+	// transfer returns a transparent v4 transfer, signed but not accepted by
+	// the mock client core.
+	transfer := func(core *mockClientCore) []byte {
+		account, _, _ := newMockAccount(core, 1000000)
+		_, _, to := newMockAccount(core, 0)
+		_, err := account.Transfer(context.Background(), to.EncodeAddress(), 100000, Standard, false)
+		Expect(err).Should(BeNil())
+		return core.published[0]
+	}
+
+	It("should accept transparent v4 transactions", func() {
+		core := newMockClientCore(&chaincfg.TestNet3Params, 1842420)
+		stx := transfer(core)
+		Expect(NewClient(core).TestMempoolAccept(stx)).Should(BeNil())
+
+		for _, utxos := range core.utxos {
+			for _, utxo := range utxos {
+				core.spend(utxo)
+			}
+		}
+		Expect(NewClient(core).TestMempoolAccept(stx)).ShouldNot(BeNil())
+	})
+
+	It("should not support transactions with shielded components", func() {
+		core := newMockClientCore(&chaincfg.TestNet3Params, 1842420)
+		stx := transfer(core)
+
+		// Replace the empty shielded components of the transaction with a
+		// Sapling output.
+		shielded := new(bytes.Buffer)
+		shielded.Write(stx[:len(stx)-3])
+		shielded.Write([]byte{0, 1})
+		shielded.Write(make([]byte, 948))
+		shielded.WriteByte(0)
+		shielded.Write(make([]byte, 64))
+		decoded, err := DecodeTransaction(shielded.Bytes())
+		Expect(err).Should(BeNil())
+		Expect(decoded.Shielded.Outputs).Should(HaveLen(1))
+		Expect(NewClient(core).TestMempoolAccept(shielded.Bytes())).Should(Equal(ErrNotSupported))
+	})
+
+	It("should not support v5 transactions", func() {
+		core := newMockClientCore(&chaincfg.TestNet3Params, 1842420)
+		v5 := new(bytes.Buffer)
+		binary.Write(v5, binary.LittleEndian, uint32(5|1<<31))
+		binary.Write(v5, binary.LittleEndian, uint32(0x26A7270A))
+		binary.Write(v5, binary.LittleEndian, uint32(0xC2D6D0B4))
+		binary.Write(v5, binary.LittleEndian, uint32(0))
+		binary.Write(v5, binary.LittleEndian, uint32(1842440))
+		v5.Write([]byte{0, 0, 0, 0, 0})
+		decoded, err := DecodeTransaction(v5.Bytes())
+		Expect(err).Should(BeNil())
+		Expect(decoded.Version).Should(Equal(int32(5)))
+		Expect(NewClient(core).TestMempoolAccept(v5.Bytes())).Should(Equal(ErrNotSupported))
+	})
+})
@@ -0,0 +1,106 @@
+package libzec
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+)
+
+// messageMagic is prepended to signed messages by zcashd, so that a signed
+// message cannot be a valid transaction signature.
+const messageMagic = "Zcash Signed Message:\n"
+
+// MessageHash returns the hash of the message that is signed by the
+// signmessage RPC of zcashd.
+func MessageHash(message string) []byte {
+	buf := new(bytes.Buffer)
+	wire.WriteVarBytes(buf, 0, []byte(messageMagic))
+	wire.WriteVarBytes(buf, 0, []byte(message))
+	return chainhash.DoubleHashB(buf.Bytes())
+}
+
+// SignMessage signs the message with the signer, in the base64 encoded
+// compact format of the signmessage RPC of zcashd. The signature proves the
+// control of the P2PKH address of the public key, serialized in its compressed
+// form or not.
+func SignMessage(ctx context.Context, signer Signer, message string, compressed bool) (string, error) {
+	hash := MessageHash(message)
+	sigs, err := signer.Sign(ctx, [][]byte{hash})
+	if err != nil {
+		return "", err
+	}
+	if len(sigs) != 1 {
+		return "", fmt.Errorf("invalid number of signatures: got: %d required: %d", len(sigs), 1)
+	}
+
+	// The signer does not return the recovery id of the signature, so every
+	// recovery id is tried until the public key of the signer is recovered.
+	compact := make([]byte, 65)
+	rBytes, sBytes := sigs[0].R.Bytes(), sigs[0].S.Bytes()
+	copy(compact[33-len(rBytes):33], rBytes)
+	copy(compact[65-len(sBytes):], sBytes)
+	for recoveryID := byte(0); recoveryID < 4; recoveryID++ {
+		compact[0] = 27 + recoveryID
+		if compressed {
+			compact[0] += 4
+		}
+		pubKey, _, err := btcec.RecoverCompact(btcec.S256(), compact, hash)
+		if err == nil && pubKey.IsEqual(signer.PublicKey()) {
+			return base64.StdEncoding.EncodeToString(compact), nil
+		}
+	}
+	return "", NewErrInvalidSignature(0, "cannot recover the public key of the signer")
+}
+
+// VerifyMessage returns whether the signature of the message, in the format of
+// the signmessage RPC of zcashd, was signed by the key of the P2PKH or TEX address.
+// An error is returned if the address or the signature cannot be decoded.
+func VerifyMessage(address, signature, message string, params *chaincfg.Params) (bool, error) {
+	addr, err := DecodeAddress(address, params)
+	if err != nil {
+		return false, err
+	}
+	info, err := InspectAddress(addr.EncodeAddress())
+	if err != nil {
+		return false, err
+	}
+	if info.Type != AddressP2PKH && info.Type != AddressTEX {
+		return false, fmt.Errorf("cannot verify a message signed by a %v address", info.Type)
+	}
+
+	compact, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return false, fmt.Errorf("invalid signature encoding: %v", err)
+	}
+	pubKey, compressed, err := btcec.RecoverCompact(btcec.S256(), compact, MessageHash(message))
+	if err != nil {
+		// A signature from which no public key can be recovered is a valid
+		// encoding of a signature by another key.
+		return false, nil
+	}
+	serialized := pubKey.SerializeUncompressed()
+	if compressed {
+		serialized = pubKey.SerializeCompressed()
+	}
+	return bytes.Equal(btcutil.Hash160(serialized), info.Hash160), nil
+}
+
+// SignMessage signs the message with the key of the account, in the format of
+// the signmessage RPC of zcashd.
+func (account *account) SignMessage(ctx context.Context, message string) (string, error) {
+	if account.Signer == nil {
+		return "", ErrWatchOnly
+	}
+	pubKey, err := account.SerializedPublicKey()
+	if err != nil {
+		return "", err
+	}
+	return SignMessage(ctx, account.Signer, message, btcec.IsCompressedPubKey(pubKey))
+}
@@ -0,0 +1,39 @@
+package libzec_test
+
+import (
+	"context"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcutil"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/renproject/libzec-go"
+)
+
+var _ = Describe("Signed messages", func() {
+	for _, compressed := range []bool{true, false} {
+		compressed := compressed
+		It("should verify signed messages", func() {
+			privKey, err := btcec.NewPrivateKey(btcec.S256())
+			Expect(err).Should(BeNil())
+			pubKey := privKey.PubKey().SerializeUncompressed()
+			if compressed {
+				pubKey = privKey.PubKey().SerializeCompressed()
+			}
+			addr, err := AddressFromHash160(toHash160(btcutil.Hash160(pubKey)), &chaincfg.MainNetParams, false)
+			Expect(err).Should(BeNil())
+
+			sig, err := SignMessage(context.Background(), NewSigner(privKey.ToECDSA()), "hello zcash", compressed)
+			Expect(err).Should(BeNil())
+
+			ok, err := VerifyMessage(addr.EncodeAddress(), sig, "hello zcash", &chaincfg.MainNetParams)
+			Expect(err).Should(BeNil())
+			Expect(ok).Should(BeTrue())
+
+			ok, err = VerifyMessage(addr.EncodeAddress(), sig, "hello bitcoin", &chaincfg.MainNetParams)
+			Expect(err).Should(BeNil())
+			Expect(ok).Should(BeFalse())
+		})
+	}
+})
@@ -0,0 +1,161 @@
+package libzec
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+
+	"github.com/btcsuite/btcd/chaincfg"
+)
+
+// A MetadataStore stores the labels of addresses, such as the entries of an
+// address book, and notes about transactions.
+type MetadataStore interface {
+	SetLabel(address, label string) error
+	Label(address string) (string, bool, error)
+	SetNote(txHash, note string) error
+	Note(txHash string) (string, bool, error)
+}
+
+// TxRecord is a transaction receipt annotated with its metadata. Labels maps
+// the labelled addresses paid by the transaction to their labels.
+type TxRecord struct {
+	TxReceipt
+	Note   string            `json:"note,omitempty"`
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// AnnotateReceipt returns the record of the receipt, with the note of the
+// transaction and the labels of the addresses it pays.
+func AnnotateReceipt(store MetadataStore, receipt TxReceipt, params *chaincfg.Params) (TxRecord, error) {
+	record := TxRecord{TxReceipt: receipt, Labels: map[string]string{}}
+	note, ok, err := store.Note(receipt.TxHash)
+	if err != nil {
+		return record, err
+	}
+	if ok {
+		record.Note = note
+	}
+	for _, txOut := range receipt.Outputs {
+		address, err := ExtractAddress(txOut.PkScript, params)
+		if err != nil {
+			continue
+		}
+		label, ok, err := store.Label(address.EncodeAddress())
+		if err != nil {
+			return record, err
+		}
+		if ok {
+			record.Labels[address.EncodeAddress()] = label
+		}
+	}
+	return record, nil
+}
+
+type metadata struct {
+	Labels map[string]string `json:"labels"`
+	Notes  map[string]string `json:"notes"`
+}
+
+type memoryMetadataStore struct {
+	mu   *sync.RWMutex
+	data metadata
+}
+
+// NewMemoryMetadataStore returns a metadata store that keeps the metadata in
+// memory.
+func NewMemoryMetadataStore() MetadataStore {
+	return newMemoryMetadataStore()
+}
+
+func newMemoryMetadataStore() *memoryMetadataStore {
+	return &memoryMetadataStore{
+		mu:   new(sync.RWMutex),
+		data: metadata{Labels: map[string]string{}, Notes: map[string]string{}},
+	}
+}
+
+func (store *memoryMetadataStore) SetLabel(address, label string) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	store.data.Labels[address] = label
+	return nil
+}
+
+func (store *memoryMetadataStore) Label(address string) (string, bool, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+	label, ok := store.data.Labels[address]
+	return label, ok, nil
+}
+
+func (store *memoryMetadataStore) SetNote(txHash, note string) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	store.data.Notes[txHash] = note
+	return nil
+}
+
+func (store *memoryMetadataStore) Note(txHash string) (string, bool, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+	note, ok := store.data.Notes[txHash]
+	return note, ok, nil
+}
+
+type fileMetadataStore struct {
+	*memoryMetadataStore
+	path string
+}
+
+// NewFileMetadataStore returns a metadata store that persists the metadata as
+// JSON in the file at the given path. The file is created if it does not
+// exist.
+func NewFileMetadataStore(path string) (MetadataStore, error) {
+	store := &fileMetadataStore{newMemoryMetadataStore(), path}
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &store.data); err != nil {
+		return nil, fmt.Errorf("cannot decode metadata store %s: %v", path, err)
+	}
+	if store.data.Labels == nil {
+		store.data.Labels = map[string]string{}
+	}
+	if store.data.Notes == nil {
+		store.data.Notes = map[string]string{}
+	}
+	return store, nil
+}
+
+func (store *fileMetadataStore) SetLabel(address, label string) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	store.data.Labels[address] = label
+	return store.save()
+}
+
+func (store *fileMetadataStore) SetNote(txHash, note string) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	store.data.Notes[txHash] = note
+	return store.save()
+}
+
+func (store *fileMetadataStore) save() error {
+	data, err := json.Marshal(store.data)
+	if err != nil {
+		return err
+	}
+	tmp := store.path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, store.path)
+}
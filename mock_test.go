@@ -0,0 +1,195 @@
+package libzec_test
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"sync"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcutil"
+	. "github.com/onsi/gomega"
+	. "github.com/renproject/libzec-go"
+	"github.com/renproject/libzec-go/clients"
+	"github.com/renproject/libzec-go/errors"
+)
+
+// mockClientCore is an in-memory client core, backing the specs that do not
+// interact with a live network.
+type mockClientCore struct {
+	mu *sync.Mutex
+
+	params        *chaincfg.Params
+	height        int64
+	heightErr     error
+	utxos         map[string][]clients.UTXO
+	confirmations map[string]int64
+	spent         map[string]bool
+	limits        []int64
+	published     [][]byte
+	publishErr    error
+}
+
+func newMockClientCore(params *chaincfg.Params, height int64) *mockClientCore {
+	return &mockClientCore{
+		mu:            new(sync.Mutex),
+		params:        params,
+		height:        height,
+		utxos:         map[string][]clients.UTXO{},
+		confirmations: map[string]int64{},
+		spent:         map[string]bool{},
+	}
+}
+
+func (core *mockClientCore) NetworkParams() *chaincfg.Params {
+	return core.params
+}
+
+func (core *mockClientCore) GetUTXO(txHash string, vout uint32) (clients.UTXO, error) {
+	core.mu.Lock()
+	defer core.mu.Unlock()
+	if core.spent[fmt.Sprintf("%s:%d", txHash, vout)] {
+		return clients.UTXO{}, errors.ErrUTXOSpent
+	}
+	for _, utxos := range core.utxos {
+		for _, utxo := range utxos {
+			if utxo.TxHash == txHash && utxo.Vout == vout {
+				return utxo, nil
+			}
+		}
+	}
+	return clients.UTXO{}, errors.ErrTxNotFound
+}
+
+func (core *mockClientCore) GetUTXOs(address string, limit, confirmations int64) ([]clients.UTXO, error) {
+	if err := clients.CheckUTXOQuery(limit, confirmations); err != nil {
+		return nil, err
+	}
+	core.mu.Lock()
+	defer core.mu.Unlock()
+	core.limits = append(core.limits, limit)
+	utxos := []clients.UTXO{}
+	for _, utxo := range core.utxos[address] {
+		if limit > 0 && int64(len(utxos)) == limit {
+			break
+		}
+		if utxo.Confirmations >= confirmations {
+			utxos = append(utxos, utxo)
+		}
+	}
+	return utxos, nil
+}
+
+func (core *mockClientCore) Confirmations(txHash string) (int64, error) {
+	core.mu.Lock()
+	defer core.mu.Unlock()
+	confirmations, ok := core.confirmations[txHash]
+	if !ok {
+		return 0, errors.ErrTxNotFound
+	}
+	return confirmations, nil
+}
+
+func (core *mockClientCore) AddressBalance(address string, confirmations int64) (int64, error) {
+	return 0, errors.ErrNotSupported
+}
+
+func (core *mockClientCore) AddressUTXOCount(address string, confirmations int64) (int, error) {
+	return 0, errors.ErrNotSupported
+}
+
+func (core *mockClientCore) BlockHeight() (int64, error) {
+	core.mu.Lock()
+	defer core.mu.Unlock()
+	return core.height, core.heightErr
+}
+
+func (core *mockClientCore) ScriptFunded(address string, value int64) (bool, int64, error) {
+	return false, 0, errors.ErrNotSupported
+}
+
+func (core *mockClientCore) ScriptRedeemed(address string, value int64) (bool, int64, error) {
+	return false, 0, errors.ErrNotSupported
+}
+
+func (core *mockClientCore) ScriptSpent(script, spender string) (bool, string, error) {
+	return false, "", errors.ErrNotSupported
+}
+
+func (core *mockClientCore) PublishTransaction(stx []byte) error {
+	core.mu.Lock()
+	defer core.mu.Unlock()
+	if core.publishErr != nil {
+		return core.publishErr
+	}
+	core.published = append(core.published, stx)
+	return nil
+}
+
+func (core *mockClientCore) Ping(ctx context.Context) error {
+	return nil
+}
+
+func (core *mockClientCore) Capabilities() clients.Capabilities {
+	return clients.Capabilities{Mempool: true, TxLookup: true, BlockHeight: true}
+}
+
+// newMockAccount returns an account of a new key on the mock client core, with
+// a single utxo of the given amount.
+func newMockAccount(core *mockClientCore, amount int64) (Account, *btcec.PrivateKey, btcutil.Address) {
+	privKey, err := btcec.NewPrivateKey(btcec.S256())
+	Expect(err).Should(BeNil())
+	client := NewClient(core)
+	client.SetPubKeyCompression(true)
+	account := NewAccount(client, privKey.ToECDSA(), nil)
+	addr, err := account.Address()
+	Expect(err).Should(BeNil())
+	if amount > 0 {
+		core.addUTXO(addr, chainhash.Hash{byte(len(core.utxos) + 1)}, amount, 1)
+	}
+	return account, privKey, addr
+}
+
+// addUTXO adds a utxo of the given amount, locked by the P2PKH script of the
+// address, in a transaction with the given number of confirmations.
+func (core *mockClientCore) addUTXO(addr btcutil.Address, txHash chainhash.Hash, amount, confirmations int64) clients.UTXO {
+	script, err := PayToAddrScript(addr)
+	Expect(err).Should(BeNil())
+	core.mu.Lock()
+	defer core.mu.Unlock()
+	utxo := clients.UTXO{
+		TxHash:        txHash.String(),
+		Vout:          uint32(len(core.utxos[addr.EncodeAddress()])),
+		Amount:        amount,
+		ScriptPubKey:  hex.EncodeToString(script),
+		Confirmations: confirmations,
+		Address:       addr.EncodeAddress(),
+	}
+	core.utxos[addr.EncodeAddress()] = append(core.utxos[addr.EncodeAddress()], utxo)
+	core.confirmations[utxo.TxHash] = confirmations
+	return utxo
+}
+
+// setHeight sets the height of the chain.
+func (core *mockClientCore) setHeight(height int64) {
+	core.mu.Lock()
+	defer core.mu.Unlock()
+	core.height = height
+}
+
+// spend removes the utxo, as if it had been spent by a transaction that is not
+// known to the specs.
+func (core *mockClientCore) spend(utxo clients.UTXO) {
+	core.mu.Lock()
+	defer core.mu.Unlock()
+	core.spent[fmt.Sprintf("%s:%d", utxo.TxHash, utxo.Vout)] = true
+	utxos := []clients.UTXO{}
+	for _, other := range core.utxos[utxo.Address] {
+		if other.TxHash != utxo.TxHash || other.Vout != utxo.Vout {
+			utxos = append(utxos, other)
+		}
+	}
+	core.utxos[utxo.Address] = utxos
+}
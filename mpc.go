@@ -0,0 +1,377 @@
+package libzec
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/iqoption/zecutil"
+)
+
+// AsyncSigner is a Signer whose signatures are produced elsewhere, such as by
+// a threshold-ECDSA ceremony in RenVM. The hashes that need to be signed are
+// exported when Sign is called, and Sign blocks until a signature for every
+// hash has been delivered, in any order, using Deliver.
+type AsyncSigner struct {
+	pubKey *btcec.PublicKey
+	export func(ctx context.Context, hashes [][]byte) error
+
+	mu      *sync.Mutex
+	waiting map[string][]chan *btcec.Signature
+}
+
+// NewAsyncSigner returns an asynchronous signer for the given public key. The
+// export function is called with the hashes that need to be signed, it should
+// not block until they are signed.
+func NewAsyncSigner(pubKey ecdsa.PublicKey, export func(ctx context.Context, hashes [][]byte) error) *AsyncSigner {
+	return &AsyncSigner{
+		pubKey:  (*btcec.PublicKey)(&pubKey),
+		export:  export,
+		mu:      new(sync.Mutex),
+		waiting: map[string][]chan *btcec.Signature{},
+	}
+}
+
+// Sign exports the hashes, and waits for their signatures to be delivered.
+func (signer *AsyncSigner) Sign(ctx context.Context, hashes [][]byte) ([]*btcec.Signature, error) {
+	chans := make([]chan *btcec.Signature, len(hashes))
+	signer.mu.Lock()
+	for i, hash := range hashes {
+		chans[i] = make(chan *btcec.Signature, 1)
+		key := hex.EncodeToString(hash)
+		signer.waiting[key] = append(signer.waiting[key], chans[i])
+	}
+	signer.mu.Unlock()
+	defer signer.forget(hashes, chans)
+
+	if err := signer.export(ctx, hashes); err != nil {
+		return nil, err
+	}
+
+	sigs := make([]*btcec.Signature, len(hashes))
+	for i := range hashes {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case sigs[i] = <-chans[i]:
+		}
+	}
+	return sigs, nil
+}
+
+// Deliver delivers the signature of the given hash. The signature is verified
+// against the signer's public key, and an error is returned if no call to Sign
+// is waiting for the hash.
+func (signer *AsyncSigner) Deliver(hash []byte, sig *btcec.Signature) error {
+	sig, err := verifySig(sig, hash, signer.pubKey)
+	if err != nil {
+		return err
+	}
+	signer.mu.Lock()
+	defer signer.mu.Unlock()
+	key := hex.EncodeToString(hash)
+	chans, ok := signer.waiting[key]
+	if !ok {
+		return fmt.Errorf("no signature is pending for hash %s", key)
+	}
+	for _, ch := range chans {
+		select {
+		case ch <- sig:
+		default:
+		}
+	}
+	delete(signer.waiting, key)
+	return nil
+}
+
+func (signer *AsyncSigner) PublicKey() *btcec.PublicKey {
+	return signer.pubKey
+}
+
+func (signer *AsyncSigner) forget(hashes [][]byte, chans []chan *btcec.Signature) {
+	signer.mu.Lock()
+	defer signer.mu.Unlock()
+	for i, hash := range hashes {
+		key := hex.EncodeToString(hash)
+		waiting := signer.waiting[key]
+		for j, ch := range waiting {
+			if ch == chans[i] {
+				waiting = append(waiting[:j], waiting[j+1:]...)
+				break
+			}
+		}
+		if len(waiting) == 0 {
+			delete(signer.waiting, key)
+		} else {
+			signer.waiting[key] = waiting
+		}
+	}
+}
+
+// PendingTx is a transaction built by a TxBuilder that is waiting for its
+// signatures. Signatures can be added in any order as they arrive, and the
+// pending transaction can be serialized, so that it can be resumed and
+// finalized after the process restarts.
+type PendingTx struct {
+	tx   *transaction
+	sigs []*btcec.Signature
+}
+
+// NewPendingTx returns a pending transaction for the unsigned transaction.
+func NewPendingTx(tx Tx) (*PendingTx, error) {
+	unsigned, ok := tx.(*transaction)
+	if !ok {
+		return nil, fmt.Errorf("unsupported transaction type %T", tx)
+	}
+	return &PendingTx{
+		tx:   unsigned,
+		sigs: make([]*btcec.Signature, len(unsigned.hashes)),
+	}, nil
+}
+
+// ResumePendingTx deserializes a pending transaction, and connects it to the
+// given client.
+func ResumePendingTx(client Client, data []byte) (*PendingTx, error) {
+	pending := &PendingTx{}
+	if err := json.Unmarshal(data, pending); err != nil {
+		return nil, err
+	}
+	pending.tx.client = client
+	return pending, nil
+}
+
+// Hashes returns the signature hashes of every input of the transaction.
+func (pending *PendingTx) Hashes() [][]byte {
+	return pending.tx.hashes
+}
+
+// MissingHashes returns the signature hashes that have not been signed yet.
+func (pending *PendingTx) MissingHashes() [][]byte {
+	hashes := [][]byte{}
+	for i, hash := range pending.tx.hashes {
+		if pending.sigs[i] == nil {
+			hashes = append(hashes, hash)
+		}
+	}
+	return hashes
+}
+
+// AddSig adds the signature of the given hash. The signature is verified
+// against the public key of every input with the same hash before it is
+// added.
+func (pending *PendingTx) AddSig(hash []byte, sig *btcec.Signature) error {
+	found := false
+	for i, inputHash := range pending.tx.hashes {
+		if !bytes.Equal(inputHash, hash) {
+			continue
+		}
+		normalized, err := verifySig(sig, hash, pending.tx.inputs[i].pubKey)
+		if err != nil {
+			return NewErrInvalidSignature(i, err.Error())
+		}
+		pending.sigs[i] = normalized
+		found = true
+	}
+	if !found {
+		return fmt.Errorf("hash %x does not belong to the transaction", hash)
+	}
+	return nil
+}
+
+// IsComplete returns true if every input of the transaction has a signature.
+func (pending *PendingTx) IsComplete() bool {
+	return len(pending.MissingHashes()) == 0
+}
+
+// Finalize injects the collected signatures, and returns the signed
+// transaction.
+func (pending *PendingTx) Finalize() (Tx, error) {
+	if !pending.IsComplete() {
+		return nil, fmt.Errorf("missing signatures: got: %d required: %d", len(pending.sigs)-len(pending.MissingHashes()), len(pending.sigs))
+	}
+	if err := pending.tx.InjectSigs(pending.sigs); err != nil {
+		return nil, err
+	}
+	return pending.tx, nil
+}
+
+type pendingTxJSON struct {
+	Version      int32                `json:"version"`
+	LockTime     uint32               `json:"lockTime"`
+	ExpiryHeight uint32               `json:"expiryHeight"`
+	BranchID     *uint32              `json:"branchId,omitempty"`
+	HashType     txscript.SigHashType `json:"hashType,omitempty"`
+	Inputs       []pendingTxInputJSON `json:"inputs"`
+	Outputs      []psztOutputJSON     `json:"outputs"`
+	Value        int64                `json:"value"`
+	Change       int64                `json:"change"`
+	Verify       bool                 `json:"verify"`
+}
+
+type pendingTxInputJSON struct {
+	TxHash       string `json:"txHash"`
+	Vout         uint32 `json:"vout"`
+	Sequence     uint32 `json:"sequence"`
+	Amount       int64  `json:"amount"`
+	ScriptPubKey string `json:"scriptPubKey"`
+	RedeemScript string `json:"redeemScript,omitempty"`
+	Unlock       string `json:"unlock,omitempty"`
+	PubKey       string `json:"pubKey"`
+	Sig          string `json:"sig,omitempty"`
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+func (pending *PendingTx) MarshalJSON() ([]byte, error) {
+	msgTx := pending.tx.msgTx
+	val := pendingTxJSON{
+		Version:      msgTx.Version,
+		LockTime:     msgTx.LockTime,
+		ExpiryHeight: msgTx.ExpiryHeight,
+		BranchID:     &pending.tx.branchID,
+		HashType:     pending.tx.hashType,
+		Value:        pending.tx.value,
+		Change:       pending.tx.change,
+		Verify:       pending.tx.verify,
+	}
+	for i, input := range pending.tx.inputs {
+		txIn := msgTx.TxIn[i]
+		var sig string
+		if pending.sigs[i] != nil {
+			sig = hex.EncodeToString(pending.sigs[i].Serialize())
+		}
+		val.Inputs = append(val.Inputs, pendingTxInputJSON{
+			TxHash:       txIn.PreviousOutPoint.Hash.String(),
+			Vout:         txIn.PreviousOutPoint.Index,
+			Sequence:     txIn.Sequence,
+			Amount:       input.amount,
+			ScriptPubKey: hex.EncodeToString(input.scriptPubKey),
+			RedeemScript: hex.EncodeToString(input.redeemScript),
+			Unlock:       hex.EncodeToString(input.unlock),
+			PubKey:       hex.EncodeToString(input.pubKey.SerializeCompressed()),
+			Sig:          sig,
+		})
+	}
+	for _, txOut := range msgTx.TxOut {
+		val.Outputs = append(val.Outputs, psztOutputJSON{
+			Value:    txOut.Value,
+			PkScript: hex.EncodeToString(txOut.PkScript),
+		})
+	}
+	return json.Marshal(val)
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface. The signature
+// hashes are recomputed from the transaction, and the collected signatures
+// are verified against them.
+func (pending *PendingTx) UnmarshalJSON(data []byte) error {
+	val := pendingTxJSON{}
+	if err := json.Unmarshal(data, &val); err != nil {
+		return err
+	}
+
+	msgTx := &zecutil.MsgTx{
+		MsgTx:        wire.NewMsgTx(val.Version),
+		ExpiryHeight: val.ExpiryHeight,
+	}
+	msgTx.LockTime = val.LockTime
+	inputs := make([]txInput, len(val.Inputs))
+	for i, input := range val.Inputs {
+		hash, err := chainhash.NewHashFromStr(input.TxHash)
+		if err != nil {
+			return err
+		}
+		txIn := wire.NewTxIn(wire.NewOutPoint(hash, input.Vout), []byte{}, [][]byte{})
+		txIn.Sequence = input.Sequence
+		msgTx.AddTxIn(txIn)
+
+		scriptPubKey, err := hex.DecodeString(input.ScriptPubKey)
+		if err != nil {
+			return err
+		}
+		var redeemScript []byte
+		if input.RedeemScript != "" {
+			if redeemScript, err = hex.DecodeString(input.RedeemScript); err != nil {
+				return err
+			}
+		}
+		var unlock []byte
+		if input.Unlock != "" {
+			if unlock, err = hex.DecodeString(input.Unlock); err != nil {
+				return err
+			}
+		}
+		pubKeyBytes, err := hex.DecodeString(input.PubKey)
+		if err != nil {
+			return err
+		}
+		pubKey, err := btcec.ParsePubKey(pubKeyBytes, btcec.S256())
+		if err != nil {
+			return err
+		}
+		inputs[i] = txInput{
+			amount:       input.Amount,
+			scriptPubKey: scriptPubKey,
+			redeemScript: redeemScript,
+			pubKey:       pubKey,
+			unlock:       unlock,
+		}
+	}
+	for _, output := range val.Outputs {
+		pkScript, err := hex.DecodeString(output.PkScript)
+		if err != nil {
+			return err
+		}
+		msgTx.AddTxOut(wire.NewTxOut(output.Value, pkScript))
+	}
+
+	// Pending transactions serialized before the hash type was recorded
+	// were always signed with SigHashAll.
+	if val.HashType == 0 {
+		val.HashType = txscript.SigHashAll
+	}
+	if val.BranchID == nil {
+		return fmt.Errorf("cannot decode pending transaction: missing consensus branch id")
+	}
+	hashes, err := inputHashes(msgTx, inputs, *val.BranchID, val.HashType)
+	if err != nil {
+		return err
+	}
+	sigs := make([]*btcec.Signature, len(inputs))
+	for i := range inputs {
+		if val.Inputs[i].Sig == "" {
+			continue
+		}
+		sigBytes, err := hex.DecodeString(val.Inputs[i].Sig)
+		if err != nil {
+			return err
+		}
+		sig, err := btcec.ParseDERSignature(sigBytes, btcec.S256())
+		if err != nil {
+			return err
+		}
+		if sigs[i], err = verifySig(sig, hashes[i], inputs[i].pubKey); err != nil {
+			return NewErrInvalidSignature(i, err.Error())
+		}
+	}
+
+	pending.tx = &transaction{
+		msgTx:    msgTx,
+		hashes:   hashes,
+		inputs:   inputs,
+		verify:   val.Verify,
+		value:    val.Value,
+		change:   val.Change,
+		branchID: *val.BranchID,
+		hashType: val.HashType,
+	}
+	pending.sigs = sigs
+	return nil
+}
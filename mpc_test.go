@@ -0,0 +1,216 @@
+package libzec_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcutil"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/renproject/libzec-go"
+	"github.com/renproject/libzec-go/clients"
+)
+
+var _ = Describe("Async signers", func() {
+	hashes := [][]byte{{1}, {2}, {3}}
+	for i := range hashes {
+		hash := sha256.Sum256(hashes[i])
+		hashes[i] = hash[:]
+	}
+
+	newSigner := func() (*AsyncSigner, *btcec.PrivateKey, chan [][]byte) {
+		privKey, err := btcec.NewPrivateKey(btcec.S256())
+		Expect(err).Should(BeNil())
+		exported := make(chan [][]byte, 1)
+		signer := NewAsyncSigner(privKey.PublicKey, func(ctx context.Context, hashes [][]byte) error {
+			exported <- hashes
+			return nil
+		})
+		return signer, privKey, exported
+	}
+
+	It("should wait for the signatures to be delivered in any order", func() {
+		signer, privKey, exported := newSigner()
+		go func() {
+			defer GinkgoRecover()
+			exportedHashes := <-exported
+			for i := len(exportedHashes) - 1; i >= 0; i-- {
+				sig, err := privKey.Sign(exportedHashes[i])
+				Expect(err).Should(BeNil())
+				Expect(signer.Deliver(exportedHashes[i], sig)).Should(BeNil())
+			}
+		}()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		sigs, err := signer.Sign(ctx, hashes)
+		Expect(err).Should(BeNil())
+		Expect(sigs).Should(HaveLen(len(hashes)))
+		for i, sig := range sigs {
+			Expect(sig.Verify(hashes[i], privKey.PubKey())).Should(BeTrue())
+		}
+	})
+
+	It("should not accept signatures that are not pending", func() {
+		signer, privKey, _ := newSigner()
+		sig, err := privKey.Sign(hashes[0])
+		Expect(err).Should(BeNil())
+		Expect(signer.Deliver(hashes[0], sig)).ShouldNot(BeNil())
+	})
+
+	It("should not accept invalid signatures", func() {
+		signer, _, exported := newSigner()
+		other, err := btcec.NewPrivateKey(btcec.S256())
+		Expect(err).Should(BeNil())
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error, 1)
+		go func() {
+			_, err := signer.Sign(ctx, hashes)
+			done <- err
+		}()
+		<-exported
+		sig, err := other.Sign(hashes[0])
+		Expect(err).Should(BeNil())
+		Expect(signer.Deliver(hashes[0], sig)).ShouldNot(BeNil())
+
+		cancel()
+		Eventually(done, 5*time.Second).Should(Receive(Equal(context.Canceled)))
+	})
+
+	It("should stop waiting when the context is cancelled", func() {
+		signer, privKey, exported := newSigner()
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error, 1)
+		go func() {
+			_, err := signer.Sign(ctx, hashes)
+			done <- err
+		}()
+		<-exported
+		cancel()
+		Eventually(done, 5*time.Second).Should(Receive(Equal(context.Canceled)))
+
+		sig, err := privKey.Sign(hashes[0])
+		Expect(err).Should(BeNil())
+		Expect(signer.Deliver(hashes[0], sig)).ShouldNot(BeNil())
+	})
+})
+
+var _ = Describe("Pending transactions", func() {
+	secret := [32]byte{1, 2, 3}
+	secretHash := sha256.Sum256(secret[:])
+
+	newPendingTx := func() (*PendingTx, *btcec.PrivateKey, Client) {
+		privKey, err := btcec.NewPrivateKey(btcec.S256())
+		Expect(err).Should(BeNil())
+		pkh := btcutil.Hash160(privKey.PubKey().SerializeCompressed())
+		contract, err := HTLCScript(pkh, pkh, secretHash, 1000)
+		Expect(err).Should(BeNil())
+		scriptPubKey, err := PayToScriptHashScript(contract)
+		Expect(err).Should(BeNil())
+		addr, err := AddressFromHash160(toHash160(pkh), &chaincfg.TestNet3Params, false)
+		Expect(err).Should(BeNil())
+		unlock, err := HTLCRedeemUnlock(secret)
+		Expect(err).Should(BeNil())
+
+		client := NewClient(newMockClientCore(&chaincfg.TestNet3Params, 1842420))
+		client.SetPubKeyCompression(true)
+		utxos := []clients.UTXO{}
+		for i := byte(1); i <= 2; i++ {
+			utxos = append(utxos, clients.UTXO{
+				TxHash:       chainhash.Hash{i}.String(),
+				Amount:       50000,
+				ScriptPubKey: hex.EncodeToString(scriptPubKey),
+			})
+		}
+		tx, err := NewTxBuilder(client).BuildMulti(addr.EncodeAddress(), addr.EncodeAddress(), 80000, []SignerUTXOs{{
+			PubKey:   privKey.PublicKey,
+			Contract: contract,
+			Unlock:   unlock,
+			UTXOs:    utxos,
+		}})
+		Expect(err).Should(BeNil())
+		pending, err := NewPendingTx(tx)
+		Expect(err).Should(BeNil())
+		return pending, privKey, client
+	}
+
+	sign := func(privKey *btcec.PrivateKey, hash []byte) *btcec.Signature {
+		sig, err := privKey.Sign(hash)
+		Expect(err).Should(BeNil())
+		return sig
+	}
+
+	It("should finalize once every signature has been added in any order", func() {
+		pending, privKey, _ := newPendingTx()
+		hashes := pending.Hashes()
+		Expect(hashes).Should(HaveLen(2))
+		Expect(pending.MissingHashes()).Should(Equal(hashes))
+
+		Expect(pending.AddSig(hashes[1], sign(privKey, hashes[1]))).Should(BeNil())
+		Expect(pending.IsComplete()).Should(BeFalse())
+		Expect(pending.MissingHashes()).Should(Equal(hashes[:1]))
+		_, err := pending.Finalize()
+		Expect(err).ShouldNot(BeNil())
+
+		Expect(pending.AddSig(hashes[0], sign(privKey, hashes[0]))).Should(BeNil())
+		Expect(pending.IsComplete()).Should(BeTrue())
+		tx, err := pending.Finalize()
+		Expect(err).Should(BeNil())
+		Expect(tx.Verify()).Should(BeNil())
+	})
+
+	It("should not accept invalid signatures or unknown hashes", func() {
+		pending, privKey, _ := newPendingTx()
+		hashes := pending.Hashes()
+		other, err := btcec.NewPrivateKey(btcec.S256())
+		Expect(err).Should(BeNil())
+		Expect(pending.AddSig(hashes[0], sign(other, hashes[0]))).ShouldNot(BeNil())
+
+		unknown := sha256.Sum256([]byte("unknown"))
+		Expect(pending.AddSig(unknown[:], sign(privKey, unknown[:]))).ShouldNot(BeNil())
+		Expect(pending.MissingHashes()).Should(Equal(hashes))
+	})
+
+	It("should resume a serialized pending transaction", func() {
+		pending, privKey, client := newPendingTx()
+		hashes := pending.Hashes()
+		Expect(pending.AddSig(hashes[0], sign(privKey, hashes[0]))).Should(BeNil())
+
+		data, err := json.Marshal(pending)
+		Expect(err).Should(BeNil())
+		resumed, err := ResumePendingTx(client, data)
+		Expect(err).Should(BeNil())
+		Expect(resumed.Hashes()).Should(Equal(hashes))
+		Expect(resumed.MissingHashes()).Should(Equal(hashes[1:]))
+
+		Expect(resumed.AddSig(hashes[1], sign(privKey, hashes[1]))).Should(BeNil())
+		tx, err := resumed.Finalize()
+		Expect(err).Should(BeNil())
+		Expect(tx.Verify()).Should(BeNil())
+	})
+
+	It("should not resume a pending transaction with invalid signatures", func() {
+		pending, _, client := newPendingTx()
+		hashes := pending.Hashes()
+		other, err := btcec.NewPrivateKey(btcec.S256())
+		Expect(err).Should(BeNil())
+		data, err := json.Marshal(pending)
+		Expect(err).Should(BeNil())
+
+		decoded := map[string]interface{}{}
+		Expect(json.Unmarshal(data, &decoded)).Should(BeNil())
+		input := decoded["inputs"].([]interface{})[0].(map[string]interface{})
+		input["sig"] = hex.EncodeToString(sign(other, hashes[0]).Serialize())
+		data, err = json.Marshal(decoded)
+		Expect(err).Should(BeNil())
+		_, err = ResumePendingTx(client, data)
+		Expect(err).ShouldNot(BeNil())
+	})
+})
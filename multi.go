@@ -0,0 +1,75 @@
+package libzec
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/renproject/libzec-go/clients"
+)
+
+// MaxConcurrentQueries is the number of addresses that are queried at the
+// same time by GetUTXOsMulti and BalanceOf.
+const MaxConcurrentQueries = 8
+
+// GetUTXOsMulti returns the utxos of all the addresses, in the order of the
+// addresses. The limit applies to every address. The addresses are queried
+// concurrently, and an error is returned if any of the queries fails.
+func (client *client) GetUTXOsMulti(addresses []string, limit, confirmations int64) ([]clients.UTXO, error) {
+	results := make([][]clients.UTXO, len(addresses))
+	if err := forEachAddress(addresses, func(i int, address string) error {
+		utxos, err := client.GetUTXOs(address, limit, confirmations)
+		results[i] = utxos
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	utxos := []clients.UTXO{}
+	for _, result := range results {
+		utxos = append(utxos, result...)
+	}
+	return utxos, nil
+}
+
+// BalanceOf returns the total balance of the addresses. The addresses are
+// queried concurrently, and an error is returned if any of the queries fails.
+func (client *client) BalanceOf(addresses []string, confirmations int64) (int64, error) {
+	balances := make([]int64, len(addresses))
+	if err := forEachAddress(addresses, func(i int, address string) error {
+		balance, err := client.Balance(address, confirmations)
+		balances[i] = balance
+		return err
+	}); err != nil {
+		return 0, err
+	}
+
+	var total int64
+	for _, balance := range balances {
+		total += balance
+	}
+	return total, nil
+}
+
+// forEachAddress calls f for every address, with at most MaxConcurrentQueries
+// calls running at the same time, and returns the first error.
+func forEachAddress(addresses []string, f func(i int, address string) error) error {
+	errs := make([]error, len(addresses))
+	sem := make(chan struct{}, MaxConcurrentQueries)
+	wg := new(sync.WaitGroup)
+	for i, address := range addresses {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(i int, address string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			errs[i] = f(i, address)
+		}(i, address)
+	}
+	wg.Wait()
+	for i, err := range errs {
+		if err != nil {
+			return fmt.Errorf("cannot query %s: %v", addresses[i], err)
+		}
+	}
+	return nil
+}
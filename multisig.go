@@ -0,0 +1,66 @@
+package libzec
+
+import (
+	"fmt"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcutil"
+)
+
+// MaxMultisigPubKeys is the maximum number of public keys in a multisig redeem
+// script that can be spent using P2SH.
+const MaxMultisigPubKeys = 15
+
+// MultisigScript creates an m-of-n multisig redeem script from the given
+// serialized public keys. The order of the public keys is the order in which
+// the signatures must be provided.
+func MultisigScript(required int, pubKeys [][]byte) ([]byte, error) {
+	if len(pubKeys) == 0 || len(pubKeys) > MaxMultisigPubKeys {
+		return nil, fmt.Errorf("invalid number of public keys: got: %d max: %d", len(pubKeys), MaxMultisigPubKeys)
+	}
+	if required < 1 || required > len(pubKeys) {
+		return nil, fmt.Errorf("invalid number of required signatures: got: %d max: %d", required, len(pubKeys))
+	}
+	b := txscript.NewScriptBuilder()
+	b.AddInt64(int64(required))
+	for _, pubKey := range pubKeys {
+		if _, err := btcec.ParsePubKey(pubKey, btcec.S256()); err != nil {
+			return nil, err
+		}
+		b.AddData(pubKey)
+	}
+	b.AddInt64(int64(len(pubKeys)))
+	b.AddOp(txscript.OP_CHECKMULTISIG)
+	return b.Script()
+}
+
+// MultisigAddress returns the P2SH address of the given multisig redeem script.
+func MultisigAddress(script []byte, params *chaincfg.Params) (btcutil.Address, error) {
+	if txscript.GetScriptClass(script) != txscript.MultiSigTy {
+		return nil, fmt.Errorf("not a multisig script: %x", script)
+	}
+	return ScriptAddress(script, params)
+}
+
+// MultisigSigScript assembles the signature script spending a multisig redeem
+// script from the given signatures. The signatures must already have the
+// sighash type appended, and must be in the same order as their public keys in
+// the redeem script.
+func MultisigSigScript(sigs [][]byte, script []byte) ([]byte, error) {
+	_, required, err := txscript.CalcMultiSigStats(script)
+	if err != nil {
+		return nil, err
+	}
+	if len(sigs) != required {
+		return nil, fmt.Errorf("invalid number of signatures: got: %d required: %d", len(sigs), required)
+	}
+	b := txscript.NewScriptBuilder()
+	b.AddOp(txscript.OP_0)
+	for _, sig := range sigs {
+		b.AddData(sig)
+	}
+	b.AddData(script)
+	return b.Script()
+}
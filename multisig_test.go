@@ -0,0 +1,51 @@
+package libzec_test
+
+import (
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/renproject/libzec-go"
+)
+
+var _ = Describe("Multisig", func() {
+	randomPubKeys := func(n int) [][]byte {
+		pubKeys := make([][]byte, n)
+		for i := range pubKeys {
+			privKey, err := btcec.NewPrivateKey(btcec.S256())
+			Expect(err).Should(BeNil())
+			pubKeys[i] = privKey.PubKey().SerializeCompressed()
+		}
+		return pubKeys
+	}
+
+	It("should build a 2-of-3 multisig script and address", func() {
+		script, err := MultisigScript(2, randomPubKeys(3))
+		Expect(err).Should(BeNil())
+		addr, err := MultisigAddress(script, &chaincfg.TestNet3Params)
+		Expect(err).Should(BeNil())
+		Expect(addr.EncodeAddress()[:2]).Should(Equal("t2"))
+	})
+
+	It("should not build a script requiring more signatures than keys", func() {
+		_, err := MultisigScript(4, randomPubKeys(3))
+		Expect(err).ShouldNot(BeNil())
+	})
+
+	It("should not assemble a sig script with too few signatures", func() {
+		script, err := MultisigScript(2, randomPubKeys(3))
+		Expect(err).Should(BeNil())
+		_, err = MultisigSigScript([][]byte{{0x01}}, script)
+		Expect(err).ShouldNot(BeNil())
+	})
+
+	It("should create a 2-of-3 escrow", func() {
+		pubKeys := randomPubKeys(3)
+		escrow, err := NewEscrow(pubKeys[0], pubKeys[1], pubKeys[2], &chaincfg.TestNet3Params)
+		Expect(err).Should(BeNil())
+		script, err := MultisigScript(2, pubKeys)
+		Expect(err).Should(BeNil())
+		Expect(escrow.Script).Should(Equal(script))
+		Expect(escrow.Address.EncodeAddress()[:2]).Should(Equal("t2"))
+	})
+})
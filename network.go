@@ -0,0 +1,31 @@
+package libzec
+
+import (
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/renproject/libzec-go/clients"
+)
+
+// Network is a ZCash network. The zero value is Testnet.
+type Network = clients.Network
+
+const (
+	Testnet = clients.Testnet
+	Mainnet = clients.Mainnet
+	Regtest = clients.Regtest
+)
+
+// networks are the supported networks. Testnet comes before regtest, as their
+// transparent addresses cannot be told apart.
+var networks = []Network{Mainnet, Testnet, Regtest}
+
+// ParseNetwork parses the name of a network, such as "mainnet", "testnet" or
+// "regtest".
+func ParseNetwork(name string) (Network, error) {
+	return clients.ParseNetwork(name)
+}
+
+// NetworkOf returns the network of the parameters, and an
+// UnsupportedNetworkError if the network is unknown.
+func NetworkOf(params *chaincfg.Params) (Network, error) {
+	return clients.NetworkOf(params)
+}
@@ -0,0 +1,39 @@
+package libzec_test
+
+import (
+	"github.com/btcsuite/btcd/chaincfg"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/renproject/libzec-go"
+)
+
+var _ = Describe("Networks", func() {
+	It("should parse the names of the networks", func() {
+		for name, network := range map[string]Network{
+			"mainnet":  Mainnet,
+			"MainNet":  Mainnet,
+			"testnet":  Testnet,
+			"testnet3": Testnet,
+			"":         Testnet,
+			"regtest":  Regtest,
+		} {
+			parsed, err := ParseNetwork(name)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(parsed).Should(Equal(network))
+		}
+		_, err := ParseNetwork("simnet")
+		Expect(err).Should(HaveOccurred())
+	})
+
+	It("should map networks to their parameters and back", func() {
+		for _, network := range []Network{Mainnet, Testnet, Regtest} {
+			Expect(network.String()).Should(Equal(network.Params().Name))
+			parsed, err := NetworkOf(network.Params())
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(parsed).Should(Equal(network))
+		}
+		_, err := NetworkOf(&chaincfg.SimNetParams)
+		Expect(err).Should(HaveOccurred())
+	})
+})
@@ -0,0 +1,160 @@
+package libzec
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/btcsuite/btcd/wire"
+	"github.com/iqoption/zecutil"
+	"github.com/renproject/libzec-go/clients"
+	"github.com/sirupsen/logrus"
+)
+
+// zcashdWalletAccount is an account whose transfers are funded and signed by
+// the wallet of a zcashd node. The other operations of the account behave as
+// the ones of a watch-only account of its address.
+type zcashdWalletAccount struct {
+	*account
+	wallet clients.ZcashdWallet
+}
+
+// NewZcashdWalletAccount returns an Account that delegates coin selection and
+// signing to the wallet of the zcashd node backing the client, using
+// createrawtransaction, fundrawtransaction and signrawtransaction. The wallet
+// selects the inputs among all of its utxos, sends the change to an address of
+// its own, and pays its own fee, so the speed of transfers is ignored. The
+// address is the address of the account, which should be controlled by the
+// wallet. Operations that require the key of the account, other than
+// transfers, return ErrWatchOnly.
+func NewZcashdWalletAccount(client Client, address string, logger logrus.FieldLogger) (Account, error) {
+	wallet, ok := zcashdWallet(client)
+	if !ok {
+		return nil, ErrNoZcashdWallet
+	}
+	addr, err := DecodeAddress(address, client.NetworkParams())
+	if err != nil {
+		return nil, err
+	}
+	return &zcashdWalletAccount{newAccount(client, nil, nil, addr, logger), wallet}, nil
+}
+
+// Transfer sends the value to the address. Sending the whole balance is not
+// supported, as the wallet adds its fee on top of the value.
+func (account *zcashdWalletAccount) Transfer(ctx context.Context, to string, value int64, speed TxExecutionSpeed, sendAll bool) (TxReceipt, error) {
+	if sendAll {
+		return TxReceipt{}, ErrNotSupported
+	}
+	funded, err := account.fund(to, value)
+	if err != nil {
+		return TxReceipt{}, err
+	}
+	signed, complete, err := account.wallet.SignRawTransaction(funded.Tx)
+	if err != nil {
+		return TxReceipt{}, err
+	}
+	if !complete {
+		return TxReceipt{}, fmt.Errorf("the zcashd wallet cannot sign every input of the transaction")
+	}
+
+	decoded, err := DecodeTransaction(signed)
+	if err != nil {
+		return TxReceipt{}, err
+	}
+	msgTx := &zecutil.MsgTx{
+		MsgTx:        wire.NewMsgTx(decoded.Version),
+		ExpiryHeight: decoded.ExpiryHeight,
+	}
+	msgTx.LockTime = decoded.LockTime
+	msgTx.TxIn = decoded.TxIn
+	msgTx.TxOut = decoded.TxOut
+
+	select {
+	case <-ctx.Done():
+		return TxReceipt{}, ctx.Err()
+	default:
+	}
+	if err := account.publish(msgTx, signed); err != nil {
+		return TxReceipt{}, err
+	}
+	txID, err := TxID(signed)
+	if err != nil {
+		return TxReceipt{}, err
+	}
+	receipt := fundedReceipt(msgTx, funded, value)
+	receipt.TxHash = hex.EncodeToString(txID)
+	account.Logger.Infof("sent %d ZAT to %s in %s", value, to, receipt.TxHash)
+	return receipt, nil
+}
+
+// TransferAndWait transfers the value to the address, and waits until the
+// transaction has the given number of confirmations.
+func (account *zcashdWalletAccount) TransferAndWait(ctx context.Context, to string, value int64, speed TxExecutionSpeed, sendAll bool, confirmations int64) (TxReceipt, error) {
+	receipt, err := account.Transfer(ctx, to, value, speed, sendAll)
+	if err != nil {
+		return TxReceipt{}, err
+	}
+	return receipt, account.waitForConfirmations(ctx, receipt.TxHash, receipt.ExpiryHeight, confirmations)
+}
+
+// PreviewTransfer returns the inputs selected by the wallet, and its fee. The
+// amounts of the inputs are not known.
+func (account *zcashdWalletAccount) PreviewTransfer(ctx context.Context, to string, value int64, speed TxExecutionSpeed, sendAll bool) (TxPreview, error) {
+	if sendAll {
+		return TxPreview{}, ErrNotSupported
+	}
+	funded, err := account.fund(to, value)
+	if err != nil {
+		return TxPreview{}, err
+	}
+	decoded, err := DecodeTransaction(funded.Tx)
+	if err != nil {
+		return TxPreview{}, err
+	}
+	msgTx := &zecutil.MsgTx{MsgTx: wire.NewMsgTx(decoded.Version)}
+	msgTx.TxIn = decoded.TxIn
+	msgTx.TxOut = decoded.TxOut
+	receipt := fundedReceipt(msgTx, funded, value)
+	return TxPreview{
+		Inputs:        receipt.Inputs,
+		Value:         receipt.Value,
+		Fee:           receipt.Fee,
+		Change:        receipt.Change,
+		EstimatedSize: len(funded.Tx) + len(decoded.TxIn)*(maxSigPushSize+1+33),
+	}, nil
+}
+
+// fund creates the transaction paying the value to the address, funded by the
+// wallet.
+func (account *zcashdWalletAccount) fund(to string, value int64) (clients.FundedTx, error) {
+	if _, err := DecodeAddress(to, account.NetworkParams()); err != nil {
+		return clients.FundedTx{}, err
+	}
+	raw, err := account.wallet.CreateRawTransaction(map[string]int64{to: value})
+	if err != nil {
+		return clients.FundedTx{}, err
+	}
+	return account.wallet.FundRawTransaction(raw)
+}
+
+// fundedReceipt returns the receipt of a transaction funded by the wallet,
+// without its hash. The inputs only identify the spent outpoints.
+func fundedReceipt(msgTx *zecutil.MsgTx, funded clients.FundedTx, value int64) TxReceipt {
+	receipt := TxReceipt{
+		Outputs:      msgTx.TxOut,
+		Value:        value,
+		Fee:          funded.Fee,
+		ChangeIndex:  funded.ChangeIndex,
+		ExpiryHeight: msgTx.ExpiryHeight,
+	}
+	for _, txIn := range msgTx.TxIn {
+		receipt.Inputs = append(receipt.Inputs, clients.UTXO{
+			TxHash: txIn.PreviousOutPoint.Hash.String(),
+			Vout:   txIn.PreviousOutPoint.Index,
+		})
+	}
+	if funded.ChangeIndex >= 0 && funded.ChangeIndex < len(msgTx.TxOut) {
+		receipt.Change = msgTx.TxOut[funded.ChangeIndex].Value
+	}
+	return receipt
+}
@@ -0,0 +1,50 @@
+package libzec
+
+import (
+	"math/big"
+)
+
+// pallasP is the base field of Pallas, the curve of the Orchard keys and
+// addresses: y^2 = x^3 + 5.
+var pallasP, _ = new(big.Int).SetString("40000000000000000000000000000000224698fc094cf91b992d30ed00000001", 16)
+
+// OrchardReceiver is the Orchard receiver of a unified address. Orchard notes
+// can only be created by a wallet that supports Orchard, so transactions
+// built by this library cannot pay it.
+type OrchardReceiver struct {
+	Diversifier [11]byte
+	PkD         [32]byte
+}
+
+// newOrchardReceiver validates the encoding of an Orchard receiver, which is
+// its diversifier followed by its encoded transmission key.
+func newOrchardReceiver(data []byte) (*OrchardReceiver, bool) {
+	if len(data) != 43 {
+		return nil, false
+	}
+	receiver := &OrchardReceiver{}
+	copy(receiver.Diversifier[:], data[:11])
+	copy(receiver.PkD[:], data[11:])
+	if !isPallasPoint(receiver.PkD) {
+		return nil, false
+	}
+	return receiver, true
+}
+
+// isPallasPoint returns whether the bytes are the encoding of a point of
+// Pallas other than the identity: the little endian x-coordinate, with the
+// sign of the y-coordinate in its most significant bit.
+func isPallasPoint(encoded [32]byte) bool {
+	sign := encoded[31] >> 7
+	encoded[31] &= 0x7f
+	x := new(big.Int).SetBytes(reverseBytes(encoded[:]))
+	if x.Cmp(pallasP) >= 0 || x.Sign() == 0 {
+		// The identity is encoded as zero, and zero is not the x-coordinate
+		// of a point as 5 is not a square.
+		return false
+	}
+	yy := new(big.Int).Exp(x, big.NewInt(3), pallasP)
+	yy.Add(yy, big.NewInt(5)).Mod(yy, pallasP)
+	y := new(big.Int).ModSqrt(yy, pallasP)
+	return y != nil && (y.Sign() != 0 || sign == 0)
+}
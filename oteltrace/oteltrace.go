@@ -0,0 +1,49 @@
+// Package oteltrace adapts OpenTelemetry tracers to the Tracer interface of
+// libzec, so that the spans of the library join the traces of the services
+// using it:
+//
+//	libzec.SetTracer(oteltrace.NewTracer(otel.Tracer("libzec")))
+package oteltrace
+
+import (
+	"context"
+
+	"github.com/renproject/libzec-go"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type tracer struct {
+	tracer trace.Tracer
+}
+
+// NewTracer returns a libzec tracer that starts its spans using the
+// OpenTelemetry tracer.
+func NewTracer(t trace.Tracer) libzec.Tracer {
+	return tracer{t}
+}
+
+func (t tracer) Start(ctx context.Context, spanName string) (context.Context, libzec.Span) {
+	ctx, s := t.tracer.Start(ctx, spanName)
+	return ctx, span{s}
+}
+
+type span struct {
+	span trace.Span
+}
+
+func (s span) SetAttribute(key, value string) {
+	s.span.SetAttributes(attribute.String(key, value))
+}
+
+// RecordError records the error as an event of the span, and sets the status
+// of the span to error.
+func (s span) RecordError(err error) {
+	s.span.RecordError(err)
+	s.span.SetStatus(codes.Error, err.Error())
+}
+
+func (s span) End() {
+	s.span.End()
+}
@@ -0,0 +1,13 @@
+package oteltrace_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestOtelTrace(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "OtelTrace Suite")
+}
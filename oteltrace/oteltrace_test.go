@@ -0,0 +1,78 @@
+package oteltrace_test
+
+import (
+	"context"
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/renproject/libzec-go/oteltrace"
+
+	"github.com/renproject/libzec-go"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+type recordingTracer struct {
+	noop.Tracer
+	spans []*recordingSpan
+}
+
+func (t *recordingTracer) Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	span := &recordingSpan{name: name}
+	t.spans = append(t.spans, span)
+	return trace.ContextWithSpan(ctx, span), span
+}
+
+type recordingSpan struct {
+	noop.Span
+	name       string
+	attributes []attribute.KeyValue
+	errs       []error
+	status     codes.Code
+	ended      bool
+}
+
+func (s *recordingSpan) SetAttributes(kv ...attribute.KeyValue) {
+	s.attributes = append(s.attributes, kv...)
+}
+
+func (s *recordingSpan) RecordError(err error, opts ...trace.EventOption) {
+	s.errs = append(s.errs, err)
+}
+
+func (s *recordingSpan) SetStatus(code codes.Code, description string) {
+	s.status = code
+}
+
+func (s *recordingSpan) End(opts ...trace.SpanEndOption) {
+	s.ended = true
+}
+
+var _ = Describe("OpenTelemetry tracers", func() {
+	It("should start spans with the opentelemetry tracer", func() {
+		otelTracer := &recordingTracer{}
+		ctx, span := NewTracer(otelTracer).Start(context.Background(), "zcash.Transfer")
+		Expect(trace.SpanFromContext(ctx)).Should(Equal(otelTracer.spans[0]))
+
+		span.SetAttribute(libzec.AttributeTxID, "00ff")
+		span.End()
+		Expect(otelTracer.spans).Should(HaveLen(1))
+		Expect(otelTracer.spans[0].name).Should(Equal("zcash.Transfer"))
+		Expect(otelTracer.spans[0].attributes).Should(Equal([]attribute.KeyValue{attribute.String(libzec.AttributeTxID, "00ff")}))
+		Expect(otelTracer.spans[0].status).Should(Equal(codes.Unset))
+		Expect(otelTracer.spans[0].ended).Should(BeTrue())
+	})
+
+	It("should record errors and mark the span as failed", func() {
+		otelTracer := &recordingTracer{}
+		_, span := NewTracer(otelTracer).Start(context.Background(), "zcash.PublishTransaction")
+		err := errors.New("rejected")
+		span.RecordError(err)
+		span.End()
+		Expect(otelTracer.spans[0].errs).Should(Equal([]error{err}))
+		Expect(otelTracer.spans[0].status).Should(Equal(codes.Error))
+	})
+})
@@ -0,0 +1,177 @@
+package libzec
+
+import (
+	"context"
+	"encoding/asn1"
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/btcsuite/btcd/btcec"
+)
+
+// A PKCS11Module is a PKCS#11 library, such as the one shipped by an HSM
+// vendor, that has been loaded and initialized. OpenPKCS11Module loads a
+// library using github.com/miekg/pkcs11.
+type PKCS11Module interface {
+	// OpenSession opens a session on the given slot, and logs in as the user
+	// with the given pin.
+	OpenSession(slot uint, pin string) (PKCS11Session, error)
+}
+
+// A PKCS11Session is a logged in session of a PKCS#11 module. Sessions are not
+// safe for concurrent use.
+type PKCS11Session interface {
+	// FindKeyPair returns the handles of the private and public key objects
+	// with the given CKA_LABEL.
+	FindKeyPair(label string) (privKey, pubKey uint, err error)
+
+	// ECPoint returns the CKA_EC_POINT attribute of the public key object.
+	ECPoint(pubKey uint) ([]byte, error)
+
+	// Sign signs the digest with the private key using the CKM_ECDSA
+	// mechanism, and returns the signature as r || s.
+	Sign(privKey uint, digest []byte) ([]byte, error)
+
+	// Close closes the session. The user is logged out once every session of
+	// the module is closed.
+	Close() error
+}
+
+// PKCS11Signer is a signer that signs using a secp256k1 key stored in an HSM,
+// which is accessed over PKCS#11. If signing fails, the session is reopened
+// and the signature is retried once, so that signers survive HSM restarts.
+type PKCS11Signer struct {
+	module PKCS11Module
+	slot   uint
+	pin    string
+	label  string
+
+	mu      *sync.Mutex
+	session PKCS11Session
+	privKey uint
+	pubKey  *btcec.PublicKey
+}
+
+// NewPKCS11Signer opens a session on the given slot, and looks up the key with
+// the given label.
+func NewPKCS11Signer(module PKCS11Module, slot uint, pin, label string) (*PKCS11Signer, error) {
+	signer := &PKCS11Signer{
+		module: module,
+		slot:   slot,
+		pin:    pin,
+		label:  label,
+		mu:     new(sync.Mutex),
+	}
+	if err := signer.open(); err != nil {
+		return nil, err
+	}
+	return signer, nil
+}
+
+func (signer *PKCS11Signer) Sign(ctx context.Context, hashes [][]byte) ([]*btcec.Signature, error) {
+	signer.mu.Lock()
+	defer signer.mu.Unlock()
+
+	sigs := make([]*btcec.Signature, len(hashes))
+	for i, hash := range hashes {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		default:
+		}
+		if signer.session == nil {
+			if err := signer.open(); err != nil {
+				return nil, err
+			}
+		}
+		sig, err := signer.sign(hash)
+		if err != nil {
+			// The session is cleared before it is reopened, so that a failed
+			// reopen is retried by the next call instead of signing with, or
+			// closing again, the closed session.
+			signer.session.Close()
+			signer.session = nil
+			if err := signer.open(); err != nil {
+				return nil, err
+			}
+			if sig, err = signer.sign(hash); err != nil {
+				return nil, fmt.Errorf("cannot sign hash %d with the hsm: %v", i, err)
+			}
+		}
+		sigs[i] = sig
+	}
+	return sigs, nil
+}
+
+func (signer *PKCS11Signer) PublicKey() *btcec.PublicKey {
+	return signer.pubKey
+}
+
+// Close closes the session of the signer.
+func (signer *PKCS11Signer) Close() error {
+	signer.mu.Lock()
+	defer signer.mu.Unlock()
+	if signer.session == nil {
+		return nil
+	}
+	session := signer.session
+	signer.session = nil
+	return session.Close()
+}
+
+func (signer *PKCS11Signer) open() error {
+	session, err := signer.module.OpenSession(signer.slot, signer.pin)
+	if err != nil {
+		return fmt.Errorf("cannot open a session on slot %d: %v", signer.slot, err)
+	}
+	privKey, pubKeyHandle, err := session.FindKeyPair(signer.label)
+	if err != nil {
+		session.Close()
+		return fmt.Errorf("cannot find the key %q: %v", signer.label, err)
+	}
+	ecPoint, err := session.ECPoint(pubKeyHandle)
+	if err != nil {
+		session.Close()
+		return err
+	}
+
+	// CKA_EC_POINT is a DER encoded octet string, although some modules
+	// return the raw point. The raw point is parsed first, as an uncompressed
+	// point starts with the tag of an octet string.
+	pubKey, err := btcec.ParsePubKey(ecPoint, btcec.S256())
+	if err != nil {
+		point := []byte{}
+		if rest, derErr := asn1.Unmarshal(ecPoint, &point); derErr == nil && len(rest) == 0 {
+			pubKey, err = btcec.ParsePubKey(point, btcec.S256())
+		}
+	}
+	if err != nil {
+		session.Close()
+		return fmt.Errorf("cannot parse the public key of %q: %v", signer.label, err)
+	}
+	if signer.pubKey != nil && !signer.pubKey.IsEqual(pubKey) {
+		session.Close()
+		return fmt.Errorf("the key %q has changed", signer.label)
+	}
+
+	signer.session = session
+	signer.privKey = privKey
+	signer.pubKey = pubKey
+	return nil
+}
+
+func (signer *PKCS11Signer) sign(hash []byte) (*btcec.Signature, error) {
+	rs, err := signer.session.Sign(signer.privKey, hash)
+	if err != nil {
+		return nil, err
+	}
+	if len(rs) != 64 {
+		return nil, fmt.Errorf("invalid signature length: got: %d required: 64", len(rs))
+	}
+	sig := newLowSSignature(new(big.Int).SetBytes(rs[:32]), new(big.Int).SetBytes(rs[32:]))
+	if !sig.Verify(hash, signer.pubKey) {
+		return nil, fmt.Errorf("hsm returned an invalid signature")
+	}
+	return sig, nil
+}
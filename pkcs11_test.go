@@ -0,0 +1,132 @@
+package libzec_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+
+	"github.com/btcsuite/btcd/btcec"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/renproject/libzec-go"
+)
+
+// mockPKCS11Module is a PKCS#11 module holding a single key, that fails to
+// sign or to open sessions on demand.
+type mockPKCS11Module struct {
+	privKey  *btcec.PrivateKey
+	openErr  error
+	signErr  error
+	open     int
+	sessions []*mockPKCS11Session
+}
+
+func (module *mockPKCS11Module) OpenSession(slot uint, pin string) (PKCS11Session, error) {
+	if module.openErr != nil {
+		return nil, module.openErr
+	}
+	module.open++
+	session := &mockPKCS11Session{module: module}
+	module.sessions = append(module.sessions, session)
+	return session, nil
+}
+
+type mockPKCS11Session struct {
+	module *mockPKCS11Module
+	closed int
+}
+
+func (session *mockPKCS11Session) FindKeyPair(label string) (uint, uint, error) {
+	return 1, 2, nil
+}
+
+func (session *mockPKCS11Session) ECPoint(pubKey uint) ([]byte, error) {
+	return session.module.privKey.PubKey().SerializeUncompressed(), nil
+}
+
+func (session *mockPKCS11Session) Sign(privKey uint, digest []byte) ([]byte, error) {
+	if session.closed > 0 {
+		return nil, errors.New("session closed")
+	}
+	if session.module.signErr != nil {
+		return nil, session.module.signErr
+	}
+	sig, err := session.module.privKey.Sign(digest)
+	if err != nil {
+		return nil, err
+	}
+	r, s := sig.R.Bytes(), sig.S.Bytes()
+	rs := make([]byte, 64)
+	copy(rs[32-len(r):32], r)
+	copy(rs[64-len(s):], s)
+	return rs, nil
+}
+
+func (session *mockPKCS11Session) Close() error {
+	session.closed++
+	if session.closed == 1 {
+		session.module.open--
+	}
+	return nil
+}
+
+var _ = Describe("PKCS#11 signers", func() {
+	digest := sha256.Sum256([]byte("libzec"))
+
+	newModule := func() *mockPKCS11Module {
+		privKey, err := btcec.NewPrivateKey(btcec.S256())
+		Expect(err).Should(BeNil())
+		return &mockPKCS11Module{privKey: privKey}
+	}
+
+	It("should sign with the key of the hsm", func() {
+		module := newModule()
+		signer, err := NewPKCS11Signer(module, 0, "1234", "libzec")
+		Expect(err).Should(BeNil())
+		Expect(signer.PublicKey().IsEqual(module.privKey.PubKey())).Should(BeTrue())
+
+		sigs, err := signer.Sign(context.Background(), [][]byte{digest[:]})
+		Expect(err).Should(BeNil())
+		Expect(sigs[0].Verify(digest[:], module.privKey.PubKey())).Should(BeTrue())
+		Expect(signer.Close()).Should(BeNil())
+		Expect(module.open).Should(Equal(0))
+	})
+
+	It("should reopen the session when signing fails", func() {
+		module := newModule()
+		signer, err := NewPKCS11Signer(module, 0, "1234", "libzec")
+		Expect(err).Should(BeNil())
+		module.sessions[0].Close()
+
+		_, err = signer.Sign(context.Background(), [][]byte{digest[:]})
+		Expect(err).Should(BeNil())
+		Expect(module.sessions).Should(HaveLen(2))
+		Expect(module.open).Should(Equal(1))
+	})
+
+	It("should not reuse the closed session when reopening fails", func() {
+		module := newModule()
+		signer, err := NewPKCS11Signer(module, 0, "1234", "libzec")
+		Expect(err).Should(BeNil())
+
+		module.signErr = errors.New("device error")
+		module.openErr = errors.New("device unavailable")
+		_, err = signer.Sign(context.Background(), [][]byte{digest[:]})
+		Expect(err).ShouldNot(BeNil())
+		Expect(module.sessions[0].closed).Should(Equal(1))
+
+		// The next call opens a new session once the hsm is back, and the
+		// closed session is never closed again.
+		_, err = signer.Sign(context.Background(), [][]byte{digest[:]})
+		Expect(err).ShouldNot(BeNil())
+		module.signErr = nil
+		module.openErr = nil
+		_, err = signer.Sign(context.Background(), [][]byte{digest[:]})
+		Expect(err).Should(BeNil())
+		Expect(module.sessions).Should(HaveLen(2))
+		Expect(signer.Close()).Should(BeNil())
+		Expect(signer.Close()).Should(BeNil())
+		Expect(module.sessions[0].closed).Should(Equal(1))
+		Expect(module.sessions[1].closed).Should(Equal(1))
+	})
+})
@@ -0,0 +1,113 @@
+//go:build cgo
+// +build cgo
+
+package libzec
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/miekg/pkcs11"
+)
+
+type pkcs11Module struct {
+	ctx *pkcs11.Ctx
+}
+
+// OpenPKCS11Module loads and initializes the PKCS#11 library at the given
+// path. The returned closer finalizes and unloads the library, after every
+// signer using it has been closed. Loading a library requires cgo.
+func OpenPKCS11Module(path string) (PKCS11Module, io.Closer, error) {
+	ctx := pkcs11.New(path)
+	if ctx == nil {
+		return nil, nil, fmt.Errorf("cannot load the pkcs11 library %s", path)
+	}
+	if err := ctx.Initialize(); err != nil {
+		ctx.Destroy()
+		return nil, nil, fmt.Errorf("cannot initialize the pkcs11 library %s: %v", path, err)
+	}
+	module := &pkcs11Module{ctx}
+	return module, module, nil
+}
+
+func (module *pkcs11Module) OpenSession(slot uint, pin string) (PKCS11Session, error) {
+	handle, err := module.ctx.OpenSession(slot, pkcs11.CKF_SERIAL_SESSION)
+	if err != nil {
+		return nil, err
+	}
+	// The login state is shared by every session of the application, so the
+	// user is already logged in when other sessions are open.
+	if err := module.ctx.Login(handle, pkcs11.CKU_USER, pin); err != nil && err != pkcs11.Error(pkcs11.CKR_USER_ALREADY_LOGGED_IN) {
+		module.ctx.CloseSession(handle)
+		return nil, fmt.Errorf("cannot log in: %v", err)
+	}
+	return &pkcs11Session{module.ctx, handle}, nil
+}
+
+func (module *pkcs11Module) Close() error {
+	defer module.ctx.Destroy()
+	return module.ctx.Finalize()
+}
+
+type pkcs11Session struct {
+	ctx    *pkcs11.Ctx
+	handle pkcs11.SessionHandle
+}
+
+func (session *pkcs11Session) FindKeyPair(label string) (uint, uint, error) {
+	privKey, err := session.findObject(pkcs11.CKO_PRIVATE_KEY, label)
+	if err != nil {
+		return 0, 0, err
+	}
+	pubKey, err := session.findObject(pkcs11.CKO_PUBLIC_KEY, label)
+	if err != nil {
+		return 0, 0, err
+	}
+	return uint(privKey), uint(pubKey), nil
+}
+
+func (session *pkcs11Session) ECPoint(pubKey uint) ([]byte, error) {
+	attrs, err := session.ctx.GetAttributeValue(session.handle, pkcs11.ObjectHandle(pubKey), []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_EC_POINT, nil),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(attrs) != 1 {
+		return nil, fmt.Errorf("invalid number of attributes: got: %d required: 1", len(attrs))
+	}
+	return attrs[0].Value, nil
+}
+
+func (session *pkcs11Session) Sign(privKey uint, digest []byte) ([]byte, error) {
+	mechanism := []*pkcs11.Mechanism{pkcs11.NewMechanism(pkcs11.CKM_ECDSA, nil)}
+	if err := session.ctx.SignInit(session.handle, mechanism, pkcs11.ObjectHandle(privKey)); err != nil {
+		return nil, err
+	}
+	return session.ctx.Sign(session.handle, digest)
+}
+
+func (session *pkcs11Session) Close() error {
+	return session.ctx.CloseSession(session.handle)
+}
+
+// findObject returns the only object of the given class with the given label.
+func (session *pkcs11Session) findObject(class uint, label string) (pkcs11.ObjectHandle, error) {
+	if err := session.ctx.FindObjectsInit(session.handle, []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_CLASS, class),
+		pkcs11.NewAttribute(pkcs11.CKA_LABEL, label),
+	}); err != nil {
+		return 0, err
+	}
+	objects, _, err := session.ctx.FindObjects(session.handle, 2)
+	if finalErr := session.ctx.FindObjectsFinal(session.handle); err == nil {
+		err = finalErr
+	}
+	if err != nil {
+		return 0, err
+	}
+	if len(objects) != 1 {
+		return 0, fmt.Errorf("invalid number of objects labelled %q: got: %d required: 1", label, len(objects))
+	}
+	return objects[0], nil
+}
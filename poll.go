@@ -0,0 +1,98 @@
+package libzec
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/btcsuite/btcd/txscript"
+)
+
+// Intervals between the polls of the WaitForScript helpers. The interval
+// starts at MinPollInterval, and doubles after every poll up to
+// MaxPollInterval.
+const (
+	MinPollInterval = 5 * time.Second
+	MaxPollInterval = 2 * time.Minute
+)
+
+// ScriptSpend is the signature script of the transaction that spent a script.
+type ScriptSpend struct {
+	SigScript []byte
+
+	// Data is the data pushed by the signature script, such as the
+	// signature, the public key and the secret of a swap.
+	Data [][]byte
+}
+
+// WaitForScriptFunded polls until the address has received at least the given
+// value, and returns its balance.
+func (client *client) WaitForScriptFunded(ctx context.Context, address string, value int64) (int64, error) {
+	var balance int64
+	err := pollWithBackoff(ctx, func() (bool, error) {
+		funded, current, err := client.ScriptFunded(address, value)
+		balance = current
+		return funded, err
+	})
+	return balance, err
+}
+
+// WaitForScriptRedeemed polls until the address has received at least the
+// given value, and has been spent entirely.
+func (client *client) WaitForScriptRedeemed(ctx context.Context, address string, value int64) error {
+	return pollWithBackoff(ctx, func() (bool, error) {
+		redeemed, _, err := client.ScriptRedeemed(address, value)
+		return redeemed, err
+	})
+}
+
+// WaitForScriptSpent polls until the script is spent by the spender, and
+// returns the signature script of the spending transaction.
+func (client *client) WaitForScriptSpent(ctx context.Context, script, spender string) (ScriptSpend, error) {
+	var sigScript string
+	if err := pollWithBackoff(ctx, func() (bool, error) {
+		spent, current, err := client.ScriptSpent(script, spender)
+		sigScript = current
+		return spent, err
+	}); err != nil {
+		return ScriptSpend{}, err
+	}
+
+	spend := ScriptSpend{}
+	var err error
+	if spend.SigScript, err = hex.DecodeString(sigScript); err != nil {
+		return ScriptSpend{}, fmt.Errorf("cannot decode the signature script %s: %v", sigScript, err)
+	}
+	if spend.Data, err = txscript.PushedData(spend.SigScript); err != nil {
+		return ScriptSpend{}, err
+	}
+	return spend, nil
+}
+
+// pollWithBackoff calls f until it returns true, or the context is done.
+// Errors are treated as transient, and the last one is returned if the
+// context is done before the condition holds.
+func pollWithBackoff(ctx context.Context, f func() (bool, error)) error {
+	interval := MinPollInterval
+	for {
+		ok, err := f()
+		if err == nil && ok {
+			return nil
+		}
+
+		timer := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			if err != nil {
+				return fmt.Errorf("%v: %v", ctx.Err(), err)
+			}
+			return ctx.Err()
+		case <-timer.C:
+		}
+		if interval *= 2; interval > MaxPollInterval {
+			interval = MaxPollInterval
+		}
+	}
+}
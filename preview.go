@@ -0,0 +1,84 @@
+package libzec
+
+import (
+	"bytes"
+	"encoding/hex"
+
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/iqoption/zecutil"
+	"github.com/renproject/libzec-go/clients"
+)
+
+// maxSigPushSize is the size of the push of a DER encoded signature, with the
+// sighash type appended, in the worst case.
+const maxSigPushSize = 1 + 72 + 1
+
+// TxPreview describes what a transaction would do if it was signed and
+// broadcast.
+type TxPreview struct {
+	Inputs        []clients.UTXO `json:"inputs"`
+	Value         int64          `json:"value"`
+	Fee           int64          `json:"fee"`
+	Change        int64          `json:"change"`
+	EstimatedSize int            `json:"estimatedSize"`
+}
+
+// newTxPreview creates a preview of the unsigned transaction. The sigScripts
+// are the estimated sizes of the signature scripts of every input.
+func newTxPreview(msgTx *zecutil.MsgTx, amounts []int64, scriptPubKeys [][]byte, sigScripts []int, value, change int64) (TxPreview, error) {
+	preview := TxPreview{
+		Value:  value,
+		Change: change,
+	}
+
+	var amt int64
+	for i, txIn := range msgTx.TxIn {
+		amt += amounts[i]
+		preview.Inputs = append(preview.Inputs, clients.UTXO{
+			TxHash:       txIn.PreviousOutPoint.Hash.String(),
+			Amount:       amounts[i],
+			ScriptPubKey: hex.EncodeToString(scriptPubKeys[i]),
+			Vout:         txIn.PreviousOutPoint.Index,
+		})
+	}
+	for _, txOut := range msgTx.TxOut {
+		amt -= txOut.Value
+	}
+	preview.Fee = amt
+
+	buf := new(bytes.Buffer)
+	if err := msgTx.ZecEncode(buf, 0, wire.BaseEncoding); err != nil {
+		return preview, err
+	}
+	preview.EstimatedSize = buf.Len()
+	for i, txIn := range msgTx.TxIn {
+		preview.EstimatedSize += sigScripts[i] - len(txIn.SignatureScript) +
+			wire.VarIntSerializeSize(uint64(sigScripts[i])) - wire.VarIntSerializeSize(uint64(len(txIn.SignatureScript)))
+	}
+	return preview, nil
+}
+
+// estimateSigScriptSize estimates the size of a signature script that spends
+// an input signed by a single public key of the given size, with an optional
+// redeem script and the unlock script spending it.
+func estimateSigScriptSize(pubKeySize int, unlock, redeemScript []byte) int {
+	size := maxSigPushSize + 1 + pubKeySize
+	if redeemScript != nil {
+		size += len(unlock) + len(redeemScript) + pushDataOverhead(len(redeemScript))
+	}
+	return size
+}
+
+func pushDataOverhead(size int) int {
+	switch {
+	case size <= txscript.OP_DATA_75:
+		return 1
+	case size <= 0xff:
+		return 2
+	case size <= 0xffff:
+		return 3
+	default:
+		return 5
+	}
+}
@@ -0,0 +1,5 @@
+// Package proto contains the gRPC bindings of the remote signing service,
+// generated from signing.proto with protoc-gen-go v1.3.1.
+package proto
+
+//go:generate protoc --go_out=plugins=grpc,paths=source_relative:. signing.proto
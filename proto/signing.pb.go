@@ -0,0 +1,339 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// source: signing.proto
+
+package proto
+
+import (
+	context "context"
+	fmt "fmt"
+	proto "github.com/golang/protobuf/proto"
+	grpc "google.golang.org/grpc"
+	math "math"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.ProtoPackageIsVersion3 // please upgrade the proto package
+
+type PublicKeyRequest struct {
+	KeyId                string   `protobuf:"bytes,1,opt,name=key_id,json=keyId,proto3" json:"key_id,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *PublicKeyRequest) Reset()         { *m = PublicKeyRequest{} }
+func (m *PublicKeyRequest) String() string { return proto.CompactTextString(m) }
+func (*PublicKeyRequest) ProtoMessage()    {}
+func (*PublicKeyRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_3fb6a4dae14700bd, []int{0}
+}
+
+func (m *PublicKeyRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_PublicKeyRequest.Unmarshal(m, b)
+}
+func (m *PublicKeyRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_PublicKeyRequest.Marshal(b, m, deterministic)
+}
+func (m *PublicKeyRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_PublicKeyRequest.Merge(m, src)
+}
+func (m *PublicKeyRequest) XXX_Size() int {
+	return xxx_messageInfo_PublicKeyRequest.Size(m)
+}
+func (m *PublicKeyRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_PublicKeyRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_PublicKeyRequest proto.InternalMessageInfo
+
+func (m *PublicKeyRequest) GetKeyId() string {
+	if m != nil {
+		return m.KeyId
+	}
+	return ""
+}
+
+type PublicKeyResponse struct {
+	// The compressed secp256k1 public key.
+	PubKey               []byte   `protobuf:"bytes,1,opt,name=pub_key,json=pubKey,proto3" json:"pub_key,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *PublicKeyResponse) Reset()         { *m = PublicKeyResponse{} }
+func (m *PublicKeyResponse) String() string { return proto.CompactTextString(m) }
+func (*PublicKeyResponse) ProtoMessage()    {}
+func (*PublicKeyResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_3fb6a4dae14700bd, []int{1}
+}
+
+func (m *PublicKeyResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_PublicKeyResponse.Unmarshal(m, b)
+}
+func (m *PublicKeyResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_PublicKeyResponse.Marshal(b, m, deterministic)
+}
+func (m *PublicKeyResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_PublicKeyResponse.Merge(m, src)
+}
+func (m *PublicKeyResponse) XXX_Size() int {
+	return xxx_messageInfo_PublicKeyResponse.Size(m)
+}
+func (m *PublicKeyResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_PublicKeyResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_PublicKeyResponse proto.InternalMessageInfo
+
+func (m *PublicKeyResponse) GetPubKey() []byte {
+	if m != nil {
+		return m.PubKey
+	}
+	return nil
+}
+
+type SignRequest struct {
+	KeyId  string   `protobuf:"bytes,1,opt,name=key_id,json=keyId,proto3" json:"key_id,omitempty"`
+	Hashes [][]byte `protobuf:"bytes,2,rep,name=hashes,proto3" json:"hashes,omitempty"`
+	// Free form metadata, such as the transaction being signed, that the
+	// signing service can use for policy checks and auditing.
+	Metadata             map[string]string `protobuf:"bytes,3,rep,name=metadata,proto3" json:"metadata,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	XXX_NoUnkeyedLiteral struct{}          `json:"-"`
+	XXX_unrecognized     []byte            `json:"-"`
+	XXX_sizecache        int32             `json:"-"`
+}
+
+func (m *SignRequest) Reset()         { *m = SignRequest{} }
+func (m *SignRequest) String() string { return proto.CompactTextString(m) }
+func (*SignRequest) ProtoMessage()    {}
+func (*SignRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_3fb6a4dae14700bd, []int{2}
+}
+
+func (m *SignRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SignRequest.Unmarshal(m, b)
+}
+func (m *SignRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SignRequest.Marshal(b, m, deterministic)
+}
+func (m *SignRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SignRequest.Merge(m, src)
+}
+func (m *SignRequest) XXX_Size() int {
+	return xxx_messageInfo_SignRequest.Size(m)
+}
+func (m *SignRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_SignRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SignRequest proto.InternalMessageInfo
+
+func (m *SignRequest) GetKeyId() string {
+	if m != nil {
+		return m.KeyId
+	}
+	return ""
+}
+
+func (m *SignRequest) GetHashes() [][]byte {
+	if m != nil {
+		return m.Hashes
+	}
+	return nil
+}
+
+func (m *SignRequest) GetMetadata() map[string]string {
+	if m != nil {
+		return m.Metadata
+	}
+	return nil
+}
+
+type SignResponse struct {
+	// The DER encoded low-S signatures, in the same order as the hashes.
+	Signatures           [][]byte `protobuf:"bytes,1,rep,name=signatures,proto3" json:"signatures,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *SignResponse) Reset()         { *m = SignResponse{} }
+func (m *SignResponse) String() string { return proto.CompactTextString(m) }
+func (*SignResponse) ProtoMessage()    {}
+func (*SignResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_3fb6a4dae14700bd, []int{3}
+}
+
+func (m *SignResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SignResponse.Unmarshal(m, b)
+}
+func (m *SignResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SignResponse.Marshal(b, m, deterministic)
+}
+func (m *SignResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SignResponse.Merge(m, src)
+}
+func (m *SignResponse) XXX_Size() int {
+	return xxx_messageInfo_SignResponse.Size(m)
+}
+func (m *SignResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_SignResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SignResponse proto.InternalMessageInfo
+
+func (m *SignResponse) GetSignatures() [][]byte {
+	if m != nil {
+		return m.Signatures
+	}
+	return nil
+}
+
+func init() {
+	proto.RegisterType((*PublicKeyRequest)(nil), "libzec.PublicKeyRequest")
+	proto.RegisterType((*PublicKeyResponse)(nil), "libzec.PublicKeyResponse")
+	proto.RegisterType((*SignRequest)(nil), "libzec.SignRequest")
+	proto.RegisterMapType((map[string]string)(nil), "libzec.SignRequest.MetadataEntry")
+	proto.RegisterType((*SignResponse)(nil), "libzec.SignResponse")
+}
+
+func init() { proto.RegisterFile("signing.proto", fileDescriptor_3fb6a4dae14700bd) }
+
+var fileDescriptor_3fb6a4dae14700bd = []byte{
+	// 329 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x7c, 0x51, 0x4d, 0x4b, 0xc3, 0x40,
+	0x10, 0x25, 0x8d, 0x8d, 0x76, 0xda, 0x4a, 0x5d, 0xab, 0xc6, 0x1e, 0xa4, 0x06, 0xc4, 0x0a, 0x9a,
+	0x62, 0xbd, 0x88, 0x22, 0x88, 0xe0, 0x41, 0x8a, 0x20, 0xe9, 0xcd, 0x4b, 0x49, 0xd2, 0x21, 0x5d,
+	0xdb, 0x26, 0x71, 0x3f, 0x0a, 0xeb, 0xdd, 0xff, 0xe4, 0xcf, 0x93, 0x64, 0xdb, 0x92, 0x4a, 0xf1,
+	0x94, 0xcc, 0xcc, 0x7b, 0x6f, 0xde, 0xbc, 0x85, 0x3a, 0xa7, 0x51, 0x4c, 0xe3, 0xc8, 0x4d, 0x59,
+	0x22, 0x12, 0x62, 0x4d, 0x69, 0xf0, 0x85, 0xa1, 0x73, 0x01, 0x8d, 0x37, 0x19, 0x4c, 0x69, 0xd8,
+	0x47, 0xe5, 0xe1, 0xa7, 0x44, 0x2e, 0xc8, 0x01, 0x58, 0x13, 0x54, 0x43, 0x3a, 0xb2, 0x8d, 0xb6,
+	0xd1, 0xa9, 0x78, 0xe5, 0x09, 0xaa, 0x97, 0x91, 0x73, 0x09, 0x7b, 0x05, 0x28, 0x4f, 0x93, 0x98,
+	0x23, 0x39, 0x82, 0xed, 0x54, 0x06, 0xc3, 0x09, 0xaa, 0x1c, 0x5c, 0xf3, 0xac, 0x54, 0x06, 0x7d,
+	0x54, 0xce, 0x8f, 0x01, 0xd5, 0x01, 0x8d, 0xe2, 0xff, 0x45, 0xc9, 0x21, 0x58, 0x63, 0x9f, 0x8f,
+	0x91, 0xdb, 0xa5, 0xb6, 0x99, 0xd1, 0x75, 0x45, 0x1e, 0x60, 0x67, 0x86, 0xc2, 0x1f, 0xf9, 0xc2,
+	0xb7, 0xcd, 0xb6, 0xd9, 0xa9, 0xf6, 0x4e, 0x5d, 0x6d, 0xd9, 0x2d, 0xa8, 0xba, 0xaf, 0x0b, 0xcc,
+	0x73, 0x2c, 0x98, 0xf2, 0x56, 0x94, 0xd6, 0x3d, 0xd4, 0xd7, 0x46, 0xa4, 0x01, 0xe6, 0xd2, 0x63,
+	0xc5, 0xcb, 0x7e, 0x49, 0x13, 0xca, 0x73, 0x7f, 0x2a, 0xd1, 0x2e, 0x69, 0x3f, 0x79, 0x71, 0x57,
+	0xba, 0x35, 0x1c, 0x17, 0x6a, 0x7a, 0xc7, 0xe2, 0xc6, 0x13, 0x80, 0x2c, 0x3c, 0x5f, 0x48, 0x86,
+	0xdc, 0x36, 0x72, 0x9f, 0x85, 0x4e, 0xef, 0xdb, 0x80, 0xdd, 0x81, 0x4e, 0x77, 0x80, 0x6c, 0x4e,
+	0x43, 0x24, 0x8f, 0x50, 0x59, 0x65, 0x45, 0xec, 0xa5, 0xf3, 0xbf, 0x49, 0xb7, 0x8e, 0x37, 0x4c,
+	0x16, 0x4b, 0xaf, 0x61, 0x2b, 0xd3, 0x24, 0xfb, 0x1b, 0xce, 0x6e, 0x35, 0xd7, 0x9b, 0x9a, 0xf2,
+	0x74, 0xfe, 0x7e, 0x16, 0x51, 0x31, 0x96, 0x81, 0x1b, 0x26, 0xb3, 0x2e, 0xc3, 0x38, 0x65, 0xc9,
+	0x07, 0x86, 0xa2, 0xab, 0xc1, 0x57, 0x51, 0xd2, 0xcd, 0x1f, 0x3f, 0xb0, 0xf2, 0xcf, 0xcd, 0xef,
+	0x00, 0x99, 0xe3, 0x20, 0x6f, 0x14, 0x02, 0x00, 0x00,
+}
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ context.Context
+var _ grpc.ClientConn
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion4
+
+// SigningServiceClient is the client API for SigningService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type SigningServiceClient interface {
+	PublicKey(ctx context.Context, in *PublicKeyRequest, opts ...grpc.CallOption) (*PublicKeyResponse, error)
+	Sign(ctx context.Context, in *SignRequest, opts ...grpc.CallOption) (*SignResponse, error)
+}
+
+type signingServiceClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewSigningServiceClient(cc *grpc.ClientConn) SigningServiceClient {
+	return &signingServiceClient{cc}
+}
+
+func (c *signingServiceClient) PublicKey(ctx context.Context, in *PublicKeyRequest, opts ...grpc.CallOption) (*PublicKeyResponse, error) {
+	out := new(PublicKeyResponse)
+	err := c.cc.Invoke(ctx, "/libzec.SigningService/PublicKey", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *signingServiceClient) Sign(ctx context.Context, in *SignRequest, opts ...grpc.CallOption) (*SignResponse, error) {
+	out := new(SignResponse)
+	err := c.cc.Invoke(ctx, "/libzec.SigningService/Sign", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// SigningServiceServer is the server API for SigningService service.
+type SigningServiceServer interface {
+	PublicKey(context.Context, *PublicKeyRequest) (*PublicKeyResponse, error)
+	Sign(context.Context, *SignRequest) (*SignResponse, error)
+}
+
+func RegisterSigningServiceServer(s *grpc.Server, srv SigningServiceServer) {
+	s.RegisterService(&_SigningService_serviceDesc, srv)
+}
+
+func _SigningService_PublicKey_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PublicKeyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SigningServiceServer).PublicKey(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/libzec.SigningService/PublicKey",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SigningServiceServer).PublicKey(ctx, req.(*PublicKeyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SigningService_Sign_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SignRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SigningServiceServer).Sign(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/libzec.SigningService/Sign",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SigningServiceServer).Sign(ctx, req.(*SignRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var _SigningService_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "libzec.SigningService",
+	HandlerType: (*SigningServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "PublicKey",
+			Handler:    _SigningService_PublicKey_Handler,
+		},
+		{
+			MethodName: "Sign",
+			Handler:    _SigningService_Sign_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "signing.proto",
+}
@@ -0,0 +1,368 @@
+package libzec
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/iqoption/zecutil"
+	"github.com/renproject/libzec-go/clients"
+)
+
+// PSZT is a partially signed ZCash transaction. It is a container that can be
+// passed between the parties of a multi-party signing workflow: a creator adds
+// the inputs and outputs, each signer adds partial signatures for the inputs
+// it controls, and a finalizer assembles the signature scripts once enough
+// signatures have been collected. Adding inputs or outputs invalidates the
+// partial signatures collected so far.
+type PSZT struct {
+	msgTx    *zecutil.MsgTx
+	inputs   []psztInput
+	branchID *uint32
+}
+
+type psztInput struct {
+	txInput
+	partialSigs map[string][]byte
+}
+
+// NewPSZT returns an empty partially signed ZCash transaction, that expires at
+// the given height. It should be ZCashExpiryDelta blocks after the current
+// height of the chain.
+func NewPSZT(expiryHeight uint32) *PSZT {
+	return &PSZT{
+		msgTx: &zecutil.MsgTx{
+			MsgTx:        wire.NewMsgTx(versionSapling),
+			ExpiryHeight: expiryHeight,
+		},
+	}
+}
+
+// AddInput adds the given utxo as an input of the transaction. The redeem
+// script must be provided if the utxo is locked by a P2SH script, otherwise it
+// should be nil.
+func (pszt *PSZT) AddInput(utxo clients.UTXO, redeemScript []byte) error {
+	inputs, err := addInputs(pszt.msgTx, []clients.UTXO{utxo}, redeemScript, nil)
+	if err != nil {
+		return err
+	}
+	pszt.inputs = append(pszt.inputs, psztInput{txInput: inputs[0]})
+	pszt.resetSigs()
+	return nil
+}
+
+// AddOutput adds an output paying the given value to the given public key
+// script.
+func (pszt *PSZT) AddOutput(value int64, pkScript []byte) {
+	pszt.msgTx.AddTxOut(wire.NewTxOut(value, pkScript))
+	pszt.resetSigs()
+}
+
+// SetConsensusBranchID sets the consensus branch id that the signature hashes
+// commit to. It must be set before the transaction is signed, and setting it
+// invalidates the partial signatures collected so far.
+func (pszt *PSZT) SetConsensusBranchID(branchID uint32) {
+	pszt.branchID = &branchID
+	pszt.resetSigs()
+}
+
+// Hashes returns the signature hashes of every input of the transaction.
+func (pszt *PSZT) Hashes() ([][]byte, error) {
+	inputs := make([]txInput, len(pszt.inputs))
+	for i, input := range pszt.inputs {
+		inputs[i] = input.txInput
+	}
+	if pszt.branchID == nil {
+		return nil, errNoConsensusBranch
+	}
+	return inputHashes(pszt.msgTx, inputs, *pszt.branchID, txscript.SigHashAll)
+}
+
+// errNoConsensusBranch is returned when a PSZT is signed before its consensus
+// branch id is set.
+var errNoConsensusBranch = fmt.Errorf("consensus branch id is not set")
+
+// AddPartialSig adds the signature of the given serialized public key for the
+// input at the given index. The public key must be able to spend the input,
+// and the signature is verified against the input's signature hash before it
+// is added.
+func (pszt *PSZT) AddPartialSig(index int, pubKeyBytes []byte, sig *btcec.Signature) error {
+	if index < 0 || index >= len(pszt.inputs) {
+		return fmt.Errorf("invalid input index %d: transaction has %d inputs", index, len(pszt.inputs))
+	}
+	sigBytes := append(sig.Serialize(), byte(txscript.SigHashAll))
+	if err := pszt.verifyPartialSig(index, pubKeyBytes, sigBytes); err != nil {
+		return err
+	}
+	pszt.inputs[index].partialSigs[hex.EncodeToString(pubKeyBytes)] = sigBytes
+	return nil
+}
+
+// verifyPartialSig checks that the public key can spend the input at the given
+// index, and that the signature, with its sighash type appended, is a valid
+// SigHashAll signature of the input by the public key.
+func (pszt *PSZT) verifyPartialSig(index int, pubKeyBytes, sigBytes []byte) error {
+	input := pszt.inputs[index]
+	pubKey, err := btcec.ParsePubKey(pubKeyBytes, btcec.S256())
+	if err != nil {
+		return err
+	}
+	if !input.spendableBy(pubKeyBytes) {
+		return fmt.Errorf("public key %x cannot spend input %d", pubKeyBytes, index)
+	}
+	if len(sigBytes) == 0 || txscript.SigHashType(sigBytes[len(sigBytes)-1]) != txscript.SigHashAll {
+		return fmt.Errorf("invalid sighash type for input %d", index)
+	}
+	sig, err := btcec.ParseDERSignature(sigBytes[:len(sigBytes)-1], btcec.S256())
+	if err != nil {
+		return fmt.Errorf("invalid signature encoding for input %d: %v", index, err)
+	}
+	if pszt.branchID == nil {
+		return errNoConsensusBranch
+	}
+	hash, err := calcSignatureHash(input.subScript(), txscript.SigHashAll, pszt.msgTx, index, input.amount, branchSigHashKey(*pszt.branchID))
+	if err != nil {
+		return err
+	}
+	if !sig.Verify(hash, pubKey) {
+		return fmt.Errorf("invalid signature for input %d", index)
+	}
+	return nil
+}
+
+// IsComplete returns true if enough signatures have been collected to finalize
+// every input of the transaction.
+func (pszt *PSZT) IsComplete() bool {
+	for _, input := range pszt.inputs {
+		if _, err := input.sigScript(); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// Finalize assembles the signature scripts of every input from the collected
+// partial signatures, and returns the serialized transaction.
+func (pszt *PSZT) Finalize() ([]byte, error) {
+	for i, input := range pszt.inputs {
+		sigScript, err := input.sigScript()
+		if err != nil {
+			return nil, fmt.Errorf("cannot finalize input %d: %v", i, err)
+		}
+		pszt.msgTx.TxIn[i].SignatureScript = sigScript
+	}
+	buf := new(bytes.Buffer)
+	if err := pszt.msgTx.ZecEncode(buf, 0, wire.BaseEncoding); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (pszt *PSZT) resetSigs() {
+	for i := range pszt.inputs {
+		pszt.inputs[i].partialSigs = map[string][]byte{}
+	}
+}
+
+// spendableBy returns whether the serialized public key, or its hash, is
+// committed to by the script that locks the input: the P2PKH script of the
+// input, or its redeem script.
+func (input psztInput) spendableBy(pubKeyBytes []byte) bool {
+	ops, err := parseScript(input.subScript())
+	if err != nil {
+		return false
+	}
+	pubKeyHash := btcutil.Hash160(pubKeyBytes)
+	for _, op := range ops {
+		if op.push && (bytes.Equal(op.data, pubKeyBytes) || bytes.Equal(op.data, pubKeyHash)) {
+			return true
+		}
+	}
+	return false
+}
+
+func (input psztInput) sigScript() ([]byte, error) {
+	builder := txscript.NewScriptBuilder()
+	if txscript.GetScriptClass(input.redeemScript) == txscript.MultiSigTy {
+		pubKeys, err := txscript.PushedData(input.redeemScript)
+		if err != nil {
+			return nil, err
+		}
+		_, required, err := txscript.CalcMultiSigStats(input.redeemScript)
+		if err != nil {
+			return nil, err
+		}
+
+		// OP_CHECKMULTISIG pops an extra item from the stack, and expects the
+		// signatures to be in the same order as the public keys.
+		builder.AddOp(txscript.OP_0)
+		sigs := 0
+		for _, pubKey := range pubKeys {
+			if sigs == required {
+				break
+			}
+			if sig, ok := input.partialSigs[hex.EncodeToString(pubKey)]; ok {
+				builder.AddData(sig)
+				sigs++
+			}
+		}
+		if sigs < required {
+			return nil, fmt.Errorf("insufficient signatures: got: %d required: %d", sigs, required)
+		}
+		builder.AddData(input.redeemScript)
+		return builder.Script()
+	}
+
+	if len(input.partialSigs) != 1 {
+		return nil, fmt.Errorf("expected exactly one signature: got: %d", len(input.partialSigs))
+	}
+	for pubKey, sig := range input.partialSigs {
+		pubKeyBytes, err := hex.DecodeString(pubKey)
+		if err != nil {
+			return nil, err
+		}
+		builder.AddData(sig)
+		builder.AddData(pubKeyBytes)
+	}
+	if input.redeemScript != nil {
+		builder.AddData(input.redeemScript)
+	}
+	return builder.Script()
+}
+
+type psztJSON struct {
+	Version      int32            `json:"version"`
+	LockTime     uint32           `json:"lockTime"`
+	ExpiryHeight uint32           `json:"expiryHeight"`
+	BranchID     *uint32          `json:"branchId,omitempty"`
+	Inputs       []psztInputJSON  `json:"inputs"`
+	Outputs      []psztOutputJSON `json:"outputs"`
+}
+
+type psztInputJSON struct {
+	TxHash       string            `json:"txHash"`
+	Vout         uint32            `json:"vout"`
+	Sequence     uint32            `json:"sequence"`
+	Amount       int64             `json:"amount"`
+	ScriptPubKey string            `json:"scriptPubKey"`
+	RedeemScript string            `json:"redeemScript,omitempty"`
+	PartialSigs  map[string]string `json:"partialSigs,omitempty"`
+}
+
+type psztOutputJSON struct {
+	Value    int64  `json:"value"`
+	PkScript string `json:"pkScript"`
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+func (pszt *PSZT) MarshalJSON() ([]byte, error) {
+	val := psztJSON{
+		Version:      pszt.msgTx.Version,
+		LockTime:     pszt.msgTx.LockTime,
+		ExpiryHeight: pszt.msgTx.ExpiryHeight,
+		BranchID:     pszt.branchID,
+	}
+	for i, input := range pszt.inputs {
+		txIn := pszt.msgTx.TxIn[i]
+		sigs := map[string]string{}
+		for pubKey, sig := range input.partialSigs {
+			sigs[pubKey] = hex.EncodeToString(sig)
+		}
+		val.Inputs = append(val.Inputs, psztInputJSON{
+			TxHash:       txIn.PreviousOutPoint.Hash.String(),
+			Vout:         txIn.PreviousOutPoint.Index,
+			Sequence:     txIn.Sequence,
+			Amount:       input.amount,
+			ScriptPubKey: hex.EncodeToString(input.scriptPubKey),
+			RedeemScript: hex.EncodeToString(input.redeemScript),
+			PartialSigs:  sigs,
+		})
+	}
+	for _, txOut := range pszt.msgTx.TxOut {
+		val.Outputs = append(val.Outputs, psztOutputJSON{
+			Value:    txOut.Value,
+			PkScript: hex.EncodeToString(txOut.PkScript),
+		})
+	}
+	return json.Marshal(val)
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface. The partial
+// signatures are verified against the decoded transaction.
+func (pszt *PSZT) UnmarshalJSON(data []byte) error {
+	val := psztJSON{}
+	if err := json.Unmarshal(data, &val); err != nil {
+		return err
+	}
+
+	msgTx := &zecutil.MsgTx{
+		MsgTx:        wire.NewMsgTx(val.Version),
+		ExpiryHeight: val.ExpiryHeight,
+	}
+	msgTx.LockTime = val.LockTime
+	inputs := make([]psztInput, len(val.Inputs))
+	for i, input := range val.Inputs {
+		hash, err := chainhash.NewHashFromStr(input.TxHash)
+		if err != nil {
+			return err
+		}
+		txIn := wire.NewTxIn(wire.NewOutPoint(hash, input.Vout), []byte{}, [][]byte{})
+		txIn.Sequence = input.Sequence
+		msgTx.AddTxIn(txIn)
+
+		scriptPubKey, err := hex.DecodeString(input.ScriptPubKey)
+		if err != nil {
+			return err
+		}
+		var redeemScript []byte
+		if input.RedeemScript != "" {
+			if redeemScript, err = hex.DecodeString(input.RedeemScript); err != nil {
+				return err
+			}
+		}
+		sigs := map[string][]byte{}
+		for pubKey, sig := range input.PartialSigs {
+			sigBytes, err := hex.DecodeString(sig)
+			if err != nil {
+				return err
+			}
+			sigs[pubKey] = sigBytes
+		}
+		inputs[i] = psztInput{
+			txInput: txInput{
+				amount:       input.Amount,
+				scriptPubKey: scriptPubKey,
+				redeemScript: redeemScript,
+			},
+			partialSigs: sigs,
+		}
+	}
+	for _, output := range val.Outputs {
+		pkScript, err := hex.DecodeString(output.PkScript)
+		if err != nil {
+			return err
+		}
+		msgTx.AddTxOut(wire.NewTxOut(output.Value, pkScript))
+	}
+
+	decoded := &PSZT{msgTx: msgTx, inputs: inputs, branchID: val.BranchID}
+	for i, input := range inputs {
+		for pubKey, sig := range input.partialSigs {
+			pubKeyBytes, err := hex.DecodeString(pubKey)
+			if err != nil {
+				return err
+			}
+			if err := decoded.verifyPartialSig(i, pubKeyBytes, sig); err != nil {
+				return err
+			}
+		}
+	}
+	*pszt = *decoded
+	return nil
+}
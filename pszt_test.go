@@ -0,0 +1,140 @@
+package libzec_test
+
+import (
+	"encoding/hex"
+	"encoding/json"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcutil"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/renproject/libzec-go"
+	"github.com/renproject/libzec-go/clients"
+)
+
+var _ = Describe("Partially signed transactions", func() {
+	const branchID = uint32(0xc2d6d0b4)
+
+	p2pkhScript := func(privKey *btcec.PrivateKey) []byte {
+		addr, err := AddressFromHash160(toHash160(btcutil.Hash160(privKey.PubKey().SerializeCompressed())), &chaincfg.TestNet3Params, false)
+		Expect(err).Should(BeNil())
+		script, err := PayToAddrScript(addr)
+		Expect(err).Should(BeNil())
+		return script
+	}
+
+	newPSZT := func(scriptPubKey []byte, redeemScript []byte) *PSZT {
+		pszt := NewPSZT(1842440)
+		Expect(pszt.AddInput(clients.UTXO{
+			TxHash:       chainhash.Hash{1}.String(),
+			Vout:         0,
+			Amount:       20000,
+			ScriptPubKey: hex.EncodeToString(scriptPubKey),
+		}, redeemScript)).Should(BeNil())
+		pszt.AddOutput(10000, scriptPubKey)
+		pszt.SetConsensusBranchID(branchID)
+		return pszt
+	}
+
+	sign := func(pszt *PSZT, privKey *btcec.PrivateKey) *btcec.Signature {
+		hashes, err := pszt.Hashes()
+		Expect(err).Should(BeNil())
+		sig, err := privKey.Sign(hashes[0])
+		Expect(err).Should(BeNil())
+		return sig
+	}
+
+	It("should finalize a signed P2PKH input", func() {
+		privKey, err := btcec.NewPrivateKey(btcec.S256())
+		Expect(err).Should(BeNil())
+		scriptPubKey := p2pkhScript(privKey)
+		pszt := newPSZT(scriptPubKey, nil)
+		Expect(pszt.IsComplete()).Should(BeFalse())
+
+		Expect(pszt.AddPartialSig(0, privKey.PubKey().SerializeCompressed(), sign(pszt, privKey))).Should(BeNil())
+		Expect(pszt.IsComplete()).Should(BeTrue())
+		stx, err := pszt.Finalize()
+		Expect(err).Should(BeNil())
+
+		decoded, err := DecodeTransaction(stx)
+		Expect(err).Should(BeNil())
+		msgTx, err := decoded.MsgTx()
+		Expect(err).Should(BeNil())
+		Expect(VerifyScript(msgTx, 0, scriptPubKey, 20000, branchID)).Should(BeNil())
+	})
+
+	It("should reject the signature of a key that cannot spend the input", func() {
+		privKey, err := btcec.NewPrivateKey(btcec.S256())
+		Expect(err).Should(BeNil())
+		foreignKey, err := btcec.NewPrivateKey(btcec.S256())
+		Expect(err).Should(BeNil())
+		pszt := newPSZT(p2pkhScript(privKey), nil)
+		Expect(pszt.AddPartialSig(0, foreignKey.PubKey().SerializeCompressed(), sign(pszt, foreignKey))).ShouldNot(BeNil())
+		Expect(pszt.IsComplete()).Should(BeFalse())
+	})
+
+	It("should finalize a multisig input once enough signatures are collected", func() {
+		privKeys := make([]*btcec.PrivateKey, 3)
+		pubKeys := make([][]byte, 3)
+		for i := range privKeys {
+			var err error
+			privKeys[i], err = btcec.NewPrivateKey(btcec.S256())
+			Expect(err).Should(BeNil())
+			pubKeys[i] = privKeys[i].PubKey().SerializeCompressed()
+		}
+		redeemScript, err := MultisigScript(2, pubKeys)
+		Expect(err).Should(BeNil())
+		scriptPubKey, err := PayToScriptHashScript(redeemScript)
+		Expect(err).Should(BeNil())
+		pszt := newPSZT(scriptPubKey, redeemScript)
+
+		Expect(pszt.AddPartialSig(0, pubKeys[2], sign(pszt, privKeys[2]))).Should(BeNil())
+		Expect(pszt.IsComplete()).Should(BeFalse())
+		Expect(pszt.AddPartialSig(0, pubKeys[0], sign(pszt, privKeys[0]))).Should(BeNil())
+		Expect(pszt.IsComplete()).Should(BeTrue())
+		stx, err := pszt.Finalize()
+		Expect(err).Should(BeNil())
+
+		decoded, err := DecodeTransaction(stx)
+		Expect(err).Should(BeNil())
+		msgTx, err := decoded.MsgTx()
+		Expect(err).Should(BeNil())
+		Expect(VerifyScript(msgTx, 0, scriptPubKey, 20000, branchID)).Should(BeNil())
+	})
+
+	It("should round trip through JSON with its partial signatures", func() {
+		privKey, err := btcec.NewPrivateKey(btcec.S256())
+		Expect(err).Should(BeNil())
+		pszt := newPSZT(p2pkhScript(privKey), nil)
+		Expect(pszt.AddPartialSig(0, privKey.PubKey().SerializeCompressed(), sign(pszt, privKey))).Should(BeNil())
+		data, err := json.Marshal(pszt)
+		Expect(err).Should(BeNil())
+
+		decoded := &PSZT{}
+		Expect(json.Unmarshal(data, decoded)).Should(BeNil())
+		Expect(decoded.IsComplete()).Should(BeTrue())
+		stx, err := pszt.Finalize()
+		Expect(err).Should(BeNil())
+		decodedStx, err := decoded.Finalize()
+		Expect(err).Should(BeNil())
+		Expect(decodedStx).Should(Equal(stx))
+	})
+
+	It("should reject partial signatures that do not sign the decoded transaction", func() {
+		privKey, err := btcec.NewPrivateKey(btcec.S256())
+		Expect(err).Should(BeNil())
+		pszt := newPSZT(p2pkhScript(privKey), nil)
+		Expect(pszt.AddPartialSig(0, privKey.PubKey().SerializeCompressed(), sign(pszt, privKey))).Should(BeNil())
+		data, err := json.Marshal(pszt)
+		Expect(err).Should(BeNil())
+
+		val := map[string]interface{}{}
+		Expect(json.Unmarshal(data, &val)).Should(BeNil())
+		val["outputs"].([]interface{})[0].(map[string]interface{})["value"] = 15000
+		tampered, err := json.Marshal(val)
+		Expect(err).Should(BeNil())
+		Expect(json.Unmarshal(tampered, &PSZT{})).ShouldNot(BeNil())
+	})
+})
@@ -0,0 +1,26 @@
+package libzec
+
+import (
+	"encoding/hex"
+	"errors"
+)
+
+// PublishTransaction publishes the signed transaction. Publishing a
+// transaction that is already in the mempool or in the blockchain succeeds, so
+// that a transaction can safely be published again after a timeout. The
+// rejection is only ignored if the transaction can be found by its txid, or if
+// the backend does not support looking up transactions.
+func (client *client) PublishTransaction(stx []byte) error {
+	err := client.ClientCore.PublishTransaction(stx)
+	if err == nil || !errors.Is(err, ErrAlreadyInMempool) {
+		return err
+	}
+	txID, idErr := TxID(stx)
+	if idErr != nil {
+		return err
+	}
+	if _, confErr := client.Confirmations(hex.EncodeToString(txID)); confErr != nil && confErr != ErrNotSupported {
+		return err
+	}
+	return nil
+}
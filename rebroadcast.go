@@ -0,0 +1,174 @@
+package libzec
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// A Rebroadcaster publishes the pending transactions of a tx store again
+// until they are mined, so that a transaction evicted from the mempool, or
+// never relayed, does not leave a payment stranded. A transaction is only
+// published again if the client cannot find it, and the delay between two
+// attempts doubles after every attempt, up to a maximum. Transactions are no
+// longer rebroadcast once they are confirmed, or once they expire.
+type Rebroadcaster interface {
+	// Poll checks every pending transaction of the store that is due, and
+	// publishes again those that cannot be found.
+	Poll()
+
+	// Run polls the pending transactions at the given interval, until the
+	// context is done.
+	Run(ctx context.Context, interval time.Duration)
+}
+
+type rebroadcastState struct {
+	delay       time.Duration
+	nextAttempt time.Time
+}
+
+type rebroadcaster struct {
+	client             Client
+	store              TxStore
+	minDelay, maxDelay time.Duration
+	logger             logrus.FieldLogger
+
+	mu     *sync.Mutex
+	states map[string]*rebroadcastState
+}
+
+// NewRebroadcaster returns a Rebroadcaster of the pending transactions of the
+// store. A missing transaction is published again after the minimum delay, and
+// the delay doubles after every attempt up to the maximum delay.
+func NewRebroadcaster(client Client, store TxStore, minDelay, maxDelay time.Duration, logger logrus.FieldLogger) Rebroadcaster {
+	return &rebroadcaster{
+		client:   client,
+		store:    store,
+		minDelay: minDelay,
+		maxDelay: maxDelay,
+		logger:   defaultLogger(logger),
+		mu:       new(sync.Mutex),
+		states:   map[string]*rebroadcastState{},
+	}
+}
+
+func (rebroadcaster *rebroadcaster) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		rebroadcaster.Poll()
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (rebroadcaster *rebroadcaster) Poll() {
+	txs, err := rebroadcaster.store.All()
+	if err != nil {
+		rebroadcaster.logger.Infof("cannot load the pending transactions: %v", err)
+		return
+	}
+
+	// The height is only fetched once per poll, when a missing transaction
+	// needs to be checked for expiry.
+	var height int64
+	hasHeight := false
+
+	now := time.Now()
+	for _, tx := range txs {
+		if tx.State != TxStatePending {
+			rebroadcaster.forget(tx.TxHash)
+			continue
+		}
+		state := rebroadcaster.state(tx.TxHash, tx.BroadcastAt)
+		if now.Before(state.nextAttempt) {
+			continue
+		}
+
+		conf, err := rebroadcaster.client.Confirmations(tx.TxHash)
+		if err != nil && !errors.Is(err, ErrTxNotFound) {
+			rebroadcaster.logger.Infof("cannot get the confirmations of %s: %v", tx.TxHash, err)
+			continue
+		}
+		if err == nil && conf > 0 {
+			rebroadcaster.finish(tx, TxStateConfirmed)
+			continue
+		}
+		if err == nil {
+			// The transaction is still in the mempool.
+			rebroadcaster.backOff(state, now)
+			continue
+		}
+
+		if tx.ExpiryHeight != 0 && !hasHeight {
+			if height, err = rebroadcaster.client.BlockHeight(); err != nil && err != ErrNotSupported {
+				rebroadcaster.logger.Infof("cannot get the block height: %v", err)
+				continue
+			}
+			hasHeight = err == nil
+		}
+		if hasHeight && expired(tx.ExpiryHeight, height) {
+			rebroadcaster.finish(tx, TxStateExpired)
+			continue
+		}
+
+		if err := rebroadcaster.client.PublishTransaction(tx.Stx); err != nil {
+			rebroadcaster.logger.Infof("cannot rebroadcast %s: %v", tx.TxHash, err)
+		} else {
+			rebroadcaster.logger.Infof("rebroadcast %s", tx.TxHash)
+		}
+		rebroadcaster.backOff(state, now)
+	}
+}
+
+// state returns the rebroadcast state of the transaction. The first attempt
+// is due the minimum delay after the transaction was broadcast.
+func (rebroadcaster *rebroadcaster) state(txHash string, broadcastAt time.Time) *rebroadcastState {
+	rebroadcaster.mu.Lock()
+	defer rebroadcaster.mu.Unlock()
+	state, ok := rebroadcaster.states[txHash]
+	if !ok {
+		state = &rebroadcastState{
+			delay:       rebroadcaster.minDelay,
+			nextAttempt: broadcastAt.Add(rebroadcaster.minDelay),
+		}
+		rebroadcaster.states[txHash] = state
+	}
+	return state
+}
+
+// backOff schedules the next attempt, and doubles the delay up to the maximum
+// delay.
+func (rebroadcaster *rebroadcaster) backOff(state *rebroadcastState, now time.Time) {
+	rebroadcaster.mu.Lock()
+	defer rebroadcaster.mu.Unlock()
+	state.nextAttempt = now.Add(state.delay)
+	state.delay *= 2
+	if state.delay > rebroadcaster.maxDelay {
+		state.delay = rebroadcaster.maxDelay
+	}
+}
+
+// finish records the final state of the transaction in the store, and stops
+// rebroadcasting it.
+func (rebroadcaster *rebroadcaster) finish(tx StoredTx, state TxState) {
+	tx.State = state
+	if err := rebroadcaster.store.Put(tx); err != nil {
+		rebroadcaster.logger.Infof("cannot update %s: %v", tx.TxHash, err)
+		return
+	}
+	rebroadcaster.logger.Infof("transaction %s is %v", tx.TxHash, state)
+	rebroadcaster.forget(tx.TxHash)
+}
+
+func (rebroadcaster *rebroadcaster) forget(txHash string) {
+	rebroadcaster.mu.Lock()
+	defer rebroadcaster.mu.Unlock()
+	delete(rebroadcaster.states, txHash)
+}
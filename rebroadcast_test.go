@@ -0,0 +1,94 @@
+package libzec_test
+
+import (
+	"context"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/renproject/libzec-go"
+	"github.com/sirupsen/logrus"
+)
+
+var _ = Describe("Rebroadcasts", func() {
+	// transfer publishes a transfer recorded in the returned tx store.
+	transfer := func(core *mockClientCore) (TxStore, TxReceipt) {
+		account, _, _ := newMockAccount(core, 1000000)
+		store := NewMemoryTxStore()
+		account.SetTxStore(store)
+		_, _, to := newMockAccount(core, 0)
+		receipt, err := account.Transfer(context.Background(), to.EncodeAddress(), 100000, Standard, false)
+		Expect(err).Should(BeNil())
+		Expect(core.published).Should(HaveLen(1))
+		return store, receipt
+	}
+
+	expectState := func(store TxStore, txHash string, state TxState) {
+		tx, ok, err := store.Get(txHash)
+		Expect(err).Should(BeNil())
+		Expect(ok).Should(BeTrue())
+		Expect(tx.State).Should(Equal(state))
+	}
+
+	It("should publish missing transactions again until they are mined", func() {
+		core := newMockClientCore(&chaincfg.TestNet3Params, 1842420)
+		store, receipt := transfer(core)
+		rebroadcaster := NewRebroadcaster(NewClient(core), store, 0, 0, logrus.StandardLogger())
+
+		rebroadcaster.Poll()
+		Expect(core.published).Should(HaveLen(2))
+		Expect(core.published[1]).Should(Equal(core.published[0]))
+
+		// Transactions in the mempool are not published again.
+		core.confirmations[receipt.TxHash] = 0
+		rebroadcaster.Poll()
+		Expect(core.published).Should(HaveLen(2))
+		expectState(store, receipt.TxHash, TxStatePending)
+
+		core.confirmations[receipt.TxHash] = 1
+		rebroadcaster.Poll()
+		Expect(core.published).Should(HaveLen(2))
+		expectState(store, receipt.TxHash, TxStateConfirmed)
+	})
+
+	It("should stop publishing transactions at their expiry height", func() {
+		core := newMockClientCore(&chaincfg.TestNet3Params, 1842420)
+		store, receipt := transfer(core)
+		Expect(receipt.ExpiryHeight).Should(Equal(uint32(1842440)))
+		rebroadcaster := NewRebroadcaster(NewClient(core), store, 0, 0, logrus.StandardLogger())
+
+		core.setHeight(1842439)
+		rebroadcaster.Poll()
+		Expect(core.published).Should(HaveLen(2))
+		expectState(store, receipt.TxHash, TxStatePending)
+
+		core.setHeight(1842440)
+		rebroadcaster.Poll()
+		Expect(core.published).Should(HaveLen(2))
+		expectState(store, receipt.TxHash, TxStateExpired)
+	})
+
+	It("should double the delay between attempts up to the maximum delay", func() {
+		core := newMockClientCore(&chaincfg.TestNet3Params, 1842420)
+		store, _ := transfer(core)
+		delay := 100 * time.Millisecond
+		rebroadcaster := NewRebroadcaster(NewClient(core), store, delay, 2*delay, logrus.StandardLogger())
+
+		// pollAfter polls once the duration has passed, and returns the
+		// number of times the transaction has been rebroadcast.
+		pollAfter := func(d time.Duration) int {
+			time.Sleep(d)
+			rebroadcaster.Poll()
+			return len(core.published) - 1
+		}
+		Expect(pollAfter(0)).Should(Equal(0))
+		Expect(pollAfter(delay * 3 / 2)).Should(Equal(1))
+		Expect(pollAfter(delay / 2)).Should(Equal(1))
+		Expect(pollAfter(delay)).Should(Equal(2))
+		Expect(pollAfter(delay * 3 / 2)).Should(Equal(2))
+		Expect(pollAfter(delay)).Should(Equal(3))
+		Expect(pollAfter(delay * 3 / 2)).Should(Equal(3))
+		Expect(pollAfter(delay)).Should(Equal(4))
+	})
+})
@@ -0,0 +1,221 @@
+package libzec
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+)
+
+// A SlaveEntry maps a master public key hash and a nonce to the address of
+// their slave script.
+type SlaveEntry struct {
+	MPKH    []byte `json:"mpkh"`
+	Nonce   []byte `json:"nonce"`
+	Address string `json:"address"`
+}
+
+// SlaveStatus is the on-chain status of a registered slave address. Funded is
+// true if the address has ever received funds, and Spent is true if it has
+// received funds and has no balance left.
+type SlaveStatus struct {
+	SlaveEntry
+	Funded  bool  `json:"funded"`
+	Spent   bool  `json:"spent"`
+	Balance int64 `json:"balance"`
+	Err     error `json:"-"`
+}
+
+// A SlaveStore persists the entries of a slave registry.
+type SlaveStore interface {
+	Put(entry SlaveEntry) error
+	Get(mpkh, nonce []byte) (SlaveEntry, bool, error)
+	All() ([]SlaveEntry, error)
+}
+
+// SlaveRegistry keeps track of the slave addresses that have been handed out,
+// so that they can be monitored and swept.
+type SlaveRegistry interface {
+	// Register creates the slave address of the master public key hash and
+	// nonce, and stores it in the registry.
+	Register(mpkh, nonce []byte) (string, error)
+
+	// Lookup returns the registered entry of the master public key hash and
+	// nonce.
+	Lookup(mpkh, nonce []byte) (SlaveEntry, error)
+
+	// Scan fetches the status of every registered slave address, using at
+	// most the given number of concurrent requests. Errors of individual
+	// addresses are reported in their status.
+	Scan(ctx context.Context, concurrency int) ([]SlaveStatus, error)
+}
+
+type slaveRegistry struct {
+	client Client
+	store  SlaveStore
+}
+
+// NewSlaveRegistry returns a slave registry that creates slave addresses using
+// the client, and persists them in the store.
+func NewSlaveRegistry(client Client, store SlaveStore) SlaveRegistry {
+	return &slaveRegistry{client, store}
+}
+
+func (registry *slaveRegistry) Register(mpkh, nonce []byte) (string, error) {
+	address, err := registry.client.SlaveAddress(mpkh, nonce)
+	if err != nil {
+		return "", err
+	}
+	if address == nil {
+		return "", fmt.Errorf("cannot create slave address for nonce %x", nonce)
+	}
+	entry := SlaveEntry{
+		MPKH:    mpkh,
+		Nonce:   nonce,
+		Address: address.EncodeAddress(),
+	}
+	if err := registry.store.Put(entry); err != nil {
+		return "", err
+	}
+	return entry.Address, nil
+}
+
+func (registry *slaveRegistry) Lookup(mpkh, nonce []byte) (SlaveEntry, error) {
+	entry, ok, err := registry.store.Get(mpkh, nonce)
+	if err != nil {
+		return SlaveEntry{}, err
+	}
+	if !ok {
+		return SlaveEntry{}, fmt.Errorf("slave address for mpkh %x and nonce %x is not registered", mpkh, nonce)
+	}
+	return entry, nil
+}
+
+func (registry *slaveRegistry) Scan(ctx context.Context, concurrency int) ([]SlaveStatus, error) {
+	entries, err := registry.store.All()
+	if err != nil {
+		return nil, err
+	}
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	statuses := make([]SlaveStatus, len(entries))
+	sem := make(chan struct{}, concurrency)
+	wg := new(sync.WaitGroup)
+	for i, entry := range entries {
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			return nil, ctx.Err()
+		case sem <- struct{}{}:
+		}
+		wg.Add(1)
+		go func(i int, entry SlaveEntry) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			statuses[i] = slaveStatus(registry.client, entry)
+		}(i, entry)
+	}
+	wg.Wait()
+	return statuses, nil
+}
+
+type memorySlaveStore struct {
+	mu      *sync.RWMutex
+	entries map[string]SlaveEntry
+}
+
+// NewMemorySlaveStore returns a slave store that keeps the entries in memory.
+func NewMemorySlaveStore() SlaveStore {
+	return &memorySlaveStore{
+		mu:      new(sync.RWMutex),
+		entries: map[string]SlaveEntry{},
+	}
+}
+
+func (store *memorySlaveStore) Put(entry SlaveEntry) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	store.entries[slaveKey(entry.MPKH, entry.Nonce)] = entry
+	return nil
+}
+
+func (store *memorySlaveStore) Get(mpkh, nonce []byte) (SlaveEntry, bool, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+	entry, ok := store.entries[slaveKey(mpkh, nonce)]
+	return entry, ok, nil
+}
+
+func (store *memorySlaveStore) All() ([]SlaveEntry, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+	entries := make([]SlaveEntry, 0, len(store.entries))
+	for _, entry := range store.entries {
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+type fileSlaveStore struct {
+	*memorySlaveStore
+	path    string
+	writeMu *sync.Mutex
+}
+
+// NewFileSlaveStore returns a slave store that persists the entries as JSON in
+// the file at the given path. The file is created if it does not exist.
+func NewFileSlaveStore(path string) (SlaveStore, error) {
+	store := &fileSlaveStore{
+		memorySlaveStore: NewMemorySlaveStore().(*memorySlaveStore),
+		path:             path,
+		writeMu:          new(sync.Mutex),
+	}
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	entries := []SlaveEntry{}
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("cannot decode slave store %s: %v", path, err)
+	}
+	for _, entry := range entries {
+		store.entries[slaveKey(entry.MPKH, entry.Nonce)] = entry
+	}
+	return store, nil
+}
+
+func (store *fileSlaveStore) Put(entry SlaveEntry) error {
+	store.writeMu.Lock()
+	defer store.writeMu.Unlock()
+	if err := store.memorySlaveStore.Put(entry); err != nil {
+		return err
+	}
+	entries, err := store.All()
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return err
+	}
+
+	// Write to a temporary file first, so that the store is not corrupted
+	// if the process crashes while writing.
+	tmp := store.path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, store.path)
+}
+
+func slaveKey(mpkh, nonce []byte) string {
+	return hex.EncodeToString(mpkh) + ":" + hex.EncodeToString(nonce)
+}
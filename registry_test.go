@@ -0,0 +1,180 @@
+package libzec_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/renproject/libzec-go"
+	"github.com/renproject/libzec-go/errors"
+)
+
+// fundedMockClientCore reports the received values and balances of the script
+// addresses, and records the number of concurrent queries.
+type fundedMockClientCore struct {
+	*mockClientCore
+	balances map[string]int64
+	received map[string]int64
+
+	queryMu  *sync.Mutex
+	inFlight int
+	maxQuery int
+}
+
+func (core *fundedMockClientCore) ScriptFunded(address string, value int64) (bool, int64, error) {
+	core.queryMu.Lock()
+	core.inFlight++
+	if core.inFlight > core.maxQuery {
+		core.maxQuery = core.inFlight
+	}
+	core.queryMu.Unlock()
+	time.Sleep(10 * time.Millisecond)
+	core.queryMu.Lock()
+	core.inFlight--
+	core.queryMu.Unlock()
+
+	received, ok := core.received[address]
+	if !ok {
+		return false, 0, errors.ErrTxNotFound
+	}
+	return received >= value, core.balances[address], nil
+}
+
+var _ = Describe("Slave registries", func() {
+	mpkh := make([]byte, 20)
+	nonce := func(i byte) []byte {
+		return SlaveNonce([]byte("user"), uint64(i))
+	}
+
+	newRegistry := func(store SlaveStore) (SlaveRegistry, Client, *fundedMockClientCore) {
+		core := &fundedMockClientCore{
+			mockClientCore: newMockClientCore(&chaincfg.TestNet3Params, 1842420),
+			balances:       map[string]int64{},
+			received:       map[string]int64{},
+			queryMu:        new(sync.Mutex),
+		}
+		client := NewClient(core)
+		return NewSlaveRegistry(client, store), client, core
+	}
+
+	It("should register and look up slave addresses", func() {
+		registry, client, _ := newRegistry(NewMemorySlaveStore())
+		address, err := registry.Register(mpkh, nonce(0))
+		Expect(err).Should(BeNil())
+		expected, err := client.SlaveAddress(mpkh, nonce(0))
+		Expect(err).Should(BeNil())
+		Expect(address).Should(Equal(expected.EncodeAddress()))
+
+		entry, err := registry.Lookup(mpkh, nonce(0))
+		Expect(err).Should(BeNil())
+		Expect(entry).Should(Equal(SlaveEntry{MPKH: mpkh, Nonce: nonce(0), Address: address}))
+
+		_, err = registry.Lookup(mpkh, nonce(1))
+		Expect(err).ShouldNot(BeNil())
+	})
+
+	It("should persist the entries of a file store", func() {
+		dir, err := ioutil.TempDir("", "slaves")
+		Expect(err).Should(BeNil())
+		defer os.RemoveAll(dir)
+		path := filepath.Join(dir, "slaves.json")
+
+		store, err := NewFileSlaveStore(path)
+		Expect(err).Should(BeNil())
+		registry, _, _ := newRegistry(store)
+		addresses := []string{}
+		for i := byte(0); i < 3; i++ {
+			address, err := registry.Register(mpkh, nonce(i))
+			Expect(err).Should(BeNil())
+			addresses = append(addresses, address)
+		}
+
+		reopened, err := NewFileSlaveStore(path)
+		Expect(err).Should(BeNil())
+		entries, err := reopened.All()
+		Expect(err).Should(BeNil())
+		Expect(entries).Should(HaveLen(3))
+		for i := byte(0); i < 3; i++ {
+			entry, ok, err := reopened.Get(mpkh, nonce(i))
+			Expect(err).Should(BeNil())
+			Expect(ok).Should(BeTrue())
+			Expect(entry.Address).Should(Equal(addresses[i]))
+		}
+	})
+
+	It("should not open a corrupted file store", func() {
+		dir, err := ioutil.TempDir("", "slaves")
+		Expect(err).Should(BeNil())
+		defer os.RemoveAll(dir)
+		path := filepath.Join(dir, "slaves.json")
+		Expect(ioutil.WriteFile(path, []byte("{"), 0600)).Should(BeNil())
+
+		_, err = NewFileSlaveStore(path)
+		Expect(err).ShouldNot(BeNil())
+	})
+
+	It("should scan the status of every slave address", func() {
+		registry, _, core := newRegistry(NewMemorySlaveStore())
+		funded, err := registry.Register(mpkh, nonce(0))
+		Expect(err).Should(BeNil())
+		spent, err := registry.Register(mpkh, nonce(1))
+		Expect(err).Should(BeNil())
+		unknown, err := registry.Register(mpkh, nonce(2))
+		Expect(err).Should(BeNil())
+		core.received[funded] = 50000
+		core.balances[funded] = 50000
+		core.received[spent] = 50000
+
+		statuses, err := registry.Scan(context.Background(), 2)
+		Expect(err).Should(BeNil())
+		Expect(statuses).Should(HaveLen(3))
+		byAddress := map[string]SlaveStatus{}
+		for _, status := range statuses {
+			byAddress[status.Address] = status
+		}
+
+		Expect(byAddress[funded].Err).Should(BeNil())
+		Expect(byAddress[funded].Funded).Should(BeTrue())
+		Expect(byAddress[funded].Spent).Should(BeFalse())
+		Expect(byAddress[funded].Balance).Should(Equal(int64(50000)))
+
+		Expect(byAddress[spent].Err).Should(BeNil())
+		Expect(byAddress[spent].Funded).Should(BeTrue())
+		Expect(byAddress[spent].Spent).Should(BeTrue())
+
+		Expect(byAddress[unknown].Err).ShouldNot(BeNil())
+	})
+
+	It("should limit the number of concurrent queries of a scan", func() {
+		registry, _, core := newRegistry(NewMemorySlaveStore())
+		for i := byte(0); i < 8; i++ {
+			_, err := registry.Register(mpkh, nonce(i))
+			Expect(err).Should(BeNil())
+		}
+
+		statuses, err := registry.Scan(context.Background(), 3)
+		Expect(err).Should(BeNil())
+		Expect(statuses).Should(HaveLen(8))
+		Expect(core.maxQuery).Should(BeNumerically("<=", 3))
+		Expect(core.maxQuery).Should(BeNumerically(">", 1))
+	})
+
+	It("should stop scanning when the context is cancelled", func() {
+		registry, _, _ := newRegistry(NewMemorySlaveStore())
+		for i := byte(0); i < 4; i++ {
+			_, err := registry.Register(mpkh, nonce(i))
+			Expect(err).Should(BeNil())
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err := registry.Scan(ctx, 1)
+		Expect(err).Should(Equal(context.Canceled))
+	})
+})
@@ -0,0 +1,198 @@
+package libzec
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/btcsuite/btcd/btcec"
+	pb "github.com/renproject/libzec-go/proto"
+	"google.golang.org/grpc"
+)
+
+// PublicKeyRequest requests the public key of a remote signer. The messages of
+// the remote signing protocol are defined in proto/signing.proto.
+type PublicKeyRequest struct {
+	KeyID string `json:"keyID"`
+}
+
+// PublicKeyResponse contains the compressed public key of a remote signer.
+type PublicKeyResponse struct {
+	PubKey []byte `json:"pubKey"`
+}
+
+// SignRequest requests signatures of the given hashes from a remote signer.
+type SignRequest struct {
+	KeyID    string            `json:"keyID"`
+	Hashes   [][]byte          `json:"hashes"`
+	Metadata map[string]string `json:"metadata"`
+}
+
+// SignResponse contains the DER encoded signatures of the requested hashes, in
+// the same order as the hashes.
+type SignResponse struct {
+	Signatures [][]byte `json:"signatures"`
+}
+
+// SigningService is the remote signing service. It is implemented by the
+// server returned by NewSigningServer, which is served over gRPC using
+// RegisterSigningServer, and by the gRPC client of the service returned by
+// NewGRPCSigningService.
+type SigningService interface {
+	PublicKey(ctx context.Context, req *PublicKeyRequest) (*PublicKeyResponse, error)
+	Sign(ctx context.Context, req *SignRequest) (*SignResponse, error)
+}
+
+type remoteSigner struct {
+	service  SigningService
+	keyID    string
+	metadata map[string]string
+	pubKey   *btcec.PublicKey
+}
+
+// NewRemoteSigner returns a signer that signs using the key with the given id
+// of the remote signing service. The metadata is sent with every request. The
+// public key is fetched once, when the signer is created.
+func NewRemoteSigner(ctx context.Context, service SigningService, keyID string, metadata map[string]string) (Signer, error) {
+	res, err := service.PublicKey(ctx, &PublicKeyRequest{KeyID: keyID})
+	if err != nil {
+		return nil, fmt.Errorf("cannot get the public key of %s: %v", keyID, err)
+	}
+	pubKey, err := btcec.ParsePubKey(res.PubKey, btcec.S256())
+	if err != nil {
+		return nil, err
+	}
+	return &remoteSigner{service, keyID, metadata, pubKey}, nil
+}
+
+// NewGRPCRemoteSigner returns a signer that signs using the key with the given
+// id of the signing service served on the gRPC connection.
+func NewGRPCRemoteSigner(ctx context.Context, conn *grpc.ClientConn, keyID string, metadata map[string]string) (Signer, error) {
+	return NewRemoteSigner(ctx, NewGRPCSigningService(conn), keyID, metadata)
+}
+
+func (signer *remoteSigner) Sign(ctx context.Context, hashes [][]byte) ([]*btcec.Signature, error) {
+	res, err := signer.service.Sign(ctx, &SignRequest{
+		KeyID:    signer.keyID,
+		Hashes:   hashes,
+		Metadata: signer.metadata,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cannot sign with %s: %v", signer.keyID, err)
+	}
+	if len(res.Signatures) != len(hashes) {
+		return nil, fmt.Errorf("invalid number of signatures: got: %d required: %d", len(res.Signatures), len(hashes))
+	}
+	sigs := make([]*btcec.Signature, len(hashes))
+	for i, der := range res.Signatures {
+		sig, err := btcec.ParseDERSignature(der, btcec.S256())
+		if err != nil {
+			return nil, NewErrInvalidSignature(i, err.Error())
+		}
+		if sigs[i], err = verifySig(sig, hashes[i], signer.pubKey); err != nil {
+			return nil, NewErrInvalidSignature(i, err.Error())
+		}
+	}
+	return sigs, nil
+}
+
+func (signer *remoteSigner) PublicKey() *btcec.PublicKey {
+	return signer.pubKey
+}
+
+type signingServer struct {
+	keyID  string
+	signer Signer
+}
+
+// NewSigningServer returns a reference implementation of the signing service,
+// that signs every request for the given key id using the signer.
+func NewSigningServer(keyID string, signer Signer) SigningService {
+	return &signingServer{keyID, signer}
+}
+
+func (server *signingServer) PublicKey(ctx context.Context, req *PublicKeyRequest) (*PublicKeyResponse, error) {
+	if req.KeyID != server.keyID {
+		return nil, fmt.Errorf("unknown key %s", req.KeyID)
+	}
+	return &PublicKeyResponse{PubKey: server.signer.PublicKey().SerializeCompressed()}, nil
+}
+
+func (server *signingServer) Sign(ctx context.Context, req *SignRequest) (*SignResponse, error) {
+	if req.KeyID != server.keyID {
+		return nil, fmt.Errorf("unknown key %s", req.KeyID)
+	}
+	for i, hash := range req.Hashes {
+		if len(hash) != 32 {
+			return nil, fmt.Errorf("invalid hash %d: expected 32 bytes, got %d", i, len(hash))
+		}
+	}
+	sigs, err := server.signer.Sign(ctx, req.Hashes)
+	if err != nil {
+		return nil, err
+	}
+	res := &SignResponse{Signatures: make([][]byte, len(sigs))}
+	for i, sig := range sigs {
+		res.Signatures[i] = sig.Serialize()
+	}
+	return res, nil
+}
+
+type grpcSigningService struct {
+	client pb.SigningServiceClient
+}
+
+// NewGRPCSigningService returns the signing service served on the gRPC
+// connection.
+func NewGRPCSigningService(conn *grpc.ClientConn) SigningService {
+	return &grpcSigningService{pb.NewSigningServiceClient(conn)}
+}
+
+func (service *grpcSigningService) PublicKey(ctx context.Context, req *PublicKeyRequest) (*PublicKeyResponse, error) {
+	res, err := service.client.PublicKey(ctx, &pb.PublicKeyRequest{KeyId: req.KeyID})
+	if err != nil {
+		return nil, err
+	}
+	return &PublicKeyResponse{PubKey: res.PubKey}, nil
+}
+
+func (service *grpcSigningService) Sign(ctx context.Context, req *SignRequest) (*SignResponse, error) {
+	res, err := service.client.Sign(ctx, &pb.SignRequest{
+		KeyId:    req.KeyID,
+		Hashes:   req.Hashes,
+		Metadata: req.Metadata,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &SignResponse{Signatures: res.Signatures}, nil
+}
+
+type grpcSigningServer struct {
+	service SigningService
+}
+
+// RegisterSigningServer registers the signing service, such as the one
+// returned by NewSigningServer, on the gRPC server.
+func RegisterSigningServer(server *grpc.Server, service SigningService) {
+	pb.RegisterSigningServiceServer(server, &grpcSigningServer{service})
+}
+
+func (server *grpcSigningServer) PublicKey(ctx context.Context, req *pb.PublicKeyRequest) (*pb.PublicKeyResponse, error) {
+	res, err := server.service.PublicKey(ctx, &PublicKeyRequest{KeyID: req.KeyId})
+	if err != nil {
+		return nil, err
+	}
+	return &pb.PublicKeyResponse{PubKey: res.PubKey}, nil
+}
+
+func (server *grpcSigningServer) Sign(ctx context.Context, req *pb.SignRequest) (*pb.SignResponse, error) {
+	res, err := server.service.Sign(ctx, &SignRequest{
+		KeyID:    req.KeyId,
+		Hashes:   req.Hashes,
+		Metadata: req.Metadata,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &pb.SignResponse{Signatures: res.Signatures}, nil
+}
@@ -0,0 +1,63 @@
+package libzec_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"net"
+
+	"github.com/btcsuite/btcd/btcec"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/renproject/libzec-go"
+	"google.golang.org/grpc"
+)
+
+var _ = Describe("Remote signers", func() {
+	var privKey *btcec.PrivateKey
+	var server *grpc.Server
+	var conn *grpc.ClientConn
+
+	BeforeEach(func() {
+		var err error
+		privKey, err = btcec.NewPrivateKey(btcec.S256())
+		Expect(err).Should(BeNil())
+
+		lis, err := net.Listen("tcp", "127.0.0.1:0")
+		Expect(err).Should(BeNil())
+		server = grpc.NewServer()
+		RegisterSigningServer(server, NewSigningServer("key", NewSigner(privKey.ToECDSA())))
+		go server.Serve(lis)
+
+		conn, err = grpc.Dial(lis.Addr().String(), grpc.WithInsecure())
+		Expect(err).Should(BeNil())
+	})
+
+	AfterEach(func() {
+		Expect(conn.Close()).Should(BeNil())
+		server.Stop()
+	})
+
+	It("should sign over gRPC", func() {
+		signer, err := NewGRPCRemoteSigner(context.Background(), conn, "key", map[string]string{"tx": "test"})
+		Expect(err).Should(BeNil())
+		Expect(signer.PublicKey().IsEqual(privKey.PubKey())).Should(BeTrue())
+
+		hash := sha256.Sum256([]byte("libzec"))
+		sigs, err := signer.Sign(context.Background(), [][]byte{hash[:]})
+		Expect(err).Should(BeNil())
+		Expect(sigs).Should(HaveLen(1))
+		Expect(sigs[0].Verify(hash[:], privKey.PubKey())).Should(BeTrue())
+	})
+
+	It("should not sign with an unknown key", func() {
+		_, err := NewGRPCRemoteSigner(context.Background(), conn, "other", nil)
+		Expect(err).ShouldNot(BeNil())
+	})
+
+	It("should not sign invalid hashes", func() {
+		signer, err := NewGRPCRemoteSigner(context.Background(), conn, "key", nil)
+		Expect(err).Should(BeNil())
+		_, err = signer.Sign(context.Background(), [][]byte{{1, 2, 3}})
+		Expect(err).ShouldNot(BeNil())
+	})
+})
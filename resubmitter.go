@@ -0,0 +1,106 @@
+package libzec
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// A Resubmitter watches the transactions broadcast by an account, and replaces
+// them with a new transaction spending the same utxos when they expire without
+// being mined.
+type Resubmitter interface {
+	// Watch starts watching the transaction described by the receipt.
+	Watch(receipt TxReceipt)
+
+	// Pending returns the receipts of the transactions that have not been
+	// confirmed yet.
+	Pending() []TxReceipt
+
+	// Run polls the pending transactions at the given interval, until the
+	// context is done.
+	Run(ctx context.Context, interval time.Duration)
+}
+
+type resubmitter struct {
+	account Account
+	feeBump int64
+
+	mu      *sync.Mutex
+	pending map[string]TxReceipt
+}
+
+// NewResubmitter returns a Resubmitter that replaces the expired transactions
+// of the account, bumping their fee by the given amount.
+func NewResubmitter(account Account, feeBump int64) Resubmitter {
+	return &resubmitter{
+		account: account,
+		feeBump: feeBump,
+		mu:      new(sync.Mutex),
+		pending: map[string]TxReceipt{},
+	}
+}
+
+func (resubmitter *resubmitter) Watch(receipt TxReceipt) {
+	resubmitter.mu.Lock()
+	defer resubmitter.mu.Unlock()
+	resubmitter.pending[receipt.TxHash] = receipt
+}
+
+func (resubmitter *resubmitter) Pending() []TxReceipt {
+	resubmitter.mu.Lock()
+	defer resubmitter.mu.Unlock()
+	receipts := make([]TxReceipt, 0, len(resubmitter.pending))
+	for _, receipt := range resubmitter.pending {
+		receipts = append(receipts, receipt)
+	}
+	return receipts
+}
+
+func (resubmitter *resubmitter) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		resubmitter.poll(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (resubmitter *resubmitter) poll(ctx context.Context) {
+	height, err := resubmitter.account.BlockHeight()
+	if err != nil {
+		return
+	}
+	for _, receipt := range resubmitter.Pending() {
+		// Expired transactions are evicted from the mempool, so a
+		// transaction that cannot be found is only pending until it expires.
+		conf, err := resubmitter.account.Confirmations(receipt.TxHash)
+		if err != nil && !errors.Is(err, ErrTxNotFound) {
+			continue
+		}
+		if conf > 0 {
+			resubmitter.remove(receipt.TxHash)
+			continue
+		}
+		if !expired(receipt.ExpiryHeight, height) {
+			continue
+		}
+		replacement, err := resubmitter.account.Resubmit(ctx, receipt, resubmitter.feeBump)
+		if err != nil {
+			continue
+		}
+		resubmitter.remove(receipt.TxHash)
+		resubmitter.Watch(replacement)
+	}
+}
+
+func (resubmitter *resubmitter) remove(txHash string) {
+	resubmitter.mu.Lock()
+	defer resubmitter.mu.Unlock()
+	delete(resubmitter.pending, txHash)
+}
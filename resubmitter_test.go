@@ -0,0 +1,95 @@
+package libzec_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcutil"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/renproject/libzec-go"
+)
+
+var _ = Describe("Expiry and resubmission", func() {
+	const height = 1842420
+
+	It("should expire transactions a fixed number of blocks after the tip", func() {
+		core := newMockClientCore(&chaincfg.TestNet3Params, height)
+		account, _, addr := newMockAccount(core, 1000000)
+		receipt, err := account.Transfer(context.Background(), addr.EncodeAddress(), 100000, Standard, false)
+		Expect(err).Should(BeNil())
+		Expect(receipt.ExpiryHeight).Should(Equal(uint32(height + ZCashExpiryDelta)))
+	})
+
+	It("should not build a transaction if the tip cannot be fetched", func() {
+		core := newMockClientCore(&chaincfg.TestNet3Params, height)
+		account, _, addr := newMockAccount(core, 1000000)
+		core.heightErr = fmt.Errorf("unavailable")
+		_, err := account.Transfer(context.Background(), addr.EncodeAddress(), 100000, Standard, false)
+		Expect(err).ShouldNot(BeNil())
+		Expect(core.published).Should(BeEmpty())
+
+		_, err = NewTxBuilder(NewClient(core)).BuildMulti(addr.EncodeAddress(), addr.EncodeAddress(), 100000, nil)
+		Expect(err).ShouldNot(BeNil())
+	})
+
+	It("should replace a transaction dropped once expired", func() {
+		core := newMockClientCore(&chaincfg.TestNet3Params, height)
+		account, _, addr := newMockAccount(core, 1000000)
+		receipt, err := account.Transfer(context.Background(), addr.EncodeAddress(), 100000, Standard, false)
+		Expect(err).Should(BeNil())
+		Expect(core.published).Should(HaveLen(1))
+
+		resubmitter := NewResubmitter(account, 1000)
+		resubmitter.Watch(receipt)
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		resubmitter.Run(ctx, time.Hour)
+		cancel()
+		Expect(core.published).Should(HaveLen(1))
+
+		core.height = int64(receipt.ExpiryHeight)
+		ctx, cancel = context.WithTimeout(context.Background(), 100*time.Millisecond)
+		resubmitter.Run(ctx, time.Hour)
+		cancel()
+		Expect(core.published).Should(HaveLen(2))
+		pending := resubmitter.Pending()
+		Expect(pending).Should(HaveLen(1))
+		Expect(pending[0].TxHash).ShouldNot(Equal(receipt.TxHash))
+		Expect(pending[0].ExpiryHeight).Should(Equal(receipt.ExpiryHeight + ZCashExpiryDelta))
+		Expect(pending[0].Fee).Should(Equal(receipt.Fee + 1000))
+	})
+
+	It("should resubmit the spend of a contract", func() {
+		core := newMockClientCore(&chaincfg.TestNet3Params, height)
+		account, privKey, _ := newMockAccount(core, 0)
+		secret := [32]byte{1}
+		pkh := btcutil.Hash160(privKey.PubKey().SerializeCompressed())
+		contract, err := HTLCScript(pkh, pkh, sha256.Sum256(secret[:]), 1000)
+		Expect(err).Should(BeNil())
+		contractAddr, err := ScriptAddress(contract, &chaincfg.TestNet3Params)
+		Expect(err).Should(BeNil())
+		utxo := core.addUTXO(contractAddr, chainhash.Hash{9}, 100000, 1)
+		scriptPubKey, err := PayToAddrScript(contractAddr)
+		Expect(err).Should(BeNil())
+
+		receipt, err := account.RedeemHTLC(context.Background(), contract, secret, Standard)
+		Expect(err).Should(BeNil())
+		Expect(receipt.RedeemScripts).Should(Equal([][]byte{contract}))
+
+		core.height = int64(receipt.ExpiryHeight)
+		_, err = account.Resubmit(context.Background(), receipt, 1000)
+		Expect(err).Should(BeNil())
+		Expect(core.published).Should(HaveLen(2))
+		for _, stx := range core.published {
+			decoded, err := DecodeTransaction(stx)
+			Expect(err).Should(BeNil())
+			msgTx, err := decoded.MsgTx()
+			Expect(err).Should(BeNil())
+			Expect(VerifyScript(msgTx, 0, scriptPubKey, utxo.Amount, 0xC2D6D0B4)).Should(BeNil())
+		}
+	})
+})
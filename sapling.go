@@ -0,0 +1,265 @@
+package libzec
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/big"
+
+	"github.com/btcsuite/btcd/chaincfg"
+)
+
+// saplingURS is the uniform random string used by the Sapling group hash.
+const saplingURS = "096b36a5804bfacef1691e173c366a47ff5ba84a44f26ddd7e8d9f79d5b42df0"
+
+// saplingExtendedFVKLength is the length of an encoded extended full viewing
+// key: its depth, parent tag, child index, chain code, ak, nk, ovk and dk.
+const saplingExtendedFVKLength = 1 + 4 + 4 + 32 + 4*32
+
+// DiversifierIndex is the 88 bit little endian index of a diversified
+// address. The default address of a key is its valid address with the lowest
+// index.
+type DiversifierIndex [11]byte
+
+// NewDiversifierIndex returns the diversifier index of the integer, for
+// example the id of the user that is given a deposit address.
+func NewDiversifierIndex(i uint64) DiversifierIndex {
+	index := DiversifierIndex{}
+	binary.LittleEndian.PutUint64(index[:], i)
+	return index
+}
+
+// Next returns the next diversifier index, and false if the index is the
+// last one.
+func (index DiversifierIndex) Next() (DiversifierIndex, bool) {
+	for i := range index {
+		index[i]++
+		if index[i] != 0 {
+			return index, true
+		}
+	}
+	return index, false
+}
+
+// SaplingFullViewingKey is a Sapling full viewing key, together with the
+// diversifier key that derives its diversified addresses. Every diversified
+// address of a key is paid to the same wallet, but the addresses cannot be
+// linked to each other without the viewing key.
+type SaplingFullViewingKey struct {
+	ak, nk, ovk, dk [32]byte
+	ivk             *big.Int
+	params          *chaincfg.Params
+}
+
+// NewSaplingFullViewingKey returns the full viewing key with the encoded
+// spend validating key ak, nullifier deriving key nk, outgoing viewing key
+// ovk and diversifier key dk.
+func NewSaplingFullViewingKey(ak, nk, ovk, dk [32]byte, params *chaincfg.Params) (*SaplingFullViewingKey, error) {
+	if _, err := encodingOf(params); err != nil {
+		return nil, err
+	}
+	if _, ok := decodeJubjubPoint(ak); !ok {
+		return nil, fmt.Errorf("invalid sapling full viewing key: ak is not a point")
+	}
+	if _, ok := decodeJubjubPoint(nk); !ok {
+		return nil, fmt.Errorf("invalid sapling full viewing key: nk is not a point")
+	}
+
+	// ivk is the hash of ak and nk, truncated to 251 bits.
+	hash := blake2s256("Zcashivk", ak[:], nk[:])
+	hash[31] &= 0x07
+	ivk := new(big.Int).SetBytes(reverseBytes(hash[:]))
+	if ivk.Sign() == 0 {
+		return nil, fmt.Errorf("invalid sapling full viewing key: ivk is zero")
+	}
+	return &SaplingFullViewingKey{ak, nk, ovk, dk, ivk, params}, nil
+}
+
+// DecodeSaplingFullViewingKey decodes an extended full viewing key, as
+// exported by the z_exportviewingkey RPC of zcashd. Only the keys are used,
+// and the position of the key in its derivation tree is ignored.
+func DecodeSaplingFullViewingKey(encoded string, params *chaincfg.Params) (*SaplingFullViewingKey, error) {
+	encoding, err := encodingOf(params)
+	if err != nil {
+		return nil, err
+	}
+	hrp := encoding.saplingFVKHRP
+	decodedHRP, data, err := bech32Decode(encoded, bech32Classic)
+	if err != nil {
+		return nil, err
+	}
+	if decodedHRP != hrp {
+		return nil, fmt.Errorf("invalid sapling full viewing key prefix: got: %s required: %s", decodedHRP, hrp)
+	}
+	if len(data) != saplingExtendedFVKLength {
+		return nil, fmt.Errorf("invalid sapling full viewing key length: got: %d required: %d", len(data), saplingExtendedFVKLength)
+	}
+	var ak, nk, ovk, dk [32]byte
+	keys := data[saplingExtendedFVKLength-4*32:]
+	copy(ak[:], keys[0:32])
+	copy(nk[:], keys[32:64])
+	copy(ovk[:], keys[64:96])
+	copy(dk[:], keys[96:128])
+	return NewSaplingFullViewingKey(ak, nk, ovk, dk, params)
+}
+
+// Address returns the diversified address at the index. About half of the
+// indices do not have an address, in which case ErrInvalidDiversifier is
+// returned.
+func (fvk *SaplingFullViewingKey) Address(index DiversifierIndex) (*SaplingAddress, error) {
+	diversifier, err := fvk.diversifier(index)
+	if err != nil {
+		return nil, err
+	}
+	gd, ok := diversifyHash(diversifier)
+	if !ok {
+		return nil, ErrInvalidDiversifier
+	}
+	network, err := NetworkOf(fvk.params)
+	if err != nil {
+		return nil, err
+	}
+	return &SaplingAddress{diversifier, gd.mul(fvk.ivk).bytes(), network}, nil
+}
+
+// DefaultAddress returns the address with the lowest valid index.
+func (fvk *SaplingFullViewingKey) DefaultAddress() (*SaplingAddress, error) {
+	_, address, err := fvk.Addresses(DiversifierIndex{}).Next()
+	return address, err
+}
+
+// Addresses returns an iterator over the diversified addresses, starting at
+// the given index.
+func (fvk *SaplingFullViewingKey) Addresses(start DiversifierIndex) *DiversifierIterator {
+	return &DiversifierIterator{fvk, start, false}
+}
+
+// diversifier returns the diversifier at the index, which is the index
+// encrypted using FF1-AES256 with the diversifier key, as specified in ZIP-32.
+func (fvk *SaplingFullViewingKey) diversifier(index DiversifierIndex) ([11]byte, error) {
+	bits := make([]uint32, 88)
+	for i := range bits {
+		bits[i] = uint32(index[i/8]>>uint(i%8)) & 1
+	}
+	encrypted, err := ff1Encrypt(fvk.dk[:], 2, nil, bits)
+	if err != nil {
+		return [11]byte{}, err
+	}
+	diversifier := [11]byte{}
+	for i, bit := range encrypted {
+		diversifier[i/8] |= byte(bit) << uint(i%8)
+	}
+	return diversifier, nil
+}
+
+// diversifyHash returns the base point of the addresses with the diversifier,
+// and false if the diversifier is invalid.
+func diversifyHash(diversifier [11]byte) (jubjubPoint, bool) {
+	hash := blake2s256("Zcash_gd", []byte(saplingURS), diversifier[:])
+	p, ok := decodeJubjubPoint(hash)
+	if !ok {
+		return jubjubPoint{}, false
+	}
+	p = p.mul(big.NewInt(jubjubCofactor))
+	if p.isIdentity() {
+		return jubjubPoint{}, false
+	}
+	return p, true
+}
+
+// DiversifierIterator iterates over the diversified addresses of a full
+// viewing key, in increasing order of index, skipping the invalid indices.
+type DiversifierIterator struct {
+	fvk   *SaplingFullViewingKey
+	index DiversifierIndex
+	done  bool
+}
+
+// Next returns the next address and its index. It returns
+// ErrDiversifierSpaceExhausted once every index has been used.
+func (iter *DiversifierIterator) Next() (DiversifierIndex, *SaplingAddress, error) {
+	for !iter.done {
+		index := iter.index
+		next, ok := index.Next()
+		iter.index, iter.done = next, !ok
+
+		address, err := iter.fvk.Address(index)
+		if err == ErrInvalidDiversifier {
+			continue
+		}
+		return index, address, err
+	}
+	return DiversifierIndex{}, nil, ErrDiversifierSpaceExhausted
+}
+
+// SaplingAddress is a Sapling payment address.
+type SaplingAddress struct {
+	diversifier [11]byte
+	pkD         [32]byte
+	net         Network
+}
+
+// DecodeSaplingAddress decodes a Sapling payment address of the network.
+func DecodeSaplingAddress(address string, params *chaincfg.Params) (*SaplingAddress, error) {
+	encoding, err := encodingOf(params)
+	if err != nil {
+		return nil, err
+	}
+	hrp := encoding.saplingHRP
+	decodedHRP, data, err := bech32Decode(address, bech32Classic)
+	if err != nil {
+		return nil, err
+	}
+	if decodedHRP != hrp {
+		return nil, fmt.Errorf("invalid sapling address prefix: got: %s required: %s", decodedHRP, hrp)
+	}
+	return newSaplingAddress(data, params)
+}
+
+// newSaplingAddress validates the encoding of a Sapling address, which is its
+// diversifier followed by its encoded transmission key.
+func newSaplingAddress(data []byte, params *chaincfg.Params) (*SaplingAddress, error) {
+	network, err := NetworkOf(params)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) != 43 {
+		return nil, fmt.Errorf("invalid sapling address length: got: %d required: %d", len(data), 43)
+	}
+	addr := &SaplingAddress{net: network}
+	copy(addr.diversifier[:], data[:11])
+	copy(addr.pkD[:], data[11:])
+	if _, ok := diversifyHash(addr.diversifier); !ok {
+		return nil, ErrInvalidDiversifier
+	}
+	if _, ok := decodeJubjubPoint(addr.pkD); !ok {
+		return nil, fmt.Errorf("invalid sapling address: pk_d is not a point")
+	}
+	return addr, nil
+}
+
+func (addr *SaplingAddress) String() string {
+	return addr.EncodeAddress()
+}
+
+func (addr *SaplingAddress) EncodeAddress() string {
+	encoded, err := bech32Encode(networkEncodings[addr.net].saplingHRP, append(addr.diversifier[:], addr.pkD[:]...), bech32Classic)
+	if err != nil {
+		return ""
+	}
+	return encoded
+}
+
+// Diversifier returns the diversifier of the address.
+func (addr *SaplingAddress) Diversifier() [11]byte {
+	return addr.diversifier
+}
+
+// PkD returns the encoded diversified transmission key of the address.
+func (addr *SaplingAddress) PkD() [32]byte {
+	return addr.pkD
+}
+
+func (addr *SaplingAddress) IsForNet(params *chaincfg.Params) bool {
+	network, err := NetworkOf(params)
+	return err == nil && addr.net == network
+}
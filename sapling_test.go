@@ -0,0 +1,71 @@
+package libzec_test
+
+import (
+	"encoding/hex"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/renproject/libzec-go"
+)
+
+var _ = Describe("Sapling diversified addresses", func() {
+	decode32 := func(s string) [32]byte {
+		b, err := hex.DecodeString(s)
+		Expect(err).Should(BeNil())
+		out := [32]byte{}
+		copy(out[:], b)
+		return out
+	}
+
+	newFVK := func() *SaplingFullViewingKey {
+		fvk, err := NewSaplingFullViewingKey(
+			decode32("f109663f2351de3db935211d9712531adcee2ac99ecd4ebf2ce68f868e0a1e6c"),
+			decode32("4a71c5ac02deb81b8770d3fef3ea2ceef24838f08e38a9038885bd1fcfd940f0"),
+			[32]byte{}, [32]byte{}, &chaincfg.MainNetParams,
+		)
+		Expect(err).Should(BeNil())
+		return fvk
+	}
+
+	It("should iterate over the valid diversifier indices", func() {
+		iter := newFVK().Addresses(DiversifierIndex{})
+		expected := []struct {
+			index   DiversifierIndex
+			address string
+		}{
+			{NewDiversifierIndex(0), "zs1mnnha08vpgn2l45e338j5fzrq3h58gyfl420vsd2lkz8mec2ggzrunx5pquuw3rsvv49kk08dj0"},
+			{NewDiversifierIndex(3), "zs18n765z9tj0wp7qusy2t78acck7ym3snmtq2w7mpydr9ppqpr34ks80xhhlrz33nmnc0xcgt2chk"},
+			{NewDiversifierIndex(9), "zs1uc6jmjqrerpav0kjq9rlnxkwa9ku548axjw7sqyr0r7u75q78rpex9tp07al0kedjxstsnszatp"},
+		}
+		for _, exp := range expected {
+			index, address, err := iter.Next()
+			Expect(err).Should(BeNil())
+			Expect(index).Should(Equal(exp.index))
+			Expect(address.EncodeAddress()).Should(Equal(exp.address))
+
+			decoded, err := DecodeSaplingAddress(exp.address, &chaincfg.MainNetParams)
+			Expect(err).Should(BeNil())
+			Expect(decoded).Should(Equal(address))
+		}
+	})
+
+	It("should not return addresses for invalid diversifier indices", func() {
+		_, err := newFVK().Address(NewDiversifierIndex(1))
+		Expect(err).Should(Equal(ErrInvalidDiversifier))
+	})
+
+	It("should stop after the last diversifier index", func() {
+		last := DiversifierIndex{}
+		for i := range last {
+			last[i] = 0xFF
+		}
+		_, ok := last.Next()
+		Expect(ok).Should(BeFalse())
+
+		iter := newFVK().Addresses(last)
+		iter.Next()
+		_, _, err := iter.Next()
+		Expect(err).Should(Equal(ErrDiversifierSpaceExhausted))
+	})
+})
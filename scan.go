@@ -0,0 +1,129 @@
+package libzec
+
+import (
+	"bytes"
+	"encoding/hex"
+
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcutil"
+)
+
+// ScriptSpent checks whether the script address is spent by a transaction
+// paying the spender, and returns the signature script of the spending input.
+// Backends without a dedicated endpoint are supported if they implement
+// clients.AddressHistory and clients.BatchClient, in which case the
+// transactions spending from the address are scanned.
+func (client *client) ScriptSpent(script, spender string) (bool, string, error) {
+	if client.Capabilities().ScriptSpent {
+		if spent, sigScript, err := client.ClientCore.ScriptSpent(script, spender); err != ErrNotSupported {
+			return spent, sigScript, err
+		}
+	}
+	return client.scanScriptSpent(script, spender)
+}
+
+// ScriptRedeemed checks whether the script address received at least the
+// value, and was then emptied. Backends that do not support it are supported
+// if they implement clients.AddressHistory.
+func (client *client) ScriptRedeemed(address string, value int64) (bool, int64, error) {
+	redeemed, balance, err := client.ClientCore.ScriptRedeemed(address, value)
+	if err != ErrNotSupported {
+		return redeemed, balance, err
+	}
+	history, ok := addressHistory(client)
+	if !ok {
+		return false, 0, ErrNotSupported
+	}
+	deltas, err := history.AddressDeltas(address)
+	if err != nil {
+		return false, 0, err
+	}
+	var received int64
+	for _, delta := range deltas {
+		if delta.Amount > 0 {
+			received += delta.Amount
+		}
+		balance += delta.Amount
+	}
+	return received >= value && balance == 0, balance, nil
+}
+
+// scanScriptSpent looks for the spender among the outputs of the transactions
+// spending from the script address.
+func (client *client) scanScriptSpent(script, spender string) (bool, string, error) {
+	history, ok := addressHistory(client)
+	if !ok {
+		return false, "", ErrNotSupported
+	}
+	batcher, ok := batchClient(client)
+	if !ok {
+		return false, "", ErrNotSupported
+	}
+	scriptAddr, err := DecodeAddress(script, client.NetworkParams())
+	if err != nil {
+		return false, "", err
+	}
+	spenderAddr, err := DecodeAddress(spender, client.NetworkParams())
+	if err != nil {
+		return false, "", err
+	}
+	spenderScript, err := PayToAddrScript(spenderAddr)
+	if err != nil {
+		return false, "", err
+	}
+
+	deltas, err := history.AddressDeltas(script)
+	if err != nil {
+		return false, "", err
+	}
+	txHashes := []string{}
+	for _, delta := range deltas {
+		if delta.Amount < 0 {
+			txHashes = append(txHashes, delta.TxHash)
+		}
+	}
+	if len(txHashes) == 0 {
+		return false, "", nil
+	}
+	raw, err := batcher.BatchRawTransactions(txHashes)
+	if err != nil {
+		return false, "", err
+	}
+
+	for _, txHash := range txHashes {
+		stx, ok := raw[txHash]
+		if !ok {
+			continue
+		}
+		tx, err := DecodeTransaction(stx)
+		if err != nil {
+			return false, "", err
+		}
+		paysSpender := false
+		for _, txOut := range tx.TxOut {
+			if bytes.Equal(txOut.PkScript, spenderScript) {
+				paysSpender = true
+				break
+			}
+		}
+		if !paysSpender {
+			continue
+		}
+		for _, txIn := range tx.TxIn {
+			if spendsScript(txIn.SignatureScript, scriptAddr.ScriptAddress()) {
+				return true, hex.EncodeToString(txIn.SignatureScript), nil
+			}
+		}
+	}
+	return false, "", nil
+}
+
+// spendsScript returns whether the signature script redeems the script with
+// the given hash, which is the last data it pushes.
+func spendsScript(sigScript, scriptHash []byte) bool {
+	pushes, err := txscript.PushedData(sigScript)
+	if err != nil || len(pushes) == 0 {
+		return false
+	}
+	return bytes.Equal(btcutil.Hash160(pushes[len(pushes)-1]), scriptHash)
+}
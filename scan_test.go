@@ -0,0 +1,83 @@
+package libzec_test
+
+import (
+	"bytes"
+	"encoding/hex"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/iqoption/zecutil"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/renproject/libzec-go"
+	"github.com/renproject/libzec-go/clients"
+)
+
+var _ = Describe("Script scans", func() {
+	It("should find redeemed scripts using the address history behind wrapped client cores", func() {
+		core := newHistoryMockClientCore(newMockClientCore(&chaincfg.TestNet3Params, 1842420))
+		client := NewClient(wrap(core))
+		script, err := ScriptAddress([]byte{txscript.OP_TRUE}, &chaincfg.TestNet3Params)
+		Expect(err).Should(BeNil())
+		core.deltas[script.EncodeAddress()] = []clients.AddressDelta{{TxHash: "a", Amount: 1000, BlockHeight: 10}}
+
+		redeemed, balance, err := client.ScriptRedeemed(script.EncodeAddress(), 1000)
+		Expect(err).Should(BeNil())
+		Expect(redeemed).Should(BeFalse())
+		Expect(balance).Should(Equal(int64(1000)))
+
+		core.deltas[script.EncodeAddress()] = append(core.deltas[script.EncodeAddress()], clients.AddressDelta{TxHash: "b", Amount: -1000, BlockHeight: 11})
+		redeemed, balance, err = client.ScriptRedeemed(script.EncodeAddress(), 1000)
+		Expect(err).Should(BeNil())
+		Expect(redeemed).Should(BeTrue())
+		Expect(balance).Should(Equal(int64(0)))
+
+		_, _, err = NewClient(newMockClientCore(&chaincfg.TestNet3Params, 1842420)).ScriptRedeemed(script.EncodeAddress(), 1000)
+		Expect(err).Should(Equal(ErrNotSupported))
+	})
+
+	It("should find the transactions spending scripts to the spender behind wrapped client cores", func() {
+		mock := newMockClientCore(&chaincfg.TestNet3Params, 1842420)
+		core := newHistoryMockClientCore(mock)
+		client := NewClient(wrap(core))
+		redeemScript := []byte{txscript.OP_TRUE}
+		script, err := ScriptAddress(redeemScript, &chaincfg.TestNet3Params)
+		Expect(err).Should(BeNil())
+		_, _, spender := newMockAccount(mock, 0)
+		spenderScript, err := PayToAddrScript(spender)
+		Expect(err).Should(BeNil())
+
+		// The spending transaction pushes the redeem script last, and pays
+		// the spender.
+		sigScript, err := txscript.NewScriptBuilder().AddData([]byte{0xAA}).AddData(redeemScript).Script()
+		Expect(err).Should(BeNil())
+		msgTx := &zecutil.MsgTx{MsgTx: wire.NewMsgTx(4)}
+		msgTx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&chainhash.Hash{0xD0}, 0), sigScript, nil))
+		msgTx.AddTxOut(wire.NewTxOut(900, spenderScript))
+		buf := new(bytes.Buffer)
+		Expect(msgTx.ZecEncode(buf, 0, wire.BaseEncoding)).Should(BeNil())
+		txID, err := TxID(buf.Bytes())
+		Expect(err).Should(BeNil())
+		mock.published = append(mock.published, buf.Bytes())
+
+		spent, _, err := client.ScriptSpent(script.EncodeAddress(), spender.EncodeAddress())
+		Expect(err).Should(BeNil())
+		Expect(spent).Should(BeFalse())
+
+		core.deltas[script.EncodeAddress()] = []clients.AddressDelta{
+			{TxHash: chainhash.Hash{0xD0}.String(), Amount: 1000, BlockHeight: 10},
+			{TxHash: hex.EncodeToString(txID), Amount: -1000, BlockHeight: 11},
+		}
+		spent, spentSigScript, err := client.ScriptSpent(script.EncodeAddress(), spender.EncodeAddress())
+		Expect(err).Should(BeNil())
+		Expect(spent).Should(BeTrue())
+		Expect(spentSigScript).Should(Equal(hex.EncodeToString(sigScript)))
+
+		_, _, other := newMockAccount(mock, 0)
+		spent, _, err = client.ScriptSpent(script.EncodeAddress(), other.EncodeAddress())
+		Expect(err).Should(BeNil())
+		Expect(spent).Should(BeFalse())
+	})
+})
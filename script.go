@@ -0,0 +1,281 @@
+package libzec
+
+import (
+	"fmt"
+
+	"github.com/btcsuite/btcd/txscript"
+)
+
+// ScriptType is the type of a script, as returned by ClassifyScript.
+type ScriptType int
+
+const (
+	ScriptNonStandard ScriptType = iota
+	ScriptP2PKH
+	ScriptP2SH
+	ScriptMultisig
+	ScriptNullData
+	ScriptSlave
+	ScriptSlaveWithRefund
+	ScriptHTLC
+)
+
+func (typ ScriptType) String() string {
+	switch typ {
+	case ScriptNonStandard:
+		return "nonstandard"
+	case ScriptP2PKH:
+		return "p2pkh"
+	case ScriptP2SH:
+		return "p2sh"
+	case ScriptMultisig:
+		return "multisig"
+	case ScriptNullData:
+		return "nulldata"
+	case ScriptSlave:
+		return "slave"
+	case ScriptSlaveWithRefund:
+		return "slave-with-refund"
+	case ScriptHTLC:
+		return "htlc"
+	default:
+		return fmt.Sprintf("unknown(%d)", int(typ))
+	}
+}
+
+// ScriptInfo describes a script, as returned by ClassifyScript. Only the fields
+// relevant to the type of the script are set.
+type ScriptInfo struct {
+	Type ScriptType
+
+	// Disassembly is the human-readable form of the script.
+	Disassembly string
+
+	// Hash160 is the public key hash of P2PKH scripts, the script hash of
+	// P2SH scripts, the master public key hash of slave scripts, and the
+	// public key hash of the spender of hash time locked contracts.
+	Hash160 []byte
+
+	// Nonce is the nonce of slave scripts.
+	Nonce []byte
+
+	// RefundPKH and LockTime are the public key hash that can spend slave
+	// scripts with refund, and hash time locked contracts, once the lock
+	// time has passed.
+	RefundPKH []byte
+	LockTime  int64
+
+	// SecretHash is the SHA-256 hash of the secret of hash time locked
+	// contracts.
+	SecretHash []byte
+
+	// Required and PubKeys are the number of signatures required by multisig
+	// scripts, and their serialized public keys.
+	Required int
+	PubKeys  [][]byte
+
+	// Data is the data pushed after the OP_RETURN of null data scripts.
+	Data [][]byte
+}
+
+// SigScriptInfo describes a signature script, as returned by
+// InspectSigScript.
+type SigScriptInfo struct {
+	// Disassembly is the human-readable form of the signature script.
+	Disassembly string
+
+	// Pushes are the data pushed by the signature script.
+	Pushes [][]byte
+
+	// RedeemScript describes the last push of the signature script, if it is
+	// a script spent using P2SH, such as a slave script. It is nil for the
+	// signature scripts of P2PKH inputs.
+	RedeemScript *ScriptInfo
+}
+
+// templatePush matches any push in a script template. It is OP_INVALIDOPCODE,
+// which no script of a template contains.
+const templatePush = 0xff
+
+var (
+	p2pkhTemplate = []byte{
+		txscript.OP_DUP, txscript.OP_HASH160, templatePush, txscript.OP_EQUALVERIFY, txscript.OP_CHECKSIG,
+	}
+	p2shTemplate = []byte{
+		txscript.OP_HASH160, templatePush, txscript.OP_EQUAL,
+	}
+	slaveTemplate = []byte{
+		templatePush, txscript.OP_DROP,
+		txscript.OP_DUP, txscript.OP_HASH160, templatePush, txscript.OP_EQUALVERIFY, txscript.OP_CHECKSIG,
+	}
+	slaveWithRefundTemplate = []byte{
+		txscript.OP_IF, templatePush, txscript.OP_DROP, txscript.OP_DUP, txscript.OP_HASH160, templatePush,
+		txscript.OP_ELSE, templatePush, txscript.OP_CHECKLOCKTIMEVERIFY, txscript.OP_DROP, txscript.OP_DUP, txscript.OP_HASH160, templatePush,
+		txscript.OP_ENDIF, txscript.OP_EQUALVERIFY, txscript.OP_CHECKSIG,
+	}
+	htlcTemplate = []byte{
+		txscript.OP_IF, txscript.OP_SHA256, templatePush, txscript.OP_EQUALVERIFY, txscript.OP_DUP, txscript.OP_HASH160, templatePush,
+		txscript.OP_ELSE, templatePush, txscript.OP_CHECKLOCKTIMEVERIFY, txscript.OP_DROP, txscript.OP_DUP, txscript.OP_HASH160, templatePush,
+		txscript.OP_ENDIF, txscript.OP_EQUALVERIFY, txscript.OP_CHECKSIG,
+	}
+)
+
+// DisassembleScript returns the human-readable form of a public key script or
+// of a signature script.
+func DisassembleScript(script []byte) (string, error) {
+	return txscript.DisasmString(script)
+}
+
+// ClassifyScript returns the type of the public key script, or redeem script,
+// and the keys, hashes and data it contains. Scripts that do not match any of
+// the scripts created by this package, nor a standard script, are reported as
+// non-standard. An error is only returned if the script cannot be parsed.
+func ClassifyScript(script []byte) (ScriptInfo, error) {
+	ops, err := parseScript(script)
+	if err != nil {
+		return ScriptInfo{}, err
+	}
+	info := ScriptInfo{Type: ScriptNonStandard}
+	if info.Disassembly, err = DisassembleScript(script); err != nil {
+		return ScriptInfo{}, err
+	}
+
+	if pushes, ok := matchTemplate(ops, p2pkhTemplate); ok && len(pushes[0]) == 20 {
+		info.Type = ScriptP2PKH
+		info.Hash160 = pushes[0]
+		return info, nil
+	}
+	if pushes, ok := matchTemplate(ops, p2shTemplate); ok && len(pushes[0]) == 20 {
+		info.Type = ScriptP2SH
+		info.Hash160 = pushes[0]
+		return info, nil
+	}
+	if pushes, ok := matchTemplate(ops, slaveTemplate); ok && len(pushes[1]) == 20 {
+		info.Type = ScriptSlave
+		info.Nonce = pushes[0]
+		info.Hash160 = pushes[1]
+		return info, nil
+	}
+	if pushes, ok := matchTemplate(ops, slaveWithRefundTemplate); ok && len(pushes[1]) == 20 && len(pushes[3]) == 20 {
+		lockTime, err := scriptNum(pushes[2])
+		if err != nil {
+			return info, nil
+		}
+		info.Type = ScriptSlaveWithRefund
+		info.Nonce = pushes[0]
+		info.Hash160 = pushes[1]
+		info.LockTime = lockTime
+		info.RefundPKH = pushes[3]
+		return info, nil
+	}
+	if pushes, ok := matchTemplate(ops, htlcTemplate); ok && len(pushes[0]) == 32 && len(pushes[1]) == 20 && len(pushes[3]) == 20 {
+		lockTime, err := scriptNum(pushes[2])
+		if err != nil {
+			return info, nil
+		}
+		info.Type = ScriptHTLC
+		info.SecretHash = pushes[0]
+		info.Hash160 = pushes[1]
+		info.LockTime = lockTime
+		info.RefundPKH = pushes[3]
+		return info, nil
+	}
+	if required, pubKeys, ok := matchMultisig(ops); ok {
+		info.Type = ScriptMultisig
+		info.Required = required
+		info.PubKeys = pubKeys
+		return info, nil
+	}
+	if len(ops) > 0 && ops[0].opcode == txscript.OP_RETURN {
+		data := [][]byte{}
+		for _, op := range ops[1:] {
+			if !op.push {
+				return info, nil
+			}
+			data = append(data, op.data)
+		}
+		info.Type = ScriptNullData
+		info.Data = data
+	}
+	return info, nil
+}
+
+// InspectSigScript disassembles the signature script, and classifies the
+// redeem script it reveals, if any.
+func InspectSigScript(sigScript []byte) (SigScriptInfo, error) {
+	ops, err := parseScript(sigScript)
+	if err != nil {
+		return SigScriptInfo{}, err
+	}
+	info := SigScriptInfo{Pushes: [][]byte{}}
+	if info.Disassembly, err = DisassembleScript(sigScript); err != nil {
+		return SigScriptInfo{}, err
+	}
+	for _, op := range ops {
+		if !op.push {
+			return SigScriptInfo{}, fmt.Errorf("signature script is not push only")
+		}
+		info.Pushes = append(info.Pushes, op.data)
+	}
+	if len(info.Pushes) == 0 {
+		return info, nil
+	}
+
+	// The last push of a P2SH input is the redeem script. Signatures and
+	// public keys do not parse as scripts of a known type.
+	redeemScript, err := ClassifyScript(info.Pushes[len(info.Pushes)-1])
+	if err == nil && redeemScript.Type != ScriptNonStandard {
+		info.RedeemScript = &redeemScript
+	}
+	return info, nil
+}
+
+// matchTemplate returns the data pushed by the script if its opcodes match
+// the template.
+func matchTemplate(ops []scriptOp, template []byte) ([][]byte, bool) {
+	if len(ops) != len(template) {
+		return nil, false
+	}
+	pushes := [][]byte{}
+	for i, op := range ops {
+		if template[i] == templatePush {
+			if !op.push {
+				return nil, false
+			}
+			pushes = append(pushes, op.data)
+			continue
+		}
+		if op.push || op.opcode != template[i] {
+			return nil, false
+		}
+	}
+	return pushes, true
+}
+
+// matchMultisig returns the number of required signatures and the public keys
+// of an m-of-n multisig script.
+func matchMultisig(ops []scriptOp) (int, [][]byte, bool) {
+	if len(ops) < 4 || ops[len(ops)-1].opcode != txscript.OP_CHECKMULTISIG {
+		return 0, nil, false
+	}
+	isSmallInt := func(op scriptOp) bool {
+		return op.opcode >= txscript.OP_1 && op.opcode <= txscript.OP_16
+	}
+	first, last := ops[0], ops[len(ops)-2]
+	if !isSmallInt(first) || !isSmallInt(last) {
+		return 0, nil, false
+	}
+	required := int(first.opcode-txscript.OP_1) + 1
+	total := int(last.opcode-txscript.OP_1) + 1
+	pubKeys := [][]byte{}
+	for _, op := range ops[1 : len(ops)-2] {
+		if !op.push || (len(op.data) != 33 && len(op.data) != 65) {
+			return 0, nil, false
+		}
+		pubKeys = append(pubKeys, op.data)
+	}
+	if len(pubKeys) != total || required > total {
+		return 0, nil, false
+	}
+	return required, pubKeys, true
+}
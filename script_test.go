@@ -0,0 +1,58 @@
+package libzec_test
+
+import (
+	"crypto/sha256"
+
+	"github.com/btcsuite/btcd/txscript"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/renproject/libzec-go"
+)
+
+var _ = Describe("Script classification", func() {
+	pkh := make([]byte, 20)
+	refundPKH := append(make([]byte, 19), 1)
+
+	It("should classify hash time locked contracts", func() {
+		secretHash := sha256.Sum256([]byte("secret"))
+		contract, err := HTLCScript(pkh, refundPKH, secretHash, 1000)
+		Expect(err).Should(BeNil())
+		info, err := ClassifyScript(contract)
+		Expect(err).Should(BeNil())
+		Expect(info.Type).Should(Equal(ScriptHTLC))
+		Expect(info.SecretHash).Should(Equal(secretHash[:]))
+		Expect(info.RefundPKH).Should(Equal(refundPKH))
+		Expect(info.LockTime).Should(Equal(int64(1000)))
+	})
+
+	It("should classify null data scripts", func() {
+		script, err := txscript.NewScriptBuilder().AddOp(txscript.OP_RETURN).AddData([]byte("memo")).Script()
+		Expect(err).Should(BeNil())
+		info, err := ClassifyScript(script)
+		Expect(err).Should(BeNil())
+		Expect(info.Type).Should(Equal(ScriptNullData))
+		Expect(info.Data).Should(Equal([][]byte{[]byte("memo")}))
+	})
+
+	It("should find the redeem script of a signature script", func() {
+		nonce := sha256.Sum256([]byte("nonce"))
+		slaveScript, err := txscript.NewScriptBuilder().
+			AddData(nonce[:]).AddOp(txscript.OP_DROP).
+			AddOp(txscript.OP_DUP).AddOp(txscript.OP_HASH160).AddData(pkh).
+			AddOp(txscript.OP_EQUALVERIFY).AddOp(txscript.OP_CHECKSIG).
+			Script()
+		Expect(err).Should(BeNil())
+		sigScript, err := txscript.NewScriptBuilder().
+			AddData(make([]byte, 71)).
+			AddData(make([]byte, 33)).
+			AddData(slaveScript).
+			Script()
+		Expect(err).Should(BeNil())
+		info, err := InspectSigScript(sigScript)
+		Expect(err).Should(BeNil())
+		Expect(info.Pushes).Should(HaveLen(3))
+		Expect(info.RedeemScript).ShouldNot(BeNil())
+		Expect(info.RedeemScript.Type).Should(Equal(ScriptSlave))
+		Expect(info.RedeemScript.Nonce).Should(Equal(nonce[:]))
+	})
+})
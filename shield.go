@@ -0,0 +1,94 @@
+package libzec
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/renproject/libzec-go/clients"
+)
+
+// ShieldTo sends the amount from the address of the account to the shielded
+// address, with the given memo, using z_sendmany. Shielded transactions are
+// built by the zcashd node backing the client, so the wallet of the node must
+// control the address of the account (see importprivkey). The operation is
+// tracked until the transaction is created, and its txid is returned.
+func (account *account) ShieldTo(ctx context.Context, zaddr string, amount int64, memo string) (string, error) {
+	wallet, ok := zcashdWallet(account.Client)
+	if !ok {
+		return "", ErrNoZcashdWallet
+	}
+	from, err := account.Address()
+	if err != nil {
+		return "", err
+	}
+	recipient := clients.ZRecipient{Address: zaddr, Amount: amount}
+	if memo != "" {
+		encoded, err := NewTextMemo(memo)
+		if err != nil {
+			return "", err
+		}
+		recipient.Memo = encoded[:]
+	}
+	opid, err := wallet.ZSendMany(from.EncodeAddress(), []clients.ZRecipient{recipient}, 1, MaxZCashFee)
+	if err != nil {
+		return "", err
+	}
+	account.Logger.Infof("shielding %d ZAT to %s in operation %s", amount, zaddr, opid)
+	return waitForOperation(ctx, wallet, opid)
+}
+
+// ShieldCoinbase sends the coinbase utxos of the address of the account to
+// the shielded address using z_shieldcoinbase, as coinbase outputs must be
+// shielded before they can be spent to transparent addresses. As for ShieldTo
+// the wallet of the zcashd node must control the address of the account.
+func (account *account) ShieldCoinbase(ctx context.Context, zaddr string) (string, error) {
+	wallet, ok := zcashdWallet(account.Client)
+	if !ok {
+		return "", ErrNoZcashdWallet
+	}
+	from, err := account.Address()
+	if err != nil {
+		return "", err
+	}
+	opid, err := wallet.ZShieldCoinbase(from.EncodeAddress(), zaddr, MaxZCashFee)
+	if err != nil {
+		return "", err
+	}
+	account.Logger.Infof("shielding the coinbase utxos to %s in operation %s", zaddr, opid)
+	return waitForOperation(ctx, wallet, opid)
+}
+
+// waitForOperation polls the status of the operation until it completes, and
+// returns the txid of the transaction it created.
+func waitForOperation(ctx context.Context, wallet clients.ZcashdWallet, opid string) (string, error) {
+	var status clients.OperationStatus
+	if err := pollWithBackoff(ctx, func() (bool, error) {
+		var err error
+		status, err = wallet.OperationStatus(opid)
+		if err != nil {
+			return false, err
+		}
+		switch status.Status {
+		case clients.OperationSuccess, clients.OperationFailed, clients.OperationCancelled:
+			return true, nil
+		default:
+			return false, nil
+		}
+	}); err != nil {
+		return "", err
+	}
+	if status.Status != clients.OperationSuccess {
+		return "", fmt.Errorf("operation %s %s: %s", opid, status.Status, status.Error)
+	}
+	return status.TxID, nil
+}
+
+// zcashdWallet returns the wallet of the zcashd node backing the client.
+func zcashdWallet(c Client) (clients.ZcashdWallet, bool) {
+	for _, core := range clientCores(c) {
+		if wallet, ok := core.(clients.ZcashdWallet); ok {
+			return wallet, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,129 @@
+package libzec
+
+import (
+	"bytes"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/iqoption/zecutil"
+)
+
+const (
+	joinSplitsHashPersonalization      = "ZcashJSplitsHash"
+	shieldedSpendsHashPersonalization  = "ZcashSSpendsHash"
+	shieldedOutputsHashPersonalization = "ZcashSOutputHash"
+)
+
+// SaplingSpend is a Sapling spend description.
+type SaplingSpend struct {
+	CV           [32]byte
+	Anchor       [32]byte
+	Nullifier    [32]byte
+	RK           [32]byte
+	ZKProof      [192]byte
+	SpendAuthSig [64]byte
+}
+
+// SaplingOutput is a Sapling output description.
+type SaplingOutput struct {
+	CV            [32]byte
+	CMU           [32]byte
+	EphemeralKey  [32]byte
+	EncCiphertext [580]byte
+	OutCiphertext [80]byte
+	ZKProof       [192]byte
+}
+
+// ShieldedData are the shielded components of a transaction. They are not
+// part of zecutil.MsgTx, so they are provided separately when computing the
+// signature hashes of the transparent inputs of a transaction that also
+// spends or creates shielded notes.
+type ShieldedData struct {
+	// ValueBalance is the net value of the Sapling spends minus the Sapling
+	// outputs.
+	ValueBalance int64
+	Spends       []SaplingSpend
+	Outputs      []SaplingOutput
+
+	// BindingSig is the Sapling binding signature. It is not committed to
+	// by the signature hashes.
+	BindingSig [64]byte
+
+	// JoinSplits are the serialized Sprout JoinSplit descriptions, and
+	// JoinSplitPubKey is the key that signs them. The public key and the
+	// signature are ignored if there are no JoinSplits.
+	JoinSplits      [][]byte
+	JoinSplitPubKey [32]byte
+	JoinSplitSig    [64]byte
+}
+
+// CalcSignatureHashWithShieldedData returns the signature hash of the
+// transparent input at the given index of a transaction that carries the
+// given shielded components, committing to the given consensus branch id.
+func CalcSignatureHashWithShieldedData(
+	subScript []byte,
+	hashType txscript.SigHashType,
+	tx *zecutil.MsgTx,
+	idx int,
+	amt int64,
+	branchID uint32,
+	shielded *ShieldedData,
+) ([]byte, error) {
+	return calcShieldedSignatureHash(subScript, hashType, tx, idx, amt, branchSigHashKey(branchID), shielded)
+}
+
+// hashJoinSplits returns the hash of the JoinSplit descriptions, or the zero
+// hash if there are none.
+func (shielded *ShieldedData) hashJoinSplits() (chainhash.Hash, error) {
+	if shielded == nil || len(shielded.JoinSplits) == 0 {
+		return chainhash.Hash{}, nil
+	}
+	buf := new(bytes.Buffer)
+	for _, joinSplit := range shielded.JoinSplits {
+		buf.Write(joinSplit)
+	}
+	buf.Write(shielded.JoinSplitPubKey[:])
+	return blake2bHash(buf.Bytes(), []byte(joinSplitsHashPersonalization))
+}
+
+// hashShieldedSpends returns the hash of the spend descriptions without their
+// spend authorization signatures, or the zero hash if there are none.
+func (shielded *ShieldedData) hashShieldedSpends() (chainhash.Hash, error) {
+	if shielded == nil || len(shielded.Spends) == 0 {
+		return chainhash.Hash{}, nil
+	}
+	buf := new(bytes.Buffer)
+	for _, spend := range shielded.Spends {
+		buf.Write(spend.CV[:])
+		buf.Write(spend.Anchor[:])
+		buf.Write(spend.Nullifier[:])
+		buf.Write(spend.RK[:])
+		buf.Write(spend.ZKProof[:])
+	}
+	return blake2bHash(buf.Bytes(), []byte(shieldedSpendsHashPersonalization))
+}
+
+// hashShieldedOutputs returns the hash of the output descriptions, or the
+// zero hash if there are none.
+func (shielded *ShieldedData) hashShieldedOutputs() (chainhash.Hash, error) {
+	if shielded == nil || len(shielded.Outputs) == 0 {
+		return chainhash.Hash{}, nil
+	}
+	buf := new(bytes.Buffer)
+	for _, output := range shielded.Outputs {
+		buf.Write(output.CV[:])
+		buf.Write(output.CMU[:])
+		buf.Write(output.EphemeralKey[:])
+		buf.Write(output.EncCiphertext[:])
+		buf.Write(output.OutCiphertext[:])
+		buf.Write(output.ZKProof[:])
+	}
+	return blake2bHash(buf.Bytes(), []byte(shieldedOutputsHashPersonalization))
+}
+
+func (shielded *ShieldedData) valueBalance() int64 {
+	if shielded == nil {
+		return 0
+	}
+	return shielded.ValueBalance
+}
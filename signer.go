@@ -0,0 +1,45 @@
+package libzec
+
+import (
+	"context"
+	"crypto/ecdsa"
+
+	"github.com/btcsuite/btcd/btcec"
+)
+
+// A Signer signs the signature hashes of a transaction. Implementations can
+// keep the private key in memory, or delegate signing to a hardware wallet, a
+// key management service or an MPC network.
+type Signer interface {
+	// Sign returns a signature for every hash, in the same order.
+	Sign(ctx context.Context, hashes [][]byte) ([]*btcec.Signature, error)
+
+	// PublicKey returns the public key of the signer.
+	PublicKey() *btcec.PublicKey
+}
+
+type privKeySigner struct {
+	privKey *btcec.PrivateKey
+}
+
+// NewSigner returns a signer that signs using the given private key, which is
+// kept in memory.
+func NewSigner(privateKey *ecdsa.PrivateKey) Signer {
+	return &privKeySigner{(*btcec.PrivateKey)(privateKey)}
+}
+
+func (signer *privKeySigner) Sign(ctx context.Context, hashes [][]byte) ([]*btcec.Signature, error) {
+	sigs := make([]*btcec.Signature, len(hashes))
+	for i, hash := range hashes {
+		sig, err := signer.privKey.Sign(hash)
+		if err != nil {
+			return nil, err
+		}
+		sigs[i] = sig
+	}
+	return sigs, nil
+}
+
+func (signer *privKeySigner) PublicKey() *btcec.PublicKey {
+	return signer.privKey.PubKey()
+}
@@ -0,0 +1,80 @@
+package libzec
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+)
+
+// SlaveNonce returns the deterministic nonce of the index-th slave address of
+// a user, which is the SHA256 hash of the user id followed by the big endian
+// index. It allows deposit addresses to be derived again from the user id
+// alone.
+func SlaveNonce(userID []byte, index uint64) []byte {
+	data := make([]byte, len(userID)+8)
+	copy(data, userID)
+	binary.BigEndian.PutUint64(data[len(userID):], index)
+	nonce := sha256.Sum256(data)
+	return nonce[:]
+}
+
+// SlaveNonces returns the nonces of the slave addresses of a user, from the
+// start index.
+func SlaveNonces(userID []byte, start uint64, count int) [][]byte {
+	nonces := make([][]byte, count)
+	for i := range nonces {
+		nonces[i] = SlaveNonce(userID, start+uint64(i))
+	}
+	return nonces
+}
+
+// SlaveEntries derives the slave addresses of the master public key hash and
+// the nonces.
+func (client *client) SlaveEntries(mpkh []byte, nonces [][]byte) ([]SlaveEntry, error) {
+	entries := make([]SlaveEntry, len(nonces))
+	for i, nonce := range nonces {
+		script, err := client.SlaveScript(mpkh, nonce)
+		if err != nil {
+			return nil, err
+		}
+		address, err := ScriptAddress(script, client.NetworkParams())
+		if err != nil {
+			return nil, err
+		}
+		entries[i] = SlaveEntry{MPKH: mpkh, Nonce: nonce, Address: address.EncodeAddress()}
+	}
+	return entries, nil
+}
+
+// ScanSlaves fetches the status of the slave addresses of the master public
+// key hash and the nonces, querying at most MaxConcurrentQueries addresses at
+// the same time. Errors of individual addresses are reported in their status.
+func (client *client) ScanSlaves(mpkh []byte, nonces [][]byte) ([]SlaveStatus, error) {
+	entries, err := client.SlaveEntries(mpkh, nonces)
+	if err != nil {
+		return nil, err
+	}
+	addresses := make([]string, len(entries))
+	for i, entry := range entries {
+		addresses[i] = entry.Address
+	}
+	statuses := make([]SlaveStatus, len(entries))
+	forEachAddress(addresses, func(i int, address string) error {
+		statuses[i] = slaveStatus(client, entries[i])
+		return nil
+	})
+	return statuses, nil
+}
+
+// slaveStatus fetches the on-chain status of the slave address.
+func slaveStatus(client Client, entry SlaveEntry) SlaveStatus {
+	status := SlaveStatus{SlaveEntry: entry}
+	funded, balance, err := client.ScriptFunded(entry.Address, 1)
+	if err != nil {
+		status.Err = err
+		return status
+	}
+	status.Funded = funded
+	status.Balance = balance
+	status.Spent = funded && balance == 0
+	return status
+}
@@ -0,0 +1,20 @@
+package libzec_test
+
+import (
+	"encoding/hex"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/renproject/libzec-go"
+)
+
+var _ = Describe("Slave nonces", func() {
+	It("should derive the nonces of a user deterministically", func() {
+		nonce := SlaveNonce([]byte("user-42"), 7)
+		Expect(hex.EncodeToString(nonce)).Should(Equal("28dc91568d4ac873afe3abf5eef68f34d81fe279624b815bba953286c24cb4b2"))
+
+		nonces := SlaveNonces([]byte("user-42"), 5, 3)
+		Expect(nonces).Should(HaveLen(3))
+		Expect(nonces[2]).Should(Equal(nonce))
+	})
+})
@@ -0,0 +1,163 @@
+package libzec
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+)
+
+// swapsVersion is the version of the format of exported swaps.
+const swapsVersion = 1
+
+// Swap is the state of an atomic swap in flight: everything needed to redeem
+// or refund its hash time locked contract after a restart, or on another
+// machine. The secret is only known by the initiator of the swap, until the
+// counterparty redeems the contract, and the refund transaction is only set
+// if a signed refund transaction was prepared in advance.
+type Swap struct {
+	Contract      []byte
+	Counterparty  string
+	SecretHash    [32]byte
+	Secret        *[32]byte
+	LockTime      int64
+	Value         int64
+	FundingTxHash string
+	RefundTx      []byte
+}
+
+// NewSwap returns the swap of the hash time locked contract with the
+// counterparty. The secret hash and the lock time are read from the contract.
+func NewSwap(contract []byte, counterparty string, value int64) (Swap, error) {
+	secretHash, lockTime, err := parseHTLC(contract)
+	if err != nil {
+		return Swap{}, err
+	}
+	return Swap{
+		Contract:     contract,
+		Counterparty: counterparty,
+		SecretHash:   secretHash,
+		LockTime:     lockTime,
+		Value:        value,
+	}, nil
+}
+
+type swapJSON struct {
+	Contract      string `json:"contract"`
+	Counterparty  string `json:"counterparty"`
+	SecretHash    string `json:"secretHash"`
+	Secret        string `json:"secret,omitempty"`
+	LockTime      int64  `json:"lockTime"`
+	Value         int64  `json:"value"`
+	FundingTxHash string `json:"fundingTxHash,omitempty"`
+	RefundTx      string `json:"refundTx,omitempty"`
+}
+
+type swapsJSON struct {
+	Version int    `json:"version"`
+	Swaps   []Swap `json:"swaps"`
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+func (swap Swap) MarshalJSON() ([]byte, error) {
+	val := swapJSON{
+		Contract:      hex.EncodeToString(swap.Contract),
+		Counterparty:  swap.Counterparty,
+		SecretHash:    hex.EncodeToString(swap.SecretHash[:]),
+		LockTime:      swap.LockTime,
+		Value:         swap.Value,
+		FundingTxHash: swap.FundingTxHash,
+		RefundTx:      hex.EncodeToString(swap.RefundTx),
+	}
+	if swap.Secret != nil {
+		val.Secret = hex.EncodeToString(swap.Secret[:])
+	}
+	return json.Marshal(val)
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface. The secret hash and
+// the lock time are checked against the contract, and the secret against the
+// secret hash.
+func (swap *Swap) UnmarshalJSON(data []byte) error {
+	val := swapJSON{}
+	if err := json.Unmarshal(data, &val); err != nil {
+		return err
+	}
+	contract, err := hex.DecodeString(val.Contract)
+	if err != nil {
+		return err
+	}
+	secretHash, lockTime, err := parseHTLC(contract)
+	if err != nil {
+		return err
+	}
+	if hex.EncodeToString(secretHash[:]) != val.SecretHash {
+		return fmt.Errorf("invalid secret hash: got: %s required: %x", val.SecretHash, secretHash)
+	}
+	if lockTime != val.LockTime {
+		return fmt.Errorf("invalid lock time: got: %d required: %d", val.LockTime, lockTime)
+	}
+	restored := Swap{
+		Contract:      contract,
+		Counterparty:  val.Counterparty,
+		SecretHash:    secretHash,
+		LockTime:      lockTime,
+		Value:         val.Value,
+		FundingTxHash: val.FundingTxHash,
+	}
+	if val.Secret != "" {
+		secretBytes, err := hex.DecodeString(val.Secret)
+		if err != nil {
+			return err
+		}
+		if len(secretBytes) != 32 || sha256.Sum256(secretBytes) != secretHash {
+			return fmt.Errorf("secret does not match the secret hash %x", secretHash)
+		}
+		secret := [32]byte{}
+		copy(secret[:], secretBytes)
+		restored.Secret = &secret
+	}
+	if val.RefundTx != "" {
+		if restored.RefundTx, err = hex.DecodeString(val.RefundTx); err != nil {
+			return err
+		}
+		if _, err := DecodeTransaction(restored.RefundTx); err != nil {
+			return fmt.Errorf("invalid refund transaction: %v", err)
+		}
+	}
+	*swap = restored
+	return nil
+}
+
+// ExportSwaps serializes the swaps to a portable JSON blob, which can be
+// restored with ImportSwaps.
+func ExportSwaps(swaps []Swap) ([]byte, error) {
+	if swaps == nil {
+		swaps = []Swap{}
+	}
+	return json.Marshal(swapsJSON{swapsVersion, swaps})
+}
+
+// ImportSwaps restores the swaps exported by ExportSwaps.
+func ImportSwaps(data []byte) ([]Swap, error) {
+	val := swapsJSON{}
+	if err := json.Unmarshal(data, &val); err != nil {
+		return nil, err
+	}
+	if val.Version != swapsVersion {
+		return nil, fmt.Errorf("unsupported swaps version: got: %d required: %d", val.Version, swapsVersion)
+	}
+	return val.Swaps, nil
+}
+
+// parseHTLC returns the secret hash and the lock time of a hash time locked
+// contract created by HTLCScript.
+func parseHTLC(script []byte) ([32]byte, int64, error) {
+	info, err := classifyHTLC(script)
+	if err != nil {
+		return [32]byte{}, 0, err
+	}
+	secretHash := [32]byte{}
+	copy(secretHash[:], info.SecretHash)
+	return secretHash, info.LockTime, nil
+}
@@ -0,0 +1,65 @@
+package libzec_test
+
+import (
+	"crypto/sha256"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/renproject/libzec-go"
+)
+
+var _ = Describe("Swap export", func() {
+	secret := [32]byte{1, 2, 3}
+	secretHash := sha256.Sum256(secret[:])
+	contract, err := HTLCScript(make([]byte, 20), make([]byte, 20), secretHash, 1600000000)
+	if err != nil {
+		panic(err)
+	}
+
+	It("should restore the exported swaps", func() {
+		swap, err := NewSwap(contract, "tmNzrdyJ8e6ozpLYpvGwDZEA5NqLp9TqSxH", 100000)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(swap.SecretHash).Should(Equal(secretHash))
+		Expect(swap.LockTime).Should(Equal(int64(1600000000)))
+		swap.Secret = &secret
+
+		data, err := ExportSwaps([]Swap{swap})
+		Expect(err).ShouldNot(HaveOccurred())
+		swaps, err := ImportSwaps(data)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(swaps).Should(Equal([]Swap{swap}))
+	})
+
+	It("should read the lock times of contracts pushed as small integers", func() {
+		for _, lockTime := range []int64{0, 16} {
+			small, err := HTLCScript(make([]byte, 20), make([]byte, 20), secretHash, lockTime)
+			Expect(err).ShouldNot(HaveOccurred())
+			swap, err := NewSwap(small, "tmNzrdyJ8e6ozpLYpvGwDZEA5NqLp9TqSxH", 100000)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(swap.SecretHash).Should(Equal(secretHash))
+			Expect(swap.LockTime).Should(Equal(lockTime))
+
+			data, err := ExportSwaps([]Swap{swap})
+			Expect(err).ShouldNot(HaveOccurred())
+			swaps, err := ImportSwaps(data)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(swaps).Should(Equal([]Swap{swap}))
+		}
+	})
+
+	It("should not create swaps of other scripts", func() {
+		_, err := NewSwap(make([]byte, 20), "tmNzrdyJ8e6ozpLYpvGwDZEA5NqLp9TqSxH", 100000)
+		Expect(err).Should(HaveOccurred())
+	})
+
+	It("should reject a secret that does not match the contract", func() {
+		swap, err := NewSwap(contract, "tmNzrdyJ8e6ozpLYpvGwDZEA5NqLp9TqSxH", 100000)
+		Expect(err).ShouldNot(HaveOccurred())
+		swap.Secret = &[32]byte{4}
+
+		data, err := ExportSwaps([]Swap{swap})
+		Expect(err).ShouldNot(HaveOccurred())
+		_, err = ImportSwaps(data)
+		Expect(err).Should(HaveOccurred())
+	})
+})
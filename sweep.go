@@ -0,0 +1,199 @@
+package libzec
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/renproject/libzec-go/clients"
+)
+
+// SweepBatchSize is the maximum number of slave utxos spent by a single sweep
+// transaction.
+const SweepBatchSize = 50
+
+// SweepSlaves discovers the funded slave scripts of the account, created with
+// the account's public key hash and the given nonces, and sweeps them back to
+// the account's address. The utxos are spent in batches of SweepBatchSize, and
+// a receipt is returned for every transaction that was submitted.
+func (account *account) SweepSlaves(ctx context.Context, nonces [][]byte, speed TxExecutionSpeed) ([]TxReceipt, error) {
+	utxos, scripts, err := account.slaveUTXOs(nonces)
+	if err != nil {
+		return nil, err
+	}
+	account.Logger.Infof("sweeping %d utxos from %d slave addresses", len(utxos), len(nonces))
+
+	receipts := []TxReceipt{}
+	for start := 0; start < len(utxos); start += SweepBatchSize {
+		end := start + SweepBatchSize
+		if end > len(utxos) {
+			end = len(utxos)
+		}
+
+		tx, err := account.buildSweep(ctx, utxos[start:end], scripts[start:end], speed)
+		if err == errSweepDust {
+			account.Logger.Infof("skipping %d utxos: worth less than the fee", end-start)
+			continue
+		}
+		if err != nil {
+			return receipts, err
+		}
+
+		if err := tx.sign(ctx, nil, nil, nil); err != nil {
+			return receipts, err
+		}
+		select {
+		case <-ctx.Done():
+			return receipts, ctx.Err()
+		default:
+		}
+		if err := tx.submit(); err != nil {
+			return receipts, fmt.Errorf("cannot submit sweep transaction %d: %v", len(receipts), err)
+		}
+		receipt, err := tx.receipt()
+		if err != nil {
+			return receipts, err
+		}
+		receipts = append(receipts, receipt)
+	}
+	return receipts, nil
+}
+
+// BuildSweep builds the unsigned transaction that sweeps the funded slave
+// scripts of the given nonces back to the account's address, spending at most
+// SweepBatchSize utxos. The hashes of the transaction can be signed by the
+// key of the account outside of the process, such as by an MPC network.
+func (account *account) BuildSweep(ctx context.Context, nonces [][]byte, speed TxExecutionSpeed) (Tx, error) {
+	utxos, scripts, err := account.sweepBatch(nonces)
+	if err != nil {
+		return nil, err
+	}
+	tx, err := account.buildSweep(ctx, utxos, scripts, speed)
+	if err != nil {
+		return nil, err
+	}
+	return tx.unsigned()
+}
+
+// BuildSponsoredSweep builds the unsigned transaction that sweeps the funded
+// slave scripts of the given nonces back to the account's address without
+// paying a fee. Its inputs are signed with SigHashAll|SigHashAnyOneCanPay, so
+// once it is signed a sponsor can add the inputs that pay the fee, using
+// TopUpFee, without invalidating the signatures.
+func (account *account) BuildSponsoredSweep(ctx context.Context, nonces [][]byte) (Tx, error) {
+	utxos, scripts, err := account.sweepBatch(nonces)
+	if err != nil {
+		return nil, err
+	}
+	tx, value, err := account.newSweepTx(utxos, scripts)
+	if err != nil {
+		return nil, err
+	}
+	if value < ZCashDust {
+		return nil, errSweepDust
+	}
+	tx.hashType = txscript.SigHashAll | txscript.SigHashAnyOneCanPay
+	return tx.unsigned()
+}
+
+// sweepBatch returns the first SweepBatchSize spendable utxos of the slave
+// scripts of the nonces, and the slave script of every utxo.
+func (account *account) sweepBatch(nonces [][]byte) ([]clients.UTXO, [][]byte, error) {
+	utxos, scripts, err := account.slaveUTXOs(nonces)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(utxos) == 0 {
+		return nil, nil, fmt.Errorf("no funded slave scripts to sweep")
+	}
+	if len(utxos) > SweepBatchSize {
+		utxos, scripts = utxos[:SweepBatchSize], scripts[:SweepBatchSize]
+	}
+	return utxos, scripts, nil
+}
+
+// errSweepDust is returned when the slave utxos are not worth the fee of the
+// transaction that sweeps them.
+var errSweepDust = fmt.Errorf("sweep is worth less than the fee")
+
+// slaveUTXOs returns the spendable utxos of the slave scripts of the nonces,
+// and the slave script of every utxo.
+func (account *account) slaveUTXOs(nonces [][]byte) ([]clients.UTXO, [][]byte, error) {
+	pubKeyBytes, err := account.SerializedPublicKey()
+	if err != nil {
+		return nil, nil, err
+	}
+	mpkh := btcutil.Hash160(pubKeyBytes)
+
+	utxos := []clients.UTXO{}
+	scripts := [][]byte{}
+	for _, nonce := range nonces {
+		script, err := account.SlaveScript(mpkh, nonce)
+		if err != nil {
+			return nil, nil, err
+		}
+		address, err := ScriptAddress(script, account.NetworkParams())
+		if err != nil {
+			return nil, nil, err
+		}
+		slaveUTXOs, err := account.GetUTXOs(address.EncodeAddress(), 0, 0)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, utxo := range account.spendable(slaveUTXOs) {
+			utxos = append(utxos, utxo)
+			scripts = append(scripts, script)
+		}
+	}
+	return utxos, scripts, nil
+}
+
+// buildSweep builds a transaction spending the slave utxos to the account's
+// address.
+func (account *account) buildSweep(ctx context.Context, utxos []clients.UTXO, scripts [][]byte, speed TxExecutionSpeed) (*tx, error) {
+	tx, value, err := account.newSweepTx(utxos, scripts)
+	if err != nil {
+		return nil, err
+	}
+	fee, err := account.payFee(ctx, tx, speed)
+	if err == errFeeExceedsOutput {
+		return nil, errSweepDust
+	}
+	if err != nil {
+		return nil, err
+	}
+	if value-fee < ZCashDust {
+		return nil, errSweepDust
+	}
+	return tx, nil
+}
+
+// newSweepTx returns a transaction spending the whole value of the slave utxos
+// to the account's address, before the fee is deducted, and that value.
+func (account *account) newSweepTx(utxos []clients.UTXO, scripts [][]byte) (*tx, int64, error) {
+	me, err := account.Address()
+	if err != nil {
+		return nil, 0, err
+	}
+	P2PKHScript, err := PayToAddrScript(me)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	tx, err := account.newTx(wire.NewMsgTx(4))
+	if err != nil {
+		return nil, 0, err
+	}
+	var value int64
+	for i := range utxos {
+		if err := tx.addScriptInput(utxos[i], scripts[i]); err != nil {
+			return nil, 0, err
+		}
+		value += utxos[i].Amount
+	}
+	tx.msgTx.AddTxOut(wire.NewTxOut(value, P2PKHScript))
+	tx.sendAll = true
+	return tx, value, nil
+}
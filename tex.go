@@ -0,0 +1,98 @@
+package libzec
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
+)
+
+// TEXAddress is a transparent-source-only address, as specified in ZIP-320.
+// It is paid using a P2PKH script, but wallets must only pay it from
+// transparent inputs, which is always the case for the transactions built by
+// this library.
+type TEXAddress struct {
+	hash [20]byte
+	net  Network
+}
+
+// NewTEXAddress returns the TEX address of the public key hash.
+func NewTEXAddress(pubKeyHash [20]byte, params *chaincfg.Params) (*TEXAddress, error) {
+	network, err := NetworkOf(params)
+	if err != nil {
+		return nil, err
+	}
+	return &TEXAddress{pubKeyHash, network}, nil
+}
+
+// DecodeTEXAddress decodes a TEX address of the network.
+func DecodeTEXAddress(address string, params *chaincfg.Params) (*TEXAddress, error) {
+	encoding, err := encodingOf(params)
+	if err != nil {
+		return nil, err
+	}
+	hrp := encoding.texHRP
+	decodedHRP, data, err := bech32Decode(address, bech32m)
+	if err != nil {
+		return nil, err
+	}
+	if decodedHRP != hrp {
+		return nil, fmt.Errorf("invalid tex address prefix: got: %s required: %s", decodedHRP, hrp)
+	}
+	if len(data) != 20 {
+		return nil, fmt.Errorf("invalid tex address length: got: %d required: %d", len(data), 20)
+	}
+	network, err := NetworkOf(params)
+	if err != nil {
+		return nil, err
+	}
+	addr := &TEXAddress{net: network}
+	copy(addr.hash[:], data)
+	return addr, nil
+}
+
+// isTEXAddress returns whether the address looks like a TEX address of any
+// network.
+func isTEXAddress(address string) bool {
+	address = strings.ToLower(address)
+	for _, encoding := range networkEncodings {
+		if strings.HasPrefix(address, encoding.texHRP+"1") {
+			return true
+		}
+	}
+	return false
+}
+
+func (addr *TEXAddress) String() string {
+	return addr.EncodeAddress()
+}
+
+func (addr *TEXAddress) EncodeAddress() string {
+	encoded, err := bech32Encode(networkEncodings[addr.net].texHRP, addr.hash[:], bech32m)
+	if err != nil {
+		return ""
+	}
+	return encoded
+}
+
+// ScriptAddress returns the public key hash of the address.
+func (addr *TEXAddress) ScriptAddress() []byte {
+	return addr.hash[:]
+}
+
+func (addr *TEXAddress) IsForNet(params *chaincfg.Params) bool {
+	network, err := NetworkOf(params)
+	return err == nil && addr.net == network
+}
+
+// PayToAddrScript returns the P2PKH script of the public key hash.
+func (addr *TEXAddress) PayToAddrScript() ([]byte, error) {
+	return txscript.NewScriptBuilder().
+		AddOp(txscript.OP_DUP).
+		AddOp(txscript.OP_HASH160).
+		AddData(addr.hash[:]).
+		AddOp(txscript.OP_EQUALVERIFY).
+		AddOp(txscript.OP_CHECKSIG).
+		Script()
+}
@@ -0,0 +1,47 @@
+package libzec_test
+
+import (
+	"encoding/hex"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/renproject/libzec-go"
+)
+
+var _ = Describe("TEX addresses", func() {
+	// Test vector of ZIP-320.
+	const texAddress = "tex1s2rt77ggv6q989lr49rkgzmh5slsksa9khdgte"
+	const pubKeyHash = "8286bf790866805397e3a947640b77a43f0b43a5"
+
+	It("should encode the public key hash", func() {
+		hashBytes, err := hex.DecodeString(pubKeyHash)
+		Expect(err).Should(BeNil())
+		hash := [20]byte{}
+		copy(hash[:], hashBytes)
+
+		addr, err := NewTEXAddress(hash, &chaincfg.MainNetParams)
+		Expect(err).Should(BeNil())
+		Expect(addr.EncodeAddress()).Should(Equal(texAddress))
+	})
+
+	It("should decode to a p2pkh script", func() {
+		addr, err := DecodeAddress(texAddress, &chaincfg.MainNetParams)
+		Expect(err).Should(BeNil())
+		Expect(hex.EncodeToString(addr.ScriptAddress())).Should(Equal(pubKeyHash))
+
+		script, err := PayToAddrScript(addr)
+		Expect(err).Should(BeNil())
+		Expect(hex.EncodeToString(script)).Should(Equal("76a914" + pubKeyHash + "88ac"))
+	})
+
+	It("should not decode addresses of another network", func() {
+		_, err := DecodeAddress(texAddress, &chaincfg.TestNet3Params)
+		Expect(err).ShouldNot(BeNil())
+	})
+
+	It("should not decode addresses with an invalid checksum", func() {
+		_, err := DecodeAddress(texAddress[:len(texAddress)-1]+"q", &chaincfg.MainNetParams)
+		Expect(err).ShouldNot(BeNil())
+	})
+})
@@ -0,0 +1,232 @@
+package libzec
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"sort"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/iqoption/zecutil"
+	"github.com/renproject/libzec-go/clients"
+)
+
+// TopUpFee adds inputs spending the utxos of the account to the signed
+// transaction, whose inputs must all be signed with
+// SigHashAll|SigHashAnyOneCanPay, and submits it. The transaction commits to
+// its outputs, so the whole value of the added inputs is paid as fee: the
+// smallest utxo worth at least the fee is spent if there is one, otherwise the
+// largest utxos are spent until the fee is covered. The value paid must be
+// within the fee limits of the account, and the top up is refused if it pays
+// more than MaxTopUpOverpayment times the fee. The Fee of the receipt is the
+// value paid by the account.
+func (account *account) TopUpFee(ctx context.Context, stx []byte, fee int64) (TxReceipt, error) {
+	if account.Signer == nil {
+		return TxReceipt{}, ErrWatchOnly
+	}
+	decoded, err := DecodeTransaction(stx)
+	if err != nil {
+		return TxReceipt{}, err
+	}
+	msgTx, err := decoded.MsgTx()
+	if err != nil {
+		return TxReceipt{}, err
+	}
+	if err := account.feeLimits.check(fee); err != nil {
+		return TxReceipt{}, err
+	}
+	branchID, err := account.consensusBranchID()
+	if err != nil {
+		return TxReceipt{}, err
+	}
+	if err := account.checkSponsoredInputs(msgTx, branchID); err != nil {
+		return TxReceipt{}, err
+	}
+
+	me, err := account.Address()
+	if err != nil {
+		return TxReceipt{}, err
+	}
+	utxos, err := account.GetUTXOs(me.EncodeAddress(), 0, 0)
+	if err != nil {
+		return TxReceipt{}, err
+	}
+	spendable := account.spendable(utxos)
+	inputs, ok := selectTopUp(spendable, fee)
+	if !ok {
+		return TxReceipt{}, NewErrInsufficientBalance(me.EncodeAddress(), fee, sumUTXOs(spendable))
+	}
+	paid := sumUTXOs(inputs)
+	if err := account.feeLimits.check(paid); err != nil {
+		return TxReceipt{}, err
+	}
+	if paid > MaxTopUpOverpayment*fee {
+		return TxReceipt{}, fmt.Errorf("top up overpays the fee: got: %d required: %d", paid, fee)
+	}
+
+	first := len(msgTx.TxIn)
+	scriptPubKeys := make([][]byte, len(inputs))
+	for i, utxo := range inputs {
+		if scriptPubKeys[i], err = hex.DecodeString(utxo.ScriptPubKey); err != nil {
+			return TxReceipt{}, err
+		}
+		hash, err := chainhash.NewHashFromStr(utxo.TxHash)
+		if err != nil {
+			return TxReceipt{}, err
+		}
+		msgTx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(hash, utxo.Vout), []byte{}, [][]byte{}))
+	}
+
+	// Only the added inputs are signed. They are signed with SigHashAll, as
+	// the sponsor has no reason to let the transaction be modified further.
+	hasher, err := newSigHasher(msgTx, branchSigHashKey(branchID), nil)
+	if err != nil {
+		return TxReceipt{}, err
+	}
+	hashes := make([][]byte, len(inputs))
+	for i, utxo := range inputs {
+		if hashes[i], err = hasher.hash(scriptPubKeys[i], txscript.SigHashAll, first+i, utxo.Amount); err != nil {
+			return TxReceipt{}, err
+		}
+	}
+	sigs, err := account.Signer.Sign(ctx, hashes)
+	if err != nil {
+		return TxReceipt{}, err
+	}
+	if len(sigs) != len(hashes) {
+		return TxReceipt{}, fmt.Errorf("invalid number of signatures: got: %d required: %d", len(sigs), len(hashes))
+	}
+	serializedPublicKey, err := account.SerializedPublicKey()
+	if err != nil {
+		return TxReceipt{}, err
+	}
+	for i := range inputs {
+		sig, err := verifySig(sigs[i], hashes[i], account.PubKey)
+		if err != nil {
+			return TxReceipt{}, NewErrInvalidSignature(first+i, err.Error())
+		}
+		builder := txscript.NewScriptBuilder()
+		builder.AddData(append(sig.Serialize(), byte(txscript.SigHashAll)))
+		builder.AddData(serializedPublicKey)
+		if msgTx.TxIn[first+i].SignatureScript, err = builder.Script(); err != nil {
+			return TxReceipt{}, err
+		}
+	}
+
+	select {
+	case <-ctx.Done():
+		return TxReceipt{}, ctx.Err()
+	default:
+	}
+	buf := new(bytes.Buffer)
+	if err := msgTx.ZecEncode(buf, 0, wire.BaseEncoding); err != nil {
+		return TxReceipt{}, err
+	}
+	if err := account.publish(msgTx, buf.Bytes()); err != nil {
+		return TxReceipt{}, err
+	}
+	txHash, err := txHashString(msgTx)
+	if err != nil {
+		return TxReceipt{}, err
+	}
+	account.Logger.Infof("topped up the fee of %s by %d", txHash, paid)
+	return TxReceipt{
+		TxHash:       txHash,
+		Inputs:       inputs,
+		Outputs:      msgTx.TxOut,
+		Fee:          paid,
+		ChangeIndex:  -1,
+		ExpiryHeight: msgTx.ExpiryHeight,
+	}, nil
+}
+
+// MaxTopUpOverpayment bounds the value paid by TopUpFee, as a multiple of the
+// requested fee. The transaction cannot carry change for the sponsor, so the
+// value of the selected utxos beyond the fee is burnt.
+const MaxTopUpOverpayment = 2
+
+// checkSponsoredInputs checks that every input of the transaction is validly
+// signed for the given consensus branch, and only with
+// SigHashAll|SigHashAnyOneCanPay signatures, so that the sponsor's inputs can
+// be added without invalidating them and the outputs cannot be changed once
+// the sponsor has paid.
+func (account *account) checkSponsoredInputs(msgTx *zecutil.MsgTx, branchID uint32) error {
+	for i, txIn := range msgTx.TxIn {
+		if !signedAnyoneCanPay(txIn.SignatureScript) {
+			return fmt.Errorf("input %d is not signed with SigHashAll|SigHashAnyOneCanPay", i)
+		}
+		utxo, err := account.GetUTXO(txIn.PreviousOutPoint.Hash.String(), txIn.PreviousOutPoint.Index)
+		if err != nil {
+			return fmt.Errorf("cannot fetch input %d: %v", i, err)
+		}
+		scriptPubKey, err := hex.DecodeString(utxo.ScriptPubKey)
+		if err != nil {
+			return err
+		}
+		if err := verifyScript(msgTx, i, scriptPubKey, utxo.Amount, branchSigHashKey(branchID)); err != nil {
+			return NewErrInvalidSignature(i, err.Error())
+		}
+	}
+	return nil
+}
+
+// signedAnyoneCanPay returns whether the signature script carries signatures,
+// all of them with the SigHashAll|SigHashAnyOneCanPay hash type. Signatures are
+// the pushes that are DER encoded signatures followed by a hash type, which
+// skips the dummy element of multisig scripts, public keys and redeem scripts.
+func signedAnyoneCanPay(sigScript []byte) bool {
+	pushes, err := txscript.PushedData(sigScript)
+	if err != nil {
+		return false
+	}
+	var signed bool
+	for _, push := range pushes {
+		if len(push) == 0 {
+			continue
+		}
+		if _, err := btcec.ParseDERSignature(push[:len(push)-1], btcec.S256()); err != nil {
+			continue
+		}
+		if txscript.SigHashType(push[len(push)-1]) != txscript.SigHashAll|txscript.SigHashAnyOneCanPay {
+			return false
+		}
+		signed = true
+	}
+	return signed
+}
+
+// selectTopUp selects the utxos that pay the fee of a sponsored transaction,
+// wasting as little value as possible: the smallest utxo worth at least the
+// fee, or the largest utxos until the fee is covered. It returns false if the
+// utxos are not worth the fee.
+func selectTopUp(utxos []clients.UTXO, fee int64) ([]clients.UTXO, bool) {
+	sorted := make([]clients.UTXO, len(utxos))
+	copy(sorted, utxos)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Amount < sorted[j].Amount
+	})
+	for _, utxo := range sorted {
+		if utxo.Amount >= fee {
+			return []clients.UTXO{utxo}, true
+		}
+	}
+	var selected []clients.UTXO
+	var total int64
+	for i := len(sorted) - 1; i >= 0 && total < fee; i-- {
+		selected = append(selected, sorted[i])
+		total += sorted[i].Amount
+	}
+	return selected, total >= fee
+}
+
+func sumUTXOs(utxos []clients.UTXO) int64 {
+	var total int64
+	for _, utxo := range utxos {
+		total += utxo.Amount
+	}
+	return total
+}
@@ -0,0 +1,131 @@
+package libzec_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/iqoption/zecutil"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/renproject/libzec-go"
+)
+
+var _ = Describe("Fee top ups", func() {
+	// sponsored returns a transaction spending a new utxo of the given amount
+	// to a single output of the same amount, signed with the given hash type.
+	// The output is changed after signing if tamper is true.
+	sponsored := func(core *mockClientCore, amount int64, hashType txscript.SigHashType, tamper bool) []byte {
+		_, privKey, addr := newMockAccount(core, 0)
+		utxo := core.addUTXO(addr, chainhash.Hash{0xAC, byte(len(core.utxos))}, amount, 1)
+		scriptPubKey, err := hex.DecodeString(utxo.ScriptPubKey)
+		Expect(err).Should(BeNil())
+		hash, err := chainhash.NewHashFromStr(utxo.TxHash)
+		Expect(err).Should(BeNil())
+
+		msgTx := &zecutil.MsgTx{
+			MsgTx:        wire.NewMsgTx(4),
+			ExpiryHeight: 1842440,
+		}
+		msgTx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(hash, utxo.Vout), []byte{}, [][]byte{}))
+		msgTx.AddTxOut(wire.NewTxOut(amount, scriptPubKey))
+		sigHash, err := CalcSignatureHash(scriptPubKey, hashType, msgTx, 0, amount, 0xC2D6D0B4)
+		Expect(err).Should(BeNil())
+		sig, err := privKey.Sign(sigHash)
+		Expect(err).Should(BeNil())
+		msgTx.TxIn[0].SignatureScript, err = txscript.NewScriptBuilder().
+			AddData(append(sig.Serialize(), byte(hashType))).
+			AddData(privKey.PubKey().SerializeCompressed()).
+			Script()
+		Expect(err).Should(BeNil())
+		if tamper {
+			msgTx.TxOut[0].Value--
+		}
+
+		buf := new(bytes.Buffer)
+		Expect(msgTx.ZecEncode(buf, 0, wire.BaseEncoding)).Should(BeNil())
+		return buf.Bytes()
+	}
+
+	anyoneCanPay := txscript.SigHashAll | txscript.SigHashAnyOneCanPay
+
+	It("should pay the fee of a sponsored transaction", func() {
+		core := newMockClientCore(&chaincfg.TestNet3Params, 1842420)
+		sponsor, _, _ := newMockAccount(core, 3000)
+		stx := sponsored(core, 100000, anyoneCanPay, false)
+
+		receipt, err := sponsor.TopUpFee(context.Background(), stx, 2000)
+		Expect(err).Should(BeNil())
+		Expect(receipt.Fee).Should(Equal(int64(3000)))
+		Expect(core.published).Should(HaveLen(1))
+
+		decoded, err := DecodeTransaction(core.published[0])
+		Expect(err).Should(BeNil())
+		msgTx, err := decoded.MsgTx()
+		Expect(err).Should(BeNil())
+		Expect(msgTx.TxIn).Should(HaveLen(2))
+		for i, input := range receipt.Inputs {
+			scriptPubKey, err := hex.DecodeString(input.ScriptPubKey)
+			Expect(err).Should(BeNil())
+			Expect(VerifyScript(msgTx, i+1, scriptPubKey, input.Amount, 0xC2D6D0B4)).Should(BeNil())
+		}
+	})
+
+	It("should not burn a utxo worth much more than the fee", func() {
+		core := newMockClientCore(&chaincfg.TestNet3Params, 1842420)
+		sponsor, _, _ := newMockAccount(core, 100000)
+		stx := sponsored(core, 100000, anyoneCanPay, false)
+
+		_, err := sponsor.TopUpFee(context.Background(), stx, 2000)
+		Expect(err).ShouldNot(BeNil())
+		Expect(core.published).Should(BeEmpty())
+	})
+
+	It("should not pay a fee outside of the fee limits", func() {
+		core := newMockClientCore(&chaincfg.TestNet3Params, 1842420)
+		sponsor, _, _ := newMockAccount(core, 3000)
+		sponsor.SetFeeLimits(0, 1000)
+		stx := sponsored(core, 100000, anyoneCanPay, false)
+
+		_, err := sponsor.TopUpFee(context.Background(), stx, 2000)
+		Expect(err).ShouldNot(BeNil())
+		Expect(core.published).Should(BeEmpty())
+
+		sponsor.SetFeeLimits(0, 2500)
+		_, err = sponsor.TopUpFee(context.Background(), stx, 2000)
+		Expect(err).ShouldNot(BeNil())
+		Expect(core.published).Should(BeEmpty())
+
+		_, err = sponsor.TopUpFee(context.Background(), stx, DefaultMaxFee+1)
+		Expect(err).ShouldNot(BeNil())
+	})
+
+	It("should reject inputs that do not commit to the outputs", func() {
+		core := newMockClientCore(&chaincfg.TestNet3Params, 1842420)
+		sponsor, _, _ := newMockAccount(core, 3000)
+
+		for _, hashType := range []txscript.SigHashType{
+			txscript.SigHashAll,
+			txscript.SigHashNone | txscript.SigHashAnyOneCanPay,
+			txscript.SigHashSingle | txscript.SigHashAnyOneCanPay,
+		} {
+			_, err := sponsor.TopUpFee(context.Background(), sponsored(core, 100000, hashType, false), 2000)
+			Expect(err).ShouldNot(BeNil())
+		}
+		Expect(core.published).Should(BeEmpty())
+	})
+
+	It("should reject inputs with invalid signatures", func() {
+		core := newMockClientCore(&chaincfg.TestNet3Params, 1842420)
+		sponsor, _, _ := newMockAccount(core, 3000)
+		stx := sponsored(core, 100000, anyoneCanPay, true)
+
+		_, err := sponsor.TopUpFee(context.Background(), stx, 2000)
+		Expect(err).ShouldNot(BeNil())
+		Expect(core.published).Should(BeEmpty())
+	})
+})
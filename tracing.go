@@ -0,0 +1,172 @@
+package libzec
+
+import (
+	"context"
+	"encoding/hex"
+	"sync"
+
+	"github.com/renproject/libzec-go/clients"
+)
+
+// A Tracer starts the spans that instrument client calls, building, signing
+// and broadcasting transactions. It has the same shape as an OpenTelemetry
+// tracer, and the oteltrace package adapts OpenTelemetry tracers, so that the
+// library itself does not depend on OpenTelemetry.
+type Tracer interface {
+	Start(ctx context.Context, spanName string) (context.Context, Span)
+}
+
+// A Span is an operation traced by a Tracer.
+type Span interface {
+	// SetAttribute sets an attribute of the span, such as the txid of the
+	// transaction or the address involved in the operation.
+	SetAttribute(key, value string)
+
+	// RecordError records that the operation failed.
+	RecordError(err error)
+
+	// End ends the span.
+	End()
+}
+
+// Span attributes set by the library.
+const (
+	AttributeTxID    = "zcash.txid"
+	AttributeAddress = "zcash.address"
+	AttributeInputs  = "zcash.inputs"
+)
+
+var (
+	tracerMu = new(sync.RWMutex)
+	tracer   = Tracer(noopTracer{})
+)
+
+// SetTracer sets the tracer used by the library. By default spans are
+// discarded.
+func SetTracer(t Tracer) {
+	tracerMu.Lock()
+	defer tracerMu.Unlock()
+	if t == nil {
+		t = noopTracer{}
+	}
+	tracer = t
+}
+
+// startSpan starts a span, with the given attributes as key value pairs.
+func startSpan(ctx context.Context, name string, attributes ...string) (context.Context, Span) {
+	tracerMu.RLock()
+	t := tracer
+	tracerMu.RUnlock()
+
+	ctx, span := t.Start(ctx, name)
+	for i := 0; i+1 < len(attributes); i += 2 {
+		span.SetAttribute(attributes[i], attributes[i+1])
+	}
+	return ctx, span
+}
+
+// endSpan records the error, if any, and ends the span.
+func endSpan(span Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+	}
+	span.End()
+}
+
+type noopTracer struct{}
+
+func (noopTracer) Start(ctx context.Context, spanName string) (context.Context, Span) {
+	return ctx, noopSpan{}
+}
+
+type noopSpan struct{}
+
+func (noopSpan) SetAttribute(key, value string) {}
+func (noopSpan) RecordError(err error)          {}
+func (noopSpan) End()                           {}
+
+type tracedClientCore struct {
+	clients.ClientCore
+}
+
+// NewTracedClientCore wraps the client core, so that every call to the backend
+// is traced using the tracer set by SetTracer. The calls of a client core do
+// not take a context, so their spans are the roots of their own traces.
+func NewTracedClientCore(core clients.ClientCore) clients.ClientCore {
+	return tracedClientCore{core}
+}
+
+// Unwrap returns the traced client core. Calls to the extensions of the core,
+// such as clients.BatchClient, are not traced.
+func (core tracedClientCore) Unwrap() clients.ClientCore {
+	return core.ClientCore
+}
+
+func (core tracedClientCore) GetUTXO(txHash string, vout uint32) (utxo clients.UTXO, err error) {
+	_, span := startSpan(context.Background(), "zcash.GetUTXO", AttributeTxID, txHash)
+	defer func() { endSpan(span, err) }()
+	return core.ClientCore.GetUTXO(txHash, vout)
+}
+
+func (core tracedClientCore) GetUTXOs(address string, limit, confirmations int64) (utxos []clients.UTXO, err error) {
+	_, span := startSpan(context.Background(), "zcash.GetUTXOs", AttributeAddress, address)
+	defer func() { endSpan(span, err) }()
+	return core.ClientCore.GetUTXOs(address, limit, confirmations)
+}
+
+func (core tracedClientCore) AddressBalance(address string, confirmations int64) (balance int64, err error) {
+	_, span := startSpan(context.Background(), "zcash.AddressBalance", AttributeAddress, address)
+	defer func() { endSpan(span, err) }()
+	return core.ClientCore.AddressBalance(address, confirmations)
+}
+
+func (core tracedClientCore) AddressUTXOCount(address string, confirmations int64) (count int, err error) {
+	_, span := startSpan(context.Background(), "zcash.AddressUTXOCount", AttributeAddress, address)
+	defer func() { endSpan(span, err) }()
+	return core.ClientCore.AddressUTXOCount(address, confirmations)
+}
+
+func (core tracedClientCore) Confirmations(txHash string) (conf int64, err error) {
+	_, span := startSpan(context.Background(), "zcash.Confirmations", AttributeTxID, txHash)
+	defer func() { endSpan(span, err) }()
+	return core.ClientCore.Confirmations(txHash)
+}
+
+func (core tracedClientCore) BlockHeight() (height int64, err error) {
+	_, span := startSpan(context.Background(), "zcash.BlockHeight")
+	defer func() { endSpan(span, err) }()
+	return core.ClientCore.BlockHeight()
+}
+
+func (core tracedClientCore) ScriptFunded(address string, value int64) (funded bool, balance int64, err error) {
+	_, span := startSpan(context.Background(), "zcash.ScriptFunded", AttributeAddress, address)
+	defer func() { endSpan(span, err) }()
+	return core.ClientCore.ScriptFunded(address, value)
+}
+
+func (core tracedClientCore) ScriptRedeemed(address string, value int64) (redeemed bool, balance int64, err error) {
+	_, span := startSpan(context.Background(), "zcash.ScriptRedeemed", AttributeAddress, address)
+	defer func() { endSpan(span, err) }()
+	return core.ClientCore.ScriptRedeemed(address, value)
+}
+
+func (core tracedClientCore) ScriptSpent(script, spender string) (spent bool, sigScript string, err error) {
+	_, span := startSpan(context.Background(), "zcash.ScriptSpent", AttributeAddress, script)
+	defer func() { endSpan(span, err) }()
+	return core.ClientCore.ScriptSpent(script, spender)
+}
+
+func (core tracedClientCore) PublishTransaction(stx []byte) (err error) {
+	_, span := startSpan(context.Background(), "zcash.PublishTransaction")
+	defer func() { endSpan(span, err) }()
+	if txID, err := TxID(stx); err == nil {
+		span.SetAttribute(AttributeTxID, hex.EncodeToString(txID))
+	}
+	return core.ClientCore.PublishTransaction(stx)
+}
+
+func (core tracedClientCore) Ping(ctx context.Context) (err error) {
+	ctx, span := startSpan(ctx, "zcash.Ping")
+	defer func() { endSpan(span, err) }()
+	return core.ClientCore.Ping(ctx)
+}
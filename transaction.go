@@ -2,38 +2,67 @@ package libzec
 
 import (
 	"bytes"
+	"context"
 	"encoding/hex"
 	"fmt"
+	"strconv"
 
 	"github.com/btcsuite/btcd/chaincfg/chainhash"
 	"github.com/btcsuite/btcd/txscript"
 	"github.com/btcsuite/btcd/wire"
 	"github.com/btcsuite/btcutil"
 	"github.com/iqoption/zecutil"
+	"github.com/renproject/libzec-go/clients"
 )
 
 const ZCashDust = 600
 const MaxZCashFee = int64(10000)
 const ZCashExpiryHeight = 6000000
 
+// CoinbaseMaturity is the number of confirmations after which the outputs of
+// a coinbase transaction can be spent.
+const CoinbaseMaturity = 100
+
+// ZCashExpiryDelta is the number of blocks, after the height of the chain when
+// they are built, after which transactions expire.
+const ZCashExpiryDelta = 20
+
 type tx struct {
-	receiveValues   []int64
-	scriptPublicKey []byte
-	account         *account
-	msgTx           *zecutil.MsgTx
+	receiveValues []int64
+	scriptPubKeys [][]byte
+	redeemScripts [][]byte
+	changeIndex   int
+	sendAll       bool
+	contract      []byte
+	unlock        []byte
+	account       *account
+	msgTx         *zecutil.MsgTx
+	hashType      txscript.SigHashType
 }
 
-func (account *account) newTx(msgtx *wire.MsgTx) *tx {
+// newTx returns a transaction that expires ZCashExpiryDelta blocks after the
+// current height of the chain.
+func (account *account) newTx(msgtx *wire.MsgTx) (*tx, error) {
+	expiryHeight, err := nextExpiryHeight(account.Client)
+	if err != nil {
+		return nil, err
+	}
 	return &tx{
 		msgTx: &zecutil.MsgTx{
 			MsgTx:        msgtx,
-			ExpiryHeight: ZCashExpiryHeight,
+			ExpiryHeight: expiryHeight,
 		},
-		account: account,
-	}
+		account:     account,
+		changeIndex: -1,
+		hashType:    txscript.SigHashAll,
+	}, nil
 }
 
-func (tx *tx) fund(addr btcutil.Address) error {
+// fund selects enough utxos of the address to pay for the outputs and the
+// reserved fee, and adds a change output unless the change left after paying
+// the reserved fee is dust. The actual fee is deducted from the change once
+// the size of the transaction is known.
+func (tx *tx) fund(addr btcutil.Address, reserve int64) error {
 	if addr == nil {
 		var err error
 		addr, err = tx.account.Address()
@@ -44,120 +73,197 @@ func (tx *tx) fund(addr btcutil.Address) error {
 
 	var value int64
 	for i, j := range tx.msgTx.TxOut {
-		if j.Value < 600 {
+		if j.Value < ZCashDust {
 			return fmt.Errorf("transaction's %d output value (%d) is less than zcash's minimum value (%d)", i, j.Value, ZCashDust)
 		}
 		value = value + j.Value
 	}
 
-	balance, err := tx.account.Balance(addr.EncodeAddress(), 0)
+	utxos, err := tx.account.GetUTXOs(addr.EncodeAddress(), 0, 0)
 	if err != nil {
 		return err
 	}
 
-	if value+MaxZCashFee > balance {
-		return NewErrInsufficientBalance(addr.EncodeAddress(), value+MaxZCashFee, balance)
-	}
-
-	utxos, err := tx.account.GetUTXOs(addr.EncodeAddress(), 999999, 0)
-	if err != nil {
-		return err
+	required := value + reserve
+	spendable := tx.account.spendable(utxos)
+	selected, ok := tx.account.coinSelection().SelectCoins(spendable, required)
+	if !ok {
+		return NewErrInsufficientBalance(addr.EncodeAddress(), required, sumUTXOs(spendable))
 	}
-
-	for _, j := range utxos {
-		ScriptPubKey, err := hex.DecodeString(j.ScriptPubKey)
-		if err != nil {
-			return err
-		}
-		if len(tx.scriptPublicKey) == 0 {
-			tx.scriptPublicKey = ScriptPubKey
-		} else {
-			if bytes.Compare(tx.scriptPublicKey, ScriptPubKey) != 0 {
-				continue
-			}
-		}
-		tx.receiveValues = append(tx.receiveValues, j.Amount)
-		hash, err := chainhash.NewHashFromStr(j.TxHash)
-		if err != nil {
+	var total int64
+	for _, j := range selected {
+		if err := tx.addInput(j); err != nil {
 			return err
 		}
-		tx.msgTx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(hash, j.Vout), []byte{}, [][]byte{}))
-		value = value - j.Amount
-		if value <= -MaxZCashFee {
-			break
-		}
+		total += j.Amount
 	}
 
-	if value > -MaxZCashFee {
-		return ErrMismatchedPubKeys
-	}
-
-	if value < -MaxZCashFee-ZCashDust {
+	// If the change, after paying the reserved fee, is dust it is left to the
+	// miners instead.
+	change := total - value
+	if change-reserve >= ZCashDust {
 		P2PKHScript, err := PayToAddrScript(addr)
 		if err != nil {
 			return err
 		}
-		tx.msgTx.AddTxOut(wire.NewTxOut(-value, P2PKHScript))
+		tx.changeIndex = len(tx.msgTx.TxOut)
+		tx.msgTx.AddTxOut(wire.NewTxOut(change, P2PKHScript))
 	}
 
 	return nil
 }
 
+// fundFrom funds the transaction using the utxos of the address, spending all
+// of them if sendAll is set, or reserving the given fee otherwise.
+func (tx *tx) fundFrom(ctx context.Context, addr btcutil.Address, sendAll bool, reserve int64) (err error) {
+	_, span := startSpan(ctx, "zcash.Fund", AttributeAddress, addr.EncodeAddress())
+	defer func() {
+		span.SetAttribute(AttributeInputs, strconv.Itoa(len(tx.msgTx.TxIn)))
+		endSpan(span, err)
+	}()
+	if sendAll {
+		return tx.fundAll(addr)
+	}
+	return tx.fund(addr, reserve)
+}
+
+// unfund removes the inputs and the change output added by fund.
+func (tx *tx) unfund() {
+	if tx.changeIndex >= 0 {
+		tx.msgTx.TxOut = append(tx.msgTx.TxOut[:tx.changeIndex], tx.msgTx.TxOut[tx.changeIndex+1:]...)
+		tx.changeIndex = -1
+	}
+	tx.msgTx.TxIn = nil
+	tx.receiveValues = nil
+	tx.scriptPubKeys = nil
+	tx.redeemScripts = nil
+}
+
+// fundAll spends every spendable utxo of the address, and pays what is left
+// after the other outputs to the last output, which the fee is then deducted
+// from. The value is computed from the utxos that are spent, and not from the
+// balance of the address, which also counts frozen and immature coinbase
+// utxos.
 func (tx *tx) fundAll(addr btcutil.Address) error {
-	utxos, err := tx.account.GetUTXOs(addr.EncodeAddress(), 1000, 0)
+	tx.sendAll = true
+	if len(tx.msgTx.TxOut) == 0 {
+		return fmt.Errorf("cannot send all the utxos without an output")
+	}
+	utxos, err := tx.account.GetUTXOs(addr.EncodeAddress(), 0, 0)
 	if err != nil {
 		return err
 	}
-	for _, j := range utxos {
-		ScriptPubKey, err := hex.DecodeString(j.ScriptPubKey)
-		if err != nil {
+	spendable := tx.account.spendable(utxos)
+	for _, j := range spendable {
+		if err := tx.addInput(j); err != nil {
 			return err
 		}
-		if len(tx.scriptPublicKey) == 0 {
-			tx.scriptPublicKey = ScriptPubKey
-		} else {
-			if bytes.Compare(tx.scriptPublicKey, ScriptPubKey) != 0 {
-				continue
-			}
-		}
-		tx.receiveValues = append(tx.receiveValues, j.Amount)
-		hash, err := chainhash.NewHashFromStr(j.TxHash)
-		if err != nil {
-			return err
-		}
-		tx.msgTx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(hash, j.Vout), []byte{}, [][]byte{}))
 	}
+
+	value := sumUTXOs(spendable)
+	last := len(tx.msgTx.TxOut) - 1
+	for _, out := range tx.msgTx.TxOut[:last] {
+		value -= out.Value
+	}
+	if value < ZCashDust {
+		return NewErrInsufficientBalance(addr.EncodeAddress(), sumUTXOs(spendable)-value+ZCashDust, sumUTXOs(spendable))
+	}
+	tx.msgTx.TxOut[last].Value = value
 	return nil
 }
 
-func (tx *tx) sign(f func(*txscript.ScriptBuilder), updateTxIn func(*wire.TxIn), contract []byte) error {
-	var subScript []byte
-	if contract == nil {
-		subScript = tx.scriptPublicKey
-	} else {
-		subScript = contract
+func (tx *tx) addInput(utxo clients.UTXO) error {
+	return tx.addScriptInput(utxo, nil)
+}
+
+// addScriptInput adds a utxo locked by the P2SH script of the given redeem
+// script. The redeem script is used to sign the input, unless a contract is
+// passed to sign.
+func (tx *tx) addScriptInput(utxo clients.UTXO, redeemScript []byte) error {
+	scriptPubKey, err := hex.DecodeString(utxo.ScriptPubKey)
+	if err != nil {
+		return err
+	}
+	hash, err := chainhash.NewHashFromStr(utxo.TxHash)
+	if err != nil {
+		return err
+	}
+	tx.msgTx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(hash, utxo.Vout), []byte{}, [][]byte{}))
+	tx.receiveValues = append(tx.receiveValues, utxo.Amount)
+	tx.scriptPubKeys = append(tx.scriptPubKeys, scriptPubKey)
+	tx.redeemScripts = append(tx.redeemScripts, redeemScript)
+	return nil
+}
+
+func (tx *tx) sign(ctx context.Context, f func(*txscript.ScriptBuilder), updateTxIn func(*wire.TxIn), contract []byte) (err error) {
+	ctx, span := startSpan(ctx, "zcash.Sign", AttributeInputs, strconv.Itoa(len(tx.msgTx.TxIn)))
+	defer func() { endSpan(span, err) }()
+
+	if tx.account.Signer == nil {
+		return ErrWatchOnly
 	}
 	serializedPublicKey, err := tx.account.SerializedPublicKey()
 	if err != nil {
 		return err
 	}
 
-	for i, txin := range tx.msgTx.TxIn {
-		if updateTxIn != nil {
+	// All inputs need to be updated before signing, as the signature hash of
+	// each input commits to the sequence numbers of every input.
+	if updateTxIn != nil {
+		for _, txin := range tx.msgTx.TxIn {
 			updateTxIn(txin)
 		}
-		sig, err := zecutil.RawTxInSignature(tx.msgTx, i, subScript, txscript.SigHashAll, tx.account.PrivKey, tx.receiveValues[i])
-		if err != nil {
+	}
+
+	branchID, err := tx.account.consensusBranchID()
+	if err != nil {
+		return err
+	}
+	hasher, err := newSigHasher(tx.msgTx, branchSigHashKey(branchID), nil)
+	if err != nil {
+		return err
+	}
+	subScripts := make([][]byte, len(tx.msgTx.TxIn))
+	for i := range tx.msgTx.TxIn {
+		subScripts[i] = tx.subScript(i, contract)
+	}
+	hashes, err := hasher.hashInputs(subScripts, tx.receiveValues, tx.hashType)
+	if err != nil {
+		return err
+	}
+
+	// The contract and the pushes selecting its branch are kept, so that
+	// the transaction can be signed again by Resubmit.
+	tx.contract = contract
+	if f != nil {
+		unlock := txscript.NewScriptBuilder()
+		f(unlock)
+		if tx.unlock, err = unlock.Script(); err != nil {
 			return err
 		}
+	}
+
+	sigs, err := tx.account.Signer.Sign(ctx, hashes)
+	if err != nil {
+		return err
+	}
+	if len(sigs) != len(hashes) {
+		return fmt.Errorf("invalid number of signatures: got: %d required: %d", len(sigs), len(hashes))
+	}
+
+	for i, txin := range tx.msgTx.TxIn {
+		sig, err := verifySig(sigs[i], hashes[i], tx.account.PubKey)
+		if err != nil {
+			return NewErrInvalidSignature(i, err.Error())
+		}
 		builder := txscript.NewScriptBuilder()
-		builder.AddData(sig)
+		builder.AddData(append(sig.Serialize(), byte(tx.hashType)))
 		builder.AddData(serializedPublicKey)
 		if f != nil {
 			f(builder)
 		}
-		if contract != nil {
-			builder.AddData(contract)
+		if redeemScript := tx.subScript(i, contract); contract != nil || tx.redeemScripts[i] != nil {
+			builder.AddData(redeemScript)
 		}
 		sigScript, err := builder.Script()
 		if err != nil {
@@ -168,10 +274,135 @@ func (tx *tx) sign(f func(*txscript.ScriptBuilder), updateTxIn func(*wire.TxIn),
 	return nil
 }
 
+// subScript returns the script that the signature of the input at the given
+// index commits to.
+func (tx *tx) subScript(i int, contract []byte) []byte {
+	if contract != nil {
+		return contract
+	}
+	if tx.redeemScripts[i] != nil {
+		return tx.redeemScripts[i]
+	}
+	return tx.scriptPubKeys[i]
+}
+
+func (tx *tx) preview() (TxPreview, error) {
+	// Watch-only accounts created from an address do not know their public
+	// key, so the size of an uncompressed public key is assumed.
+	pubKeySize := 65
+	serializedPublicKey, err := tx.account.SerializedPublicKey()
+	if err == nil {
+		pubKeySize = len(serializedPublicKey)
+	} else if err != ErrNoPublicKey {
+		return TxPreview{}, err
+	}
+	sigScripts := make([]int, len(tx.msgTx.TxIn))
+	for i := range sigScripts {
+		sigScripts[i] = estimateSigScriptSize(pubKeySize, nil, tx.redeemScripts[i])
+	}
+	value, change := tx.values()
+	return newTxPreview(tx.msgTx, tx.receiveValues, tx.scriptPubKeys, sigScripts, value, change)
+}
+
+// unsigned returns the transaction as an unsigned Tx, that can be signed
+// externally by the owner of the account's public key.
+func (tx *tx) unsigned() (Tx, error) {
+	if tx.account.PubKey == nil {
+		return nil, ErrNoPublicKey
+	}
+	inputs := make([]txInput, len(tx.msgTx.TxIn))
+	for i := range inputs {
+		inputs[i] = txInput{
+			amount:       tx.receiveValues[i],
+			scriptPubKey: tx.scriptPubKeys[i],
+			redeemScript: tx.redeemScripts[i],
+			pubKey:       tx.account.PubKey,
+		}
+	}
+	branchID, err := tx.account.consensusBranchID()
+	if err != nil {
+		return nil, err
+	}
+	hashes, err := inputHashes(tx.msgTx, inputs, branchID, tx.hashType)
+	if err != nil {
+		return nil, err
+	}
+	value, change := tx.values()
+	return &transaction{
+		hashes:   hashes,
+		msgTx:    tx.msgTx,
+		client:   tx.account.Client,
+		inputs:   inputs,
+		value:    value,
+		change:   change,
+		branchID: branchID,
+		hashType: tx.hashType,
+	}, nil
+}
+
+// values returns the value sent by the transaction, and its change.
+func (tx *tx) values() (int64, int64) {
+	var value, change int64
+	for i, txOut := range tx.msgTx.TxOut {
+		if i == tx.changeIndex {
+			change = txOut.Value
+			continue
+		}
+		value += txOut.Value
+	}
+	return value, change
+}
+
+func (tx *tx) receipt() (TxReceipt, error) {
+	preview, err := tx.preview()
+	if err != nil {
+		return TxReceipt{}, err
+	}
+	txHash, err := txHashString(tx.msgTx)
+	if err != nil {
+		return TxReceipt{}, err
+	}
+	var redeemScripts [][]byte
+	for i := range tx.msgTx.TxIn {
+		if tx.contract != nil || tx.redeemScripts[i] != nil {
+			redeemScripts = make([][]byte, len(tx.msgTx.TxIn))
+			break
+		}
+	}
+	for i := range redeemScripts {
+		if tx.contract != nil {
+			redeemScripts[i] = tx.contract
+		} else {
+			redeemScripts[i] = tx.redeemScripts[i]
+		}
+	}
+	return TxReceipt{
+		TxHash:        txHash,
+		Inputs:        preview.Inputs,
+		Outputs:       tx.msgTx.TxOut,
+		Value:         preview.Value,
+		Fee:           preview.Fee,
+		Change:        preview.Change,
+		ChangeIndex:   tx.changeIndex,
+		ExpiryHeight:  tx.msgTx.ExpiryHeight,
+		RedeemScripts: redeemScripts,
+		Unlock:        tx.unlock,
+		LockTime:      tx.msgTx.LockTime,
+	}, nil
+}
+
+// broadcast submits the signed transaction, tracing it as a child of the
+// context.
+func (tx *tx) broadcast(ctx context.Context) (err error) {
+	_, span := startSpan(ctx, "zcash.Broadcast", AttributeTxID, tx.msgTx.TxHash().String())
+	defer func() { endSpan(span, err) }()
+	return tx.submit()
+}
+
 func (tx *tx) submit() error {
 	buf := new(bytes.Buffer)
 	if err := tx.msgTx.ZecEncode(buf, 0, wire.BaseEncoding); err != nil {
 		return err
 	}
-	return tx.account.PublishTransaction(buf.Bytes())
+	return tx.account.publish(tx.msgTx, buf.Bytes())
 }
@@ -0,0 +1,150 @@
+package libzec_test
+
+import (
+	"context"
+	"errors"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/renproject/libzec-go"
+	"github.com/renproject/libzec-go/clients"
+)
+
+var _ = Describe("Transfers", func() {
+	It("should not sign without the consensus branch of the chain", func() {
+		core := newMockClientCore(&chaincfg.TestNet3Params, 1842420)
+		account, _, _ := newMockAccount(core, 1000000)
+		_, _, to := newMockAccount(core, 0)
+		core.heightErr = errors.New("unavailable")
+		_, err := account.Transfer(context.Background(), to.EncodeAddress(), 100000, Standard, false)
+		Expect(err).ShouldNot(BeNil())
+		Expect(core.published).Should(BeEmpty())
+	})
+
+	It("should pay the exact value to the recipient and the fee from the change", func() {
+		core := newMockClientCore(&chaincfg.TestNet3Params, 1842420)
+		account, _, _ := newMockAccount(core, 1000000)
+		_, _, to := newMockAccount(core, 0)
+		receipt, err := account.Transfer(context.Background(), to.EncodeAddress(), 100000, Standard, false)
+		Expect(err).Should(BeNil())
+
+		Expect(receipt.Outputs).Should(HaveLen(2))
+		Expect(receipt.Outputs[0].Value).Should(Equal(int64(100000)))
+		Expect(receipt.ChangeIndex).Should(Equal(1))
+		Expect(receipt.Outputs[1].Value).Should(Equal(1000000 - 100000 - receipt.Fee))
+	})
+
+	It("should not take the fee from the recipient when there is no change", func() {
+		// The change left after the fee of the transaction with a change
+		// output is dust, so the utxos are selected again without it.
+		core := newMockClientCore(&chaincfg.TestNet3Params, 1842420)
+		account, _, _ := newMockAccount(core, 100000+MinRelayFee+500)
+		account.SetFeeEstimator(NewStaticFeeEstimator(1, 1, 1))
+		_, _, to := newMockAccount(core, 0)
+		receipt, err := account.Transfer(context.Background(), to.EncodeAddress(), 100000, Standard, false)
+		Expect(err).Should(BeNil())
+		Expect(receipt.Outputs).Should(HaveLen(1))
+		Expect(receipt.Outputs[0].Value).Should(Equal(int64(100000)))
+		Expect(receipt.Fee).Should(Equal(MinRelayFee + 500))
+
+		core = newMockClientCore(&chaincfg.TestNet3Params, 1842420)
+		account, _, _ = newMockAccount(core, 100000+MinRelayFee+500)
+		account.SetFeeEstimator(NewStaticFeeEstimator(100, 100, 100))
+		_, err = account.Transfer(context.Background(), to.EncodeAddress(), 100000, Standard, false)
+		Expect(err).ShouldNot(BeNil())
+		Expect(core.published).Should(BeEmpty())
+	})
+
+	It("should only reserve the estimated fee when funding a transfer", func() {
+		core := newMockClientCore(&chaincfg.TestNet3Params, 1842420)
+		account, _, _ := newMockAccount(core, 100000+MinRelayFee+ZCashDust)
+		account.SetFeeEstimator(NewStaticFeeEstimator(1, 1, 1))
+		_, _, to := newMockAccount(core, 0)
+		receipt, err := account.Transfer(context.Background(), to.EncodeAddress(), 100000, Standard, false)
+		Expect(err).Should(BeNil())
+		Expect(receipt.Fee).Should(Equal(MinRelayFee))
+		Expect(receipt.Outputs).Should(HaveLen(2))
+		Expect(receipt.Outputs[0].Value).Should(Equal(int64(100000)))
+		Expect(receipt.Outputs[1].Value).Should(Equal(int64(ZCashDust)))
+	})
+
+	It("should fund a hash time locked contract with the exact value", func() {
+		core := newMockClientCore(&chaincfg.TestNet3Params, 1842420)
+		account, _, _ := newMockAccount(core, 1000000)
+		contract, err := HTLCScript(make([]byte, 20), make([]byte, 20), [32]byte{}, 1000)
+		Expect(err).Should(BeNil())
+		receipt, err := account.InitiateHTLC(context.Background(), contract, 100000, Standard)
+		Expect(err).Should(BeNil())
+
+		scriptPubKey, err := PayToScriptHashScript(contract)
+		Expect(err).Should(BeNil())
+		Expect(receipt.Outputs[0].PkScript).Should(Equal(scriptPubKey))
+		Expect(receipt.Outputs[0].Value).Should(Equal(int64(100000)))
+	})
+
+	It("should take the fee from the value when sending everything", func() {
+		core := newMockClientCore(&chaincfg.TestNet3Params, 1842420)
+		account, _, _ := newMockAccount(core, 100000)
+		_, _, to := newMockAccount(core, 0)
+		receipt, err := account.Transfer(context.Background(), to.EncodeAddress(), 0, Standard, true)
+		Expect(err).Should(BeNil())
+		Expect(receipt.Outputs).Should(HaveLen(1))
+		Expect(receipt.Outputs[0].Value).Should(Equal(100000 - receipt.Fee))
+	})
+
+	It("should only send the utxos that can be spent when sending everything", func() {
+		core := newMockClientCore(&chaincfg.TestNet3Params, 1842420)
+		account, _, addr := newMockAccount(core, 100000)
+		_, _, to := newMockAccount(core, 0)
+		frozen := core.addUTXO(addr, chainhash.Hash{0xF0}, 200000, 10)
+		account.FreezeUTXO(frozen.TxHash, frozen.Vout)
+		core.addUTXO(addr, chainhash.Hash{0xCB}, 500000, 10)
+		core.utxos[addr.EncodeAddress()][2].Coinbase = true
+
+		tx, err := account.BuildTransfer(context.Background(), to.EncodeAddress(), 0, Standard, true)
+		Expect(err).Should(BeNil())
+		preview, err := tx.Preview()
+		Expect(err).Should(BeNil())
+		Expect(preview.Inputs).Should(HaveLen(1))
+
+		receipt, err := account.Transfer(context.Background(), to.EncodeAddress(), 0, Standard, true)
+		Expect(err).Should(BeNil())
+		Expect(receipt.Outputs).Should(HaveLen(1))
+		Expect(receipt.Outputs[0].Value).Should(Equal(100000 - receipt.Fee))
+	})
+
+	It("should fetch every utxo when sending everything", func() {
+		core := newMockClientCore(&chaincfg.TestNet3Params, 1842420)
+		account, _, addr := newMockAccount(core, 100000)
+		_, _, to := newMockAccount(core, 0)
+		for i := 0; i < 3; i++ {
+			core.addUTXO(addr, chainhash.Hash{0xA0, byte(i)}, 100000, 1)
+		}
+		receipt, err := account.Transfer(context.Background(), to.EncodeAddress(), 0, Standard, true)
+		Expect(err).Should(BeNil())
+		Expect(receipt.Inputs).Should(HaveLen(4))
+		Expect(receipt.Outputs[0].Value).Should(Equal(400000 - receipt.Fee))
+		Expect(core.limits).ShouldNot(BeEmpty())
+		for _, limit := range core.limits {
+			Expect(limit).Should(BeZero())
+		}
+	})
+})
+
+var _ = Describe("UTXO queries", func() {
+	utxos := []clients.UTXO{{Vout: 0}, {Vout: 1}, {Vout: 2}}
+
+	It("should return every utxo when the limit is 0", func() {
+		Expect(clients.LimitUTXOs(utxos, 0)).Should(Equal(utxos))
+		Expect(clients.LimitUTXOs(utxos, 3)).Should(Equal(utxos))
+		Expect(clients.LimitUTXOs(utxos, 2)).Should(Equal(utxos[:2]))
+	})
+
+	It("should not accept negative limits or confirmations", func() {
+		Expect(clients.CheckUTXOQuery(0, 0)).Should(BeNil())
+		Expect(clients.CheckUTXOQuery(-1, 0)).Should(Equal(ErrNegativeLimit))
+		Expect(clients.CheckUTXOQuery(0, -1)).Should(Equal(ErrNegativeConfirmations))
+	})
+})
@@ -2,50 +2,197 @@ package libzec
 
 import (
 	"bytes"
+	"context"
 	"crypto/ecdsa"
 	"encoding/hex"
 	"fmt"
+	"math/big"
 
 	"github.com/btcsuite/btcd/btcec"
 	"github.com/btcsuite/btcd/chaincfg/chainhash"
 	"github.com/btcsuite/btcd/txscript"
 	"github.com/btcsuite/btcd/wire"
-	"github.com/btcsuite/btcutil"
 	"github.com/iqoption/zecutil"
 	"github.com/renproject/libzec-go/clients"
+	"github.com/sirupsen/logrus"
 )
 
 type txBuilder struct {
-	version   int32
-	fee, dust int64
-	client    Client
+	version    int32
+	fee, dust  int64
+	client     Client
+	lockTime   uint32
+	sequences  map[int]uint32
+	verify     bool
+	branchID   *uint32
+	hashType   txscript.SigHashType
+	logger     logrus.FieldLogger
+	feeLimits  feeLimits
+	dustPolicy DustPolicy
 }
 
 // NewTxBuilder creates a new tx builder.
 func NewTxBuilder(client Client) TxBuilder {
-	return &txBuilder{4, 10000, 600, client}
+	return &txBuilder{4, 10000, 600, client, 0, map[int]uint32{}, false, nil, txscript.SigHashAll, defaultLogger(nil), defaultFeeLimits(), DustToFee}
 }
 
+// DustPolicy decides what a tx builder does with a change that is too small to
+// be sent back.
+type DustPolicy int
+
+const (
+	// DustToFee leaves the change to the miners, as part of the fee.
+	DustToFee DustPolicy = iota
+	// DustToRecipient adds the change to the value sent to the recipient.
+	DustToRecipient
+	// DustFail refuses to build the transaction with a DustChangeError.
+	DustFail
+)
+
 // The TxBuilder can build txs, that allow the user to extract the hashes to be
 // signed.
 type TxBuilder interface {
 	Build(pubKey ecdsa.PublicKey, to string, contract []byte, value int64, mwUTXOs, scriptUTXOs []clients.UTXO) (Tx, error)
+
+	// BuildWithRedeemScripts builds a transaction like Build, spending utxos
+	// that can be locked by different P2SH scripts, such as the scripts of
+	// several gateways. The redeem scripts are keyed by the outpoints of the
+	// utxos they unlock, formatted as "txhash:vout". The utxos without a
+	// redeem script must be locked by the P2PKH script of the public key.
+	BuildWithRedeemScripts(pubKey ecdsa.PublicKey, to string, value int64, utxos []clients.UTXO, redeemScripts map[string][]byte) (Tx, error)
+
+	// SetLockTime sets the nLockTime of the transactions built by this
+	// builder. When a non zero lock time is set, inputs without an explicit
+	// sequence number are given a non final sequence number so that the lock
+	// time is enforced.
+	SetLockTime(lockTime uint32)
+
+	// SetSequence sets the nSequence of the input at the given index of the
+	// transactions built by this builder.
+	SetSequence(index int, sequence uint32)
+
+	// SetVerifyScripts sets whether the transactions built by this builder
+	// execute the scripts of every input before they are submitted.
+	SetVerifyScripts(verify bool)
+
+	// SetConsensusBranchID sets the consensus branch id that the signature
+	// hashes of the transactions built by this builder commit to. By default
+	// the branch id is selected using the current height of the chain, or
+	// using the expiry height of the transaction if the client cannot fetch
+	// the height of the chain.
+	SetConsensusBranchID(branchID uint32)
+
+	// SetSigHashType sets the signature hash type that the signatures of the
+	// transactions built by this builder commit to. By default it is
+	// SigHashAll. Transactions signed with SigHashAll|SigHashAnyOneCanPay can
+	// have inputs added by a third party, such as a sponsor paying the fee.
+	SetSigHashType(hashType txscript.SigHashType)
+
+	// SetFeeLimits sets the minimum and the maximum fee of the transactions
+	// built by this builder, including the change that is too small to be
+	// sent back and is left to the miners. By default the fee is at most
+	// DefaultMaxFee. The fee is never below the minimum relay fee of the
+	// network, whatever the minimum set.
+	SetFeeLimits(min, max int64)
+
+	// SetDustPolicy sets what the builder does with a change that is too
+	// small to be sent back. By default it is left to the miners.
+	SetDustPolicy(policy DustPolicy)
+
+	// SetLogger sets the logger to which the builder writes its diagnostics.
+	// By default they are discarded.
+	SetLogger(logger logrus.FieldLogger)
+
+	// BuildPSZT builds a partially signed transaction spending the given
+	// utxos, that are locked by the given redeem script, to the given
+	// address. The redeem script should be nil if the utxos are locked by a
+	// P2PKH script. The change is sent back to the script of the first utxo.
+	BuildPSZT(to string, redeemScript []byte, value int64, utxos []clients.UTXO) (*PSZT, error)
+
+	// BuildMulti builds a transaction spending utxos controlled by multiple
+	// signers to the given address, and sends the change to the change
+	// address.
+	BuildMulti(to, change string, value int64, signerUTXOs []SignerUTXOs) (Tx, error)
+
+	// Preview builds the same transaction as Build, and returns a preview of
+	// it without signing it.
+	Preview(pubKey ecdsa.PublicKey, to string, contract []byte, value int64, mwUTXOs, scriptUTXOs []clients.UTXO) (TxPreview, error)
 }
 
 type Tx interface {
 	Hashes() [][]byte
+
+	// SignerHashes returns the hashes grouped by the signer that needs to sign
+	// them, in the order the signers first appear in the inputs.
+	SignerHashes() []SignerHashes
+
+	// InjectSigs injects the signatures of every input, in the same order as
+	// the hashes returned by Hashes.
 	InjectSigs(sigs []*btcec.Signature) error
 	Submit() ([]byte, error)
+
+	// Serialize returns the raw transaction bytes, which can be broadcast or
+	// archived out of band.
+	Serialize() ([]byte, error)
+
+	// TxHash returns the hash of the transaction, in the same format as the
+	// one returned by Submit.
+	TxHash() ([]byte, error)
+
+	// Verify executes the scripts of every input of the signed transaction,
+	// and returns an error if any of them fail.
+	Verify() error
+
+	// Preview returns the inputs, fee, change and estimated size of the
+	// signed transaction.
+	Preview() (TxPreview, error)
 }
 
 type transaction struct {
-	sent      int64
-	msgTx     *zecutil.MsgTx
-	hashes    [][]byte
-	client    Client
-	contract  []byte
-	publicKey ecdsa.PublicKey
-	mwIns     int
+	msgTx    *zecutil.MsgTx
+	hashes   [][]byte
+	client   Client
+	inputs   []txInput
+	verify   bool
+	value    int64
+	change   int64
+	branchID uint32
+	hashType txscript.SigHashType
+}
+
+// txInput holds the information required to sign and verify an input of a
+// transaction.
+type txInput struct {
+	amount       int64
+	scriptPubKey []byte
+	redeemScript []byte
+	pubKey       *btcec.PublicKey
+
+	// unlock is pushed between the public key and the redeem script of the
+	// signature script, and selects the branch of the contract that the
+	// input is spent with. It is nil for inputs without a redeem script.
+	unlock []byte
+}
+
+// SignerUTXOs are utxos controlled by a signer. The contract must be provided
+// if the utxos are locked by a P2SH script. Unlock is the script pushed
+// between the public key and the contract when the utxos are spent, such as
+// the secret and branch of a hash time locked contract created by
+// HTLCRedeemUnlock. Refundable slave scripts are spent using their first
+// branch if it is nil.
+type SignerUTXOs struct {
+	PubKey   ecdsa.PublicKey
+	Contract []byte
+	Unlock   []byte
+	UTXOs    []clients.UTXO
+}
+
+// SignerHashes are the hashes that need to be signed by a signer, and the
+// indices of the inputs they belong to.
+type SignerHashes struct {
+	PubKey ecdsa.PublicKey
+	Inputs []int
+	Hashes [][]byte
 }
 
 func (builder *txBuilder) Build(
@@ -55,62 +202,121 @@ func (builder *txBuilder) Build(
 	value int64,
 	mwUTXOs, scriptUTXOs []clients.UTXO,
 ) (Tx, error) {
-	if value < builder.fee+builder.dust {
-		return nil, fmt.Errorf("minimum transfer amount is: %d current: %d", builder.dust+builder.fee, value)
+	pubKeyBytes, err := builder.client.SerializePublicKey((*btcec.PublicKey)(&pubKey))
+	if err != nil {
+		return nil, err
 	}
-	value -= builder.fee
 
-	pubKeyBytes, err := builder.client.SerializePublicKey((*btcec.PublicKey)(&pubKey))
+	from, err := builder.client.PublicKeyToAddress(pubKeyBytes)
 	if err != nil {
 		return nil, err
 	}
 
+	signerUTXOs := []SignerUTXOs{{PubKey: pubKey, UTXOs: mwUTXOs}}
+	if contract != nil {
+		signerUTXOs = append(signerUTXOs, SignerUTXOs{PubKey: pubKey, Contract: contract, UTXOs: scriptUTXOs})
+	}
+	return builder.BuildMulti(to, from.EncodeAddress(), value, signerUTXOs)
+}
+
+func (builder *txBuilder) BuildWithRedeemScripts(
+	pubKey ecdsa.PublicKey,
+	to string,
+	value int64,
+	utxos []clients.UTXO,
+	redeemScripts map[string][]byte,
+) (Tx, error) {
+	pubKeyBytes, err := builder.client.SerializePublicKey((*btcec.PublicKey)(&pubKey))
+	if err != nil {
+		return nil, err
+	}
 	from, err := builder.client.PublicKeyToAddress(pubKeyBytes)
 	if err != nil {
 		return nil, err
 	}
 
-	toAddr, err := zecutil.DecodeAddress(to, builder.client.NetworkParams().Name)
+	// Every utxo is given its own entry, so that the inputs keep the order
+	// of the utxos.
+	signerUTXOs := make([]SignerUTXOs, len(utxos))
+	spent := map[string]bool{}
+	for i, utxo := range utxos {
+		key := outPointKey(utxo.TxHash, utxo.Vout)
+		spent[key] = true
+		signerUTXOs[i] = SignerUTXOs{PubKey: pubKey, Contract: redeemScripts[key], UTXOs: []clients.UTXO{utxo}}
+	}
+	for key := range redeemScripts {
+		if !spent[key] {
+			return nil, fmt.Errorf("redeem script of %s does not unlock any of the utxos", key)
+		}
+	}
+	return builder.BuildMulti(to, from.EncodeAddress(), value, signerUTXOs)
+}
+
+func (builder *txBuilder) BuildMulti(to, change string, value int64, signerUTXOs []SignerUTXOs) (built Tx, err error) {
+	_, span := startSpan(context.Background(), "zcash.BuildMulti", AttributeAddress, to)
+	defer func() { endSpan(span, err) }()
+
+	if value < builder.fee+builder.dust {
+		return nil, fmt.Errorf("minimum transfer amount is: %d current: %d", builder.dust+builder.fee, value)
+	}
+	value -= builder.fee
+
+	toAddr, err := DecodeAddress(to, builder.client.NetworkParams())
 	if err != nil {
 		return nil, err
 	}
 
-	msgTx := &zecutil.MsgTx{
-		MsgTx:        wire.NewMsgTx(builder.version),
-		ExpiryHeight: ZCashExpiryHeight,
+	changeAddr, err := DecodeAddress(change, builder.client.NetworkParams())
+	if err != nil {
+		return nil, err
 	}
 
-	var sent int64
-	var amt int64
-	recvVals, pubKeyScript, err := fundZecTx(from, nil, builder.client, msgTx, mwUTXOs)
+	expiryHeight, err := nextExpiryHeight(builder.client)
 	if err != nil {
 		return nil, err
 	}
-	amt = sum(recvVals)
+	msgTx := &zecutil.MsgTx{
+		MsgTx:        wire.NewMsgTx(builder.version),
+		ExpiryHeight: expiryHeight,
+	}
+	msgTx.LockTime = builder.lockTime
 
-	if contract != nil {
-		recvVals2, _, err := fundZecTx(from, contract, builder.client, msgTx, scriptUTXOs)
+	inputs := []txInput{}
+	for _, signer := range signerUTXOs {
+		unlock := signer.Unlock
+		if unlock == nil {
+			if unlock, err = defaultUnlock(signer.Contract); err != nil {
+				return nil, err
+			}
+		}
+		signerInputs, err := addInputs(msgTx, signer.UTXOs, signer.Contract, unlock)
 		if err != nil {
 			return nil, err
 		}
-		recvVals = append(recvVals, recvVals2...)
-		amt2 := sum(recvVals2)
-		amt += amt2
-		sent = amt2 - builder.fee
+		pubKey := signer.PubKey
+		for i := range signerInputs {
+			signerInputs[i].pubKey = (*btcec.PublicKey)(&pubKey)
+		}
+		inputs = append(inputs, signerInputs...)
 	}
 
+	amt := sumInputs(inputs)
 	if amt < value+builder.fee {
 		return nil, fmt.Errorf("insufficient balance to do the transfer:"+
 			"got: %d required: %d", amt, value+builder.fee)
 	}
 
-	fmt.Println("utxos being used: ")
+	builder.updateSequences(msgTx)
+
 	for i, txIn := range msgTx.TxIn {
-		fmt.Printf("[%d]: %s:%d\n", i, txIn.PreviousOutPoint.Hash.String(), txIn.PreviousOutPoint.Index)
+		builder.logger.WithField("input", i).Debugf("spending %s", txIn.PreviousOutPoint)
 	}
 
+	value, changeValue, err := builder.splitChange(amt, value)
+	if err != nil {
+		return nil, err
+	}
 	if value > 0 {
-		sent = value
 		script, err := PayToAddrScript(toAddr)
 		if err != nil {
 			return nil, err
@@ -118,57 +324,248 @@ func (builder *txBuilder) Build(
 		msgTx.AddTxOut(wire.NewTxOut(value, script))
 	}
 
-	if amt-value > builder.fee+builder.dust {
-		P2PKHScript, err := PayToAddrScript(from)
+	if changeValue > 0 {
+		P2PKHScript, err := PayToAddrScript(changeAddr)
 		if err != nil {
 			return nil, err
 		}
-		msgTx.AddTxOut(wire.NewTxOut(amt-value-builder.fee, P2PKHScript))
+		msgTx.AddTxOut(wire.NewTxOut(changeValue, P2PKHScript))
+	}
+	if err := builder.relayableFeeLimits().check(amt - value - changeValue); err != nil {
+		return nil, err
 	}
 
-	var hashes [][]byte
-	for i := 0; i < len(mwUTXOs); i++ {
-		hash, err := CalcSignatureHash(pubKeyScript, txscript.SigHashAll, msgTx, i, recvVals[i])
-		if err != nil {
+	branchID, err := builder.consensusBranchID()
+	if err != nil {
+		return nil, err
+	}
+	hashes, err := inputHashes(msgTx, inputs, branchID, builder.hashType)
+	if err != nil {
+		return nil, err
+	}
+
+	return &transaction{
+		hashes:   hashes,
+		msgTx:    msgTx,
+		client:   builder.client,
+		inputs:   inputs,
+		verify:   builder.verify,
+		value:    value,
+		change:   changeValue,
+		branchID: branchID,
+		hashType: builder.hashType,
+	}, nil
+}
+
+func (builder *txBuilder) Preview(
+	pubKey ecdsa.PublicKey,
+	to string,
+	contract []byte,
+	value int64,
+	mwUTXOs, scriptUTXOs []clients.UTXO,
+) (TxPreview, error) {
+	tx, err := builder.Build(pubKey, to, contract, value, mwUTXOs, scriptUTXOs)
+	if err != nil {
+		return TxPreview{}, err
+	}
+	return tx.Preview()
+}
+
+func (builder *txBuilder) BuildPSZT(to string, redeemScript []byte, value int64, utxos []clients.UTXO) (*PSZT, error) {
+	if value < builder.fee+builder.dust {
+		return nil, fmt.Errorf("minimum transfer amount is: %d current: %d", builder.dust+builder.fee, value)
+	}
+	if len(utxos) == 0 {
+		return nil, fmt.Errorf("no utxos to spend")
+	}
+	value -= builder.fee
+
+	toAddr, err := DecodeAddress(to, builder.client.NetworkParams())
+	if err != nil {
+		return nil, err
+	}
+
+	expiryHeight, err := nextExpiryHeight(builder.client)
+	if err != nil {
+		return nil, err
+	}
+	pszt := NewPSZT(expiryHeight)
+	pszt.msgTx.Version = builder.version
+	branchID, err := builder.consensusBranchID()
+	if err != nil {
+		return nil, err
+	}
+	pszt.SetConsensusBranchID(branchID)
+	pszt.msgTx.LockTime = builder.lockTime
+	for _, utxo := range utxos {
+		if err := pszt.AddInput(utxo, redeemScript); err != nil {
 			return nil, err
 		}
-		hashes = append(hashes, hash)
 	}
-	for i := len(mwUTXOs); i < len(mwUTXOs)+len(scriptUTXOs); i++ {
-		hash, err := CalcSignatureHash(contract, txscript.SigHashAll, msgTx, i, recvVals[i])
+	builder.updateSequences(pszt.msgTx)
+
+	amt := int64(0)
+	for _, utxo := range utxos {
+		amt += utxo.Amount
+	}
+	if amt < value+builder.fee {
+		return nil, fmt.Errorf("insufficient balance to do the transfer:"+
+			"got: %d required: %d", amt, value+builder.fee)
+	}
+
+	value, change, err := builder.splitChange(amt, value)
+	if err != nil {
+		return nil, err
+	}
+	script, err := PayToAddrScript(toAddr)
+	if err != nil {
+		return nil, err
+	}
+	pszt.AddOutput(value, script)
+
+	if change > 0 {
+		changeScript, err := hex.DecodeString(utxos[0].ScriptPubKey)
 		if err != nil {
 			return nil, err
 		}
-		hashes = append(hashes, hash)
+		pszt.AddOutput(change, changeScript)
 	}
+	if err := builder.relayableFeeLimits().check(amt - value - change); err != nil {
+		return nil, err
+	}
+	return pszt, nil
+}
 
-	return &transaction{
-		sent:      sent,
-		hashes:    hashes,
-		msgTx:     msgTx,
-		client:    builder.client,
-		publicKey: pubKey,
-		contract:  contract,
-		mwIns:     len(mwUTXOs),
-	}, nil
+func (builder *txBuilder) SetLockTime(lockTime uint32) {
+	builder.lockTime = lockTime
+}
+
+func (builder *txBuilder) SetSequence(index int, sequence uint32) {
+	builder.sequences[index] = sequence
+}
+
+func (builder *txBuilder) SetVerifyScripts(verify bool) {
+	builder.verify = verify
+}
+
+func (builder *txBuilder) SetConsensusBranchID(branchID uint32) {
+	builder.branchID = &branchID
+}
+
+func (builder *txBuilder) SetSigHashType(hashType txscript.SigHashType) {
+	builder.hashType = hashType
+}
+
+func (builder *txBuilder) SetFeeLimits(min, max int64) {
+	builder.feeLimits = feeLimits{min, max}
+}
+
+func (builder *txBuilder) SetDustPolicy(policy DustPolicy) {
+	builder.dustPolicy = policy
+}
+
+func (builder *txBuilder) SetLogger(logger logrus.FieldLogger) {
+	builder.logger = defaultLogger(logger)
+}
+
+// relayableFeeLimits returns the fee limits of the builder, raised to the
+// minimum relay fee of the network of its client.
+func (builder *txBuilder) relayableFeeLimits() feeLimits {
+	return builder.feeLimits.relayable(relayRulesOf(builder.client.NetworkParams()))
+}
+
+// splitChange returns the value sent to the recipient and the change, once the
+// fee is paid from the amount of the inputs. A change that is not above the
+// dust threshold is handled according to the dust policy of the builder.
+func (builder *txBuilder) splitChange(amt, value int64) (int64, int64, error) {
+	change := amt - value - builder.fee
+	if change > builder.dust {
+		return value, change, nil
+	}
+	if change == 0 {
+		return value, 0, nil
+	}
+	switch builder.dustPolicy {
+	case DustToFee:
+		builder.logger.Debugf("leaving dust change of %d ZAT to the miners", change)
+		return value, 0, nil
+	case DustToRecipient:
+		return value + change, 0, nil
+	case DustFail:
+		return 0, 0, NewErrDustChange(change, builder.dust+1)
+	default:
+		return 0, 0, fmt.Errorf("unknown dust policy: %d", builder.dustPolicy)
+	}
+}
+
+// consensusBranchID returns the consensus branch id set on the builder, or the
+// one of the next block of the chain. An error is returned if neither is known.
+func (builder *txBuilder) consensusBranchID() (uint32, error) {
+	if builder.branchID != nil {
+		return *builder.branchID, nil
+	}
+	height, err := builder.client.BlockHeight()
+	if err != nil {
+		return 0, fmt.Errorf("cannot get the block height to select the consensus branch: %v", err)
+	}
+	return ConsensusBranchID(builder.client.NetworkParams(), height)
+}
+
+func (builder *txBuilder) updateSequences(msgTx *zecutil.MsgTx) {
+	for i, txIn := range msgTx.TxIn {
+		if sequence, ok := builder.sequences[i]; ok {
+			txIn.Sequence = sequence
+			continue
+		}
+		if builder.lockTime != 0 {
+			txIn.Sequence = wire.MaxTxInSequenceNum - 1
+		}
+	}
 }
 
 func (tx *transaction) Hashes() [][]byte {
 	return tx.hashes
 }
 
+func (tx *transaction) SignerHashes() []SignerHashes {
+	signerHashes := []SignerHashes{}
+	signerIndices := map[string]int{}
+	for i, input := range tx.inputs {
+		key := hex.EncodeToString(input.pubKey.SerializeCompressed())
+		j, ok := signerIndices[key]
+		if !ok {
+			j = len(signerHashes)
+			signerIndices[key] = j
+			signerHashes = append(signerHashes, SignerHashes{PubKey: ecdsa.PublicKey(*input.pubKey)})
+		}
+		signerHashes[j].Inputs = append(signerHashes[j].Inputs, i)
+		signerHashes[j].Hashes = append(signerHashes[j].Hashes, tx.hashes[i])
+	}
+	return signerHashes
+}
+
 func (tx *transaction) InjectSigs(sigs []*btcec.Signature) error {
-	pubKey := (*btcec.PublicKey)(&tx.publicKey)
-	serializedPublicKey, err := tx.client.SerializePublicKey(pubKey)
-	if err != nil {
-		return err
+	if len(sigs) != len(tx.hashes) {
+		return fmt.Errorf("invalid number of signatures: got: %d required: %d", len(sigs), len(tx.hashes))
 	}
+	normalized := make([]*btcec.Signature, len(sigs))
 	for i, sig := range sigs {
+		var err error
+		if normalized[i], err = verifySig(sig, tx.hashes[i], tx.inputs[i].pubKey); err != nil {
+			return NewErrInvalidSignature(i, err.Error())
+		}
+	}
+	for i, sig := range normalized {
+		serializedPublicKey, err := tx.client.SerializePublicKey(tx.inputs[i].pubKey)
+		if err != nil {
+			return err
+		}
 		builder := txscript.NewScriptBuilder()
-		builder.AddData(append(sig.Serialize(), byte(txscript.SigHashAll)))
+		builder.AddData(append(sig.Serialize(), byte(tx.hashType)))
 		builder.AddData(serializedPublicKey)
-		if i >= tx.mwIns && tx.contract != nil {
-			builder.AddData(tx.contract)
+		if redeemScript := tx.inputs[i].redeemScript; redeemScript != nil {
+			builder.AddOps(tx.inputs[i].unlock)
+			builder.AddData(redeemScript)
 		}
 		sigScript, err := builder.Script()
 		if err != nil {
@@ -180,60 +577,192 @@ func (tx *transaction) InjectSigs(sigs []*btcec.Signature) error {
 }
 
 func (tx *transaction) Submit() ([]byte, error) {
+	if tx.verify {
+		if err := tx.Verify(); err != nil {
+			return nil, err
+		}
+	}
+	stx, err := tx.Serialize()
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.client.PublishTransaction(stx); err != nil {
+		return nil, err
+	}
+	return tx.TxHash()
+}
+
+func (tx *transaction) Serialize() ([]byte, error) {
 	buf := new(bytes.Buffer)
 	if err := tx.msgTx.ZecEncode(buf, 0, wire.BaseEncoding); err != nil {
 		return nil, err
 	}
-	if err := tx.client.PublishTransaction(buf.Bytes()); err != nil {
+	return buf.Bytes(), nil
+}
+
+func (tx *transaction) Verify() error {
+	for i := range tx.msgTx.TxIn {
+		if err := verifyScript(tx.msgTx, i, tx.inputs[i].scriptPubKey, tx.inputs[i].amount, branchSigHashKey(tx.branchID)); err != nil {
+			return fmt.Errorf("script verification failed for input %d: %v", i, err)
+		}
+	}
+	return nil
+}
+
+func (tx *transaction) Preview() (TxPreview, error) {
+	amounts := make([]int64, len(tx.inputs))
+	scriptPubKeys := make([][]byte, len(tx.inputs))
+	sigScripts := make([]int, len(tx.inputs))
+	for i, input := range tx.inputs {
+		serializedPublicKey, err := tx.client.SerializePublicKey(input.pubKey)
+		if err != nil {
+			return TxPreview{}, err
+		}
+		amounts[i] = input.amount
+		scriptPubKeys[i] = input.scriptPubKey
+		sigScripts[i] = estimateSigScriptSize(len(serializedPublicKey), input.unlock, input.redeemScript)
+	}
+	return newTxPreview(tx.msgTx, amounts, scriptPubKeys, sigScripts, tx.value, tx.change)
+}
+
+func (tx *transaction) TxHash() ([]byte, error) {
+	stx, err := tx.Serialize()
+	if err != nil {
 		return nil, err
 	}
-	return hex.DecodeString(tx.msgTx.TxHash().String())
+	return TxID(stx)
 }
 
-func fundZecTx(from btcutil.Address, script []byte, client Client, msgTx *zecutil.MsgTx, utxos []clients.UTXO) ([]int64, []byte, error) {
-	receiveValues := make([]int64, len(utxos))
-	if script != nil {
-		script20 := [20]byte{}
-		copy(script20[:], btcutil.Hash160(script))
-		scriptAddr, err := AddressFromHash160(script20, client.NetworkParams(), true)
+// receipt returns the receipt of the transaction. The change output, if any,
+// is always the last output of transactions built by a TxBuilder.
+func (tx *transaction) receipt() (TxReceipt, error) {
+	preview, err := tx.Preview()
+	if err != nil {
+		return TxReceipt{}, err
+	}
+	changeIndex := -1
+	if tx.change > 0 {
+		changeIndex = len(tx.msgTx.TxOut) - 1
+	}
+	txHash, err := txHashString(tx.msgTx)
+	if err != nil {
+		return TxReceipt{}, err
+	}
+	return TxReceipt{
+		TxHash:       txHash,
+		Inputs:       preview.Inputs,
+		Outputs:      tx.msgTx.TxOut,
+		Value:        preview.Value,
+		Fee:          preview.Fee,
+		Change:       preview.Change,
+		ChangeIndex:  changeIndex,
+		ExpiryHeight: tx.msgTx.ExpiryHeight,
+	}, nil
+}
+
+// verifySig checks that the signature is a signature of the hash by the public
+// key, and returns it with a low S value. Signers are free to return either of
+// the two valid S values, but only the low one is standard.
+func verifySig(sig *btcec.Signature, hash []byte, pubKey *btcec.PublicKey) (*btcec.Signature, error) {
+	if sig == nil || sig.R == nil || sig.S == nil {
+		return nil, fmt.Errorf("missing signature")
+	}
+	if !sig.Verify(hash, pubKey) {
+		return nil, fmt.Errorf("signature verification failed")
+	}
+	s := sig.S
+	if halfOrder := new(big.Int).Rsh(btcec.S256().N, 1); s.Cmp(halfOrder) > 0 {
+		s = new(big.Int).Sub(btcec.S256().N, s)
+	}
+	return &btcec.Signature{R: sig.R, S: s}, nil
+}
+
+// addInputs adds the given utxos as inputs of the transaction. If the redeem
+// script is not nil, every utxo must be locked by its P2SH script, and is
+// spent with the given unlock script.
+func addInputs(msgTx *zecutil.MsgTx, utxos []clients.UTXO, redeemScript, unlock []byte) ([]txInput, error) {
+	var P2SHScript []byte
+	if redeemScript != nil {
+		var err error
+		P2SHScript, err = PayToScriptHashScript(redeemScript)
 		if err != nil {
-			return receiveValues, nil, err
+			return nil, err
 		}
-		from = scriptAddr
 	}
 
-	var scriptPubKey []byte
+	inputs := make([]txInput, len(utxos))
 	for i, utxo := range utxos {
-		ScriptPubKey, err := hex.DecodeString(utxo.ScriptPubKey)
+		scriptPubKey, err := hex.DecodeString(utxo.ScriptPubKey)
 		if err != nil {
-			return receiveValues, nil, err
+			return nil, err
 		}
-		if len(scriptPubKey) == 0 {
-			scriptPubKey = ScriptPubKey
-		} else {
-			if bytes.Compare(scriptPubKey, ScriptPubKey) != 0 {
-				continue
-			}
+		if P2SHScript != nil && !bytes.Equal(scriptPubKey, P2SHScript) {
+			return nil, fmt.Errorf("utxo %s:%d is not locked by the redeem script", utxo.TxHash, utxo.Vout)
 		}
-
 		hash, err := chainhash.NewHashFromStr(utxo.TxHash)
 		if err != nil {
-			return receiveValues, nil, err
+			return nil, err
 		}
 		msgTx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(hash, utxo.Vout), []byte{}, [][]byte{}))
-		receiveValues[i] = utxo.Amount
+		inputs[i] = txInput{
+			amount:       utxo.Amount,
+			scriptPubKey: scriptPubKey,
+			redeemScript: redeemScript,
+			unlock:       unlock,
+		}
 	}
+	return inputs, nil
+}
+
+// defaultUnlock returns the unlock script of contracts that can be spent
+// without any data besides the signature and public key of the signer.
+// Refundable slave scripts are spent using their first branch, and hash time
+// locked contracts cannot be spent without their secret.
+func defaultUnlock(contract []byte) ([]byte, error) {
+	if contract == nil {
+		return nil, nil
+	}
+	info, err := ClassifyScript(contract)
+	if err != nil {
+		return nil, err
+	}
+	switch info.Type {
+	case ScriptSlaveWithRefund:
+		return []byte{txscript.OP_TRUE}, nil
+	case ScriptHTLC:
+		return nil, fmt.Errorf("hash time locked contract requires an unlock script: %x", contract)
+	default:
+		return nil, nil
+	}
+}
+
+func (input txInput) subScript() []byte {
+	if input.redeemScript != nil {
+		return input.redeemScript
+	}
+	return input.scriptPubKey
+}
 
-	if script != nil {
-		return receiveValues, script, nil
+// inputHashes returns the signature hash of the given type of every input of
+// the transaction, computing the hashes shared by the inputs only once.
+func inputHashes(msgTx *zecutil.MsgTx, inputs []txInput, branchID uint32, hashType txscript.SigHashType) ([][]byte, error) {
+	hasher, err := newSigHasher(msgTx, branchSigHashKey(branchID), nil)
+	if err != nil {
+		return nil, err
+	}
+	subScripts := make([][]byte, len(inputs))
+	amounts := make([]int64, len(inputs))
+	for i, input := range inputs {
+		subScripts[i] = input.subScript()
+		amounts[i] = input.amount
 	}
-	return receiveValues, scriptPubKey, nil
+	return hasher.hashInputs(subScripts, amounts, hashType)
 }
 
-func sum(vals []int64) int64 {
+func sumInputs(inputs []txInput) int64 {
 	var res int64
-	for _, val := range vals {
-		res += val
+	for _, input := range inputs {
+		res += input.amount
 	}
 	return res
 }
@@ -0,0 +1,241 @@
+package libzec
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/iqoption/zecutil"
+)
+
+// Personalizations of the ZIP-244 transaction digests.
+const (
+	txHashPersonalization              = "ZcashTxHash_"
+	headersHashPersonalization         = "ZTxIdHeadersHash"
+	transparentHashPersonalization     = "ZTxIdTranspaHash"
+	prevoutsHashPersonalization        = "ZTxIdPrevoutHash"
+	sequenceHashPersonalization        = "ZTxIdSequencHash"
+	txOutsHashPersonalization          = "ZTxIdOutputsHash"
+	saplingHashPersonalization         = "ZTxIdSaplingHash"
+	saplingSpendsHashPersonalization   = "ZTxIdSSpendsHash"
+	saplingSpendsCHashPersonalization  = "ZTxIdSSpendCHash"
+	saplingSpendsNHashPersonalization  = "ZTxIdSSpendNHash"
+	saplingOutputsHashPersonalization  = "ZTxIdSOutputHash"
+	saplingOutputsCHashPersonalization = "ZTxIdSOutC__Hash"
+	saplingOutputsMHashPersonalization = "ZTxIdSOutM__Hash"
+	saplingOutputsNHashPersonalization = "ZTxIdSOutN__Hash"
+	orchardHashPersonalization         = "ZTxIdOrchardHash"
+	orchardActionsCHashPersonalization = "ZTxIdOrcActCHash"
+	orchardActionsMHashPersonalization = "ZTxIdOrcActMHash"
+	orchardActionsNHashPersonalization = "ZTxIdOrcActNHash"
+)
+
+// TxID returns the txid of the raw transaction, in the same byte order as the
+// hashes returned by Tx.TxHash. The txid of v3 and v4 transactions is the
+// double SHA-256 of the transaction, and the txid of v5 transactions is the
+// ZIP-244 transaction digest, which does not commit to the signatures.
+func TxID(rawTx []byte) ([]byte, error) {
+	tx, err := DecodeTransaction(rawTx)
+	if err != nil {
+		return nil, err
+	}
+	var hash chainhash.Hash
+	if tx.Version == versionNU5 {
+		if hash, err = tx.zip244Digest(); err != nil {
+			return nil, err
+		}
+	} else {
+		hash = chainhash.DoubleHashH(rawTx)
+	}
+	txID := hash.CloneBytes()
+	for i, j := 0, len(txID)-1; i < j; i, j = i+1, j-1 {
+		txID[i], txID[j] = txID[j], txID[i]
+	}
+	return txID, nil
+}
+
+// txHashString returns the hex encoded txid of the transaction.
+func txHashString(msgTx *zecutil.MsgTx) (string, error) {
+	buf := new(bytes.Buffer)
+	if err := msgTx.ZecEncode(buf, 0, wire.BaseEncoding); err != nil {
+		return "", err
+	}
+	txID, err := TxID(buf.Bytes())
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(txID), nil
+}
+
+func (tx *DecodedTx) zip244Digest() (chainhash.Hash, error) {
+	header := new(bytes.Buffer)
+	binary.Write(header, binary.LittleEndian, uint32(tx.Version)|1<<31)
+	binary.Write(header, binary.LittleEndian, tx.VersionGroupID)
+	binary.Write(header, binary.LittleEndian, tx.ConsensusBranchID)
+	binary.Write(header, binary.LittleEndian, tx.LockTime)
+	binary.Write(header, binary.LittleEndian, tx.ExpiryHeight)
+
+	digests := new(bytes.Buffer)
+	for _, digest := range []func() (chainhash.Hash, error){
+		func() (chainhash.Hash, error) {
+			return blake2bHash(header.Bytes(), []byte(headersHashPersonalization))
+		},
+		tx.transparentDigest,
+		tx.saplingDigest,
+		tx.orchardDigest,
+	} {
+		hash, err := digest()
+		if err != nil {
+			return chainhash.Hash{}, err
+		}
+		digests.Write(hash[:])
+	}
+
+	key := make([]byte, len(txHashPersonalization)+4)
+	copy(key, txHashPersonalization)
+	binary.LittleEndian.PutUint32(key[len(txHashPersonalization):], tx.ConsensusBranchID)
+	return blake2bHash(digests.Bytes(), key)
+}
+
+func (tx *DecodedTx) transparentDigest() (chainhash.Hash, error) {
+	if len(tx.TxIn) == 0 && len(tx.TxOut) == 0 {
+		return blake2bHash(nil, []byte(transparentHashPersonalization))
+	}
+	prevouts, sequences, txOuts := new(bytes.Buffer), new(bytes.Buffer), new(bytes.Buffer)
+	for _, txIn := range tx.TxIn {
+		prevouts.Write(txIn.PreviousOutPoint.Hash[:])
+		binary.Write(prevouts, binary.LittleEndian, txIn.PreviousOutPoint.Index)
+		binary.Write(sequences, binary.LittleEndian, txIn.Sequence)
+	}
+	for _, txOut := range tx.TxOut {
+		binary.Write(txOuts, binary.LittleEndian, txOut.Value)
+		if err := wire.WriteVarBytes(txOuts, 0, txOut.PkScript); err != nil {
+			return chainhash.Hash{}, err
+		}
+	}
+	return hashDigests(transparentHashPersonalization, nil,
+		digest{prevouts.Bytes(), prevoutsHashPersonalization},
+		digest{sequences.Bytes(), sequenceHashPersonalization},
+		digest{txOuts.Bytes(), txOutsHashPersonalization},
+	)
+}
+
+func (tx *DecodedTx) saplingDigest() (chainhash.Hash, error) {
+	spends, outputs := tx.Shielded.Spends, tx.Shielded.Outputs
+	if len(spends) == 0 && len(outputs) == 0 {
+		return blake2bHash(nil, []byte(saplingHashPersonalization))
+	}
+
+	spendsDigest, err := blake2bHash(nil, []byte(saplingSpendsHashPersonalization))
+	if err != nil {
+		return chainhash.Hash{}, err
+	}
+	if len(spends) > 0 {
+		compact, noncompact := new(bytes.Buffer), new(bytes.Buffer)
+		for _, spend := range spends {
+			compact.Write(spend.Nullifier[:])
+			noncompact.Write(spend.CV[:])
+			noncompact.Write(spend.Anchor[:])
+			noncompact.Write(spend.RK[:])
+		}
+		if spendsDigest, err = hashDigests(saplingSpendsHashPersonalization, nil,
+			digest{compact.Bytes(), saplingSpendsCHashPersonalization},
+			digest{noncompact.Bytes(), saplingSpendsNHashPersonalization},
+		); err != nil {
+			return chainhash.Hash{}, err
+		}
+	}
+
+	outputsDigest, err := blake2bHash(nil, []byte(saplingOutputsHashPersonalization))
+	if err != nil {
+		return chainhash.Hash{}, err
+	}
+	if len(outputs) > 0 {
+		compact, memos, noncompact := new(bytes.Buffer), new(bytes.Buffer), new(bytes.Buffer)
+		for _, output := range outputs {
+			compact.Write(output.CMU[:])
+			compact.Write(output.EphemeralKey[:])
+			compact.Write(output.EncCiphertext[:52])
+			memos.Write(output.EncCiphertext[52:564])
+			noncompact.Write(output.CV[:])
+			noncompact.Write(output.EncCiphertext[564:])
+			noncompact.Write(output.OutCiphertext[:])
+		}
+		if outputsDigest, err = hashDigests(saplingOutputsHashPersonalization, nil,
+			digest{compact.Bytes(), saplingOutputsCHashPersonalization},
+			digest{memos.Bytes(), saplingOutputsMHashPersonalization},
+			digest{noncompact.Bytes(), saplingOutputsNHashPersonalization},
+		); err != nil {
+			return chainhash.Hash{}, err
+		}
+	}
+
+	data := new(bytes.Buffer)
+	data.Write(spendsDigest[:])
+	data.Write(outputsDigest[:])
+	binary.Write(data, binary.LittleEndian, tx.Shielded.ValueBalance)
+	return blake2bHash(data.Bytes(), []byte(saplingHashPersonalization))
+}
+
+func (tx *DecodedTx) orchardDigest() (chainhash.Hash, error) {
+	if tx.Orchard == nil {
+		return blake2bHash(nil, []byte(orchardHashPersonalization))
+	}
+	r := bytes.NewReader(tx.Orchard)
+	numActions, err := readCount(r)
+	if err != nil {
+		return chainhash.Hash{}, err
+	}
+	compact, memos, noncompact := new(bytes.Buffer), new(bytes.Buffer), new(bytes.Buffer)
+	for i := 0; i < numActions; i++ {
+		action := make([]byte, orchardActionSize)
+		if err := readFull(r, action); err != nil {
+			return chainhash.Hash{}, err
+		}
+		cv, nullifier, rk, cmx, ephemeralKey := action[0:32], action[32:64], action[64:96], action[96:128], action[128:160]
+		encCiphertext, outCiphertext := action[160:740], action[740:820]
+		compact.Write(nullifier)
+		compact.Write(cmx)
+		compact.Write(ephemeralKey)
+		compact.Write(encCiphertext[:52])
+		memos.Write(encCiphertext[52:564])
+		noncompact.Write(cv)
+		noncompact.Write(rk)
+		noncompact.Write(encCiphertext[564:])
+		noncompact.Write(outCiphertext)
+	}
+	// Flags, value balance and anchor.
+	trailer := make([]byte, 1+8+32)
+	if err := readFull(r, trailer); err != nil {
+		return chainhash.Hash{}, err
+	}
+	return hashDigests(orchardHashPersonalization, trailer,
+		digest{compact.Bytes(), orchardActionsCHashPersonalization},
+		digest{memos.Bytes(), orchardActionsMHashPersonalization},
+		digest{noncompact.Bytes(), orchardActionsNHashPersonalization},
+	)
+}
+
+// digest is data that is hashed with a personalization.
+type digest struct {
+	data            []byte
+	personalization string
+}
+
+// hashDigests hashes the concatenation of the hashes of the digests, followed
+// by the trailer, with the given personalization.
+func hashDigests(personalization string, trailer []byte, digests ...digest) (chainhash.Hash, error) {
+	buf := new(bytes.Buffer)
+	for _, d := range digests {
+		hash, err := blake2bHash(d.data, []byte(d.personalization))
+		if err != nil {
+			return chainhash.Hash{}, fmt.Errorf("cannot compute %s: %v", d.personalization, err)
+		}
+		buf.Write(hash[:])
+	}
+	buf.Write(trailer)
+	return blake2bHash(buf.Bytes(), []byte(personalization))
+}
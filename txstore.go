@@ -0,0 +1,373 @@
+package libzec
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/btcsuite/btcd/wire"
+	"github.com/renproject/libzec-go/clients"
+	bolt "go.etcd.io/bbolt"
+)
+
+// TxState is the state of a transaction broadcast by an account.
+type TxState int
+
+const (
+	// TxStatePending transactions have been broadcast, but are not mined yet.
+	TxStatePending TxState = iota
+	// TxStateConfirmed transactions have been mined.
+	TxStateConfirmed
+	// TxStateExpired transactions were not mined before their expiry height, and
+	// will never be mined.
+	TxStateExpired
+	// TxStateConflicted transactions spend an outpoint that has been spent by
+	// another transaction, and will never be mined.
+	TxStateConflicted
+)
+
+func (state TxState) String() string {
+	switch state {
+	case TxStatePending:
+		return "pending"
+	case TxStateConfirmed:
+		return "confirmed"
+	case TxStateExpired:
+		return "expired"
+	case TxStateConflicted:
+		return "conflicted"
+	default:
+		return fmt.Sprintf("TxState(%d)", int(state))
+	}
+}
+
+// StoredTx is a transaction broadcast by an account, as recorded in its
+// TxStore. The inputs are the spent outpoints, formatted as "txhash:vout".
+type StoredTx struct {
+	TxHash       string        `json:"txHash"`
+	Stx          []byte        `json:"stx"`
+	Inputs       []string      `json:"inputs"`
+	Outputs      []*wire.TxOut `json:"outputs"`
+	ExpiryHeight uint32        `json:"expiryHeight"`
+	State        TxState       `json:"state"`
+	BroadcastAt  time.Time     `json:"broadcastAt"`
+}
+
+// A TxStore persists the transactions broadcast by an account, so that they
+// can be tracked across restarts.
+type TxStore interface {
+	Put(tx StoredTx) error
+	Get(txHash string) (StoredTx, bool, error)
+	All() ([]StoredTx, error)
+}
+
+type memoryTxStore struct {
+	mu  *sync.RWMutex
+	txs map[string]StoredTx
+}
+
+// NewMemoryTxStore returns a tx store that keeps the transactions in memory.
+func NewMemoryTxStore() TxStore {
+	return &memoryTxStore{
+		mu:  new(sync.RWMutex),
+		txs: map[string]StoredTx{},
+	}
+}
+
+func (store *memoryTxStore) Put(tx StoredTx) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	store.txs[tx.TxHash] = tx
+	return nil
+}
+
+func (store *memoryTxStore) Get(txHash string) (StoredTx, bool, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+	tx, ok := store.txs[txHash]
+	return tx, ok, nil
+}
+
+func (store *memoryTxStore) All() ([]StoredTx, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+	txs := make([]StoredTx, 0, len(store.txs))
+	for _, tx := range store.txs {
+		txs = append(txs, tx)
+	}
+	return txs, nil
+}
+
+type fileTxStore struct {
+	*memoryTxStore
+	path    string
+	writeMu *sync.Mutex
+}
+
+// NewFileTxStore returns a tx store that persists the transactions as JSON in
+// the file at the given path. The file is created if it does not exist.
+func NewFileTxStore(path string) (TxStore, error) {
+	store := &fileTxStore{
+		memoryTxStore: NewMemoryTxStore().(*memoryTxStore),
+		path:          path,
+		writeMu:       new(sync.Mutex),
+	}
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	txs := []StoredTx{}
+	if err := json.Unmarshal(data, &txs); err != nil {
+		return nil, fmt.Errorf("cannot decode tx store %s: %v", path, err)
+	}
+	for _, tx := range txs {
+		store.txs[tx.TxHash] = tx
+	}
+	return store, nil
+}
+
+func (store *fileTxStore) Put(tx StoredTx) error {
+	store.writeMu.Lock()
+	defer store.writeMu.Unlock()
+	if err := store.memoryTxStore.Put(tx); err != nil {
+		return err
+	}
+	txs, err := store.All()
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(txs)
+	if err != nil {
+		return err
+	}
+
+	// Write to a temporary file first, so that the store is not corrupted
+	// if the process crashes while writing.
+	tmp := store.path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, store.path)
+}
+
+type boltTxStore struct {
+	db *bolt.DB
+}
+
+// txStoreBucket is the bucket of the bolt database in which the transactions
+// are stored, by transaction hash.
+var txStoreBucket = []byte("libzec-txs")
+
+// NewBoltTxStore returns a tx store that persists the transactions as JSON in
+// a bucket of the bolt database. Every update is written in its own bolt
+// transaction, so the store survives crashes. The database is not closed by
+// the store.
+func NewBoltTxStore(db *bolt.DB) (TxStore, error) {
+	if err := db.Update(func(btx *bolt.Tx) error {
+		_, err := btx.CreateBucketIfNotExists(txStoreBucket)
+		return err
+	}); err != nil {
+		return nil, fmt.Errorf("cannot create the tx store bucket: %v", err)
+	}
+	return &boltTxStore{db: db}, nil
+}
+
+func (store *boltTxStore) Put(tx StoredTx) error {
+	data, err := json.Marshal(tx)
+	if err != nil {
+		return err
+	}
+	return store.db.Update(func(btx *bolt.Tx) error {
+		return btx.Bucket(txStoreBucket).Put([]byte(tx.TxHash), data)
+	})
+}
+
+func (store *boltTxStore) Get(txHash string) (StoredTx, bool, error) {
+	var tx StoredTx
+	var ok bool
+	err := store.db.View(func(btx *bolt.Tx) error {
+		data := btx.Bucket(txStoreBucket).Get([]byte(txHash))
+		if data == nil {
+			return nil
+		}
+		ok = true
+		return json.Unmarshal(data, &tx)
+	})
+	return tx, ok, err
+}
+
+func (store *boltTxStore) All() ([]StoredTx, error) {
+	txs := []StoredTx{}
+	err := store.db.View(func(btx *bolt.Tx) error {
+		return btx.Bucket(txStoreBucket).ForEach(func(key, data []byte) error {
+			var tx StoredTx
+			if err := json.Unmarshal(data, &tx); err != nil {
+				return fmt.Errorf("cannot decode transaction %s: %v", key, err)
+			}
+			txs = append(txs, tx)
+			return nil
+		})
+	})
+	return txs, err
+}
+
+// SetTxStore sets the store in which the account records every transaction it
+// broadcasts. The pending transactions of the store are tracked again: their
+// unconfirmed change is returned by GetUTXOs, and the outpoints they spend
+// are not, even if the client has not seen them.
+func (account *account) SetTxStore(store TxStore) {
+	account.mu.Lock()
+	defer account.mu.Unlock()
+	account.txStore = store
+}
+
+// store returns the tx store of the account, or nil if it has none.
+func (account *account) store() TxStore {
+	account.mu.Lock()
+	defer account.mu.Unlock()
+	return account.txStore
+}
+
+// PendingTxs updates the state of the pending transactions of the tx store,
+// and returns those that are still pending. Transactions that cannot be found
+// are only marked as expired once the chain has reached their expiry height.
+func (account *account) PendingTxs() ([]StoredTx, error) {
+	store := account.store()
+	if store == nil {
+		return nil, fmt.Errorf("account has no tx store")
+	}
+	txs, err := store.All()
+	if err != nil {
+		return nil, err
+	}
+	txHashes := []string{}
+	for _, tx := range txs {
+		if tx.State == TxStatePending {
+			txHashes = append(txHashes, tx.TxHash)
+		}
+	}
+	confs, err := BatchConfirmations(account.Client, txHashes)
+	if err != nil {
+		return nil, err
+	}
+
+	var height int64
+	pending := []StoredTx{}
+	for _, tx := range txs {
+		if tx.State != TxStatePending {
+			continue
+		}
+		conf, found := confs[tx.TxHash]
+		switch {
+		case found && conf > 0:
+			tx.State = TxStateConfirmed
+		case !found:
+			if height == 0 {
+				if height, err = account.BlockHeight(); err != nil && err != ErrNotSupported {
+					return nil, err
+				}
+			}
+			if expired(tx.ExpiryHeight, height) {
+				tx.State = TxStateExpired
+			}
+		}
+		if tx.State == TxStatePending {
+			pending = append(pending, tx)
+			continue
+		}
+		account.Logger.Infof("transaction %s is %v", tx.TxHash, tx.State)
+		if err := store.Put(tx); err != nil {
+			return nil, err
+		}
+	}
+	return pending, nil
+}
+
+// recordTx stores the broadcast transaction as pending.
+func (account *account) recordTx(store TxStore, msgTx *wire.MsgTx, expiryHeight uint32, stx []byte, txHash string) error {
+	inputs := make([]string, len(msgTx.TxIn))
+	for i, txIn := range msgTx.TxIn {
+		inputs[i] = outPointKey(txIn.PreviousOutPoint.Hash.String(), txIn.PreviousOutPoint.Index)
+	}
+	return store.Put(StoredTx{
+		TxHash:       txHash,
+		Stx:          stx,
+		Inputs:       inputs,
+		Outputs:      msgTx.TxOut,
+		ExpiryHeight: expiryHeight,
+		State:        TxStatePending,
+		BroadcastAt:  time.Now(),
+	})
+}
+
+// withPendingTxs removes the utxos of the address that are spent by the
+// pending transactions of the tx store, and adds the outputs of these
+// transactions that pay to the address and are not spent by another one.
+// Pending transactions that have expired are ignored, even if PendingTxs has
+// not marked them as expired yet, so that the utxos of a dropped transaction
+// can be spent again.
+func (account *account) withPendingTxs(store TxStore, address string, utxos []clients.UTXO) ([]clients.UTXO, error) {
+	stored, err := store.All()
+	if err != nil {
+		return nil, err
+	}
+	// Nothing expires if the height of the chain is unknown.
+	height, err := account.BlockHeight()
+	if err != nil {
+		height = 0
+	}
+	txs := []StoredTx{}
+	for _, tx := range stored {
+		if tx.State == TxStatePending && !expired(tx.ExpiryHeight, height) {
+			txs = append(txs, tx)
+		}
+	}
+	addr, err := DecodeAddress(address, account.NetworkParams())
+	if err != nil {
+		return nil, err
+	}
+	script, err := PayToAddrScript(addr)
+	if err != nil {
+		return nil, err
+	}
+	scriptPubKey := hex.EncodeToString(script)
+
+	spent := map[string]bool{}
+	for _, tx := range txs {
+		for _, input := range tx.Inputs {
+			spent[input] = true
+		}
+	}
+	known := map[string]bool{}
+	unspent := []clients.UTXO{}
+	for _, utxo := range utxos {
+		key := outPointKey(utxo.TxHash, utxo.Vout)
+		known[key] = true
+		if !spent[key] {
+			unspent = append(unspent, utxo)
+		}
+	}
+	for _, tx := range txs {
+		for i, txOut := range tx.Outputs {
+			key := outPointKey(tx.TxHash, uint32(i))
+			if known[key] || spent[key] || hex.EncodeToString(txOut.PkScript) != scriptPubKey {
+				continue
+			}
+			unspent = append(unspent, clients.UTXO{
+				TxHash:       tx.TxHash,
+				Amount:       txOut.Value,
+				ScriptPubKey: scriptPubKey,
+				Vout:         uint32(i),
+				Address:      address,
+			})
+		}
+	}
+	return unspent, nil
+}
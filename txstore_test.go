@@ -0,0 +1,210 @@
+package libzec_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/renproject/libzec-go"
+	"github.com/renproject/libzec-go/clients"
+	bolt "go.etcd.io/bbolt"
+)
+
+var _ = Describe("Tx stores", func() {
+	var dir string
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "libzec-txstore")
+		Expect(err).Should(BeNil())
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(dir)).Should(BeNil())
+	})
+
+	storedTx := func(txHash string, state TxState) StoredTx {
+		return StoredTx{
+			TxHash:       txHash,
+			Stx:          []byte{1, 2, 3},
+			Inputs:       []string{"00:1"},
+			Outputs:      []*wire.TxOut{wire.NewTxOut(1000, []byte{0x51})},
+			ExpiryHeight: 100,
+			State:        state,
+			BroadcastAt:  time.Unix(1600000000, 0).UTC(),
+		}
+	}
+
+	// testStore checks the store, and the store returned by reopen for its
+	// persistence.
+	testStore := func(store TxStore, reopen func() TxStore) {
+		_, ok, err := store.Get("a")
+		Expect(err).Should(BeNil())
+		Expect(ok).Should(BeFalse())
+
+		Expect(store.Put(storedTx("a", TxStatePending))).Should(BeNil())
+		Expect(store.Put(storedTx("b", TxStatePending))).Should(BeNil())
+		Expect(store.Put(storedTx("a", TxStateConfirmed))).Should(BeNil())
+
+		check := func(store TxStore) {
+			tx, ok, err := store.Get("a")
+			Expect(err).Should(BeNil())
+			Expect(ok).Should(BeTrue())
+			Expect(tx).Should(Equal(storedTx("a", TxStateConfirmed)))
+			txs, err := store.All()
+			Expect(err).Should(BeNil())
+			Expect(txs).Should(ConsistOf(storedTx("a", TxStateConfirmed), storedTx("b", TxStatePending)))
+		}
+		check(store)
+		check(reopen())
+	}
+
+	It("should store transactions in memory", func() {
+		store := NewMemoryTxStore()
+		testStore(store, func() TxStore { return store })
+	})
+
+	It("should persist transactions in a file", func() {
+		path := filepath.Join(dir, "txs.json")
+		store, err := NewFileTxStore(path)
+		Expect(err).Should(BeNil())
+		testStore(store, func() TxStore {
+			store, err := NewFileTxStore(path)
+			Expect(err).Should(BeNil())
+			return store
+		})
+	})
+
+	It("should persist transactions in a bolt database", func() {
+		path := filepath.Join(dir, "txs.db")
+		db, err := bolt.Open(path, 0600, nil)
+		Expect(err).Should(BeNil())
+		store, err := NewBoltTxStore(db)
+		Expect(err).Should(BeNil())
+		testStore(store, func() TxStore {
+			Expect(db.Close()).Should(BeNil())
+			db, err = bolt.Open(path, 0600, nil)
+			Expect(err).Should(BeNil())
+			store, err := NewBoltTxStore(db)
+			Expect(err).Should(BeNil())
+			return store
+		})
+		Expect(db.Close()).Should(BeNil())
+	})
+})
+
+var _ = Describe("Pending transactions", func() {
+	var core *mockClientCore
+	var account Account
+	var store TxStore
+	var receipt TxReceipt
+	var funding clients.UTXO
+
+	BeforeEach(func() {
+		var addr btcutil.Address
+		core = newMockClientCore(&chaincfg.TestNet3Params, 1842420)
+		account, _, addr = newMockAccount(core, 1000000)
+		funding = core.utxos[addr.EncodeAddress()][0]
+		_, _, to := newMockAccount(core, 0)
+		store = NewMemoryTxStore()
+		account.SetTxStore(store)
+
+		var err error
+		receipt, err = account.Transfer(context.Background(), to.EncodeAddress(), 100000, Standard, false)
+		Expect(err).Should(BeNil())
+		Expect(core.published).Should(HaveLen(1))
+	})
+
+	utxos := func() []clients.UTXO {
+		addr, err := account.Address()
+		Expect(err).Should(BeNil())
+		utxos, err := account.GetUTXOs(addr.EncodeAddress(), 0, 0)
+		Expect(err).Should(BeNil())
+		return utxos
+	}
+
+	state := func() TxState {
+		tx, ok, err := store.Get(receipt.TxHash)
+		Expect(err).Should(BeNil())
+		Expect(ok).Should(BeTrue())
+		return tx.State
+	}
+
+	It("should spend the change of pending transactions", func() {
+		Expect(utxos()).Should(HaveLen(1))
+		Expect(utxos()[0].TxHash).Should(Equal(receipt.TxHash))
+		Expect(utxos()[0].Amount).Should(Equal(receipt.Outputs[receipt.ChangeIndex].Value))
+	})
+
+	It("should expire pending transactions at their expiry height", func() {
+		core.setHeight(int64(receipt.ExpiryHeight) - 1)
+		pending, err := account.PendingTxs()
+		Expect(err).Should(BeNil())
+		Expect(pending).Should(HaveLen(1))
+
+		core.setHeight(int64(receipt.ExpiryHeight))
+		pending, err = account.PendingTxs()
+		Expect(err).Should(BeNil())
+		Expect(pending).Should(BeEmpty())
+		Expect(state()).Should(Equal(TxStateExpired))
+	})
+
+	It("should release the utxos of dropped transactions once they expire", func() {
+		core.setHeight(int64(receipt.ExpiryHeight))
+		Expect(utxos()).Should(Equal([]clients.UTXO{funding}))
+	})
+
+	It("should confirm pending transactions", func() {
+		core.confirmations[receipt.TxHash] = 1
+		pending, err := account.PendingTxs()
+		Expect(err).Should(BeNil())
+		Expect(pending).Should(BeEmpty())
+		Expect(state()).Should(Equal(TxStateConfirmed))
+	})
+
+	It("should rebroadcast missing transactions until they are mined", func() {
+		rebroadcaster := NewRebroadcaster(account, store, 0, time.Hour, nil)
+		rebroadcaster.Poll()
+		Expect(core.published).Should(HaveLen(2))
+		Expect(core.published[1]).Should(Equal(core.published[0]))
+		Expect(state()).Should(Equal(TxStatePending))
+
+		core.confirmations[receipt.TxHash] = 1
+		rebroadcaster.Poll()
+		Expect(core.published).Should(HaveLen(2))
+		Expect(state()).Should(Equal(TxStateConfirmed))
+	})
+
+	It("should not rebroadcast expired transactions", func() {
+		core.setHeight(int64(receipt.ExpiryHeight))
+		NewRebroadcaster(account, store, 0, time.Hour, nil).Poll()
+		Expect(core.published).Should(HaveLen(1))
+		Expect(state()).Should(Equal(TxStateExpired))
+	})
+
+	It("should alert when a pending transaction is double spent", func() {
+		detector := NewDoubleSpendDetector(account, store, nil)
+		doubleSpends, err := detector.Check()
+		Expect(err).Should(BeNil())
+		Expect(doubleSpends).Should(BeEmpty())
+
+		core.spend(funding)
+		doubleSpends, err = detector.Check()
+		Expect(err).Should(BeNil())
+		Expect(doubleSpends).Should(HaveLen(1))
+		Expect(doubleSpends[0].TxHash).Should(Equal(receipt.TxHash))
+		Expect(doubleSpends[0].OutPoint).Should(Equal(funding.TxHash + ":0"))
+		Expect(state()).Should(Equal(TxStateConflicted))
+
+		doubleSpends, err = detector.Check()
+		Expect(err).Should(BeNil())
+		Expect(doubleSpends).Should(BeEmpty())
+	})
+})
@@ -0,0 +1,276 @@
+package libzec
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/codahale/blake2"
+)
+
+// ReceiverType is the typecode of a receiver of a unified address, as
+// specified in ZIP-316.
+type ReceiverType uint64
+
+const (
+	ReceiverP2PKH   ReceiverType = 0x00
+	ReceiverP2SH    ReceiverType = 0x01
+	ReceiverSapling ReceiverType = 0x02
+	ReceiverOrchard ReceiverType = 0x03
+)
+
+func (typ ReceiverType) String() string {
+	switch typ {
+	case ReceiverP2PKH:
+		return "p2pkh"
+	case ReceiverP2SH:
+		return "p2sh"
+	case ReceiverSapling:
+		return "sapling"
+	case ReceiverOrchard:
+		return "orchard"
+	default:
+		return fmt.Sprintf("unknown(%d)", uint64(typ))
+	}
+}
+
+// Receiver is a receiver of a unified address. Data is its raw encoding.
+type Receiver struct {
+	Type ReceiverType
+	Data []byte
+}
+
+// UnifiedAddress is a unified address, which bundles the receivers of a
+// wallet for every pool it supports. Transactions built by this library can
+// only pay its transparent receiver.
+type UnifiedAddress struct {
+	encoded   string
+	receivers []Receiver
+	params    *chaincfg.Params
+}
+
+// isUnifiedAddress returns whether the address looks like a unified address
+// of any network.
+func isUnifiedAddress(address string) bool {
+	address = strings.ToLower(address)
+	for _, encoding := range networkEncodings {
+		if strings.HasPrefix(address, encoding.unifiedHRP+"1") {
+			return true
+		}
+	}
+	return false
+}
+
+// DecodeUnifiedAddress decodes a unified address of the network, and
+// validates its receivers. Receivers of unknown types are kept, so that
+// addresses of wallets supporting newer pools can still be paid.
+func DecodeUnifiedAddress(address string, params *chaincfg.Params) (*UnifiedAddress, error) {
+	encoding, err := encodingOf(params)
+	if err != nil {
+		return nil, err
+	}
+	hrp := encoding.unifiedHRP
+	decodedHRP, data, err := bech32Decode(address, bech32m)
+	if err != nil {
+		return nil, err
+	}
+	if decodedHRP != hrp {
+		return nil, fmt.Errorf("invalid unified address prefix: got: %s required: %s", decodedHRP, hrp)
+	}
+	data, err = f4Jumble(data, true)
+	if err != nil {
+		return nil, err
+	}
+
+	// The receivers are followed by the human readable part, padded to 16
+	// bytes.
+	padding := make([]byte, 16)
+	copy(padding, hrp)
+	if !bytes.Equal(data[len(data)-16:], padding) {
+		return nil, fmt.Errorf("invalid unified address padding")
+	}
+
+	addr := &UnifiedAddress{encoded: strings.ToLower(address), params: params}
+	r := bytes.NewReader(data[:len(data)-16])
+	for r.Len() > 0 {
+		typ, err := wire.ReadVarInt(r, 0)
+		if err != nil {
+			return nil, fmt.Errorf("invalid unified address receiver type: %v", err)
+		}
+		length, err := wire.ReadVarInt(r, 0)
+		if err != nil || length > uint64(r.Len()) {
+			return nil, fmt.Errorf("invalid unified address receiver length")
+		}
+		receiver := Receiver{ReceiverType(typ), make([]byte, length)}
+		r.Read(receiver.Data)
+		if n := len(addr.receivers); n > 0 && addr.receivers[n-1].Type >= receiver.Type {
+			return nil, fmt.Errorf("invalid unified address: receivers are not sorted by type")
+		}
+		if err := receiver.validate(params); err != nil {
+			return nil, err
+		}
+		addr.receivers = append(addr.receivers, receiver)
+	}
+
+	if _, ok := addr.Receiver(ReceiverP2PKH); ok {
+		if _, ok := addr.Receiver(ReceiverP2SH); ok {
+			return nil, fmt.Errorf("invalid unified address: both p2pkh and p2sh receivers")
+		}
+	}
+	for _, receiver := range addr.receivers {
+		if receiver.Type != ReceiverP2PKH && receiver.Type != ReceiverP2SH {
+			return addr, nil
+		}
+	}
+	return nil, fmt.Errorf("invalid unified address: no shielded receiver")
+}
+
+// validate checks the length of a receiver, and the encoding of the known
+// shielded receivers.
+func (receiver Receiver) validate(params *chaincfg.Params) error {
+	switch receiver.Type {
+	case ReceiverP2PKH, ReceiverP2SH:
+		if len(receiver.Data) != 20 {
+			return fmt.Errorf("invalid %v receiver length: got: %d required: %d", receiver.Type, len(receiver.Data), 20)
+		}
+	case ReceiverSapling:
+		if _, err := newSaplingAddress(receiver.Data, params); err != nil {
+			return fmt.Errorf("invalid sapling receiver: %v", err)
+		}
+	case ReceiverOrchard:
+		if _, ok := newOrchardReceiver(receiver.Data); !ok {
+			return fmt.Errorf("invalid orchard receiver")
+		}
+	}
+	return nil
+}
+
+func (addr *UnifiedAddress) String() string {
+	return addr.encoded
+}
+
+func (addr *UnifiedAddress) EncodeAddress() string {
+	return addr.encoded
+}
+
+func (addr *UnifiedAddress) IsForNet(params *chaincfg.Params) bool {
+	return addr.params.Name == params.Name
+}
+
+// Receivers returns the receivers of the address, sorted by type.
+func (addr *UnifiedAddress) Receivers() []Receiver {
+	return addr.receivers
+}
+
+// Receiver returns the receiver of the given type, and false if the address
+// does not have one.
+func (addr *UnifiedAddress) Receiver(typ ReceiverType) (Receiver, bool) {
+	for _, receiver := range addr.receivers {
+		if receiver.Type == typ {
+			return receiver, true
+		}
+	}
+	return Receiver{}, false
+}
+
+// Orchard returns the Orchard receiver of the address, and false if the
+// address does not have one.
+func (addr *UnifiedAddress) Orchard() (*OrchardReceiver, bool) {
+	receiver, ok := addr.Receiver(ReceiverOrchard)
+	if !ok {
+		return nil, false
+	}
+	return newOrchardReceiver(receiver.Data)
+}
+
+// Sapling returns the Sapling receiver of the address, and false if the
+// address does not have one.
+func (addr *UnifiedAddress) Sapling() (*SaplingAddress, bool) {
+	receiver, ok := addr.Receiver(ReceiverSapling)
+	if !ok {
+		return nil, false
+	}
+	sapling, err := newSaplingAddress(receiver.Data, addr.params)
+	return sapling, err == nil
+}
+
+// TransparentAddress returns the transparent receiver of the address, which
+// is the only receiver that transactions built by this library can pay. An
+// UnsupportedReceiversError is returned if the address does not have one.
+func (addr *UnifiedAddress) TransparentAddress() (btcutil.Address, error) {
+	for _, receiver := range addr.receivers {
+		hash := [20]byte{}
+		switch receiver.Type {
+		case ReceiverP2PKH:
+			copy(hash[:], receiver.Data)
+			return AddressFromHash160(hash, addr.params, false)
+		case ReceiverP2SH:
+			copy(hash[:], receiver.Data)
+			return AddressFromHash160(hash, addr.params, true)
+		}
+	}
+	types := make([]string, len(addr.receivers))
+	for i, receiver := range addr.receivers {
+		types[i] = receiver.Type.String()
+	}
+	return nil, NewErrUnsupportedReceivers(addr.encoded, types)
+}
+
+// f4Jumble applies the F4Jumble permutation of ZIP-316 to the message, or its
+// inverse. ZIP-316 defines it for messages of 48 to 4194368 bytes.
+func f4Jumble(msg []byte, inverse bool) ([]byte, error) {
+	if len(msg) < 48 || len(msg) > 4194368 {
+		return nil, fmt.Errorf("invalid f4jumble message length %d", len(msg))
+	}
+	lenL := len(msg) / 2
+	if lenL > 64 {
+		lenL = 64
+	}
+	lenR := len(msg) - lenL
+
+	h := func(i byte, u []byte) []byte {
+		personal := append([]byte("UA_F4Jumble_H"), i, 0, 0)
+		return blake2bSum(personal, lenL, u)
+	}
+	g := func(i byte, u []byte) []byte {
+		out := make([]byte, 0, lenR+64)
+		for j := 0; len(out) < lenR; j++ {
+			personal := append([]byte("UA_F4Jumble_G"), i, byte(j), byte(j>>8))
+			out = append(out, blake2bSum(personal, 64, u)...)
+		}
+		return out[:lenR]
+	}
+
+	out := append([]byte{}, msg...)
+	a, b := out[:lenL], out[lenL:]
+	if !inverse {
+		xorBytes(b, g(0, a))
+		xorBytes(a, h(0, b))
+		xorBytes(b, g(1, a))
+		xorBytes(a, h(1, b))
+	} else {
+		xorBytes(a, h(1, b))
+		xorBytes(b, g(1, a))
+		xorBytes(a, h(0, b))
+		xorBytes(b, g(0, a))
+	}
+	return out, nil
+}
+
+func blake2bSum(personal []byte, size int, data []byte) []byte {
+	hash := blake2.New(&blake2.Config{
+		Size:     uint8(size),
+		Personal: personal,
+	})
+	hash.Write(data)
+	return hash.Sum(nil)
+}
+
+func xorBytes(dst, src []byte) {
+	for i := range dst {
+		dst[i] ^= src[i]
+	}
+}
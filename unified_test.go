@@ -0,0 +1,70 @@
+package libzec_test
+
+import (
+	"errors"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/renproject/libzec-go"
+)
+
+var _ = Describe("Unified addresses", func() {
+	const (
+		// p2pkh, sapling and orchard receivers
+		fullAddress = "u1aahwv9007at9nl57h8xqzykuv8cwv0wwzkk9e7mf6v4pwr2r00qczk7qavg8ezk2apzyuuh0nsr9rsnktlhnm4k67hf2m86xjws4nhuhn7apuy240w9txq08ry8muqs77n37m40c8r2m420f0kfz36s36pfvkmy9x6w4sr57q5n6c7hsh862jyqv6j8csu5rg729kx5n9jy3q6kt7qe"
+		// sapling and orchard receivers
+		shieldedAddress = "u1q9uzcrevcl6t67uay5uany686z00cenjmylqz87l5xpk00zk2kt47e2779ajpf9v5svghy7e0rs9xmmjx2yg5hxd9p2p3skj98jvq6phtmyvhnhk7vsxm57qq8zylyh6k6ryq3fqdy6u24h3l86ul0xvaa6ddxn5td7p3d9alc7mqtdf"
+		// orchard receiver
+		orchardAddress = "u17e5aenanfhxuxjg4ccranypn4p3uqf4xhweqzpd4n5mdyn8j355q776c6a9yeyvh63xwt75ldt8twtjzjz50ydzzujw5arn27y2fxl4z"
+		// orchard receiver whose transmission key is the identity
+		invalidOrchardAddress = "u167v4z020ek5th4zsfcxsa7ft8m00yr5rr5twxvdaxuz8cx6836q87uqfqel8n3w4kkvtuhs9yeh4kcz8ers9r9auggztxctkzv9w54a7"
+		// 40 bytes, shorter than the 48 bytes required by ZIP-316
+		shortAddress = "u1qqqsyqcyq5rqwzqfpg9scrgwpugpzysnzs23v9ccrydpk8qarc0jqgfzyvjz2f38hjlpde"
+	)
+
+	It("should decode the receivers", func() {
+		addr, err := DecodeUnifiedAddress(fullAddress, &chaincfg.MainNetParams)
+		Expect(err).Should(BeNil())
+		Expect(addr.Receivers()).Should(HaveLen(3))
+		_, ok := addr.Orchard()
+		Expect(ok).Should(BeTrue())
+		sapling, ok := addr.Sapling()
+		Expect(ok).Should(BeTrue())
+		Expect(sapling.EncodeAddress()).Should(Equal("zs1mnnha08vpgn2l45e338j5fzrq3h58gyfl420vsd2lkz8mec2ggzrunx5pquuw3rsvv49kk08dj0"))
+	})
+
+	It("should pay the transparent receiver", func() {
+		addr, err := DecodeAddress(fullAddress, &chaincfg.MainNetParams)
+		Expect(err).Should(BeNil())
+		Expect(addr.EncodeAddress()).Should(Equal("t1VmmGiyjVNeCjxDZzg7vZmd99WyzVby9yC"))
+	})
+
+	It("should not pay addresses without a transparent receiver", func() {
+		_, err := DecodeAddress(shieldedAddress, &chaincfg.MainNetParams)
+		Expect(errors.Is(err, ErrUnsupportedReceivers)).Should(BeTrue())
+		Expect(errors.Is(err, ErrOrchardOnly)).Should(BeFalse())
+
+		_, err = DecodeAddress(orchardAddress, &chaincfg.MainNetParams)
+		Expect(errors.Is(err, ErrOrchardOnly)).Should(BeTrue())
+		unsupported := &UnsupportedReceiversError{}
+		Expect(errors.As(err, &unsupported)).Should(BeTrue())
+		Expect(unsupported.Receivers).Should(Equal([]string{"orchard"}))
+	})
+
+	It("should reject invalid orchard receivers", func() {
+		_, err := DecodeUnifiedAddress(invalidOrchardAddress, &chaincfg.MainNetParams)
+		Expect(err).ShouldNot(BeNil())
+	})
+
+	It("should reject addresses shorter than 48 bytes", func() {
+		_, err := DecodeUnifiedAddress(shortAddress, &chaincfg.MainNetParams)
+		Expect(err).ShouldNot(BeNil())
+		Expect(err.Error()).Should(ContainSubstring("length 40"))
+	})
+
+	It("should reject addresses of another network", func() {
+		_, err := DecodeUnifiedAddress(fullAddress, &chaincfg.TestNet3Params)
+		Expect(err).ShouldNot(BeNil())
+	})
+})
@@ -5,7 +5,10 @@ import (
 	"encoding/binary"
 	"fmt"
 	"math"
+	"runtime"
+	"sync"
 
+	"github.com/btcsuite/btcd/chaincfg"
 	"github.com/btcsuite/btcd/chaincfg/chainhash"
 	"github.com/btcsuite/btcd/txscript"
 	"github.com/btcsuite/btcd/wire"
@@ -34,10 +37,64 @@ const (
 	versionSaplingGroupID           = 0x892f2085
 )
 
-var upgradeParams = []upgradeParam{
-	{0, []byte{0x00, 0x00, 0x00, 0x00}},
-	{207500, []byte{0x19, 0x1B, 0xA8, 0x5B}},
-	{280000, []byte{0xBB, 0x09, 0xB8, 0x76}},
+// networkUpgrades are the activation heights and the consensus branch ids of
+// the network upgrades of every supported network, in activation order.
+var networkUpgrades = map[Network][]upgradeParam{
+	Mainnet: {
+		{0, []byte{0x00, 0x00, 0x00, 0x00}},
+		{347500, []byte{0x19, 0x1B, 0xA8, 0x5B}},  // Overwinter
+		{419200, []byte{0xBB, 0x09, 0xB8, 0x76}},  // Sapling
+		{653600, []byte{0x60, 0x0E, 0xB4, 0x2B}},  // Blossom
+		{903000, []byte{0x0B, 0x23, 0xB9, 0xF5}},  // Heartwood
+		{1046400, []byte{0xA6, 0x75, 0xFF, 0xE9}}, // Canopy
+		{1687104, []byte{0xB4, 0xD0, 0xD6, 0xC2}}, // NU5
+		{2726400, []byte{0x55, 0x10, 0xE7, 0xC8}}, // NU6
+		{3146400, []byte{0xF0, 0x4D, 0xEC, 0x4D}}, // NU6.1
+	},
+	Testnet: {
+		{0, []byte{0x00, 0x00, 0x00, 0x00}},
+		{207500, []byte{0x19, 0x1B, 0xA8, 0x5B}},  // Overwinter
+		{280000, []byte{0xBB, 0x09, 0xB8, 0x76}},  // Sapling
+		{584000, []byte{0x60, 0x0E, 0xB4, 0x2B}},  // Blossom
+		{903800, []byte{0x0B, 0x23, 0xB9, 0xF5}},  // Heartwood
+		{1028500, []byte{0xA6, 0x75, 0xFF, 0xE9}}, // Canopy
+		{1842420, []byte{0xB4, 0xD0, 0xD6, 0xC2}}, // NU5
+		{2976000, []byte{0x55, 0x10, 0xE7, 0xC8}}, // NU6
+		{3536500, []byte{0xF0, 0x4D, 0xEC, 0x4D}}, // NU6.1
+	},
+	// Regtest nodes activate the upgrades at the heights given by -nuparams.
+	// These are the heights of a regtest node started with every upgrade up
+	// to NU5 active from the first block, as zebrad does by default and as
+	// the zcashd test framework configures it.
+	Regtest: {
+		{0, []byte{0x00, 0x00, 0x00, 0x00}},
+		{1, []byte{0xB4, 0xD0, 0xD6, 0xC2}}, // NU5
+	},
+}
+
+// ConsensusBranchID returns the consensus branch id that a transaction mined
+// in the block after the given chain height must commit to when it is signed.
+func ConsensusBranchID(params *chaincfg.Params, height int64) (uint32, error) {
+	network, err := NetworkOf(params)
+	if err != nil {
+		return 0, err
+	}
+	upgrades, ok := networkUpgrades[network]
+	if !ok {
+		return 0, NewErrUnsupportedNetwork(params.Name)
+	}
+	return binary.LittleEndian.Uint32(activeUpgrade(upgrades, uint32(height+1)).BranchID), nil
+}
+
+// activeUpgrade returns the last upgrade that is active at the given height.
+func activeUpgrade(upgrades []upgradeParam, height uint32) upgradeParam {
+	var i int
+	for i = len(upgrades) - 1; i > 0; i-- {
+		if height >= upgrades[i].ActivationHeight {
+			break
+		}
+	}
+	return upgrades[i]
 }
 
 // blake2bHash zcash hash func
@@ -55,17 +112,162 @@ func blake2bHash(data, key []byte) (h chainhash.Hash, err error) {
 	return h, err
 }
 
+// CalcSignatureHash returns the signature hash of the input at the given
+// index, committing to the given consensus branch id. The branch id must be
+// the one of the block the transaction is mined in, as returned by
+// ConsensusBranchID for the current chain height.
 func CalcSignatureHash(
 	subScript []byte,
 	hashType txscript.SigHashType,
 	tx *zecutil.MsgTx,
 	idx int,
 	amt int64,
+	branchID uint32,
+) ([]byte, error) {
+	return calcSignatureHash(subScript, hashType, tx, idx, amt, branchSigHashKey(branchID))
+}
+
+// CalcSignatureHashForBranch is the same as CalcSignatureHash.
+//
+// Deprecated: use CalcSignatureHash.
+func CalcSignatureHashForBranch(
+	subScript []byte,
+	hashType txscript.SigHashType,
+	tx *zecutil.MsgTx,
+	idx int,
+	amt int64,
+	branchID uint32,
 ) ([]byte, error) {
+	return CalcSignatureHash(subScript, hashType, tx, idx, amt, branchID)
+}
+
+func calcSignatureHash(
+	subScript []byte,
+	hashType txscript.SigHashType,
+	tx *zecutil.MsgTx,
+	idx int,
+	amt int64,
+	key []byte,
+) ([]byte, error) {
+	return calcShieldedSignatureHash(subScript, hashType, tx, idx, amt, key, nil)
+}
+
+// calcShieldedSignatureHash computes the ZIP-243 signature hash. The hashes of
+// the shielded components are zero if shielded is nil.
+func calcShieldedSignatureHash(
+	subScript []byte,
+	hashType txscript.SigHashType,
+	tx *zecutil.MsgTx,
+	idx int,
+	amt int64,
+	key []byte,
+	shielded *ShieldedData,
+) ([]byte, error) {
+	hasher, err := newSigHasher(tx, key, shielded)
+	if err != nil {
+		return nil, err
+	}
+	return hasher.hash(subScript, hashType, idx, amt)
+}
+
+// sigHasher computes the signature hashes of the inputs of a transaction. The
+// hashes of the prevouts, sequences, outputs and shielded components do not
+// depend on the input being signed, so they are computed once and shared by
+// every input. The transaction must not be modified after the sigHasher is
+// created.
+type sigHasher struct {
+	tx        *zecutil.MsgTx
+	key       []byte
+	shielded  *ShieldedData
+	sigHashes *txscript.TxSigHashes
+
+	hashJoinSplits      chainhash.Hash
+	hashShieldedSpends  chainhash.Hash
+	hashShieldedOutputs chainhash.Hash
+}
+
+func newSigHasher(tx *zecutil.MsgTx, key []byte, shielded *ShieldedData) (*sigHasher, error) {
 	sigHashes, err := zecutil.NewTxSigHashes(tx)
 	if err != nil {
 		return nil, err
 	}
+	hasher := &sigHasher{
+		tx:        tx,
+		key:       key,
+		shielded:  shielded,
+		sigHashes: sigHashes,
+	}
+	if hasher.hashJoinSplits, err = shielded.hashJoinSplits(); err != nil {
+		return nil, err
+	}
+	if hasher.hashShieldedSpends, err = shielded.hashShieldedSpends(); err != nil {
+		return nil, err
+	}
+	if hasher.hashShieldedOutputs, err = shielded.hashShieldedOutputs(); err != nil {
+		return nil, err
+	}
+	return hasher, nil
+}
+
+// parallelSigHashThreshold is the number of inputs from which the signature
+// hashes of a transaction are computed concurrently.
+const parallelSigHashThreshold = 64
+
+// hashInputs returns the signature hash of the given type of every input,
+// given the sub script and the amount of each input. The hashes of transactions with
+// many inputs, such as sweeps, are computed by a pool of workers, one per CPU.
+func (hasher *sigHasher) hashInputs(subScripts [][]byte, amounts []int64, hashType txscript.SigHashType) ([][]byte, error) {
+	if len(subScripts) != len(amounts) {
+		return nil, fmt.Errorf("invalid number of amounts: got: %d required: %d", len(amounts), len(subScripts))
+	}
+	hashes := make([][]byte, len(subScripts))
+	workers := runtime.NumCPU()
+	if len(subScripts) < parallelSigHashThreshold || workers < 2 {
+		for i := range subScripts {
+			hash, err := hasher.hash(subScripts[i], hashType, i, amounts[i])
+			if err != nil {
+				return nil, err
+			}
+			hashes[i] = hash
+		}
+		return hashes, nil
+	}
+
+	// Every worker hashes the inputs at the indices congruent to its own
+	// index, and reports the first error it encounters.
+	errs := make([]error, workers)
+	wg := new(sync.WaitGroup)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := w; i < len(subScripts); i += workers {
+				hash, err := hasher.hash(subScripts[i], hashType, i, amounts[i])
+				if err != nil {
+					errs[w] = err
+					return
+				}
+				hashes[i] = hash
+			}
+		}(w)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return hashes, nil
+}
+
+// hash computes the ZIP-243 signature hash of the input at the given index.
+func (hasher *sigHasher) hash(
+	subScript []byte,
+	hashType txscript.SigHashType,
+	idx int,
+	amt int64,
+) ([]byte, error) {
+	tx, sigHashes, shielded := hasher.tx, hasher.sigHashes, hasher.shielded
 
 	// As a sanity check, ensure the passed input index for the transaction
 	// is valid.
@@ -146,16 +348,13 @@ func CalcSignatureHash(
 	}
 
 	// << hashJoinSplits
-	sigHash.Write(zeroHash[:])
+	sigHash.Write(hasher.hashJoinSplits[:])
 
 	// << hashShieldedSpends
-	if tx.Version == versionSapling {
-		sigHash.Write(zeroHash[:])
-	}
-
 	// << hashShieldedOutputs
 	if tx.Version == versionSapling {
-		sigHash.Write(zeroHash[:])
+		sigHash.Write(hasher.hashShieldedSpends[:])
+		sigHash.Write(hasher.hashShieldedOutputs[:])
 	}
 
 	// << nLockTime
@@ -171,7 +370,7 @@ func CalcSignatureHash(
 	// << valueBalance
 	if tx.Version == versionSapling {
 		var valueBalance [8]byte
-		binary.LittleEndian.PutUint64(valueBalance[:], 0)
+		binary.LittleEndian.PutUint64(valueBalance[:], uint64(shielded.valueBalance()))
 		sigHash.Write(valueBalance[:])
 	}
 
@@ -210,22 +409,18 @@ func CalcSignatureHash(
 		sigHash.Write(bSequence[:])
 	}
 
-	var h chainhash.Hash
-	if h, err = blake2bHash(sigHash.Bytes(), sigHashKey(tx.ExpiryHeight)); err != nil {
+	h, err := blake2bHash(sigHash.Bytes(), hasher.key)
+	if err != nil {
 		return nil, err
 	}
 
 	return h.CloneBytes(), nil
 }
 
-// sigHashKey return blake2b key by current height
-func sigHashKey(activationHeight uint32) []byte {
-	var i int
-	for i = len(upgradeParams) - 1; i >= 0; i-- {
-		if activationHeight >= upgradeParams[i].ActivationHeight {
-			break
-		}
-	}
-
-	return append([]byte(blake2BSigHash), upgradeParams[i].BranchID...)
+// branchSigHashKey returns the blake2b key for the given consensus branch id.
+func branchSigHashKey(branchID uint32) []byte {
+	key := make([]byte, len(blake2BSigHash)+4)
+	copy(key, blake2BSigHash)
+	binary.LittleEndian.PutUint32(key[len(blake2BSigHash):], branchID)
+	return key
 }
@@ -0,0 +1,61 @@
+package libzec
+
+import (
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/btcsuite/btcd/chaincfg"
+)
+
+// parallelValidationThreshold is the number of addresses from which
+// ValidateAll validates addresses concurrently.
+const parallelValidationThreshold = 256
+
+// NormalizeAddress returns the canonical form of an address of the network,
+// without surrounding whitespace and with bech32 addresses in lower case, and
+// an error if the address cannot be paid.
+func NormalizeAddress(address string, params *chaincfg.Params) (string, error) {
+	address = strings.TrimSpace(address)
+	if isTEXAddress(address) || isUnifiedAddress(address) {
+		address = strings.ToLower(address)
+	}
+	if _, err := DecodeAddress(address, params); err != nil {
+		return "", err
+	}
+	return address, nil
+}
+
+// ValidateAll validates the canonical form of the addresses, as returned by
+// NormalizeAddress, and returns the error of every address by address. The
+// error of a valid address is nil.
+func (client *client) ValidateAll(addresses []string) map[string]error {
+	params := client.NetworkParams()
+	errs := make([]error, len(addresses))
+	validate := func(start, step int) {
+		for i := start; i < len(addresses); i += step {
+			_, errs[i] = NormalizeAddress(addresses[i], params)
+		}
+	}
+
+	workers := runtime.NumCPU()
+	if len(addresses) < parallelValidationThreshold || workers == 1 {
+		validate(0, 1)
+	} else {
+		wg := new(sync.WaitGroup)
+		wg.Add(workers)
+		for w := 0; w < workers; w++ {
+			go func(w int) {
+				defer wg.Done()
+				validate(w, workers)
+			}(w)
+		}
+		wg.Wait()
+	}
+
+	results := make(map[string]error, len(addresses))
+	for i, address := range addresses {
+		results[address] = errs[i]
+	}
+	return results
+}
@@ -0,0 +1,134 @@
+package libzec_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/renproject/libzec-go"
+)
+
+// loadTestVectors loads the test vectors of the given ZIP, from the JSON file
+// generated by https://github.com/zcash/zcash-test-vectors and copied into
+// testdata, such as testdata/zip_0243.json. The vectors are not part of the
+// repository, so the spec is skipped if the file is absent. The first row of
+// the file is a comment, the second row lists the names of the fields, and
+// every other row is a vector.
+func loadTestVectors(name string) []map[string]json.RawMessage {
+	data, err := ioutil.ReadFile(filepath.Join("testdata", name+".json"))
+	if os.IsNotExist(err) {
+		Skip("testdata/" + name + ".json is absent, copy it from the zcash-test-vectors repository to run the spec")
+	}
+	Expect(err).Should(BeNil())
+
+	rows := [][]json.RawMessage{}
+	Expect(json.Unmarshal(data, &rows)).Should(BeNil())
+	Expect(len(rows)).Should(BeNumerically(">", 2))
+	header := ""
+	Expect(json.Unmarshal(rows[1][0], &header)).Should(BeNil())
+	fields := strings.Split(header, ", ")
+
+	vectors := make([]map[string]json.RawMessage, 0, len(rows)-2)
+	for _, row := range rows[2:] {
+		Expect(row).Should(HaveLen(len(fields)))
+		vector := map[string]json.RawMessage{}
+		for i, field := range fields {
+			vector[field] = row[i]
+		}
+		vectors = append(vectors, vector)
+	}
+	return vectors
+}
+
+// vectorBytes decodes the hex encoded field of the vector.
+func vectorBytes(vector map[string]json.RawMessage, field string) []byte {
+	encoded := ""
+	Expect(json.Unmarshal(vector[field], &encoded)).Should(BeNil())
+	decoded, err := hex.DecodeString(encoded)
+	Expect(err).Should(BeNil())
+	return decoded
+}
+
+var _ = Describe("ZIP-243 test vectors", func() {
+	It("should compute the signature hashes of transactions with shielded components", func() {
+		for _, vector := range loadTestVectors("zip_0243") {
+			// Vectors without a transparent input only test the signature
+			// hash of the shielded components.
+			if string(vector["transparent_input"]) == "null" {
+				continue
+			}
+			var idx int
+			var hashType uint32
+			var amount int64
+			var branchID uint32
+			Expect(json.Unmarshal(vector["transparent_input"], &idx)).Should(BeNil())
+			Expect(json.Unmarshal(vector["hash_type"], &hashType)).Should(BeNil())
+			Expect(json.Unmarshal(vector["amount"], &amount)).Should(BeNil())
+			Expect(json.Unmarshal(vector["consensus_branch_id"], &branchID)).Should(BeNil())
+
+			decoded, err := DecodeTransaction(vectorBytes(vector, "tx"))
+			Expect(err).Should(BeNil())
+			msgTx, err := decoded.MsgTx()
+			Expect(err).Should(BeNil())
+			sigHash, err := CalcSignatureHashWithShieldedData(vectorBytes(vector, "script_code"), txscript.SigHashType(hashType), msgTx, idx, amount, branchID, &decoded.Shielded)
+			Expect(err).Should(BeNil())
+			Expect(sigHash).Should(Equal(vectorBytes(vector, "sighash")))
+		}
+	})
+})
+
+var _ = Describe("Transaction decoder test vectors", func() {
+	expectDecoded := func(name string, version int32) {
+		for _, vector := range loadTestVectors(name) {
+			raw := vectorBytes(vector, "tx")
+			decoded, err := DecodeTransaction(raw)
+			Expect(err).Should(BeNil())
+			Expect(decoded.Version).Should(Equal(version))
+
+			// Transactions without shielded components are encoded by
+			// zecutil, so they must be decoded to the same transaction.
+			shielded := decoded.Shielded
+			if version != 4 || len(shielded.Spends) != 0 || len(shielded.Outputs) != 0 || len(shielded.JoinSplits) != 0 {
+				continue
+			}
+			msgTx, err := decoded.MsgTx()
+			Expect(err).Should(BeNil())
+			buf := new(bytes.Buffer)
+			Expect(msgTx.ZecEncode(buf, 0, wire.BaseEncoding)).Should(BeNil())
+			Expect(buf.Bytes()).Should(Equal(raw))
+		}
+	}
+
+	It("should decode the sapling transactions of the zip-243 test vectors", func() {
+		expectDecoded("zip_0243", 4)
+	})
+
+	It("should decode the nu5 transactions of the zip-244 test vectors", func() {
+		expectDecoded("zip_0244", 5)
+	})
+})
+
+var _ = Describe("ZIP-244 test vectors", func() {
+	It("should compute the txids of nu5 transactions", func() {
+		for _, vector := range loadTestVectors("zip_0244") {
+			txID, err := TxID(vectorBytes(vector, "tx"))
+			Expect(err).Should(BeNil())
+
+			// The vectors store the digest in its internal byte order, which
+			// is the reverse of the txids returned by TxID.
+			expected := vectorBytes(vector, "txid")
+			for i, j := 0, len(expected)-1; i < j; i, j = i+1, j-1 {
+				expected[i], expected[j] = expected[j], expected[i]
+			}
+			Expect(txID).Should(Equal(expected))
+		}
+	})
+})
@@ -0,0 +1,44 @@
+package libzec
+
+import (
+	"fmt"
+)
+
+// TransactionView describes a transaction for bots and dashboards. It is the
+// structured alternative to FormatTransactionView.
+type TransactionView struct {
+	TxID          string `json:"txid"`
+	Network       string `json:"network"`
+	ExplorerURL   string `json:"explorerUrl,omitempty"`
+	Amount        int64  `json:"amount"`
+	Fee           int64  `json:"fee"`
+	Confirmations int64  `json:"confirmations"`
+}
+
+// String formats the view as a single line. Like FormatTransactionView, the
+// line ends with the link to the transaction if the explorer has one, but the
+// view is formatted even if it does not.
+func (view TransactionView) String() string {
+	if view.ExplorerURL == "" {
+		return fmt.Sprintf("transaction %s: amount %d fee %d confirmations %d", view.TxID, view.Amount, view.Fee, view.Confirmations)
+	}
+	return fmt.Sprintf("transaction %s: amount %d fee %d confirmations %d, transaction can be viewed at %s", view.TxID, view.Amount, view.Fee, view.Confirmations, view.ExplorerURL)
+}
+
+// ViewTransaction returns the view of a transaction sent by an account, with
+// its current number of confirmations.
+func (client *client) ViewTransaction(receipt TxReceipt) (TransactionView, error) {
+	conf, err := client.Confirmations(receipt.TxHash)
+	if err != nil {
+		return TransactionView{}, err
+	}
+	network := client.NetworkParams().Name
+	return TransactionView{
+		TxID:          receipt.TxHash,
+		Network:       network,
+		ExplorerURL:   client.blockExplorer().TxURL(network, receipt.TxHash),
+		Amount:        receipt.Value,
+		Fee:           receipt.Fee,
+		Confirmations: conf,
+	}, nil
+}
@@ -0,0 +1,48 @@
+package libzec_test
+
+import (
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/renproject/libzec-go"
+)
+
+var _ = Describe("Transaction views", func() {
+	txHash := chainhash.Hash{0xC0}.String()
+	receipt := TxReceipt{TxHash: txHash, Value: 40000, Fee: 1000}
+
+	newClient := func() Client {
+		core := newMockClientCore(&chaincfg.TestNet3Params, 1842420)
+		core.confirmations[txHash] = 3
+		return NewClient(core)
+	}
+
+	It("should view a transaction with its confirmations", func() {
+		view, err := newClient().ViewTransaction(receipt)
+		Expect(err).Should(BeNil())
+		Expect(view).Should(Equal(TransactionView{
+			TxID:          txHash,
+			Network:       "testnet3",
+			ExplorerURL:   "https://chain.so/tx/ZECTEST/" + txHash,
+			Amount:        40000,
+			Fee:           1000,
+			Confirmations: 3,
+		}))
+		Expect(view.String()).Should(Equal("transaction " + txHash + ": amount 40000 fee 1000 confirmations 3, transaction can be viewed at https://chain.so/tx/ZECTEST/" + txHash))
+	})
+
+	It("should view a transaction without a link if the explorer has none", func() {
+		client := newClient()
+		client.SetExplorer(BlockchairExplorer)
+		view, err := client.ViewTransaction(receipt)
+		Expect(err).Should(BeNil())
+		Expect(view.ExplorerURL).Should(BeEmpty())
+		Expect(view.String()).Should(Equal("transaction " + txHash + ": amount 40000 fee 1000 confirmations 3"))
+	})
+
+	It("should not view unknown transactions", func() {
+		_, err := newClient().ViewTransaction(TxReceipt{TxHash: chainhash.Hash{0xC1}.String()})
+		Expect(err).Should(Equal(ErrTxNotFound))
+	})
+})
@@ -1,6 +1,12 @@
 package libzec
 
 import (
+	"crypto/ecdsa"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/btcsuite/btcd/btcec"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/sirupsen/logrus"
 	"github.com/tyler-smith/go-bip32"
@@ -8,30 +14,122 @@ import (
 )
 
 type wallet struct {
-	mnemonic string
-	client   Client
-	logger   logrus.FieldLogger
+	mnemonic  string
+	masterKey *bip32.Key
+	metadata  MetadataStore
+	client    Client
+	logger    logrus.FieldLogger
+
+	// keystore is the keystore that holds the master key, or nil if the
+	// wallet was not created from a keystore.
+	keystore *Keystore
 }
 
 type Wallet interface {
+	// NewAccount returns the account at the given derivation path. Indices
+	// greater than or equal to 0x80000000 are derived as hardened children,
+	// see HardenedIndex and ParseDerivationPath.
 	NewAccount(derivationPath []uint32, password string) (Account, error)
+
+	// NewHDAccount returns an HD account for the BIP-44 account key at the
+	// given derivation path, such as m/44'/133'/0'.
+	NewHDAccount(derivationPath []uint32, password string) (HDAccount, error)
+
+	// AccountAt returns the account at the first receive address of the given
+	// account index, using the standard ZCash derivation path
+	// m/44'/133'/account'/0/0.
+	AccountAt(accountIndex uint32, password string) (Account, error)
+
+	// HDAccountAt returns the HD account at the given account index, using
+	// the standard ZCash derivation path m/44'/133'/account'.
+	HDAccountAt(accountIndex uint32, password string) (HDAccount, error)
+
+	// ExtendedPublicKey returns the base58 encoded extended public key at the
+	// given derivation path, which can be used to create a watch-only wallet.
+	ExtendedPublicKey(derivationPath []uint32, password string) (string, error)
+
+	// Metadata returns the store of address labels and transaction notes of
+	// the wallet. By default, the metadata is kept in memory.
+	Metadata() MetadataStore
+
+	// SetMetadataStore sets the store of address labels and transaction
+	// notes of the wallet.
+	SetMetadataStore(store MetadataStore)
+}
+
+// ZCashCoinType is the BIP-44 coin type of ZCash, as registered in SLIP-44.
+const ZCashCoinType = uint32(133)
+
+// ZCashDerivationPath returns the standard BIP-44 derivation path of ZCash
+// keys: m/44'/133'/account'/change/index.
+func ZCashDerivationPath(accountIndex, change, index uint32) []uint32 {
+	return append(zcashAccountPath(accountIndex), change, index)
+}
+
+func zcashAccountPath(accountIndex uint32) []uint32 {
+	return []uint32{HardenedIndex(44), HardenedIndex(ZCashCoinType), HardenedIndex(accountIndex)}
+}
+
+// HardenedIndex returns the hardened child index of the given index, which is
+// written as index' in derivation paths.
+func HardenedIndex(index uint32) uint32 {
+	return index + bip32.FirstHardenedChild
+}
+
+// ParseDerivationPath parses a derivation path, such as "m/44'/133'/0'/0/0",
+// into child indices that can be passed to NewAccount. Hardened components
+// are marked by an apostrophe, or by an h.
+func ParseDerivationPath(path string) ([]uint32, error) {
+	components := strings.Split(strings.TrimSpace(path), "/")
+	if len(components) == 0 || components[0] != "m" {
+		return nil, fmt.Errorf("invalid derivation path %q: must start with m", path)
+	}
+	indices := make([]uint32, 0, len(components)-1)
+	for _, component := range components[1:] {
+		hardened := strings.HasSuffix(component, "'") || strings.HasSuffix(component, "h")
+		if hardened {
+			component = component[:len(component)-1]
+		}
+		index, err := strconv.ParseUint(component, 10, 32)
+		if err != nil || index >= uint64(bip32.FirstHardenedChild) {
+			return nil, fmt.Errorf("invalid derivation path %q: invalid index %q", path, component)
+		}
+		if hardened {
+			index += uint64(bip32.FirstHardenedChild)
+		}
+		indices = append(indices, uint32(index))
+	}
+	return indices, nil
 }
 
 func NewWallet(mnemonic string, client Client, logger logrus.FieldLogger) Wallet {
-	return &wallet{mnemonic, client, logger}
+	return &wallet{mnemonic, nil, NewMemoryMetadataStore(), client, logger, nil}
+}
+
+// NewWalletFromExtendedKey returns a wallet for the given base58 encoded
+// extended private key (xprv) or extended public key (xpub). Derivation paths
+// are relative to the extended key, and passwords are ignored. The accounts of
+// a wallet created from an extended public key are watch-only, and can only be
+// derived using non-hardened indices.
+func NewWalletFromExtendedKey(extendedKey string, client Client, logger logrus.FieldLogger) (Wallet, error) {
+	key, err := bip32.B58Deserialize(extendedKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid extended key: %v", err)
+	}
+	return &wallet{"", key, NewMemoryMetadataStore(), client, logger, nil}, nil
 }
 
 func (wallet *wallet) NewAccount(derivationPath []uint32, password string) (Account, error) {
-	seed := bip39.NewSeed(wallet.mnemonic, password)
-	key, err := bip32.NewMasterKey(seed)
+	key, err := wallet.deriveKey(derivationPath, password)
 	if err != nil {
 		return nil, err
 	}
-	for _, val := range derivationPath {
-		key, err = key.NewChildKey(val)
+	if !key.IsPrivate {
+		pubKey, err := btcec.ParsePubKey(key.Key, btcec.S256())
 		if err != nil {
 			return nil, err
 		}
+		return NewWatchOnlyAccount(wallet.client, (*ecdsa.PublicKey)(pubKey), wallet.logger), nil
 	}
 	privKey, err := crypto.ToECDSA(key.Key)
 	if err != nil {
@@ -39,3 +137,68 @@ func (wallet *wallet) NewAccount(derivationPath []uint32, password string) (Acco
 	}
 	return NewAccount(wallet.client, privKey, wallet.logger), nil
 }
+
+func (wallet *wallet) NewHDAccount(derivationPath []uint32, password string) (HDAccount, error) {
+	key, err := wallet.deriveKey(derivationPath, password)
+	if err != nil {
+		return nil, err
+	}
+	return NewHDAccount(wallet.client, key, wallet.logger)
+}
+
+func (wallet *wallet) AccountAt(accountIndex uint32, password string) (Account, error) {
+	return wallet.NewAccount(ZCashDerivationPath(accountIndex, ExternalChain, 0), password)
+}
+
+func (wallet *wallet) HDAccountAt(accountIndex uint32, password string) (HDAccount, error) {
+	return wallet.NewHDAccount(zcashAccountPath(accountIndex), password)
+}
+
+func (wallet *wallet) Metadata() MetadataStore {
+	return wallet.metadata
+}
+
+func (wallet *wallet) SetMetadataStore(store MetadataStore) {
+	wallet.metadata = store
+}
+
+func (wallet *wallet) ExtendedPublicKey(derivationPath []uint32, password string) (string, error) {
+	key, err := wallet.deriveKey(derivationPath, password)
+	if err != nil {
+		return "", err
+	}
+	if key.IsPrivate {
+		key = key.PublicKey()
+	}
+	return key.B58Serialize(), nil
+}
+
+func (wallet *wallet) deriveKey(derivationPath []uint32, password string) (*bip32.Key, error) {
+	if wallet.keystore != nil {
+		// The master key is zeroed when the keystore is locked, so it is only
+		// used while the keystore holds it.
+		wallet.keystore.mu.Lock()
+		defer wallet.keystore.mu.Unlock()
+		if !wallet.keystore.holds(wallet.masterKey) {
+			return nil, ErrKeystoreLocked
+		}
+	}
+	key := wallet.masterKey
+	if key == nil {
+		var err error
+		seed := bip39.NewSeed(wallet.mnemonic, password)
+		if key, err = bip32.NewMasterKey(seed); err != nil {
+			return nil, err
+		}
+	}
+	for _, val := range derivationPath {
+		if !key.IsPrivate && val >= bip32.FirstHardenedChild {
+			return nil, fmt.Errorf("cannot derive hardened index %d from an extended public key", val-bip32.FirstHardenedChild)
+		}
+		var err error
+		if key, err = key.NewChildKey(val); err != nil {
+			return nil, err
+		}
+	}
+	return key, nil
+}
@@ -0,0 +1,42 @@
+package libzec_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/renproject/libzec-go"
+)
+
+var _ = Describe("Derivation paths", func() {
+	It("should parse hardened and normal components", func() {
+		path, err := ParseDerivationPath("m/44'/133'/0'/0/5")
+		Expect(err).Should(BeNil())
+		Expect(path).Should(Equal([]uint32{HardenedIndex(44), HardenedIndex(133), HardenedIndex(0), 0, 5}))
+	})
+
+	It("should parse the h notation for hardened components", func() {
+		path, err := ParseDerivationPath("m/44h/133h")
+		Expect(err).Should(BeNil())
+		Expect(path).Should(Equal([]uint32{0x8000002C, 0x80000085}))
+	})
+
+	It("should parse the master path", func() {
+		path, err := ParseDerivationPath("m")
+		Expect(err).Should(BeNil())
+		Expect(path).Should(BeEmpty())
+	})
+
+	It("should not parse invalid paths", func() {
+		for _, path := range []string{"", "44'/0", "m/", "m/x", "m/2147483648", "m/-1"} {
+			_, err := ParseDerivationPath(path)
+			Expect(err).ShouldNot(BeNil())
+		}
+	})
+})
+
+var _ = Describe("ZCash derivation paths", func() {
+	It("should use coin type 133", func() {
+		path, err := ParseDerivationPath("m/44'/133'/2'/1/7")
+		Expect(err).Should(BeNil())
+		Expect(ZCashDerivationPath(2, 1, 7)).Should(Equal(path))
+	})
+})
@@ -0,0 +1,335 @@
+package libzec
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/renproject/libzec-go/clients"
+	"github.com/sirupsen/logrus"
+)
+
+// TxEventType is the type of a TxEvent.
+type TxEventType int
+
+const (
+	// TxSeen is emitted when a watched transaction is found for the first
+	// time, either in the mempool or in a block.
+	TxSeen TxEventType = iota
+
+	// TxConfirmed is emitted once for every confirmation of a watched
+	// transaction, up to the number of confirmations of the watcher.
+	TxConfirmed
+
+	// TxReorged is emitted when a watched transaction loses confirmations,
+	// disappears after being mined, or is mined at another height, because
+	// of a chain reorganisation. The confirmations of the transaction are
+	// counted again from the event, so TxConfirmed events follow if it is
+	// still mined.
+	TxReorged
+
+	// TxExpired is emitted when a watched transaction is not mined before its
+	// expiry height. The transaction is no longer watched.
+	TxExpired
+
+	// TxDeposit is emitted when a new utxo appears at a watched address. The
+	// transaction that created it is then watched, so that the confirmations
+	// of the deposit are tracked.
+	TxDeposit
+)
+
+func (eventType TxEventType) String() string {
+	switch eventType {
+	case TxSeen:
+		return "seen"
+	case TxConfirmed:
+		return "confirmed"
+	case TxReorged:
+		return "reorged"
+	case TxExpired:
+		return "expired"
+	case TxDeposit:
+		return "deposit"
+	default:
+		return "unknown"
+	}
+}
+
+// TxEvent is a change of the state of a watched transaction. The address and
+// the utxo are only set for TxDeposit events.
+type TxEvent struct {
+	TxHash        string
+	Type          TxEventType
+	Confirmations int64
+	Address       string
+	UTXO          clients.UTXO
+}
+
+// A Watcher tracks a set of transactions, and delivers an event every time
+// the state of one of them changes. Transactions are no longer watched once
+// they reach the number of confirmations of the watcher, or once they expire.
+type Watcher interface {
+	// Watch starts watching the transaction. The expiry height should be
+	// zero if the transaction does not expire.
+	Watch(txHash string, expiryHeight uint32)
+
+	// Unwatch stops watching the transaction.
+	Unwatch(txHash string)
+
+	// WatchAddress watches the address for new utxos of at least the given
+	// amount, until the context is done. Every utxo at the address is
+	// reported once, including the ones that exist when the address is first
+	// polled.
+	WatchAddress(ctx context.Context, address string, minAmount int64)
+
+	// Events returns the channel on which events are delivered.
+	Events() <-chan TxEvent
+
+	// Run polls the watched transactions at the given interval, until the
+	// context is done. The events channel is closed when Run returns.
+	Run(ctx context.Context, interval time.Duration)
+}
+
+type watchedTx struct {
+	expiryHeight  uint32
+	seen          bool
+	confirmations int64
+
+	// minedAt is the height of the block that mined the transaction, or 0
+	// if it is not mined or the height is unknown.
+	minedAt int64
+}
+
+type watchedAddress struct {
+	ctx       context.Context
+	minAmount int64
+	known     map[string]bool
+}
+
+type watcher struct {
+	client        Client
+	confirmations int64
+	logger        logrus.FieldLogger
+	events        chan TxEvent
+
+	mu        *sync.Mutex
+	watched   map[string]*watchedTx
+	addresses map[string]*watchedAddress
+}
+
+// NewWatcher returns a Watcher that tracks transactions until they have the
+// given number of confirmations. The confirmations are fetched in batches if
+// the client core implements clients.BatchClient. Transactions that cannot be
+// found are only considered to be missing if the client returns
+// ErrTxNotFound, so that transient failures of the backend are not reported
+// as reorganisations. A reorganisation is also detected when the height at
+// which a transaction is mined, computed from the block height and its
+// confirmations, changes.
+func NewWatcher(client Client, confirmations int64, logger logrus.FieldLogger) Watcher {
+	return &watcher{
+		client:        client,
+		confirmations: confirmations,
+		logger:        defaultLogger(logger),
+		events:        make(chan TxEvent, 16),
+		mu:            new(sync.Mutex),
+		watched:       map[string]*watchedTx{},
+		addresses:     map[string]*watchedAddress{},
+	}
+}
+
+func (watcher *watcher) Watch(txHash string, expiryHeight uint32) {
+	watcher.mu.Lock()
+	defer watcher.mu.Unlock()
+	if _, ok := watcher.watched[txHash]; !ok {
+		watcher.watched[txHash] = &watchedTx{expiryHeight: expiryHeight}
+	}
+}
+
+func (watcher *watcher) Unwatch(txHash string) {
+	watcher.mu.Lock()
+	defer watcher.mu.Unlock()
+	delete(watcher.watched, txHash)
+}
+
+func (watcher *watcher) WatchAddress(ctx context.Context, address string, minAmount int64) {
+	watcher.mu.Lock()
+	defer watcher.mu.Unlock()
+	watcher.addresses[address] = &watchedAddress{ctx, minAmount, map[string]bool{}}
+}
+
+func (watcher *watcher) Events() <-chan TxEvent {
+	return watcher.events
+}
+
+func (watcher *watcher) Run(ctx context.Context, interval time.Duration) {
+	defer close(watcher.events)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		watcher.poll(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// poll fetches the utxos of every watched address, and the confirmations of
+// every watched transaction in a batch, between two fetches of the block
+// height.
+func (watcher *watcher) poll(ctx context.Context) {
+	if !watcher.pollAddresses(ctx) {
+		return
+	}
+
+	watcher.mu.Lock()
+	txHashes := make([]string, 0, len(watcher.watched))
+	for txHash := range watcher.watched {
+		txHashes = append(txHashes, txHash)
+	}
+	watcher.mu.Unlock()
+	if len(txHashes) == 0 {
+		return
+	}
+
+	height, hasHeight := watcher.blockHeight()
+	confs, err := BatchConfirmations(watcher.client, txHashes)
+	if err != nil {
+		watcher.logger.Infof("cannot get the confirmations of the watched transactions: %v", err)
+		return
+	}
+	// The heights at which the transactions are mined are only known if no
+	// block was mined while their confirmations were fetched.
+	stable := false
+	if hasHeight {
+		after, ok := watcher.blockHeight()
+		stable = ok && after == height
+	}
+
+	for _, txHash := range txHashes {
+		conf, found := confs[txHash]
+		var minedAt int64
+		if found && conf > 0 && stable {
+			minedAt = height - conf + 1
+		}
+
+		watcher.mu.Lock()
+		tx, ok := watcher.watched[txHash]
+		if !ok {
+			watcher.mu.Unlock()
+			continue
+		}
+		events := watcher.update(txHash, tx, found, conf, minedAt, hasHeight, height)
+		watcher.mu.Unlock()
+
+		for _, event := range events {
+			select {
+			case <-ctx.Done():
+				return
+			case watcher.events <- event:
+			}
+		}
+	}
+}
+
+// blockHeight returns the block height, and whether it is known.
+func (watcher *watcher) blockHeight() (int64, bool) {
+	height, err := watcher.client.BlockHeight()
+	if err != nil && err != ErrNotSupported {
+		watcher.logger.Infof("cannot get the block height: %v", err)
+	}
+	return height, err == nil
+}
+
+// pollAddresses fetches the utxos of the watched addresses, and reports the
+// new ones. It returns false if the context is done.
+func (watcher *watcher) pollAddresses(ctx context.Context) bool {
+	watcher.mu.Lock()
+	addresses := make([]string, 0, len(watcher.addresses))
+	for address, watched := range watcher.addresses {
+		if watched.ctx.Err() != nil {
+			delete(watcher.addresses, address)
+			continue
+		}
+		addresses = append(addresses, address)
+	}
+	watcher.mu.Unlock()
+
+	for _, address := range addresses {
+		utxos, err := watcher.client.GetUTXOs(address, 0, 0)
+		if err != nil {
+			watcher.logger.Infof("cannot get the utxos of %s: %v", address, err)
+			continue
+		}
+
+		events := []TxEvent{}
+		watcher.mu.Lock()
+		watched, ok := watcher.addresses[address]
+		for _, utxo := range utxos {
+			key := outPointKey(utxo.TxHash, utxo.Vout)
+			if !ok || utxo.Amount < watched.minAmount || watched.known[key] {
+				continue
+			}
+			watched.known[key] = true
+			events = append(events, TxEvent{TxHash: utxo.TxHash, Type: TxDeposit, Address: address, UTXO: utxo})
+			if _, ok := watcher.watched[utxo.TxHash]; !ok {
+				watcher.watched[utxo.TxHash] = &watchedTx{}
+			}
+		}
+		watcher.mu.Unlock()
+
+		for _, event := range events {
+			select {
+			case <-ctx.Done():
+				return false
+			case watcher.events <- event:
+			}
+		}
+	}
+	return true
+}
+
+// update updates the state of the transaction, and returns the resulting
+// events. The mined height is 0 if it is unknown. It must be called with the
+// lock held.
+func (watcher *watcher) update(txHash string, tx *watchedTx, found bool, conf, minedAt int64, hasHeight bool, height int64) []TxEvent {
+	events := []TxEvent{}
+	if !found {
+		tx.minedAt = 0
+		if tx.confirmations > 0 {
+			tx.confirmations = 0
+			events = append(events, TxEvent{TxHash: txHash, Type: TxReorged})
+		}
+	} else {
+		if !tx.seen {
+			tx.seen = true
+			events = append(events, TxEvent{TxHash: txHash, Type: TxSeen, Confirmations: conf})
+		}
+		switch {
+		case minedAt != 0 && tx.minedAt != 0 && minedAt != tx.minedAt:
+			tx.confirmations = 0
+			events = append(events, TxEvent{TxHash: txHash, Type: TxReorged, Confirmations: conf})
+		case conf < tx.confirmations:
+			tx.confirmations = conf
+			events = append(events, TxEvent{TxHash: txHash, Type: TxReorged, Confirmations: conf})
+		}
+		if minedAt != 0 || conf == 0 {
+			tx.minedAt = minedAt
+		}
+		for tx.confirmations < conf && tx.confirmations < watcher.confirmations {
+			tx.confirmations++
+			events = append(events, TxEvent{TxHash: txHash, Type: TxConfirmed, Confirmations: tx.confirmations})
+		}
+		if tx.confirmations >= watcher.confirmations {
+			delete(watcher.watched, txHash)
+			return events
+		}
+	}
+
+	if tx.confirmations == 0 && hasHeight && expired(tx.expiryHeight, height) {
+		delete(watcher.watched, txHash)
+		events = append(events, TxEvent{TxHash: txHash, Type: TxExpired})
+	}
+	return events
+}
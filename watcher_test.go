@@ -0,0 +1,114 @@
+package libzec_test
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/renproject/libzec-go"
+	"github.com/renproject/libzec-go/clients"
+	"github.com/sirupsen/logrus"
+)
+
+// countingBatchClientCore is a batch mock client core that counts the
+// confirmations it looks up one by one.
+type countingBatchClientCore struct {
+	*batchMockClientCore
+	lookups int64
+}
+
+func (core *countingBatchClientCore) Confirmations(txHash string) (int64, error) {
+	atomic.AddInt64(&core.lookups, 1)
+	return core.mockClientCore.Confirmations(txHash)
+}
+
+var _ = Describe("Transaction watchers", func() {
+	txHash := chainhash.Hash{0xB0}.String()
+
+	// mine sets the block height and the confirmations of the transaction
+	// at once, so that the watcher never sees one without the other.
+	mine := func(core *mockClientCore, height, conf int64) {
+		core.mu.Lock()
+		defer core.mu.Unlock()
+		core.height = height
+		core.confirmations[txHash] = conf
+	}
+
+	expectEvent := func(events <-chan TxEvent, eventType TxEventType, conf int64) {
+		event := TxEvent{}
+		Eventually(events).Should(Receive(&event))
+		Expect(event.TxHash).Should(Equal(txHash))
+		Expect(event.Type).Should(Equal(eventType))
+		Expect(event.Confirmations).Should(Equal(conf))
+	}
+
+	run := func(core clients.ClientCore) (Watcher, context.CancelFunc) {
+		watcher := NewWatcher(NewClient(core), 3, logrus.StandardLogger())
+		ctx, cancel := context.WithCancel(context.Background())
+		go watcher.Run(ctx, 10*time.Millisecond)
+		return watcher, cancel
+	}
+
+	It("should batch the confirmations and detect transactions mined at another height", func() {
+		core := newMockClientCore(&chaincfg.TestNet3Params, 100)
+		counting := &countingBatchClientCore{batchMockClientCore: &batchMockClientCore{core, map[string]bool{}}}
+		mine(core, 100, 0)
+		watcher, cancel := run(counting)
+		defer cancel()
+		watcher.Watch(txHash, 0)
+		events := watcher.Events()
+		expectEvent(events, TxSeen, 0)
+
+		mine(core, 101, 1)
+		expectEvent(events, TxConfirmed, 1)
+
+		// The block that mined the transaction is replaced, and the
+		// transaction is mined again in the next block.
+		mine(core, 102, 1)
+		expectEvent(events, TxReorged, 1)
+		expectEvent(events, TxConfirmed, 1)
+
+		mine(core, 104, 3)
+		expectEvent(events, TxConfirmed, 2)
+		expectEvent(events, TxConfirmed, 3)
+		Expect(atomic.LoadInt64(&counting.lookups)).Should(Equal(int64(0)))
+	})
+
+	It("should detect transactions that lose their confirmations or disappear", func() {
+		core := newMockClientCore(&chaincfg.TestNet3Params, 100)
+		mine(core, 101, 2)
+		watcher, cancel := run(core)
+		defer cancel()
+		watcher.Watch(txHash, 0)
+		events := watcher.Events()
+		expectEvent(events, TxSeen, 2)
+		expectEvent(events, TxConfirmed, 1)
+		expectEvent(events, TxConfirmed, 2)
+
+		mine(core, 101, 0)
+		expectEvent(events, TxReorged, 0)
+
+		mine(core, 102, 1)
+		expectEvent(events, TxConfirmed, 1)
+		core.mu.Lock()
+		delete(core.confirmations, txHash)
+		core.mu.Unlock()
+		expectEvent(events, TxReorged, 0)
+	})
+
+	It("should expire transactions that are not mined before their expiry height", func() {
+		core := newMockClientCore(&chaincfg.TestNet3Params, 100)
+		watcher, cancel := run(core)
+		defer cancel()
+		watcher.Watch(txHash, 102)
+		events := watcher.Events()
+		Consistently(events, 50*time.Millisecond).ShouldNot(Receive())
+
+		core.setHeight(102)
+		expectEvent(events, TxExpired, 0)
+	})
+})